@@ -0,0 +1,246 @@
+// Package agent reads the classic BPF filters attached to packet sockets
+// on a node, such as the one the Antrea agent attaches for a
+// PacketCapture, so that they can be compared with the prototype's
+// program for the same CRD.
+//
+// The filters are read with ss, which asks the kernel's sock_diag
+// interface for them. /proc/net/packet lists packet sockets without their
+// filters, and bpftool lists eBPF programs only, while the kernel keeps a
+// classic socket filter as it was attached.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+)
+
+// DefaultProcess is the name of the Antrea agent's process
+const DefaultProcess = "antrea-agent"
+
+// Source marks reference programs read from a node, in reports
+const Source = "agent"
+
+// DefaultTimeout bounds one run of ss on the node, including the time
+// kubectl or ssh takes to reach it
+const DefaultTimeout = 30 * time.Second
+
+// ssCommand lists packet sockets with their filters and owners, without
+// the header line
+var ssCommand = []string{"ss", "--packet", "--bpf", "--processes", "--no-header"}
+
+// Access is how commands reach the node. With neither field set they run
+// on this host.
+type Access struct {
+	Pod       string // agent pod as namespace/name, for kubectl exec
+	Container string // container in the pod (empty means the pod's default)
+	SSH       string // node as [user@]host, for ssh
+}
+
+// Validate checks that at most one way to reach the node is given
+func (a Access) Validate() error {
+	if a.Pod != "" && a.SSH != "" {
+		return fmt.Errorf("a node is reached through kubectl exec or ssh, not both")
+	}
+	if a.Container != "" && a.Pod == "" {
+		return fmt.Errorf("a container applies to kubectl exec into a pod")
+	}
+	if a.Pod != "" && strings.Count(a.Pod, "/") != 1 {
+		return fmt.Errorf("invalid pod '%s', want namespace/name", a.Pod)
+	}
+	return nil
+}
+
+// Command returns the command line running argv on the node
+func (a Access) Command(argv ...string) []string {
+	switch {
+	case a.Pod != "":
+		namespace, name, _ := strings.Cut(a.Pod, "/")
+		command := []string{"kubectl", "exec", "--namespace", namespace, name}
+		if a.Container != "" {
+			command = append(command, "--container", a.Container)
+		}
+		return append(append(command, "--"), argv...)
+	case a.SSH != "":
+		// BatchMode fails rather than prompt for a password nobody can type
+		return append([]string{"ssh", "-o", "BatchMode=yes", a.SSH, "--"}, argv...)
+	}
+	return argv
+}
+
+// String names the node for reports
+func (a Access) String() string {
+	switch {
+	case a.Pod != "":
+		return "pod " + a.Pod
+	case a.SSH != "":
+		return "host " + a.SSH
+	}
+	return "this host"
+}
+
+// Socket is a packet socket with a classic BPF filter attached
+type Socket struct {
+	Netid   string // p_raw for SOCK_RAW, p_dgr for SOCK_DGRAM
+	Local   string // protocol and interface the socket is bound to, as ss prints them
+	Process string // name of the first process holding the socket, "" when ss could not tell
+	PID     int
+	FD      int
+	Filter  []*bpf.Instruction
+}
+
+// String names the socket, e.g. "antrea-agent pid 4242 fd 17 (p_raw
+// *:eth0)"
+func (s *Socket) String() string {
+	if s.Process == "" {
+		return fmt.Sprintf("unknown process (%s %s)", s.Netid, s.Local)
+	}
+	return fmt.Sprintf("%s pid %d fd %d (%s %s)", s.Process, s.PID, s.FD, s.Netid, s.Local)
+}
+
+// Extract runs ss on the node and returns the packet sockets with a
+// filter that a process of the given name holds ("" for any process).
+// Reading filters needs CAP_NET_ADMIN on the node; without it ss lists
+// the sockets but not their filters.
+func Extract(ctx context.Context, access Access, process string) ([]*Socket, error) {
+	if err := access.Validate(); err != nil {
+		return nil, err
+	}
+	argv := access.Command(ssCommand...)
+	runCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	logging.Logger().Debug("listing packet sockets", "command", strings.Join(argv, " "))
+
+	out, err := exec.CommandContext(runCtx, argv[0], argv[1:]...).Output()
+	if runCtx.Err() != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("ss on %s did not finish within %v", access, DefaultTimeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("ss on %s failed: %w\nStderr: %s", access, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to run ss on %s: %w", access, err)
+	}
+
+	sockets, err := ParseSS(string(out))
+	if err != nil {
+		return nil, err
+	}
+	var owned []*Socket
+	for _, s := range sockets {
+		if process == "" || s.Process == process {
+			owned = append(owned, s)
+		}
+	}
+	if len(owned) == 0 {
+		owner := "any process"
+		if process != "" {
+			owner = process
+		}
+		return nil, fmt.Errorf("no packet socket of %s on %s has a filter attached; is a PacketCapture running, and does ss run with CAP_NET_ADMIN?", owner, access)
+	}
+	return owned, nil
+}
+
+// ParseSS reads the packet sockets with a filter from the output of
+// "ss --packet --bpf --processes". A socket line is followed by its
+// filter on an indented line such as
+//
+//	bpf filter (4):  0x28 0 0 12, 0x15 0 1 2048, 0x06 0 0 262144, 0x06 0 0 0,
+func ParseSS(output string) ([]*Socket, error) {
+	var sockets []*Socket
+	var current *Socket
+	for n, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "Netid"):
+			continue
+		case strings.HasPrefix(trimmed, "bpf filter"):
+			if current == nil {
+				return nil, fmt.Errorf("ss output line %d: a filter without a socket", n+1)
+			}
+			filter, err := parseFilter(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("ss output line %d: %w", n+1, err)
+			}
+			current.Filter = filter
+			sockets = append(sockets, current)
+			current = nil
+		default:
+			current = parseSocket(trimmed)
+		}
+	}
+	return sockets, nil
+}
+
+// parseSocket reads a socket line: netid, queues, local and peer
+// address, and the processes holding it
+func parseSocket(line string) *Socket {
+	fields := strings.Fields(line)
+	s := &Socket{Netid: fields[0]}
+	if len(fields) > 3 {
+		s.Local = fields[3]
+	}
+	// users:(("antrea-agent",pid=4242,fd=17),...)
+	_, users, found := strings.Cut(line, "users:((")
+	if !found {
+		return s
+	}
+	first, _, _ := strings.Cut(users, ")")
+	parts := strings.Split(first, ",")
+	s.Process = strings.Trim(parts[0], `"`)
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, "=")
+		n, _ := strconv.Atoi(value)
+		switch key {
+		case "pid":
+			s.PID = n
+		case "fd":
+			s.FD = n
+		}
+	}
+	return s
+}
+
+// parseFilter reads "bpf filter (N): code jt jf k, ..." with the code in
+// hex and the rest in decimal
+func parseFilter(line string) ([]*bpf.Instruction, error) {
+	head, body, found := strings.Cut(line, ":")
+	if !found {
+		return nil, fmt.Errorf("malformed filter line '%s'", line)
+	}
+	count, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(head, "bpf filter"), " ()"))
+	if err != nil {
+		return nil, fmt.Errorf("malformed filter length in '%s'", head)
+	}
+
+	var filter []*bpf.Instruction
+	for _, text := range strings.Split(body, ",") {
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("malformed instruction '%s'", strings.TrimSpace(text))
+		}
+		var values [4]uint64
+		for i, bits := range []int{16, 8, 8, 32} {
+			if values[i], err = strconv.ParseUint(fields[i], 0, bits); err != nil {
+				return nil, fmt.Errorf("malformed instruction '%s': %w", strings.TrimSpace(text), err)
+			}
+		}
+		filter = append(filter, &bpf.Instruction{Code: uint16(values[0]), JT: uint8(values[1]), JF: uint8(values[2]), K: uint32(values[3])})
+	}
+	if len(filter) != count {
+		return nil, fmt.Errorf("filter of %d instructions lists %d", count, len(filter))
+	}
+	return filter, nil
+}
@@ -0,0 +1,5 @@
+// Package validatorv1 holds the gRPC API of the validator, generated from
+// validator.proto. The server is in package server.
+package validatorv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative validator.proto
@@ -0,0 +1,222 @@
+// Typed API of the BPF validator, for the Antrea agent and CI harnesses
+// that call it over gRPC instead of running the CLI.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: validator.proto
+
+package validatorv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Validator_Compile_FullMethodName  = "/antrea.bpfvalidator.v1.Validator/Compile"
+	Validator_Compare_FullMethodName  = "/antrea.bpfvalidator.v1.Validator/Compare"
+	Validator_Simulate_FullMethodName = "/antrea.bpfvalidator.v1.Validator/Simulate"
+)
+
+// ValidatorClient is the client API for Validator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Validator compiles packet filters with the prototype generator and the
+// tcpdump reference, compares the two programs and runs packets through
+// them.
+type ValidatorClient interface {
+	// Compile generates the prototype and/or reference program for a filter.
+	Compile(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (*CompileResponse, error)
+	// Compare generates both programs and compares them, like the compare
+	// command, optionally applying a CI gate.
+	Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error)
+	// Simulate runs every packet through the requested programs and streams
+	// one result per packet, in request order.
+	Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SimulateResult], error)
+}
+
+type validatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewValidatorClient(cc grpc.ClientConnInterface) ValidatorClient {
+	return &validatorClient{cc}
+}
+
+func (c *validatorClient) Compile(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (*CompileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompileResponse)
+	err := c.cc.Invoke(ctx, Validator_Compile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *validatorClient) Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompareResponse)
+	err := c.cc.Invoke(ctx, Validator_Compare_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *validatorClient) Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SimulateResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Validator_ServiceDesc.Streams[0], Validator_Simulate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SimulateRequest, SimulateResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Validator_SimulateClient = grpc.ServerStreamingClient[SimulateResult]
+
+// ValidatorServer is the server API for Validator service.
+// All implementations must embed UnimplementedValidatorServer
+// for forward compatibility.
+//
+// Validator compiles packet filters with the prototype generator and the
+// tcpdump reference, compares the two programs and runs packets through
+// them.
+type ValidatorServer interface {
+	// Compile generates the prototype and/or reference program for a filter.
+	Compile(context.Context, *CompileRequest) (*CompileResponse, error)
+	// Compare generates both programs and compares them, like the compare
+	// command, optionally applying a CI gate.
+	Compare(context.Context, *CompareRequest) (*CompareResponse, error)
+	// Simulate runs every packet through the requested programs and streams
+	// one result per packet, in request order.
+	Simulate(*SimulateRequest, grpc.ServerStreamingServer[SimulateResult]) error
+	mustEmbedUnimplementedValidatorServer()
+}
+
+// UnimplementedValidatorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedValidatorServer struct{}
+
+func (UnimplementedValidatorServer) Compile(context.Context, *CompileRequest) (*CompileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compile not implemented")
+}
+func (UnimplementedValidatorServer) Compare(context.Context, *CompareRequest) (*CompareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compare not implemented")
+}
+func (UnimplementedValidatorServer) Simulate(*SimulateRequest, grpc.ServerStreamingServer[SimulateResult]) error {
+	return status.Errorf(codes.Unimplemented, "method Simulate not implemented")
+}
+func (UnimplementedValidatorServer) mustEmbedUnimplementedValidatorServer() {}
+func (UnimplementedValidatorServer) testEmbeddedByValue()                   {}
+
+// UnsafeValidatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ValidatorServer will
+// result in compilation errors.
+type UnsafeValidatorServer interface {
+	mustEmbedUnimplementedValidatorServer()
+}
+
+func RegisterValidatorServer(s grpc.ServiceRegistrar, srv ValidatorServer) {
+	// If the following call pancis, it indicates UnimplementedValidatorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Validator_ServiceDesc, srv)
+}
+
+func _Validator_Compile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ValidatorServer).Compile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Validator_Compile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ValidatorServer).Compile(ctx, req.(*CompileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Validator_Compare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ValidatorServer).Compare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Validator_Compare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ValidatorServer).Compare(ctx, req.(*CompareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Validator_Simulate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SimulateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ValidatorServer).Simulate(m, &grpc.GenericServerStream[SimulateRequest, SimulateResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Validator_SimulateServer = grpc.ServerStreamingServer[SimulateResult]
+
+// Validator_ServiceDesc is the grpc.ServiceDesc for Validator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Validator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "antrea.bpfvalidator.v1.Validator",
+	HandlerType: (*ValidatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Compile",
+			Handler:    _Validator_Compile_Handler,
+		},
+		{
+			MethodName: "Compare",
+			Handler:    _Validator_Compare_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Simulate",
+			Handler:       _Validator_Simulate_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "validator.proto",
+}
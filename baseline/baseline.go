@@ -0,0 +1,297 @@
+// Package baseline records the programs and comparison scores generated for
+// a list of filters and flags regressions on later runs. Unlike golden
+// files, which pin a curated set of programs in the repository, a baseline
+// is a local store for any corpus, such as nightly policy exports: each
+// filter's record is kept as a JSON file under a directory per reference
+// compiler version, since another tcpdump or libpcap release emits other
+// code and scores differently.
+package baseline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/batch"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// Check statuses, from best to worst
+const (
+	StatusMatch     = "ok"        // same programs and score
+	StatusNew       = "new"       // no record for this filter and reference
+	StatusImproved  = "improved"  // the score went up
+	StatusDrifted   = "drifted"   // the prototype changed, the score did not
+	StatusRegressed = "REGRESSED" // the score went down
+	StatusSaved     = "saved"     // recorded by Save
+)
+
+// Record is the stored outcome of one filter
+type Record struct {
+	Name      string    `json:"name"`
+	Filter    string    `json:"filter"` // tcpdump expression
+	LinkType  string    `json:"link-type"`
+	Reference string    `json:"reference"` // reference compiler and version
+	Tool      string    `json:"tool"`      // validator build that saved the record
+	Saved     time.Time `json:"saved"`
+
+	Score   float64 `json:"score"`
+	Verdict string  `json:"verdict"`
+
+	// Programs as tcpdump -d style disassembly, one instruction per line
+	Prototype        []string `json:"prototype"`
+	ReferenceProgram []string `json:"reference-program"`
+}
+
+// Result is the outcome of saving or checking one filter
+type Result struct {
+	Name    string
+	Status  string
+	Current *Record
+	Saved   *Record // nil for new filters and when saving
+	Diff    string  // prototype line differences when it changed
+	Err     error
+}
+
+// Passed reports whether the filter was compared and did not regress
+func (r *Result) Passed() bool {
+	return r.Err == nil && r.Status != StatusRegressed
+}
+
+// Drifted reports whether the prototype program changed, whatever the score
+func (r *Result) Drifted() bool {
+	return r.Saved != nil && !slices.Equal(r.Saved.Prototype, r.Current.Prototype)
+}
+
+// Save generates and compares both programs for every entry and writes
+// the records to dir, replacing earlier records of the same filters and
+// reference version
+func Save(dir string, entries []*batch.Entry) []*Result {
+	var results []*Result
+	for _, e := range entries {
+		result := &Result{Name: e.Name}
+		results = append(results, result)
+		if result.Current, result.Err = record(e); result.Err != nil {
+			continue
+		}
+		result.Err = write(dir, result.Current)
+		result.Status = StatusSaved
+	}
+	return results
+}
+
+// Check generates and compares both programs for every entry and compares
+// the outcome with the records in dir
+func Check(dir string, entries []*batch.Entry) []*Result {
+	var results []*Result
+	for _, e := range entries {
+		result := &Result{Name: e.Name}
+		results = append(results, result)
+		if result.Current, result.Err = record(e); result.Err != nil {
+			continue
+		}
+		if result.Saved, result.Err = read(dir, result.Current); result.Err != nil {
+			continue
+		}
+
+		saved, current := result.Saved, result.Current
+		switch {
+		case saved == nil:
+			result.Status = StatusNew
+		case current.Score < saved.Score:
+			result.Status = StatusRegressed
+		case current.Score > saved.Score:
+			result.Status = StatusImproved
+		case result.Drifted():
+			result.Status = StatusDrifted
+		default:
+			result.Status = StatusMatch
+		}
+		if result.Drifted() {
+			result.Diff = diff(saved.Prototype, current.Prototype)
+		}
+	}
+	return results
+}
+
+// record generates and compares both programs for an entry
+func record(e *batch.Entry) (*Record, error) {
+	reference, err := tcpdump.GenerateBPF(context.Background(), &e.PacketFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tcpdump BPF: %w", err)
+	}
+	prototype, err := bpfgen.GenerateBPF(&e.PacketFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate prototype BPF: %w", err)
+	}
+	comparison := compare.Compare(reference, prototype)
+
+	return &Record{
+		Name:             e.Name,
+		Filter:           e.ToTcpdumpFilter(),
+		LinkType:         prototype.LinkType,
+		Reference:        tcpdump.SourceVersion(reference.Source),
+		Tool:             toolVersion(),
+		Saved:            time.Now().UTC().Truncate(time.Second),
+		Score:            comparison.Score,
+		Verdict:          comparison.Verdict,
+		Prototype:        lines(bpf.Disassemble(prototype.Instructions)),
+		ReferenceProgram: lines(bpf.Disassemble(reference.Instructions)),
+	}, nil
+}
+
+// unsafeChars are replaced in directory names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// path returns the record's file: a directory for the reference version
+// and a file named by a hash of the filter, so that renaming an entry
+// keeps its history
+func path(dir string, r *Record) string {
+	sum := sha256.Sum256([]byte(r.LinkType + "\x00" + r.Filter))
+	version := strings.Trim(unsafeChars.ReplaceAllString(r.Reference, "-"), "-")
+	return filepath.Join(dir, version, hex.EncodeToString(sum[:8])+".json")
+}
+
+// write stores a record
+func write(dir string, r *Record) error {
+	p := path(dir, r)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// read loads the saved record of the same filter and reference version,
+// or nil if there is none
+func read(dir string, current *Record) (*Record, error) {
+	p := path(dir, current)
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", p, err)
+	}
+	return &r, nil
+}
+
+// toolVersion identifies the validator build from its module and VCS
+// information
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+			version += " " + s.Value[:12]
+		}
+		if s.Key == "vcs.modified" && s.Value == "true" {
+			version += "+dirty"
+		}
+	}
+	return version
+}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+// diff lists the lines that differ by position, prefixing the saved line
+// with "-" and the current one with "+"
+func diff(saved, current []string) string {
+	var sb strings.Builder
+	for i := 0; i < len(saved) || i < len(current); i++ {
+		var s, c string
+		if i < len(saved) {
+			s = saved[i]
+		}
+		if i < len(current) {
+			c = current[i]
+		}
+		if s == c {
+			continue
+		}
+		if i < len(saved) {
+			sb.WriteString(fmt.Sprintf("- %s\n", s))
+		}
+		if i < len(current) {
+			sb.WriteString(fmt.Sprintf("+ %s\n", c))
+		}
+	}
+	return sb.String()
+}
+
+// Report formats results as a table of statuses and scores followed by the
+// prototype differences of every drifted filter
+func Report(results []*Result) string {
+	var sb strings.Builder
+	regressed, drifted, saved := 0, 0, 0
+
+	for _, r := range results {
+		status := r.Status
+		if r.Err != nil {
+			status = "ERROR"
+		}
+		score := ""
+		switch {
+		case r.Current != nil && r.Saved != nil:
+			score = fmt.Sprintf("%.2f -> %.2f", r.Saved.Score, r.Current.Score)
+		case r.Current != nil:
+			score = fmt.Sprintf("%.2f", r.Current.Score)
+		}
+		switch r.Status {
+		case StatusRegressed:
+			regressed++
+		case StatusSaved:
+			saved++
+		}
+		if r.Err == nil && r.Drifted() {
+			drifted++
+		}
+		sb.WriteString(fmt.Sprintf("%-9s %-14s %s\n", status, score, r.Name))
+	}
+
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			sb.WriteString(fmt.Sprintf("\n%s: %v\n", r.Name, r.Err))
+		case r.Diff != "":
+			sb.WriteString(fmt.Sprintf("\n%s (prototype):\n%s", r.Name, r.Diff))
+		}
+	}
+
+	if len(results) > 0 && results[0].Current != nil {
+		sb.WriteString(fmt.Sprintf("\nReference: %s\n", results[0].Current.Reference))
+	}
+	if saved > 0 {
+		sb.WriteString(fmt.Sprintf("%d of %d filters saved\n", saved, len(results)))
+	} else {
+		sb.WriteString(fmt.Sprintf("%d regressed, %d drifted of %d filters\n", regressed, drifted, len(results)))
+	}
+	return sb.String()
+}
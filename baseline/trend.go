@@ -0,0 +1,278 @@
+package baseline
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyDir is the directory of the store that holds score histories
+const historyDir = "history"
+
+// Causes of a score change, from what changed between the two runs
+const (
+	CausePrototype = "prototype changed" // the prototype program differs
+	CauseReference = "reference changed" // the reference compiler or its version differs
+	CauseBoth      = "prototype and reference changed"
+	CauseValidator = "validator changed" // same programs, so the comparison itself scores differently
+)
+
+// Point is the score of one filter at one run
+type Point struct {
+	Time      time.Time `json:"time"`
+	Name      string    `json:"name"`
+	Filter    string    `json:"filter"`
+	LinkType  string    `json:"link-type"`
+	Reference string    `json:"reference"`
+	Tool      string    `json:"tool"`
+	Score     float64   `json:"score"`
+	Verdict   string    `json:"verdict"`
+	Program   string    `json:"program"` // hash of the prototype program
+}
+
+// Trend is the history of one filter's score, oldest run first
+type Trend struct {
+	Name     string // the latest run's name for the filter
+	Filter   string
+	LinkType string
+	Points   []*Point
+}
+
+// Change is a run whose score or prototype program differs from the run
+// before it
+type Change struct {
+	From, To *Point
+	Cause    string
+}
+
+// Regressed reports whether the score went down
+func (c *Change) Regressed() bool {
+	return c.To.Score < c.From.Score
+}
+
+// String formats the change, e.g. "0.93 -> 0.87 REGRESSED: prototype
+// changed (build a -> build b)"
+func (c *Change) String() string {
+	status := StatusImproved
+	switch {
+	case c.Regressed():
+		status = StatusRegressed
+	case c.To.Score == c.From.Score:
+		status = StatusDrifted
+	}
+	s := fmt.Sprintf("%.2f -> %.2f %s: %s", c.From.Score, c.To.Score, status, c.Cause)
+	switch {
+	case c.Cause == CauseReference || c.Cause == CauseBoth:
+		s += fmt.Sprintf(" (%s -> %s)", c.From.Reference, c.To.Reference)
+	case c.From.Tool != c.To.Tool:
+		s += fmt.Sprintf(" (build %s -> %s)", c.From.Tool, c.To.Tool)
+	}
+	return s
+}
+
+// AppendHistory adds the score of every compared filter to its history
+// in dir, so that later runs can be listed as a trend. Save and Check
+// results both count as runs.
+func AppendHistory(dir string, results []*Result) error {
+	for _, r := range results {
+		if r.Err != nil || r.Current == nil {
+			continue
+		}
+		if err := appendPoint(dir, point(r.Current)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// point takes the score of a record
+func point(r *Record) *Point {
+	sum := sha256.Sum256([]byte(strings.Join(r.Prototype, "\n")))
+	return &Point{
+		Time:      r.Saved,
+		Name:      r.Name,
+		Filter:    r.Filter,
+		LinkType:  r.LinkType,
+		Reference: r.Reference,
+		Tool:      r.Tool,
+		Score:     r.Score,
+		Verdict:   r.Verdict,
+		Program:   hex.EncodeToString(sum[:8]),
+	}
+}
+
+// historyPath returns a filter's history file, named like its records but
+// kept across reference versions, which a trend shows as changes
+func historyPath(dir string, p *Point) string {
+	sum := sha256.Sum256([]byte(p.LinkType + "\x00" + p.Filter))
+	return filepath.Join(dir, historyDir, hex.EncodeToString(sum[:8])+".jsonl")
+}
+
+// appendPoint adds one line to a history file
+func appendPoint(dir string, p *Point) error {
+	path := historyPath(dir, p)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return f.Close()
+}
+
+// History reads the trend of every filter with a history in dir, leaving
+// out runs before since unless it is zero, sorted by name
+func History(dir string, since time.Time) ([]*Trend, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, historyDir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var trends []*Trend
+	for _, path := range paths {
+		points, err := readHistory(path)
+		if err != nil {
+			return nil, err
+		}
+		kept := points[:0]
+		for _, p := range points {
+			if since.IsZero() || !p.Time.Before(since) {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].Time.Before(kept[j].Time) })
+		last := kept[len(kept)-1]
+		trends = append(trends, &Trend{Name: last.Name, Filter: last.Filter, LinkType: last.LinkType, Points: kept})
+	}
+	sort.Slice(trends, func(i, j int) bool { return trends[i].Name < trends[j].Name })
+	return trends, nil
+}
+
+// readHistory reads the points of one history file
+func readHistory(path string) ([]*Point, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	var points []*Point
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var p Point
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			return nil, fmt.Errorf("failed to parse history %s:%d: %w", path, line, err)
+		}
+		points = append(points, &p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	return points, nil
+}
+
+// Changes lists the runs whose score or prototype program differs from
+// the run before, with what changed between them. Reruns of the same
+// programs with the same score are left out.
+func (t *Trend) Changes() []*Change {
+	var changes []*Change
+	for i := 1; i < len(t.Points); i++ {
+		from, to := t.Points[i-1], t.Points[i]
+		prototype, reference := from.Program != to.Program, from.Reference != to.Reference
+		var cause string
+		switch {
+		case prototype && reference:
+			cause = CauseBoth
+		case prototype:
+			cause = CausePrototype
+		case reference:
+			cause = CauseReference
+		case from.Score != to.Score:
+			cause = CauseValidator
+		default:
+			continue
+		}
+		changes = append(changes, &Change{From: from, To: to, Cause: cause})
+	}
+	return changes
+}
+
+// Regression returns the change that introduced a drop in the score the
+// filter still has, when the prototype program changed with it, or nil.
+// Drops that only follow a new reference compiler are not the generator's.
+func (t *Trend) Regression() *Change {
+	changes := t.Changes()
+	latest := t.Points[len(t.Points)-1].Score
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		if c.Regressed() && c.From.Score > latest {
+			if c.Cause == CausePrototype || c.Cause == CauseBoth {
+				return c
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// sparkBlocks draw scores from 0 to 1
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws the scores of the last width runs, one block per run
+func (t *Trend) Sparkline(width int) string {
+	points := t.Points
+	if len(points) > width {
+		points = points[len(points)-width:]
+	}
+	var sb strings.Builder
+	for _, p := range points {
+		i := int(p.Score * float64(len(sparkBlocks)))
+		sb.WriteRune(sparkBlocks[max(0, min(i, len(sparkBlocks)-1))])
+	}
+	return sb.String()
+}
+
+// TrendReport formats trends as a table of the first and latest score
+// with a sparkline of the runs between, each filter followed by its score
+// changes, oldest first; a generator regression the filter has not
+// recovered from is marked
+func TrendReport(trends []*Trend) string {
+	var sb strings.Builder
+	regressed := 0
+	for _, t := range trends {
+		first, last := t.Points[0], t.Points[len(t.Points)-1]
+		status := ""
+		if t.Regression() != nil {
+			status = StatusRegressed
+			regressed++
+		}
+		sb.WriteString(fmt.Sprintf("%-9s %.2f -> %.2f  %-20s %3d runs  %s\n",
+			status, first.Score, last.Score, t.Sparkline(20), len(t.Points), t.Name))
+		for _, c := range t.Changes() {
+			sb.WriteString(fmt.Sprintf("          %s  %s\n", c.To.Time.UTC().Format("2006-01-02 15:04"), c))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\n%d of %d filters regressed by a prototype change\n", regressed, len(trends)))
+	return sb.String()
+}
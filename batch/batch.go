@@ -0,0 +1,206 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// DefaultMinScore is the lowest comparison score that passes, matching the
+// EXCELLENT verdict band
+const DefaultMinScore = 0.8
+
+// Entry is one filter of a batch file. The filter fields are inline, so a
+// batch file is a plain list of filters with optional names.
+type Entry struct {
+	Name                string  `yaml:"name"`
+	MinScore            float64 `yaml:"min-score"` // 0 means the run's default
+	Line                int     `yaml:"-"`         // line of the entry in its batch file
+	filter.PacketFilter `yaml:",inline"`
+}
+
+// Result is the outcome of comparing one entry
+type Result struct {
+	Entry    *Entry
+	Score    float64
+	MinScore float64
+	Verdict  string
+	Details  []string // differences reported by the comparison
+	Failure  error    // why the comparison did not pass the gate (nil if it did)
+	Err      error
+
+	Comparison *compare.ComparisonResult // nil if Err is set
+}
+
+// Passed reports whether the entry was compared and passed the gate
+func (r *Result) Passed() bool {
+	return r.Err == nil && r.Failure == nil
+}
+
+// Load reads a YAML or JSON list of filters
+func Load(path string) ([]*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+
+	var doc yaml.Node
+	var entries []*Entry
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse batch file %s: %w", path, err)
+	}
+	if len(doc.Content) > 0 {
+		if err := doc.Content[0].Decode(&entries); err != nil {
+			return nil, fmt.Errorf("failed to parse batch file %s: %w", path, err)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("batch file %s contains no filters", path)
+	}
+
+	for i, e := range entries {
+		e.Line = doc.Content[0].Content[i].Line
+		if err := e.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+		}
+		if e.Name == "" {
+			e.Name = e.ToTcpdumpFilter()
+		}
+	}
+	return entries, nil
+}
+
+// Run compares every entry using up to jobs goroutines and checks each
+// comparison against the gate; an entry's own min-score replaces the
+// gate's. Results are in entry order.
+func Run(entries []*Entry, jobs int, gate compare.Gate) []*Result {
+	return RunWithOptions(entries, jobs, gate, compare.Options{})
+}
+
+// RunWithOptions is Run with comparison options, such as a score policy
+// allowing intended differences
+func RunWithOptions(entries []*Entry, jobs int, gate compare.Gate, opts compare.Options) []*Result {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make([]*Result, len(entries))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = runEntry(entries[i], gate, opts)
+			}
+		}()
+	}
+	for i := range entries {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// runEntry generates and compares both programs for one entry
+func runEntry(e *Entry, gate compare.Gate, opts compare.Options) *Result {
+	if e.MinScore > 0 {
+		gate.MinScore = e.MinScore
+	}
+	result := &Result{Entry: e, MinScore: gate.MinScore}
+
+	tcpdumpBPF, err := tcpdump.GenerateBPF(context.Background(), &e.PacketFilter)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to generate tcpdump BPF: %w", err)
+		return result
+	}
+	prototypeBPF, err := bpfgen.GenerateBPF(&e.PacketFilter)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to generate prototype BPF: %w", err)
+		return result
+	}
+
+	comparison := compare.CompareWithOptions(tcpdumpBPF, prototypeBPF, opts)
+	result.Comparison = comparison
+	result.Score = comparison.Score
+	result.Verdict = comparison.Verdict
+	result.Failure = gate.Check(comparison)
+	for _, d := range comparison.MissingInPrototype {
+		result.Details = append(result.Details, "missing: "+d)
+	}
+	for _, d := range comparison.ExtraInPrototype {
+		result.Details = append(result.Details, "extra: "+d)
+	}
+	for _, d := range comparison.Differences {
+		result.Details = append(result.Details, "difference: "+d)
+	}
+	for _, d := range comparison.StructuralDiffs {
+		result.Details = append(result.Details, "structure: "+d)
+	}
+	for _, d := range comparison.OffsetMismatches {
+		result.Details = append(result.Details, "offset: "+d)
+	}
+	for _, d := range comparison.VerifierFailures {
+		result.Details = append(result.Details, "verifier: "+d)
+	}
+	for _, d := range comparison.Allowed {
+		result.Details = append(result.Details, "allowed: "+d)
+	}
+	return result
+}
+
+// Report renders a summary table, details for failed entries and the
+// aggregate pass rate
+func Report(results []*Result) string {
+	var sb strings.Builder
+	passed := 0
+
+	sb.WriteString(fmt.Sprintf("%-6s %-5s  %-40s %s\n", "STATUS", "SCORE", "FILTER", "VERDICT"))
+	for _, r := range results {
+		status := "FAIL"
+		if r.Passed() {
+			status = "PASS"
+			passed++
+		}
+		verdict := r.Verdict
+		if r.Err != nil {
+			verdict = "error"
+		}
+		sb.WriteString(fmt.Sprintf("%-6s %5.2f  %-40s %s\n", status, r.Score, r.Entry.Name, verdict))
+	}
+
+	for _, r := range results {
+		if r.Passed() {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n--- %s ---\n", r.Entry.Name))
+		sb.WriteString(fmt.Sprintf("Filter: %s\n", r.Entry.ToTcpdumpFilter()))
+		if r.Err != nil {
+			sb.WriteString(fmt.Sprintf("error: %v\n", r.Err))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%v\n", r.Failure))
+		for _, d := range r.Details {
+			sb.WriteString(fmt.Sprintf("  - %s\n", d))
+		}
+	}
+
+	rate := 0.0
+	if len(results) > 0 {
+		rate = 100 * float64(passed) / float64(len(results))
+	}
+	sb.WriteString(fmt.Sprintf("\n%d/%d filters passed (%.1f%%)\n", passed, len(results), rate))
+	return sb.String()
+}
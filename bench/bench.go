@@ -0,0 +1,168 @@
+// Package bench measures what programs cost to run over a packet trace.
+// Instruction counts say how large a program is, not how much of it a
+// packet executes: a program that rejects early runs few instructions for
+// most traffic however long its accepting path. Path lengths, the
+// instructions executed per packet, show that difference.
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// barWidth is the length of the longest histogram bar
+const barWidth = 40
+
+// Profile is the path lengths of one program over a trace
+type Profile struct {
+	Program      string
+	Instructions int // length of the program
+	Packets      int // packets the program ran without error
+	Errors       int // packets the program failed on
+	Accepted     int
+
+	// Paths counts packets by the instructions executed for them
+	Paths map[int]int
+
+	// Executed sums the instructions executed, over all packets and over
+	// the accepted ones
+	Executed, ExecutedAccepted int
+
+	Elapsed time.Duration // interpreter time for the whole trace
+}
+
+// Run runs every packet through the program in the interpreter
+func Run(program string, prog []*bpf.Instruction, packets [][]byte) *Profile {
+	p := &Profile{Program: program, Instructions: len(prog), Paths: make(map[int]int)}
+	start := time.Now()
+	for _, pkt := range packets {
+		result, err := vm.Run(prog, pkt)
+		if err != nil {
+			p.Errors++
+			continue
+		}
+		p.Packets++
+		p.Paths[result.Executed]++
+		p.Executed += result.Executed
+		if result.Accepted {
+			p.Accepted++
+			p.ExecutedAccepted += result.Executed
+		}
+	}
+	p.Elapsed = time.Since(start)
+	return p
+}
+
+// Mean is the average path length, or 0 without packets
+func (p *Profile) Mean() float64 {
+	return mean(p.Executed, p.Packets)
+}
+
+// MeanAccepted is the average path length of accepted packets
+func (p *Profile) MeanAccepted() float64 {
+	return mean(p.ExecutedAccepted, p.Accepted)
+}
+
+// MeanRejected is the average path length of rejected packets, where a
+// program that fails fast saves the most
+func (p *Profile) MeanRejected() float64 {
+	return mean(p.Executed-p.ExecutedAccepted, p.Packets-p.Accepted)
+}
+
+func mean(total, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
+
+// Percentile returns the smallest path length at least a fraction q of
+// the packets do not exceed
+func (p *Profile) Percentile(q float64) int {
+	lengths := p.lengths()
+	seen := 0
+	for _, n := range lengths {
+		seen += p.Paths[n]
+		if float64(seen) >= q*float64(p.Packets) {
+			return n
+		}
+	}
+	return 0
+}
+
+// lengths returns the path lengths taken, shortest first
+func (p *Profile) lengths() []int {
+	lengths := make([]int, 0, len(p.Paths))
+	for n := range p.Paths {
+		lengths = append(lengths, n)
+	}
+	sort.Ints(lengths)
+	return lengths
+}
+
+// Histogram draws the packets of each path length as a bar
+func (p *Profile) Histogram() string {
+	most := 0
+	for _, count := range p.Paths {
+		most = max(most, count)
+	}
+	var sb strings.Builder
+	for _, n := range p.lengths() {
+		count := p.Paths[n]
+		bar := strings.Repeat("#", max(1, count*barWidth/most))
+		sb.WriteString(fmt.Sprintf("%4d insns %8d  %-*s %5.1f%%\n", n, count, barWidth, bar, 100*float64(count)/float64(p.Packets)))
+	}
+	return sb.String()
+}
+
+// Report sets the profiles side by side, with the histogram of each and,
+// for two programs, how much shorter the second one's paths are
+func Report(profiles []*Profile) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-10s %6s %8s %8s %8s %8s %4s %4s %4s %8s\n",
+		"PROGRAM", "INSNS", "PACKETS", "MEAN", "ACCEPTED", "REJECTED", "P50", "P90", "MAX", "NS/PKT"))
+	for _, p := range profiles {
+		perPacket := 0.0
+		if n := p.Packets + p.Errors; n > 0 {
+			perPacket = float64(p.Elapsed.Nanoseconds()) / float64(n)
+		}
+		sb.WriteString(fmt.Sprintf("%-10s %6d %8d %8.2f %8.2f %8.2f %4d %4d %4d %8.0f\n",
+			p.Program, p.Instructions, p.Packets, p.Mean(), p.MeanAccepted(), p.MeanRejected(),
+			p.Percentile(0.5), p.Percentile(0.9), p.Percentile(1), perPacket))
+	}
+	for _, p := range profiles {
+		if p.Errors > 0 {
+			sb.WriteString(fmt.Sprintf("%s failed on %d packets\n", p.Program, p.Errors))
+		}
+	}
+	for _, p := range profiles {
+		if p.Packets > 0 {
+			sb.WriteString(fmt.Sprintf("\n%s path lengths:\n%s", p.Program, p.Histogram()))
+		}
+	}
+	if len(profiles) == 2 && profiles[0].Packets > 0 {
+		a, b := profiles[0], profiles[1]
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("Mean %s path against %s: %s (rejected packets: %s, accepted: %s)\n",
+			b.Program, a.Program, change(a.Mean(), b.Mean()), change(a.MeanRejected(), b.MeanRejected()), change(a.MeanAccepted(), b.MeanAccepted())))
+	}
+	return sb.String()
+}
+
+// change describes how much shorter or longer after is than before
+func change(before, after float64) string {
+	switch {
+	case before == after:
+		return "the same"
+	case before == 0:
+		return "longer"
+	case after < before:
+		return fmt.Sprintf("%.1f%% shorter", 100*(before-after)/before)
+	}
+	return fmt.Sprintf("%.1f%% longer", 100*(after-before)/before)
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/agent"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "agent",
+		Summary: "Compare the filter a live Antrea agent attached with the prototype's",
+		Run:     runAgent,
+	})
+}
+
+// runAgent reads the filters of the agent's packet sockets on a node and
+// compares each with the prototype's program for the filter or CRD
+func runAgent(args []string) error {
+	fs := newFlagSet("agent", "[--pod NAMESPACE/NAME [--container NAME] | --ssh HOST] [--process NAME] [--socket PID:FD] [--output report|table|json|yaml] (--list | --from-crd FILE [--pod-ip NS/NAME=IP ...] | filter flags)")
+	pod := fs.String("pod", "", "Read the filters in the agent pod NAMESPACE/NAME through kubectl exec")
+	container := fs.String("container", "", "Container of --pod to run ss in (default: the pod's default container)")
+	ssh := fs.String("ssh", "", "Read the filters on the node [USER@]HOST through ssh")
+	process := fs.String("process", agent.DefaultProcess, "Only read sockets of processes with this name (\"\" for any)")
+	socket := fs.String("socket", "", "Only compare the socket PID:FD, as --list shows it")
+	list := fs.Bool("list", false, "List the filtered sockets and their programs instead of comparing them")
+	outputName := addOutputFlag(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	output, err := parseOutput(*outputName)
+	if err != nil {
+		return err
+	}
+	access := agent.Access{Pod: *pod, Container: *container, SSH: *ssh}
+	if err := access.Validate(); err != nil {
+		return err
+	}
+	filterGiven := ff.criteriaGiven() || *ff.expr != "" || *ff.fromCRD != "" || *ff.file != ""
+	if *list && filterGiven {
+		return fmt.Errorf("--list shows the agent's programs and takes no filter")
+	}
+	if !*list && !filterGiven {
+		return fmt.Errorf("give the PacketCapture the agent captures for with --from-crd, or its filter with filter flags or --expr")
+	}
+
+	sockets, err := agent.Extract(context.Background(), access, *process)
+	if err != nil {
+		return err
+	}
+	if *socket != "" {
+		if sockets, err = selectSocket(sockets, *socket); err != nil {
+			return err
+		}
+	}
+	// A summary leaves stdout to itself
+	printf := fmt.Printf
+	if output != "" && !*list {
+		printf = func(string, ...any) (int, error) { return 0, nil }
+	}
+	printf("Read %d filtered packet socket(s) on %s\n", len(sockets), access)
+
+	if *list {
+		for _, s := range sockets {
+			fmt.Printf("\n=== %s ===\n%s", s, bpf.Disassemble(s.Filter))
+		}
+		return nil
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+	printf("Parsed filter: %s\n", f.String())
+	prototypeBPF, err := bpfgen.GenerateBPF(f)
+	if err != nil {
+		return fmt.Errorf("failed to generate prototype BPF: %v", err)
+	}
+
+	disagreeing := 0
+	var checks []compare.Check
+	for _, s := range sockets {
+		agentBPF := tcpdump.ProgramFromFile(s.String(), s.Filter, string(f.LinkType))
+		agentBPF.Source = agent.Source
+		comparison := compare.Compare(agentBPF, prototypeBPF)
+		if comparison.Behavior.Disagreements > 0 {
+			disagreeing++
+		}
+		if output != "" {
+			checks = append(checks, comparison.Check(fmt.Sprintf("%s/%d:%d", s.Process, s.PID, s.FD)))
+			continue
+		}
+		fmt.Printf("\n=== %s ===\n", s)
+		if err := renderComparison(comparison); err != nil {
+			return err
+		}
+	}
+	if output != "" {
+		if err := compare.WriteChecks(os.Stdout, output, checks); err != nil {
+			return err
+		}
+	}
+
+	if disagreeing > 0 {
+		printf("\nFAIL: %d of %d agent filter(s) decide probe packets differently from the prototype\n", disagreeing, len(sockets))
+		return errFailed
+	}
+	printf("\nEvery agent filter decides the probe packets as the prototype does\n")
+	return nil
+}
+
+// selectSocket keeps the socket named PID:FD
+func selectSocket(sockets []*agent.Socket, name string) ([]*agent.Socket, error) {
+	pidText, fdText, found := strings.Cut(name, ":")
+	pid, pidErr := strconv.Atoi(pidText)
+	fd, fdErr := strconv.Atoi(fdText)
+	if !found || pidErr != nil || fdErr != nil {
+		return nil, fmt.Errorf("invalid socket '%s', want PID:FD", name)
+	}
+	for _, s := range sockets {
+		if s.PID == pid && s.FD == fd {
+			return []*agent.Socket{s}, nil
+		}
+	}
+	return nil, fmt.Errorf("no filtered socket with pid %d and fd %d", pid, fd)
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/baseline"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/batch"
+)
+
+// defaultBaselineDir is the local baseline store
+const defaultBaselineDir = ".baseline"
+
+func init() {
+	register(&Command{
+		Name:    "baseline",
+		Summary: "Save or check programs and scores of a filter list against a local baseline",
+		Run:     runBaseline,
+	})
+}
+
+// runBaseline records the outcome of every filter of a batch file, or
+// checks it against the records and fails on regressions. Either way the
+// scores are added to the history the trend command lists.
+func runBaseline(args []string) error {
+	fs := newFlagSet("baseline", "save|check [--dir DIR] [--strict] <batch file>")
+	dir := fs.String("dir", defaultBaselineDir, "Baseline store directory")
+	strict := fs.Bool("strict", false, "With check, also fail when a prototype program changed without its score dropping")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 || (positional[0] != "save" && positional[0] != "check") {
+		fs.Usage()
+		return fmt.Errorf("expected save or check and one batch file")
+	}
+	action, path := positional[0], positional[1]
+
+	entries, err := batch.Load(path)
+	if err != nil {
+		return err
+	}
+
+	var results []*baseline.Result
+	if action == "save" {
+		results = baseline.Save(*dir, entries)
+	} else {
+		results = baseline.Check(*dir, entries)
+	}
+	fmt.Printf("=== Baseline %s: %s (%s) ===\n%s", action, path, *dir, baseline.Report(results))
+	if err := baseline.AppendHistory(*dir, results); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if !r.Passed() || (*strict && r.Drifted()) {
+			return errFailed
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/bench"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/fuzz"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "bench",
+		Summary: "Compare the instructions the programs execute per packet over a trace",
+		Run:     runBench,
+	})
+}
+
+// runBench runs a packet trace through the programs and reports their path
+// lengths, which show what a program costs better than its length does
+func runBench(args []string) error {
+	fs := newFlagSet("bench", "[--pcap FILE | --packets N [--seed N]] [--program both] [--reference NAME] [--fragments POLICY] [--snaplen N] [filter flags]")
+	pcapPath := fs.String("pcap", "", "Pcap file with packets of the --link-type to run")
+	count := fs.Int("packets", 100000, "Packets of a synthetic trace aimed at the filter, without --pcap")
+	seed := fs.Int64("seed", 0, "Random seed of the synthetic trace (0 picks one from the clock)")
+	program := fs.String("program", "both", "Program to run (prototype, reference, both)")
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	referenceName := addReferenceFlag(fs, false)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return fmt.Errorf("--packets must be at least 1")
+	}
+
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	// The reference comes first, so the summary reads as the prototype's gain
+	var programs []namedProgram
+	if *program == "reference" || *program == "both" {
+		tcpdumpBPF, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Compiler: compiler, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+		}
+		programs = append(programs, namedProgram{name: "reference", prog: tcpdumpBPF.Instructions})
+	}
+	if *program == "prototype" || *program == "both" {
+		prototypeBPF, err := bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Fragments: policy, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate prototype BPF: %v", err)
+		}
+		programs = append(programs, namedProgram{name: "prototype", prog: prototypeBPF.Instructions})
+	}
+	if len(programs) == 0 {
+		return fmt.Errorf("invalid --program '%s', must be prototype, reference, or both", *program)
+	}
+
+	var packets [][]byte
+	if *pcapPath != "" {
+		reader, err := pcap.Open(*pcapPath)
+		if err != nil {
+			return err
+		}
+		records, err := reader.ReadAll()
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			packets = append(packets, r.Data)
+		}
+		fmt.Printf("Trace: %d packets from %s\n", len(packets), *pcapPath)
+	} else {
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		packets = fuzz.Packets(f, rand.New(rand.NewSource(*seed)), *count)
+		fmt.Printf("Trace: %d synthetic packets aimed at '%s' with seed %d\n", len(packets), f.ToTcpdumpFilter(), *seed)
+	}
+
+	var profiles []*bench.Profile
+	for _, p := range programs {
+		profiles = append(profiles, bench.Run(p.name, p.prog, packets))
+	}
+	fmt.Printf("\n=== Path Lengths ===\n%s", bench.Report(profiles))
+	return nil
+}
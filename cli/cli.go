@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/lifecycle"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// Command is a single CLI subcommand
+type Command struct {
+	Name    string                    // name used on the command line
+	Summary string                    // one-line description shown in usage
+	Run     func(args []string) error // executes the command with its own flags
+}
+
+// errFailed signals a command that already reported its failure and only
+// needs a non-zero exit status
+var errFailed = errors.New("command failed")
+
+// programName is how usage and hints spell the command line
+var programName = "go run main.go"
+
+// noColor disables colored reports even on a terminal (--no-color)
+var noColor bool
+
+// commands holds every registered subcommand keyed by name
+var commands = map[string]*Command{}
+
+// register adds a subcommand to the dispatcher
+func register(cmd *Command) {
+	commands[cmd.Name] = cmd
+}
+
+// Run dispatches the arguments to a subcommand and returns the exit status
+func Run(args []string) int {
+	settings, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	current = settings
+	args, err = extractGlobalFlags(append(settings.globalArgs(), args...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if lifecycle.Enabled() {
+		defer lifecycle.Report(os.Stderr)
+	}
+
+	if len(args) == 0 {
+		usage()
+		return 1
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return 0
+	}
+
+	if strings.HasPrefix(name, "-") {
+		name, args = translateLegacy(args)
+		if name == "" {
+			usage()
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Warning: invoking without a command is deprecated and will be removed.\n")
+		fmt.Fprintf(os.Stderr, "Use the equivalent command instead:\n  %s %s %s\n\n", programName, name, strings.Join(args, " "))
+	} else {
+		args = args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n", name)
+		fmt.Fprintf(os.Stderr, "Use --help for usage information\n")
+		return 1
+	}
+
+	if err := cmd.Run(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if !errors.Is(err, errFailed) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// RunPlugin runs the arguments as the kubectl-antrea-bpf-check plugin,
+// which kubectl invokes as "kubectl antrea bpf check". Commands that
+// summarize comparisons print tables by default, as antctl does.
+func RunPlugin(args []string) int {
+	programName = "kubectl antrea bpf check"
+	defaultOutput = string(compare.OutputTable)
+	return Run(args)
+}
+
+// extractGlobalFlags removes flags accepted before or after any command
+// and applies them
+func extractGlobalFlags(args []string) ([]string, error) {
+	level := slog.LevelInfo
+	fixtureMode, fixtureDir := "", tcpdump.DefaultFixtureDir
+	cacheDir, _ := tcpdump.DefaultCacheDir()
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-debug-leaks" || arg == "--debug-leaks":
+			lifecycle.Enable()
+		case arg == "-v":
+			level = slog.LevelDebug
+		case arg == "-q":
+			level = slog.LevelError
+		case arg == "-no-color" || arg == "--no-color":
+			noColor = true
+		case arg == "-container-fallback" || arg == "--container-fallback":
+			tcpdump.ContainerFallback = true
+		case isValueFlag(arg, "tcpdump-timeout") || isValueFlag(arg, "container-image") ||
+			isValueFlag(arg, "fixtures") || isValueFlag(arg, "fixture-dir") || isValueFlag(arg, "reference-cache"):
+			name, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if !ok {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("%s requires a value", arg)
+				}
+				i++
+				value = args[i]
+			}
+			switch name {
+			case "container-image":
+				tcpdump.Container.Image = value
+			case "fixtures":
+				fixtureMode = value
+			case "fixture-dir":
+				fixtureDir = value
+			case "reference-cache":
+				cacheDir = value
+			default:
+				if err := setTcpdumpTimeout(value); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	logging.SetLogger(slog.New(logging.NewHandler(os.Stderr, level)))
+
+	// Without a user cache directory there is no default cache
+	if cacheDir != "" && cacheDir != "off" {
+		tcpdump.Cache = &tcpdump.ReferenceCache{Dir: cacheDir}
+	}
+
+	switch fixtureMode {
+	case "":
+	case "record", "replay":
+		tcpdump.Fixtures = &tcpdump.FixtureStore{Dir: fixtureDir, Record: fixtureMode == "record"}
+	default:
+		return nil, fmt.Errorf("invalid --fixtures '%s', must be record or replay", fixtureMode)
+	}
+	return rest, nil
+}
+
+// isValueFlag reports whether arg is the flag name, with one or two
+// dashes, either alone or as name=value
+func isValueFlag(arg, name string) bool {
+	trimmed := strings.TrimLeft(arg, "-")
+	return len(arg)-len(trimmed) <= 2 && (trimmed == name || strings.HasPrefix(trimmed, name+"="))
+}
+
+// setTcpdumpTimeout bounds every tcpdump run, such as "10s"
+func setTcpdumpTimeout(s string) error {
+	timeout, err := time.ParseDuration(s)
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid --tcpdump-timeout '%s', must be a positive duration such as 10s", s)
+	}
+	tcpdump.DefaultTimeout = timeout
+	return nil
+}
+
+// translateLegacy maps the pre-subcommand flat flag invocation onto the
+// equivalent command. An empty name means only --help was requested.
+func translateLegacy(args []string) (string, []string) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		trimmed := strings.TrimLeft(arg, "-")
+		switch {
+		case trimmed == "help" || trimmed == "h":
+			return "", nil
+		case trimmed == "test-file" && i+1 < len(args):
+			return "test", []string{args[i+1]}
+		case strings.HasPrefix(trimmed, "test-file="):
+			return "test", []string{strings.TrimPrefix(trimmed, "test-file=")}
+		}
+		rest = append(rest, arg)
+	}
+	return "compare", rest
+}
+
+// usage prints the top-level help listing every command
+func usage() {
+	fmt.Fprintf(os.Stderr, "Antrea BPF Prototype - Packet Filter Validation\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\n", programName)
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].Summary)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  %s compare --protocol tcp --dst-port 80\n", programName)
+	fmt.Fprintf(os.Stderr, "  %s compare --protocol udp --src-ip 192.168.1.1 --dst-port 53\n", programName)
+	fmt.Fprintf(os.Stderr, "  %s generate --protocol tcp --dst-port 80\n", programName)
+	fmt.Fprintf(os.Stderr, "  %s simulate --protocol tcp --dst-port 80 --pcap capture.pcap\n", programName)
+	fmt.Fprintf(os.Stderr, "  %s test testcases/basic.yaml\n", programName)
+	fmt.Fprintf(os.Stderr, "\nGlobal flags:\n")
+	fmt.Fprintf(os.Stderr, "  --debug-leaks  Report unclosed resources and goroutine growth on exit\n")
+	fmt.Fprintf(os.Stderr, "  -v             Log generation progress (debug level) to stderr\n")
+	fmt.Fprintf(os.Stderr, "  -q             Log errors only, hiding warnings such as fallbacks\n")
+	fmt.Fprintf(os.Stderr, "  --no-color     Never color reports (also set by NO_COLOR); they are colored on a terminal\n")
+	fmt.Fprintf(os.Stderr, "  --tcpdump-timeout D  Kill tcpdump runs after D (default %v)\n", tcpdump.DefaultTimeout)
+	fmt.Fprintf(os.Stderr, "  --container-fallback  Without tcpdump, compile references in a Docker/Podman container instead of the mock\n")
+	fmt.Fprintf(os.Stderr, "  --container-image I   Image for container references (default %s)\n", tcpdump.DefaultContainerImage)
+	fmt.Fprintf(os.Stderr, "  --fixtures record|replay  Record real reference programs, or replay them without tcpdump\n")
+	fmt.Fprintf(os.Stderr, "  --fixture-dir DIR     Fixture directory (default %s)\n", tcpdump.DefaultFixtureDir)
+	fmt.Fprintf(os.Stderr, "  --reference-cache DIR|off  Cache tcpdump, dumpcap and container programs in DIR (default under the user cache directory)\n")
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> --help' for command flags.\n", programName)
+}
+
+// renderComparison writes the comparison report to stdout, in color when
+// stdout is a terminal and neither --no-color nor NO_COLOR is set
+func renderComparison(c *compare.ComparisonResult) error {
+	if colorEnabled() {
+		return c.RenderColor(os.Stdout)
+	}
+	return c.Render(os.Stdout)
+}
+
+// colorEnabled reports whether stdout is a terminal that wants color. Any
+// non-empty NO_COLOR disables it, as https://no-color.org asks.
+func colorEnabled() bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
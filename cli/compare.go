@@ -0,0 +1,458 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/batch"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "compare",
+		Summary: "Generate tcpdump and prototype BPF and compare them",
+		Run:     runCompare,
+	})
+}
+
+// runCompare generates both programs for a filter and displays the comparison
+func runCompare(args []string) error {
+	fs := newFlagSet("compare", "[--plain|--quiet|--output table|json|yaml] [--vocabulary FILE] [--score-policy FILE] [--left FILE] [--right FILE] [--partial] [-O0|-O1|-O2] [--fragments POLICY] [--snaplen N] [--max-instructions N] [--reference-opt MODE] [--reference LIST] [--tcpdump-format F] [--dot PREFIX] [--sarif FILE] [--min-score S] [--fail-on LIST] [filter flags] | --batch FILE [--jobs N] [--output table|json|yaml] [--score-policy FILE] [--sarif FILE] [--min-score S] [--fail-on LIST]")
+	plain := fs.Bool("plain", false, "Write the comparison as ASCII key=value lines instead of the boxed report")
+	quiet := fs.Bool("quiet", false, "Write only the verdict and score lines of the gated comparison")
+	outputName := addOutputFlag(fs)
+	vocabPath := fs.String("vocabulary", "", "YAML file overriding verdict and report wording")
+	policyPath := fs.String("score-policy", "", "YAML file overriding score weights and verdict bands, and allowing intended differences")
+	partial := fs.Bool("partial", false, "Generate the prototype for the supported subset of the filter")
+	batchPath := fs.String("batch", "", "Compare every filter in a YAML/JSON list concurrently")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "Concurrent comparisons for --batch")
+	minScore := fs.Float64("min-score", batch.DefaultMinScore, "Lowest passing score; exit non-zero below it (single comparisons only check it when given)")
+	failOn := fs.String("fail-on", "", "Also exit non-zero on these findings, comma-separated (missing-critical, any-diff)")
+	dotPrefix := fs.String("dot", "", "Also write both control flow graphs to PREFIX.reference.dot and PREFIX.prototype.dot")
+	sarifPath := fs.String("sarif", "", "Also write the findings to FILE as a SARIF log for code review annotations")
+	pcapOut := fs.String("pcap-out", "", "Also write the probe packets the programs decide differently to FILE, to open in Wireshark or replay")
+	dotHighlight := fs.Bool("dot-highlight", true, "Highlight blocks whose checks differ between the programs in --dot output")
+	referenceOpt := fs.String("reference-opt", "optimized", "Reference compilation: optimized, unoptimized (tcpdump -O) or both")
+	referenceNames := addReferenceFlag(fs, true)
+	formatName := fs.String("tcpdump-format", "ddd", "Dump format to request from tcpdump (d, dd or ddd), for builds whose -ddd output differs")
+	leftPath := fs.String("left", "", "Use the program in FILE as the reference instead of compiling the filter")
+	rightPath := fs.String("right", "", "Use the program in FILE as the prototype instead of generating it")
+	leftFormat := fs.String("left-format", "auto", "Format of --left: d, dd, ddd, json, bin (little-endian struct sock_filter) or auto to detect")
+	rightFormat := fs.String("right-format", "auto", "Format of --right: d, dd, ddd, json, bin (little-endian struct sock_filter) or auto to detect")
+	of := addOptFlags(fs)
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	budget := addBudgetFlag(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+
+	conditions, err := compare.ParseFailOn(*failOn)
+	if err != nil {
+		return err
+	}
+	gate := compare.Gate{MinScore: *minScore, FailOn: conditions}
+	output, err := parseOutput(*outputName)
+	if err != nil {
+		return err
+	}
+	level, err := of.level()
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+	if err := checkBudget(*budget); err != nil {
+		return err
+	}
+	references, err := parseReferenceOpt(*referenceOpt)
+	if err != nil {
+		return err
+	}
+	compilers, err := tcpdump.ParseReferences(*referenceNames)
+	if err != nil {
+		return err
+	}
+	if len(compilers) > 1 && len(references) > 1 {
+		return fmt.Errorf("--reference-opt both applies to a single --reference compiler")
+	}
+	format, err := tcpdump.ParseOutputFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	for i := range references {
+		references[i].Format = format
+		references[i].Snaplen = *snaplen
+	}
+	if len(compilers) > 1 {
+		base := references[0]
+		references = nil
+		for _, c := range compilers {
+			opts := base
+			opts.Compiler = c
+			references = append(references, opts)
+		}
+	} else {
+		for i := range references {
+			references[i].Compiler = compilers[0]
+		}
+	}
+
+	if *batchPath != "" {
+		if *leftPath != "" || *rightPath != "" {
+			return fmt.Errorf("--left and --right apply to single comparisons, not --batch")
+		}
+		if of.given() || policy != bpfgen.FragmentsMatchFirst || *snaplen != 0 || *budget != 0 || changed(fs, "reference-opt") || changed(fs, "reference") {
+			return fmt.Errorf("optimization levels, --fragments, --snaplen, --max-instructions, --reference-opt and --reference apply to single comparisons, not --batch")
+		}
+		if *pcapOut != "" {
+			return fmt.Errorf("--pcap-out applies to single comparisons, not --batch, whose filters may differ in link type")
+		}
+		if *plain || *quiet {
+			return fmt.Errorf("--plain and --quiet apply to single comparisons, not --batch, whose table is already plain text")
+		}
+		opts := compare.Options{}
+		if *policyPath != "" {
+			if opts.Policy, err = compare.LoadScorePolicy(*policyPath); err != nil {
+				return err
+			}
+		}
+		return runBatch(*batchPath, *jobs, gate, opts, *sarifPath, output)
+	}
+
+	if *plain && *quiet || (*plain || *quiet) && output != "" {
+		return fmt.Errorf("--plain, --quiet and --output are mutually exclusive")
+	}
+	// Quiet output leaves only the verdict and score on stdout, and
+	// --output only its summary
+	printf := fmt.Printf
+	if *quiet || output != "" {
+		printf = func(string, ...any) (int, error) { return 0, nil }
+	}
+
+	// A single comparison has no score bar unless one is asked for
+	if !given(fs, "min-score") {
+		gate.MinScore = 0
+	}
+
+	opts := compare.Options{}
+	if *vocabPath != "" {
+		vocab, err := compare.LoadVocabulary(*vocabPath)
+		if err != nil {
+			return err
+		}
+		opts.Vocabulary = vocab
+	}
+	if *policyPath != "" {
+		policy, err := compare.LoadScorePolicy(*policyPath)
+		if err != nil {
+			return err
+		}
+		opts.Policy = policy
+	}
+
+	if *leftPath != "" && (*referenceOpt == "both" || changed(fs, "reference")) {
+		return fmt.Errorf("--reference-opt and --reference apply to a compiled reference, not --left")
+	}
+	if *rightPath != "" && (of.given() || *partial || policy != bpfgen.FragmentsMatchFirst || *budget != 0) {
+		return fmt.Errorf("optimization levels, --partial, --fragments and --max-instructions apply to a generated prototype, not --right")
+	}
+
+	// Two program files are compared as they are, with no filter at all
+	var f *filter.PacketFilter
+	var linkType string
+	if *leftPath != "" && *rightPath != "" {
+		if ff.criteriaGiven() || *ff.expr != "" || *ff.fromCRD != "" {
+			return fmt.Errorf("filter flags do not apply when comparing --left with --right; only --link-type does")
+		}
+		if *snaplen != 0 {
+			return fmt.Errorf("--snaplen applies to compiled and generated programs, not --left with --right")
+		}
+		link, err := filter.ParseLinkType(*ff.linkType)
+		if err != nil {
+			return err
+		}
+		linkType = string(link)
+	} else {
+		if f, err = ff.build(); err != nil {
+			return err
+		}
+		linkType = string(f.LinkType)
+		printf("Parsed filter: %s\n\n", f.String())
+	}
+
+	var prototypeBPF *bpfgen.BPFCode
+	if *rightPath != "" {
+		instructions, err := loadProgramFile(*rightPath, *rightFormat)
+		if err != nil {
+			return err
+		}
+		prototypeBPF = &bpfgen.BPFCode{
+			Code:      tcpdump.ProgramFromFile(*rightPath, instructions, linkType).Code,
+			Reasoning: fmt.Sprintf("Loaded from %s", *rightPath),
+		}
+	}
+	// Compare the prototype with each selected reference compilation
+	var comparisons []*compare.ComparisonResult
+	for _, ref := range references {
+		var tcpdumpBPF *tcpdump.BPFCode
+		if *leftPath != "" {
+			instructions, err := loadProgramFile(*leftPath, *leftFormat)
+			if err != nil {
+				return err
+			}
+			tcpdumpBPF = tcpdump.ProgramFromFile(*leftPath, instructions, linkType)
+		} else if tcpdumpBPF, err = tcpdump.GenerateBPFWithOptions(context.Background(), f, ref); err != nil {
+			return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+		}
+
+		printf("\n%s\n", tcpdumpBPF.String())
+
+		// Generate prototype Antrea-style BPF once, unless it was loaded
+		if prototypeBPF == nil {
+			prototypeBPF, err = bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Partial: *partial, OptLevel: level, Fragments: policy, Snaplen: *snaplen, MaxInstructions: *budget})
+			if err != nil {
+				return fmt.Errorf("failed to generate prototype BPF: %v", err)
+			}
+		}
+		if len(comparisons) == 0 {
+			printf("\n%s\n", prototypeBPF.String())
+		}
+
+		// Compare the results
+		comparison := compare.CompareWithOptions(tcpdumpBPF, prototypeBPF, opts)
+		switch {
+		case output != "":
+		case *plain:
+			printf("\n")
+			err = comparison.RenderPlain(os.Stdout)
+		case *quiet:
+			if len(comparisons) == 0 {
+				err = comparison.RenderQuiet(os.Stdout)
+			}
+		default:
+			err = renderComparison(comparison)
+		}
+		if err != nil {
+			return err
+		}
+		comparisons = append(comparisons, comparison)
+	}
+
+	// The first comparison is the one exported and gated
+	comparison := comparisons[0]
+	switch {
+	case *quiet || output != "":
+	case len(compilers) > 1:
+		printReferenceCrossCheck(comparisons)
+	case len(comparisons) == 2:
+		printOptimizationSplit(level, comparisons[0], comparisons[1])
+	}
+
+	if *dotPrefix != "" {
+		reference, prototype := comparison.DOT(*dotHighlight)
+		for _, out := range []struct{ suffix, graph string }{{"reference", reference}, {"prototype", prototype}} {
+			path := fmt.Sprintf("%s.%s.dot", *dotPrefix, out.suffix)
+			if err := os.WriteFile(path, []byte(out.graph), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %v", path, err)
+			}
+			printf("Wrote %s\n", path)
+		}
+	}
+
+	if *sarifPath != "" {
+		target := compare.SARIFTarget{Result: comparison}
+		switch {
+		case f != nil:
+			target.Name = f.ToTcpdumpFilter()
+			target.URI = *ff.fromCRD
+		default:
+			target.Name = *rightPath
+		}
+		if err := writeSARIF(*sarifPath, []compare.SARIFTarget{target}); err != nil {
+			return err
+		}
+		printf("Wrote %s\n", *sarifPath)
+	}
+
+	if *pcapOut != "" {
+		packets := comparison.Behavior.Disagreeing
+		if err := pcap.WriteFile(*pcapOut, filter.LinkType(comparison.TcpdumpBPF.LinkType), packets); err != nil {
+			return err
+		}
+		printf("Wrote %s (%d packets)\n", *pcapOut, len(packets))
+		if f != nil {
+			printf("Wireshark display filter: %s\n", f.ToDisplayFilter())
+		}
+	}
+
+	if output != "" {
+		name := *rightPath
+		if f != nil {
+			name = f.ToTcpdumpFilter()
+		}
+		var checks []compare.Check
+		for _, c := range comparisons {
+			checks = append(checks, c.Check(name))
+		}
+		if err := compare.WriteChecks(os.Stdout, output, checks); err != nil {
+			return err
+		}
+	}
+
+	// A summary on stdout stays parseable, so the failure goes to stderr
+	failures := os.Stdout
+	if output != "" {
+		failures = os.Stderr
+	}
+	for _, c := range comparisons {
+		if err := gate.Check(c); err != nil {
+			fmt.Fprintf(failures, "\nFAIL: %v\n", err)
+			return errFailed
+		}
+	}
+	return nil
+}
+
+// loadProgramFile reads a program in any format tcpdump.LoadProgram
+// accepts, such as one dumped from a running Antrea agent
+func loadProgramFile(path, formatName string) ([]*bpf.Instruction, error) {
+	format, err := tcpdump.ParseProgramFormat(formatName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read program: %v", err)
+	}
+	instructions, _, err := tcpdump.LoadProgram(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return instructions, nil
+}
+
+// printOptimizationSplit sets the scores against the optimized and the
+// unoptimized reference side by side. Differences that survive in both are
+// semantic; a score that changes with the reference's optimizer points at
+// optimization strategy.
+func printOptimizationSplit(level bpfgen.OptLevel, optimized, unoptimized *compare.ComparisonResult) {
+	fmt.Printf("\nPrototype %s vs reference: %.2f optimized, %.2f unoptimized (%d vs %d instructions)\n",
+		level, optimized.Score, unoptimized.Score,
+		optimized.TcpdumpBPF.InstructionCount, unoptimized.TcpdumpBPF.InstructionCount)
+	switch {
+	case bpf.FormatDDD(optimized.TcpdumpBPF.Instructions) == bpf.FormatDDD(unoptimized.TcpdumpBPF.Instructions):
+		fmt.Println("Both reference compilations are the same program, so they cannot separate semantics from optimization; compare -O0 with -O2 instead")
+	case optimized.Score == unoptimized.Score && optimized.Score == 1:
+		fmt.Println("No differences with either reference")
+	case optimized.Score == unoptimized.Score:
+		fmt.Println("The scores agree, so the differences are semantic rather than optimization strategy")
+	default:
+		fmt.Println("The scores differ, so part of the difference comes from optimization strategy")
+	}
+}
+
+// printReferenceCrossCheck sets the references of several compilers side
+// by side. Compilers built on different libpcap releases may emit
+// different code for the same filter; when they do, a difference with only
+// one of them is less likely to be the prototype's fault.
+func printReferenceCrossCheck(comparisons []*compare.ComparisonResult) {
+	fmt.Printf("\n=== Reference Cross-Check ===\n")
+	first := comparisons[0].TcpdumpBPF
+	identical := true
+	for _, c := range comparisons {
+		ref := c.TcpdumpBPF
+		same := bpf.FormatDDD(ref.Instructions) == bpf.FormatDDD(first.Instructions)
+		identical = identical && same
+		note := ""
+		if !same {
+			note = fmt.Sprintf(" (differs from %s)", first.Source)
+		}
+		fmt.Printf("  %-8s %2d instructions, prototype score %.2f%s\n", ref.Source, ref.InstructionCount, c.Score, note)
+	}
+	if identical {
+		fmt.Println("All references compiled the same program")
+	} else {
+		fmt.Println("The references disagree; compare their programs above before blaming the prototype")
+	}
+}
+
+// runBatch compares every filter of a batch file and prints the summary,
+// writing the findings to sarifPath unless it is empty
+func runBatch(path string, jobs int, gate compare.Gate, opts compare.Options, sarifPath string, output compare.Output) error {
+	entries, err := batch.Load(path)
+	if err != nil {
+		return err
+	}
+
+	results := batch.RunWithOptions(entries, jobs, gate, opts)
+	if output == "" {
+		fmt.Printf("=== Batch Results: %s ===\n%s", path, batch.Report(results))
+	} else {
+		checks := make([]compare.Check, 0, len(results))
+		for _, r := range results {
+			if r.Err != nil {
+				checks = append(checks, compare.Check{Name: r.Entry.Name, Error: r.Err.Error()})
+				continue
+			}
+			checks = append(checks, r.Comparison.Check(r.Entry.Name))
+		}
+		if err := compare.WriteChecks(os.Stdout, output, checks); err != nil {
+			return err
+		}
+	}
+
+	if sarifPath != "" {
+		var targets []compare.SARIFTarget
+		for _, r := range results {
+			targets = append(targets, compare.SARIFTarget{
+				Name:   r.Entry.Name,
+				URI:    filepath.ToSlash(path),
+				Line:   r.Entry.Line,
+				Result: r.Comparison,
+				Err:    r.Err,
+			})
+		}
+		if err := writeSARIF(sarifPath, targets); err != nil {
+			return err
+		}
+		if output == "" {
+			fmt.Printf("Wrote %s\n", sarifPath)
+		}
+	}
+
+	for _, r := range results {
+		if !r.Passed() {
+			return errFailed
+		}
+	}
+	return nil
+}
+
+// writeSARIF writes the findings of the comparisons to a SARIF file
+func writeSARIF(path string, targets []compare.SARIFTarget) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := compare.WriteSARIF(out, targets); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "compile",
+		Summary: "Assemble a hand-written BPF program, optionally comparing it to the prototype",
+		Run:     runCompile,
+	})
+}
+
+// runCompile assembles mnemonic text, or reads a tcpdump -d, -dd or -ddd
+// dump, and prints it as -ddd and disassembly. When filter flags are given,
+// the program is used as the reference and compared against the prototype
+// output for that filter.
+func runCompile(args []string) error {
+	fs := newFlagSet("compile", "<program> [--format auto|d|dd|ddd] [filter flags to compare against the prototype]")
+	formatName := fs.String("format", "auto", "Program format: d (tcpdump -d or bpf_asm mnemonics), dd, ddd, or auto to detect")
+	ff := addFilterFlags(fs)
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one program file is required")
+	}
+
+	path := files[0]
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read program: %v", err)
+	}
+
+	format := tcpdump.DetectFormat(string(text))
+	if *formatName != "auto" {
+		if format, err = tcpdump.ParseOutputFormat(*formatName); err != nil {
+			return err
+		}
+	}
+	instructions, err := tcpdump.ParseOutput(string(text), format)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	fmt.Printf("=== Assembled %s (%d instructions) ===\n", path, len(instructions))
+	fmt.Printf("%s\n", bpf.Disassemble(instructions))
+	fmt.Printf("tcpdump -ddd format:\n%s", bpf.FormatDDD(instructions))
+
+	filterGiven := false
+	fs.Visit(func(fl *flag.Flag) { filterGiven = true })
+	if !filterGiven {
+		return nil
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	prototypeBPF, err := bpfgen.GenerateBPF(f)
+	if err != nil {
+		return fmt.Errorf("failed to generate prototype BPF: %v", err)
+	}
+
+	reference := &tcpdump.BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       path,
+			InstructionCount: len(instructions),
+			LinkType:         prototypeBPF.LinkType,
+		},
+		RawOutput: bpf.FormatDDD(instructions),
+	}
+
+	fmt.Printf("\n")
+	comparison := compare.Compare(reference, prototypeBPF)
+	return renderComparison(comparison)
+}
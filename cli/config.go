@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings give flags new defaults, so that a user does not retype the
+// same reference compiler or output format on every command. They come
+// from ~/.antrea-bpf.yaml, or the file ANTREA_BPF_CONFIG names ("off" for
+// none), and from ANTREA_BPF_<NAME> environment variables, which win over
+// the file. A flag on the command line wins over both.
+//
+//	reference: libpcap
+//	link-type: LINUX_SLL
+//	commands:
+//	  compare:
+//	    min-score: 0.95
+
+// configEnv names the settings file, or turns it off
+const configEnv = "ANTREA_BPF_CONFIG"
+
+// envPrefix starts the environment variable of each setting
+const envPrefix = "ANTREA_BPF_"
+
+// commandSettings are defaults for the flags of the same name, on every
+// command that has the flag
+var commandSettings = []string{"emit", "jobs", "link-type", "min-score", "output", "reference", "score-policy"}
+
+// globalSettings are defaults for the flags accepted with any command
+var globalSettings = []string{"container-image", "no-color", "reference-cache", "tcpdump-timeout"}
+
+// settings holds the configured values by flag name
+type settings struct {
+	path     string                       // settings file read, if any
+	file     map[string]string            // top level of the file
+	commands map[string]map[string]string // command sections of the file
+	env      map[string]string            // environment variables
+}
+
+// settingsFile is the YAML layout of the settings file
+type settingsFile struct {
+	Values   map[string]string            `yaml:",inline"`
+	Commands map[string]map[string]string `yaml:"commands"`
+}
+
+// current is the settings of the run, loaded by Run
+var current = &settings{}
+
+// configured records the flags of each flag set whose default a setting
+// replaced
+var configured = map[*flag.FlagSet]map[string]bool{}
+
+// loadSettings reads the settings file, if any, and the environment. A
+// missing ~/.antrea-bpf.yaml is no error; a missing ANTREA_BPF_CONFIG file
+// is.
+func loadSettings() (*settings, error) {
+	s := &settings{env: map[string]string{}}
+	for _, name := range append(append([]string{}, commandSettings...), globalSettings...) {
+		if value, ok := os.LookupEnv(envVar(name)); ok {
+			s.env[name] = value
+		}
+	}
+
+	path := os.Getenv(configEnv)
+	if path == "off" {
+		return s, nil
+	}
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return s, nil
+		}
+		path = filepath.Join(home, ".antrea-bpf.yaml")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var file settingsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file %s: %w", path, err)
+	}
+	for name := range file.Values {
+		if !isSetting(name, commandSettings) && !isSetting(name, globalSettings) {
+			return nil, fmt.Errorf("%s: unknown setting %q; settings are %s", path, name, settingNames())
+		}
+	}
+	for command, values := range file.Commands {
+		if _, ok := commands[command]; !ok {
+			return nil, fmt.Errorf("%s: unknown command %q under commands", path, command)
+		}
+		for name := range values {
+			if !isSetting(name, commandSettings) {
+				return nil, fmt.Errorf("%s: unknown setting %q for %s; command settings are %s", path, name, command, strings.Join(commandSettings, ", "))
+			}
+		}
+	}
+	s.path, s.file, s.commands = path, file.Values, file.Commands
+	return s, nil
+}
+
+// envVar returns the environment variable of a setting, such as
+// ANTREA_BPF_LINK_TYPE
+func envVar(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// isSetting reports whether name is one of the settings
+func isSetting(name string, names []string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// settingNames lists every setting, for errors
+func settingNames() string {
+	names := append(append([]string{}, commandSettings...), globalSettings...)
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// lookup returns a setting for the command, and where it comes from. The
+// environment wins over the file's command section, which wins over the
+// file's top level.
+func (s *settings) lookup(command, name string) (value, source string, ok bool) {
+	if value, ok := s.env[name]; ok {
+		return value, envVar(name), true
+	}
+	if value, ok := s.commands[command][name]; ok {
+		return value, fmt.Sprintf("%s (commands.%s)", s.path, command), true
+	}
+	if value, ok := s.file[name]; ok {
+		return value, s.path, true
+	}
+	return "", "", false
+}
+
+// globalArgs returns the global settings as flags, to go before the
+// command line so that its own flags win
+func (s *settings) globalArgs() []string {
+	var args []string
+	for _, name := range globalSettings {
+		value, _, ok := s.lookup("", name)
+		switch {
+		case !ok:
+		case name == "no-color":
+			if value == "true" || value == "1" {
+				args = append(args, "--no-color")
+			}
+		default:
+			args = append(args, "--"+name+"="+value)
+		}
+	}
+	return args
+}
+
+// apply makes the settings the defaults of the command's flags
+func (s *settings) apply(fs *flag.FlagSet) error {
+	for _, name := range commandSettings {
+		fl := fs.Lookup(name)
+		value, source, ok := s.lookup(fs.Name(), name)
+		if fl == nil || !ok {
+			continue
+		}
+		if err := fl.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s setting %q from %s: %v", name, value, source, err)
+		}
+		fl.DefValue = value
+		if configured[fs] == nil {
+			configured[fs] = map[string]bool{}
+		}
+		configured[fs][name] = true
+	}
+	return nil
+}
+
+// parseFlags applies the settings to a command's flags and parses its
+// arguments
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := current.apply(fs); err != nil {
+		return err
+	}
+	return fs.Parse(args)
+}
+
+// given reports whether a flag was set on the command line or by a
+// setting
+func given(fs *flag.FlagSet, name string) bool {
+	found := configured[fs][name]
+	fs.Visit(func(fl *flag.Flag) { found = found || fl.Name == name })
+	return found
+}
+
+// changed reports whether the command line set a flag to other than its
+// default, which a setting may have replaced
+func changed(fs *flag.FlagSet, name string) bool {
+	fl := fs.Lookup(name)
+	return fl != nil && fl.Value.String() != fl.DefValue
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/prove"
+)
+
+func init() {
+	register(&Command{
+		Name:    "decompile",
+		Summary: "Reconstruct a filter expression from a BPF program, such as one taken from a running agent",
+		Run:     runDecompile,
+	})
+}
+
+// runDecompile reads a program back as a filter expression and, when the
+// program fits a filter, proves whether the reference or the prototype
+// program for that filter accepts the same packets. It exits non-zero
+// unless one of them does.
+func runDecompile(args []string) error {
+	fs := newFlagSet("decompile", "<program> [--format auto|d|dd|ddd|json|bin] [--link-type TYPE] [--reference NAME] [--fragments POLICY]")
+	formatName := fs.String("format", "auto", "Program format: d, dd, ddd, json, bin or auto to detect")
+	linkType := fs.String("link-type", "", "Capture link type the program was compiled for (EN10MB, LINUX_SLL, RAW, NULL; default EN10MB)")
+	referenceName := addReferenceFlag(fs, false)
+	fragments := addFragmentsFlag(fs)
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one program file is required")
+	}
+
+	link, err := filter.ParseLinkType(*linkType)
+	if err != nil {
+		return err
+	}
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	instructions, err := loadProgramFile(files[0], *formatName)
+	if err != nil {
+		return err
+	}
+
+	d := compare.Decompile(instructions, link)
+	fmt.Printf("Program: %s (%d instructions, %d accepting paths)\n", files[0], len(instructions), d.Paths)
+	switch {
+	case d.Paths == 0:
+		fmt.Printf("Expression: (accepts no packets)\n")
+	case d.Expression == "":
+		fmt.Printf("Expression: (accepts every packet)\n")
+	default:
+		fmt.Printf("Expression: %s\n", d.Expression)
+	}
+	if d.Truncated {
+		fmt.Printf("Only the first %d accepting paths were followed\n", compare.MaxDecompilePaths)
+	}
+	if len(d.Untranslated) > 0 {
+		fmt.Printf("Untranslated (left out of the expression):\n")
+		for _, u := range d.Untranslated {
+			fmt.Printf("  %s\n", u)
+		}
+	}
+	if d.Filter == nil {
+		fmt.Printf("Filter: none (%s)\n", d.Reason)
+		fmt.Printf("Result: PARTIAL\n")
+		return errFailed
+	}
+	fmt.Printf("Filter: %s\n", d.Filter.ToTcpdumpFilter())
+
+	// The filter is a guess, so check it against the program both ways
+	candidates := []struct {
+		name     string
+		generate func() ([]*bpf.Instruction, error)
+	}{
+		{"reference", func() ([]*bpf.Instruction, error) {
+			code, err := tcpdump.GenerateBPFWithOptions(context.Background(), d.Filter, tcpdump.Options{Compiler: compiler})
+			if err != nil {
+				return nil, err
+			}
+			return code.Instructions, nil
+		}},
+		{"prototype", func() ([]*bpf.Instruction, error) {
+			code, err := bpfgen.GenerateBPFWithOptions(d.Filter, bpfgen.Options{Fragments: policy})
+			if err != nil {
+				return nil, err
+			}
+			return code.Instructions, nil
+		}},
+	}
+	var last *prove.Proof
+	for _, c := range candidates {
+		program, err := c.generate()
+		if err != nil {
+			fmt.Printf("The %s program for the filter: %v\n", c.name, err)
+			continue
+		}
+		proof, err := prove.Prove(instructions, program, link)
+		if err != nil {
+			return err
+		}
+		if proof.Equivalent {
+			fmt.Printf("Result: EXACT (the %s program for the filter accepts exactly the same packets)\n", c.name)
+			return nil
+		}
+		fmt.Printf("The %s program for the filter differs on %.4g%% of packet contents\n", c.name, 100*proof.Fraction)
+		last = proof
+	}
+
+	fmt.Printf("Result: APPROXIMATE\n")
+	if last != nil {
+		accepts, rejects := "program", "filter"
+		if !last.FirstAccepts {
+			accepts, rejects = rejects, accepts
+		}
+		fmt.Printf("The %s accepts and the %s rejects packets with:\n", accepts, rejects)
+		for _, c := range last.Region {
+			fmt.Printf("  %s\n", c)
+		}
+		fmt.Printf("Witness: %x\n", last.Witness)
+	}
+	return errFailed
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "disassemble",
+		Summary: "Print generated programs as tcpdump -d style mnemonics",
+		Run:     runDisassemble,
+	})
+}
+
+// runDisassemble prints the disassembly of the requested programs
+func runDisassemble(args []string) error {
+	fs := newFlagSet("disassemble", "[--program both] [filter flags]")
+	program := fs.String("program", "both", "Program to disassemble (prototype, reference, both)")
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *program != "prototype" && *program != "reference" && *program != "both" {
+		return fmt.Errorf("invalid --program '%s', must be prototype, reference, or both", *program)
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	if *program == "reference" || *program == "both" {
+		tcpdumpBPF, err := tcpdump.GenerateBPF(context.Background(), f)
+		if err != nil {
+			return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+		}
+		fmt.Printf("\n=== Reference (%s) ===\n%s", tcpdumpBPF.FilterExpr, bpf.Disassemble(tcpdumpBPF.Instructions))
+	}
+
+	if *program == "prototype" || *program == "both" {
+		prototypeBPF, err := bpfgen.GenerateBPF(f)
+		if err != nil {
+			return fmt.Errorf("failed to generate prototype BPF: %v", err)
+		}
+		// Comments name the filter clause each run of instructions implements
+		fmt.Printf("\n=== Prototype (%s) ===\n%s", prototypeBPF.FilterExpr, prototypeBPF.Disassembly())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// emitFlags select how a generated program is written out
+type emitFlags struct {
+	format   *string
+	pkg      *string
+	variable *string
+	out      *string
+}
+
+// addEmitFlags registers the output flags on a command's flag set
+func addEmitFlags(fs *flag.FlagSet) *emitFlags {
+	return &emitFlags{
+		format:   fs.String("emit", "text", "Output format: text, go (golang.org/x/net/bpf literals), c-array, ddd, json or raw (struct sock_filter bytes)"),
+		pkg:      fs.String("package", "", "Package clause for --emit go (empty emits a paste-ready fragment)"),
+		variable: fs.String("var", "filter", "Variable name for --emit go and c-array"),
+		out:      fs.String("o", "", "Write the program to FILE instead of stdout"),
+	}
+}
+
+// validate rejects unknown formats before any generation work is done
+func (ef *emitFlags) validate() error {
+	switch *ef.format {
+	case "text", "go", "c-array", "ddd", "json", "raw":
+		return nil
+	}
+	return fmt.Errorf("unknown --emit format '%s' (want text, go, c-array, ddd, json or raw)", *ef.format)
+}
+
+// write renders the program in the selected format. text is the
+// generator's own description of the program. raw is the little-endian
+// struct sock_filter array a struct sock_fprog points to, as read back by
+// compare --left/--right.
+func (ef *emitFlags) write(code *bpf.Code, text string) error {
+	var output string
+	switch *ef.format {
+	case "go":
+		snippet, err := bpf.GoSnippet(code.Instructions, bpf.GoSnippetOptions{
+			Package:  *ef.pkg,
+			Variable: *ef.variable,
+			Filter:   code.FilterExpr,
+		})
+		if err != nil {
+			return err
+		}
+		output = snippet
+	case "c-array":
+		source, err := bpf.CArray(code.Instructions, bpf.CArrayOptions{
+			Variable: *ef.variable,
+			Filter:   code.FilterExpr,
+		})
+		if err != nil {
+			return err
+		}
+		output = source
+	case "json":
+		output = bpf.FormatJSON(code.Instructions)
+	case "raw":
+		output = string(bpf.EncodeBinary(code.Instructions, binary.LittleEndian))
+	case "ddd":
+		output = bpf.FormatDDD(code.Instructions)
+	default:
+		output = text
+	}
+
+	if *ef.out == "" {
+		if *ef.format == "raw" {
+			// Binary goes to stdout unseparated, ready for a pipe
+			_, err := os.Stdout.WriteString(output)
+			return err
+		}
+		fmt.Printf("\n%s", output)
+		return nil
+	}
+	if err := os.WriteFile(*ef.out, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", *ef.out, err)
+	}
+	fmt.Printf("Wrote %s\n", *ef.out)
+	return nil
+}
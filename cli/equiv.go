@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+func init() {
+	register(&Command{
+		Name:    "equiv",
+		Summary: "Decide whether two filter expressions match the same or nested packet sets",
+		Run:     runEquiv,
+	})
+}
+
+// runEquiv parses two tcpdump expressions and reports whether they are
+// equal, one subsumes the other, or neither, with a packet only one of
+// them matches for each direction that fails. Like cmp, it exits non-zero
+// unless the filters are equal.
+func runEquiv(args []string) error {
+	fs := newFlagSet("equiv", "[--link-type TYPE] <expression A> <expression B>")
+	linkType := fs.String("link-type", "", "Capture link type of both filters (EN10MB, LINUX_SLL, RAW, NULL; default EN10MB)")
+	exprs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 2 {
+		fs.Usage()
+		return fmt.Errorf("exactly two expressions are required")
+	}
+
+	var filters [2]*filter.PacketFilter
+	for i, expr := range exprs {
+		f, err := filter.ParseExpr(expr)
+		if err != nil {
+			return fmt.Errorf("expression %c: %v", 'A'+i, err)
+		}
+		f.LinkType = filter.LinkType(*linkType)
+		filters[i] = f
+	}
+	a, b := filters[0], filters[1]
+
+	// onlyA is matched by A but not B, and onlyB the other way round
+	onlyB, err := filter.Witness(a, b)
+	if err != nil {
+		return err
+	}
+	onlyA, err := filter.Witness(b, a)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("A: %s\n", a.ToTcpdumpFilter())
+	fmt.Printf("B: %s\n", b.ToTcpdumpFilter())
+	switch {
+	case onlyA == nil && onlyB == nil:
+		fmt.Printf("Result: EQUAL (A and B match the same packets)\n")
+		return nil
+	case onlyB == nil:
+		fmt.Printf("Result: A SUBSUMES B (A matches every packet B matches)\n")
+	case onlyA == nil:
+		fmt.Printf("Result: B SUBSUMES A (B matches every packet A matches)\n")
+	default:
+		fmt.Printf("Result: NEITHER (each matches packets the other does not)\n")
+	}
+	if onlyA != nil {
+		fmt.Printf("Only A matches: %s\n", onlyA)
+	}
+	if onlyB != nil {
+		fmt.Printf("Only B matches: %s\n", onlyB)
+	}
+	return errFailed
+}
@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/k8s"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// filterFlags binds the packet filter flags shared by several commands
+type filterFlags struct {
+	protocol  *string
+	etherType *int
+	srcIP     *string
+	dstIP     *string
+	hostIP    *string
+	port      *int
+	srcPorts  portList
+	dstPorts  portList
+	between   *string
+	vlan      *bool
+	vlanID    *int
+	tunnel    *string
+	vni       *int
+	inner     *bool
+	linkType  *string
+	expr      *string
+	fromCRD   *string
+	podIPs    stringList
+	file      *string
+	name      *string
+	fs        *flag.FlagSet
+}
+
+// addFilterFlags registers the filter flags on a command's flag set
+func addFilterFlags(fs *flag.FlagSet) *filterFlags {
+	ff := &filterFlags{
+		protocol:  fs.String("protocol", "", "Protocol (tcp, udp, icmp, arp, rarp, or an IPv4 protocol number such as 47)"),
+		etherType: fs.Int("ether-type", 0, "Match frames of this EtherType, e.g. 0x88cc, instead of IP packets"),
+		srcIP:     fs.String("src-ip", "", "Source IP address or CIDR"),
+		dstIP:     fs.String("dst-ip", "", "Destination IP address or CIDR"),
+		hostIP:    fs.String("host", "", "Source or destination IP address or CIDR"),
+		port:      fs.Int("port", 0, "Source or destination port"),
+		between:   fs.String("between", "", "Any IP traffic between two networks, as \"A_CIDR,B_CIDR\" or --between A_CIDR B_CIDR"),
+		vlan:      fs.Bool("assume-vlan", false, "Match 802.1Q-tagged frames, reading headers after the tag"),
+		vlanID:    fs.Int("vlan-id", 0, "Match this VLAN ID (implies --assume-vlan)"),
+		tunnel:    fs.String("tunnel", "", "Match VXLAN or Geneve encapsulated traffic (vxlan, geneve)"),
+		vni:       fs.Int("vni", 0, "Match this VXLAN or Geneve network identifier (requires --tunnel)"),
+		inner:     fs.Bool("inner", false, "Apply the IP, protocol and port criteria to the encapsulated packet (requires --tunnel)"),
+		linkType:  fs.String("link-type", "", "Capture link type (EN10MB, LINUX_SLL, RAW, NULL; default EN10MB)"),
+		expr:      fs.String("expr", "", "Read the filter from a tcpdump expression, e.g. \"tcp and dst port 80\""),
+		fromCRD:   fs.String("from-crd", "", "Read the filter from an Antrea PacketCapture YAML file"),
+		file:      fs.String("filter-file", "", "Read the filter from a YAML or JSON file of one or more filters, keyed like batch entries"),
+		name:      fs.String("filter-name", "", "Name of the filter to use from a --filter-file with several"),
+		fs:        fs,
+	}
+	fs.Var(&ff.srcPorts, "src-port", "Source port, or a comma-separated list matching any of them")
+	fs.Var(&ff.dstPorts, "dst-port", "Destination port, or a comma-separated list matching any of them")
+	fs.Var(&ff.podIPs, "pod-ip", "Pod IP for --from-crd, as namespace/name=IP (repeatable)")
+	return ff
+}
+
+// build creates and validates the filter described by the flags
+func (ff *filterFlags) build() (*filter.PacketFilter, error) {
+	sources := 0
+	for _, given := range []bool{*ff.expr != "", *ff.fromCRD != "", *ff.file != ""} {
+		if given {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("--expr, --from-crd and --filter-file cannot be combined")
+	}
+	if *ff.name != "" && *ff.file == "" {
+		return nil, fmt.Errorf("--filter-name selects a filter of a --filter-file")
+	}
+	if *ff.expr != "" {
+		return ff.buildFromExpr()
+	}
+	if *ff.fromCRD != "" {
+		return ff.buildFromCRD()
+	}
+	if *ff.file != "" {
+		return ff.buildFromFile()
+	}
+	f := &filter.PacketFilter{
+		Protocol:  *ff.protocol,
+		EtherType: *ff.etherType,
+		SrcIP:     *ff.srcIP,
+		DstIP:     *ff.dstIP,
+		HostIP:    *ff.hostIP,
+		Port:      *ff.port,
+		SrcPorts:  ff.srcPorts,
+		DstPorts:  ff.dstPorts,
+		VLAN:      *ff.vlan,
+		VLANID:    *ff.vlanID,
+		Tunnel:    filter.Tunnel(*ff.tunnel),
+		VNI:       *ff.vni,
+		Inner:     *ff.inner,
+		LinkType:  filter.LinkType(*ff.linkType),
+	}
+
+	if *ff.between != "" {
+		f.Between = ff.betweenNetworks()
+	}
+
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("%v\nUse --help for usage information", err)
+	}
+	return f, nil
+}
+
+// parse parses a command's flags. --between may also take its two
+// networks as separate arguments, "--between A B", like the pair of
+// tcpdump clauses it expands to; any other argument is an error.
+func (ff *filterFlags) parse(fs *flag.FlagSet, args []string) error {
+	if err := current.apply(fs); err != nil {
+		return err
+	}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return nil
+		}
+		if *ff.between == "" || len(ff.betweenNetworks()) != 1 {
+			return fmt.Errorf("unexpected argument '%s'\nUse --help for usage information", fs.Arg(0))
+		}
+		*ff.between += "," + fs.Arg(0)
+		args = fs.Args()[1:]
+	}
+}
+
+// betweenNetworks splits the --between value at commas and spaces
+func (ff *filterFlags) betweenNetworks() []string {
+	return strings.FieldsFunc(*ff.between, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// newFlagSet creates a flag set whose usage lists the command's flags
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n\nFlags:\n", programName, name, usageLine)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// stringList is a repeatable string flag
+type stringList []string
+
+// String returns the values joined by commas
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set appends a value each time the flag is given
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// portList is a port flag accepting a comma-separated list of ports
+type portList []int
+
+// String returns the ports joined by commas
+func (p *portList) String() string {
+	s := make([]string, len(*p))
+	for i, port := range *p {
+		s[i] = strconv.Itoa(port)
+	}
+	return strings.Join(s, ",")
+}
+
+// Set parses the list, replacing any earlier value
+func (p *portList) Set(value string) error {
+	var ports portList
+	for _, field := range strings.Split(value, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return fmt.Errorf("invalid port '%s'", field)
+		}
+		ports = append(ports, port)
+	}
+	*p = ports
+	return nil
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments and returns the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := current.apply(fs); err != nil {
+		return nil, err
+	}
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// criteriaGiven reports whether any flag describing the filter itself, as
+// opposed to the capture, was set
+func (ff *filterFlags) criteriaGiven() bool {
+	return *ff.protocol != "" || *ff.etherType != 0 || *ff.srcIP != "" || *ff.dstIP != "" || *ff.hostIP != "" || *ff.port != 0 || len(ff.srcPorts) != 0 || len(ff.dstPorts) != 0 || *ff.between != "" || *ff.vlan || *ff.vlanID != 0 || *ff.tunnel != "" || *ff.vni != 0 || *ff.inner
+}
+
+// buildFromExpr parses the tcpdump expression given with --expr
+func (ff *filterFlags) buildFromExpr() (*filter.PacketFilter, error) {
+	if ff.criteriaGiven() {
+		return nil, fmt.Errorf("--expr cannot be combined with other filter flags")
+	}
+
+	f, err := filter.ParseExpr(*ff.expr)
+	if err != nil {
+		return nil, fmt.Errorf("--expr: %v", err)
+	}
+
+	// The link type belongs to the capture interface, not the expression
+	if *ff.linkType != "" {
+		f.LinkType = filter.LinkType(*ff.linkType)
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// buildFromFile loads the filter file named by --filter-file and picks
+// its only filter, or the one --filter-name names
+func (ff *filterFlags) buildFromFile() (*filter.PacketFilter, error) {
+	if ff.criteriaGiven() {
+		return nil, fmt.Errorf("--filter-file cannot be combined with other filter flags")
+	}
+
+	defs, err := filter.LoadFile(*ff.file)
+	if err != nil {
+		return nil, err
+	}
+	def, err := filter.Select(defs, *ff.name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v; select one with --filter-name", *ff.file, err)
+	}
+	f := &def.PacketFilter
+
+	// --link-type overrides the file's, but a configured default does not
+	if *ff.linkType != "" && (f.LinkType == "" || changed(ff.fs, "link-type")) {
+		f.LinkType = filter.LinkType(*ff.linkType)
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// buildFromCRD converts the PacketCapture named by --from-crd
+func (ff *filterFlags) buildFromCRD() (*filter.PacketFilter, error) {
+	if ff.criteriaGiven() {
+		return nil, fmt.Errorf("--from-crd cannot be combined with other filter flags")
+	}
+
+	opts := k8s.Options{PodIPs: make(map[string]string)}
+	for _, entry := range ff.podIPs {
+		pod, ip, ok := strings.Cut(entry, "=")
+		if !ok || !strings.Contains(pod, "/") {
+			return nil, fmt.Errorf("invalid --pod-ip '%s', want namespace/name=IP", entry)
+		}
+		opts.PodIPs[pod] = ip
+	}
+
+	captures, err := k8s.LoadPacketCaptures(*ff.fromCRD)
+	if err != nil {
+		return nil, err
+	}
+	if len(captures) > 1 {
+		var names []string
+		for _, pc := range captures {
+			names = append(names, pc.Metadata.Name)
+		}
+		return nil, fmt.Errorf("%s contains %d PacketCaptures (%s); keep one per file", *ff.fromCRD, len(captures), strings.Join(names, ", "))
+	}
+
+	f, err := captures[0].ToPacketFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The link type belongs to the capture interface, not the resource
+	if *ff.linkType != "" {
+		f.LinkType = filter.LinkType(*ff.linkType)
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Printf("Loaded PacketCapture %s: %s\n", captures[0].Metadata.Name, f.ToTcpdumpFilter())
+	return f, nil
+}
+
+// defaultOutput is the --output of commands that summarize comparisons;
+// the kubectl plugin prints tables unless told otherwise
+var defaultOutput = "report"
+
+// addOutputFlag adds --output and its kubectl shorthand -o, choosing
+// between the full report and the summaries antctl and kubectl print
+func addOutputFlag(fs *flag.FlagSet) *string {
+	output := fs.String("output", defaultOutput, "Print the full report, or a summary as table, json or yaml as antctl does")
+	fs.StringVar(output, "o", defaultOutput, "Shorthand for --output")
+	return output
+}
+
+// parseOutput parses an --output value; the full report is ""
+func parseOutput(s string) (compare.Output, error) {
+	if s == "report" {
+		return "", nil
+	}
+	output, err := compare.ParseOutput(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid --output '%s', must be report, table, json or yaml", s)
+	}
+	return output, nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/fuzz"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/property"
+)
+
+func init() {
+	register(&Command{
+		Name:    "fuzz",
+		Summary: "Check the prototype against the reference on random filters and packets",
+		Run:     runFuzz,
+	})
+}
+
+// runFuzz checks random cases until one fails or the iterations run out,
+// or replays a single input given with --input
+func runFuzz(args []string) error {
+	fs := newFlagSet("fuzz", "[--iterations N] [--seed N] [--input HEX] [--pcap-out FILE] [--properties]")
+	iterations := fs.Int("iterations", 1000, "Number of random filters to check")
+	seed := fs.Int64("seed", 0, "Random seed (0 picks one from the clock)")
+	inputHex := fs.String("input", "", "Replay one case from the hex input printed for a failure")
+	pcapOut := fs.String("pcap-out", "", "Write the packet of a failure to FILE, to open in Wireshark or replay")
+	properties := fs.Bool("properties", false, "Also check that the prototype's programs terminate, read within the snapshot length and accept only packets the filter selects")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *iterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+
+	if *inputHex != "" {
+		data, err := packet.ParseHex(*inputHex)
+		if err != nil {
+			return fmt.Errorf("invalid --input: %v", err)
+		}
+		return checkFuzzInput(data, *pcapOut, *properties)
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Fuzzing %d filters with seed %d\n", *iterations, *seed)
+	r := rand.New(rand.NewSource(*seed))
+	for i := 0; i < *iterations; i++ {
+		if err := checkFuzzInput(fuzz.RandomInput(r), *pcapOut, *properties); err != nil {
+			return err
+		}
+	}
+	if *properties {
+		fmt.Printf("PASS: %d filters, %d packets, no mismatches or property violations\n", *iterations, *iterations*fuzz.PacketsPerCase)
+		return nil
+	}
+	fmt.Printf("PASS: %d filters, %d packets, no mismatches\n", *iterations, *iterations*fuzz.PacketsPerCase)
+	return nil
+}
+
+// checkFuzzInput checks the case decoded from data, and with properties
+// the invariants of package property, printing the details needed to
+// reproduce a failure and writing its packet to pcapOut, unless empty
+func checkFuzzInput(data []byte, pcapOut string, properties bool) error {
+	c := fuzz.CaseFromBytes(data)
+	err := fuzz.Check(c)
+	if err == nil && properties {
+		err = property.Check(data)
+	}
+	if err == nil {
+		return nil
+	}
+
+	fmt.Printf("FAIL: %s\n", c.Filter.ToTcpdumpFilter())
+	var (
+		mismatch  *fuzz.Mismatch
+		violation *property.Violation
+		failing   []byte
+	)
+	switch {
+	case errors.As(err, &mismatch):
+		fmt.Printf("  Reference (%s) accepts: %v\n", mismatch.Source, mismatch.Reference)
+		fmt.Printf("  Prototype accepts: %v\n", mismatch.Prototype)
+		if mismatch.Spec != nil {
+			fmt.Printf("  Go predicate accepts: %v\n", *mismatch.Spec)
+		}
+		failing = mismatch.Packet
+		if len(mismatch.Packet) < len(mismatch.Original) {
+			fmt.Printf("  Minimized from %d to %d bytes\n", len(mismatch.Original), len(mismatch.Packet))
+		}
+	case errors.As(err, &violation):
+		fmt.Printf("  Property: %s\n", violation.Property)
+		fmt.Printf("  Violation: %s\n", violation.Detail)
+		failing = violation.Packet
+	}
+	if failing != nil {
+		fmt.Printf("  Packet: %s\n", packet.Decode(failing, c.Filter.LinkType))
+		fmt.Printf("  Bytes: %s\n", hex.EncodeToString(failing))
+		if pcapOut != "" {
+			if err := pcap.WriteFile(pcapOut, c.Filter.LinkType, [][]byte{failing}); err != nil {
+				return err
+			}
+			fmt.Printf("  Wrote %s\n", pcapOut)
+		}
+	} else {
+		fmt.Printf("  Error: %v\n", err)
+	}
+	fmt.Printf("Reproduce with:\n  %s fuzz --input %s\n", programName, hex.EncodeToString(data))
+	return errFailed
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ebpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ovs"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "generate",
+		Summary: "Generate prototype (Antrea-style) BPF for a filter",
+		Run:     runGenerate,
+	})
+	register(&Command{
+		Name:    "reference",
+		Summary: "Generate reference BPF for a filter using tcpdump",
+		Run:     runReference,
+	})
+}
+
+// runGenerate emits the prototype program for a filter
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate", "[--partial [--uncovered FILE]] [-O0|-O1|-O2] [--fragments POLICY] [--snaplen N | --headers-only] [--max-instructions N] [--emit text|go|c-array|ddd|json|raw] [-o FILE] [--ebpf xdp|tc] [--ovs] [--firewall] [--display-filter] [filter flags]")
+	partial := fs.Bool("partial", false, "Drop unsupported criteria instead of failing (program matches a superset)")
+	uncoveredPath := fs.String("uncovered", "", "Write the uncovered criteria as JSON to FILE (- for stdout)")
+	ebpfTarget := fs.String("ebpf", "", "Also generate the equivalent eBPF program for a hook (xdp or tc)")
+	ovsFlows := fs.Bool("ovs", false, "Also print the equivalent Open vSwitch flow matches (experimental)")
+	firewall := fs.Bool("firewall", false, "Also print the equivalent nft rules and iptables u32 matches")
+	displayFilter := fs.Bool("display-filter", false, "Also print the equivalent Wireshark display filter")
+	of := addOptFlags(fs)
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	headersOnly := addHeadersOnlyFlag(fs)
+	budget := addBudgetFlag(fs)
+	ef := addEmitFlags(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	if err := ef.validate(); err != nil {
+		return err
+	}
+	level, err := of.level()
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+	if err := checkBudget(*budget); err != nil {
+		return err
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	var target ebpf.Target
+	if *ebpfTarget != "" {
+		if target, err = ebpf.ParseTarget(*ebpfTarget); err != nil {
+			return err
+		}
+	}
+
+	prototypeBPF, err := bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Partial: *partial, OptLevel: level, Fragments: policy, Snaplen: *snaplen, HeadersOnly: *headersOnly, MaxInstructions: *budget})
+	if err != nil {
+		return fmt.Errorf("failed to generate prototype BPF: %v", err)
+	}
+
+	if err := ef.write(&prototypeBPF.Code, prototypeBPF.String()); err != nil {
+		return err
+	}
+
+	if target != "" {
+		program, err := ebpf.Generate(f, target)
+		if err != nil {
+			return fmt.Errorf("failed to generate eBPF program: %v", err)
+		}
+		fmt.Printf("\n%s", program.String())
+	}
+
+	if *ovsFlows {
+		translation, err := ovs.Translate(f)
+		if err != nil {
+			return fmt.Errorf("failed to translate to OVS flows: %v", err)
+		}
+		fmt.Printf("\n%s", translation.String())
+	}
+
+	if *firewall {
+		rules, err := bpfgen.GenerateFirewall(f, policy)
+		if err != nil {
+			return fmt.Errorf("failed to generate firewall rules: %v", err)
+		}
+		fmt.Printf("\n%s", rules.String())
+	}
+
+	if *displayFilter {
+		fmt.Printf("\nWireshark Display Filter: %s\n", f.ToDisplayFilter())
+	}
+
+	if *uncoveredPath != "" {
+		data, err := prototypeBPF.UncoveredJSON()
+		if err != nil {
+			return err
+		}
+		if *uncoveredPath == "-" {
+			fmt.Printf("%s\n", data)
+			return nil
+		}
+		if err := os.WriteFile(*uncoveredPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write uncovered criteria: %v", err)
+		}
+	}
+	return nil
+}
+
+// runReference emits the tcpdump reference program for a filter
+func runReference(args []string) error {
+	fs := newFlagSet("reference", "[--reference NAME] [--unoptimized] [--snaplen N] [--tcpdump-format d|dd|ddd] [--emit text|go|c-array|ddd|json|raw] [-o FILE] [filter flags]")
+	unoptimized := fs.Bool("unoptimized", false, "Disable libpcap's optimizer, like tcpdump -O")
+	formatName := fs.String("tcpdump-format", "ddd", "Dump format to request from tcpdump (d, dd or ddd), for builds whose -ddd output differs")
+	referenceName := addReferenceFlag(fs, false)
+	snaplen := addSnaplenFlag(fs)
+	ef := addEmitFlags(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	if err := ef.validate(); err != nil {
+		return err
+	}
+	format, err := tcpdump.ParseOutputFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	tcpdumpBPF, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Unoptimized: *unoptimized, Format: format, Compiler: compiler, Snaplen: *snaplen})
+	if err != nil {
+		return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+	}
+
+	return ef.write(&tcpdumpBPF.Code, tcpdumpBPF.String())
+}
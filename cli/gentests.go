@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "gen-tests",
+		Summary: "Write a Go test of the prototype program for a filter, with packets and the reference's verdicts",
+		Run:     runGenTests,
+	})
+}
+
+// runGenTests writes a Go test file recording the prototype program for a
+// filter and probe packets with the verdicts the reference gives them.
+// The prototype must decide every probe packet as the reference does, so
+// that the test records behavior that was validated.
+func runGenTests(args []string) error {
+	fs := newFlagSet("gen-tests", "[--package NAME] [--name NAME] [--call EXPR] [--packets N] [-o FILE] [--reference NAME] [--fragments POLICY] [--snaplen N] [filter flags]")
+	pkg := fs.String("package", "capture", "Package clause of the test file")
+	name := fs.String("name", "", "Test function name without the Test prefix (default derived from the filter)")
+	call := fs.String("call", "", "Go expression returning the []bpf.Instruction under test, e.g. \"compilePacketFilter(spec, srcIP, dstIP)\"; empty tests the recorded program")
+	count := fs.Int("packets", 24, "Most packets to record, covering every kind of packet and both verdicts first (0 records every probe)")
+	out := fs.String("o", "", "Write the test to FILE instead of stdout")
+	referenceName := addReferenceFlag(fs, false)
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	reference, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Compiler: compiler, Snaplen: *snaplen})
+	if err != nil {
+		return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+	}
+	prototype, err := bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Fragments: policy, Snaplen: *snaplen})
+	if err != nil {
+		return fmt.Errorf("failed to generate prototype BPF: %v", err)
+	}
+	comparison := compare.Compare(reference, prototype)
+	if n := comparison.Behavior.Disagreements; n > 0 {
+		return fmt.Errorf("the prototype decides %d of %d probe packets differently from the reference; run compare to see which, and fix it before recording a test",
+			n, comparison.Behavior.Packets)
+	}
+
+	var packets []bpf.GoTestPacket
+	for _, p := range comparison.Corpus(*count) {
+		packets = append(packets, bpf.GoTestPacket{Name: p.Name, Data: p.Data, Accept: p.Accept})
+	}
+	source, err := bpf.GoTest(prototype.Instructions, packets, bpf.GoTestOptions{
+		Package:   *pkg,
+		Name:      *name,
+		Filter:    f.ToTcpdumpFilter(),
+		Reference: tcpdump.SourceVersion(reference.Source),
+		Call:      *call,
+	})
+	if err != nil {
+		return err
+	}
+	if reference.Source == tcpdump.SourceMock {
+		fmt.Fprintf(os.Stderr, "Warning: the verdicts come from the mock compiler; record the test where tcpdump or libpcap is installed\n")
+	}
+
+	if *out == "" {
+		fmt.Print(source)
+		return nil
+	}
+	if err := os.WriteFile(*out, []byte(source), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", *out, err)
+	}
+	fmt.Printf("Wrote %s (%d instructions, %d packets)\n", *out, len(prototype.Instructions), len(packets))
+	return nil
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/golden"
+)
+
+// defaultGoldenDir holds the repository's golden cases
+const defaultGoldenDir = "testdata/golden"
+
+func init() {
+	register(&Command{
+		Name:    "golden",
+		Summary: "Check generated BPF against recorded golden files",
+		Run:     runGolden,
+	})
+}
+
+// runGolden checks or rewrites the golden files of a directory
+func runGolden(args []string) error {
+	fs := newFlagSet("golden", "[--update] [dir]")
+	update := fs.Bool("update", false, "Rewrite the golden files from the current generators")
+	dirs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(dirs) > 1 {
+		fs.Usage()
+		return fmt.Errorf("at most one golden directory may be given")
+	}
+	dir := defaultGoldenDir
+	if len(dirs) == 1 {
+		dir = dirs[0]
+	}
+
+	cases, err := golden.Load(dir)
+	if err != nil {
+		return err
+	}
+
+	results := golden.Run(dir, cases, *update)
+	fmt.Printf("=== Golden Files: %s ===\n%s", dir, golden.Report(results))
+
+	for _, r := range results {
+		if !r.Passed() {
+			return errFailed
+		}
+	}
+	return nil
+}
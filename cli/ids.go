@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/ids"
+)
+
+func init() {
+	register(&Command{
+		Name:    "ids",
+		Summary: "Check that Suricata and Zeek accept a filter's tcpdump expression",
+		Run:     runIDS,
+	})
+}
+
+// runIDS lints the tcpdump expression of a filter, or an expression taken
+// as is, for the IDS sensors, and fails if one of them would reject it, or
+// with --strict if one would handle it differently
+func runIDS(args []string) error {
+	fs := newFlagSet("ids", "[--sensor suricata|zeek] [--strict] (--raw EXPR | filter flags)")
+	sensorName := fs.String("sensor", "", "Check for one sensor only (suricata or zeek; default both)")
+	strict := fs.Bool("strict", false, "Also fail on warnings, for constructs a sensor compiles but may match differently")
+	raw := fs.String("raw", "", "Check this expression as is, without converting it to a filter, so constructs the filter model lacks, such as portrange, are checked too")
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+
+	var sensors []ids.Sensor
+	if *sensorName != "" {
+		sensor, err := ids.ParseSensor(*sensorName)
+		if err != nil {
+			return err
+		}
+		sensors = append(sensors, sensor)
+	}
+
+	expr := *raw
+	switch {
+	case expr != "" && ff.criteriaGiven():
+		return fmt.Errorf("--raw cannot be combined with filter flags")
+	case expr == "":
+		f, err := ff.build()
+		if err != nil {
+			return err
+		}
+		expr = f.ToTcpdumpFilter()
+	}
+
+	findings := ids.Check(expr, sensors...)
+	fmt.Printf("Expression: %s\n", expr)
+	warnings := 0
+	for _, finding := range findings {
+		fmt.Printf("  %s\n", finding)
+		if finding.Level == ids.LevelWarning {
+			warnings++
+		}
+	}
+	switch {
+	case ids.Failed(findings):
+		fmt.Printf("Result: FAIL (%d error(s), %d warning(s))\n", len(findings)-warnings, warnings)
+		return errFailed
+	case warnings > 0 && *strict:
+		fmt.Printf("Result: FAIL (%d warning(s))\n", warnings)
+		return errFailed
+	case warnings > 0:
+		fmt.Printf("Result: PASS (%d warning(s))\n", warnings)
+	default:
+		fmt.Printf("Result: PASS\n")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/fuzz"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/kernel"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ebpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "kernel",
+		Summary: "Check both programs and the interpreter against the Linux kernel on a veth pair",
+		Run:     runKernel,
+	})
+}
+
+// runKernel attaches both programs to packet sockets, sends a corpus
+// through a veth pair and reports the packets the sockets, or the
+// interpreter, decide differently
+func runKernel(args []string) error {
+	fs := newFlagSet("kernel", "[--packet HEX ... | --packet-json FIELDS ... | --pcap FILE | --packets N [--seed N]] [--reference NAME | --xdp] [filter flags]")
+	var packets stringList
+	fs.Var(&packets, "packet", "Ethernet frame as hex (repeatable)")
+	var specs stringList
+	fs.Var(&specs, "packet-json", "Packet built from header fields as JSON, as for simulate (repeatable)")
+	pcapPath := fs.String("pcap", "", "Pcap file of Ethernet frames to send")
+	count := fs.Int("packets", 256, "Without other packets, send N synthetic packets aimed at the filter")
+	seed := fs.Int64("seed", 0, "Random seed of the synthetic packets (0 picks one from the clock)")
+	referenceName := addReferenceFlag(fs, false)
+	xdp := fs.Bool("xdp", false, "Attach the prototype's eBPF program to an XDP hook in a network namespace and check the packets it matches against the interpreter")
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return fmt.Errorf("--packets must be at least 1")
+	}
+
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+	if !f.LinkType.IsEthernet() {
+		return fmt.Errorf("the kernel oracle sends Ethernet frames through a veth pair, not %s packets", f.LinkType)
+	}
+
+	var corpus [][]byte
+	for _, h := range packets {
+		data, err := packet.ParseHex(h)
+		if err != nil {
+			return err
+		}
+		corpus = append(corpus, data)
+	}
+	for _, s := range specs {
+		data, err := buildPacket(s, f.LinkType)
+		if err != nil {
+			return err
+		}
+		corpus = append(corpus, data)
+	}
+	if *pcapPath != "" {
+		reader, err := pcap.Open(*pcapPath)
+		if err != nil {
+			return err
+		}
+		records, err := reader.ReadAll()
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		if reader.LinkType != pcap.LinkTypeEthernet {
+			return fmt.Errorf("%s holds link type %d packets; the kernel oracle sends Ethernet frames", *pcapPath, reader.LinkType)
+		}
+		for _, r := range records {
+			corpus = append(corpus, r.Data)
+		}
+	}
+	if len(corpus) == 0 {
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		corpus = fuzz.Packets(f, rand.New(rand.NewSource(*seed)), *count)
+		fmt.Printf("Sending %d synthetic packets with seed %d\n", *count, *seed)
+	}
+
+	prototypeBPF, err := bpfgen.GenerateBPF(f)
+	if err != nil {
+		return fmt.Errorf("failed to generate prototype BPF: %v", err)
+	}
+	if *xdp {
+		program, err := ebpf.Generate(f, ebpf.TargetXDP)
+		if err != nil {
+			return fmt.Errorf("failed to generate eBPF program: %v", err)
+		}
+		result, err := kernel.RunXDP(program, prototypeBPF.Instructions, corpus)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n=== XDP Oracle ===\n%s", result.Report())
+		if result.Failed() {
+			return errFailed
+		}
+		return nil
+	}
+	tcpdumpBPF, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Compiler: compiler})
+	if err != nil {
+		return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+	}
+
+	result, err := kernel.Run(tcpdumpBPF.Instructions, prototypeBPF.Instructions, f.LinkType, corpus)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n=== Kernel Oracle (%s reference) ===\n%s", tcpdumpBPF.Source, result.Report())
+	if result.Failed() {
+		return errFailed
+	}
+	return nil
+}
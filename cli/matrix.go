@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "matrix",
+		Summary: "Compare reference bytecode across tcpdump/libpcap versions",
+		Run:     runMatrix,
+	})
+}
+
+// runMatrix compiles one filter with several reference backends and
+// reports how the reference bytecode varies between them
+func runMatrix(args []string) error {
+	fs := newFlagSet("matrix", "--backend NAME=COMMAND ... [--tcpdump-format d|dd|ddd] [filter flags]")
+	var specs stringList
+	fs.Var(&specs, "backend", "Reference backend as name=command, e.g. \"4.9=docker run --rm img tcpdump\" (repeatable)")
+	formatName := fs.String("tcpdump-format", "ddd", "Dump format to request from every backend (d, dd or ddd)")
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	format, err := tcpdump.ParseOutputFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	if len(specs) == 0 {
+		specs = append(specs, "local=tcpdump")
+	}
+
+	var backends []*tcpdump.Backend
+	for _, spec := range specs {
+		b, err := tcpdump.ParseBackend(spec)
+		if err != nil {
+			return err
+		}
+		b.Format = format
+		backends = append(backends, b)
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	m := tcpdump.RunMatrix(context.Background(), backends, f.ToTcpdumpFilter(), f.LinkType)
+	fmt.Printf("\n=== Reference Version Matrix ===\n%s", m.Report())
+	return nil
+}
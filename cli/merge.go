@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/batch"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/fuzz"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// maxReportedMismatches bounds the packets --check lists
+const maxReportedMismatches = 5
+
+func init() {
+	register(&Command{
+		Name:    "merge",
+		Summary: "Generate one prototype program for several filters, or for a list of capture rules",
+		Run:     runMerge,
+	})
+}
+
+// runMerge emits the merged prototype program for a list of filters, or
+// the program applying a list of rules
+func runMerge(args []string) error {
+	fs := newFlagSet("merge", "(--filters FILE | --expr EXPR ... | --rules FILE) [--check N [--seed N] [--reference NAME] [--pcap-out FILE]] [-O0|-O1|-O2] [--fragments POLICY] [--snaplen N | --headers-only] [--max-instructions N] [--emit text|go|c-array|ddd|json|raw] [-o FILE]")
+	filtersPath := fs.String("filters", "", "YAML or JSON list of filters, as for compare --batch")
+	var exprs stringList
+	fs.Var(&exprs, "expr", "A filter as a tcpdump expression (repeatable)")
+	rulesPath := fs.String("rules", "", "YAML or JSON list of rules: filters with an action (accept, truncate with a snaplen, or drop), the first matching rule deciding")
+	linkType := fs.String("link-type", "", "Capture link type of --expr filters (EN10MB, LINUX_SLL, RAW, NULL; default EN10MB)")
+	check := fs.Int("check", 0, "Also run N synthetic packets per filter through the program and each filter's reference, compiled with the filter's snapshot length")
+	seed := fs.Int64("seed", 0, "Random seed of the --check packets (0 picks one from the clock)")
+	pcapOut := fs.String("pcap-out", "", "With --check, write the packets the program decides differently from the rules to FILE, to open in Wireshark or replay")
+	referenceName := addReferenceFlag(fs, false)
+	of := addOptFlags(fs)
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	headersOnly := addHeadersOnlyFlag(fs)
+	budget := addBudgetFlag(fs)
+	ef := addEmitFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if err := ef.validate(); err != nil {
+		return err
+	}
+	level, err := of.level()
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+	if err := checkBudget(*budget); err != nil {
+		return err
+	}
+	if *check < 0 {
+		return fmt.Errorf("--check must not be negative")
+	}
+	if *pcapOut != "" && *check == 0 {
+		return fmt.Errorf("--pcap-out applies to --check")
+	}
+	if *check > 0 && *headersOnly {
+		return fmt.Errorf("--check compares lengths with tcpdump, which has no headers-only capture")
+	}
+
+	sources := 0
+	for _, given := range []bool{*filtersPath != "", len(exprs) > 0, *rulesPath != ""} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("merge needs one of --filters FILE, --expr per filter or --rules FILE")
+	}
+	if changed(fs, "link-type") && len(exprs) == 0 {
+		return fmt.Errorf("--link-type applies to --expr filters; set link-type in the entries of the file")
+	}
+
+	// Filters are rules that all accept
+	var rules []*filter.Rule
+	switch {
+	case *rulesPath != "":
+		if rules, err = loadRules(*rulesPath); err != nil {
+			return err
+		}
+	case *filtersPath != "":
+		entries, err := batch.Load(*filtersPath)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			rules = append(rules, &filter.Rule{PacketFilter: e.PacketFilter})
+		}
+	default:
+		link, err := filter.ParseLinkType(*linkType)
+		if err != nil {
+			return err
+		}
+		for _, expr := range exprs {
+			f, err := filter.ParseExpr(expr)
+			if err != nil {
+				return err
+			}
+			f.LinkType = link
+			rules = append(rules, &filter.Rule{PacketFilter: *f})
+		}
+	}
+
+	opts := bpfgen.Options{OptLevel: level, Fragments: policy, Snaplen: *snaplen, HeadersOnly: *headersOnly, MaxInstructions: *budget}
+	var merged *bpfgen.BPFCode
+	if *rulesPath != "" {
+		merged, err = bpfgen.GenerateRulesBPF(rules, opts)
+	} else {
+		filters := make([]*filter.PacketFilter, len(rules))
+		for i, r := range rules {
+			filters[i] = &r.PacketFilter
+		}
+		merged, err = bpfgen.GenerateMergedBPF(filters, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate merged prototype BPF: %v", err)
+	}
+	if err := ef.write(&merged.Code, merged.String()); err != nil {
+		return err
+	}
+
+	if *check == 0 {
+		return nil
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	return checkRules(rules, merged.Instructions, compiler, *snaplen, *check, *seed, *pcapOut)
+}
+
+// loadRules reads a YAML or JSON list of rules
+func loadRules(path string) ([]*filter.Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	var rules []*filter.Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rules file %s contains no rules", path)
+	}
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// checkRules runs packets aimed at each rule through the program and
+// through the reference of every rule, compiled like tcpdump -s with the
+// length the rule returns. The first reference accepting a packet gives
+// the length expected of the program, or 0 for a drop rule or no match.
+// A packet too short for a field an earlier rule loads ends the program,
+// as it ends tcpdump's "or" of the rules; such packets are counted apart.
+// Mismatched packets are written to pcapOut, unless empty.
+func checkRules(rules []*filter.Rule, program []*bpf.Instruction, compiler tcpdump.ReferenceCompiler, snaplen, perRule int, seed int64, pcapOut string) error {
+	references := make([]*tcpdump.BPFCode, len(rules))
+	for i, r := range rules {
+		length := snaplen
+		if r.Action == filter.ActionTruncate {
+			length = r.Snaplen
+		}
+		reference, err := tcpdump.GenerateBPFWithOptions(context.Background(), &r.PacketFilter, tcpdump.Options{Compiler: compiler, Snaplen: length})
+		if err != nil {
+			return fmt.Errorf("rule %d: failed to generate tcpdump BPF: %v", i+1, err)
+		}
+		references[i] = reference
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	var packets [][]byte
+	for _, r := range rules {
+		packets = append(packets, fuzz.Packets(&r.PacketFilter, rng, perRule)...)
+	}
+
+	var mismatched [][]byte
+	aborted := 0
+	for _, pkt := range packets {
+		got, err := vm.Run(program, pkt)
+		if err != nil {
+			return fmt.Errorf("merged program: %w", err)
+		}
+		if got.Aborted {
+			aborted++
+			continue
+		}
+		want, decidedBy := uint32(0), "no rule"
+		for i, reference := range references {
+			result, err := vm.Run(reference.Instructions, pkt)
+			if err != nil {
+				return fmt.Errorf("rule %d reference: %w", i+1, err)
+			}
+			if result.Accepted {
+				if rules[i].Action != filter.ActionDrop {
+					want = result.Length
+				}
+				decidedBy = fmt.Sprintf("rule %d", i+1)
+				break
+			}
+		}
+		if got.Length == want {
+			continue
+		}
+		mismatched = append(mismatched, pkt)
+		if len(mismatched) <= maxReportedMismatches {
+			fmt.Printf("Mismatch: program returns %d, %s returns %d for packet %s\n", got.Length, decidedBy, want, hex.EncodeToString(pkt))
+			fmt.Printf("  %s\n", packet.Decode(pkt, rules[0].LinkType))
+		}
+	}
+
+	fmt.Printf("\nChecked %d packets with seed %d against the %s reference of each rule", len(packets), seed, references[0].Source)
+	if aborted > 0 {
+		fmt.Printf("; %d too short for an earlier rule's loads were dropped", aborted)
+	}
+	fmt.Println()
+	if pcapOut != "" {
+		if err := pcap.WriteFile(pcapOut, rules[0].LinkType, mismatched); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s (%d packets)\n", pcapOut, len(mismatched))
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%d packets got a different length than the rules' references give", len(mismatched))
+	}
+	fmt.Println("PASS: every packet got the length of its first matching rule")
+	return nil
+}
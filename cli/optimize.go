@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// optFlags select the prototype's optimization level, like a compiler's
+// -O0, -O1 and -O2
+type optFlags struct {
+	levels map[bpfgen.OptLevel]*bool
+}
+
+// addOptFlags registers the optimization level flags on a command's flag set
+func addOptFlags(fs *flag.FlagSet) *optFlags {
+	return &optFlags{levels: map[bpfgen.OptLevel]*bool{
+		bpfgen.O0: fs.Bool("O0", false, "Emit the prototype program without optimizing it"),
+		bpfgen.O1: fs.Bool("O1", false, "Run only the local optimizer passes (constant folding, dead code elimination)"),
+		bpfgen.O2: fs.Bool("O2", false, "Run every optimizer pass (the default)"),
+	}}
+}
+
+// level returns the selected level, O2 when none is given
+func (of *optFlags) level() (bpfgen.OptLevel, error) {
+	var selected bpfgen.OptLevel
+	for _, level := range []bpfgen.OptLevel{bpfgen.O0, bpfgen.O1, bpfgen.O2} {
+		if !*of.levels[level] {
+			continue
+		}
+		if selected != 0 {
+			return 0, fmt.Errorf("%s and %s are mutually exclusive", selected, level)
+		}
+		selected = level
+	}
+	if selected == 0 {
+		selected = bpfgen.O2
+	}
+	return selected, nil
+}
+
+// given reports whether any level flag was set
+func (of *optFlags) given() bool {
+	for _, set := range of.levels {
+		if *set {
+			return true
+		}
+	}
+	return false
+}
+
+// addFragmentsFlag registers --fragments, the prototype's fragment policy
+func addFragmentsFlag(fs *flag.FlagSet) *string {
+	return fs.String("fragments", string(bpfgen.FragmentsMatchFirst),
+		"IPv4 fragment policy: match-first-fragment (like tcpdump), reject-fragments or ignore")
+}
+
+// addSnaplenFlag registers --snaplen, the snapshot length both programs
+// return for accepted packets
+func addSnaplenFlag(fs *flag.FlagSet) *int {
+	return fs.Int("snaplen", 0, "Snapshot length accepting programs return, like tcpdump -s (0 means 262144)")
+}
+
+// checkSnaplen validates a --snaplen value
+func checkSnaplen(snaplen int) error {
+	if snaplen < 0 || snaplen > pcap.DefaultSnaplen {
+		return fmt.Errorf("invalid --snaplen %d, must be between 0 and %d", snaplen, pcap.DefaultSnaplen)
+	}
+	return nil
+}
+
+// addHeadersOnlyFlag registers --headers-only, which makes the prototype
+// return the header length of accepted packets
+func addHeadersOnlyFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("headers-only", false, "Make the prototype return the length of an accepted packet's link-layer, IPv4 and transport headers instead of the snapshot length")
+}
+
+// addBudgetFlag registers --max-instructions, the prototype's instruction
+// budget
+func addBudgetFlag(fs *flag.FlagSet) *int {
+	return fs.Int("max-instructions", 0, "Fail when the prototype program is longer, listing the instructions each clause takes (0 means no budget)")
+}
+
+// checkBudget validates a --max-instructions value
+func checkBudget(budget int) error {
+	if budget < 0 {
+		return fmt.Errorf("invalid --max-instructions %d, must not be negative", budget)
+	}
+	return nil
+}
+
+// addReferenceFlag registers --reference, the reference compiler
+func addReferenceFlag(fs *flag.FlagSet, list bool) *string {
+	usage := "Reference compiler: auto (libpcap, then tcpdump, then mock), libpcap, tcpdump, tshark, container, replay or mock"
+	if list {
+		usage += "; several comma-separated names cross-check the references"
+	}
+	return fs.String("reference", "auto", usage)
+}
+
+// parseReferenceOpt returns the reference compilations --reference-opt
+// selects, the optimized one first
+func parseReferenceOpt(s string) ([]tcpdump.Options, error) {
+	switch s {
+	case "optimized":
+		return []tcpdump.Options{{}}, nil
+	case "unoptimized":
+		return []tcpdump.Options{{Unoptimized: true}}, nil
+	case "both":
+		return []tcpdump.Options{{}, {Unoptimized: true}}, nil
+	}
+	return nil, fmt.Errorf("invalid --reference-opt '%s', must be optimized, unoptimized or both", s)
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/oracle"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+)
+
+func init() {
+	register(&Command{
+		Name:    "oracle",
+		Summary: "Check prototype decisions against tcpdump filtering a pcap",
+		Run:     runOracle,
+	})
+}
+
+// runOracle filters a pcap with tcpdump and the prototype program and
+// reports every packet on which they disagree
+func runOracle(args []string) error {
+	fs := newFlagSet("oracle", "--pcap FILE [filter flags]")
+	pcapPath := fs.String("pcap", "", "Pcap file to filter, whose link type must match --link-type")
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	if *pcapPath == "" {
+		fs.Usage()
+		return fmt.Errorf("--pcap is required")
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	prototypeBPF, err := bpfgen.GenerateBPF(f)
+	if err != nil {
+		return fmt.Errorf("failed to generate prototype BPF: %v", err)
+	}
+
+	result, err := oracle.Run(context.Background(), f.ToTcpdumpFilter(), *pcapPath, f.LinkType, prototypeBPF.Instructions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== Pcap Oracle ===\n%s", result.Report())
+	if result.Mismatches > 0 {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "patch",
+		Summary: "List the instruction edits that turn the prototype program into the reference, as JSON",
+		Run:     runPatch,
+	})
+}
+
+// patchDocument is the JSON the patch command writes
+type patchDocument struct {
+	Filter     string         `json:"filter,omitempty"`
+	Reference  int            `json:"reference"` // instructions of the reference program
+	Prototype  int            `json:"prototype"` // instructions of the prototype program
+	Distance   int            `json:"distance"`  // number of operations
+	Operations []*bpf.PatchOp `json:"operations"`
+}
+
+// runPatch writes the operations that transform the prototype program into
+// the reference, checking that they do. Unlike prove, it exits zero when
+// the programs differ, as the operations are its output.
+func runPatch(args []string) error {
+	fs := newFlagSet("patch", "[--output json|text] [--left FILE] [--right FILE] [--reference NAME] [--fragments POLICY] [--snaplen N] [filter flags]")
+	output := fs.String("output", "json", "Write the operations as a JSON document or as text, one per line")
+	leftPath := fs.String("left", "", "Use the program in FILE as the reference instead of compiling the filter")
+	rightPath := fs.String("right", "", "Use the program in FILE as the prototype instead of generating it")
+	leftFormat := fs.String("left-format", "auto", "Format of --left: d, dd, ddd, json, bin or auto to detect")
+	rightFormat := fs.String("right-format", "auto", "Format of --right: d, dd, ddd, json, bin or auto to detect")
+	referenceName := addReferenceFlag(fs, false)
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "json" && *output != "text" {
+		return fmt.Errorf("invalid --output '%s', must be json or text", *output)
+	}
+
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+
+	var f *filter.PacketFilter
+	if *leftPath == "" || *rightPath == "" {
+		if f, err = ff.build(); err != nil {
+			return err
+		}
+	} else if ff.criteriaGiven() || *ff.expr != "" || *ff.fromCRD != "" {
+		return fmt.Errorf("filter flags do not apply to --left against --right")
+	}
+
+	var reference, prototype []*bpf.Instruction
+	if *leftPath != "" {
+		if reference, err = loadProgramFile(*leftPath, *leftFormat); err != nil {
+			return err
+		}
+	} else {
+		tcpdumpBPF, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Compiler: compiler, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+		}
+		reference = tcpdumpBPF.Instructions
+	}
+	if *rightPath != "" {
+		if prototype, err = loadProgramFile(*rightPath, *rightFormat); err != nil {
+			return err
+		}
+	} else {
+		prototypeBPF, err := bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Fragments: policy, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate prototype BPF: %v", err)
+		}
+		prototype = prototypeBPF.Instructions
+	}
+
+	ops := bpf.Diff(prototype, reference)
+	patched, err := bpf.ApplyPatch(prototype, ops)
+	if err != nil {
+		return fmt.Errorf("the patch does not apply to the prototype: %v", err)
+	}
+	if !slices.EqualFunc(patched, reference, func(a, b *bpf.Instruction) bool { return *a == *b }) {
+		return fmt.Errorf("the patch does not turn the prototype into the reference")
+	}
+
+	if *output == "text" {
+		if f != nil {
+			fmt.Printf("Filter: %s\n", f.ToTcpdumpFilter())
+		}
+		fmt.Printf("Prototype: %d instructions, reference: %d instructions, distance: %d\n", len(prototype), len(reference), len(ops))
+		for _, op := range ops {
+			fmt.Printf("  %s\n", op)
+		}
+		return nil
+	}
+	doc := patchDocument{Reference: len(reference), Prototype: len(prototype), Distance: len(ops), Operations: ops}
+	if doc.Operations == nil {
+		doc.Operations = []*bpf.PatchOp{}
+	}
+	if f != nil {
+		doc.Filter = f.ToTcpdumpFilter()
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", data)
+	return nil
+}
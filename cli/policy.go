@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/k8s"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+func init() {
+	register(&Command{
+		Name:    "policy",
+		Summary: "Expand NetworkPolicy rules into filters and compare their BPF",
+		Run:     runPolicy,
+	})
+}
+
+// runPolicy generates and compares BPF for every rule of the policies in
+// the given files
+func runPolicy(args []string) error {
+	fs := newFlagSet("policy", "[--verbose] <networkpolicy.yaml> [...]")
+	verbose := fs.Bool("verbose", false, "Show the full comparison report for each filter")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return fmt.Errorf("at least one NetworkPolicy file is required")
+	}
+
+	type summary struct {
+		name    string
+		verdict string
+		score   float64
+	}
+	var summaries []summary
+	var skipped []string
+
+	// Rules whose filter an earlier one subsumes add nothing to a capture
+	var earlier []*k8s.RuleFilter
+	var redundant []string
+
+	for _, path := range files {
+		policies, err := k8s.LoadNetworkPolicies(path)
+		if err != nil {
+			return err
+		}
+
+		for _, np := range policies {
+			filters, s := np.Expand()
+			skipped = append(skipped, s...)
+
+			for _, rf := range filters {
+				fmt.Printf("\n=== %s ===\n", rf.Name)
+				fmt.Printf("Filter: %s\n", rf.Filter.ToTcpdumpFilter())
+				for _, note := range rf.Notes {
+					fmt.Printf("Note: %s (filter matches a superset)\n", note)
+				}
+				for _, prev := range earlier {
+					if covered, err := filter.Subsumes(prev.Filter, rf.Filter); err == nil && covered {
+						fmt.Printf("Note: every packet is also matched by %s\n", prev.Name)
+						redundant = append(redundant, fmt.Sprintf("%s (covered by %s)", rf.Name, prev.Name))
+						break
+					}
+				}
+				earlier = append(earlier, rf)
+
+				tcpdumpBPF, err := tcpdump.GenerateBPF(context.Background(), rf.Filter)
+				if err != nil {
+					return fmt.Errorf("%s: failed to generate tcpdump BPF: %v", rf.Name, err)
+				}
+				prototypeBPF, err := bpfgen.GenerateBPF(rf.Filter)
+				if err != nil {
+					return fmt.Errorf("%s: failed to generate prototype BPF: %v", rf.Name, err)
+				}
+
+				fmt.Printf("\nPrototype program:\n%s", bpf.Disassemble(prototypeBPF.Instructions))
+
+				comparison := compare.Compare(tcpdumpBPF, prototypeBPF)
+				if *verbose {
+					if err := renderComparison(comparison); err != nil {
+						return err
+					}
+				}
+				summaries = append(summaries, summary{rf.Name, comparison.Verdict, comparison.Score})
+			}
+		}
+	}
+
+	fmt.Printf("\n=== Policy Summary ===\n")
+	for _, s := range summaries {
+		fmt.Printf("%5.2f  %-60s %s\n", s.score, s.name, s.verdict)
+	}
+	if len(redundant) > 0 {
+		fmt.Printf("\nRedundant filters:\n")
+		for _, r := range redundant {
+			fmt.Printf("  - %s\n", r)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("\nNot expressible as filters:\n")
+		for _, s := range skipped {
+			fmt.Printf("  - %s\n", s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/prove"
+)
+
+func init() {
+	register(&Command{
+		Name:    "prove",
+		Summary: "Prove the reference and prototype programs accept the same packets, or find a region where they differ",
+		Run:     runProve,
+	})
+}
+
+// runProve decides exactly, rather than on probe packets, whether the
+// reference and the prototype accept the same packets. Like equiv, it
+// exits non-zero unless they do.
+func runProve(args []string) error {
+	fs := newFlagSet("prove", "[--backend bdd|smt] [--solver CMD] [--left FILE] [--right FILE] [--reference NAME] [--fragments POLICY] [--snaplen N] [filter flags]")
+	backend := fs.String("backend", "bdd", "Prover: bdd (decision diagrams, with a region of differing packets) or smt (an SMT solver, modeling packet length and any arithmetic)")
+	solverCmd := fs.String("solver", "", "SMT-LIB 2 solver command reading standard input for --backend smt (default \"z3 -in -smt2\")")
+	solverTimeout := fs.Duration("solver-timeout", prove.DefaultSolverTimeout, "Time limit of the SMT solver")
+	leftPath := fs.String("left", "", "Use the program in FILE as the reference instead of compiling the filter")
+	rightPath := fs.String("right", "", "Use the program in FILE as the prototype instead of generating it")
+	leftFormat := fs.String("left-format", "auto", "Format of --left: d, dd, ddd, json, bin or auto to detect")
+	rightFormat := fs.String("right-format", "auto", "Format of --right: d, dd, ddd, json, bin or auto to detect")
+	referenceName := addReferenceFlag(fs, false)
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+	if *backend != "bdd" && *backend != "smt" {
+		return fmt.Errorf("invalid --backend '%s', must be bdd or smt", *backend)
+	}
+	solver, err := prove.ParseSolver(*solverCmd)
+	if err != nil {
+		return err
+	}
+	solver.Timeout = *solverTimeout
+
+	var f *filter.PacketFilter
+	var link filter.LinkType
+	if *leftPath != "" && *rightPath != "" {
+		if ff.criteriaGiven() || *ff.expr != "" || *ff.fromCRD != "" {
+			return fmt.Errorf("filter flags do not apply when proving --left against --right; only --link-type does")
+		}
+		if *snaplen != 0 {
+			return fmt.Errorf("--snaplen applies to compiled and generated programs, not --left against --right")
+		}
+		if link, err = filter.ParseLinkType(*ff.linkType); err != nil {
+			return err
+		}
+	} else {
+		if f, err = ff.build(); err != nil {
+			return err
+		}
+		link = f.LinkType
+		fmt.Printf("Filter: %s\n", f.ToTcpdumpFilter())
+	}
+
+	var reference, prototype []*bpf.Instruction
+	if *leftPath != "" {
+		if reference, err = loadProgramFile(*leftPath, *leftFormat); err != nil {
+			return err
+		}
+	} else {
+		tcpdumpBPF, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Compiler: compiler, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+		}
+		reference = tcpdumpBPF.Instructions
+	}
+	if *rightPath != "" {
+		if prototype, err = loadProgramFile(*rightPath, *rightFormat); err != nil {
+			return err
+		}
+	} else {
+		prototypeBPF, err := bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Fragments: policy, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate prototype BPF: %v", err)
+		}
+		prototype = prototypeBPF.Instructions
+	}
+
+	var proof *prove.Proof
+	if *backend == "smt" {
+		proof, err = prove.ProveSMT(context.Background(), reference, prototype, solver)
+	} else {
+		proof, err = prove.Prove(reference, prototype, link)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reference: %d instructions, prototype: %d instructions\n", len(reference), len(prototype))
+	if proof.Equivalent {
+		fmt.Printf("Result: EQUIVALENT (both programs accept exactly the same packets)\n")
+		return nil
+	}
+
+	accepts, rejects := "reference", "prototype"
+	if !proof.FirstAccepts {
+		accepts, rejects = rejects, accepts
+	}
+	// Only the decision diagrams describe a region
+	if proof.Region == nil {
+		fmt.Printf("Result: DIFFERENT\n")
+		fmt.Printf("The %s accepts and the %s rejects this %d-byte packet:\n", accepts, rejects, len(proof.Witness))
+		fmt.Printf("Witness: %x\n", proof.Witness)
+		return errFailed
+	}
+	fmt.Printf("Result: DIFFERENT (they disagree on %.4g%% of packet contents)\n", 100*proof.Fraction)
+	fmt.Printf("The %s accepts and the %s rejects packets with:\n", accepts, rejects)
+	for _, c := range proof.Region {
+		fmt.Printf("  %s\n", c)
+	}
+	fmt.Printf("Witness: %x\n", proof.Witness)
+	return errFailed
+}
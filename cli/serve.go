@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/server"
+)
+
+func init() {
+	register(&Command{
+		Name:    "serve",
+		Summary: "Serve the gRPC API for compiling, comparing and simulating filters",
+		Run:     runServe,
+	})
+}
+
+// runServe serves the Validator gRPC service until interrupted, finishing
+// in-flight calls before it exits
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "[--listen ADDR] [--metrics-listen ADDR]")
+	listen := fs.String("listen", "127.0.0.1:50051", "TCP address to serve gRPC on")
+	metricsListen := fs.String("metrics-listen", "", "Also serve Prometheus metrics at /metrics on this TCP address")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument '%s'", fs.Arg(0))
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+
+	var metrics *server.Metrics
+	if *metricsListen != "" {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		if metrics, err = server.NewMetrics(reg); err != nil {
+			return err
+		}
+		metricsLis, err := net.Listen("tcp", *metricsListen)
+		if err != nil {
+			return fmt.Errorf("failed to listen for metrics: %v", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		go http.Serve(metricsLis, mux)
+		fmt.Printf("Serving metrics on http://%s/metrics\n", metricsLis.Addr())
+	}
+	server.Register(s, metrics)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		s.GracefulStop()
+	}()
+
+	fmt.Printf("Serving the Validator API on %s\n", lis.Addr())
+	return s.Serve(lis)
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/coverage"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+func init() {
+	register(&Command{
+		Name:    "simulate",
+		Summary: "Run packets through the generated programs",
+		Run:     runSimulate,
+	})
+}
+
+// namedProgram pairs a program with the label used in output
+type namedProgram struct {
+	name    string
+	prog    []*bpf.Instruction
+	clauses []string // filter clause of each instruction, for --coverage
+}
+
+// runSimulate builds the requested programs and reports the verdict of each
+// program for every input packet
+func runSimulate(args []string) error {
+	fs := newFlagSet("simulate", "(--packet HEX ... | --packet-json FIELDS ... | --pcap FILE) [--program both] [--trace [--annotate]] [--diff] [--coverage] [--reference NAME] [--fragments POLICY] [--snaplen N] [--headers-only] [filter flags]")
+	var packets stringList
+	fs.Var(&packets, "packet", "Packet as hex, starting with the --link-type header (repeatable)")
+	var specs stringList
+	fs.Var(&specs, "packet-json", `Packet built from header fields as JSON, e.g. '{"protocol":"tcp","dst-ip":"10.0.0.5","dst-port":80}', behind the --link-type header (repeatable)`)
+	pcapPath := fs.String("pcap", "", "Pcap file with packets of the --link-type to simulate")
+	program := fs.String("program", "both", "Program to run (prototype, reference, both)")
+	trace := fs.Bool("trace", false, "Print every instruction each program executes, with the A and X registers it leaves")
+	annotate := fs.Bool("annotate", false, "With --trace, print each program's whole disassembly, marking the instructions executed")
+	diff := fs.Bool("diff", false, "Align the checks both programs make on each packet, and explain where their paths part")
+	showCoverage := fs.Bool("coverage", false, "Report which filter clauses accepted and rejected packets exercised in each program")
+	fragments := addFragmentsFlag(fs)
+	snaplen := addSnaplenFlag(fs)
+	headersOnly := addHeadersOnlyFlag(fs)
+	referenceName := addReferenceFlag(fs, false)
+	ff := addFilterFlags(fs)
+	if err := ff.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *annotate && !*trace {
+		return fmt.Errorf("--annotate applies to --trace")
+	}
+	if *diff && *program != "both" {
+		return fmt.Errorf("--diff needs --program both")
+	}
+	if len(packets) == 0 && len(specs) == 0 && *pcapPath == "" {
+		fs.Usage()
+		return fmt.Errorf("at least one --packet, --packet-json or a --pcap file is required")
+	}
+
+	policy, err := bpfgen.ParseFragmentPolicy(*fragments)
+	if err != nil {
+		return err
+	}
+	compiler, err := tcpdump.ParseReference(*referenceName)
+	if err != nil {
+		return err
+	}
+	if err := checkSnaplen(*snaplen); err != nil {
+		return err
+	}
+
+	f, err := ff.build()
+	if err != nil {
+		return err
+	}
+
+	// The reference's clauses are named after the prototype's, so the
+	// prototype is generated for --coverage and --trace even when it is
+	// not run
+	clauses := *showCoverage || *trace
+	var programs []namedProgram
+	var prototypeBPF *bpfgen.BPFCode
+	var comparison *compare.ComparisonResult
+	if *program == "prototype" || *program == "both" || clauses {
+		prototypeBPF, err = bpfgen.GenerateBPFWithOptions(f, bpfgen.Options{Fragments: policy, Snaplen: *snaplen, HeadersOnly: *headersOnly})
+		if err != nil {
+			return fmt.Errorf("failed to generate prototype BPF: %v", err)
+		}
+	}
+	if *program == "prototype" || *program == "both" {
+		programs = append(programs, namedProgram{"prototype", prototypeBPF.Instructions, prototypeBPF.Sources})
+	}
+	if *program == "reference" || *program == "both" {
+		tcpdumpBPF, err := tcpdump.GenerateBPFWithOptions(context.Background(), f, tcpdump.Options{Compiler: compiler, Snaplen: *snaplen})
+		if err != nil {
+			return fmt.Errorf("failed to generate tcpdump BPF: %v", err)
+		}
+		if clauses || *diff {
+			comparison = compare.Compare(tcpdumpBPF, prototypeBPF)
+		}
+		var referenceClauses []string
+		if clauses {
+			referenceClauses = comparison.ReferenceClauses()
+		}
+		programs = append(programs, namedProgram{"reference", tcpdumpBPF.Instructions, referenceClauses})
+	}
+	if len(programs) == 0 {
+		return fmt.Errorf("invalid --program '%s', must be prototype, reference, or both", *program)
+	}
+
+	var inputs [][]byte
+	for _, h := range packets {
+		data, err := packet.ParseHex(h)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, data)
+	}
+	for _, s := range specs {
+		data, err := buildPacket(s, f.LinkType)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, data)
+	}
+	if *pcapPath != "" {
+		reader, err := pcap.Open(*pcapPath)
+		if err != nil {
+			return err
+		}
+		records, err := reader.ReadAll()
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			inputs = append(inputs, r.Data)
+		}
+	}
+
+	fmt.Printf("\n=== Simulation ===\n")
+	for i, data := range inputs {
+		fmt.Printf("packet #%d (%d bytes):", i, len(data))
+		for _, p := range programs {
+			result, err := vm.Run(p.prog, data)
+			if err != nil {
+				fmt.Printf("  %s=error(%v)", p.name, err)
+				continue
+			}
+			verdict := "reject"
+			if result.Accepted {
+				verdict = fmt.Sprintf("accept(%d)", result.Length)
+			}
+			fmt.Printf("  %s=%s [%d insns]", p.name, verdict, result.Executed)
+		}
+		fmt.Printf("\n  %s\n", packet.Decode(data, f.LinkType))
+		if *trace {
+			for _, p := range programs {
+				if err := printTrace(p, data, *annotate); err != nil {
+					return err
+				}
+			}
+		}
+		if *diff {
+			d, err := comparison.DiffTrace(data)
+			if err != nil {
+				return err
+			}
+			if err := d.Render(os.Stdout); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *showCoverage {
+		for _, p := range programs {
+			fmt.Printf("\n=== Clause Coverage: %s ===\n%s", p.name, coverage.Measure(p.name, p.prog, p.clauses, inputs))
+		}
+	}
+	return nil
+}
+
+// buildPacket builds the packet a --packet-json value describes, behind
+// the header of the link type. Unknown fields are an error rather than
+// left at their defaults.
+func buildPacket(fields string, link filter.LinkType) ([]byte, error) {
+	dec := json.NewDecoder(strings.NewReader(fields))
+	dec.DisallowUnknownFields()
+	var spec packet.Spec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid --packet-json %s: %v", fields, err)
+	}
+	data, err := spec.BuildFor(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --packet-json %s: %v", fields, err)
+	}
+	return data, nil
+}
+
+// printTrace prints the run of a program over the packet, as the steps it
+// executed or, with annotate, as its disassembly marking them
+func printTrace(p namedProgram, data []byte, annotate bool) error {
+	result, steps, err := vm.Steps(p.prog, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.name, err)
+	}
+	text := vm.FormatSteps(p.prog, steps, p.clauses)
+	if annotate {
+		text = vm.Annotate(p.prog, steps, p.clauses)
+	}
+	if result.Aborted {
+		text += fmt.Sprintf("load outside the %d-byte packet, returns 0\n", len(data))
+	}
+	fmt.Printf("  %s:\n%s", p.name, indent(text, "    "))
+	return nil
+}
+
+// indent prefixes every line of text
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n"+prefix) + "\n"
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/integration"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/testcase"
+)
+
+func init() {
+	register(&Command{
+		Name:    "test",
+		Summary: "Run declarative YAML test cases",
+		Run:     runTest,
+	})
+}
+
+// runTest runs every case in the given YAML files
+func runTest(args []string) error {
+	fs := newFlagSet("test", "[--kernel] <file.yaml> [file.yaml ...]")
+	throughKernel := fs.Bool("kernel", false, "Send every case's packets through a veth pair in a network namespace, to the reference and prototype sockets and the XDP program, instead of the interpreter")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return fmt.Errorf("at least one test file is required")
+	}
+
+	failed := false
+	for _, path := range files {
+		suite, err := testcase.Load(path)
+		if err != nil {
+			return err
+		}
+
+		if *throughKernel {
+			results, err := integration.Run(suite)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\n=== Kernel Test Results: %s ===\n%s", path, integration.Report(results))
+			for _, r := range results {
+				failed = failed || !r.Passed()
+			}
+			continue
+		}
+
+		results := suite.Run()
+		fmt.Printf("\n=== Test Case Results: %s ===\n%s", path, testcase.Report(results))
+
+		for _, r := range results {
+			if !r.Passed() {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
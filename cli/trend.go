@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/baseline"
+)
+
+func init() {
+	register(&Command{
+		Name:    "trend",
+		Summary: "List score changes of baseline runs over time, flagging regressions from prototype changes",
+		Run:     runTrend,
+	})
+}
+
+// runTrend lists the score history baseline runs recorded, filter by
+// filter, and fails when a filter still has a score drop that came with a
+// change of its prototype program
+func runTrend(args []string) error {
+	fs := newFlagSet("trend", "[--dir DIR] [--since DURATION|DATE] [--name TEXT]")
+	dir := fs.String("dir", defaultBaselineDir, "Baseline store directory")
+	sinceFlag := fs.String("since", "", "Only list runs of the last DURATION (e.g. 720h) or since DATE (YYYY-MM-DD)")
+	name := fs.String("name", "", "Only list filters whose name contains TEXT")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument '%s'", positional[0])
+	}
+	since, err := parseSince(*sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	trends, err := baseline.History(*dir, since)
+	if err != nil {
+		return err
+	}
+	kept := trends[:0]
+	for _, t := range trends {
+		if strings.Contains(t.Name, *name) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		return fmt.Errorf("no score history in %s; run baseline save or check to record some", *dir)
+	}
+
+	fmt.Printf("=== Score trend (%s) ===\n%s", *dir, baseline.TrendReport(kept))
+	for _, t := range kept {
+		if t.Regression() != nil {
+			return errFailed
+		}
+	}
+	return nil
+}
+
+// parseSince reads --since as a duration before now or a date, or returns
+// the zero time for none
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since '%s', want a duration such as 720h or a date such as 2026-01-31", s)
+}
@@ -0,0 +1,18 @@
+// Command kubectl-antrea-bpf-check is the validation framework packaged as
+// a kubectl plugin. Installed on the PATH, it runs as
+//
+//	kubectl antrea bpf check agent --pod kube-system/antrea-agent-7x2kq --from-crd capture.yaml
+//
+// with the commands and flags of the standalone tool, printing tables as
+// antctl does unless --output asks for json, yaml or the full report.
+package main
+
+import (
+	"os"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/cli"
+)
+
+func main() {
+	os.Exit(cli.RunPlugin(os.Args[1:]))
+}
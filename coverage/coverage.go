@@ -0,0 +1,144 @@
+// Package coverage measures which filter clauses of a program a packet
+// corpus exercises. A clause is exercised when packets that ran one of its
+// checks were both accepted and rejected in the end; a check whose jump
+// only ever goes one way is a dead branch the corpus never executes.
+package coverage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// Clause is the coverage of one filter clause
+type Clause struct {
+	Name     string
+	Checks   []int // conditional jumps implementing the clause
+	Accepted int   // packets that ran a check of the clause and were accepted
+	Rejected int   // packets that ran a check of the clause and were rejected
+	Outcomes int   // branch outcomes some packet took, of two per check
+
+	// DeadBranches describes the outcomes of its checks no packet took,
+	// e.g. "instruction 7 never true"
+	DeadBranches []string
+}
+
+// Exercised reports whether both an accepted and a rejected packet ran the
+// clause's checks
+func (c *Clause) Exercised() bool {
+	return c.Accepted > 0 && c.Rejected > 0
+}
+
+// Report is the clause coverage of one program
+type Report struct {
+	Program string
+	Packets int // packets the program ran without error
+	Errors  int // packets the program failed on
+	Clauses []*Clause
+}
+
+// Exercised counts the clauses Exercised reports true for
+func (r *Report) Exercised() int {
+	n := 0
+	for _, c := range r.Clauses {
+		if c.Exercised() {
+			n++
+		}
+	}
+	return n
+}
+
+// Measure runs the packets through the program. clauses names the clause
+// each instruction implements, as bpfgen.BPFCode.Sources does; clauses
+// without conditional jumps, such as the final returns, are left out, and
+// jumps with no clause are grouped under "(unattributed)". Clauses are
+// listed in program order.
+func Measure(program string, prog []*bpf.Instruction, clauses []string, packets [][]byte) *Report {
+	report := &Report{Program: program}
+	byName := make(map[string]*Clause)
+	clauseOf := make(map[int]*Clause)
+	for pc, inst := range prog {
+		if !inst.IsJump() || inst.Code&0xf0 == bpf.JmpJA {
+			continue
+		}
+		name := "(unattributed)"
+		if pc < len(clauses) && clauses[pc] != "" {
+			name = clauses[pc]
+		}
+		c := byName[name]
+		if c == nil {
+			c = &Clause{Name: name}
+			byName[name] = c
+			report.Clauses = append(report.Clauses, c)
+		}
+		c.Checks = append(c.Checks, pc)
+		clauseOf[pc] = c
+	}
+
+	taken := make(map[vm.Branch]bool)
+	for _, pkt := range packets {
+		result, branches, err := vm.Trace(prog, pkt)
+		if err != nil {
+			report.Errors++
+			continue
+		}
+		report.Packets++
+		ran := make(map[*Clause]bool)
+		for _, b := range branches {
+			taken[b] = true
+			ran[clauseOf[b.PC]] = true
+		}
+		for c := range ran {
+			if result.Accepted {
+				c.Accepted++
+			} else {
+				c.Rejected++
+			}
+		}
+	}
+
+	for _, c := range report.Clauses {
+		for _, pc := range c.Checks {
+			t, f := taken[vm.Branch{PC: pc, Taken: true}], taken[vm.Branch{PC: pc, Taken: false}]
+			for _, ok := range []bool{t, f} {
+				if ok {
+					c.Outcomes++
+				}
+			}
+			switch {
+			case !t && !f:
+				c.DeadBranches = append(c.DeadBranches, fmt.Sprintf("instruction %d never runs", pc))
+			case !t:
+				c.DeadBranches = append(c.DeadBranches, fmt.Sprintf("instruction %d never true", pc))
+			case !f:
+				c.DeadBranches = append(c.DeadBranches, fmt.Sprintf("instruction %d never false", pc))
+			}
+		}
+	}
+	return report
+}
+
+// String formats the report as a table of clauses with the dead branches
+// of each under it, and a summary line
+func (r *Report) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-32s %8s %8s  %s\n", "CLAUSE", "ACCEPTED", "REJECTED", "BRANCHES"))
+	for _, c := range r.Clauses {
+		mark := ""
+		if !c.Exercised() {
+			mark = "  not exercised"
+		}
+		sb.WriteString(fmt.Sprintf("%-32s %8d %8d  %d/%d%s\n", c.Name, c.Accepted, c.Rejected, c.Outcomes, 2*len(c.Checks), mark))
+		for _, d := range c.DeadBranches {
+			sb.WriteString(fmt.Sprintf("  - %s\n", d))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%d of %d clauses exercised by accepted and rejected packets (%d packets", r.Exercised(), len(r.Clauses), r.Packets))
+	if r.Errors > 0 {
+		sb.WriteString(fmt.Sprintf(", %d failed", r.Errors))
+	}
+	sb.WriteString(")\n")
+	return sb.String()
+}
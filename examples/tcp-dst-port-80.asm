@@ -0,0 +1,14 @@
+; Hand-written reference for "tcp dst port 80" on Ethernet (IPv4 only).
+; Assemble and compare against the prototype with:
+;   go run main.go compile examples/tcp-dst-port-80.asm --protocol tcp --dst-port 80
+        ldh [12]
+        jeq #0x800, ipv4, drop          ; IPv4?
+ipv4:   ldb [23]
+        jeq #6, tcp, drop               ; TCP?
+tcp:    ldh [20]
+        jset #0x1fff, drop, first       ; skip non-first fragments
+first:  ldxb 4*([14]&0xf)
+        ldh [x + 16]
+        jeq #80, accept, drop           ; destination port 80
+accept: ret #262144
+drop:   ret #0
@@ -0,0 +1,535 @@
+// Package fuzz differentially tests the prototype generator against the
+// reference compiler. A byte string is decoded into a random valid filter
+// and a set of packets aimed at it, both programs run over every packet in
+// the interpreter, and any disagreement in verdict is a failure. The
+// filter's meaning in Go, filter.PacketFilter.MatchesPacket, is a third
+// opinion, so that a mistake both compilers make fails too. Because a
+// case is a pure function of its input, a failing input reproduces the
+// failure exactly, whether it came from go test -fuzz or a seeded source.
+package fuzz
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// PacketsPerCase is the number of packets generated for each filter
+const PacketsPerCase = 16
+
+// InputSize is the length of inputs made by RandomInput, enough for the
+// filter and every packet to draw fresh bytes
+const InputSize = 512
+
+// Case is a filter and the packets it is checked against
+type Case struct {
+	Filter  *filter.PacketFilter
+	Packets [][]byte
+}
+
+// Mismatch reports a packet on which the two programs disagree, or on
+// which they agree and the filter's meaning in Go does not
+type Mismatch struct {
+	Filter    string // tcpdump expression of the filter
+	Source    string // reference compiler that produced the expected verdict
+	Packet    []byte
+	Original  []byte // the packet as drawn, which Packet is minimized from
+	Reference bool   // verdict of the reference program
+	Prototype bool   // verdict of the prototype program
+
+	// Spec is the verdict of filter.PacketFilter.MatchesPacket when it
+	// differs from both programs, and nil otherwise
+	Spec *bool
+}
+
+// Error describes the disagreement and the packet that caused it
+func (m *Mismatch) Error() string {
+	if m.Spec != nil {
+		return fmt.Sprintf("filter '%s': %s and prototype %s, Go predicate %s packet %s",
+			m.Filter, m.Source, verdict(m.Reference), verdict(*m.Spec), hex.EncodeToString(m.Packet))
+	}
+	return fmt.Sprintf("filter '%s': %s %s, prototype %s packet %s",
+		m.Filter, m.Source, verdict(m.Reference), verdict(m.Prototype), hex.EncodeToString(m.Packet))
+}
+
+// verdict names an accept decision
+func verdict(accepted bool) string {
+	if accepted {
+		return "accepts"
+	}
+	return "rejects"
+}
+
+// RandomInput returns an input of InputSize random bytes
+func RandomInput(r *rand.Rand) []byte {
+	data := make([]byte, InputSize)
+	r.Read(data)
+	return data
+}
+
+// Check compiles the case's filter with the reference compiler and the
+// prototype and runs both over every packet. It returns a *Mismatch for
+// the first packet with differing verdicts, or whose verdict the Go
+// predicate contradicts where it can judge it (see Spec), with the packet
+// minimized. The filter's tcpdump expression must also parse back to the
+// same expression.
+func Check(c *Case) error {
+	expr := c.Filter.ToTcpdumpFilter()
+	parsed, err := filter.ParseExpr(expr)
+	if err != nil {
+		return fmt.Errorf("parsing '%s': %w", expr, err)
+	}
+	if back := parsed.ToTcpdumpFilter(); back != expr {
+		return fmt.Errorf("'%s' parsed back as '%s'", expr, back)
+	}
+
+	reference, err := tcpdump.GenerateBPF(context.Background(), c.Filter)
+	if err != nil {
+		return fmt.Errorf("reference compiler: %w", err)
+	}
+	proto, err := bpfgen.GenerateBPF(c.Filter)
+	if err != nil {
+		return fmt.Errorf("prototype generator: %w", err)
+	}
+
+	decide := func(pkt []byte) (outcome, error) {
+		return decideOutcome(c.Filter, reference.Instructions, proto.Instructions, pkt)
+	}
+	for _, pkt := range c.Packets {
+		o, err := decide(pkt)
+		if err != nil {
+			return err
+		}
+		if !o.failed() {
+			continue
+		}
+
+		// Report the smallest packet that fails the same way
+		small := Minimize(pkt, func(candidate []byte) bool {
+			other, err := decide(candidate)
+			return err == nil && other == o
+		})
+		mismatch := &Mismatch{
+			Filter:    reference.FilterExpr,
+			Source:    reference.Source,
+			Packet:    small,
+			Original:  pkt,
+			Reference: o.reference.accepted,
+			Prototype: o.prototype.accepted,
+		}
+		if o.reference.accepted == o.prototype.accepted {
+			mismatch.Spec = &o.spec
+		}
+		return mismatch
+	}
+	return nil
+}
+
+// Spec returns the verdict of filter.PacketFilter.MatchesPacket on a
+// packet, and false for a packet it cannot decide, which the compilers
+// cannot be held to
+func Spec(f *filter.PacketFilter, pkt []byte) (bool, bool) {
+	match, err := f.MatchesPacket(pkt)
+	return match, err == nil
+}
+
+// CaseFromBytes decodes a valid IPv4 filter and PacketsPerCase packets from
+// data. Most packet fields are drawn to satisfy the filter so accepting
+// paths are exercised, the rest are random, and some packets are then
+// fragmented, given IP options, retyped or truncated.
+func CaseFromBytes(data []byte) *Case {
+	in := &input{data: data}
+	f := randomFilter(in)
+	c := &Case{Filter: f}
+	for i := 0; i < PacketsPerCase; i++ {
+		c.Packets = append(c.Packets, randomPacket(f, in))
+	}
+	return c
+}
+
+// Packets draws n packets aimed at the filter the way cases do, for a
+// synthetic trace: most fields match it, and some packets are fragmented,
+// given IP options, retyped or truncated
+func Packets(f *filter.PacketFilter, r *rand.Rand, n int) [][]byte {
+	packets := make([][]byte, n)
+	for i := range packets {
+		packets[i] = randomPacket(f, &input{data: RandomInput(r)})
+	}
+	return packets
+}
+
+// input consumes fuzz data, yielding zeros once it runs out
+type input struct {
+	data []byte
+}
+
+// byte returns the next input byte
+func (in *input) byte() byte {
+	if len(in.data) == 0 {
+		return 0
+	}
+	b := in.data[0]
+	in.data = in.data[1:]
+	return b
+}
+
+// uint16 returns the next two input bytes as a number
+func (in *input) uint16() uint16 {
+	return uint16(in.byte())<<8 | uint16(in.byte())
+}
+
+// uint32 returns the next four input bytes as a number
+func (in *input) uint32() uint32 {
+	return uint32(in.uint16())<<16 | uint32(in.uint16())
+}
+
+// choose returns a number in [0, n)
+func (in *input) choose(n int) int {
+	return int(in.byte()) % n
+}
+
+// randomFilter decodes a filter that passes Validate
+func randomFilter(in *input) *filter.PacketFilter {
+	f := &filter.PacketFilter{}
+	f.Protocol = []string{"", "tcp", "udp", "icmp", "47", "132"}[in.choose(6)]
+
+	switch in.choose(6) {
+	case 1:
+		f.SrcIP = randomNet(in)
+	case 2:
+		f.DstIP = randomNet(in)
+	case 3:
+		f.SrcIP = randomNet(in)
+		f.DstIP = randomNet(in)
+	case 4:
+		f.Between = []string{randomNet(in), randomNet(in)}
+	case 5:
+		f.HostIP = randomNet(in)
+		// An overlapping source network lets the optimizer reuse its load
+		if in.choose(2) == 0 {
+			f.SrcIP = overlappingNet(f.HostIP, in)
+		}
+	}
+
+	if f.Protocol != "icmp" && f.Protocol != "47" {
+		if in.choose(2) == 0 {
+			f.SrcPort = 1 + int(in.uint16())%65535
+		} else if in.choose(4) == 0 {
+			f.SrcPorts = randomPorts(in)
+		}
+		if in.choose(2) == 0 {
+			f.DstPort = 1 + int(in.uint16())%65535
+		} else if in.choose(4) == 0 {
+			f.DstPorts = randomPorts(in)
+		}
+		if in.choose(4) == 0 {
+			f.Port = 1 + int(in.uint16())%65535
+			if ports := f.SrcPortList(); len(ports) > 0 && in.choose(2) == 0 {
+				f.Port = ports[in.choose(len(ports))]
+			}
+		}
+	}
+
+	// Mostly Ethernet, the only link type that carries VLAN tags
+	if in.choose(4) == 0 {
+		f.LinkType = filter.LinkTypes[in.choose(len(filter.LinkTypes))]
+	}
+	if f.LinkType.IsEthernet() {
+		switch in.choose(8) {
+		case 0:
+			f.VLAN = true
+		case 1:
+			f.VLANID = 1 + int(in.uint16())%4095
+		}
+	}
+
+	// Occasionally a tunnel, with the criteria on the inner or the outer
+	// packet; the tunnel fixes the outer protocol and ports
+	if in.choose(8) == 0 {
+		f.Tunnel = filter.Tunnels[in.choose(len(filter.Tunnels))]
+		if in.choose(2) == 0 {
+			f.VNI = 1 + int(in.uint32())%filter.MaxVNI
+		}
+		f.Inner = in.choose(2) == 0
+		if !f.Inner {
+			if f.Protocol != "udp" {
+				f.Protocol = ""
+			}
+			f.SrcPort, f.SrcPorts, f.DstPort, f.DstPorts, f.Port = 0, nil, 0, nil, 0
+		}
+	}
+
+	// Occasionally a link-layer protocol, which takes no IP criteria
+	if in.choose(16) == 0 && hasEtherType(f.LinkType) {
+		*f = filter.PacketFilter{VLAN: f.VLAN, VLANID: f.VLANID, LinkType: f.LinkType}
+		switch in.choose(3) {
+		case 0:
+			f.Protocol = "arp"
+		case 1:
+			f.Protocol = "rarp"
+		default:
+			f.EtherType = 0x0600 + int(in.uint16())%(0x10000-0x0600)
+		}
+	}
+
+	// The only way the choices above can be invalid is an empty filter
+	if f.Validate() != nil {
+		f.Protocol = "tcp"
+	}
+	return f
+}
+
+// hasEtherType reports whether frames of the link type carry an EtherType,
+// and so can be ARP or another non-IP protocol
+func hasEtherType(link filter.LinkType) bool {
+	return link.IsEthernet() || link == filter.LinkLinuxSLL
+}
+
+// randomPorts returns a list of two to four distinct ports. Each port
+// steps forward from the previous one, so the list stays distinct even
+// after the input runs out.
+func randomPorts(in *input) []int {
+	port := int(in.uint16())
+	ports := make([]int, 2+in.choose(3))
+	for i := range ports {
+		port = 1 + (port+int(in.byte()))%65535
+		ports[i] = port
+	}
+	return ports
+}
+
+// randomNet returns a host address or a CIDR, possibly with host bits set
+func randomNet(in *input) string {
+	ip := ipString(in.uint32())
+	if in.choose(2) == 0 {
+		return ip
+	}
+	return fmt.Sprintf("%s/%d", ip, 1+in.choose(32))
+}
+
+// overlappingNet returns a network with the same address as s and a
+// random prefix length, so one of the two contains the other
+func overlappingNet(s string, in *input) string {
+	ip, _, _ := strings.Cut(s, "/")
+	return fmt.Sprintf("%s/%d", ip, 1+in.choose(32))
+}
+
+// randomPacket builds a frame whose fields mostly satisfy the filter
+func randomPacket(f *filter.PacketFilter, in *input) []byte {
+	protocols := []string{"tcp", "udp", "icmp", "47", "132"}
+	spec := packet.Spec{
+		Protocol: protocols[in.choose(len(protocols))],
+		SrcIP:    ipString(in.uint32()),
+		DstIP:    ipString(in.uint32()),
+		SrcPort:  int(in.uint16()),
+		DstPort:  int(in.uint16()),
+	}
+
+	// Each criterion is satisfied three times out of four
+	match := func() bool { return in.choose(4) != 0 }
+	if hasEtherType(f.LinkType) && in.choose(8) == 0 {
+		spec.Protocol = "arp"
+	}
+	if f.Protocol != "" && match() {
+		spec.Protocol = f.Protocol
+	}
+	if f.EtherType != 0 && match() {
+		spec.EtherType = f.EtherType
+	}
+	if f.SrcIP != "" && match() {
+		spec.SrcIP = addressIn(f.SrcIP, in)
+	}
+	if f.DstIP != "" && match() {
+		spec.DstIP = addressIn(f.DstIP, in)
+	}
+	if f.HostIP != "" && match() {
+		if in.choose(2) == 0 {
+			spec.SrcIP = addressIn(f.HostIP, in)
+		} else {
+			spec.DstIP = addressIn(f.HostIP, in)
+		}
+	}
+	if len(f.Between) == 2 {
+		a, b := f.Between[0], f.Between[1]
+		if in.choose(2) == 0 {
+			a, b = b, a
+		}
+		if match() {
+			spec.SrcIP = addressIn(a, in)
+		}
+		if match() {
+			spec.DstIP = addressIn(b, in)
+		}
+	}
+	if ports := f.SrcPortList(); len(ports) > 0 && match() {
+		spec.SrcPort = ports[in.choose(len(ports))]
+	}
+	if ports := f.DstPortList(); len(ports) > 0 && match() {
+		spec.DstPort = ports[in.choose(len(ports))]
+	}
+	if f.Port != 0 && match() {
+		if in.choose(2) == 0 {
+			spec.SrcPort = f.Port
+		} else {
+			spec.DstPort = f.Port
+		}
+	}
+	if in.choose(4) == 0 {
+		spec.FragOff = int(in.uint16()) & 0x1fff
+	}
+	spec.MoreFragments = in.choose(4) == 0
+
+	// Tagged frames, mostly on the filter's VLAN
+	spec.VLAN = in.choose(8) == 0 && f.LinkType.IsEthernet()
+	if f.VLAN && match() {
+		spec.VLAN = true
+	}
+	if spec.VLAN {
+		spec.VLANID = int(in.uint16()) & 0xfff
+		if f.VLANID != 0 && match() {
+			spec.VLANID = f.VLANID
+		}
+	}
+
+	// Tunnelled packets, mostly in the filter's tunnel and network
+	if (f.Tunnel != "" && match()) || in.choose(16) == 0 {
+		spec = encapsulate(f, spec, in)
+	}
+
+	frame, err := spec.BuildFor(f.LinkType)
+	if err != nil {
+		// Every field above is in range, so this is a harness bug
+		panic(fmt.Sprintf("fuzz: building packet: %v", err))
+	}
+	return mutate(frame, f.LinkType, in)
+}
+
+// encapsulate wraps a packet in a VXLAN or Geneve tunnel. For a filter on
+// the inner packet the spec drawn for the filter goes inside the tunnel,
+// otherwise it stays the outer packet around a random inner one.
+func encapsulate(f *filter.PacketFilter, spec packet.Spec, in *input) packet.Spec {
+	match := func() bool { return in.choose(4) != 0 }
+	tunnel := filter.Tunnels[in.choose(len(filter.Tunnels))]
+	if f.Tunnel != "" && match() {
+		tunnel = f.Tunnel
+	}
+
+	outer := spec
+	if f.Inner {
+		inner := spec
+		inner.VLAN, inner.VLANID = false, 0
+		outer = packet.Spec{
+			SrcIP:         ipString(in.uint32()),
+			DstIP:         ipString(in.uint32()),
+			SrcPort:       int(in.uint16()),
+			VLAN:          spec.VLAN,
+			VLANID:        spec.VLANID,
+			MoreFragments: in.choose(8) == 0,
+			Inner:         &inner,
+		}
+	} else {
+		protocols := []string{"tcp", "udp", "icmp"}
+		outer.Inner = &packet.Spec{
+			Protocol: protocols[in.choose(3)],
+			SrcIP:    ipString(in.uint32()),
+			DstIP:    ipString(in.uint32()),
+			SrcPort:  int(in.uint16()),
+			DstPort:  int(in.uint16()),
+		}
+	}
+
+	outer.Protocol, outer.EtherType, outer.DstPort = "udp", 0, 0
+	outer.Tunnel = string(tunnel)
+	outer.VNI = int(in.uint32()) & filter.MaxVNI
+	if f.VNI != 0 && match() {
+		outer.VNI = f.VNI
+	}
+	if tunnel == filter.TunnelGeneve && in.choose(4) == 0 {
+		outer.GeneveOptions = in.choose(64)
+	}
+	// Now and then another service on the tunnel's port
+	if in.choose(16) == 0 {
+		outer.DstPort = int(in.uint16())
+	}
+	return outer
+}
+
+// mutate applies the header changes the packet builder cannot express
+func mutate(frame []byte, link filter.LinkType, in *input) []byte {
+	ipStart := link.HeaderLen()
+	if link.IsEthernet() {
+		switch binary.BigEndian.Uint16(frame[12:]) {
+		case 0x8100, 0x88a8, 0x9100:
+			ipStart += packet.VLANTagLen
+		}
+	}
+	l4Start := ipStart + packet.IPv4HeaderLen
+
+	// ARP and other non-IP frames only get the link-layer changes
+	ipv4 := len(frame) >= l4Start &&
+		(link == filter.LinkRaw || link == filter.LinkNull || binary.BigEndian.Uint16(frame[ipStart-2:]) == 0x0800)
+
+	// IP options move the transport header
+	if ipv4 && in.choose(8) == 0 {
+		options := []byte{0x01, 0x01, 0x01, 0x00} // NOP, NOP, NOP, end of options
+		frame = append(frame[:l4Start:l4Start], append(options, frame[l4Start:]...)...)
+		frame[ipStart] = 0x46
+		binary.BigEndian.PutUint16(frame[ipStart+2:], uint16(len(frame)-ipStart))
+	}
+
+	// Other transports keep the port bytes in place: sctp, gre or random
+	if ipv4 && in.choose(8) == 0 {
+		protocols := []byte{132, 47, in.byte()}
+		frame[ipStart+9] = protocols[in.choose(len(protocols))]
+	}
+
+	// Non-IPv4 link-layer protocols, replacing the outer TPID of tagged
+	// frames with another (802.1ad, QinQ) or a protocol, or other IP
+	// versions on raw links
+	if in.choose(8) == 0 {
+		switch link {
+		case filter.LinkRaw:
+			versions := []byte{0x60, 0x00, in.byte() & 0xf0}
+			frame[0] = versions[in.choose(len(versions))] | frame[0]&0x0f
+		case filter.LinkNull:
+			families := []uint32{10, 24, 28, 30, in.uint32()}
+			binary.NativeEndian.PutUint32(frame[0:], families[in.choose(len(families))])
+		default:
+			etherTypes := []uint16{0x86dd, 0x0806, 0x8100, 0x88a8, 0x9100, in.uint16()}
+			binary.BigEndian.PutUint16(frame[link.HeaderLen()-2:], etherTypes[in.choose(len(etherTypes))])
+		}
+	}
+
+	// Truncated captures end before the loaded fields
+	if in.choose(8) == 0 {
+		frame = frame[:1+in.choose(len(frame))]
+	}
+	return frame
+}
+
+// addressIn returns a random address inside the host or network s
+func addressIn(s string, in *input) string {
+	ipnet, err := filter.ParseNet(s)
+	if err != nil {
+		panic(fmt.Sprintf("fuzz: filter network %s: %v", s, err))
+	}
+	network := binary.BigEndian.Uint32(ipnet.IP.To4())
+	mask := binary.BigEndian.Uint32(ipnet.Mask)
+	return ipString(network | in.uint32()&^mask)
+}
+
+// ipString formats an IPv4 address held in a number
+func ipString(addr uint32) string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, addr)
+	return ip.String()
+}
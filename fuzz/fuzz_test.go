@@ -0,0 +1,14 @@
+package fuzz
+
+import "testing"
+
+// FuzzFilters checks the case decoded from each input. The seed corpus in
+// testdata/fuzz/FuzzFilters runs with go test; go test -fuzz=FuzzFilters
+// explores from it.
+func FuzzFilters(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := Check(CaseFromBytes(data)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
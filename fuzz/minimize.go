@@ -0,0 +1,78 @@
+package fuzz
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// Minimize shrinks a packet for which fails holds into the smallest one
+// found that still fails: it cuts the packet to the shortest length that
+// fails, then zeroes every byte the failure does not depend on, and
+// repeats until neither changes the packet. The packet is not modified.
+func Minimize(pkt []byte, fails func([]byte) bool) []byte {
+	pkt = slices.Clone(pkt)
+	for changed := true; changed; {
+		changed = false
+		for n := 0; n < len(pkt); n++ {
+			if fails(pkt[:n]) {
+				pkt, changed = pkt[:n], true
+				break
+			}
+		}
+		for i, b := range pkt {
+			if b == 0 {
+				continue
+			}
+			pkt[i] = 0
+			if fails(pkt) {
+				changed = true
+			} else {
+				pkt[i] = b
+			}
+		}
+	}
+	return pkt
+}
+
+// outcome is what the two programs and the Go predicate make of a packet.
+// Two packets fail the same way when their outcomes are equal: a program
+// that reached a return does so again, so cutting a packet cannot turn a
+// wrong verdict into a load past its end.
+type outcome struct {
+	reference, prototype ending
+	spec, judged         bool
+}
+
+// ending is how a program ended over a packet
+type ending struct {
+	accepted, aborted bool
+}
+
+// decideOutcome runs both programs over the packet and asks the Go
+// predicate, where it can judge the packet
+func decideOutcome(f *filter.PacketFilter, reference, prototype []*bpf.Instruction, pkt []byte) (outcome, error) {
+	want, err := vm.Run(reference, pkt)
+	if err != nil {
+		return outcome{}, fmt.Errorf("reference program: %w", err)
+	}
+	got, err := vm.Run(prototype, pkt)
+	if err != nil {
+		return outcome{}, fmt.Errorf("prototype program: %w", err)
+	}
+	o := outcome{
+		reference: ending{accepted: want.Accepted, aborted: want.Aborted},
+		prototype: ending{accepted: got.Accepted, aborted: got.Aborted},
+	}
+	o.spec, o.judged = Spec(f, pkt)
+	return o, nil
+}
+
+// failed reports whether the programs disagree, or agree and the Go
+// predicate does not
+func (o outcome) failed() bool {
+	return o.reference.accepted != o.prototype.accepted || o.judged && o.spec != o.reference.accepted
+}
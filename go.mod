@@ -1,3 +1,26 @@
-module antrea-bpf-prototype
+module github.com/imshubham22apr-gif/Antrea-Project-Prototype
 
-go 1.21
\ No newline at end of file
+go 1.21
+
+require (
+	github.com/cilium/ebpf v0.16.0
+	github.com/google/gopacket v1.1.19
+	github.com/prometheus/client_golang v1.19.1
+	golang.org/x/sys v0.21.0
+	google.golang.org/grpc v1.66.3
+	google.golang.org/protobuf v1.34.2
+	gopkg.in/yaml.v3 v3.0.1
+	pgregory.net/rapid v1.1.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/exp v0.0.0-20230224173230-c95f2b4c22f2 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240604185151-ef581f913117 // indirect
+)
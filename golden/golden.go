@@ -0,0 +1,261 @@
+// Package golden guards generated BPF against unintended changes. A golden
+// directory holds a cases.yaml list of filters and, for each case, the
+// expected prototype listing (NAME.prototype) and the expected reference
+// program in tcpdump -ddd format (NAME.SOURCE.ddd). The reference file is
+// keyed by the compiler that produced it (mock, tcpdump or libpcap), since
+// each emits different code, and is only checked against the same source.
+// Each prototype is also generated several times and must come out
+// byte-identical, since diffs and baselines rely on deterministic output.
+package golden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// CasesFile is the name of the case list inside a golden directory
+const CasesFile = "cases.yaml"
+
+// determinismRuns is how many times each prototype is generated. Go
+// randomizes map iteration on every range, so order-dependent code shows
+// up within a few runs.
+const determinismRuns = 8
+
+// Golden file statuses
+const (
+	StatusMatch    = "ok"
+	StatusMismatch = "MISMATCH"
+	StatusMissing  = "missing"
+	StatusUpdated  = "updated"
+)
+
+// Case is one filter of the case list. The filter fields are inline.
+type Case struct {
+	Name                string `yaml:"name"`
+	filter.PacketFilter `yaml:",inline"`
+}
+
+// Result is the outcome of checking one golden file
+type Result struct {
+	Case   string
+	File   string // golden file name within the directory
+	Status string
+	Diff   string // line differences when the status is StatusMismatch
+	Err    error
+
+	// Optional marks reference files, whose absence only means the
+	// current reference compiler has not been recorded yet
+	Optional bool
+}
+
+// Passed reports whether the file matched or was rewritten, or is an
+// optional file that has not been recorded
+func (r *Result) Passed() bool {
+	if r.Err != nil || r.Status == StatusMismatch {
+		return false
+	}
+	return r.Status != StatusMissing || r.Optional
+}
+
+// caseName restricts names to characters that are safe in file names
+var caseName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Load reads the case list of a golden directory
+func Load(dir string) ([]*Case, error) {
+	path := filepath.Join(dir, CasesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden cases: %w", err)
+	}
+
+	var cases []*Case
+	if err := yaml.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to parse golden cases %s: %w", path, err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("golden cases %s contains no cases", path)
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range cases {
+		if !caseName.MatchString(c.Name) {
+			return nil, fmt.Errorf("%s: case %d: name '%s' must be lowercase letters, digits and dashes", path, i+1, c.Name)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("%s: duplicate case name '%s'", path, c.Name)
+		}
+		seen[c.Name] = true
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: case %s: %w", path, c.Name, err)
+		}
+	}
+	return cases, nil
+}
+
+// Run generates both programs for every case and compares them with the
+// golden files in dir, or rewrites the files when update is set
+func Run(dir string, cases []*Case, update bool) []*Result {
+	var results []*Result
+	for _, c := range cases {
+		results = append(results, c.run(dir, update)...)
+	}
+	return results
+}
+
+// run checks the case's prototype and reference golden files
+func (c *Case) run(dir string, update bool) []*Result {
+	var results []*Result
+
+	protoFile := c.Name + ".prototype"
+	proto, err := bpfgen.GenerateBPF(&c.PacketFilter)
+	if err != nil {
+		err = fmt.Errorf("failed to generate prototype BPF: %w", err)
+	} else {
+		err = c.checkDeterministic(proto)
+	}
+	if err != nil {
+		results = append(results, &Result{Case: c.Name, File: protoFile, Err: err})
+	} else {
+		results = append(results, check(dir, c.Name, protoFile, bpf.Disassemble(proto.Instructions), update))
+	}
+
+	reference, err := tcpdump.GenerateBPF(context.Background(), &c.PacketFilter)
+	if err != nil {
+		results = append(results, &Result{Case: c.Name, File: c.Name + ".ddd", Err: fmt.Errorf("failed to generate tcpdump BPF: %w", err)})
+	} else {
+		r := check(dir, c.Name, c.Name+"."+reference.Source+".ddd", bpf.FormatDDD(reference.Instructions), update)
+		r.Optional = true
+		results = append(results, r)
+	}
+	return results
+}
+
+// checkDeterministic generates the prototype again and requires the same
+// instructions, source map and optimizations as the first program
+func (c *Case) checkDeterministic(first *bpfgen.BPFCode) error {
+	want := fingerprint(first)
+	for run := 2; run <= determinismRuns; run++ {
+		again, err := bpfgen.GenerateBPF(&c.PacketFilter)
+		if err != nil {
+			return fmt.Errorf("failed to generate prototype BPF on run %d: %w", run, err)
+		}
+		if got := fingerprint(again); got != want {
+			return fmt.Errorf("nondeterministic generation, run %d differs from run 1:\n%s", run, diff(want, got))
+		}
+	}
+	return nil
+}
+
+// fingerprint lists everything deterministic generation must reproduce
+func fingerprint(code *bpfgen.BPFCode) string {
+	return bpf.FormatDDD(code.Instructions) + strings.Join(code.Sources, "\n") + "\n" + strings.Join(code.Optimizations, "\n") + "\n"
+}
+
+// check compares got with the golden file, or writes it when updating
+func check(dir, name, file, got string, update bool) *Result {
+	result := &Result{Case: name, File: file}
+	path := filepath.Join(dir, file)
+
+	if update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			result.Err = fmt.Errorf("failed to write golden file: %w", err)
+			return result
+		}
+		result.Status = StatusUpdated
+		return result
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		result.Status = StatusMissing
+		return result
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed to read golden file: %w", err)
+		return result
+	}
+
+	if string(want) == got {
+		result.Status = StatusMatch
+		return result
+	}
+	result.Status = StatusMismatch
+	result.Diff = diff(string(want), got)
+	return result
+}
+
+// diff lists the lines that differ by position, prefixing the golden line
+// with "-" and the generated one with "+"
+func diff(want, got string) string {
+	wantLines := strings.Split(strings.TrimRight(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimRight(got, "\n"), "\n")
+
+	var sb strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		if i < len(wantLines) {
+			sb.WriteString(fmt.Sprintf("- %s\n", w))
+		}
+		if i < len(gotLines) {
+			sb.WriteString(fmt.Sprintf("+ %s\n", g))
+		}
+	}
+	return sb.String()
+}
+
+// Report formats results as a table followed by the differences of every
+// mismatched file
+func Report(results []*Result) string {
+	var sb strings.Builder
+	passed, missing := 0, 0
+
+	for _, r := range results {
+		status := r.Status
+		if r.Err != nil {
+			status = "ERROR"
+		}
+		if r.Passed() {
+			passed++
+		}
+		if r.Status == StatusMissing {
+			missing++
+		}
+		sb.WriteString(fmt.Sprintf("%-9s %s\n", status, r.File))
+	}
+
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			sb.WriteString(fmt.Sprintf("\n%s: %v\n", r.File, r.Err))
+		case r.Status == StatusMismatch:
+			sb.WriteString(fmt.Sprintf("\n%s:\n%s", r.File, r.Diff))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d/%d golden files passed", passed, len(results)))
+	if missing > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d missing, record them with --update)", missing))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files instead of checking them")
+
+// goldenDir is the repository's golden directory, relative to this package
+const goldenDir = "../testdata/golden"
+
+// TestGolden checks every case in the golden directory as a subtest, or
+// rewrites its files with -update
+func TestGolden(t *testing.T) {
+	cases, err := Load(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			for _, r := range c.run(goldenDir, *update) {
+				switch {
+				case r.Err != nil:
+					t.Errorf("%s: %v", r.File, r.Err)
+				case r.Status == StatusMismatch:
+					t.Errorf("%s differs from the generated program:\n%s", r.File, r.Diff)
+				case r.Status == StatusMissing && !r.Optional:
+					t.Errorf("%s: no golden file, run with -update to record it", r.File)
+				case r.Status == StatusMissing:
+					t.Logf("%s: no golden file, run with -update to record it", r.File)
+				}
+			}
+		})
+	}
+}
+
+// TestDeterministic generates every case's prototype repeatedly and
+// requires each run to be byte-identical to the committed snapshot
+func TestDeterministic(t *testing.T) {
+	cases, err := Load(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			snapshot, err := os.ReadFile(filepath.Join(goldenDir, c.Name+".prototype"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for run := 1; run <= determinismRuns; run++ {
+				code, err := bpfgen.GenerateBPF(&c.PacketFilter)
+				if err != nil {
+					t.Fatalf("run %d: %v", run, err)
+				}
+				if got := bpf.Disassemble(code.Instructions); got != string(snapshot) {
+					t.Fatalf("run %d differs from the snapshot:\n%s", run, diff(string(snapshot), got))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package ids checks tcpdump expressions against the way IDS sensors
+// compile their capture filters, for teams that feed the same expression
+// to Suricata or Zeek. Both hand the expression to libpcap, but Suricata's
+// af-packet capture compiles it without a live handle and attaches it to
+// the socket itself, and both link against whatever libpcap the sensor
+// host ships, so some constructs tcpdump accepts are rejected or quietly
+// match nothing. The check is a lint over the expression's words, not a
+// parse: it does not decide whether the expression is valid.
+package ids
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Sensor is an IDS whose filter handling is checked
+type Sensor string
+
+const (
+	// SensorSuricata is Suricata capturing with af-packet, its default on
+	// Linux, which compiles the filter for Ethernet without a live handle
+	SensorSuricata Sensor = "suricata"
+
+	// SensorZeek is Zeek capturing with its default libpcap packet
+	// source, which compiles the filter on the live handle
+	SensorZeek Sensor = "zeek"
+)
+
+// Sensors lists the supported sensors
+var Sensors = []Sensor{SensorSuricata, SensorZeek}
+
+// ParseSensor parses a sensor name, ignoring case
+func ParseSensor(s string) (Sensor, error) {
+	for _, sensor := range Sensors {
+		if strings.EqualFold(s, string(sensor)) {
+			return sensor, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sensor '%s' (want suricata or zeek)", s)
+}
+
+// Level is how badly a sensor handles a construct
+type Level string
+
+const (
+	LevelError   Level = "error"   // the sensor fails to compile the filter
+	LevelWarning Level = "warning" // the sensor compiles it, but it may match differently or not everywhere
+)
+
+// Finding is a construct one sensor rejects or handles differently
+type Finding struct {
+	Sensor    Sensor
+	Level     Level
+	Construct string // the word of the expression, e.g. "portrange"
+	Message   string
+}
+
+// String formats the finding, e.g. "suricata: error: vxlan: ..."
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", f.Sensor, f.Level, f.Construct, f.Message)
+}
+
+// rule flags a construct for some sensors
+type rule struct {
+	keywords []string         // words that are the construct
+	levels   map[Sensor]Level // sensors that mind it, and how much
+	message  string
+}
+
+// rules lists the constructs sensors reject or handle differently
+var rules = []rule{
+	{
+		keywords: []string{"vxlan"},
+		levels:   map[Sensor]Level{SensorSuricata: LevelError, SensorZeek: LevelError},
+		message:  "no libpcap release up to 1.10 has the vxlan primitive; match UDP port 4789 instead",
+	},
+	{
+		keywords: []string{"geneve"},
+		levels:   map[Sensor]Level{SensorSuricata: LevelWarning, SensorZeek: LevelWarning},
+		message:  "needs libpcap 1.8 or later, which older sensor hosts (libpcap 1.5 on RHEL 7) lack",
+	},
+	{
+		keywords: []string{"vlan"},
+		levels:   map[Sensor]Level{SensorSuricata: LevelWarning},
+		message:  "af-packet compiles the filter without a live handle, so the tag is looked for in the frame, where the kernel has removed it; tagged traffic does not match",
+	},
+	{
+		keywords: []string{"inbound", "outbound"},
+		levels:   map[Sensor]Level{SensorSuricata: LevelError},
+		message:  "libpcap supports the packet direction only on a live Linux handle, not on the Ethernet link type af-packet compiles for",
+	},
+	{
+		keywords: []string{"on", "ifname", "rnr", "rulenum", "reason", "action", "rset", "ruleset", "srnr", "subrulenum"},
+		levels:   map[Sensor]Level{SensorSuricata: LevelError, SensorZeek: LevelError},
+		message:  "pflog primitives only compile for OpenBSD pflog captures, not Ethernet",
+	},
+	{
+		keywords: []string{"portrange"},
+		levels:   map[Sensor]Level{SensorSuricata: LevelWarning, SensorZeek: LevelWarning},
+		message:  "not portable: filter offloads that turn expressions into NIC rules take single ports only; use a port list",
+	},
+}
+
+// Check lints the expression for each sensor, or for all of them when
+// none is given, and returns what each rejects or handles differently, in
+// the order of the expression
+func Check(expr string, sensors ...Sensor) []Finding {
+	if len(sensors) == 0 {
+		sensors = Sensors
+	}
+	words := tokenize(expr)
+	var findings []Finding
+	for i, word := range words {
+		for _, r := range rules {
+			if !slices.Contains(r.keywords, word) {
+				continue
+			}
+			for _, s := range sensors {
+				if level, ok := r.levels[s]; ok {
+					findings = append(findings, Finding{Sensor: s, Level: level, Construct: word, Message: r.message})
+				}
+			}
+		}
+		if i > 0 && slices.Contains([]string{"host", "net", "gateway"}, words[i-1]) && isName(word) {
+			for _, s := range sensors {
+				findings = append(findings, Finding{
+					Sensor:    s,
+					Level:     LevelWarning,
+					Construct: word,
+					Message:   "host names are resolved once when the sensor starts, and a sensor without DNS fails to compile the filter; use the address",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// Failed reports whether any sensor rejects the expression
+func Failed(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits the expression into lowercase words, taking parentheses
+// and negation apart from the words they touch
+func tokenize(expr string) []string {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ", "!", " ! ").Replace(strings.ToLower(expr))
+	return strings.Fields(expr)
+}
+
+// isName reports whether a host or net value is a name rather than an
+// address, network, Ethernet address or number
+func isName(word string) bool {
+	if addr, _, ok := strings.Cut(word, "/"); ok {
+		word = addr
+	}
+	if net.ParseIP(word) != nil {
+		return false
+	}
+	if _, err := net.ParseMAC(word); err == nil {
+		return false
+	}
+	// libpcap takes abbreviated networks such as "10" or "10.1"
+	return strings.Trim(word, "0123456789.") != "" && word != "(" && word != "!"
+}
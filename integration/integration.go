@@ -0,0 +1,243 @@
+// Package integration runs test-case suites end to end through the Linux
+// kernel. Each case's packets are sent through a veth pair in a throwaway
+// network namespace, with the reference and the prototype attached to
+// packet sockets and the generated eBPF program to an XDP hook, and every
+// verdict is checked against the packet's expected match. It is the
+// counterpart of package testcase, which runs the same suites in the
+// interpreter.
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/kernel"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ebpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/testcase"
+)
+
+// PacketResult records what the kernel did with one packet of a case
+type PacketResult struct {
+	Name     string
+	Expected bool
+
+	// Arrived is false when the kernel refused to send the packet or
+	// dropped it on receive; no verdict is checked then
+	Arrived bool
+
+	Reference bool // received by the reference's socket
+	Prototype bool // received by the prototype's socket
+	XDP       bool // matched by the eBPF program on the XDP hook
+	Err       error
+}
+
+// CaseResult is the outcome of running one case through the kernel
+type CaseResult struct {
+	Name            string
+	ReferenceMocked bool
+	Packets         []*PacketResult
+
+	// Skipped says why the case was not sent through the kernel, and
+	// XDPSkipped why its eBPF program was not checked; "" when they were
+	Skipped    string
+	XDPSkipped string
+
+	Err error
+}
+
+// Passed reports whether the prototype's socket, and the XDP program when
+// checked, decided every packet that arrived as expected
+func (p *PacketResult) Passed(xdp bool) bool {
+	if p.Err != nil {
+		return false
+	}
+	if !p.Arrived {
+		return true
+	}
+	return p.Prototype == p.Expected && (!xdp || p.XDP == p.Expected)
+}
+
+// Passed reports whether the case met every expectation it was checked
+// for
+func (r *CaseResult) Passed() bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, p := range r.Packets {
+		if !p.Passed(r.XDPSkipped == "") {
+			return false
+		}
+	}
+	return true
+}
+
+// Run sends every case of the suite through the kernel. It fails with an
+// error wrapping kernel.ErrUnavailable when the kernel cannot be used
+// here, so that callers can skip rather than fail.
+func Run(suite *testcase.Suite) ([]*CaseResult, error) {
+	results := make([]*CaseResult, 0, len(suite.Cases))
+	for _, c := range suite.Cases {
+		result := runCase(c)
+		if errors.Is(result.Err, kernel.ErrUnavailable) {
+			return nil, result.Err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runCase generates the case's programs and sends its packets through the
+// kernel once for the sockets and once for the XDP hook
+func runCase(c *testcase.Case) *CaseResult {
+	result := &CaseResult{Name: c.Name}
+	f := c.Filter
+	if err := f.Validate(); err != nil {
+		result.Err = fmt.Errorf("invalid filter: %w", err)
+		return result
+	}
+	if !f.LinkType.IsEthernet() {
+		result.Skipped = fmt.Sprintf("%s packets cannot be sent through a veth pair", f.LinkType)
+		return result
+	}
+	if f.VLAN {
+		// The programs read the tag in the frame, which libpcap would
+		// rewrite to read the packet metadata
+		result.Skipped = "the kernel takes the 802.1Q tag out of a frame before socket filters see it"
+		return result
+	}
+
+	tcpdumpBPF, err := tcpdump.GenerateBPF(context.Background(), &f)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to generate tcpdump BPF: %w", err)
+		return result
+	}
+	result.ReferenceMocked = tcpdumpBPF.IsMocked
+	prototypeBPF, err := bpfgen.GenerateBPFWithOptions(&f, bpfgen.Options{Fragments: c.Fragments})
+	if err != nil {
+		result.Err = fmt.Errorf("failed to generate prototype BPF: %w", err)
+		return result
+	}
+	program, err := ebpf.Generate(&f, ebpf.TargetXDP)
+	switch {
+	case err != nil:
+		result.XDPSkipped = err.Error()
+	case c.Fragments != "" && c.Fragments != bpfgen.FragmentsMatchFirst:
+		result.XDPSkipped = fmt.Sprintf("the eBPF program follows the %s policy, not %s", bpfgen.FragmentsMatchFirst, c.Fragments)
+	}
+
+	// The traffic is built with gopacket rather than the in-tree builder
+	// the interpreter runs use. The kernel runs leave out copies, so only
+	// distinct packets are sent and the verdicts are looked up by content
+	var corpus [][]byte
+	position := make(map[string]int)
+	data := make([][]byte, len(c.Packets))
+	for i, p := range c.Packets {
+		pr := &PacketResult{Name: p.Name, Expected: p.Match}
+		result.Packets = append(result.Packets, pr)
+		if data[i], pr.Err = caseFrame(p); pr.Err != nil {
+			continue
+		}
+		if _, ok := position[string(data[i])]; !ok {
+			position[string(data[i])] = len(corpus)
+			corpus = append(corpus, data[i])
+		}
+	}
+	if len(corpus) == 0 {
+		return result
+	}
+
+	sockets, err := kernel.Run(tcpdumpBPF.Instructions, prototypeBPF.Instructions, f.LinkType, corpus)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	var xdp *kernel.XDPResult
+	if result.XDPSkipped == "" {
+		if xdp, err = kernel.RunXDP(program, prototypeBPF.Instructions, corpus); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	for i, pr := range result.Packets {
+		if pr.Err != nil {
+			continue
+		}
+		v := sockets.Packets[position[string(data[i])]]
+		pr.Arrived = v.Sent && v.Arrived
+		pr.Reference, pr.Prototype = v.Reference, v.Prototype
+		if xdp != nil {
+			x := xdp.Packets[position[string(data[i])]]
+			pr.Arrived = pr.Arrived && x.Sent && x.Arrived
+			pr.XDP = x.Matched
+		}
+	}
+	return result
+}
+
+// Report formats case results as a human-readable summary, in the layout
+// of testcase.Report with the XDP verdict added
+func Report(results []*CaseResult) string {
+	var sb strings.Builder
+	passed := 0
+
+	for _, r := range results {
+		status := "FAIL"
+		switch {
+		case r.Skipped != "":
+			status = "SKIP"
+			passed++
+		case r.Passed():
+			status = "PASS"
+			passed++
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s\n", status, r.Name))
+
+		switch {
+		case r.Skipped != "":
+			sb.WriteString(fmt.Sprintf("    skipped: %s\n", r.Skipped))
+			continue
+		case r.Err != nil:
+			sb.WriteString(fmt.Sprintf("    error: %v\n", r.Err))
+			continue
+		case r.XDPSkipped != "":
+			sb.WriteString(fmt.Sprintf("    XDP skipped: %s\n", r.XDPSkipped))
+		}
+
+		for _, p := range r.Packets {
+			if p.Err != nil {
+				sb.WriteString(fmt.Sprintf("    %s: error: %v\n", p.Name, p.Err))
+				continue
+			}
+			if !p.Arrived {
+				sb.WriteString(fmt.Sprintf("    %-24s expected=%-5t refused by the kernel\n", p.Name, p.Expected))
+				continue
+			}
+			mark := "ok"
+			if !p.Passed(r.XDPSkipped == "") {
+				mark = "MISMATCH"
+			}
+			xdp := "-"
+			if r.XDPSkipped == "" {
+				xdp = fmt.Sprintf("%t", p.XDP)
+			}
+			refNote := ""
+			if p.Reference != p.Expected {
+				if r.ReferenceMocked {
+					refNote = " (reference disagrees, mock data)"
+				} else {
+					refNote = " (reference disagrees)"
+				}
+			}
+			sb.WriteString(fmt.Sprintf("    %-24s expected=%-5t prototype=%-5t reference=%-5t xdp=%-5s %s%s\n",
+				p.Name, p.Expected, p.Prototype, p.Reference, xdp, mark, refNote))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d/%d cases passed\n", passed, len(results)))
+	return sb.String()
+}
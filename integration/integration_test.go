@@ -0,0 +1,35 @@
+//go:build integration
+
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/kernel"
+)
+
+// TestKernel sends every suite under testcases through the kernel. It
+// needs root and a Linux kernel with veth and XDP support, and skips where
+// they are missing:
+//
+//	sudo go test -tags integration ./integration
+func TestKernel(t *testing.T) {
+	for name, suite := range loadSuites(t) {
+		suite := suite
+		t.Run(name, func(t *testing.T) {
+			results, err := Run(suite)
+			if errors.Is(err, kernel.ErrUnavailable) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				if r.Skipped == "" && !r.Passed() {
+					t.Errorf("case %s failed:\n%s", r.Name, Report([]*CaseResult{r}))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package integration
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/testcase"
+)
+
+// Addresses the frames are sent between, the defaults of packet.Spec
+var (
+	srcMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	dstMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+// Frame builds the Ethernet frame a spec describes with gopacket, so that
+// the traffic sent through the kernel is serialized independently of the
+// in-tree builder the interpreter runs use. Lengths and checksums are
+// computed as a real stack would. Geneve headers, which gopacket cannot
+// serialize, are written by hand in front of the inner frame.
+func Frame(s *packet.Spec) ([]byte, error) {
+	// The in-tree builder checks the spec's ranges and combinations
+	if _, err := s.Build(); err != nil {
+		return nil, err
+	}
+
+	ls, err := specLayers(s)
+	if err != nil {
+		return nil, err
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ls...); err != nil {
+		return nil, fmt.Errorf("failed to serialize packet: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// specLayers returns the layers of the frame, outermost first
+func specLayers(s *packet.Spec) ([]gopacket.SerializableLayer, error) {
+	if s.Tunnel != "" {
+		return tunnelLayers(s)
+	}
+
+	eth := &layers.Ethernet{SrcMAC: srcMAC, DstMAC: dstMAC}
+	ls := []gopacket.SerializableLayer{eth}
+	link := &eth.EthernetType
+	if s.VLAN || s.VLANID != 0 {
+		tag := &layers.Dot1Q{VLANIdentifier: uint16(s.VLANID)}
+		eth.EthernetType = layers.EthernetTypeDot1Q
+		ls = append(ls, tag)
+		link = &tag.Type
+	}
+
+	protocol := strings.ToLower(s.Protocol)
+	switch {
+	case s.EtherType != 0:
+		*link = layers.EthernetType(s.EtherType)
+		return append(ls, gopacket.Payload(s.Payload)), nil
+	case protocol == "arp" || protocol == "rarp":
+		*link = layers.EthernetTypeARP
+		if protocol == "rarp" {
+			*link = 0x8035
+		}
+		return append(ls, arp(s, protocol)), nil
+	}
+	*link = layers.EthernetTypeIPv4
+	ip, l4, err := ipv4Layers(s, protocol)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(ls, ip), l4...), nil
+}
+
+// ipv4Layers returns the IPv4 header and the transport layers after it
+func ipv4Layers(s *packet.Spec, protocol string) (*layers.IPv4, []gopacket.SerializableLayer, error) {
+	number, err := ipProtocol(protocol)
+	if err != nil {
+		return nil, nil, err
+	}
+	ip := &layers.IPv4{
+		Version:    4,
+		Id:         1,
+		TTL:        64,
+		Protocol:   layers.IPProtocol(number),
+		SrcIP:      address(s.SrcIP, "10.0.0.1"),
+		DstIP:      address(s.DstIP, "10.0.0.2"),
+		FragOffset: uint16(s.FragOff),
+	}
+	if s.MoreFragments {
+		ip.Flags = layers.IPv4MoreFragments
+	}
+
+	payload := gopacket.Payload(s.Payload)
+	switch ip.Protocol {
+	case layers.IPProtocolTCP:
+		tcp := &layers.TCP{SrcPort: layers.TCPPort(s.SrcPort), DstPort: layers.TCPPort(s.DstPort), SYN: true, Window: 65535}
+		if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+			return nil, nil, err
+		}
+		return ip, []gopacket.SerializableLayer{tcp, payload}, nil
+	case layers.IPProtocolUDP:
+		udp := &layers.UDP{SrcPort: layers.UDPPort(s.SrcPort), DstPort: layers.UDPPort(s.DstPort)}
+		if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+			return nil, nil, err
+		}
+		return ip, []gopacket.SerializableLayer{udp, payload}, nil
+	case layers.IPProtocolICMPv4:
+		icmp := &layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0)}
+		return ip, []gopacket.SerializableLayer{icmp, payload}, nil
+	case layers.IPProtocolSCTP:
+		sctp := &layers.SCTP{SrcPort: layers.SCTPPort(s.SrcPort), DstPort: layers.SCTPPort(s.DstPort)}
+		return ip, []gopacket.SerializableLayer{sctp, payload}, nil
+	}
+	return ip, []gopacket.SerializableLayer{payload}, nil
+}
+
+// tunnelLayers returns the layers of a VXLAN or Geneve packet: the outer
+// UDP packet, the tunnel header and the layers of the inner frame
+func tunnelLayers(s *packet.Spec) ([]gopacket.SerializableLayer, error) {
+	tunnel, err := filter.ParseTunnel(s.Tunnel)
+	if err != nil {
+		return nil, err
+	}
+	inner := s.Inner
+	if inner == nil {
+		inner = &packet.Spec{}
+	}
+	innerLayers, err := specLayers(inner)
+	if err != nil {
+		return nil, fmt.Errorf("inner packet: %w", err)
+	}
+
+	outer := *s
+	outer.Tunnel, outer.VNI, outer.GeneveOptions, outer.Inner = "", 0, 0, nil
+	outer.Protocol, outer.Payload = "udp", ""
+	if outer.DstPort == 0 {
+		outer.DstPort = tunnel.Port()
+	}
+	ls, err := specLayers(&outer)
+	if err != nil {
+		return nil, err
+	}
+	ls = ls[:len(ls)-1] // the empty outer payload
+
+	if tunnel == filter.TunnelGeneve {
+		ls = append(ls, geneve(s.VNI, s.GeneveOptions))
+	} else {
+		ls = append(ls, &layers.VXLAN{ValidIDFlag: true, VNI: uint32(s.VNI)})
+	}
+	ls = append(ls, innerLayers...)
+	return append(ls, gopacket.Payload(s.Payload)), nil
+}
+
+// geneve writes a Geneve header carrying Ethernet, with one experimental
+// option filling the option space as packet.Spec does
+func geneve(vni, options int) gopacket.Payload {
+	header := make([]byte, filter.TunnelHeaderLen+4*options)
+	header[0] = byte(options)                      // version 0 and option length
+	binary.BigEndian.PutUint16(header[2:], 0x6558) // Transparent Ethernet Bridging
+	if options > 0 {
+		binary.BigEndian.PutUint16(header[8:], 0xffff)
+		header[11] = byte(options - 1)
+	}
+	binary.BigEndian.PutUint32(header[4:], uint32(vni)<<8)
+	return header
+}
+
+// arp builds an ARP request from the source address asking for the
+// destination, or for rarp a reverse request for the source MAC
+func arp(s *packet.Spec, protocol string) *layers.ARP {
+	a := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   srcMAC,
+		SourceProtAddress: address(s.SrcIP, "10.0.0.1"),
+		DstHwAddress:      make(net.HardwareAddr, 6),
+		DstProtAddress:    address(s.DstIP, "10.0.0.2"),
+	}
+	if protocol == "rarp" {
+		a.Operation = 3 // reverse request
+		a.SourceProtAddress = make(net.IP, 4)
+		a.DstHwAddress = srcMAC
+		a.DstProtAddress = make(net.IP, 4)
+	}
+	return a
+}
+
+// ipProtocol returns the IP protocol number of a transport name or a
+// number 0-255, TCP when it is empty
+func ipProtocol(protocol string) (uint8, error) {
+	switch protocol {
+	case "", "tcp":
+		return 6, nil
+	case "udp":
+		return 17, nil
+	case "icmp":
+		return 1, nil
+	}
+	number, err := strconv.ParseUint(protocol, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported packet protocol '%s'", protocol)
+	}
+	return uint8(number), nil
+}
+
+// address parses an IPv4 address already checked by packet.Spec, using
+// def when the value is empty
+func address(value, def string) net.IP {
+	if value == "" {
+		value = def
+	}
+	return net.ParseIP(value).To4()
+}
+
+// caseFrame returns a case's packet as sent through the kernel: hex
+// packets as they are, and packets given as fields built by Frame
+func caseFrame(p *testcase.Packet) ([]byte, error) {
+	if p.Fields == nil {
+		return p.Bytes(filter.LinkEN10MB)
+	}
+	return Frame(p.Fields)
+}
@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/testcase"
+)
+
+// loadSuites loads every suite under testcases
+func loadSuites(t *testing.T) map[string]*testcase.Suite {
+	t.Helper()
+	paths, err := filepath.Glob("../testcases/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no test-case suites found: %v", err)
+	}
+	suites := make(map[string]*testcase.Suite)
+	for _, path := range paths {
+		suite, err := testcase.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suites[filepath.Base(path)] = suite
+	}
+	return suites
+}
+
+// TestFrames checks that the frames gopacket builds for the Ethernet cases
+// get the expected verdict from the prototype in the interpreter, as the
+// in-tree builder's do
+func TestFrames(t *testing.T) {
+	for name, suite := range loadSuites(t) {
+		for _, c := range suite.Cases {
+			if !c.Filter.LinkType.IsEthernet() {
+				continue
+			}
+			c := c
+			t.Run(name+"/"+c.Name, func(t *testing.T) {
+				prog, err := bpfgen.GenerateBPFWithOptions(&c.Filter, bpfgen.Options{Fragments: c.Fragments})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, p := range c.Packets {
+					data, err := caseFrame(p)
+					if err != nil {
+						t.Fatalf("%s: %v", p.Name, err)
+					}
+					result, err := vm.Run(prog.Instructions, data)
+					if err != nil {
+						t.Fatalf("%s: %v", p.Name, err)
+					}
+					if result.Accepted != p.Match {
+						t.Errorf("%s: prototype accepted=%t, expected %t, frame %x", p.Name, result.Accepted, p.Match, data)
+					}
+				}
+			})
+		}
+	}
+}
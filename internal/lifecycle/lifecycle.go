@@ -0,0 +1,151 @@
+package lifecycle
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Handle identifies one tracked resource. Release must be called exactly
+// once when the resource is closed.
+type Handle struct {
+	tracker *Tracker
+	id      uint64
+}
+
+// Release marks the resource as closed
+func (h *Handle) Release() {
+	if h == nil || h.tracker == nil {
+		return
+	}
+	h.tracker.release(h.id)
+	h.tracker = nil
+}
+
+// entry describes an open resource
+type entry struct {
+	kind    string
+	name    string
+	opened  time.Time
+	creator string
+}
+
+// Tracker records open resources and goroutine counts so long-running modes
+// can report leaks. Tracking is off until Enable is called.
+type Tracker struct {
+	mu         sync.Mutex
+	enabled    bool
+	next       uint64
+	open       map[uint64]*entry
+	goroutines int
+}
+
+// defaultTracker is the process-wide tracker used by Track and Report
+var defaultTracker = &Tracker{}
+
+// Enable starts tracking resources and records the goroutine baseline
+func Enable() {
+	defaultTracker.Enable()
+}
+
+// Enabled reports whether leak tracking is active
+func Enabled() bool {
+	return defaultTracker.Enabled()
+}
+
+// Track registers an open resource with the default tracker
+func Track(kind, name string) *Handle {
+	return defaultTracker.Track(kind, name)
+}
+
+// Report writes leaked resources and goroutine growth for the default
+// tracker and returns the number of problems found
+func Report(w io.Writer) int {
+	return defaultTracker.Report(w)
+}
+
+// Enable starts tracking on this tracker
+func (t *Tracker) Enable() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.enabled = true
+	t.open = make(map[uint64]*entry)
+	t.goroutines = runtime.NumGoroutine()
+}
+
+// Enabled reports whether the tracker is active
+func (t *Tracker) Enabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.enabled
+}
+
+// Track registers an open resource. The returned handle is safe to use even
+// when tracking is disabled.
+func (t *Tracker) Track(kind, name string) *Handle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return &Handle{}
+	}
+
+	creator := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		creator = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	t.next++
+	t.open[t.next] = &entry{kind: kind, name: name, opened: time.Now(), creator: creator}
+	return &Handle{tracker: t, id: t.next}
+}
+
+// release removes a resource from the open set
+func (t *Tracker) release(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.open, id)
+}
+
+// Report writes every resource still open and any goroutine growth since
+// Enable, and returns the number of problems found
+func (t *Tracker) Report(w io.Writer) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return 0
+	}
+
+	ids := make([]uint64, 0, len(t.open))
+	for id := range t.open {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	problems := 0
+	fmt.Fprintf(w, "=== Resource Leak Report ===\n")
+	for _, id := range ids {
+		e := t.open[id]
+		fmt.Fprintf(w, "  unclosed %s %q opened %s ago at %s\n",
+			e.kind, e.name, time.Since(e.opened).Round(time.Millisecond), e.creator)
+		problems++
+	}
+
+	// Give goroutines that are already exiting a moment to finish
+	current := runtime.NumGoroutine()
+	for i := 0; i < 10 && current > t.goroutines; i++ {
+		time.Sleep(10 * time.Millisecond)
+		current = runtime.NumGoroutine()
+	}
+	if growth := current - t.goroutines; growth > 0 {
+		fmt.Fprintf(w, "  goroutines grew from %d to %d (+%d)\n", t.goroutines, current, growth)
+		problems++
+	}
+
+	if problems == 0 {
+		fmt.Fprintf(w, "  no leaks detected\n")
+	}
+	return problems
+}
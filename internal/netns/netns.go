@@ -0,0 +1,7 @@
+// Package netns runs code inside network namespaces created with ip netns,
+// so that interfaces, sockets and XDP hooks can be set up away from the
+// host's own.
+package netns
+
+// runDir is where ip netns keeps its named namespaces
+const runDir = "/var/run/netns"
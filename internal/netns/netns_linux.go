@@ -0,0 +1,47 @@
+//go:build linux
+
+package netns
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// Do runs fn on a thread moved into the named network namespace, or in the
+// current namespace when name is "". Should the thread fail to move back,
+// it stays locked, so that the runtime retires it rather than run other
+// goroutines in the namespace.
+func Do(name string, fn func() error) error {
+	if name == "" {
+		return fn()
+	}
+	target, err := os.Open(filepath.Join(runDir, name))
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace %s: %w", name, err)
+	}
+	defer target.Close()
+
+	runtime.LockOSThread()
+	current, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to open the current network namespace: %w", err)
+	}
+	defer current.Close()
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter network namespace %s: %w", name, err)
+	}
+
+	err = fn()
+	if restoreErr := unix.Setns(int(current.Fd()), unix.CLONE_NEWNET); restoreErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to leave network namespace %s: %w", name, restoreErr))
+	}
+	runtime.UnlockOSThread()
+	return err
+}
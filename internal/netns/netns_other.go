@@ -0,0 +1,17 @@
+//go:build !linux
+
+package netns
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Do runs fn when name is "" and fails otherwise: network namespaces are a
+// Linux facility
+func Do(name string, fn func() error) error {
+	if name == "" {
+		return fn()
+	}
+	return fmt.Errorf("network namespaces need Linux, not %s", runtime.GOOS)
+}
@@ -0,0 +1,230 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// NetworkPolicy is a Kubernetes NetworkPolicy (networking.k8s.io/v1)
+type NetworkPolicy struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Spec       NetworkPolicySpec `yaml:"spec"`
+}
+
+// NetworkPolicySpec holds the policy rules
+type NetworkPolicySpec struct {
+	Ingress []PolicyRule `yaml:"ingress"`
+	Egress  []PolicyRule `yaml:"egress"`
+}
+
+// PolicyRule is an ingress (From) or egress (To) rule
+type PolicyRule struct {
+	From  []PolicyPeer `yaml:"from"`
+	To    []PolicyPeer `yaml:"to"`
+	Ports []PolicyPort `yaml:"ports"`
+}
+
+// PolicyPeer is a rule peer. Only ipBlock peers can be expressed without
+// cluster state.
+type PolicyPeer struct {
+	IPBlock           *IPBlock  `yaml:"ipBlock"`
+	PodSelector       yaml.Node `yaml:"podSelector"`
+	NamespaceSelector yaml.Node `yaml:"namespaceSelector"`
+}
+
+// PolicyPort is a rule port; Port may be a number or a named port
+type PolicyPort struct {
+	Protocol string    `yaml:"protocol"`
+	Port     yaml.Node `yaml:"port"`
+	EndPort  *int32    `yaml:"endPort"`
+}
+
+// RuleFilter is one packet filter derived from a policy rule
+type RuleFilter struct {
+	Name      string // e.g. "default/allow-web ingress[0] from 10.0.0.0/8 TCP/80"
+	Direction string // "ingress" or "egress"
+	Rule      int    // index of the rule within its direction
+	Filter    *filter.PacketFilter
+	Notes     []string // ways in which the filter is broader than the rule
+}
+
+// LoadNetworkPolicies reads every NetworkPolicy from a (possibly
+// multi-document) YAML file, ignoring other kinds
+func LoadNetworkPolicies(path string) ([]*NetworkPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var policies []*NetworkPolicy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		np := &NetworkPolicy{}
+		err := dec.Decode(np)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if np.Kind == "NetworkPolicy" {
+			policies = append(policies, np)
+		}
+	}
+
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no NetworkPolicy found in %s", path)
+	}
+	return policies, nil
+}
+
+// Expand turns every rule into packet filters, one per (peer, port)
+// combination. An ingress peer is the packet source and an egress peer the
+// destination; ports are always destination ports. The Pods selected by
+// the policy are not part of the filters. Rule entries that cannot be
+// expressed are reported in skipped rather than failing the expansion.
+func (np *NetworkPolicy) Expand() (filters []*RuleFilter, skipped []string) {
+	name := np.Metadata.Name
+	if np.Metadata.Namespace != "" {
+		name = np.Metadata.Namespace + "/" + name
+	}
+
+	for i, rule := range np.Spec.Ingress {
+		f, s := expandRule(fmt.Sprintf("%s ingress[%d]", name, i), "ingress", i, rule.From, rule.Ports)
+		filters, skipped = append(filters, f...), append(skipped, s...)
+	}
+	for i, rule := range np.Spec.Egress {
+		f, s := expandRule(fmt.Sprintf("%s egress[%d]", name, i), "egress", i, rule.To, rule.Ports)
+		filters, skipped = append(filters, f...), append(skipped, s...)
+	}
+	return filters, skipped
+}
+
+// peerMatch is an expressible peer: a CIDR (empty for any address) and the
+// notes that apply to it
+type peerMatch struct {
+	cidr  string
+	notes []string
+}
+
+// portMatch is an expressible port entry
+type portMatch struct {
+	protocol string // empty for any protocol
+	port     int    // zero for any port
+}
+
+// expandRule expands a single rule's peers and ports
+func expandRule(prefix, direction string, index int, peers []PolicyPeer, ports []PolicyPort) ([]*RuleFilter, []string) {
+	var skipped []string
+
+	// No peers means any address
+	matches := []peerMatch{{}}
+	if len(peers) > 0 {
+		matches = nil
+		for j, peer := range peers {
+			if peer.IPBlock == nil {
+				skipped = append(skipped, fmt.Sprintf("%s peer[%d]: pod and namespace selectors need cluster state", prefix, j))
+				continue
+			}
+			m := peerMatch{cidr: peer.IPBlock.CIDR}
+			if len(peer.IPBlock.Except) > 0 {
+				m.notes = append(m.notes, fmt.Sprintf("except %s not enforced", strings.Join(peer.IPBlock.Except, ", ")))
+			}
+			matches = append(matches, m)
+		}
+	}
+
+	// No ports means any port and protocol
+	portMatches := []portMatch{{}}
+	if len(ports) > 0 {
+		portMatches = nil
+		for j, port := range ports {
+			pm, err := port.match()
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s port[%d]: %v", prefix, j, err))
+				continue
+			}
+			portMatches = append(portMatches, pm)
+		}
+	}
+
+	var filters []*RuleFilter
+	for _, m := range matches {
+		for _, pm := range portMatches {
+			f := &filter.PacketFilter{Protocol: pm.protocol, DstPort: pm.port}
+			peerDesc := "any"
+			if m.cidr != "" {
+				peerDesc = m.cidr
+				if direction == "ingress" {
+					f.SrcIP = m.cidr
+				} else {
+					f.DstIP = m.cidr
+				}
+			}
+			portDesc := "all ports"
+			if pm.protocol != "" {
+				portDesc = strings.ToUpper(pm.protocol)
+				if pm.port != 0 {
+					portDesc += fmt.Sprintf("/%d", pm.port)
+				}
+			}
+			preposition := "from"
+			if direction == "egress" {
+				preposition = "to"
+			}
+			name := fmt.Sprintf("%s %s %s %s", prefix, preposition, peerDesc, portDesc)
+
+			if m.cidr == "" && pm.protocol == "" {
+				skipped = append(skipped, fmt.Sprintf("%s: matches all traffic, no filter needed", name))
+				continue
+			}
+			if err := f.Validate(); err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			filters = append(filters, &RuleFilter{
+				Name:      name,
+				Direction: direction,
+				Rule:      index,
+				Filter:    f,
+				Notes:     m.notes,
+			})
+		}
+	}
+	return filters, skipped
+}
+
+// match converts a policy port; the protocol defaults to TCP
+func (p PolicyPort) match() (portMatch, error) {
+	protocol := strings.ToLower(p.Protocol)
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return portMatch{}, fmt.Errorf("protocol %s is not supported", p.Protocol)
+	}
+	if p.EndPort != nil {
+		return portMatch{}, fmt.Errorf("port ranges (endPort) are not supported")
+	}
+
+	pm := portMatch{protocol: protocol}
+	if p.Port.Kind == 0 {
+		return pm, nil
+	}
+	var n int
+	if p.Port.Tag != "!!int" || p.Port.Decode(&n) != nil {
+		return portMatch{}, fmt.Errorf("named port %s needs the Pod spec to resolve", p.Port.Value)
+	}
+	pm.port = n
+	return pm, nil
+}
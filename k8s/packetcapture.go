@@ -0,0 +1,250 @@
+// Package k8s converts Antrea custom resources into packet filters. The
+// resource types mirror only the fields the conversion needs, so the
+// package does not depend on the Kubernetes or Antrea API modules.
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// PacketCapture is an Antrea PacketCapture resource (crd.antrea.io)
+type PacketCapture struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Spec       PacketCaptureSpec `yaml:"spec"`
+}
+
+// ObjectMeta holds the identifying metadata of a resource
+type ObjectMeta struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// PacketCaptureSpec is the part of the spec that selects packets
+type PacketCaptureSpec struct {
+	Source      Endpoint `yaml:"source"`
+	Destination Endpoint `yaml:"destination"`
+	Packet      *Packet  `yaml:"packet"`
+}
+
+// Endpoint is a capture source or destination. Exactly one of the fields
+// may be set; Pod references need their IP supplied through Options.
+type Endpoint struct {
+	Pod     *PodReference `yaml:"pod"`
+	IP      string        `yaml:"ip"`
+	IPBlock *IPBlock      `yaml:"ipBlock"`
+}
+
+// PodReference names a Pod
+type PodReference struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+}
+
+// IPBlock is a CIDR, as in NetworkPolicy peers
+type IPBlock struct {
+	CIDR   string   `yaml:"cidr"`
+	Except []string `yaml:"except"`
+}
+
+// Packet describes the headers to match
+type Packet struct {
+	IPFamily        string          `yaml:"ipFamily"`
+	Protocol        Protocol        `yaml:"protocol"`
+	TransportHeader TransportHeader `yaml:"transportHeader"`
+}
+
+// TransportHeader holds the per-protocol header matchers
+type TransportHeader struct {
+	TCP *PortHeader `yaml:"tcp"`
+	UDP *PortHeader `yaml:"udp"`
+}
+
+// PortHeader matches transport ports
+type PortHeader struct {
+	SrcPort *int32 `yaml:"srcPort"`
+	DstPort *int32 `yaml:"dstPort"`
+}
+
+// Protocol is an IntOrString protocol: a name ("TCP") or an IP protocol
+// number (6). It is stored as the lower-case name, or as the number when
+// the filter model has no name for it.
+type Protocol string
+
+// UnmarshalYAML accepts protocol names and numbers
+func (p *Protocol) UnmarshalYAML(node *yaml.Node) error {
+	value := strings.ToLower(node.Value)
+	if n, err := strconv.Atoi(value); err == nil {
+		names := map[int]string{1: "icmp", 6: "tcp", 17: "udp"}
+		if n < 0 || n > 255 {
+			return fmt.Errorf("line %d: invalid protocol number %d", node.Line, n)
+		}
+		if name, ok := names[n]; ok {
+			value = name
+		}
+	}
+	*p = Protocol(value)
+	return nil
+}
+
+// Options supply cluster state the conversion cannot look up itself
+type Options struct {
+	// PodIPs maps "namespace/name" to the Pod's IP address
+	PodIPs map[string]string
+}
+
+// LoadPacketCaptures reads every PacketCapture from a (possibly
+// multi-document) YAML file, ignoring other kinds
+func LoadPacketCaptures(path string) ([]*PacketCapture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var captures []*PacketCapture
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		pc := &PacketCapture{}
+		err := dec.Decode(pc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if pc.Kind == "PacketCapture" {
+			captures = append(captures, pc)
+		}
+	}
+
+	if len(captures) == 0 {
+		return nil, fmt.Errorf("no PacketCapture found in %s", path)
+	}
+	return captures, nil
+}
+
+// ToPacketFilter converts the capture's packet selection into a filter
+func (pc *PacketCapture) ToPacketFilter(opts Options) (*filter.PacketFilter, error) {
+	f := &filter.PacketFilter{}
+	spec := pc.Spec
+
+	var err error
+	if f.SrcIP, err = spec.Source.address("source", opts); err != nil {
+		return nil, err
+	}
+	if f.DstIP, err = spec.Destination.address("destination", opts); err != nil {
+		return nil, err
+	}
+
+	if spec.Packet != nil {
+		f.Protocol = string(spec.Packet.Protocol)
+
+		header := spec.Packet.TransportHeader
+		ports := header.TCP
+		if header.UDP != nil {
+			if header.TCP != nil {
+				return nil, fmt.Errorf("transportHeader may set only one of tcp and udp")
+			}
+			ports = header.UDP
+		}
+		if ports != nil {
+			// The header type implies the protocol when it is not given
+			headerProtocol := "tcp"
+			if ports == header.UDP {
+				headerProtocol = "udp"
+			}
+			if f.Protocol == "" {
+				f.Protocol = headerProtocol
+			} else if f.Protocol != headerProtocol {
+				return nil, fmt.Errorf("protocol %s does not match the %s transportHeader", f.Protocol, headerProtocol)
+			}
+			if ports.SrcPort != nil {
+				f.SrcPort = int(*ports.SrcPort)
+			}
+			if ports.DstPort != nil {
+				f.DstPort = int(*ports.DstPort)
+			}
+		}
+
+		if err := checkFamily(spec.Packet.IPFamily, f); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("PacketCapture %s: %w", pc.Metadata.Name, err)
+	}
+	return f, nil
+}
+
+// address resolves an endpoint to an IP address or CIDR
+func (e Endpoint) address(role string, opts Options) (string, error) {
+	set := 0
+	for _, present := range []bool{e.Pod != nil, e.IP != "", e.IPBlock != nil} {
+		if present {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", fmt.Errorf("%s may set only one of pod, ip and ipBlock", role)
+	}
+
+	switch {
+	case e.Pod != nil:
+		namespace := e.Pod.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		key := namespace + "/" + e.Pod.Name
+		ip, ok := opts.PodIPs[key]
+		if !ok {
+			return "", fmt.Errorf("%s Pod %s: IP unknown (supply it with --pod-ip %s=IP)", role, key, key)
+		}
+		return ip, nil
+	case e.IPBlock != nil:
+		if len(e.IPBlock.Except) > 0 {
+			return "", fmt.Errorf("%s ipBlock: except is not supported", role)
+		}
+		return e.IPBlock.CIDR, nil
+	}
+	return e.IP, nil
+}
+
+// checkFamily rejects addresses that contradict the requested IP family
+func checkFamily(family string, f *filter.PacketFilter) error {
+	if family == "" {
+		return nil
+	}
+	want := strings.ToLower(family)
+	if want != "ipv4" && want != "ipv6" {
+		return fmt.Errorf("invalid ipFamily '%s', must be IPv4 or IPv6", family)
+	}
+	if want == "ipv6" && f.SrcIP == "" && f.DstIP == "" {
+		return fmt.Errorf("ipFamily IPv6 requires an IPv6 source or destination; filters are IPv4 otherwise")
+	}
+	for _, addr := range []string{f.SrcIP, f.DstIP} {
+		if addr == "" {
+			continue
+		}
+		ipnet, err := filter.ParseNet(addr)
+		if err != nil {
+			return err
+		}
+		isV4 := ipnet.IP.To4() != nil
+		if isV4 != (want == "ipv4") {
+			return fmt.Errorf("address %s is not %s", addr, family)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package kernel checks programs against the Linux kernel itself: both
+// are attached to packet sockets on a veth pair, packets are sent into the
+// pair, and the packets each socket receives are the kernel's verdicts. It
+// is the ground truth for the userspace interpreter in package vm. RunXDP
+// checks a generated eBPF program the same way, on an XDP hook.
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// ErrUnavailable is wrapped by the errors of a run that cannot happen
+// here: another operating system, no CAP_NET_ADMIN or CAP_NET_RAW, or no
+// ip command to create the veth pair
+var ErrUnavailable = errors.New("kernel oracle not available")
+
+// PacketVerdict records what the kernel and the interpreter did with one
+// packet
+type PacketVerdict struct {
+	Index   int  // zero-based position in the corpus
+	Length  int  // length in bytes
+	Sent    bool // the kernel took the packet; a frame shorter than the Ethernet header or longer than the MTU is refused
+	Arrived bool // the packet reached the receiving end; the kernel drops some malformed frames, such as a cut 802.1Q tag
+
+	Reference, Prototype     bool // received by the socket of each program
+	VMReference, VMPrototype bool // accepted by each program in the interpreter
+
+	Decoded *packet.Decoded // the packet's headers
+}
+
+// Agrees reports whether both sockets received the packet or neither did
+func (v *PacketVerdict) Agrees() bool {
+	return v.Reference == v.Prototype
+}
+
+// InterpreterAgrees reports whether the interpreter predicted what both
+// sockets received
+func (v *PacketVerdict) InterpreterAgrees() bool {
+	return v.Reference == v.VMReference && v.Prototype == v.VMPrototype
+}
+
+// Result summarizes a kernel run over a corpus
+type Result struct {
+	Packets           []*PacketVerdict
+	Duplicates        int // corpus packets left out as copies of earlier ones
+	Unsent            int // packets the kernel refused to send
+	Dropped           int // packets sent that never arrived
+	ReferenceReceived int
+	PrototypeReceived int
+	Mismatches        int // packets one socket received and the other did not
+	InterpreterMisses int // packets on which the interpreter mispredicted a socket
+}
+
+// Failed reports whether the sockets disagreed or the interpreter
+// mispredicted either
+func (r *Result) Failed() bool {
+	return r.Mismatches > 0 || r.InterpreterMisses > 0
+}
+
+// Run sends the corpus of Ethernet frames through a veth pair with the
+// reference and the prototype attached to packet sockets on the receiving
+// end, and compares what each socket receives, with each other and with
+// the interpreter's verdicts. It needs Linux and CAP_NET_ADMIN, and only
+// Ethernet frames can be sent through a veth pair.
+func Run(reference, prototype []*bpf.Instruction, link filter.LinkType, corpus [][]byte) (*Result, error) {
+	if !link.IsEthernet() {
+		return nil, fmt.Errorf("the kernel oracle sends Ethernet frames through a veth pair, not %s packets", link)
+	}
+	if len(corpus) == 0 {
+		return nil, fmt.Errorf("no packets to send")
+	}
+
+	unique, duplicates := dedupe(corpus)
+	result := &Result{Duplicates: duplicates}
+
+	verdicts, err := run(reference, prototype, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pkt := range unique {
+		v := verdicts[i]
+		v.Decoded = packet.Decode(pkt, link)
+		var err error
+		if v.VMReference, err = accepts(reference, pkt); err != nil {
+			return nil, fmt.Errorf("reference: %w", err)
+		}
+		if v.VMPrototype, err = accepts(prototype, pkt); err != nil {
+			return nil, fmt.Errorf("prototype: %w", err)
+		}
+		result.Packets = append(result.Packets, v)
+
+		switch {
+		case !v.Sent:
+			result.Unsent++
+			continue
+		case !v.Arrived:
+			result.Dropped++
+			continue
+		}
+		if v.Reference {
+			result.ReferenceReceived++
+		}
+		if v.Prototype {
+			result.PrototypeReceived++
+		}
+		if !v.Agrees() {
+			result.Mismatches++
+		}
+		if !v.InterpreterAgrees() {
+			result.InterpreterMisses++
+		}
+	}
+	return result, nil
+}
+
+// dedupe leaves out copies of earlier packets, returning the packets left
+// and the number of copies
+func dedupe(corpus [][]byte) ([][]byte, int) {
+	seen := make(map[string]bool)
+	var unique [][]byte
+	for _, pkt := range corpus {
+		if !seen[string(pkt)] {
+			seen[string(pkt)] = true
+			unique = append(unique, pkt)
+		}
+	}
+	return unique, len(corpus) - len(unique)
+}
+
+// accepts runs a program over a packet in the interpreter
+func accepts(prog []*bpf.Instruction, pkt []byte) (bool, error) {
+	r, err := vm.Run(prog, pkt)
+	if err != nil {
+		return false, err
+	}
+	return r.Accepted, nil
+}
+
+// Report formats the result, listing every packet the sockets disagree on
+// or the interpreter mispredicted
+func (r *Result) Report() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Packets sent: %d", len(r.Packets)-r.Unsent-r.Dropped))
+	if r.Duplicates > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d duplicates left out)", r.Duplicates))
+	}
+	sb.WriteString("\n")
+	if r.Unsent > 0 || r.Dropped > 0 {
+		sb.WriteString(fmt.Sprintf("Refused by the kernel: %d sending, %d receiving\n", r.Unsent, r.Dropped))
+	}
+	sb.WriteString(fmt.Sprintf("Received by reference socket: %d\n", r.ReferenceReceived))
+	sb.WriteString(fmt.Sprintf("Received by prototype socket: %d\n", r.PrototypeReceived))
+
+	if !r.Failed() {
+		sb.WriteString("Result: both sockets receive the same packets, as the interpreter predicts\n")
+		return sb.String()
+	}
+
+	if r.Mismatches > 0 {
+		sb.WriteString(fmt.Sprintf("Result: %d packet(s) received by one socket only\n", r.Mismatches))
+	}
+	if r.InterpreterMisses > 0 {
+		sb.WriteString(fmt.Sprintf("Result: the interpreter mispredicts %d packet(s)\n", r.InterpreterMisses))
+	}
+	for _, v := range r.Packets {
+		if !v.Arrived || (v.Agrees() && v.InterpreterAgrees()) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  packet #%d (%d bytes): kernel reference=%t prototype=%t, interpreter reference=%t prototype=%t\n",
+			v.Index, v.Length, v.Reference, v.Prototype, v.VMReference, v.VMPrototype))
+		sb.WriteString(fmt.Sprintf("    %s\n", v.Decoded))
+	}
+	return sb.String()
+}
@@ -0,0 +1,250 @@
+//go:build linux
+
+package kernel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/lifecycle"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/netns"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// ethPAll is ETH_P_ALL in network byte order, as packet sockets take it
+const ethPAll = 0x0300
+
+// arrivalTimeout bounds the wait for a sent packet to reach the receiving
+// end of the pair, past which the kernel dropped it
+const arrivalTimeout = 250 * time.Millisecond
+
+// run creates a veth pair, attaches the programs to packet sockets on one
+// end and sends the packets, one at a time, into the other. A third
+// socket without a filter sees every packet arrive. Sockets bound to the
+// same device get packets in the reverse of the order they were bound, so
+// it is bound first: once it has a packet, the filtered sockets have had
+// their chance at it.
+func run(reference, prototype []*bpf.Instruction, packets [][]byte) ([]*PacketVerdict, error) {
+	namespace, tx, rx, cleanup, err := createPair()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	witness, err := openSocket(namespace, rx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer witness.close()
+	referenceSocket, err := openSocket(namespace, rx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %w", err)
+	}
+	defer referenceSocket.close()
+	prototypeSocket, err := openSocket(namespace, rx, prototype)
+	if err != nil {
+		return nil, fmt.Errorf("prototype: %w", err)
+	}
+	defer prototypeSocket.close()
+	sender, err := openSocket(namespace, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sender.close()
+
+	verdicts := make([]*PacketVerdict, len(packets))
+	for i, pkt := range packets {
+		v := &PacketVerdict{Index: i, Length: len(pkt)}
+		verdicts[i] = v
+		err := syscall.Sendto(sender.fd, pkt, 0, &syscall.SockaddrLinklayer{Protocol: ethPAll, Ifindex: sender.ifindex})
+		switch {
+		case errors.Is(err, syscall.EINVAL), errors.Is(err, syscall.EMSGSIZE):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to send packet #%d: %w", i, err)
+		}
+		v.Sent = true
+		if v.Arrived, err = witness.await(pkt); err != nil || !v.Arrived {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if v.Reference, err = referenceSocket.drain(pkt); err != nil {
+			return nil, fmt.Errorf("reference: %w", err)
+		}
+		if v.Prototype, err = prototypeSocket.drain(pkt); err != nil {
+			return nil, fmt.Errorf("prototype: %w", err)
+		}
+	}
+	return verdicts, nil
+}
+
+// createPair creates a throwaway network namespace holding a veth pair
+// with both ends up. It returns the namespace, as named by ip netns, the
+// sending and receiving interfaces, and a function deleting the namespace
+// and the pair with it. IPv6 is turned off on both ends, so that neighbor
+// discovery does not add packets of its own.
+func createPair() (namespace string, tx, rx *net.Interface, cleanup func(), err error) {
+	namespace = fmt.Sprintf("bpfk%d", os.Getpid()%1000000)
+	if err := ip("netns", "add", namespace); err != nil {
+		return "", nil, nil, nil, err
+	}
+	handle := lifecycle.Track("network namespace", namespace)
+	cleanup = func() {
+		ip("netns", "del", namespace)
+		handle.Release()
+	}
+
+	txName, rxName := namespace+"a", namespace+"b"
+	err = ip("-n", namespace, "link", "add", txName, "type", "veth", "peer", "name", rxName)
+	for _, dev := range []string{txName, rxName} {
+		if err == nil {
+			err = ip("-n", namespace, "link", "set", dev, "up")
+		}
+	}
+	if err == nil {
+		err = netns.Do(namespace, func() error {
+			for _, dev := range []string{txName, rxName} {
+				// Best effort: a kernel without IPv6 has nothing to turn off
+				os.WriteFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/disable_ipv6", dev), []byte("1"), 0644)
+			}
+			var err error
+			if tx, err = net.InterfaceByName(txName); err == nil {
+				rx, err = net.InterfaceByName(rxName)
+			}
+			return err
+		})
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, nil, nil, err
+	}
+	return namespace, tx, rx, cleanup, nil
+}
+
+// ip runs the ip command
+func ip(args ...string) error {
+	out, err := exec.Command("ip", args...).CombinedOutput()
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("the kernel oracle creates a network namespace and a veth pair with the ip command: %w (%w)", err, ErrUnavailable)
+	case err != nil && strings.Contains(string(out), "Operation not permitted"):
+		return fmt.Errorf("ip %s: creating a network namespace and a veth pair needs CAP_NET_ADMIN (%w)", strings.Join(args, " "), ErrUnavailable)
+	case err != nil:
+		return fmt.Errorf("ip %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// socket is a packet socket bound to one interface
+type socket struct {
+	fd      int
+	ifindex int
+	handle  *lifecycle.Handle
+	buf     []byte
+}
+
+// openSocket opens a packet socket on the interface in the namespace with
+// the program, if any, attached. The socket is created for no protocol
+// and bound for all once the filter is in place, so it sees no packet
+// unfiltered.
+func openSocket(namespace string, iface *net.Interface, prog []*bpf.Instruction) (*socket, error) {
+	var fd int
+	err := netns.Do(namespace, func() error {
+		var err error
+		fd, err = syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, 0)
+		return err
+	})
+	if errors.Is(err, syscall.EPERM) {
+		return nil, fmt.Errorf("packet sockets need CAP_NET_RAW (%w)", ErrUnavailable)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open packet socket: %w", err)
+	}
+	s := &socket{fd: fd, ifindex: iface.Index, handle: lifecycle.Track("packet socket", iface.Name), buf: make([]byte, 1<<16)}
+
+	if prog != nil {
+		program := make([]syscall.SockFilter, len(prog))
+		for i, inst := range prog {
+			program[i] = syscall.SockFilter{Code: inst.Code, Jt: inst.JT, Jf: inst.JF, K: inst.K}
+		}
+		if err := syscall.AttachLsf(fd, program); err != nil {
+			s.close()
+			return nil, fmt.Errorf("the kernel refused the program: %w", err)
+		}
+	}
+	tv := syscall.NsecToTimeval(arrivalTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to set receive timeout: %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: ethPAll, Ifindex: iface.Index}); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to bind packet socket to %s: %w", iface.Name, err)
+	}
+	return s, nil
+}
+
+// await reads until the packet arrives, skipping any other, and reports
+// false if it does not arrive in time
+func (s *socket) await(pkt []byte) (bool, error) {
+	for {
+		n, _, err := syscall.Recvfrom(s.fd, s.buf, 0)
+		switch {
+		case errors.Is(err, syscall.EAGAIN):
+			return false, nil
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case err != nil:
+			return false, fmt.Errorf("failed to receive: %w", err)
+		}
+		if arrived(pkt, s.buf[:n]) {
+			return true, nil
+		}
+	}
+}
+
+// drain reads every packet queued on the socket and reports whether the
+// packet was among them
+func (s *socket) drain(pkt []byte) (bool, error) {
+	found := false
+	for {
+		n, _, err := syscall.Recvfrom(s.fd, s.buf, syscall.MSG_DONTWAIT)
+		switch {
+		case errors.Is(err, syscall.EAGAIN):
+			return found, nil
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case err != nil:
+			return false, fmt.Errorf("failed to receive: %w", err)
+		}
+		found = found || arrived(pkt, s.buf[:n])
+	}
+}
+
+// close closes the socket
+func (s *socket) close() {
+	syscall.Close(s.fd)
+	s.handle.Release()
+}
+
+// arrived reports whether got is the packet sent, allowing for the kernel
+// taking an 802.1Q tag out of the frame into packet metadata on receive
+func arrived(sent, got []byte) bool {
+	if bytes.Equal(sent, got) {
+		return true
+	}
+	if len(sent) < 18 || sent[12] != 0x81 || sent[13] != 0x00 {
+		return false
+	}
+	untagged := append(append([]byte{}, sent[:12]...), sent[16:]...)
+	return bytes.Equal(untagged, got)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package kernel
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ebpf"
+)
+
+// run fails: packet sockets and veth pairs are Linux facilities
+func run(reference, prototype []*bpf.Instruction, packets [][]byte) ([]*PacketVerdict, error) {
+	return nil, fmt.Errorf("the kernel oracle needs Linux, not %s (%w)", runtime.GOOS, ErrUnavailable)
+}
+
+// runXDP fails: XDP and network namespaces are Linux facilities
+func runXDP(program *ebpf.Program, packets [][]byte) ([]*XDPVerdict, error) {
+	return nil, fmt.Errorf("the XDP oracle needs Linux, not %s (%w)", runtime.GOOS, ErrUnavailable)
+}
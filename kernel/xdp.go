@@ -0,0 +1,126 @@
+package kernel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ebpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+)
+
+// XDPVerdict records what the XDP program and the interpreter did with
+// one packet
+type XDPVerdict struct {
+	Index   int  // zero-based position in the corpus
+	Length  int  // length in bytes
+	Sent    bool // the kernel took the packet
+	Arrived bool // the XDP program ran on the packet
+
+	Matched     bool // the XDP program returned its pass verdict
+	Interpreter bool // the prototype's classic program accepted the packet in the interpreter
+
+	Decoded *packet.Decoded // the packet's headers
+}
+
+// Agrees reports whether the XDP program and the interpreter decided the
+// packet alike
+func (v *XDPVerdict) Agrees() bool {
+	return v.Matched == v.Interpreter
+}
+
+// XDPResult summarizes an XDP run over a corpus
+type XDPResult struct {
+	Packets    []*XDPVerdict
+	Duplicates int // corpus packets left out as copies of earlier ones
+	Unsent     int // packets the kernel refused to send
+	Dropped    int // packets sent that the program never ran on
+	Matched    int
+	Mismatches int // packets the program and the interpreter decide differently
+}
+
+// Failed reports whether the XDP program and the interpreter disagreed
+func (r *XDPResult) Failed() bool {
+	return r.Mismatches > 0
+}
+
+// RunXDP attaches the eBPF program to the XDP hook of one end of a veth
+// pair, moved into a throwaway network namespace, sends the corpus of
+// Ethernet frames into the other end and checks the verdicts the
+// program counts against the interpreter running the prototype's classic
+// program, which the eBPF program is generated to agree with. It needs
+// Linux, CAP_NET_ADMIN and CAP_BPF.
+func RunXDP(program *ebpf.Program, prototype []*bpf.Instruction, corpus [][]byte) (*XDPResult, error) {
+	if program.Target != ebpf.TargetXDP {
+		return nil, fmt.Errorf("the XDP oracle attaches XDP programs, not %s", program.Target)
+	}
+	if len(corpus) == 0 {
+		return nil, fmt.Errorf("no packets to send")
+	}
+
+	unique, duplicates := dedupe(corpus)
+	result := &XDPResult{Duplicates: duplicates}
+
+	verdicts, err := runXDP(program, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pkt := range unique {
+		v := verdicts[i]
+		v.Decoded = packet.Decode(pkt, filter.LinkEN10MB)
+		var err error
+		if v.Interpreter, err = accepts(prototype, pkt); err != nil {
+			return nil, fmt.Errorf("prototype: %w", err)
+		}
+		result.Packets = append(result.Packets, v)
+
+		switch {
+		case !v.Sent:
+			result.Unsent++
+			continue
+		case !v.Arrived:
+			result.Dropped++
+			continue
+		}
+		if v.Matched {
+			result.Matched++
+		}
+		if !v.Agrees() {
+			result.Mismatches++
+		}
+	}
+	return result, nil
+}
+
+// Report formats the result, listing every packet the XDP program and the
+// interpreter decide differently
+func (r *XDPResult) Report() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Packets sent: %d", len(r.Packets)-r.Unsent-r.Dropped))
+	if r.Duplicates > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d duplicates left out)", r.Duplicates))
+	}
+	sb.WriteString("\n")
+	if r.Unsent > 0 || r.Dropped > 0 {
+		sb.WriteString(fmt.Sprintf("Refused by the kernel: %d sending, %d receiving\n", r.Unsent, r.Dropped))
+	}
+	sb.WriteString(fmt.Sprintf("Matched by the XDP program: %d\n", r.Matched))
+
+	if !r.Failed() {
+		sb.WriteString("Result: the XDP program matches the packets the prototype accepts in the interpreter\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("Result: %d packet(s) decided differently\n", r.Mismatches))
+	for _, v := range r.Packets {
+		if !v.Arrived || v.Agrees() {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  packet #%d (%d bytes): XDP matched=%t, interpreter prototype=%t\n",
+			v.Index, v.Length, v.Matched, v.Interpreter))
+		sb.WriteString(fmt.Sprintf("    %s\n", v.Decoded))
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen/ebpf"
+)
+
+// countPollInterval is how often the verdict counts are read while
+// waiting for a sent packet
+const countPollInterval = time.Millisecond
+
+// runXDP creates a veth pair, attaches the program to the XDP hook of one
+// end and sends the packets, one at a time, into the other. Nothing else reaches the
+// receiving end, so the count that grows after a packet is sent is that
+// packet's verdict.
+func runXDP(program *ebpf.Program, packets [][]byte) ([]*XDPVerdict, error) {
+	namespace, tx, rx, cleanup, err := createPair()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	attachment, err := ebpf.Attach(program, rx.Name, namespace)
+	if errors.Is(err, syscall.EPERM) {
+		return nil, fmt.Errorf("%v: loading eBPF programs needs CAP_BPF (%w)", err, ErrUnavailable)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer attachment.Close()
+
+	sender, err := openSocket(namespace, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sender.close()
+
+	before, err := attachment.Counts()
+	if err != nil {
+		return nil, err
+	}
+	verdicts := make([]*XDPVerdict, len(packets))
+	for i, pkt := range packets {
+		v := &XDPVerdict{Index: i, Length: len(pkt)}
+		verdicts[i] = v
+		err := syscall.Sendto(sender.fd, pkt, 0, &syscall.SockaddrLinklayer{Protocol: ethPAll, Ifindex: sender.ifindex})
+		switch {
+		case errors.Is(err, syscall.EINVAL), errors.Is(err, syscall.EMSGSIZE):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to send packet #%d: %w", i, err)
+		}
+		v.Sent = true
+
+		deadline := time.Now().Add(arrivalTimeout)
+		for {
+			after, err := attachment.Counts()
+			if err != nil {
+				return nil, err
+			}
+			if after.Total() > before.Total() {
+				v.Arrived, v.Matched = true, after.Matched > before.Matched
+				before = after
+				break
+			}
+			if time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(countPollInterval)
+		}
+	}
+	return verdicts, nil
+}
@@ -0,0 +1,191 @@
+// Package oracle checks the prototype against tcpdump filtering a real
+// capture: tcpdump writes the packets it accepts from a pcap to a second
+// one, and each packet's presence there is compared with the prototype's
+// verdict in the interpreter of package vm.
+package oracle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/lifecycle"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// PacketVerdict records the reference and prototype decision for one packet
+type PacketVerdict struct {
+	Index     int  // zero-based position in the pcap
+	Length    int  // captured length in bytes
+	Reference bool // accepted by tcpdump
+	Prototype bool // accepted by the prototype program
+
+	Decoded *packet.Decoded // the packet's headers
+}
+
+// Agrees reports whether both filters made the same decision
+func (v *PacketVerdict) Agrees() bool {
+	return v.Reference == v.Prototype
+}
+
+// Result summarizes an oracle run over a pcap
+type Result struct {
+	Expr              string
+	PcapPath          string
+	Packets           []*PacketVerdict
+	ReferenceAccepted int
+	PrototypeAccepted int
+	Mismatches        int
+}
+
+// Run filters the pcap with tcpdump itself and with the prototype program,
+// and compares the per-packet accept sets. tcpdump takes the link type from
+// the file, so the prototype program must be generated for the same one.
+// tcpdump is killed with its process group after tcpdump.DefaultTimeout or
+// when the context is cancelled.
+func Run(ctx context.Context, expr, pcapPath string, link filter.LinkType, prog []*bpf.Instruction) (*Result, error) {
+	if _, err := exec.LookPath("tcpdump"); err != nil {
+		return nil, fmt.Errorf("oracle mode requires tcpdump on PATH: %w (%w)", err, tcpdump.ErrTcpdumpUnavailable)
+	}
+
+	reader, err := pcap.Open(pcapPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if link == "" {
+		link = filter.LinkEN10MB
+	}
+	if want := pcap.LinkTypeOf(link); reader.LinkType != want {
+		return nil, fmt.Errorf("pcap link type %d does not match %s (%d)", reader.LinkType, link, want)
+	}
+
+	input, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	accepted, err := referenceAccepts(ctx, expr, pcapPath, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Expr: expr, PcapPath: pcapPath}
+	for i, p := range input {
+		vmResult, err := vm.Run(prog, p.Data)
+		if err != nil {
+			return nil, fmt.Errorf("prototype program failed on packet %d: %w", i, err)
+		}
+
+		verdict := &PacketVerdict{
+			Index:     i,
+			Length:    len(p.Data),
+			Reference: accepted[i],
+			Prototype: vmResult.Accepted,
+			Decoded:   packet.Decode(p.Data, link),
+		}
+		result.Packets = append(result.Packets, verdict)
+
+		if verdict.Reference {
+			result.ReferenceAccepted++
+		}
+		if verdict.Prototype {
+			result.PrototypeAccepted++
+		}
+		if !verdict.Agrees() {
+			result.Mismatches++
+		}
+	}
+
+	return result, nil
+}
+
+// referenceAccepts runs "tcpdump -r in -w out expr" and maps the packets
+// written to the output back onto their positions in the input
+func referenceAccepts(ctx context.Context, expr, pcapPath string, input []*pcap.Packet) ([]bool, error) {
+	out, err := os.CreateTemp("", "oracle-*.pcap")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary pcap: %w", err)
+	}
+	outPath := out.Name()
+	out.Close()
+	tmp := lifecycle.Track("temporary pcap", outPath)
+	defer func() {
+		os.Remove(outPath)
+		tmp.Release()
+	}()
+
+	argv := []string{"tcpdump", "-n", "-r", pcapPath, "-w", outPath, expr}
+	if _, err := tcpdump.RunCommand(ctx, "tcpdump", argv, 0); err != nil {
+		return nil, err
+	}
+
+	reader, err := pcap.Open(outPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	filtered, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// tcpdump preserves order, so the output is a subsequence of the input
+	accepted := make([]bool, len(input))
+	j := 0
+	for i, p := range input {
+		if j < len(filtered) && samePacket(p, filtered[j]) {
+			accepted[i] = true
+			j++
+		}
+	}
+	if j != len(filtered) {
+		return nil, fmt.Errorf("could not match %d tcpdump output packets to the input", len(filtered)-j)
+	}
+
+	return accepted, nil
+}
+
+// samePacket compares two records by timestamp and contents. tcpdump
+// writes microsecond timestamps whatever the input's resolution, so both
+// are truncated to microseconds first.
+func samePacket(a, b *pcap.Packet) bool {
+	return a.Timestamp.Truncate(time.Microsecond).Equal(b.Timestamp.Truncate(time.Microsecond)) &&
+		bytes.Equal(a.Data, b.Data)
+}
+
+// Report formats the oracle result, listing every disagreement
+func (r *Result) Report() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Oracle expression: %s\n", r.Expr))
+	sb.WriteString(fmt.Sprintf("Pcap: %s (%d packets)\n", r.PcapPath, len(r.Packets)))
+	sb.WriteString(fmt.Sprintf("Accepted by tcpdump:   %d\n", r.ReferenceAccepted))
+	sb.WriteString(fmt.Sprintf("Accepted by prototype: %d\n", r.PrototypeAccepted))
+
+	if r.Mismatches == 0 {
+		sb.WriteString("Result: prototype agrees with tcpdump on every packet\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("Result: %d packet(s) disagree\n", r.Mismatches))
+	for _, v := range r.Packets {
+		if v.Agrees() {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  packet #%d (%d bytes): tcpdump=%t prototype=%t\n",
+			v.Index, v.Length, v.Reference, v.Prototype))
+		sb.WriteString(fmt.Sprintf("    %s\n", v.Decoded))
+	}
+	return sb.String()
+}
@@ -0,0 +1,509 @@
+package bpf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pendingJump records a jump operand that still needs label resolution
+type pendingJump struct {
+	target string // label name or absolute index
+	line   int
+}
+
+// asmInstruction is an instruction whose jump targets are not yet resolved
+type asmInstruction struct {
+	inst   *Instruction
+	jt, jf *pendingJump // conditional targets (nil means fall through)
+	ja     *pendingJump // unconditional target
+	line   int
+}
+
+// Assemble parses bpf_asm-style mnemonic text into instructions. Each line
+// holds an optional "label:" and one instruction. Conditional jumps accept
+// either bpf_asm operands ("jeq #0x800, ipv4, drop") or tcpdump -d operands
+// ("jeq #0x800 jt 2 jf 12"); numeric targets are absolute instruction
+// indices. Comments start with ';' or '//', and a leading "(NNN)" index as
+// printed by Disassemble is ignored, so disassembly output round-trips.
+func Assemble(text string) ([]*Instruction, error) {
+	program, labels, err := parseProgram(text)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := make([]*Instruction, len(program))
+	for pc, ai := range program {
+		if ai.ja != nil {
+			off, err := resolve(ai.ja, pc, labels, len(program), 0xffffffff)
+			if err != nil {
+				return nil, err
+			}
+			ai.inst.K = uint32(off)
+		}
+		if ai.jt != nil {
+			off, err := resolve(ai.jt, pc, labels, len(program), 255)
+			if err != nil {
+				return nil, err
+			}
+			ai.inst.JT = uint8(off)
+		}
+		if ai.jf != nil {
+			off, err := resolve(ai.jf, pc, labels, len(program), 255)
+			if err != nil {
+				return nil, err
+			}
+			ai.inst.JF = uint8(off)
+		}
+		instructions[pc] = ai.inst
+	}
+
+	return instructions, nil
+}
+
+// AssembleLongJumps is Assemble for generated text whose conditional jumps
+// may branch further than their 8-bit offsets reach. Such a branch goes
+// through an unconditional jump placed right after it, as libpcap lays
+// out its long jumps, so the program may come out longer than the text.
+func AssembleLongJumps(text string) ([]*Instruction, error) {
+	program, labels, err := parseProgram(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// The destinations of each instruction's branches, jt then jf, or -1
+	// for a fall-through
+	n := len(program)
+	dest := make([][2]int, n)
+	for pc, ai := range program {
+		for k, target := range [2]*pendingJump{ai.jt, ai.jf} {
+			if target == nil {
+				dest[pc][k] = pc + 1
+				continue
+			}
+			off, err := resolve(target, pc, labels, n, 0xffffffff)
+			if err != nil {
+				return nil, err
+			}
+			dest[pc][k] = pc + 1 + int(off)
+		}
+		if ai.ja != nil {
+			off, err := resolve(ai.ja, pc, labels, n, 0xffffffff)
+			if err != nil {
+				return nil, err
+			}
+			dest[pc][0], dest[pc][1] = pc+1+int(off), -1
+		}
+	}
+	conditional := func(ai *asmInstruction) bool {
+		return ai.inst.Class() == ClassJMP && ai.inst.Code != OpJA
+	}
+
+	// far marks the branches that go through a trampoline. Trampolines
+	// only lengthen the jumps across them, so marks are never taken back.
+	far := make([][2]bool, n)
+	pos := make([]int, n+1)
+	for changed := true; changed; {
+		next := 0
+		for pc := range program {
+			pos[pc] = next
+			next++
+			if far[pc][0] {
+				next++
+			}
+			if far[pc][1] && !(far[pc][0] && dest[pc][0] == dest[pc][1]) {
+				next++
+			}
+		}
+		pos[n] = next
+		changed = false
+		for pc, ai := range program {
+			if !conditional(ai) {
+				continue
+			}
+			for k := range far[pc] {
+				if !far[pc][k] && pos[dest[pc][k]]-pos[pc]-1 > 255 {
+					far[pc][k], changed = true, true
+				}
+			}
+		}
+	}
+
+	instructions := make([]*Instruction, 0, pos[n])
+	for pc, ai := range program {
+		inst := ai.inst
+		var trampolines []int
+		switch {
+		case conditional(ai):
+			var encoded [2]uint8
+			for k := range far[pc] {
+				target := pos[dest[pc][k]]
+				switch {
+				case !far[pc][k]:
+					encoded[k] = uint8(target - pos[pc] - 1)
+				case k == 1 && far[pc][0] && dest[pc][0] == dest[pc][1]:
+					encoded[1] = encoded[0]
+				default:
+					encoded[k] = uint8(len(trampolines))
+					trampolines = append(trampolines, target)
+				}
+			}
+			inst.JT, inst.JF = encoded[0], encoded[1]
+		case ai.ja != nil:
+			inst.K = uint32(pos[dest[pc][0]] - pos[pc] - 1)
+		}
+		instructions = append(instructions, inst)
+		for _, target := range trampolines {
+			at := len(instructions)
+			instructions = append(instructions, &Instruction{Code: OpJA, K: uint32(target - at - 1)})
+		}
+	}
+	return instructions, nil
+}
+
+// parseProgram parses the lines of assembly text, returning the
+// instructions with their jumps unresolved and the index of each label
+func parseProgram(text string) ([]*asmInstruction, map[string]int, error) {
+	labels := make(map[string]int)
+	var program []*asmInstruction
+
+	for n, raw := range strings.Split(text, "\n") {
+		lineNo := n + 1
+		line := stripComment(raw)
+
+		// Leading "(000)" index from disassembly
+		if strings.HasPrefix(line, "(") {
+			if end := strings.Index(line, ")"); end > 0 {
+				line = strings.TrimSpace(line[end+1:])
+			}
+		}
+
+		// Labels, possibly several on one line
+		for {
+			colon := strings.Index(line, ":")
+			if colon <= 0 || strings.ContainsAny(line[:colon], " \t[#") {
+				break
+			}
+			name := line[:colon]
+			if _, exists := labels[name]; exists {
+				return nil, nil, fmt.Errorf("line %d: duplicate label '%s'", lineNo, name)
+			}
+			labels[name] = len(program)
+			line = strings.TrimSpace(line[colon+1:])
+		}
+
+		if line == "" {
+			continue
+		}
+
+		ai, err := parseLine(line, lineNo)
+		if err != nil {
+			return nil, nil, err
+		}
+		program = append(program, ai)
+	}
+
+	if len(program) == 0 {
+		return nil, nil, fmt.Errorf("no instructions found")
+	}
+	return program, labels, nil
+}
+
+// stripComment removes comments and surrounding whitespace from a line
+func stripComment(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// resolve converts a jump target into an offset relative to pc+1
+func resolve(target *pendingJump, pc int, labels map[string]int, size int, max int64) (int64, error) {
+	dest, ok := labels[target.target]
+	if !ok {
+		n, err := strconv.Atoi(target.target)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: undefined label '%s'", target.line, target.target)
+		}
+		dest = n
+	}
+	if dest >= size {
+		return 0, fmt.Errorf("line %d: jump target %s is past the end of the program: %w", target.line, target.target, ErrJumpOutOfRange)
+	}
+	off := int64(dest - pc - 1)
+	if off < 0 {
+		return 0, fmt.Errorf("line %d: backward jump to %s is not allowed", target.line, target.target)
+	}
+	if off > max {
+		return 0, fmt.Errorf("line %d: jump to %s is too far (%d instructions): %w", target.line, target.target, off, ErrJumpOutOfRange)
+	}
+	return off, nil
+}
+
+// parseLine parses a single instruction without its label
+func parseLine(line string, lineNo int) (*asmInstruction, error) {
+	op, rest, _ := strings.Cut(line, " ")
+	op = strings.ToLower(strings.TrimSpace(op))
+	rest = strings.TrimSpace(rest)
+	ai := &asmInstruction{inst: &Instruction{}, line: lineNo}
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("line %d: %s", lineNo, fmt.Sprintf(format, args...))
+	}
+
+	switch op {
+	case "ld", "ldh", "ldb":
+		size := map[string]uint16{"ld": SizeW, "ldh": SizeH, "ldb": SizeB}[op]
+		switch {
+		case rest == "#pktlen" || rest == "#len" || rest == "len":
+			ai.inst.Code = ClassLD | SizeW | ModeLEN
+		case strings.HasPrefix(rest, "#"):
+			k, err := parseK(rest[1:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = ClassLD|SizeW|ModeIMM, k
+		case strings.HasPrefix(rest, "M["):
+			k, err := parseBracket(rest[1:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = ClassLD|SizeW|ModeMEM, k
+		case strings.HasPrefix(rest, "[x"):
+			inner := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(rest, "[x"), "]"))
+			inner = strings.TrimSpace(strings.TrimPrefix(inner, "+"))
+			k := uint32(0)
+			if inner != "" {
+				v, err := parseK(inner)
+				if err != nil {
+					return nil, errorf("%v", err)
+				}
+				k = v
+			}
+			ai.inst.Code, ai.inst.K = ClassLD|size|ModeIND, k
+		case strings.HasPrefix(rest, "["):
+			k, err := parseBracket(rest)
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = ClassLD|size|ModeABS, k
+		default:
+			return nil, errorf("invalid %s operand '%s'", op, rest)
+		}
+
+	case "ldx", "ldxb":
+		switch {
+		case strings.HasPrefix(rest, "4*(["):
+			inner := strings.TrimSuffix(strings.TrimPrefix(rest, "4*("), ")")
+			inner = strings.TrimSuffix(inner, "&0xf")
+			k, err := parseBracket(inner)
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = ClassLDX|SizeB|ModeMSH, k
+		case rest == "#pktlen" || rest == "#len" || rest == "len":
+			ai.inst.Code = ClassLDX | SizeW | ModeLEN
+		case strings.HasPrefix(rest, "#"):
+			k, err := parseK(rest[1:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = ClassLDX|SizeW|ModeIMM, k
+		case strings.HasPrefix(rest, "M["):
+			k, err := parseBracket(rest[1:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = ClassLDX|SizeW|ModeMEM, k
+		default:
+			return nil, errorf("invalid %s operand '%s'", op, rest)
+		}
+
+	case "st", "stx":
+		if !strings.HasPrefix(rest, "M[") {
+			return nil, errorf("invalid %s operand '%s'", op, rest)
+		}
+		k, err := parseBracket(rest[1:])
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		ai.inst.Code, ai.inst.K = ClassST, k
+		if op == "stx" {
+			ai.inst.Code = ClassSTX
+		}
+
+	case "add", "sub", "mul", "div", "mod", "and", "or", "xor", "lsh", "rsh":
+		aluOps := map[string]uint16{
+			"add": ALUAdd, "sub": ALUSub, "mul": ALUMul, "div": ALUDiv, "mod": ALUMod,
+			"and": ALUAnd, "or": ALUOr, "xor": ALUXor, "lsh": ALULsh, "rsh": ALURsh,
+		}
+		ai.inst.Code = ClassALU | aluOps[op]
+		if rest == "x" || rest == "%x" {
+			ai.inst.Code |= SrcX
+			break
+		}
+		if !strings.HasPrefix(rest, "#") {
+			return nil, errorf("invalid %s operand '%s'", op, rest)
+		}
+		k, err := parseK(rest[1:])
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		ai.inst.K = k
+
+	case "neg":
+		ai.inst.Code = ClassALU | ALUNeg
+
+	case "ja", "jmp":
+		if rest == "" {
+			return nil, errorf("%s requires a target", op)
+		}
+		ai.inst.Code = OpJA
+		ai.ja = &pendingJump{target: rest, line: lineNo}
+
+	case "jeq", "jne", "jneq", "jgt", "jge", "jlt", "jle", "jset":
+		if err := parseConditional(ai, op, rest); err != nil {
+			return nil, errorf("%v", err)
+		}
+
+	case "ret":
+		switch {
+		case rest == "" || rest == "a" || rest == "%a":
+			ai.inst.Code = OpRetA
+		case strings.HasPrefix(rest, "#"):
+			k, err := parseK(rest[1:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ai.inst.Code, ai.inst.K = OpRetK, k
+		default:
+			return nil, errorf("invalid ret operand '%s'", rest)
+		}
+
+	case "tax":
+		ai.inst.Code = ClassMISC | MiscTAX
+
+	case "txa":
+		ai.inst.Code = ClassMISC | MiscTXA
+
+	default:
+		return nil, errorf("unknown instruction '%s'", op)
+	}
+
+	return ai, nil
+}
+
+// parseConditional parses a conditional jump in either bpf_asm form
+// ("jeq #k, Ltrue, Lfalse") or tcpdump -d form ("jeq #k jt N jf M")
+func parseConditional(ai *asmInstruction, op, rest string) error {
+	var operand string
+	var targets []string
+
+	if strings.Contains(rest, ",") {
+		parts := strings.Split(rest, ",")
+		operand = strings.TrimSpace(parts[0])
+		for _, p := range parts[1:] {
+			targets = append(targets, strings.TrimSpace(p))
+		}
+	} else {
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return fmt.Errorf("%s requires an operand", op)
+		}
+		operand = fields[0]
+		var jt, jf string
+		for i := 1; i+1 < len(fields); i += 2 {
+			switch fields[i] {
+			case "jt":
+				jt = fields[i+1]
+			case "jf":
+				jf = fields[i+1]
+			default:
+				return fmt.Errorf("unexpected '%s' in %s", fields[i], op)
+			}
+		}
+		if jt == "" {
+			return fmt.Errorf("%s requires a jt target", op)
+		}
+		targets = []string{jt}
+		if jf != "" {
+			targets = append(targets, jf)
+		}
+	}
+
+	if len(targets) == 0 || len(targets) > 2 {
+		return fmt.Errorf("%s requires one or two targets", op)
+	}
+
+	// jne/jlt/jle are encoded as jeq/jge/jgt with the targets swapped
+	base := map[string]uint16{
+		"jeq": JmpJEQ, "jne": JmpJEQ, "jneq": JmpJEQ, "jgt": JmpJGT,
+		"jge": JmpJGE, "jlt": JmpJGE, "jle": JmpJGT, "jset": JmpJSET,
+	}[op]
+	negate := op == "jne" || op == "jneq" || op == "jlt" || op == "jle"
+
+	ai.inst.Code = ClassJMP | base
+	switch {
+	case operand == "x" || operand == "%x":
+		ai.inst.Code |= SrcX
+	case strings.HasPrefix(operand, "#"):
+		k, err := parseK(operand[1:])
+		if err != nil {
+			return err
+		}
+		ai.inst.K = k
+	default:
+		return fmt.Errorf("invalid %s operand '%s'", op, operand)
+	}
+
+	taken := &pendingJump{target: targets[0], line: ai.line}
+	var other *pendingJump
+	if len(targets) == 2 {
+		other = &pendingJump{target: targets[1], line: ai.line}
+	}
+	if negate {
+		ai.jt, ai.jf = other, taken
+	} else {
+		ai.jt, ai.jf = taken, other
+	}
+	return nil
+}
+
+// parseBracket parses "[k]" into k
+func parseBracket(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return 0, fmt.Errorf("expected [offset], got '%s'", s)
+	}
+	return parseK(strings.TrimSpace(s[1 : len(s)-1]))
+}
+
+// parseK parses a decimal, hex (0x) or negative 32-bit constant
+func parseK(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		v, err := strconv.ParseInt(s, 0, 33)
+		if err != nil || v < -(1<<31) {
+			return 0, fmt.Errorf("invalid constant '%s'", s)
+		}
+		return uint32(int32(v)), nil
+	}
+	v, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid constant '%s'", s)
+	}
+	return uint32(v), nil
+}
+
+// FormatDDD renders a program in tcpdump -ddd format: the instruction count
+// followed by one "code jt jf k" line per instruction
+func FormatDDD(instructions []*Instruction) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d\n", len(instructions)))
+	for _, inst := range instructions {
+		sb.WriteString(fmt.Sprintf("%d %d %d %d\n", inst.Code, inst.JT, inst.JF, inst.K))
+	}
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package bpf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrJumpOutOfRange is wrapped by errors about a jump whose target is past
+// the end of the program, or too far for the instruction to encode
+var ErrJumpOutOfRange = errors.New("jump out of range")
+
+// Instruction represents a single classic BPF instruction
+type Instruction struct {
+	Code uint16 `json:"code"` // BPF opcode
+	JT   uint8  `json:"jt"`   // jump if true
+	JF   uint8  `json:"jf"`   // jump if false
+	K    uint32 `json:"k"`    // constant value
+}
+
+// String returns a human-readable representation of the instruction
+func (inst *Instruction) String() string {
+	return fmt.Sprintf("{ 0x%04x, %3d, %3d, 0x%08x }", inst.Code, inst.JT, inst.JF, inst.K)
+}
+
+// Class returns the instruction class (BPF_LD, BPF_JMP, ...)
+func (inst *Instruction) Class() uint16 {
+	return inst.Code & 0x07
+}
+
+// IsJump reports whether the instruction transfers control
+func (inst *Instruction) IsJump() bool {
+	return inst.Class() == ClassJMP
+}
+
+// IsReturn reports whether the instruction terminates the program
+func (inst *Instruction) IsReturn() bool {
+	return inst.Class() == ClassRET
+}
+
+// Code holds the fields shared by every generated BPF program
+type Code struct {
+	Instructions     []*Instruction // BPF instructions
+	FilterExpr       string         // filter the program was generated from
+	InstructionCount int            // number of instructions
+	LinkType         string         // data link type the offsets assume (empty means EN10MB)
+	Snaplen          int            // length accepting returns keep, as set by tcpdump -s (0 if unknown)
+}
@@ -0,0 +1,100 @@
+package bpf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// SockFilterSize is the size of one struct sock_filter, the instruction
+// layout a struct sock_fprog points to
+const SockFilterSize = 8
+
+// DecodeBinary decodes a program in the kernel's struct sock_filter layout:
+// code (u16), jt (u8), jf (u8) and k (u32) per instruction, in the byte
+// order of the machine the program was taken from
+func DecodeBinary(data []byte, order binary.ByteOrder) ([]*Instruction, error) {
+	if len(data) == 0 || len(data)%SockFilterSize != 0 {
+		return nil, fmt.Errorf("binary program of %d bytes is not a whole number of %d-byte instructions", len(data), SockFilterSize)
+	}
+	instructions := make([]*Instruction, 0, len(data)/SockFilterSize)
+	for off := 0; off < len(data); off += SockFilterSize {
+		instructions = append(instructions, &Instruction{
+			Code: order.Uint16(data[off:]),
+			JT:   data[off+2],
+			JF:   data[off+3],
+			K:    order.Uint32(data[off+4:]),
+		})
+	}
+	return instructions, nil
+}
+
+// EncodeBinary encodes a program in the struct sock_filter layout, the
+// inverse of DecodeBinary
+func EncodeBinary(instructions []*Instruction, order binary.ByteOrder) []byte {
+	data := make([]byte, len(instructions)*SockFilterSize)
+	for i, inst := range instructions {
+		off := i * SockFilterSize
+		order.PutUint16(data[off:], inst.Code)
+		data[off+2] = inst.JT
+		data[off+3] = inst.JF
+		order.PutUint32(data[off+4:], inst.K)
+	}
+	return data
+}
+
+// jsonInstruction is one instruction of a JSON program. Field names match
+// case-insensitively, so golang.org/x/net/bpf RawInstruction values
+// ({"Op": 40, "Jt": 0, "Jf": 0, "K": 12}) decode too.
+type jsonInstruction struct {
+	Code *uint16 `json:"code"`
+	Op   *uint16 `json:"op"`
+	JT   uint8   `json:"jt"`
+	JF   uint8   `json:"jf"`
+	K    uint32  `json:"k"`
+}
+
+// DecodeJSON decodes a JSON program: an array whose elements are either
+// objects with code (or op), jt, jf and k fields or [code, jt, jf, k]
+// arrays. The array may also be the "instructions" field of an object.
+func DecodeJSON(data []byte) ([]*Instruction, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		var wrapped struct {
+			Instructions []json.RawMessage `json:"instructions"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil || wrapped.Instructions == nil {
+			return nil, fmt.Errorf("JSON program must be an array of instructions: %w", err)
+		}
+		elements = wrapped.Instructions
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("JSON program has no instructions")
+	}
+
+	instructions := make([]*Instruction, 0, len(elements))
+	for i, element := range elements {
+		var tuple []uint32
+		if err := json.Unmarshal(element, &tuple); err == nil {
+			if len(tuple) != 4 || tuple[0] > 0xffff || tuple[1] > 0xff || tuple[2] > 0xff {
+				return nil, fmt.Errorf("instruction %d: want [code, jt, jf, k], got %s", i, element)
+			}
+			instructions = append(instructions, &Instruction{Code: uint16(tuple[0]), JT: uint8(tuple[1]), JF: uint8(tuple[2]), K: tuple[3]})
+			continue
+		}
+
+		var obj jsonInstruction
+		if err := json.Unmarshal(element, &obj); err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		code := obj.Code
+		if code == nil {
+			code = obj.Op
+		}
+		if code == nil {
+			return nil, fmt.Errorf("instruction %d: missing code", i)
+		}
+		instructions = append(instructions, &Instruction{Code: *code, JT: obj.JT, JF: obj.JF, K: obj.K})
+	}
+	return instructions, nil
+}
@@ -0,0 +1,127 @@
+package bpf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Disassemble renders a program as tcpdump -d style text, one instruction
+// per line prefixed with its index. Jump targets are absolute indices.
+func Disassemble(instructions []*Instruction) string {
+	var sb strings.Builder
+	for pc, inst := range instructions {
+		sb.WriteString(fmt.Sprintf("(%03d) %s\n", pc, inst.Mnemonic(pc)))
+	}
+	return sb.String()
+}
+
+// Mnemonic returns the assembly form of the instruction at index pc, e.g.
+// "ldh [12]" or "jeq #0x800 jt 2 jf 12"
+func (inst *Instruction) Mnemonic(pc int) string {
+	op, operand := inst.decode()
+	if inst.IsJump() && inst.Code&0xf0 != JmpJA {
+		jt := pc + 1 + int(inst.JT)
+		jf := pc + 1 + int(inst.JF)
+		return fmt.Sprintf("%-8s %-16s jt %d\tjf %d", op, operand, jt, jf)
+	}
+	if inst.IsJump() {
+		operand = fmt.Sprintf("%d", pc+1+int(inst.K))
+	}
+	if operand == "" {
+		return op
+	}
+	return fmt.Sprintf("%-8s %s", op, operand)
+}
+
+// decode splits the instruction into its mnemonic and operand text
+func (inst *Instruction) decode() (string, string) {
+	k := inst.K
+	switch inst.Class() {
+	case ClassLD:
+		op := map[uint16]string{SizeW: "ld", SizeH: "ldh", SizeB: "ldb"}[inst.Code&0x18]
+		switch inst.Code & 0xe0 {
+		case ModeABS:
+			return op, fmt.Sprintf("[%d]", k)
+		case ModeIND:
+			return op, fmt.Sprintf("[x + %d]", k)
+		case ModeIMM:
+			return "ld", fmt.Sprintf("#0x%x", k)
+		case ModeLEN:
+			return "ld", "#pktlen"
+		case ModeMEM:
+			return "ld", fmt.Sprintf("M[%d]", k)
+		}
+
+	case ClassLDX:
+		switch inst.Code & 0xe0 {
+		case ModeIMM:
+			return "ldx", fmt.Sprintf("#0x%x", k)
+		case ModeLEN:
+			return "ldx", "#pktlen"
+		case ModeMEM:
+			return "ldx", fmt.Sprintf("M[%d]", k)
+		case ModeMSH:
+			return "ldxb", fmt.Sprintf("4*([%d]&0xf)", k)
+		}
+
+	case ClassST:
+		return "st", fmt.Sprintf("M[%d]", k)
+
+	case ClassSTX:
+		return "stx", fmt.Sprintf("M[%d]", k)
+
+	case ClassALU:
+		names := map[uint16]string{
+			ALUAdd: "add", ALUSub: "sub", ALUMul: "mul", ALUDiv: "div", ALUMod: "mod",
+			ALUAnd: "and", ALUOr: "or", ALUXor: "xor", ALULsh: "lsh", ALURsh: "rsh",
+		}
+		op := inst.Code & 0xf0
+		if op == ALUNeg {
+			return "neg", ""
+		}
+		name, ok := names[op]
+		if !ok {
+			break
+		}
+		if inst.Code&SrcX != 0 {
+			return name, "x"
+		}
+		switch op {
+		case ALUAnd, ALUOr, ALUXor:
+			return name, fmt.Sprintf("#0x%x", k)
+		}
+		return name, fmt.Sprintf("#%d", k)
+
+	case ClassJMP:
+		names := map[uint16]string{JmpJA: "ja", JmpJEQ: "jeq", JmpJGT: "jgt", JmpJGE: "jge", JmpJSET: "jset"}
+		name, ok := names[inst.Code&0xf0]
+		if !ok {
+			break
+		}
+		if inst.Code&0xf0 == JmpJA {
+			return name, ""
+		}
+		if inst.Code&SrcX != 0 {
+			return name, "x"
+		}
+		return name, fmt.Sprintf("#0x%x", k)
+
+	case ClassRET:
+		switch inst.Code & 0x18 {
+		case SrcK:
+			return "ret", fmt.Sprintf("#%d", k)
+		case RetA:
+			return "ret", ""
+		}
+
+	case ClassMISC:
+		switch inst.Code & 0xf8 {
+		case MiscTAX:
+			return "tax", ""
+		case MiscTXA:
+			return "txa", ""
+		}
+	}
+
+	return "unimp", fmt.Sprintf("0x%x", inst.Code)
+}
@@ -0,0 +1,62 @@
+package bpf
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CArrayOptions control the C source produced by CArray
+type CArrayOptions struct {
+	Variable string // name of the struct sock_filter array (default "filter")
+	Filter   string // filter expression recorded in the leading comment
+}
+
+var cIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CArray renders a program as C source declaring a struct sock_filter
+// array, one tcpdump -dd style initializer per instruction with its
+// mnemonic as a comment, and a struct sock_fprog named VARIABLE_prog that
+// can be passed to setsockopt(SO_ATTACH_FILTER).
+func CArray(instructions []*Instruction, opts CArrayOptions) (string, error) {
+	name := opts.Variable
+	if name == "" {
+		name = "filter"
+	}
+	if !cIdentifier.MatchString(name) {
+		return "", fmt.Errorf("invalid C identifier '%s'", name)
+	}
+
+	var sb strings.Builder
+	if opts.Filter != "" {
+		// A "*/" in the expression would end the comment early
+		sb.WriteString(fmt.Sprintf("/* %s implements the filter \"%s\" */\n", name, strings.ReplaceAll(opts.Filter, "*/", "* /")))
+	}
+	sb.WriteString(fmt.Sprintf("static struct sock_filter %s[] = {\n", name))
+	for pc, inst := range instructions {
+		sb.WriteString(fmt.Sprintf("\t{ 0x%02x, %d, %d, 0x%08x }, /* %s */\n",
+			inst.Code, inst.JT, inst.JF, inst.K, collapseSpace(inst.Mnemonic(pc))))
+	}
+	sb.WriteString("};\n\n")
+	sb.WriteString(fmt.Sprintf("static struct sock_fprog %s_prog = {\n", name))
+	sb.WriteString(fmt.Sprintf("\t.len = sizeof(%s) / sizeof(%s[0]),\n", name, name))
+	sb.WriteString(fmt.Sprintf("\t.filter = %s,\n", name))
+	sb.WriteString("};\n")
+	return sb.String(), nil
+}
+
+// FormatJSON renders a program as a JSON array of {"code", "jt", "jf",
+// "k"} objects, one instruction per line, in the form DecodeJSON reads
+func FormatJSON(instructions []*Instruction) string {
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for i, inst := range instructions {
+		sep := ","
+		if i == len(instructions)-1 {
+			sep = ""
+		}
+		sb.WriteString(fmt.Sprintf("  {\"code\": %d, \"jt\": %d, \"jf\": %d, \"k\": %d}%s\n", inst.Code, inst.JT, inst.JF, inst.K, sep))
+	}
+	sb.WriteString("]\n")
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+package bpf
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+)
+
+// GoSnippetOptions control the Go source produced by GoSnippet
+type GoSnippetOptions struct {
+	Package  string // package clause; empty omits it, for pasting into existing files
+	Variable string // name of the generated variable (default "filter")
+	Filter   string // filter expression recorded in the doc comment
+}
+
+// GoSnippet renders a program as Go source declaring a
+// []bpf.RawInstruction from golang.org/x/net/bpf, with each instruction's
+// mnemonic as a trailing comment. The result is gofmt-formatted and can be
+// passed to bpf.NewVM or attached with SO_ATTACH_FILTER.
+func GoSnippet(instructions []*Instruction, opts GoSnippetOptions) (string, error) {
+	name := opts.Variable
+	if name == "" {
+		name = "filter"
+	}
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("invalid Go identifier '%s'", name)
+	}
+	if opts.Package != "" && !token.IsIdentifier(opts.Package) {
+		return "", fmt.Errorf("invalid package name '%s'", opts.Package)
+	}
+
+	var sb strings.Builder
+	if opts.Package != "" {
+		sb.WriteString("// Code generated by antrea-bpf-prototype. DO NOT EDIT.\n\n")
+		sb.WriteString(fmt.Sprintf("package %s\n\n", opts.Package))
+		sb.WriteString("import \"golang.org/x/net/bpf\"\n\n")
+	}
+
+	if opts.Filter != "" {
+		sb.WriteString(fmt.Sprintf("// %s implements the filter %q\n", name, opts.Filter))
+	} else {
+		sb.WriteString(fmt.Sprintf("// %s is a classic BPF program\n", name))
+	}
+	sb.WriteString(fmt.Sprintf("var %s = []bpf.RawInstruction{\n", name))
+	for pc, inst := range instructions {
+		sb.WriteString(fmt.Sprintf("\t{Op: 0x%02x, Jt: %d, Jf: %d, K: 0x%08x}, // %s\n",
+			inst.Code, inst.JT, inst.JF, inst.K, collapseSpace(inst.Mnemonic(pc))))
+	}
+	sb.WriteString("}\n")
+
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format Go snippet: %w", err)
+	}
+	return string(src), nil
+}
+
+// collapseSpace replaces runs of whitespace (including the tab in jump
+// mnemonics) with a single space
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,139 @@
+package bpf
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// GoTestPacket is a packet a generated test runs through the program, with
+// the verdict it expects
+type GoTestPacket struct {
+	Name   string
+	Data   []byte
+	Accept bool
+}
+
+// GoTestOptions control the Go test file produced by GoTest
+type GoTestOptions struct {
+	Package   string // package clause (default "capture", Antrea's packet capture package)
+	Name      string // test function name without the Test prefix (default derived from Filter)
+	Filter    string // filter expression the program implements
+	Reference string // where the expected verdicts came from, e.g. "tcpdump 4.99.4"
+	Call      string // Go expression returning the []bpf.Instruction under test; empty tests the recorded program
+}
+
+// GoTest renders a Go test file for golang.org/x/net/bpf that checks a
+// program against packets with known verdicts. The program is recorded as
+// []bpf.RawInstruction literals. When Call is given, the test first checks
+// that the code under test assembles to exactly the recorded program, then
+// runs every packet through bpf.NewVM and compares the verdict. The file
+// imports only the standard library and x/net/bpf, and is gofmt-formatted.
+func GoTest(instructions []*Instruction, packets []GoTestPacket, opts GoTestOptions) (string, error) {
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "capture"
+	}
+	if !token.IsIdentifier(pkg) {
+		return "", fmt.Errorf("invalid package name '%s'", pkg)
+	}
+	name := opts.Name
+	if name == "" {
+		name = "Filter" + exportedName(opts.Filter)
+	}
+	if !token.IsIdentifier("Test" + name) {
+		return "", fmt.Errorf("invalid test name '%s'", name)
+	}
+	program := "program" + name
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by antrea-bpf-prototype gen-tests. DO NOT EDIT.\n\n")
+	sb.WriteString(fmt.Sprintf("package %s\n\n", pkg))
+	sb.WriteString("import (\n\t\"testing\"\n\n\t\"golang.org/x/net/bpf\"\n)\n\n")
+
+	filter := opts.Filter
+	if filter == "" {
+		filter = "(unknown filter)"
+	}
+	sb.WriteString(fmt.Sprintf("// %s is the validated program for the filter %q\n", program, filter))
+	sb.WriteString(fmt.Sprintf("var %s = []bpf.RawInstruction{\n", program))
+	for pc, inst := range instructions {
+		sb.WriteString(fmt.Sprintf("\t{Op: 0x%02x, Jt: %d, Jf: %d, K: 0x%08x}, // %s\n",
+			inst.Code, inst.JT, inst.JF, inst.K, collapseSpace(inst.Mnemonic(pc))))
+	}
+	sb.WriteString("}\n\n")
+
+	reference := ""
+	if opts.Reference != "" {
+		reference = fmt.Sprintf(" against the reference (%s)", opts.Reference)
+	}
+	sb.WriteString(fmt.Sprintf("// Test%s runs %d packets through the program for\n// %q and checks each verdict%s.\n", name, len(packets), filter, reference))
+	sb.WriteString(fmt.Sprintf("func Test%s(t *testing.T) {\n", name))
+	if opts.Call != "" {
+		sb.WriteString(fmt.Sprintf("\tinstructions := %s\n", opts.Call))
+		sb.WriteString("\traw, err := bpf.Assemble(instructions)\n")
+		sb.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"failed to assemble the program: %v\", err)\n\t}\n")
+		sb.WriteString(fmt.Sprintf("\tif len(raw) != len(%s) {\n", program))
+		sb.WriteString(fmt.Sprintf("\t\tt.Fatalf(\"program has %%d instructions, want %%d\", len(raw), len(%s))\n\t}\n", program))
+		sb.WriteString("\tfor i := range raw {\n")
+		sb.WriteString(fmt.Sprintf("\t\tif raw[i] != %s[i] {\n", program))
+		sb.WriteString(fmt.Sprintf("\t\t\tt.Errorf(\"instruction %%d is %%+v, want %%+v\", i, raw[i], %s[i])\n\t\t}\n\t}\n\n", program))
+	} else {
+		sb.WriteString(fmt.Sprintf("\tinstructions, ok := bpf.Disassemble(%s)\n", program))
+		sb.WriteString("\tif !ok {\n\t\tt.Fatalf(\"failed to decode the program\")\n\t}\n")
+	}
+	sb.WriteString("\tvm, err := bpf.NewVM(instructions)\n")
+	sb.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"invalid program: %v\", err)\n\t}\n\n")
+
+	sb.WriteString("\tfor _, tc := range []struct {\n\t\tname   string\n\t\tpacket []byte\n\t\taccept bool\n\t}{\n")
+	for _, p := range packets {
+		// A slash would nest the subtest
+		sb.WriteString(fmt.Sprintf("\t\t{%q, %s, %t},\n", strings.ReplaceAll(p.Name, "/", " "), byteLiteral(p.Data), p.Accept))
+	}
+	sb.WriteString("\t} {\n")
+	sb.WriteString("\t\tt.Run(tc.name, func(t *testing.T) {\n")
+	sb.WriteString("\t\t\tn, err := vm.Run(tc.packet)\n")
+	sb.WriteString("\t\t\tif err != nil {\n\t\t\t\tt.Fatalf(\"program failed: %v\", err)\n\t\t\t}\n")
+	sb.WriteString("\t\t\tif accept := n > 0; accept != tc.accept {\n")
+	sb.WriteString("\t\t\t\tt.Errorf(\"accepted = %t, want %t\", accept, tc.accept)\n\t\t\t}\n")
+	sb.WriteString("\t\t})\n\t}\n}\n")
+
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format Go test: %w", err)
+	}
+	return string(src), nil
+}
+
+// byteLiteral writes data as a []byte literal
+func byteLiteral(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("0x%02x", b)
+	}
+	return "[]byte{" + strings.Join(parts, ", ") + "}"
+}
+
+// exportedName turns a filter expression into an exported Go name, e.g.
+// "tcp and dst port 80" into "TCPDstPort80"; joining words are left out
+func exportedName(expr string) string {
+	initialisms := map[string]string{"tcp": "TCP", "udp": "UDP", "icmp": "ICMP", "sctp": "SCTP", "ip": "IP", "ip6": "IP6", "arp": "ARP", "vlan": "VLAN", "vxlan": "VXLAN"}
+	var sb strings.Builder
+	words := strings.FieldsFunc(expr, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, w := range words {
+		switch w = strings.ToLower(w); {
+		case w == "and" || w == "or":
+			continue
+		case initialisms[w] != "":
+			sb.WriteString(initialisms[w])
+		default:
+			sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	if sb.Len() == 0 {
+		return "Program"
+	}
+	return sb.String()
+}
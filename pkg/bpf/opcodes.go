@@ -0,0 +1,84 @@
+package bpf
+
+// Instruction classes (see linux/filter.h)
+const (
+	ClassLD   = 0x00
+	ClassLDX  = 0x01
+	ClassST   = 0x02
+	ClassSTX  = 0x03
+	ClassALU  = 0x04
+	ClassJMP  = 0x05
+	ClassRET  = 0x06
+	ClassMISC = 0x07
+)
+
+// Load sizes
+const (
+	SizeW = 0x00
+	SizeH = 0x08
+	SizeB = 0x10
+)
+
+// Load addressing modes
+const (
+	ModeIMM = 0x00
+	ModeABS = 0x20
+	ModeIND = 0x40
+	ModeMEM = 0x60
+	ModeLEN = 0x80
+	ModeMSH = 0xa0
+)
+
+// Operand sources for ALU and jump instructions, and return sources
+const (
+	SrcK = 0x00
+	SrcX = 0x08
+	RetA = 0x10
+)
+
+// ALU operations
+const (
+	ALUAdd = 0x00
+	ALUSub = 0x10
+	ALUMul = 0x20
+	ALUDiv = 0x30
+	ALUOr  = 0x40
+	ALUAnd = 0x50
+	ALULsh = 0x60
+	ALURsh = 0x70
+	ALUNeg = 0x80
+	ALUMod = 0x90
+	ALUXor = 0xa0
+)
+
+// Jump operations
+const (
+	JmpJA   = 0x00
+	JmpJEQ  = 0x10
+	JmpJGT  = 0x20
+	JmpJGE  = 0x30
+	JmpJSET = 0x40
+)
+
+// Miscellaneous operations
+const (
+	MiscTAX = 0x00
+	MiscTXA = 0x80
+)
+
+// Commonly used complete opcodes
+const (
+	OpLdW    = ClassLD | SizeW | ModeABS  // ld [k]
+	OpLdH    = ClassLD | SizeH | ModeABS  // ldh [k]
+	OpLdB    = ClassLD | SizeB | ModeABS  // ldb [k]
+	OpLdHInd = ClassLD | SizeH | ModeIND  // ldh [x + k]
+	OpLdxMSH = ClassLDX | SizeB | ModeMSH // ldxb 4*([k]&0xf)
+	OpJA     = ClassJMP | JmpJA           // ja k
+	OpJEQ    = ClassJMP | JmpJEQ | SrcK   // jeq #k
+	OpJSET   = ClassJMP | JmpJSET | SrcK  // jset #k
+	OpRetK   = ClassRET | SrcK            // ret #k
+	OpRetA   = ClassRET | RetA            // ret a
+
+	// MemWords is the number of scratch memory slots
+	MemWords = 16
+)
@@ -0,0 +1,369 @@
+package bpf
+
+import (
+	"fmt"
+)
+
+// Patch operations
+const (
+	PatchRemove   = "remove"   // delete the instruction at Index
+	PatchInsert   = "insert"   // insert New before the instruction at Index
+	PatchReplace  = "replace"  // replace the instruction at Index, of another opcode, with New
+	PatchChangeK  = "change-k" // set the constant of the instruction at Index
+	PatchRetarget = "retarget" // point a branch of the jump at Index at another instruction
+)
+
+// Jump branches a retarget names
+const (
+	BranchTrue   = "jt" // the jt offset of a conditional jump
+	BranchFalse  = "jf" // the jf offset of a conditional jump
+	BranchAlways = "ja" // the k offset of ja
+)
+
+// PatchOp is one operation of a patch, in the style of JSON Patch. The
+// operations apply in order, each to the program the ones before it left,
+// so Index is a position at that point. Jumps stay attached to the
+// instructions they point at while instructions are inserted and removed,
+// as with labels in assembly; a jump to a removed instruction moves on to
+// the one after it. Inserted and replacing jumps point at the next
+// instruction until a retarget gives their targets, so their offsets in
+// New are zero. Retargets come last, when the program has the length of
+// the one patched towards, and give absolute instruction indices.
+type PatchOp struct {
+	Op     string       `json:"op"`
+	Index  int          `json:"index"`
+	Branch string       `json:"branch,omitempty"` // retarget: BranchTrue, BranchFalse or BranchAlways
+	Old    *Instruction `json:"old,omitempty"`    // remove, replace: the instruction taken out
+	New    *Instruction `json:"new,omitempty"`    // insert, replace: the instruction put in
+	From   *uint32      `json:"from,omitempty"`   // change-k: the old constant; retarget: the old target
+	To     *uint32      `json:"to,omitempty"`     // change-k: the new constant; retarget: the new target
+}
+
+// String formats the operation, e.g. "change-k 4: 0x800 -> 0x86dd"
+func (op *PatchOp) String() string {
+	switch op.Op {
+	case PatchRemove:
+		return fmt.Sprintf("remove %d: %s", op.Index, collapseSpace(op.Old.Mnemonic(op.Index)))
+	case PatchInsert:
+		return fmt.Sprintf("insert %d: %s", op.Index, collapseSpace(op.New.Mnemonic(op.Index)))
+	case PatchReplace:
+		return fmt.Sprintf("replace %d: %s -> %s", op.Index, collapseSpace(op.Old.Mnemonic(op.Index)), collapseSpace(op.New.Mnemonic(op.Index)))
+	case PatchChangeK:
+		return fmt.Sprintf("change-k %d: %#x -> %#x", op.Index, *op.From, *op.To)
+	case PatchRetarget:
+		return fmt.Sprintf("retarget %d %s: %d -> %d", op.Index, op.Branch, *op.From, *op.To)
+	}
+	return fmt.Sprintf("%s %d", op.Op, op.Index)
+}
+
+// Diff returns the operations that transform the program from into to.
+// The instructions are aligned for the fewest removals, insertions,
+// replacements and constant changes; jumps whose aligned targets still
+// differ are then retargeted. The number of operations is an edit distance
+// between the programs that ignores offsets shifted only by the edits.
+func Diff(from, to []*Instruction) []*PatchOp {
+	pairs := align(from, to)
+
+	// Removals, insertions and changes, tracking where each instruction
+	// of from ends up
+	var ops []*PatchOp
+	final := make([]int, len(from)) // final index of each instruction of from, or -1
+	origin := make([]int, len(to))  // instruction of from at each final index, or -1
+	pos := 0
+	for _, p := range pairs {
+		switch {
+		case p.to < 0:
+			ops = append(ops, &PatchOp{Op: PatchRemove, Index: pos, Old: copyInstruction(from[p.from])})
+			final[p.from] = -1
+		case p.from < 0:
+			ops = append(ops, &PatchOp{Op: PatchInsert, Index: pos, New: untargeted(to[p.to])})
+			origin[p.to] = -1
+			pos++
+		case from[p.from].Code != to[p.to].Code:
+			ops = append(ops, &PatchOp{Op: PatchReplace, Index: pos, Old: copyInstruction(from[p.from]), New: untargeted(to[p.to])})
+			// Jumps to it still land on it, its own targets are reset
+			final[p.from], origin[p.to] = pos, -1
+			pos++
+		default:
+			if !isJA(to[p.to]) && from[p.from].K != to[p.to].K {
+				ops = append(ops, &PatchOp{Op: PatchChangeK, Index: pos, From: u32(from[p.from].K), To: u32(to[p.to].K)})
+			}
+			final[p.from], origin[p.to] = pos, p.from
+			pos++
+		}
+	}
+
+	// where returns the final index a jump of from to target lands on: the
+	// target, or the first instruction after it that was kept
+	where := func(target int) int {
+		for ; target < len(from); target++ {
+			if final[target] >= 0 {
+				return final[target]
+			}
+		}
+		return len(to)
+	}
+	for j, inst := range to {
+		if !inst.IsJump() {
+			continue
+		}
+		for _, b := range branches(inst) {
+			current := j + 1
+			if i := origin[j]; i >= 0 {
+				current = where(i + 1 + b.offset(from[i]))
+			}
+			want := j + 1 + b.offset(inst)
+			if current != want {
+				ops = append(ops, &PatchOp{Op: PatchRetarget, Index: j, Branch: b.name, From: u32(uint32(current)), To: u32(uint32(want))})
+			}
+		}
+	}
+	return ops
+}
+
+// ApplyPatch applies operations to a program, returning the patched copy,
+// or an error when an operation does not fit the program, such as a
+// removal whose instruction differs from the one at its index
+func ApplyPatch(prog []*Instruction, ops []*PatchOp) ([]*Instruction, error) {
+	// Jumps are kept as pointers to the nodes they target while the
+	// program changes, and turned back into offsets at the end
+	type node struct {
+		inst   Instruction
+		jt, jf *node // targets, nil meaning the next instruction
+		alive  bool
+	}
+	nodes := make([]*node, len(prog))
+	for i, inst := range prog {
+		nodes[i] = &node{inst: *inst, alive: true}
+	}
+	for i, inst := range prog {
+		if !inst.IsJump() {
+			continue
+		}
+		for _, b := range branches(inst) {
+			t := i + 1 + b.offset(inst)
+			if t >= len(prog) {
+				return nil, fmt.Errorf("%w: instruction %d jumps to %d of %d", ErrJumpOutOfRange, i, t, len(prog))
+			}
+			if b.name == BranchFalse {
+				nodes[i].jf = nodes[t]
+			} else {
+				nodes[i].jt = nodes[t]
+			}
+		}
+	}
+	// resolve follows removed nodes to the instruction now in their place
+	forward := make(map[*node]*node)
+	resolve := func(n *node) *node {
+		for n != nil && !n.alive {
+			n = forward[n]
+		}
+		return n
+	}
+
+	for k, op := range ops {
+		bad := func(format string, args ...any) error {
+			return fmt.Errorf("patch operation %d (%s %d): %s", k, op.Op, op.Index, fmt.Sprintf(format, args...))
+		}
+		limit := len(nodes)
+		if op.Op == PatchInsert {
+			limit++
+		}
+		if op.Index < 0 || op.Index >= limit {
+			return nil, bad("index out of range of %d instructions", len(nodes))
+		}
+		switch op.Op {
+		case PatchRemove:
+			n := nodes[op.Index]
+			if op.Old != nil && n.inst != *op.Old {
+				return nil, bad("instruction is %s, not %s", n.inst.String(), op.Old.String())
+			}
+			n.alive = false
+			nodes = append(nodes[:op.Index], nodes[op.Index+1:]...)
+			if op.Index < len(nodes) {
+				forward[n] = nodes[op.Index]
+			}
+		case PatchInsert:
+			if op.New == nil {
+				return nil, bad("no instruction to insert")
+			}
+			n := &node{inst: *op.New, alive: true}
+			nodes = append(nodes[:op.Index], append([]*node{n}, nodes[op.Index:]...)...)
+		case PatchReplace:
+			if op.New == nil {
+				return nil, bad("no replacement instruction")
+			}
+			n := nodes[op.Index]
+			if op.Old != nil && n.inst != *op.Old {
+				return nil, bad("instruction is %s, not %s", n.inst.String(), op.Old.String())
+			}
+			n.inst, n.jt, n.jf = *op.New, nil, nil
+		case PatchChangeK:
+			n := nodes[op.Index]
+			if op.To == nil || (op.From != nil && n.inst.K != *op.From) {
+				return nil, bad("constant is %#x, not the one the operation changes", n.inst.K)
+			}
+			n.inst.K = *op.To
+		case PatchRetarget:
+			n := nodes[op.Index]
+			if !n.inst.IsJump() || op.To == nil {
+				return nil, bad("not a jump or no target")
+			}
+			t := int(*op.To)
+			if t <= op.Index || t >= len(nodes) {
+				return nil, bad("target %d is not after the jump and inside the program", t)
+			}
+			switch op.Branch {
+			case BranchTrue, BranchAlways:
+				n.jt = nodes[t]
+			case BranchFalse:
+				n.jf = nodes[t]
+			default:
+				return nil, bad("unknown branch '%s'", op.Branch)
+			}
+		default:
+			return nil, bad("unknown operation")
+		}
+	}
+
+	index := make(map[*node]int, len(nodes))
+	for i, n := range nodes {
+		index[n] = i
+	}
+	patched := make([]*Instruction, len(nodes))
+	for i, n := range nodes {
+		inst := n.inst
+		if inst.IsJump() {
+			offset := func(target *node) (int, error) {
+				t := resolve(target)
+				if t == nil {
+					return 0, nil
+				}
+				if index[t] <= i {
+					return 0, fmt.Errorf("%w: instruction %d would jump back to %d", ErrJumpOutOfRange, i, index[t])
+				}
+				return index[t] - i - 1, nil
+			}
+			jt, err := offset(n.jt)
+			if err != nil {
+				return nil, err
+			}
+			if isJA(&inst) {
+				inst.K = uint32(jt)
+			} else {
+				jf, err := offset(n.jf)
+				if err != nil {
+					return nil, err
+				}
+				if jt > 0xff || jf > 0xff {
+					return nil, fmt.Errorf("%w: instruction %d would jump %d and %d ahead", ErrJumpOutOfRange, i, jt, jf)
+				}
+				inst.JT, inst.JF = uint8(jt), uint8(jf)
+			}
+		}
+		patched[i] = &inst
+	}
+	return patched, nil
+}
+
+// pair aligns an instruction of each program, or one with none (-1)
+type pair struct {
+	from, to int
+}
+
+// align pairs the instructions of two programs for the fewest edits: a
+// pair of the same opcode costs nothing, or one constant change when the
+// constants differ, and a pair of different opcodes costs a replacement,
+// as does an instruction left unpaired. Offsets are ignored, since edits
+// elsewhere shift them.
+func align(a, b []*Instruction) []pair {
+	cost := func(x, y *Instruction) int {
+		switch {
+		case x.Code != y.Code:
+			return 1
+		case x.K == y.K || isJA(x):
+			return 0
+		}
+		return 1
+	}
+	n, m := len(a), len(b)
+	d := make([][]int, n+1)
+	for i := range d {
+		d[i] = make([]int, m+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			d[i][j] = min(d[i-1][j-1]+cost(a[i-1], b[j-1]), d[i-1][j]+1, d[i][j-1]+1)
+		}
+	}
+
+	// Walk back, preferring pairs so that instructions stay put
+	var pairs []pair
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+cost(a[i-1], b[j-1]):
+			i, j = i-1, j-1
+			pairs = append(pairs, pair{i, j})
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			i--
+			pairs = append(pairs, pair{i, -1})
+		default:
+			j--
+			pairs = append(pairs, pair{-1, j})
+		}
+	}
+	for l, r := 0, len(pairs)-1; l < r; l, r = l+1, r-1 {
+		pairs[l], pairs[r] = pairs[r], pairs[l]
+	}
+	return pairs
+}
+
+// branch is a jump offset a retarget can name
+type branch struct {
+	name   string
+	offset func(*Instruction) int
+}
+
+// branches returns the offsets of a jump: k for ja, jt and jf otherwise
+func branches(inst *Instruction) []branch {
+	if isJA(inst) {
+		return []branch{{BranchAlways, func(i *Instruction) int { return int(i.K) }}}
+	}
+	return []branch{
+		{BranchTrue, func(i *Instruction) int { return int(i.JT) }},
+		{BranchFalse, func(i *Instruction) int { return int(i.JF) }},
+	}
+}
+
+// isJA reports whether the instruction is an unconditional jump, whose
+// constant is its offset
+func isJA(inst *Instruction) bool {
+	return inst.Code == ClassJMP|JmpJA
+}
+
+// untargeted copies an instruction with its jump offsets cleared, as an
+// inserted or replacing instruction carries them until retargeted
+func untargeted(inst *Instruction) *Instruction {
+	c := *inst
+	if c.IsJump() {
+		c.JT, c.JF = 0, 0
+		if isJA(&c) {
+			c.K = 0
+		}
+	}
+	return &c
+}
+
+func copyInstruction(inst *Instruction) *Instruction {
+	c := *inst
+	return &c
+}
+
+func u32(v uint32) *uint32 {
+	return &v
+}
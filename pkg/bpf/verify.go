@@ -0,0 +1,121 @@
+package bpf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxInstructions is the longest classic program the kernel loads
+// (BPF_MAXINSNS)
+const MaxInstructions = 4096
+
+// ErrRejected is wrapped by Verify's errors
+var ErrRejected = errors.New("rejected by the kernel's BPF checker")
+
+// validOpcodes are the opcodes the kernel's classic BPF checker accepts
+var validOpcodes = func() map[uint16]bool {
+	codes := []uint16{
+		ClassLD | SizeW | ModeABS, ClassLD | SizeH | ModeABS, ClassLD | SizeB | ModeABS,
+		ClassLD | SizeW | ModeIND, ClassLD | SizeH | ModeIND, ClassLD | SizeB | ModeIND,
+		ClassLD | ModeIMM, ClassLD | ModeMEM, ClassLD | SizeW | ModeLEN,
+		ClassLDX | ModeIMM, ClassLDX | ModeMEM, ClassLDX | SizeW | ModeLEN, ClassLDX | SizeB | ModeMSH,
+		ClassST, ClassSTX,
+		ClassALU | ALUNeg,
+		ClassRET | SrcK, ClassRET | RetA,
+		ClassMISC | MiscTAX, ClassMISC | MiscTXA,
+		ClassJMP | JmpJA,
+	}
+	for _, op := range []uint16{ALUAdd, ALUSub, ALUMul, ALUDiv, ALUMod, ALUAnd, ALUOr, ALUXor, ALULsh, ALURsh} {
+		codes = append(codes, ClassALU|op|SrcK, ClassALU|op|SrcX)
+	}
+	for _, op := range []uint16{JmpJEQ, JmpJGT, JmpJGE, JmpJSET} {
+		codes = append(codes, ClassJMP|op|SrcK, ClassJMP|op|SrcX)
+	}
+	valid := make(map[uint16]bool)
+	for _, c := range codes {
+		valid[c] = true
+	}
+	return valid
+}()
+
+// Verify applies the checks the kernel makes before attaching a classic
+// program (bpf_check_classic): a length of 1 to MaxInstructions, known
+// opcodes, no division by a constant zero or shift by 32 or more, scratch
+// indices below MemWords, jumps inside the program, a return as the last
+// instruction, and no scratch slot read before every path to it has
+// stored to it. The first problem is returned as an error wrapping
+// ErrRejected, and ErrJumpOutOfRange too for a jump.
+func Verify(prog []*Instruction) error {
+	if len(prog) == 0 || len(prog) > MaxInstructions {
+		return fmt.Errorf("%w: %d instructions, must be 1 to %d", ErrRejected, len(prog), MaxInstructions)
+	}
+
+	for pc, inst := range prog {
+		if !validOpcodes[inst.Code] {
+			return fmt.Errorf("%w: instruction %d: invalid opcode 0x%04x", ErrRejected, pc, inst.Code)
+		}
+		switch inst.Class() {
+		case ClassALU:
+			op := inst.Code & 0xf0
+			switch {
+			case inst.Code&SrcX != 0:
+			case (op == ALUDiv || op == ALUMod) && inst.K == 0:
+				return fmt.Errorf("%w: instruction %d: division by zero", ErrRejected, pc)
+			case (op == ALULsh || op == ALURsh) && inst.K >= 32:
+				return fmt.Errorf("%w: instruction %d: shift by %d", ErrRejected, pc, inst.K)
+			}
+		case ClassLD, ClassLDX, ClassST, ClassSTX:
+			mem := inst.Code&0xe0 == ModeMEM || inst.Class() == ClassST || inst.Class() == ClassSTX
+			if mem && inst.K >= MemWords {
+				return fmt.Errorf("%w: instruction %d: scratch index %d out of range", ErrRejected, pc, inst.K)
+			}
+		case ClassJMP:
+			if inst.Code&0xf0 == JmpJA {
+				if uint64(pc)+1+uint64(inst.K) >= uint64(len(prog)) {
+					return fmt.Errorf("%w: instruction %d: %w", ErrRejected, pc, ErrJumpOutOfRange)
+				}
+			} else if pc+1+int(max(inst.JT, inst.JF)) >= len(prog) {
+				return fmt.Errorf("%w: instruction %d: %w", ErrRejected, pc, ErrJumpOutOfRange)
+			}
+		}
+	}
+	if !prog[len(prog)-1].IsReturn() {
+		return fmt.Errorf("%w: the last instruction does not return", ErrRejected)
+	}
+	return checkScratch(prog)
+}
+
+// checkScratch rejects loads from scratch slots that some path reaches
+// without a store. Jumps only go forward, so one pass carries the slots
+// stored so far to every jump target.
+func checkScratch(prog []*Instruction) error {
+	const all = 1<<MemWords - 1
+	reaching := make([]uint16, len(prog))
+	for i := range reaching {
+		reaching[i] = all
+	}
+
+	var stored uint16
+	for pc, inst := range prog {
+		stored &= reaching[pc]
+		switch inst.Class() {
+		case ClassST, ClassSTX:
+			stored |= 1 << inst.K
+		case ClassLD, ClassLDX:
+			if inst.Code&0xe0 == ModeMEM && stored&(1<<inst.K) == 0 {
+				return fmt.Errorf("%w: instruction %d: scratch slot %d read before it is stored", ErrRejected, pc, inst.K)
+			}
+		case ClassJMP:
+			if inst.Code&0xf0 == JmpJA {
+				reaching[pc+1+int(inst.K)] &= stored
+			} else {
+				reaching[pc+1+int(inst.JT)] &= stored
+				reaching[pc+1+int(inst.JF)] &= stored
+			}
+			stored = all
+		case ClassRET:
+			stored = all
+		}
+	}
+	return nil
+}
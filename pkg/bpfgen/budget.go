@@ -0,0 +1,49 @@
+package bpfgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClauseCost is the number of instructions a program spends on one clause
+type ClauseCost struct {
+	Clause       string
+	Instructions int
+}
+
+// ClauseCosts counts the instructions of each clause, largest first, with
+// ties in program order. A clause split into several spans is counted once;
+// trampolines count towards the check they serve.
+func (bpf *BPFCode) ClauseCosts() []ClauseCost {
+	var costs []ClauseCost
+	index := make(map[string]int)
+	for _, clause := range bpf.Sources {
+		i, ok := index[clause]
+		if !ok {
+			i = len(costs)
+			index[clause] = i
+			costs = append(costs, ClauseCost{Clause: clause})
+		}
+		costs[i].Instructions++
+	}
+	sort.SliceStable(costs, func(i, j int) bool { return costs[i].Instructions > costs[j].Instructions })
+	return costs
+}
+
+// BudgetError reports a generated program longer than
+// Options.MaxInstructions, with the clauses that took the instructions
+type BudgetError struct {
+	Budget       int
+	Instructions int
+	Clauses      []ClauseCost // largest first
+}
+
+func (e *BudgetError) Error() string {
+	parts := make([]string, len(e.Clauses))
+	for i, c := range e.Clauses {
+		parts[i] = fmt.Sprintf("%d for %s", c.Instructions, c.Clause)
+	}
+	return fmt.Sprintf("program needs %d instructions, over the budget of %d: %s",
+		e.Instructions, e.Budget, strings.Join(parts, "; "))
+}
@@ -0,0 +1,139 @@
+package bpfgen
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// maxJumpOffset is the largest conditional jump offset an instruction can encode
+const maxJumpOffset = 255
+
+// Fragment is an independently generated program piece. Returning a
+// non-zero value means "continue with the next fragment" and returning zero
+// rejects the packet, so a standalone filter program is also a fragment.
+type Fragment struct {
+	Name         string             // label used in error messages
+	Instructions []*bpf.Instruction // fragment body
+}
+
+// NewFragment wraps a generated program as a composable fragment
+func NewFragment(name string, instructions []*bpf.Instruction) *Fragment {
+	return &Fragment{Name: name, Instructions: instructions}
+}
+
+// Shifted returns a copy of the fragment whose packet loads are moved by
+// delta bytes. This lets a matcher written for an outer header run against
+// an encapsulated header at a fixed offset.
+func (f *Fragment) Shifted(delta uint32) *Fragment {
+	shifted := &Fragment{Name: f.Name, Instructions: make([]*bpf.Instruction, 0, len(f.Instructions))}
+	for _, inst := range f.Instructions {
+		c := *inst
+		switch {
+		case c.Class() == bpf.ClassLD && (c.Code&0xe0 == bpf.ModeABS || c.Code&0xe0 == bpf.ModeIND):
+			// ld abs / ld ind
+			c.K += delta
+		case c.Class() == bpf.ClassLDX && c.Code&0xe0 == bpf.ModeMSH:
+			// ldxb 4*([k]&0xf)
+			c.K += delta
+		}
+		shifted.Instructions = append(shifted.Instructions, &c)
+	}
+	return shifted
+}
+
+// Compose concatenates fragments into one program that accepts a packet only
+// if every fragment passes it. Pass returns of all but the last fragment are
+// relocated into jumps to the start of the following fragment; conditional
+// jumps that targeted such a return are retargeted directly when the new
+// offset fits in 8 bits. The last fragment's returns are kept verbatim.
+func Compose(fragments ...*Fragment) ([]*bpf.Instruction, error) {
+	if len(fragments) == 0 {
+		return nil, fmt.Errorf("no fragments to compose")
+	}
+
+	var program []*bpf.Instruction
+	for n, frag := range fragments {
+		if len(frag.Instructions) == 0 {
+			return nil, fmt.Errorf("fragment %q is empty", frag.Name)
+		}
+
+		start := len(program)
+		next := start + len(frag.Instructions)
+		last := n == len(fragments)-1
+
+		body := make([]*bpf.Instruction, len(frag.Instructions))
+		for i, inst := range frag.Instructions {
+			c := *inst
+			body[i] = &c
+		}
+
+		if err := checkJumps(frag.Name, body); err != nil {
+			return nil, err
+		}
+
+		if !last {
+			if err := relocatePasses(frag.Name, body, next-start); err != nil {
+				return nil, err
+			}
+		}
+
+		program = append(program, body...)
+	}
+
+	return program, nil
+}
+
+// relocatePasses rewrites pass returns in a fragment body into jumps to
+// the instruction at index end (relative to the start of the body)
+func relocatePasses(name string, body []*bpf.Instruction, end int) error {
+	isPass := make([]bool, len(body))
+	for i, inst := range body {
+		switch {
+		case inst.Code == bpf.OpRetA:
+			return fmt.Errorf("fragment %q returns the accumulator at %d, which cannot be composed", name, i)
+		case inst.Code == bpf.OpRetK && inst.K != 0:
+			isPass[i] = true
+		}
+	}
+
+	// Retarget conditional jumps that land on a pass return
+	for i, inst := range body {
+		if !inst.IsJump() || inst.Code == bpf.OpJA {
+			continue
+		}
+		if t := i + 1 + int(inst.JT); isPass[t] && end-i-1 <= maxJumpOffset {
+			inst.JT = uint8(end - i - 1)
+		}
+		if t := i + 1 + int(inst.JF); isPass[t] && end-i-1 <= maxJumpOffset {
+			inst.JF = uint8(end - i - 1)
+		}
+	}
+
+	// Replace the returns themselves so fall-through still reaches the next fragment
+	for i, inst := range body {
+		if isPass[i] {
+			*inst = bpf.Instruction{Code: bpf.OpJA, K: uint32(end - i - 1)}
+		}
+	}
+	return nil
+}
+
+// checkJumps verifies that every jump in a fragment stays inside it
+func checkJumps(name string, body []*bpf.Instruction) error {
+	for i, inst := range body {
+		if !inst.IsJump() {
+			continue
+		}
+		targets := []int{i + 1 + int(inst.JT), i + 1 + int(inst.JF)}
+		if inst.Code == bpf.OpJA {
+			targets = []int{i + 1 + int(inst.K)}
+		}
+		for _, t := range targets {
+			if t >= len(body) {
+				return fmt.Errorf("fragment %q jumps outside itself at instruction %d: %w", name, i, bpf.ErrJumpOutOfRange)
+			}
+		}
+	}
+	return nil
+}
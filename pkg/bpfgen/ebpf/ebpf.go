@@ -0,0 +1,383 @@
+// Package ebpf generates eBPF filter programs for XDP and tc from the same
+// PacketFilter model as the classic BPF generators, so both datapath
+// targets can be validated from one filter.
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	ciliumebpf "github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Target is the hook the program is generated for
+type Target string
+
+const (
+	TargetXDP Target = "xdp" // struct xdp_md context
+	TargetTC  Target = "tc"  // struct __sk_buff context (sched_cls)
+)
+
+// Verdicts returned for matching and non-matching packets
+const (
+	XDPDrop = 1 // XDP_DROP
+	XDPPass = 2 // XDP_PASS
+	TCOK    = 0 // TC_ACT_OK
+	TCShot  = 2 // TC_ACT_SHOT
+)
+
+// Packet offsets within an untagged Ethernet/IPv4 frame
+const (
+	offEtherType = 12
+	offIPHeader  = 14
+	offFragment  = 20
+	offProtocol  = 23
+	offSrcIP     = 26
+	offDstIP     = 30
+)
+
+// nativeEndian is the host byte order, which the kernel expects. cilium/ebpf
+// only accepts the concrete binary.LittleEndian and binary.BigEndian values.
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	if binary.NativeEndian.Uint16([]byte{0x12, 0x34}) == 0x1234 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// ParseTarget parses "xdp" or "tc"
+func ParseTarget(s string) (Target, error) {
+	switch Target(strings.ToLower(s)) {
+	case TargetXDP:
+		return TargetXDP, nil
+	case TargetTC:
+		return TargetTC, nil
+	}
+	return "", fmt.Errorf("unknown eBPF target '%s' (want xdp or tc)", s)
+}
+
+// context returns the offsets of the data and data_end fields in the
+// target's context struct, and the match and miss verdicts
+func (t Target) context() (data, dataEnd int16, match, miss int32) {
+	if t == TargetTC {
+		return 76, 80, TCOK, TCShot
+	}
+	return 0, 4, XDPPass, XDPDrop
+}
+
+// Program is a generated eBPF filter program
+type Program struct {
+	Target       Target
+	FilterExpr   string
+	Instructions asm.Instructions
+}
+
+// String returns the program as an annotated eBPF assembly listing
+func (p *Program) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("eBPF Filter (%s): %s\n", p.Target, p.FilterExpr))
+	sb.WriteString(fmt.Sprintf("Instructions: %d\n", len(p.Instructions)))
+	sb.WriteString(fmt.Sprintf("%v", p.Instructions))
+	return sb.String()
+}
+
+// Bytecode returns the program in the kernel's encoding for this host
+func (p *Program) Bytecode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.Instructions.Marshal(&buf, nativeEndian); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Spec returns a program spec that can be loaded with cilium/ebpf
+func (p *Program) Spec() *ciliumebpf.ProgramSpec {
+	spec := &ciliumebpf.ProgramSpec{
+		Name:         "pkt_filter",
+		Type:         ciliumebpf.XDP,
+		Instructions: p.Instructions,
+		License:      "Apache-2.0",
+	}
+	if p.Target == TargetTC {
+		spec.Type = ciliumebpf.SchedCLS
+	}
+	return spec
+}
+
+// Generate builds an eBPF program accepting the same packets as the
+// classic BPF generators. Packets are read with direct packet access;
+// matching packets get the target's pass verdict and all others its drop
+// verdict. Like the prototype, only IPv4 is supported.
+func Generate(f *filter.PacketFilter, target Target) (*Program, error) {
+	// With VLAN offload the tag is moved into skb metadata before tc runs,
+	// so a fixed in-packet layout is only right for some XDP drivers
+	if f.VLAN || f.VLANID != 0 {
+		return nil, fmt.Errorf("eBPF generator does not support VLAN filters")
+	}
+	// XDP and tc see Ethernet frames on the devices Antrea attaches to
+	if !f.LinkType.IsEthernet() {
+		return nil, fmt.Errorf("eBPF generator supports the EN10MB link type only, got %s", f.LinkType)
+	}
+	// Offsets behind a tunnel depend on the outer IP header and the
+	// Geneve options, which direct packet access would have to bound check
+	// one by one
+	if f.Tunnel != "" {
+		return nil, fmt.Errorf("eBPF generator does not support %s filters", f.Tunnel)
+	}
+
+	g := &generator{aliases: make(map[string]string)}
+	dataOff, dataEndOff, match, miss := target.context()
+
+	// R2 = data, R3 = data_end
+	g.emit(asm.LoadMem(asm.R2, asm.R1, dataOff, asm.Word))
+	g.emit(asm.LoadMem(asm.R3, asm.R1, dataEndOff, asm.Word))
+
+	// One bounds check covers every fixed-offset load the filter needs,
+	// mirroring classic BPF, which rejects a packet on an out-of-bounds load
+	g.checkBounds(asm.R2, requiredLength(f))
+
+	// ARP and other non-IP filters test the EtherType alone
+	etherType := uint32(0x0800)
+	if proto := f.EtherProto(); proto != 0 {
+		etherType = uint32(proto)
+	}
+	g.emit(asm.LoadMem(asm.R0, asm.R2, offEtherType, asm.Half))
+	g.emit(asm.HostTo(asm.BE, asm.R0, asm.Half))
+	g.expect(etherType, "miss")
+
+	if f.Protocol != "" && f.EtherProto() == 0 {
+		g.emit(asm.LoadMem(asm.R0, asm.R2, offProtocol, asm.Byte))
+		g.expect(uint32(f.IPProtocol()), "miss")
+	} else if f.HasPorts() {
+		// Like tcpdump's bare "port", only transports with ports can match
+		g.emit(asm.LoadMem(asm.R0, asm.R2, offProtocol, asm.Byte))
+		g.anyOf([]uint32{132, 6, 17}, "transport", "miss")
+	}
+
+	if f.SrcIP != "" {
+		if err := g.network(offSrcIP, f.SrcIP, "miss"); err != nil {
+			return nil, err
+		}
+	}
+	if f.DstIP != "" {
+		if err := g.network(offDstIP, f.DstIP, "miss"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Either direction: the destination is only tested when the source
+	// does not match
+	if f.HostIP != "" {
+		if err := g.network(offSrcIP, f.HostIP, "host_dst"); err != nil {
+			return nil, err
+		}
+		g.emit(asm.Ja.Label("host"))
+		g.label("host_dst")
+		if err := g.network(offDstIP, f.HostIP, "miss"); err != nil {
+			return nil, err
+		}
+		g.label("host")
+	}
+
+	if len(f.Between) == 2 {
+		a, b := f.Between[0], f.Between[1]
+		if err := g.network(offSrcIP, a, "reverse"); err != nil {
+			return nil, err
+		}
+		if err := g.network(offDstIP, b, "reverse"); err != nil {
+			return nil, err
+		}
+		g.emit(asm.Ja.Label("between"))
+		g.label("reverse")
+		if err := g.network(offSrcIP, b, "miss"); err != nil {
+			return nil, err
+		}
+		if err := g.network(offDstIP, a, "miss"); err != nil {
+			return nil, err
+		}
+		g.label("between")
+	}
+
+	if f.HasPorts() {
+		// Non-first fragments carry no transport header
+		g.emit(asm.LoadMem(asm.R0, asm.R2, offFragment, asm.Half))
+		g.emit(asm.HostTo(asm.BE, asm.R0, asm.Half))
+		g.emit(asm.And.Imm32(asm.R0, 0x1fff))
+		g.emit(asm.JNE.Imm(asm.R0, 0, "miss"))
+
+		// R5 = data + IP header length, then bounds-check the ports
+		g.emit(asm.LoadMem(asm.R4, asm.R2, offIPHeader, asm.Byte))
+		g.emit(asm.And.Imm(asm.R4, 0x0f))
+		g.emit(asm.LSh.Imm(asm.R4, 2))
+		g.emit(asm.Mov.Reg(asm.R5, asm.R2))
+		g.emit(asm.Add.Reg(asm.R5, asm.R4))
+		portsEnd := int32(offIPHeader + 2)
+		if len(f.DstPortList()) > 0 || f.Port != 0 {
+			portsEnd += 2
+		}
+		g.checkBounds(asm.R5, portsEnd)
+
+		if ports := f.SrcPortList(); len(ports) > 0 {
+			g.emit(asm.LoadMem(asm.R0, asm.R5, offIPHeader, asm.Half))
+			g.emit(asm.HostTo(asm.BE, asm.R0, asm.Half))
+			g.anyOf(portValues(ports), "sport", "miss")
+		}
+		if ports := f.DstPortList(); len(ports) > 0 {
+			g.emit(asm.LoadMem(asm.R0, asm.R5, offIPHeader+2, asm.Half))
+			g.emit(asm.HostTo(asm.BE, asm.R0, asm.Half))
+			g.anyOf(portValues(ports), "dport", "miss")
+		}
+		if f.Port != 0 {
+			g.emit(asm.LoadMem(asm.R0, asm.R5, offIPHeader, asm.Half))
+			g.emit(asm.HostTo(asm.BE, asm.R0, asm.Half))
+			g.emit(asm.JEq.Imm(asm.R0, int32(f.Port), "port"))
+			g.emit(asm.LoadMem(asm.R0, asm.R5, offIPHeader+2, asm.Half))
+			g.emit(asm.HostTo(asm.BE, asm.R0, asm.Half))
+			g.emit(asm.JNE.Imm(asm.R0, int32(f.Port), "miss"))
+			g.label("port")
+		}
+	}
+
+	g.label("match")
+	g.emit(asm.Mov.Imm(asm.R0, match))
+	g.emit(asm.Return())
+	g.label("miss")
+	g.emit(asm.Mov.Imm(asm.R0, miss))
+	g.emit(asm.Return())
+
+	insns := g.resolve()
+
+	// Marshal once so jump offsets are filled in for listings
+	if err := insns.Marshal(&bytes.Buffer{}, nativeEndian); err != nil {
+		return nil, fmt.Errorf("failed to assemble eBPF program: %w", err)
+	}
+
+	return &Program{
+		Target:       target,
+		FilterExpr:   f.ToTcpdumpFilter(),
+		Instructions: insns,
+	}, nil
+}
+
+// requiredLength is the shortest frame for which every fixed-offset load
+// of the filter is in bounds
+func requiredLength(f *filter.PacketFilter) int32 {
+	need := int32(offEtherType + 2)
+	require := func(n int32) {
+		if n > need {
+			need = n
+		}
+	}
+	if (f.Protocol != "" && f.EtherProto() == 0) || f.HasPorts() {
+		require(offProtocol + 1)
+	}
+	if f.HasPorts() {
+		require(offFragment + 2)
+	}
+	if f.SrcIP != "" {
+		require(offSrcIP + 4)
+	}
+	if f.DstIP != "" || f.HostIP != "" || len(f.Between) == 2 {
+		require(offDstIP + 4)
+	}
+	return need
+}
+
+// generator accumulates instructions and resolves labels
+type generator struct {
+	insns   asm.Instructions
+	pending []string          // labels for the next emitted instruction
+	aliases map[string]string // label -> symbol of the instruction it names
+}
+
+// emit appends an instruction, attaching any pending labels to it
+func (g *generator) emit(ins asm.Instruction) {
+	if len(g.pending) > 0 {
+		ins = ins.WithSymbol(g.pending[0])
+		for _, name := range g.pending[1:] {
+			g.aliases[name] = g.pending[0]
+		}
+		g.pending = nil
+	}
+	g.insns = append(g.insns, ins)
+}
+
+// label names the next emitted instruction
+func (g *generator) label(name string) {
+	g.pending = append(g.pending, name)
+}
+
+// resolve rewrites references to labels that share an instruction
+func (g *generator) resolve() asm.Instructions {
+	for i, ins := range g.insns {
+		if target, ok := g.aliases[ins.Reference()]; ok {
+			g.insns[i] = ins.WithReference(target)
+		}
+	}
+	return g.insns
+}
+
+// checkBounds jumps to miss unless ptr+n is within the packet
+func (g *generator) checkBounds(ptr asm.Register, n int32) {
+	g.emit(asm.Mov.Reg(asm.R4, ptr))
+	g.emit(asm.Add.Imm(asm.R4, n))
+	g.emit(asm.JGT.Reg(asm.R4, asm.R3, "miss"))
+}
+
+// expect jumps to fail unless R0 (zero-extended) equals value. Jump
+// immediates are sign-extended to 64 bits, so values of 2^31 and above
+// are compared through a register.
+func (g *generator) expect(value uint32, fail string) {
+	if value <= math.MaxInt32 {
+		g.emit(asm.JNE.Imm(asm.R0, int32(value), fail))
+		return
+	}
+	g.emit(asm.LoadImm(asm.R4, int64(value), asm.DWord))
+	g.emit(asm.JNE.Reg(asm.R0, asm.R4, fail))
+}
+
+// anyOf continues at the label ok when R0 equals one of values, which are
+// below 2^31, and jumps to fail otherwise. The label is placed after the
+// comparisons.
+func (g *generator) anyOf(values []uint32, ok, fail string) {
+	for _, v := range values[:len(values)-1] {
+		g.emit(asm.JEq.Imm(asm.R0, int32(v), ok))
+	}
+	g.emit(asm.JNE.Imm(asm.R0, int32(values[len(values)-1]), fail))
+	g.label(ok)
+}
+
+// portValues converts ports to comparison constants
+func portValues(ports []int) []uint32 {
+	values := make([]uint32, len(ports))
+	for i, p := range ports {
+		values[i] = uint32(p)
+	}
+	return values
+}
+
+// network compares the IPv4 address at offset with a host or network
+func (g *generator) network(offset int16, network, fail string) error {
+	ipnet, err := filter.ParseNet(network)
+	if err != nil {
+		return err
+	}
+	ip := ipnet.IP.To4()
+	if ip == nil || len(ipnet.Mask) != 4 {
+		return fmt.Errorf("eBPF generator supports IPv4 only, got %s", network)
+	}
+	g.emit(asm.LoadMem(asm.R0, asm.R2, offset, asm.Word))
+	g.emit(asm.HostTo(asm.BE, asm.R0, asm.Word))
+	if ones, _ := ipnet.Mask.Size(); ones != 32 {
+		g.emit(asm.And.Imm32(asm.R0, int32(binary.BigEndian.Uint32(ipnet.Mask))))
+	}
+	g.expect(binary.BigEndian.Uint32(ip), fail)
+	return nil
+}
@@ -0,0 +1,68 @@
+package ebpf
+
+import (
+	ciliumebpf "github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+// VerdictsMap names the array map a counting program keeps the number of
+// packets it returned each verdict for in, indexed by verdict
+const VerdictsMap = "verdicts"
+
+// Counts is the number of packets a loaded program matched and missed
+type Counts struct {
+	Matched uint64
+	Missed  uint64
+}
+
+// Total is the number of packets the program ran on
+func (c Counts) Total() uint64 {
+	return c.Matched + c.Missed
+}
+
+// CountingSpec returns a collection spec of the program and VerdictsMap,
+// in which every return first adds one to the count of its verdict. The
+// verdicts themselves are unchanged.
+func (p *Program) CountingSpec() *ciliumebpf.CollectionSpec {
+	insns := make(asm.Instructions, 0, len(p.Instructions)+11)
+	for _, ins := range p.Instructions {
+		if ins.OpCode.JumpOp() == asm.Exit {
+			count := asm.Ja.Label("count_verdict")
+			if sym := ins.Symbol(); sym != "" {
+				count = count.WithSymbol(sym)
+			}
+			ins = count
+		}
+		insns = append(insns, ins)
+	}
+
+	// R6 keeps the verdict across the helper call, which clobbers R0-R5
+	insns = append(insns,
+		asm.Mov.Reg(asm.R6, asm.R0).WithSymbol("count_verdict"),
+		asm.StoreMem(asm.RFP, -4, asm.R6, asm.Word),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(VerdictsMap),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "counted"),
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		asm.Mov.Reg(asm.R0, asm.R6).WithSymbol("counted"),
+		asm.Return(),
+	)
+
+	spec := p.Spec()
+	spec.Instructions = insns
+	return &ciliumebpf.CollectionSpec{
+		Maps: map[string]*ciliumebpf.MapSpec{
+			VerdictsMap: {
+				Name:       VerdictsMap,
+				Type:       ciliumebpf.Array,
+				KeySize:    4,
+				ValueSize:  8,
+				MaxEntries: 8,
+			},
+		},
+		Programs: map[string]*ciliumebpf.ProgramSpec{spec.Name: spec},
+	}
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"fmt"
+	"net"
+
+	ciliumebpf "github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/lifecycle"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/netns"
+)
+
+// Attachment is a counting program attached to an interface's XDP hook
+type Attachment struct {
+	Program   *Program
+	Interface string
+	Netns     string
+
+	collection *ciliumebpf.Collection
+	link       link.Link
+	handle     *lifecycle.Handle
+}
+
+// Attach loads the program as CountingSpec builds it and attaches it to
+// the XDP hook of the interface in the network namespace, as named by ip
+// netns, or in the current namespace when namespace is "". Only XDP
+// programs can be attached. Loading needs CAP_BPF or CAP_SYS_ADMIN and
+// attaching CAP_NET_ADMIN in the namespace.
+func Attach(p *Program, iface, namespace string) (*Attachment, error) {
+	if p.Target != TargetXDP {
+		return nil, fmt.Errorf("only XDP programs can be attached, not %s", p.Target)
+	}
+	collection, err := ciliumebpf.NewCollection(p.CountingSpec())
+	if err != nil {
+		return nil, fmt.Errorf("the kernel refused the eBPF program: %w", err)
+	}
+	a := &Attachment{Program: p, Interface: iface, Netns: namespace, collection: collection}
+
+	err = netns.Do(namespace, func() error {
+		dev, err := net.InterfaceByName(iface)
+		if err != nil {
+			return err
+		}
+		a.link, err = link.AttachXDP(link.XDPOptions{Program: collection.Programs[p.Spec().Name], Interface: dev.Index})
+		return err
+	})
+	if err != nil {
+		collection.Close()
+		return nil, fmt.Errorf("failed to attach the eBPF program to %s: %w", iface, err)
+	}
+	a.handle = lifecycle.Track("XDP program", iface)
+	return a, nil
+}
+
+// Counts reads the number of packets the program matched and missed so
+// far
+func (a *Attachment) Counts() (Counts, error) {
+	_, _, match, miss := a.Program.Target.context()
+	verdicts := a.collection.Maps[VerdictsMap]
+	var c Counts
+	if err := verdicts.Lookup(uint32(match), &c.Matched); err != nil {
+		return Counts{}, fmt.Errorf("failed to read verdict counts: %w", err)
+	}
+	if err := verdicts.Lookup(uint32(miss), &c.Missed); err != nil {
+		return Counts{}, fmt.Errorf("failed to read verdict counts: %w", err)
+	}
+	return c, nil
+}
+
+// Close detaches and unloads the program
+func (a *Attachment) Close() error {
+	err := a.link.Close()
+	a.collection.Close()
+	a.handle.Release()
+	return err
+}
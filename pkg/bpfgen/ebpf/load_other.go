@@ -0,0 +1,30 @@
+//go:build !linux
+
+package ebpf
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Attachment is a counting program attached to an interface's XDP hook
+type Attachment struct {
+	Program   *Program
+	Interface string
+	Netns     string
+}
+
+// Attach fails: XDP is a Linux facility
+func Attach(p *Program, iface, namespace string) (*Attachment, error) {
+	return nil, fmt.Errorf("attaching eBPF programs needs Linux, not %s", runtime.GOOS)
+}
+
+// Counts reads nothing on a program that cannot be attached
+func (a *Attachment) Counts() (Counts, error) {
+	return Counts{}, fmt.Errorf("attaching eBPF programs needs Linux, not %s", runtime.GOOS)
+}
+
+// Close does nothing
+func (a *Attachment) Close() error {
+	return nil
+}
@@ -0,0 +1,205 @@
+package bpfgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// FirewallMatch is one clause of a filter as a host firewall tests it
+type FirewallMatch struct {
+	Clause string // named as in the source map, e.g. "dst-port=80"
+	Nft    string // nft expression, e.g. "th dport 80"
+	U32    string // iptables u32 test, e.g. "0>>22&0x3C@0&0xFFFF=80"; "" when iptables needs none
+}
+
+// FirewallRule is one firewall rule, matching a packet when all of its
+// matches do
+type FirewallRule []FirewallMatch
+
+// Nft returns the rule's nft expression, for a rule in an inet table
+func (r FirewallRule) Nft() string {
+	var parts []string
+	for _, m := range r {
+		if m.Nft != "" {
+			parts = append(parts, m.Nft)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// IPTables returns the rule's iptables match, as one u32 test
+func (r FirewallRule) IPTables() string {
+	var tests []string
+	for _, m := range r {
+		if m.U32 != "" {
+			tests = append(tests, m.U32)
+		}
+	}
+	if len(tests) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("-m u32 --u32 \"%s\"", strings.Join(tests, " && "))
+}
+
+// FirewallRules is a filter as host firewall rules, matching a packet when
+// any rule does
+type FirewallRules struct {
+	FilterExpr string
+	Rules      []FirewallRule
+}
+
+// String lists each rule as an nft expression and an iptables match
+func (r *FirewallRules) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Firewall Rules: %s\n", r.FilterExpr))
+	sb.WriteString(fmt.Sprintf("Rules: %d\n", len(r.Rules)))
+	for i, rule := range r.Rules {
+		sb.WriteString(fmt.Sprintf("Rule %d:\n", i+1))
+		sb.WriteString(fmt.Sprintf("  nft       %s\n", rule.Nft()))
+		sb.WriteString(fmt.Sprintf("  iptables  %s\n", rule.IPTables()))
+	}
+	return sb.String()
+}
+
+// GenerateFirewall translates a filter into the nft rules and iptables u32
+// matches that accept the packets the prototype program accepts, with the
+// same clauses and the same fragment policy. A rule cannot test one field
+// or another, so either-direction hosts and ports and traffic between two
+// networks take a rule per combination; port lists stay in one rule, as an
+// nft set and u32 value list. Firewalls see IPv4 packets without their
+// link layer, so VLAN, link-layer protocol and tunnel filters cannot be
+// translated, and the link type is left out.
+func GenerateFirewall(f *filter.PacketFilter, fragments FragmentPolicy) (*FirewallRules, error) {
+	normalized, err := filter.Normalize(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	f = normalized
+
+	switch {
+	case f.VLAN:
+		return nil, fmt.Errorf("firewall rules see packets without their 802.1Q tag")
+	case f.EtherProto() != 0:
+		return nil, fmt.Errorf("firewall rules match IPv4 packets only, not %s", f.ToTcpdumpFilter())
+	case f.Tunnel != "":
+		return nil, fmt.Errorf("firewall rules cannot look into %s traffic", f.Tunnel)
+	}
+
+	// Each group holds the alternatives of one clause; iptables only
+	// ever sees IPv4
+	groups := [][]FirewallRule{{{{Clause: "(ipv4)", Nft: "meta nfproto ipv4"}}}}
+	add := func(alternatives ...FirewallRule) {
+		groups = append(groups, alternatives)
+	}
+
+	if f.Protocol != "" {
+		add(FirewallRule{{
+			Clause: "protocol=" + f.Protocol,
+			Nft:    "ip protocol " + f.Protocol,
+			U32:    fmt.Sprintf("6&0xFF=%d", f.IPProtocol()),
+		}})
+	} else if f.HasPorts() {
+		// Like tcpdump's bare "port", only transports with ports can match
+		add(FirewallRule{{Clause: "(port-carrying protocol)", Nft: "ip protocol { tcp, udp, sctp }", U32: "6&0xFF=6,17,132"}})
+	}
+
+	if f.SrcIP != "" {
+		src, err := addressMatch("src-ip="+f.SrcIP, "saddr", f.SrcIP)
+		if err != nil {
+			return nil, err
+		}
+		add(FirewallRule{src})
+	}
+	if f.DstIP != "" {
+		dst, err := addressMatch("dst-ip="+f.DstIP, "daddr", f.DstIP)
+		if err != nil {
+			return nil, err
+		}
+		add(FirewallRule{dst})
+	}
+	if f.HostIP != "" {
+		clause := "host=" + f.HostIP
+		src, err := addressMatch(clause, "saddr", f.HostIP)
+		if err != nil {
+			return nil, err
+		}
+		dst, _ := addressMatch(clause, "daddr", f.HostIP)
+		add(FirewallRule{src}, FirewallRule{dst})
+	}
+	if len(f.Between) == 2 {
+		clause := "between=" + strings.Join(f.Between, ",")
+		var matches [2][2]FirewallMatch // [network][saddr, daddr]
+		for i, n := range f.Between {
+			for j, field := range []string{"saddr", "daddr"} {
+				if matches[i][j], err = addressMatch(clause, field, n); err != nil {
+					return nil, err
+				}
+			}
+		}
+		add(FirewallRule{matches[0][0], matches[1][1]}, FirewallRule{matches[1][0], matches[0][1]})
+	}
+
+	if clause, mask, ok := fragmentCheck(fragments, f.HasPorts()); ok {
+		add(FirewallRule{{Clause: clause, Nft: fmt.Sprintf("ip frag-off & 0x%x == 0", mask), U32: fmt.Sprintf("4&0x%X=0", mask)}})
+	}
+	if ports := f.SrcPortList(); len(ports) > 0 {
+		add(FirewallRule{portMatch(portClause("src", ports), "sport", ports)})
+	}
+	if ports := f.DstPortList(); len(ports) > 0 {
+		add(FirewallRule{portMatch(portClause("dst", ports), "dport", ports)})
+	}
+	if f.Port != 0 {
+		clause, ports := fmt.Sprintf("port=%d", f.Port), []int{f.Port}
+		add(FirewallRule{portMatch(clause, "sport", ports)}, FirewallRule{portMatch(clause, "dport", ports)})
+	}
+
+	rules := []FirewallRule{nil}
+	for _, alternatives := range groups {
+		var next []FirewallRule
+		for _, rule := range rules {
+			for _, alt := range alternatives {
+				next = append(next, append(append(FirewallRule{}, rule...), alt...))
+			}
+		}
+		rules = next
+	}
+	return &FirewallRules{FilterExpr: f.ToTcpdumpFilter(), Rules: rules}, nil
+}
+
+// addressMatch tests the IPv4 source (saddr) or destination (daddr)
+// address against a network
+func addressMatch(clause, field, s string) (FirewallMatch, error) {
+	network, mask, err := netToUint32(s)
+	if err != nil {
+		return FirewallMatch{}, err
+	}
+	offset := 12
+	if field == "daddr" {
+		offset = 16
+	}
+	ipnet, _ := filter.ParseNet(s)
+	m := FirewallMatch{Clause: clause, Nft: fmt.Sprintf("ip %s %s", field, ipnet), U32: fmt.Sprintf("%d=0x%08X", offset, network)}
+	if mask == 0xffffffff {
+		m.Nft = fmt.Sprintf("ip %s %s", field, ipnet.IP)
+	} else {
+		m.U32 = fmt.Sprintf("%d&0x%08X=0x%08X", offset, mask, network)
+	}
+	return m, nil
+}
+
+// portMatch tests the transport source (sport) or destination (dport)
+// port against any of the ports. The u32 test skips the IPv4 header by
+// its length, as the prototype does with ldxb.
+func portMatch(clause, field string, ports []int) FirewallMatch {
+	nft := joinPorts(ports)
+	if len(ports) > 1 {
+		nft = "{ " + strings.ReplaceAll(nft, ",", ", ") + " }"
+	}
+	value := "0>>16"
+	if field == "dport" {
+		value = "0&0xFFFF"
+	}
+	return FirewallMatch{Clause: clause, Nft: fmt.Sprintf("th %s %s", field, nft), U32: fmt.Sprintf("0>>22&0x3C@%s=%s", value, joinPorts(ports))}
+}
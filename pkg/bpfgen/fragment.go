@@ -0,0 +1,70 @@
+package bpfgen
+
+import "fmt"
+
+// FragmentPolicy decides how the prototype treats fragmented IPv4
+// packets. Only the first fragment carries the transport header, so a
+// port check on a later fragment would read payload bytes instead. The
+// zero value is FragmentsMatchFirst.
+type FragmentPolicy string
+
+const (
+	// FragmentsMatchFirst rejects non-first fragments when the filter
+	// checks ports and matches every fragment otherwise, as tcpdump does
+	FragmentsMatchFirst FragmentPolicy = "match-first-fragment"
+
+	// FragmentsReject rejects every fragment, first ones included,
+	// whatever the filter checks
+	FragmentsReject FragmentPolicy = "reject-fragments"
+
+	// FragmentsIgnore emits no fragment check. Port checks then read
+	// whatever follows the IP header of a non-first fragment, which can
+	// match or miss by accident.
+	FragmentsIgnore FragmentPolicy = "ignore"
+)
+
+// Fragment field masks for jset on the IPv4 flags and fragment offset
+const (
+	fragmentOffsetMask = 0x1fff // non-zero on every fragment but the first
+	fragmentMask       = 0x3fff // also the more-fragments flag, set on the first
+)
+
+// ParseFragmentPolicy parses a policy name. The empty string is
+// FragmentsMatchFirst.
+func ParseFragmentPolicy(s string) (FragmentPolicy, error) {
+	switch FragmentPolicy(s) {
+	case "":
+		return FragmentsMatchFirst, nil
+	case FragmentsMatchFirst, FragmentsReject, FragmentsIgnore:
+		return FragmentPolicy(s), nil
+	}
+	return "", fmt.Errorf("invalid fragment policy '%s', must be %s, %s or %s", s, FragmentsMatchFirst, FragmentsReject, FragmentsIgnore)
+}
+
+// emitFragmentCheck loads the IPv4 flags and fragment offset and tests
+// them with jset as the policy requires, rejecting a packet with any of
+// the bits set. It emits nothing when the policy needs no check for this
+// filter.
+func emitFragmentCheck(builder *BPFBuilder, off offsets, policy FragmentPolicy, hasPorts bool) {
+	clause, mask, ok := fragmentCheck(policy, hasPorts)
+	if !ok {
+		return
+	}
+	builder.SetSource(clause)
+	builder.AddInstruction(off.ld(0x28), 0, 0, off.fragment()) // ldh [fragment] - load flags and fragment offset
+	builder.Jump(0x45, mask, rejectLabel, "")                  // jset #mask - check fragment bits
+}
+
+// fragmentCheck returns the clause and the mask of the fragment bits that
+// must be clear under the policy, or false when the policy needs no check
+// for this filter
+func fragmentCheck(policy FragmentPolicy, hasPorts bool) (string, uint32, bool) {
+	switch {
+	case policy == FragmentsReject:
+		return "(not a fragment)", fragmentMask, true
+	case policy == FragmentsIgnore || !hasPorts:
+		return "", 0, false
+	}
+	// Non-first fragments carry no transport header, so reject them
+	return "(first fragment)", fragmentOffsetMask, true
+}
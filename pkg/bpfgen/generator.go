@@ -0,0 +1,712 @@
+// Package bpfgen generates Antrea-style classic BPF programs from a
+// filter.PacketFilter. Generation has no side effects: the filter is
+// validated and the program or an error is returned. Generation is
+// deterministic: equal filters and options give byte-identical programs,
+// source maps and optimization lists in every run and with every Go
+// release, so programs can be diffed and baselined. Code here must not
+// depend on map iteration order, unstable sorts or the clock. Progress is
+// logged through the logging package, which discards records unless the
+// embedding program installs a logger.
+package bpfgen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+)
+
+// BPFCode represents Antrea-style BPF bytecode
+type BPFCode struct {
+	bpf.Code
+	Reasoning     string   // explanation of the approach
+	Optimizations []string // list of optimizations applied
+	Sources       []string // filter clause each instruction implements (see SourceMap)
+
+	// Uncovered lists criteria left out of a partial program (see Options)
+	Uncovered []Uncovered
+}
+
+// String returns a formatted representation of the BPF code
+func (bpf *BPFCode) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Antrea-style Filter: %s\n", bpf.FilterExpr))
+	sb.WriteString(fmt.Sprintf("Instructions: %d\n", bpf.InstructionCount))
+	if bpf.Snaplen != 0 && bpf.Snaplen != DefaultSnaplen {
+		sb.WriteString(fmt.Sprintf("Snapshot length: %d\n", bpf.Snaplen))
+	}
+	sb.WriteString(fmt.Sprintf("Reasoning: %s\n", bpf.Reasoning))
+
+	if len(bpf.Optimizations) > 0 {
+		sb.WriteString("Optimizations applied:\n")
+		for _, opt := range bpf.Optimizations {
+			sb.WriteString(fmt.Sprintf("  - %s\n", opt))
+		}
+	}
+
+	if len(bpf.Uncovered) > 0 {
+		sb.WriteString("Uncovered criteria (program matches a superset):\n")
+		for _, u := range bpf.Uncovered {
+			sb.WriteString(fmt.Sprintf("  - %s\n", u))
+		}
+	}
+
+	sb.WriteString("BPF Bytecode:\n")
+	for i, inst := range bpf.Instructions {
+		sb.WriteString(fmt.Sprintf("  [%2d] %s  %s\n", i, inst.String(), inst.Mnemonic(i)))
+	}
+
+	if spans := bpf.SourceMap(); len(spans) > 0 {
+		sb.WriteString("Source map:\n")
+		for _, span := range spans {
+			sb.WriteString(fmt.Sprintf("  - %s\n", span))
+		}
+	}
+
+	return sb.String()
+}
+
+// BPFBuilder helps construct BPF programs step by step
+type BPFBuilder struct {
+	instructions  []*bpf.Instruction
+	optimizations []string
+	currentOffset int
+	sources       []string
+	source        string
+	snaplen       uint32     // length the accept instruction returns
+	branches      []branches // requested jump offsets, encoded by Build
+	labels        map[string]int
+	jumps         []labelJump
+	labelCount    int
+	reject        string   // label jumps to the reject label go to (see RejectTo)
+	headersOnly   bool     // accept returns the header length (see SetHeadersOnly)
+	headers       *headers // packets the next accept measures
+	err           error    // first misuse of labels, reported by Build
+}
+
+// NewBPFBuilder creates a new BPF program builder
+func NewBPFBuilder() *BPFBuilder {
+	return &BPFBuilder{
+		instructions:  make([]*bpf.Instruction, 0),
+		optimizations: make([]string, 0),
+		currentOffset: 0,
+		snaplen:       DefaultSnaplen,
+		labels:        make(map[string]int),
+	}
+}
+
+// AddInstruction adds a BPF instruction and returns the current offset
+func (b *BPFBuilder) AddInstruction(code uint16, jt, jf uint8, k uint32) int {
+	inst := &bpf.Instruction{Code: code, JT: jt, JF: jf, K: k}
+	b.instructions = append(b.instructions, inst)
+	b.sources = append(b.sources, b.source)
+	b.branches = append(b.branches, branches{jt: int(jt), jf: int(jf)})
+	offset := b.currentOffset
+	b.currentOffset++
+	return offset
+}
+
+// AddAccept adds the instruction accepting a packet, which returns the
+// snapshot length (see SetSnaplen), or in a headers-only program the
+// instructions computing and returning the header length
+func (b *BPFBuilder) AddAccept() int {
+	if b.headersOnly {
+		if b.headers == nil {
+			if b.err == nil {
+				b.err = fmt.Errorf("accept at %d has no headers to measure", len(b.instructions))
+			}
+			return len(b.instructions)
+		}
+		return b.headers.emit(b)
+	}
+	return b.AddInstruction(0x06, 0, 0, b.snaplen) // ret #snaplen (accept)
+}
+
+// SetHeadersOnly makes the accept instructions added from now on return
+// the length of the packet's headers instead of the snapshot length
+func (b *BPFBuilder) SetHeadersOnly(headersOnly bool) {
+	b.headersOnly = headersOnly
+}
+
+// measure sets the packets the accept instructions added from now on
+// measure in a headers-only program
+func (b *BPFBuilder) measure(h *headers) {
+	b.headers = h
+}
+
+// SetSnaplen sets the length accept instructions added from now on return
+func (b *BPFBuilder) SetSnaplen(snaplen int) {
+	b.snaplen = uint32(snaplen)
+}
+
+// AddOptimization records an optimization that was applied
+func (b *BPFBuilder) AddOptimization(description string) {
+	b.optimizations = append(b.optimizations, description)
+}
+
+// SetSource names the filter clause the instructions added from now on
+// implement, such as "dst-port=80"
+func (b *BPFBuilder) SetSource(clause string) {
+	b.source = clause
+}
+
+// Sources returns the clause of every instruction of the built program,
+// or nil if it cannot be built
+func (b *BPFBuilder) Sources() []string {
+	_, sources, err := b.layout()
+	if err != nil {
+		return nil
+	}
+	return sources
+}
+
+// UpdateJumpTargets updates jump targets for previously added instructions.
+// Offsets are relative to the next instruction and may exceed 255: Build
+// reaches such targets through unconditional jumps.
+//
+// Deprecated: add the jump with Jump and its targets with Label, which
+// Build resolves, instead of computing offsets from instruction indices.
+func (b *BPFBuilder) UpdateJumpTargets(instructionIndex int, jt, jf int) {
+	if instructionIndex < len(b.instructions) {
+		b.branches[instructionIndex] = branches{jt: jt, jf: jf}
+	}
+}
+
+// Build resolves the labels and returns the final BPF program, with a
+// trampoline after every conditional jump whose target is out of 8-bit
+// reach. It fails if a jump names a label that is undefined or behind it.
+func (b *BPFBuilder) Build() ([]*bpf.Instruction, error) {
+	instructions, _, err := b.layout()
+	return instructions, err
+}
+
+// GenerateBPF creates simplified Antrea-style BPF code
+func GenerateBPF(f *filter.PacketFilter) (*BPFCode, error) {
+	return GenerateBPFWithOptions(f, Options{})
+}
+
+// GenerateBPFWithOptions creates Antrea-style BPF code with explicit options
+func GenerateBPFWithOptions(f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	// Equal filters written differently get the same program
+	normalized, err := filter.Normalize(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	f = normalized
+
+	log := logging.Logger()
+	log.Debug("generating Antrea-style BPF", "filter", buildFilterDescription(f))
+
+	builder := NewBPFBuilder()
+	builder.SetSnaplen(opts.snaplen())
+	builder.SetHeadersOnly(opts.HeadersOnly)
+	if err := checkHeadersOnly(opts, f); err != nil {
+		return nil, err
+	}
+	var reasoning string
+	var uncovered []Uncovered
+	ipv6 := false
+	if opts.Partial {
+		f, uncovered, ipv6 = supportedSubset(f)
+		for _, u := range uncovered {
+			log.Warn("partial program does not enforce criterion", "criterion", u.String())
+		}
+	}
+
+	if ipv6 {
+		reasoning = buildIPv6Superset(f, builder)
+	} else if f.EtherProto() != 0 {
+		reasoning = buildLinkProtocolBPF(f, builder)
+	} else if f.Tunnel != "" {
+		var err error
+		reasoning, err = buildTunnelBPF(f, opts.Fragments, builder)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		reasoning, err = buildAntreaBPF(f, opts.Fragments, builder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filterDesc := buildFilterDescription(f)
+	if ipv6 {
+		filterDesc = strings.TrimSpace(filterDesc + " ip6")
+	}
+
+	bpfCode, err := assemble(builder, opts, filterDesc, f.LinkType, reasoning)
+	if err != nil {
+		return nil, err
+	}
+	bpfCode.Uncovered = uncovered
+	return bpfCode, nil
+}
+
+// assemble builds the builder's program, optimizes it at the options'
+// level and checks it against their instruction budget
+func assemble(builder *BPFBuilder, opts Options, filterDesc string, link filter.LinkType, reasoning string) (*BPFCode, error) {
+	log := logging.Logger()
+	built, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+
+	// Only passes that changed the program are reported
+	instructions, sources, applied := optimize(built, builder.Sources(), opts.OptLevel)
+
+	// Dead code elimination drops unreachable instructions, which are
+	// only left in unoptimized programs
+	warnings := unreachableWarnings(instructions, sources)
+	for _, w := range warnings {
+		log.Warn("generated program has unreachable code", "warning", w)
+	}
+
+	bpfCode := &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       filterDesc,
+			InstructionCount: len(instructions),
+			LinkType:         string(link),
+			Snaplen:          opts.snaplen(),
+		},
+		Reasoning:     reasoning,
+		Optimizations: append(append(builder.optimizations, applied...), warnings...),
+		Sources:       sources,
+	}
+	if opts.HeadersOnly {
+		bpfCode.Reasoning += "; accepted packets are cut after their headers, whose length the accept path computes"
+	}
+	if opts.MaxInstructions > 0 && len(instructions) > opts.MaxInstructions {
+		return nil, &BudgetError{Budget: opts.MaxInstructions, Instructions: len(instructions), Clauses: bpfCode.ClauseCosts()}
+	}
+
+	log.Debug("generated Antrea-style BPF", "instructions", len(instructions))
+	return bpfCode, nil
+}
+
+// buildAntreaBPF constructs BPF instructions using Antrea's conceptual approach.
+// Every check falls through to the next one on success and jumps to the
+// shared reject on failure, so the criteria are ANDed together.
+// fragments decides which IPv4 fragments can match (see FragmentPolicy).
+func buildAntreaBPF(f *filter.PacketFilter, fragments FragmentPolicy, builder *BPFBuilder) (string, error) {
+	var reasoning strings.Builder
+	reasoning.WriteString("Antrea-style approach: ")
+
+	// A VLAN tag comes first and moves every later field
+	builder.SetSource(vlanClause(f))
+	off := emitLinkChecks(f, builder)
+	if f.VLAN {
+		reasoning.WriteString("0) 802.1Q tag check with shifted offsets, ")
+	}
+
+	// Antrea Concept 1: Early validation and fail-fast
+	// Check if this is an IP packet first (Ethernet type = 0x0800)
+	reasoning.WriteString("1) Early IP validation, ")
+	builder.SetSource("(ipv4)")
+	emitFamilyCheck(builder, off, false)
+
+	if err := emitIPCriteria(f, off, fragments, builder, &reasoning); err != nil {
+		return "", err
+	}
+
+	// Antrea Concept 5: Optimized accept/reject logic
+	reasoning.WriteString("5) Optimized accept/reject with minimal instructions")
+	builder.measure(ipv4Headers(f, off, fragments))
+	emitVerdicts(builder)
+
+	return reasoning.String(), nil
+}
+
+// emitIPCriteria emits the protocol, address and port checks of the
+// filter, Antrea concepts 2 to 4, for the IPv4 header at off. Failing
+// checks jump to the reject label.
+func emitIPCriteria(f *filter.PacketFilter, off offsets, fragments FragmentPolicy, builder *BPFBuilder, reasoning *strings.Builder) error {
+	emitProtocolCheck(f, off, builder, reasoning)
+	return emitAddressAndPortChecks(f, off, fragments, builder, reasoning)
+}
+
+// emitProtocolCheck emits the IP protocol check of the filter, Antrea
+// concept 2, or the port-carrying protocol check a bare port implies
+func emitProtocolCheck(f *filter.PacketFilter, off offsets, builder *BPFBuilder, reasoning *strings.Builder) {
+	// Antrea Concept 2: Structured protocol handling
+	if f.Protocol != "" {
+		reasoning.WriteString("2) Protocol-specific filtering, ")
+		builder.SetSource("protocol=" + f.Protocol)
+		builder.AddInstruction(off.ld(0x30), 0, 0, off.protocol()) // ldb [protocol] - load IP protocol
+
+		builder.Jump(0x15, uint32(f.IPProtocol()), "", rejectLabel) // jeq protocol
+	} else if f.HasPorts() {
+		// Like tcpdump's bare "port", only transports with ports can match
+		reasoning.WriteString("2) Port-carrying protocol check, ")
+		builder.SetSource("(port-carrying protocol)")
+		builder.AddInstruction(off.ld(0x30), 0, 0, off.protocol())       // ldb [protocol] - load IP protocol
+		emitAnyOf(builder, []uint32{0x00000084, 0x00000006, 0x00000011}) // jeq #132 (sctp), #6 (tcp), #17 (udp)
+	}
+}
+
+// emitAddressAndPortChecks emits the address, fragment and port checks of
+// the filter, Antrea concepts 3 and 4, for the IPv4 header at off
+func emitAddressAndPortChecks(f *filter.PacketFilter, off offsets, fragments FragmentPolicy, builder *BPFBuilder, reasoning *strings.Builder) error {
+
+	// Antrea Concept 3: Efficient address filtering
+	if f.SrcIP != "" || f.DstIP != "" || f.HostIP != "" || len(f.Between) == 2 {
+		reasoning.WriteString("3) IP address filtering, ")
+
+		if f.SrcIP != "" {
+			builder.SetSource("src-ip=" + f.SrcIP)
+			network, mask, err := netToUint32(f.SrcIP)
+			if err != nil {
+				return err
+			}
+			emitNetCheck(builder, off, off.srcIP(), network, mask, "", rejectLabel) // ld [src] - source IP
+		}
+
+		if f.DstIP != "" {
+			builder.SetSource("dst-ip=" + f.DstIP)
+			network, mask, err := netToUint32(f.DstIP)
+			if err != nil {
+				return err
+			}
+			emitNetCheck(builder, off, off.dstIP(), network, mask, "", rejectLabel) // ld [dst] - dest IP
+		}
+
+		if f.HostIP != "" {
+			builder.SetSource("host=" + f.HostIP)
+			network, mask, err := netToUint32(f.HostIP)
+			if err != nil {
+				return err
+			}
+			// A matching source skips the destination check
+			match := builder.NewLabel("host")
+			emitNetCheck(builder, off, off.srcIP(), network, mask, match, "")       // ld [src] - source IP
+			emitNetCheck(builder, off, off.dstIP(), network, mask, "", rejectLabel) // ld [dst] - dest IP
+			builder.Label(match)
+		}
+
+		if len(f.Between) == 2 {
+			builder.SetSource("between=" + strings.Join(f.Between, ","))
+			if err := buildBetween(f.Between[0], f.Between[1], off, builder); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Antrea Concept 4: Port filtering with fragmentation awareness
+	emitFragmentCheck(builder, off, fragments, f.HasPorts())
+	switch {
+	case f.HasPorts() && fragments == FragmentsIgnore:
+		reasoning.WriteString("4) Port filtering without fragment checks, ")
+	case f.HasPorts() && fragments == FragmentsReject:
+		reasoning.WriteString("4) Fragment rejection and port filtering, ")
+	case f.HasPorts():
+		reasoning.WriteString("4) Fragment-aware port filtering, ")
+	case fragments == FragmentsReject:
+		reasoning.WriteString("4) Fragment rejection, ")
+	}
+	if f.HasPorts() {
+		// Calculate header length for port offset
+		builder.SetSource("(transport header)")
+		emitHeaderLength(builder, off)
+
+		// A port list shares one load and chains its comparisons
+		if ports := f.SrcPortList(); len(ports) > 0 {
+			builder.SetSource(portClause("src", ports))
+			builder.AddInstruction(0x48, 0, 0, off.ip) // ldh [x + ip] - load source port
+			emitAnyOf(builder, portValues(ports))      // jeq src_port
+		}
+
+		if ports := f.DstPortList(); len(ports) > 0 {
+			builder.SetSource(portClause("dst", ports))
+			builder.AddInstruction(0x48, 0, 0, off.ip+2) // ldh [x + ip + 2] - load dest port
+			emitAnyOf(builder, portValues(ports))        // jeq dst_port
+		}
+
+		if f.Port != 0 {
+			// A matching source port skips the destination port check
+			builder.SetSource(fmt.Sprintf("port=%d", f.Port))
+			match := builder.NewLabel("port")
+			builder.AddInstruction(0x48, 0, 0, off.ip)          // ldh [x + ip] - load source port
+			builder.Jump(0x15, uint32(f.Port), match, "")       // jeq port
+			builder.AddInstruction(0x48, 0, 0, off.ip+2)        // ldh [x + ip + 2] - load dest port
+			builder.Jump(0x15, uint32(f.Port), "", rejectLabel) // jeq port
+			builder.Label(match)
+		}
+	}
+
+	return nil
+}
+
+// buildLinkProtocolBPF matches ARP, RARP or another EtherType. There is no
+// IP header to validate, so the link-layer protocol check is the program.
+func buildLinkProtocolBPF(f *filter.PacketFilter, builder *BPFBuilder) string {
+	builder.SetSource(vlanClause(f))
+	off := emitLinkChecks(f, builder)
+	emitLinkProtocolCheck(f, off, builder)
+	builder.measure(linkHeaders(f, off))
+	emitVerdicts(builder)
+	return "Antrea-style approach: link-layer protocol match by EtherType, with no IP assumptions"
+}
+
+// emitLinkProtocolCheck compares the link layer's protocol field with the
+// filter's EtherType, rejecting other frames
+func emitLinkProtocolCheck(f *filter.PacketFilter, off offsets, builder *BPFBuilder) {
+	if f.EtherType != 0 {
+		builder.SetSource(fmt.Sprintf("ether-type=0x%04x", f.EtherType))
+	} else {
+		builder.SetSource("protocol=" + f.Protocol)
+	}
+	builder.AddInstruction(0x28, 0, 0, off.etherType)           // ldh [ethertype] - load ethernet type
+	builder.Jump(0x15, uint32(f.EtherProto()), "", rejectLabel) // jeq ethertype
+}
+
+// emitVerdicts ends the program: the last check falls through to accept,
+// and every failing branch jumps to the reject label that follows it
+func emitVerdicts(builder *BPFBuilder) {
+	builder.SetSource("(accept)")
+	builder.AddAccept()
+	emitReject(builder)
+}
+
+// emitReject adds the reject label and the instruction it names
+func emitReject(builder *BPFBuilder) {
+	builder.SetSource("(reject)")
+	builder.Label(rejectLabel)
+	builder.AddInstruction(0x06, 0, 0, 0x00000000) // ret #0 (reject)
+}
+
+// offsets locates the fields the generator loads. The link type decides
+// where the IP header starts, and an 802.1Q tag moves the EtherType and
+// everything after it back by 4 bytes.
+type offsets struct {
+	link      filter.LinkType
+	etherType uint32 // protocol field of the link-layer header
+	ip        uint32 // start of the IPv4 header
+	indexed   bool   // the fields are relative to X, as behind a tunnel
+}
+
+// ld returns the load opcode for one of the fields: the absolute load
+// code itself, or its "ld [x + k]" form when the offsets are indexed
+func (o offsets) ld(code uint16) uint16 {
+	if o.indexed {
+		return code&^0xe0 | bpf.ModeIND
+	}
+	return code
+}
+
+func (o offsets) fragment() uint32 { return o.ip + 6 }
+func (o offsets) protocol() uint32 { return o.ip + 9 }
+func (o offsets) srcIP() uint32    { return o.ip + 12 }
+func (o offsets) dstIP() uint32    { return o.ip + 16 }
+
+// emitLinkChecks emits the VLAN tag and VLAN ID checks the filter asks
+// for, failing to the reject label. It returns the offsets of the headers
+// that follow.
+func emitLinkChecks(f *filter.PacketFilter, builder *BPFBuilder) offsets {
+	ip := uint32(f.LinkType.HeaderLen())
+	if !f.VLAN && f.VLANID == 0 {
+		// Cooked captures keep the protocol in the header's last two bytes
+		// like Ethernet; RAW and NULL have no EtherType field
+		return offsets{link: f.LinkType, etherType: ip - 2, ip: ip}
+	}
+
+	// Like libpcap, accept the 802.1Q, 802.1ad and legacy QinQ TPIDs
+	builder.AddInstruction(0x28, 0, 0, 0x0000000c)       // ldh [12] - load TPID
+	emitAnyOf(builder, []uint32{0x8100, 0x88a8, 0x9100}) // jeq #0x8100, #0x88a8, #0x9100 - VLAN tag
+
+	if f.VLANID != 0 {
+		builder.AddInstruction(0x28, 0, 0, 0x0000000e)        // ldh [14] - load TCI
+		builder.AddInstruction(0x54, 0, 0, 0x00000fff)        // and #0xfff - keep VLAN ID
+		builder.Jump(0x15, uint32(f.VLANID), "", rejectLabel) // jeq vlan_id
+	}
+	return offsets{link: f.LinkType, etherType: 16, ip: 18}
+}
+
+// emitHeaderLength adds the IPv4 header length to X, where the port
+// loads expect it. Indexed offsets already keep a length in X, and the
+// header length byte can only be loaded relative to it.
+func emitHeaderLength(builder *BPFBuilder, off offsets) {
+	if !off.indexed {
+		builder.AddInstruction(0xb1, 0, 0, off.ip) // ldxb 4*([ip]&0xf) - IP header length
+		return
+	}
+	builder.AddInstruction(0x50, 0, 0, off.ip)     // ldb [x + ip] - load version and header length
+	builder.AddInstruction(0x54, 0, 0, 0x0000000f) // and #0xf - keep header length in words
+	builder.AddInstruction(0x64, 0, 0, 0x00000002) // lsh #2 - convert to bytes
+	builder.AddInstruction(0x0c, 0, 0, 0x00000000) // add x - add the encapsulation
+	builder.AddInstruction(0x07, 0, 0, 0x00000000) // tax - X now also skips the IP header
+}
+
+// emitFamilyCheck loads the link layer's protocol field and compares it
+// with IPv4, or with IPv6 when ipv6 is set, rejecting other families
+func emitFamilyCheck(builder *BPFBuilder, off offsets, ipv6 bool) {
+	var values []uint32
+	switch off.link {
+	case filter.LinkRaw:
+		// No link header: the IP version is the first nibble
+		builder.AddInstruction(0x30, 0, 0, 0x00000000) // ldb [0] - load IP version
+		builder.AddInstruction(0x54, 0, 0, 0x000000f0) // and #0xf0 - keep version nibble
+		values = []uint32{0x40}
+		if ipv6 {
+			values = []uint32{0x60}
+		}
+	case filter.LinkNull:
+		// The address family is in host byte order, and BSDs disagree on
+		// the value of AF_INET6
+		builder.AddInstruction(0x20, 0, 0, 0x00000000) // ld [0] - load address family
+		values = []uint32{filter.NullIPv4}
+		if ipv6 {
+			values = nil
+			for _, family := range []uint32{24, 28, 30} {
+				values = append(values, binary.BigEndian.Uint32(binary.NativeEndian.AppendUint32(nil, family)))
+			}
+		}
+	default:
+		builder.AddInstruction(off.ld(0x28), 0, 0, off.etherType) // ldh [ethertype] - load ethernet type
+		values = []uint32{0x00000800}
+		if ipv6 {
+			values = []uint32{0x000086dd}
+		}
+	}
+
+	emitAnyOf(builder, values)
+}
+
+// emitAnyOf compares the accumulator with each value. A match jumps past
+// the remaining comparisons, and no match jumps to the reject label.
+func emitAnyOf(builder *BPFBuilder, values []uint32) {
+	match := builder.NewLabel("match")
+	for _, v := range values[:len(values)-1] {
+		builder.Jump(0x15, v, match, "") // jeq value
+	}
+	builder.Jump(0x15, values[len(values)-1], "", rejectLabel) // jeq value
+	builder.Label(match)
+}
+
+// portValues converts ports to comparison constants
+func portValues(ports []int) []uint32 {
+	values := make([]uint32, len(ports))
+	for i, p := range ports {
+		values[i] = uint32(p)
+	}
+	return values
+}
+
+// buildBetween emits "(src in A and dst in B) or (src in B and dst in A)".
+// The forward direction falls through to the code after the block on a
+// match; any mismatch moves on to the reverse direction, whose failures
+// jump to the reject label.
+func buildBetween(a, b string, off offsets, builder *BPFBuilder) error {
+	netA, maskA, err := netToUint32(a)
+	if err != nil {
+		return err
+	}
+	netB, maskB, err := netToUint32(b)
+	if err != nil {
+		return err
+	}
+	reverse, end := builder.NewLabel("reverse"), builder.NewLabel("between")
+
+	// Forward: src in A, dst in B
+	emitNetCheck(builder, off, off.srcIP(), netA, maskA, "", reverse)
+	emitNetCheck(builder, off, off.dstIP(), netB, maskB, end, reverse)
+
+	// Reverse: src in B, dst in A
+	builder.Label(reverse)
+	emitNetCheck(builder, off, off.srcIP(), netB, maskB, "", rejectLabel)
+	emitNetCheck(builder, off, off.dstIP(), netA, maskA, "", rejectLabel)
+	builder.Label(end)
+	return nil
+}
+
+// emitNetCheck loads the address at offset, masks it unless the network is
+// a single host, and compares it with the network address, continuing at
+// the jt label on a match and at the jf label otherwise
+func emitNetCheck(builder *BPFBuilder, off offsets, offset, network, mask uint32, jt, jf string) {
+	builder.AddInstruction(off.ld(0x20), 0, 0, offset) // ld [offset] - load IP address
+	if mask != 0xffffffff {
+		builder.AddInstruction(0x54, 0, 0, mask) // and #mask - keep network bits
+	}
+	builder.Jump(0x15, network, jt, jf) // jeq network
+}
+
+// buildFilterDescription creates a human-readable filter description
+func buildFilterDescription(f *filter.PacketFilter) string {
+	var parts []string
+
+	if !f.LinkType.IsEthernet() {
+		parts = append(parts, fmt.Sprintf("link=%s", f.LinkType))
+	}
+	if f.VLANID != 0 {
+		parts = append(parts, fmt.Sprintf("vlan=%d", f.VLANID))
+	} else if f.VLAN {
+		parts = append(parts, "vlan")
+	}
+	if f.VNI != 0 {
+		parts = append(parts, fmt.Sprintf("%s=%d", f.Tunnel, f.VNI))
+	} else if f.Tunnel != "" {
+		parts = append(parts, string(f.Tunnel))
+	}
+	if f.Inner {
+		parts = append(parts, "inner")
+	}
+
+	if f.HasProtocolNumber() {
+		parts = append(parts, "proto="+f.Protocol)
+	} else if f.Protocol != "" {
+		parts = append(parts, f.Protocol)
+	}
+	if f.EtherType != 0 {
+		parts = append(parts, fmt.Sprintf("ether=0x%04x", f.EtherType))
+	}
+	if f.SrcIP != "" {
+		parts = append(parts, fmt.Sprintf("src=%s", f.SrcIP))
+	}
+	if f.DstIP != "" {
+		parts = append(parts, fmt.Sprintf("dst=%s", f.DstIP))
+	}
+	if f.HostIP != "" {
+		parts = append(parts, fmt.Sprintf("host=%s", f.HostIP))
+	}
+	if len(f.Between) == 2 {
+		parts = append(parts, fmt.Sprintf("between=%s<->%s", f.Between[0], f.Between[1]))
+	}
+	if ports := f.SrcPortList(); len(ports) > 0 {
+		parts = append(parts, fmt.Sprintf("sport=%s", joinPorts(ports)))
+	}
+	if ports := f.DstPortList(); len(ports) > 0 {
+		parts = append(parts, fmt.Sprintf("dport=%s", joinPorts(ports)))
+	}
+	if f.Port != 0 {
+		parts = append(parts, fmt.Sprintf("port=%d", f.Port))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// joinPorts formats ports as a comma-separated list
+func joinPorts(ports []int) string {
+	s := make([]string, len(ports))
+	for i, p := range ports {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, ",")
+}
+
+// netToUint32 converts an IPv4 CIDR or address to its network address and mask
+func netToUint32(s string) (uint32, uint32, error) {
+	ipnet, err := filter.ParseNet(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	ip := ipnet.IP.To4()
+	if ip == nil || len(ipnet.Mask) != net.IPv4len {
+		return 0, 0, fmt.Errorf("prototype generator supports IPv4 addresses only, got %s", s)
+	}
+	return binary.BigEndian.Uint32(ip), binary.BigEndian.Uint32(ipnet.Mask), nil
+}
@@ -0,0 +1,179 @@
+package bpfgen
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// A headers-only program captures the headers of a matching packet and
+// none of its payload, like a PacketCapture that records connections
+// without their data. The accept path computes the length at run time:
+// the link-layer header, the IPv4 header from its IHL, and the transport
+// header, which is the TCP data offset for TCP and fixed for UDP, ICMP and
+// SCTP. A filter that matches several protocols dispatches on the IP
+// protocol byte, and a non-first fragment, which carries no transport
+// header, returns the link and IPv4 headers only.
+
+// Fixed header lengths, of headers with no length field to load
+const (
+	udpHeaderLen  = 8
+	icmpHeaderLen = 8  // type, code, checksum and the 4-byte rest of header
+	sctpHeaderLen = 12 // common header, without chunks
+	arpHeaderLen  = 28 // Ethernet/IPv4 ARP and RARP
+	ipv6HeaderLen = 40 // fixed header, without extension headers
+)
+
+// headers describes the packets an accept instruction returns the header
+// length of
+type headers struct {
+	off       offsets
+	ipv4      bool   // the IPv4 and transport lengths are computed
+	network   uint32 // fixed network header length when not ipv4, such as ARP's
+	protocol  int    // IP protocol of every matching packet, -1 for several
+	fragments bool   // non-first fragments can match
+}
+
+// ipv4Headers returns the headers of the IPv4 packets the filter matches
+// at off, with the fragments the policy lets through
+func ipv4Headers(f *filter.PacketFilter, off offsets, fragments FragmentPolicy) *headers {
+	// Port checks under match-first-fragment reject non-first fragments
+	dropped := fragments == FragmentsReject || (fragments == FragmentsMatchFirst && f.HasPorts())
+	return &headers{off: off, ipv4: true, protocol: f.IPProtocol(), fragments: !dropped}
+}
+
+// linkHeaders returns the headers of the frames a link-layer protocol
+// filter matches: ARP and RARP carry a fixed-length header, other
+// EtherTypes are captured up to the link-layer header
+func linkHeaders(f *filter.PacketFilter, off offsets) *headers {
+	h := &headers{off: off}
+	switch f.EtherProto() {
+	case 0x0806, 0x8035:
+		h.network = arpHeaderLen
+	}
+	return h
+}
+
+// transportHeaderLen returns the fixed transport header length of an IP
+// protocol, 0 for one the program does not parse. TCP has its own.
+func transportHeaderLen(protocol int) uint32 {
+	switch protocol {
+	case 17:
+		return udpHeaderLen
+	case 1:
+		return icmpHeaderLen
+	case 132:
+		return sctpHeaderLen
+	}
+	return 0
+}
+
+// headerProtocols lists the protocols whose transport header the program
+// measures, in dispatch order
+var headerProtocols = []int{6, 17, 1, 132}
+
+// emit adds the instructions computing the header length into A and
+// returning it, and returns the offset of the first. Every load is
+// guarded by the packet length, so a truncated packet the filter matched
+// is still accepted: one too short for a minimal IPv4 header is returned
+// whole, and a TCP segment cut before its data offset returns the link
+// and IPv4 headers.
+func (h *headers) emit(b *BPFBuilder) int {
+	if !h.ipv4 {
+		return b.AddInstruction(0x06, 0, 0, h.off.ip+h.network) // ret #headers
+	}
+	whole := b.NewLabel("headers")
+	first := b.AddInstruction(0x80, 0, 0, 0) // ld #len - load packet length
+	b.Jump(0x35, h.off.ip+20, "", whole)     // jge #ip+20 - minimal IPv4 header
+	b.AddInstruction(0xb1, 0, 0, h.off.ip)   // ldxb 4*([ip]&0xf) - IP header length
+
+	protocols := []int{h.protocol}
+	if h.protocol < 0 {
+		protocols = headerProtocols
+	} else if h.protocol != 6 && transportHeaderLen(h.protocol) == 0 {
+		protocols = nil
+	}
+	ipOnly := b.NewLabel("headers")
+	if len(protocols) > 0 && h.fragments {
+		b.AddInstruction(0x28, 0, 0, h.off.fragment()) // ldh [fragment] - load flags and fragment offset
+		b.Jump(0x45, fragmentOffsetMask, ipOnly, "")   // jset #0x1fff - no transport header
+	}
+
+	// With several protocols, the dispatch jumps to one block per
+	// transport header length, which protocols of equal length share
+	blocks, labels := protocols, []string{""}
+	if len(protocols) > 1 {
+		b.AddInstruction(0x30, 0, 0, h.off.protocol()) // ldb [protocol] - load IP protocol
+		blocks, labels = nil, nil
+		for i, p := range protocols {
+			block := slices.IndexFunc(blocks, func(q int) bool { return transportHeaderLen(q) == transportHeaderLen(p) })
+			if block < 0 {
+				block = len(blocks)
+				blocks = append(blocks, p)
+				labels = append(labels, b.NewLabel("headers"))
+			}
+			jf := ""
+			if i == len(protocols)-1 {
+				jf = ipOnly
+			}
+			b.Jump(0x15, uint32(p), labels[block], jf) // jeq protocol
+		}
+	}
+	for i, p := range blocks {
+		if labels[i] != "" {
+			b.Label(labels[i])
+		}
+		if p == 6 {
+			b.AddInstruction(0x80, 0, 0, 0)           // ld #len - load packet length
+			b.AddInstruction(0x14, 0, 0, h.off.ip+13) // sub #ip+13
+			b.Jump(0x3d, 0, "", ipOnly)               // jge x - data offset in the packet
+			b.AddInstruction(0x50, 0, 0, h.off.ip+12) // ldb [x + ip + 12] - TCP data offset
+			b.AddInstruction(0x54, 0, 0, 0xf0)        // and #0xf0
+			b.AddInstruction(0x74, 0, 0, 2)           // rsh #2 - data offset in bytes
+			b.AddInstruction(0x0c, 0, 0, 0)           // add x - plus the IP header
+			emitAddReturn(b, h.off.ip)
+			continue
+		}
+		b.AddInstruction(0x87, 0, 0, 0) // txa - IP header length
+		emitAddReturn(b, h.off.ip+transportHeaderLen(p))
+	}
+	// The link and IPv4 headers, for packets without a measured transport
+	// header; a packet returned whole shares the return
+	if len(protocols) != 1 || h.fragments || protocols[0] == 6 {
+		b.Label(ipOnly)
+		b.AddInstruction(0x87, 0, 0, 0) // txa - IP header length
+		if h.off.ip != 0 {
+			b.AddInstruction(0x04, 0, 0, h.off.ip) // add #ip
+		}
+	}
+	b.Label(whole)
+	b.AddInstruction(0x16, 0, 0, 0) // ret a
+	return first
+}
+
+// emitAddReturn adds k to A, if not 0, and returns A
+func emitAddReturn(b *BPFBuilder, k uint32) {
+	if k != 0 {
+		b.AddInstruction(0x04, 0, 0, k) // add #k
+	}
+	b.AddInstruction(0x16, 0, 0, 0) // ret a
+}
+
+// checkHeadersOnly checks that headers-only options apply to the filters:
+// the header length replaces the snapshot length, and the generator does
+// not measure the headers inside a tunnel
+func checkHeadersOnly(opts Options, filters ...*filter.PacketFilter) error {
+	if !opts.HeadersOnly {
+		return nil
+	}
+	if opts.Snaplen != 0 {
+		return fmt.Errorf("headers-only programs return the header length, not a snapshot length of %d", opts.Snaplen)
+	}
+	for _, f := range filters {
+		if f.Tunnel != "" {
+			return fmt.Errorf("headers-only programs cannot measure the headers of %s tunnels", f.Tunnel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package bpfgen
+
+import "fmt"
+
+// rejectLabel marks the shared reject instruction every failing check
+// jumps to (see emitVerdicts)
+const rejectLabel = "reject"
+
+// labelJump is a conditional jump whose branches name labels; an empty
+// name continues at the next instruction
+type labelJump struct {
+	idx    int
+	jt, jf string
+}
+
+// Label names the position of the next instruction added, as a target
+// for Jump. Jumps only go forward, so a label is defined after the jumps
+// to it, and only once.
+func (b *BPFBuilder) Label(name string) {
+	if _, ok := b.labels[name]; ok {
+		if b.err == nil {
+			b.err = fmt.Errorf("label %q defined twice", name)
+		}
+		return
+	}
+	b.labels[name] = len(b.instructions)
+}
+
+// NewLabel returns a label name no other NewLabel call on the builder
+// returns, for code emitted more than once, such as the end of a port list
+func (b *BPFBuilder) NewLabel(prefix string) string {
+	b.labelCount++
+	return fmt.Sprintf("%s.%d", prefix, b.labelCount)
+}
+
+// Jump adds a conditional jump whose true and false branches continue at
+// the named labels, or at the next instruction for an empty name, and
+// returns its offset. Build resolves the labels.
+func (b *BPFBuilder) Jump(code uint16, k uint32, jt, jf string) int {
+	if b.reject != "" {
+		if jt == rejectLabel {
+			jt = b.reject
+		}
+		if jf == rejectLabel {
+			jf = b.reject
+		}
+	}
+	idx := b.AddInstruction(code, 0, 0, k)
+	b.jumps = append(b.jumps, labelJump{idx: idx, jt: jt, jf: jf})
+	return idx
+}
+
+// RejectTo sends the jumps to the reject label added from now on to label
+// instead, so that a failing check of one filter of a merged program moves
+// on to the next filter. An empty label restores the reject.
+func (b *BPFBuilder) RejectTo(label string) {
+	b.reject = label
+}
+
+// resolve sets the branch offsets of every Jump from its labels
+func (b *BPFBuilder) resolve() error {
+	if b.err != nil {
+		return b.err
+	}
+	for _, j := range b.jumps {
+		var offsets [2]int
+		for k, name := range [2]string{j.jt, j.jf} {
+			if name == "" {
+				continue
+			}
+			target, ok := b.labels[name]
+			if !ok {
+				return fmt.Errorf("jump at %d to undefined label %q", j.idx, name)
+			}
+			if target <= j.idx {
+				return fmt.Errorf("jump at %d to label %q at %d goes backward", j.idx, name, target)
+			}
+			offsets[k] = target - j.idx - 1
+		}
+		b.branches[j.idx] = branches{jt: offsets[0], jf: offsets[1]}
+	}
+	return nil
+}
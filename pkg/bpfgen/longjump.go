@@ -0,0 +1,126 @@
+package bpfgen
+
+import (
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// Conditional jumps encode their offsets in 8 bits. A check far from its
+// target, such as the first value of a long port list or any early check
+// of a long program branching to the shared reject, cannot reach it
+// directly. The builder keeps the offsets as they were requested and, when
+// the program is built, routes out-of-range branches through unconditional
+// jumps, whose offset has 32 bits, placed right after the check. libpcap
+// lays out its long jumps the same way.
+
+// branches holds the offsets of an instruction's branches relative to the
+// next instruction, which may be more than the instruction can encode
+type branches struct {
+	jt, jf int
+}
+
+// layout resolves the labels and encodes the builder's program,
+// inserting a trampoline after every conditional jump with a branch out of
+// reach. It returns the instructions and the clause of each; a trampoline
+// implements the clause of the check it serves.
+func (b *BPFBuilder) layout() ([]*bpf.Instruction, []string, error) {
+	if err := b.resolve(); err != nil {
+		return nil, nil, err
+	}
+	n := len(b.instructions)
+
+	// far marks the branches that go through a trampoline, jt then jf.
+	// Trampolines only lengthen the jumps across them, so marks are never
+	// taken back and the loop ends once every branch is in reach.
+	far := make([][2]bool, n)
+	var pos []int
+	for {
+		pos = b.positions(far)
+		changed := false
+		for i, inst := range b.instructions {
+			if !isCond(inst) {
+				continue
+			}
+			for k, off := range [2]int{b.branches[i].jt, b.branches[i].jf} {
+				if !far[i][k] && at(pos, i+1+off)-pos[i]-1 > maxJumpOffset {
+					far[i][k] = true
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	instructions := make([]*bpf.Instruction, 0, pos[n])
+	sources := make([]string, 0, pos[n])
+	for i, inst := range b.instructions {
+		out := *inst
+		var trampolines []int
+		switch {
+		case isCond(inst):
+			offsets := [2]int{b.branches[i].jt, b.branches[i].jf}
+			var encoded [2]uint8
+			for k, off := range offsets {
+				target := at(pos, i+1+off)
+				if !far[i][k] {
+					encoded[k] = uint8(target - pos[i] - 1)
+					continue
+				}
+				// Both branches may share one trampoline
+				if k == 1 && far[i][0] && offsets[0] == off {
+					encoded[1] = encoded[0]
+					continue
+				}
+				encoded[k] = uint8(len(trampolines))
+				trampolines = append(trampolines, target)
+			}
+			out.JT, out.JF = encoded[0], encoded[1]
+		case inst.IsJump():
+			out.K = uint32(at(pos, i+1+int(inst.K)) - pos[i] - 1)
+		}
+		instructions = append(instructions, &out)
+		sources = append(sources, b.sources[i])
+		for _, target := range trampolines {
+			pc := len(instructions)
+			instructions = append(instructions, &bpf.Instruction{Code: bpf.OpJA, K: uint32(target - pc - 1)}) // ja target
+			sources = append(sources, b.sources[i])
+		}
+	}
+	return instructions, sources, nil
+}
+
+// positions returns where each instruction lands once the marked branches
+// have their trampolines, followed by the length of the program
+func (b *BPFBuilder) positions(far [][2]bool) []int {
+	pos := make([]int, len(b.instructions)+1)
+	next := 0
+	for i := range b.instructions {
+		pos[i] = next
+		next += 1 + b.trampolines(i, far[i])
+	}
+	pos[len(b.instructions)] = next
+	return pos
+}
+
+// trampolines returns how many trampolines follow instruction i
+func (b *BPFBuilder) trampolines(i int, far [2]bool) int {
+	switch {
+	case far[0] && far[1] && b.branches[i].jt == b.branches[i].jf:
+		return 1
+	case far[0] && far[1]:
+		return 2
+	case far[0] || far[1]:
+		return 1
+	}
+	return 0
+}
+
+// at returns where the instruction at index i lands. A target past the
+// end stays past the end by as much, for the verifier to report.
+func at(pos []int, i int) int {
+	if end := len(pos) - 1; i > end {
+		return pos[end] + i - end
+	}
+	return pos[i]
+}
@@ -0,0 +1,356 @@
+package bpfgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+)
+
+// A merged program keeps the checks its filters share in front of them:
+// one 802.1Q check when every filter asks for the same tag, one IPv4 check
+// when every filter is an IPv4 filter, and, when each names its IP
+// protocol, one protocol load that dispatches to the filters of that
+// protocol. The filters behind it are tried in turn. A failing check moves
+// on to the next filter, and the last filter's failures reject: after a
+// dispatch, no filter of another protocol can match. A matching filter
+// returns its own length, so the same layout serves rule lists, whose
+// first matching rule decides: the dispatch keeps the order of the rules
+// of each protocol.
+
+// alternative is one filter of a merged program
+type alternative struct {
+	name    string // "filter 2" or "rule 2", prefixed to its clauses
+	f       *filter.PacketFilter
+	ret     uint32 // length returned for a matching packet, 0 to drop it
+	accept  bool   // the filter accepts, which a headers-only program measures
+	verdict string // clause of that return, such as "(accept)"
+}
+
+// accepts reports whether the alternative returns what the builder's
+// accept instructions return
+func (a alternative) accepts(b *BPFBuilder) bool {
+	if b.headersOnly {
+		return a.accept
+	}
+	return a.ret == b.snaplen
+}
+
+// GenerateMergedBPF generates one program accepting the packets any of the
+// filters matches, for a socket that captures several rules at once. The
+// filters must capture the same link type. A filter that another filter
+// subsumes is left out, and the optimizations list it. Partial generation
+// applies to single filters only.
+func GenerateMergedBPF(filters []*filter.PacketFilter, opts Options) (*BPFCode, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("no filters to merge")
+	}
+	if opts.Partial {
+		return nil, fmt.Errorf("partial generation applies to single filters, not merged programs")
+	}
+	normalized, err := normalizeMerged(filters, "filter")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeadersOnly(opts, normalized...); err != nil {
+		return nil, err
+	}
+
+	log := logging.Logger()
+	builder := NewBPFBuilder()
+	builder.SetSnaplen(opts.snaplen())
+	builder.SetHeadersOnly(opts.HeadersOnly)
+	var alts []alternative
+	var descriptions []string
+	for i, f := range normalized {
+		if by := subsumedBy(normalized, i); by >= 0 {
+			note := fmt.Sprintf("Left out filter %d (%s): filter %d matches all of its packets", i+1, f.ToTcpdumpFilter(), by+1)
+			log.Debug("merged filter is subsumed", "filter", i+1, "by", by+1)
+			builder.AddOptimization(note)
+			continue
+		}
+		alts = append(alts, alternative{name: fmt.Sprintf("filter %d", i+1), f: f, ret: builder.snaplen, accept: true, verdict: "(accept)"})
+		descriptions = append(descriptions, buildFilterDescription(f))
+	}
+	log.Debug("generating merged Antrea-style BPF", "filters", len(alts))
+
+	reasoning, err := buildMergedBPF(alts, opts.Fragments, builder)
+	if err != nil {
+		return nil, err
+	}
+	filterDesc := descriptions[0]
+	if len(descriptions) > 1 {
+		filterDesc = "(" + strings.Join(descriptions, ") or (") + ")"
+	}
+	return assemble(builder, opts, filterDesc, normalized[0].LinkType, reasoning)
+}
+
+// GenerateRulesBPF generates one program applying a list of rules in
+// order: the first rule matching a packet decides whether it is captured
+// whole, truncated or dropped, and a packet no rule matches is dropped.
+// Accepting rules return the options' snapshot length and truncating
+// rules their own. A rule an earlier rule subsumes never decides and is
+// left out, as are drop rules with only drop rules after them.
+func GenerateRulesBPF(rules []*filter.Rule, opts Options) (*BPFCode, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules to generate")
+	}
+	if opts.Partial {
+		return nil, fmt.Errorf("partial generation applies to single filters, not rules")
+	}
+	filters := make([]*filter.PacketFilter, len(rules))
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid filter: %w", i+1, err)
+		}
+		filters[i] = &r.PacketFilter
+	}
+	normalized, err := normalizeMerged(filters, "rule")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeadersOnly(opts, normalized...); err != nil {
+		return nil, err
+	}
+
+	log := logging.Logger()
+	builder := NewBPFBuilder()
+	builder.SetSnaplen(opts.snaplen())
+	builder.SetHeadersOnly(opts.HeadersOnly)
+	var alts []alternative
+	var kept []int
+	for i, f := range normalized {
+		if by := shadowedBy(normalized, kept, i); by >= 0 {
+			builder.AddOptimization(fmt.Sprintf("Left out rule %d (%s): rule %d matches all of its packets first", i+1, rules[i], by+1))
+			continue
+		}
+		alt := alternative{name: fmt.Sprintf("rule %d", i+1), f: f, ret: builder.snaplen, accept: true, verdict: "(accept)"}
+		switch action, _ := filter.ParseAction(string(rules[i].Action)); action {
+		case filter.ActionTruncate:
+			alt.ret, alt.accept, alt.verdict = uint32(rules[i].Snaplen), false, fmt.Sprintf("(truncate to %d bytes)", rules[i].Snaplen)
+		case filter.ActionDrop:
+			alt.ret, alt.accept, alt.verdict = 0, false, "(drop)"
+		}
+		alts = append(alts, alt)
+		kept = append(kept, i)
+	}
+	// Packets no rule matches are dropped anyway
+	for len(alts) > 0 && alts[len(alts)-1].ret == 0 {
+		last := alts[len(alts)-1]
+		builder.AddOptimization(fmt.Sprintf("Left out %s (%s): packets no later rule accepts are dropped anyway", last.name, last.f.ToTcpdumpFilter()))
+		alts = alts[:len(alts)-1]
+	}
+	log.Debug("generating Antrea-style BPF for rules", "rules", len(alts))
+
+	var descriptions []string
+	for _, a := range alts {
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", buildFilterDescription(a.f), strings.Trim(a.verdict, "()")))
+	}
+	reasoning := "Antrea-style approach: every rule drops"
+	if len(alts) == 0 {
+		descriptions = []string{"drop everything"}
+		emitReject(builder)
+	} else if reasoning, err = buildMergedBPF(alts, opts.Fragments, builder); err != nil {
+		return nil, err
+	}
+	return assemble(builder, opts, strings.Join(descriptions, "; "), normalized[0].LinkType, reasoning)
+}
+
+// normalizeMerged normalizes the filters of a merged program, which must
+// capture the same link type. kind names the filters in errors.
+func normalizeMerged(filters []*filter.PacketFilter, kind string) ([]*filter.PacketFilter, error) {
+	normalized := make([]*filter.PacketFilter, len(filters))
+	for i, f := range filters {
+		n, err := filter.Normalize(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: invalid filter: %w", kind, i+1, err)
+		}
+		if first := normalized[0]; i > 0 && n.LinkType != first.LinkType && !(n.LinkType.IsEthernet() && first.LinkType.IsEthernet()) {
+			return nil, fmt.Errorf("%s %d captures %s and %s 1 %s; merged filters share a link type", kind, i+1, n.LinkType, kind, first.LinkType)
+		}
+		normalized[i] = n
+	}
+	return normalized, nil
+}
+
+// subsumedBy returns the filter that matches every packet filter i
+// matches and is kept, or -1 if there is none. Of equal filters, the first
+// is kept. Filters too large to relate are kept.
+func subsumedBy(filters []*filter.PacketFilter, i int) int {
+	for j, g := range filters {
+		if j == i || !subsumes(g, filters[i]) {
+			continue
+		}
+		if j > i && subsumes(filters[i], g) {
+			continue // equal, so filter i is the one kept
+		}
+		if subsumedBy(filters, j) < 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+// shadowedBy returns the earlier rule kept that matches every packet rule
+// i matches, or -1 if there is none
+func shadowedBy(filters []*filter.PacketFilter, kept []int, i int) int {
+	for _, j := range kept {
+		if subsumes(filters[j], filters[i]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// subsumes reports whether a is known to match every packet b matches
+func subsumes(a, b *filter.PacketFilter) bool {
+	ok, err := filter.Subsumes(a, b)
+	return err == nil && ok
+}
+
+// buildMergedBPF emits the shared checks, then each filter's own checks
+// followed by its return, and the reject
+func buildMergedBPF(alts []alternative, fragments FragmentPolicy, builder *BPFBuilder) (string, error) {
+	var reasoning strings.Builder
+	reasoning.WriteString("Merged Antrea-style approach: ")
+
+	first := alts[0].f
+	sharedLink, ipv4, dispatch := true, true, true
+	for _, a := range alts {
+		sharedLink = sharedLink && a.f.VLAN == first.VLAN && a.f.VLANID == first.VLANID
+		ipv4 = ipv4 && a.f.EtherProto() == 0
+		dispatch = dispatch && a.f.Tunnel == "" && a.f.IPProtocol() >= 0
+	}
+	sharedFamily := sharedLink && ipv4
+	dispatch = dispatch && sharedFamily
+
+	var off offsets
+	if sharedLink {
+		builder.SetSource(vlanClause(first))
+		off = emitLinkChecks(first, builder)
+		if first.VLAN {
+			reasoning.WriteString("shared 802.1Q tag check, ")
+		}
+	}
+	if sharedFamily {
+		reasoning.WriteString("shared IPv4 validation, ")
+		builder.SetSource("(ipv4)")
+		emitFamilyCheck(builder, off, false)
+	}
+
+	groups := [][]alternative{alts}
+	if dispatch {
+		groups = groupByProtocol(alts)
+		fmt.Fprintf(&reasoning, "one protocol load dispatching to %d protocols, ", len(groups))
+		emitProtocolDispatch(groups, off, builder)
+	}
+	fmt.Fprintf(&reasoning, "%d filters tried in turn, ", len(alts))
+
+	// The last filter falls through to the shared accept, if it accepts
+	last := alts[len(alts)-1]
+	shared := last.accepts(builder)
+
+	for g, group := range groups {
+		if dispatch && g > 0 {
+			builder.Label(protocolLabel(group))
+		}
+		for i, a := range group {
+			next := ""
+			if i < len(group)-1 {
+				next = builder.NewLabel("filter")
+			}
+			builder.RejectTo(next)
+			start := len(builder.instructions)
+			if err := emitAlternative(a.f, off, sharedLink, sharedFamily, dispatch, fragments, builder); err != nil {
+				return "", fmt.Errorf("%s: %w", a.name, err)
+			}
+			for pc := start; pc < len(builder.instructions); pc++ {
+				builder.sources[pc] = a.name + ": " + builder.sources[pc]
+			}
+			if !shared || g < len(groups)-1 || i < len(group)-1 {
+				builder.SetSource(a.name + ": " + a.verdict)
+				if a.accepts(builder) {
+					builder.AddAccept()
+				} else {
+					builder.AddInstruction(0x06, 0, 0, a.ret) // ret #length
+				}
+			}
+			if next != "" {
+				builder.Label(next)
+			}
+		}
+	}
+	builder.RejectTo("")
+
+	reasoning.WriteString("shared reject")
+	if shared {
+		emitVerdicts(builder)
+	} else {
+		emitReject(builder)
+	}
+	return reasoning.String(), nil
+}
+
+// groupByProtocol groups the filters by IP protocol, in the order the
+// protocols first appear
+func groupByProtocol(alts []alternative) [][]alternative {
+	var groups [][]alternative
+	index := make(map[int]int)
+	for _, a := range alts {
+		i, ok := index[a.f.IPProtocol()]
+		if !ok {
+			i = len(groups)
+			index[a.f.IPProtocol()] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], a)
+	}
+	return groups
+}
+
+// protocolLabel names the start of a protocol group's filters
+func protocolLabel(group []alternative) string {
+	return fmt.Sprintf("protocol.%d", group[0].f.IPProtocol())
+}
+
+// emitProtocolDispatch loads the IP protocol once and jumps to the filters
+// of the matching group. The first group's filters follow the dispatch,
+// and a protocol no group names is rejected.
+func emitProtocolDispatch(groups [][]alternative, off offsets, builder *BPFBuilder) {
+	builder.SetSource("(protocol dispatch)")
+	builder.AddInstruction(off.ld(0x30), 0, 0, off.protocol()) // ldb [protocol] - load IP protocol
+	for _, group := range groups[1:] {
+		builder.SetSource("protocol=" + group[0].f.Protocol)
+		builder.Jump(0x15, uint32(group[0].f.IPProtocol()), protocolLabel(group), "") // jeq protocol
+	}
+	builder.SetSource("protocol=" + groups[0][0].f.Protocol)
+	builder.Jump(0x15, uint32(groups[0][0].f.IPProtocol()), "", rejectLabel) // jeq protocol
+}
+
+// emitAlternative emits the checks of one filter that the shared checks
+// and the protocol dispatch have not made
+func emitAlternative(f *filter.PacketFilter, off offsets, sharedLink, sharedFamily, dispatched bool, fragments FragmentPolicy, builder *BPFBuilder) error {
+	var reasoning strings.Builder
+	if !sharedLink {
+		builder.SetSource(vlanClause(f))
+		off = emitLinkChecks(f, builder)
+	}
+	if f.EtherProto() != 0 {
+		emitLinkProtocolCheck(f, off, builder)
+		builder.measure(linkHeaders(f, off))
+		return nil
+	}
+	if !sharedFamily {
+		builder.SetSource("(ipv4)")
+		emitFamilyCheck(builder, off, false)
+	}
+	if f.Tunnel != "" {
+		return emitTunnelChecks(f, off, fragments, builder, &reasoning)
+	}
+	builder.measure(ipv4Headers(f, off, fragments))
+	if dispatched {
+		return emitAddressAndPortChecks(f, off, fragments, builder, &reasoning)
+	}
+	return emitIPCriteria(f, off, fragments, builder, &reasoning)
+}
@@ -0,0 +1,612 @@
+package bpfgen
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// OptLevel selects how much of the optimizer runs, like a compiler's -O.
+// The zero value is O2.
+type OptLevel int
+
+const (
+	// O0 emits the generated program as is
+	O0 OptLevel = iota + 1
+	// O1 runs the local passes: constant folding and dead code elimination
+	O1
+	// O2 adds the passes that follow values along every path: jump
+	// threading and the load deduplication passes
+	O2
+)
+
+// ParseOptLevel parses a level as given to -O: 0, 1 or 2
+func ParseOptLevel(s string) (OptLevel, error) {
+	switch s {
+	case "0":
+		return O0, nil
+	case "1":
+		return O1, nil
+	case "2":
+		return O2, nil
+	}
+	return 0, fmt.Errorf("invalid optimization level '%s', must be 0, 1 or 2", s)
+}
+
+// String returns the level as a flag, such as "-O2"
+func (l OptLevel) String() string {
+	if l == 0 {
+		l = O2
+	}
+	return fmt.Sprintf("-O%d", int(l)-1)
+}
+
+// maxOptimizeRounds bounds how often the passes are repeated; each round
+// only shrinks the program or shortens jumps, so few are ever needed
+const maxOptimizeRounds = 8
+
+// pass is one optimizer stage. It returns the number of changes it made;
+// a pass that cannot apply leaves the program alone and returns zero.
+type pass struct {
+	name  string
+	unit  string   // what the count of changes counts
+	level OptLevel // lowest level the pass runs at
+	run   func(p *program) int
+}
+
+// passes run in this order, round after round, until none applies
+var passes = []pass{
+	{name: "Constant folding", unit: "instruction", level: O1, run: foldConstants},
+	{name: "Jump threading", unit: "jump", level: O2, run: threadJumps},
+	{name: "Redundant-load removal", unit: "load", level: O2, run: removeRedundantLoads},
+	{name: "Common-load deduplication", unit: "instruction", level: O2, run: dedupeMaskedLoads},
+	{name: "Dead code elimination", unit: "instruction", level: O1, run: eliminateDeadCode},
+}
+
+// Optimize rewrites a classic BPF program into an equivalent one with
+// fewer instructions or shorter paths. It returns the new program and a
+// description of every pass that changed it, in the order the passes
+// run, followed by the number of instructions saved. The input is not
+// modified, and a program the passes cannot encode again (a jump that
+// would no longer fit in 8 bits) is returned as is.
+func Optimize(instructions []*bpf.Instruction) ([]*bpf.Instruction, []string) {
+	return OptimizeLevel(instructions, O2)
+}
+
+// OptimizeLevel is Optimize running only the passes of the given level
+func OptimizeLevel(instructions []*bpf.Instruction, level OptLevel) ([]*bpf.Instruction, []string) {
+	optimized, _, applied := optimize(instructions, nil, level)
+	return optimized, applied
+}
+
+// optimize is OptimizeLevel carrying the filter clause of each instruction
+// along to the instruction that replaces it. sources may be nil.
+func optimize(instructions []*bpf.Instruction, sources []string, level OptLevel) ([]*bpf.Instruction, []string, []string) {
+	if level == 0 {
+		level = O2
+	}
+	if level == O0 {
+		return instructions, sources, nil
+	}
+
+	p := decode(instructions, sources)
+	counts := make([]int, len(passes))
+	for round := 0; round < maxOptimizeRounds; round++ {
+		changed := false
+		for i, ps := range passes {
+			if ps.level > level {
+				continue
+			}
+			if n := ps.run(p); n > 0 {
+				counts[i] += n
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	optimized, optimizedSources, ok := p.encode()
+	if !ok {
+		return instructions, sources, nil
+	}
+	var applied []string
+	for i, ps := range passes {
+		if counts[i] == 0 {
+			continue
+		}
+		unit := ps.unit
+		if counts[i] > 1 {
+			unit += "s"
+		}
+		applied = append(applied, fmt.Sprintf("%s: %d %s", ps.name, counts[i], unit))
+	}
+	if saved := len(instructions) - len(optimized); saved > 0 {
+		applied = append(applied, fmt.Sprintf("Instruction savings: %d (%d -> %d)", saved, len(instructions), len(optimized)))
+	}
+	return optimized, optimizedSources, applied
+}
+
+// node is an instruction whose jump targets are absolute indices, so that
+// instructions can be removed without recomputing every offset by hand
+type node struct {
+	inst   bpf.Instruction
+	jt, jf int    // targets of a conditional jump
+	ja     int    // target of an unconditional jump
+	source string // filter clause the instruction implements
+}
+
+// program is a program under optimization
+type program struct {
+	nodes   []node
+	sources bool // the nodes carry filter clauses
+}
+
+// decode converts relative jump offsets to absolute targets
+func decode(instructions []*bpf.Instruction, sources []string) *program {
+	p := &program{nodes: make([]node, len(instructions)), sources: sources != nil}
+	for pc, inst := range instructions {
+		n := node{inst: *inst}
+		if p.sources {
+			n.source = sources[pc]
+		}
+		switch {
+		case isCond(inst):
+			n.jt, n.jf = pc+1+int(inst.JT), pc+1+int(inst.JF)
+		case inst.IsJump():
+			n.ja = pc + 1 + int(inst.K)
+		}
+		p.nodes[pc] = n
+	}
+	return p
+}
+
+// encode converts absolute targets back to relative offsets. It fails if
+// a target is out of range or a conditional offset does not fit in 8 bits.
+func (p *program) encode() ([]*bpf.Instruction, []string, bool) {
+	instructions := make([]*bpf.Instruction, len(p.nodes))
+	var sources []string
+	if p.sources {
+		sources = make([]string, len(p.nodes))
+	}
+	for pc, n := range p.nodes {
+		if sources != nil {
+			sources[pc] = n.source
+		}
+		inst := n.inst
+		switch {
+		case isCond(&inst):
+			jt, jf := n.jt-pc-1, n.jf-pc-1
+			if jt < 0 || jf < 0 || jt > maxJumpOffset || jf > maxJumpOffset || n.jt >= len(p.nodes) || n.jf >= len(p.nodes) {
+				return nil, nil, false
+			}
+			inst.JT, inst.JF = uint8(jt), uint8(jf)
+		case inst.IsJump():
+			if n.ja <= pc || n.ja >= len(p.nodes) {
+				return nil, nil, false
+			}
+			inst.K = uint32(n.ja - pc - 1)
+		}
+		instructions[pc] = &inst
+	}
+	return instructions, sources, true
+}
+
+// remove deletes the marked instructions. A jump to a removed instruction
+// continues at the next instruction that is kept.
+func (p *program) remove(dead map[int]bool) {
+	// next[i] is the new index of the first kept instruction at or after i
+	next := make([]int, len(p.nodes)+1)
+	kept := 0
+	for i := range p.nodes {
+		next[i] = kept
+		if !dead[i] {
+			kept++
+		}
+	}
+	next[len(p.nodes)] = kept
+
+	nodes := make([]node, 0, kept)
+	for i, n := range p.nodes {
+		if dead[i] {
+			continue
+		}
+		n.jt, n.jf, n.ja = next[clampTarget(n.jt, len(p.nodes))], next[clampTarget(n.jf, len(p.nodes))], next[clampTarget(n.ja, len(p.nodes))]
+		nodes = append(nodes, n)
+	}
+	p.nodes = nodes
+}
+
+// clampTarget keeps an out-of-range target out of range after removal
+func clampTarget(target, n int) int {
+	if target > n {
+		return n
+	}
+	return target
+}
+
+// isCond reports whether the instruction is a two-way jump
+func isCond(inst *bpf.Instruction) bool {
+	return inst.IsJump() && inst.Code&0xf0 != bpf.JmpJA
+}
+
+// successors returns the instructions control can reach from pc
+func (p *program) successors(pc int) []int {
+	n := &p.nodes[pc]
+	switch {
+	case isCond(&n.inst):
+		return []int{n.jt, n.jf}
+	case n.inst.IsJump():
+		return []int{n.ja}
+	case n.inst.IsReturn():
+		return nil
+	}
+	return []int{pc + 1}
+}
+
+// jumpTargets returns every instruction some jump lands on
+func (p *program) jumpTargets() map[int]bool {
+	targets := make(map[int]bool)
+	for pc, n := range p.nodes {
+		if n.inst.IsJump() {
+			for _, t := range p.successors(pc) {
+				targets[t] = true
+			}
+		}
+	}
+	return targets
+}
+
+// evalJump decides a conditional jump with a constant operand for a known
+// accumulator value
+func evalJump(inst *bpf.Instruction, a uint32) bool {
+	switch inst.Code & 0xf0 {
+	case bpf.JmpJEQ:
+		return a == inst.K
+	case bpf.JmpJGT:
+		return a > inst.K
+	case bpf.JmpJGE:
+		return a >= inst.K
+	}
+	return a&inst.K != 0 // jset
+}
+
+// foldALU computes a constant ALU operation, reporting false for division
+// or modulo, whose faulting behavior is left to the program
+func foldALU(op uint16, a, k uint32) (uint32, bool) {
+	switch op {
+	case bpf.ALUAdd:
+		return a + k, true
+	case bpf.ALUSub:
+		return a - k, true
+	case bpf.ALUMul:
+		return a * k, true
+	case bpf.ALUOr:
+		return a | k, true
+	case bpf.ALUAnd:
+		return a & k, true
+	case bpf.ALULsh:
+		return a << (k & 31), true
+	case bpf.ALURsh:
+		return a >> (k & 31), true
+	case bpf.ALUNeg:
+		return -a, true
+	case bpf.ALUXor:
+		return a ^ k, true
+	}
+	return 0, false
+}
+
+// foldConstants evaluates ALU operations and conditional jumps on an
+// accumulator holding a known constant, turning them into "ld #k" and
+// "ja". Constants are only followed within straight-line code.
+func foldConstants(p *program) int {
+	targets := p.jumpTargets()
+	changes := 0
+	known, a := false, uint32(0)
+	for pc := range p.nodes {
+		n := &p.nodes[pc]
+		inst := &n.inst
+		if targets[pc] {
+			known = false
+		}
+		switch inst.Class() {
+		case bpf.ClassLD:
+			known, a = inst.Code&0xe0 == bpf.ModeIMM, inst.K
+		case bpf.ClassALU:
+			src := inst.Code & bpf.SrcX
+			v, ok := foldALU(inst.Code&0xf0, a, inst.K)
+			if known && ok && (src == bpf.SrcK || inst.Code&0xf0 == bpf.ALUNeg) {
+				n.inst = bpf.Instruction{Code: bpf.ClassLD | bpf.ModeIMM, K: v}
+				a = v
+				changes++
+			} else {
+				known = false
+			}
+		case bpf.ClassMISC:
+			if inst.Code&0xf8 == bpf.MiscTXA {
+				known = false
+			}
+		case bpf.ClassJMP:
+			if known && isCond(inst) && inst.Code&bpf.SrcX == 0 {
+				target := n.jf
+				if evalJump(inst, a) {
+					target = n.jt
+				}
+				*n = node{inst: bpf.Instruction{Code: bpf.OpJA}, ja: target, source: n.source}
+				changes++
+			}
+			known = false
+		case bpf.ClassRET:
+			known = false
+		}
+	}
+	return changes
+}
+
+// threadJumps points jumps past instructions whose outcome is already
+// decided on the way there: unconditional jumps, and conditional jumps
+// testing the accumulator a preceding conditional jump just tested, such
+// as a "jeq #6" reached from the true branch of another "jeq #6"
+func threadJumps(p *program) int {
+	changes := 0
+	for pc := range p.nodes {
+		n := &p.nodes[pc]
+		switch {
+		case isCond(&n.inst):
+			for _, branch := range []*int{&n.jt, &n.jf} {
+				target := p.thread(pc, *branch, branch == &n.jt)
+				if target != *branch && target-pc-1 <= maxJumpOffset {
+					*branch = target
+					changes++
+				}
+			}
+		case n.inst.IsJump():
+			if target := p.skipJA(n.ja); target != n.ja {
+				n.ja = target
+				changes++
+			}
+		}
+	}
+	return changes
+}
+
+// skipJA follows a chain of unconditional jumps from target
+func (p *program) skipJA(target int) int {
+	for steps := 0; steps < len(p.nodes) && target < len(p.nodes); steps++ {
+		n := &p.nodes[target]
+		if !n.inst.IsJump() || isCond(&n.inst) {
+			break
+		}
+		target = n.ja
+	}
+	return target
+}
+
+// thread returns where the branch of the conditional jump at pc really
+// leads: past unconditional jumps, and past a conditional jump whose
+// outcome the branch taken already decides
+func (p *program) thread(pc, target int, taken bool) int {
+	target = p.skipJA(target)
+	if target >= len(p.nodes) {
+		return target
+	}
+	from, to := &p.nodes[pc].inst, &p.nodes[target].inst
+	if !isCond(to) || from.Code&bpf.SrcX != 0 || to.Code&bpf.SrcX != 0 {
+		return target
+	}
+
+	var outcome bool
+	switch {
+	case taken && from.Code&0xf0 == bpf.JmpJEQ:
+		// The accumulator is exactly K
+		outcome = evalJump(to, from.K)
+	case from.Code == to.Code && from.K == to.K:
+		// The same test again
+		outcome = taken
+	default:
+		return target
+	}
+	if outcome {
+		return p.nodes[target].jt
+	}
+	return p.nodes[target].jf
+}
+
+// location is what a register holds when it is a repeatable load: the
+// load instruction, for indexed loads the X load it was relative to, and
+// the bits an "and" kept of a packet field
+type location struct {
+	valid bool
+	code  uint16
+	k     uint32
+	xCode uint16
+	xK    uint32
+	mask  uint32
+}
+
+// field reports whether the location is a packet load, which "and" masks
+func (l location) field() bool {
+	mode := l.code & 0xe0
+	return l.valid && (mode == bpf.ModeABS || mode == bpf.ModeIND)
+}
+
+// registers is what is known about A and X before an instruction
+type registers struct {
+	a, x location
+}
+
+// apply returns the registers after inst
+func (r registers) apply(inst *bpf.Instruction) registers {
+	switch inst.Class() {
+	case bpf.ClassLD:
+		switch inst.Code & 0xe0 {
+		case bpf.ModeABS, bpf.ModeIMM, bpf.ModeLEN:
+			r.a = location{valid: true, code: inst.Code, k: inst.K, mask: 0xffffffff}
+		case bpf.ModeIND:
+			r.a = location{valid: r.x.valid, code: inst.Code, k: inst.K, xCode: r.x.code, xK: r.x.k, mask: 0xffffffff}
+		default:
+			r.a = location{}
+		}
+	case bpf.ClassLDX:
+		switch inst.Code & 0xe0 {
+		case bpf.ModeMSH, bpf.ModeIMM, bpf.ModeLEN:
+			r.x = location{valid: true, code: inst.Code, k: inst.K, mask: 0xffffffff}
+		default:
+			r.x = location{}
+		}
+	case bpf.ClassALU:
+		if inst.Code == bpf.ClassALU|bpf.ALUAnd|bpf.SrcK && r.a.field() {
+			r.a.mask &= inst.K
+		} else {
+			r.a = location{}
+		}
+	case bpf.ClassMISC:
+		if inst.Code&0xf8 == bpf.MiscTAX {
+			r.x = location{}
+		} else {
+			r.a = location{}
+		}
+	}
+	return r
+}
+
+// removeFlow walks the program forward, tracking what A and X hold on
+// every path, and deletes the instructions redundant reports true for.
+// The registers after a deleted instruction are the ones before it.
+func removeFlow(p *program, redundant func(pc int, r registers) bool) int {
+	in := make([][]registers, len(p.nodes)+1)
+	in[0] = []registers{{}}
+	dead := make(map[int]bool)
+
+	for pc := range p.nodes {
+		if len(in[pc]) == 0 {
+			continue // unreachable
+		}
+		r := in[pc][0]
+		for _, other := range in[pc][1:] {
+			if other.a != r.a {
+				r.a = location{}
+			}
+			if other.x != r.x {
+				r.x = location{}
+			}
+		}
+
+		out := r.apply(&p.nodes[pc].inst)
+		if redundant(pc, r) {
+			dead[pc] = true
+			out = r
+		}
+		for _, succ := range p.successors(pc) {
+			if succ > pc && succ <= len(p.nodes) {
+				in[succ] = append(in[succ], out)
+			}
+		}
+	}
+
+	p.remove(dead)
+	return len(dead)
+}
+
+// removeRedundantLoads deletes loads of a value the register already holds
+// on every path to the load. The earlier load succeeded, so dropping the
+// repeat cannot change which packets fault.
+func removeRedundantLoads(p *program) int {
+	return removeFlow(p, func(pc int, r registers) bool {
+		inst := &p.nodes[pc].inst
+		out := r.apply(inst)
+		return (inst.Class() == bpf.ClassLD && out.a.valid && out.a == r.a) ||
+			(inst.Class() == bpf.ClassLDX && out.x.valid && out.x == r.x)
+	})
+}
+
+// dedupeMaskedLoads reuses a masked packet field left in A by an earlier
+// check, such as the source address of "src net 10.0.0.0/8" when a later
+// "host 10.0.0.0/8" tests it again. A reload followed by "and #m" is
+// dropped when A holds the same field with at least the bits of m, and
+// the "and" too when A holds no bits outside m.
+func dedupeMaskedLoads(p *program) int {
+	return removeFlow(p, func(pc int, r registers) bool {
+		inst := &p.nodes[pc].inst
+		if !r.a.field() {
+			return false
+		}
+		switch inst.Class() {
+		case bpf.ClassLD:
+			if pc+1 >= len(p.nodes) {
+				return false
+			}
+			next := &p.nodes[pc+1].inst
+			loaded := r.apply(inst).a
+			loaded.mask = r.a.mask
+			return loaded == r.a && next.Code == bpf.ClassALU|bpf.ALUAnd|bpf.SrcK && next.K&^r.a.mask == 0
+		case bpf.ClassALU:
+			return inst.Code == bpf.ClassALU|bpf.ALUAnd|bpf.SrcK && r.a.mask&^inst.K == 0
+		}
+		return false
+	})
+}
+
+// eliminateDeadCode deletes instructions no path reaches, and accumulator
+// writes that cannot fault and are overwritten by the next instruction
+// before anything reads them
+func eliminateDeadCode(p *program) int {
+	reachable := p.reachable()
+	dead := make(map[int]bool)
+	for pc, n := range p.nodes {
+		if !reachable[pc] {
+			dead[pc] = true
+			continue
+		}
+		if pc+1 < len(p.nodes) && safeAccumulatorWrite(&n.inst) && overwritesAccumulator(&p.nodes[pc+1].inst) {
+			dead[pc] = true
+		}
+	}
+
+	p.remove(dead)
+	return len(dead)
+}
+
+// reachable marks the instructions some path from the first one reaches.
+// Jumps only go forward, so one pass in program order finds them all.
+func (p *program) reachable() []bool {
+	reachable := make([]bool, len(p.nodes))
+	if len(p.nodes) > 0 {
+		reachable[0] = true
+	}
+	for pc := range p.nodes {
+		if !reachable[pc] {
+			continue
+		}
+		for _, succ := range p.successors(pc) {
+			if succ < len(p.nodes) {
+				reachable[succ] = true
+			}
+		}
+	}
+	return reachable
+}
+
+// safeAccumulatorWrite reports whether the instruction only writes A and
+// cannot abort the program
+func safeAccumulatorWrite(inst *bpf.Instruction) bool {
+	switch inst.Class() {
+	case bpf.ClassLD:
+		mode := inst.Code & 0xe0
+		return mode == bpf.ModeIMM || mode == bpf.ModeLEN || mode == bpf.ModeMEM
+	case bpf.ClassALU:
+		op := inst.Code & 0xf0
+		return op != bpf.ALUDiv && op != bpf.ALUMod
+	case bpf.ClassMISC:
+		return inst.Code&0xf8 == bpf.MiscTXA
+	}
+	return false
+}
+
+// overwritesAccumulator reports whether the instruction sets A without
+// reading it
+func overwritesAccumulator(inst *bpf.Instruction) bool {
+	return inst.Class() == bpf.ClassLD || (inst.Class() == bpf.ClassMISC && inst.Code&0xf8 == bpf.MiscTXA)
+}
@@ -0,0 +1,337 @@
+// Package ovs translates the PacketFilter model into Open vSwitch flow
+// matches, in the syntax of ovs-ofctl, so that a capture filter can be
+// cross-checked against the flows Antrea's OVS datapath installs. It is
+// experimental and only writes matches out; it does not talk to OVS.
+package ovs
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// TunnelPort is the OVS port Antrea receives VXLAN and Geneve traffic on,
+// already decapsulated, with the outer headers kept as tunnel metadata
+const TunnelPort = "antrea-tun0"
+
+// Field is one match field of a flow, e.g. nw_src=10.0.0.0/24. A field
+// without a value is a protocol shorthand, such as tcp or arp.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// String formats the field as ovs-ofctl reads it
+func (fd Field) String() string {
+	if fd.Value == "" {
+		return fd.Name
+	}
+	return fd.Name + "=" + fd.Value
+}
+
+// Flow is the match of one OpenFlow flow
+type Flow []Field
+
+// String formats the match as ovs-ofctl reads it, e.g.
+// "tcp,nw_src=10.0.0.0/24,tp_dst=80"
+func (fl Flow) String() string {
+	parts := make([]string, len(fl))
+	for i, fd := range fl {
+		parts[i] = fd.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Translation is the set of flows matching the packets a filter matches
+type Translation struct {
+	FilterExpr string
+	Flows      []Flow
+}
+
+// String lists the flows, one per line
+func (t *Translation) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("OVS Flows (experimental): %s\n", t.FilterExpr))
+	sb.WriteString(fmt.Sprintf("Flows: %d\n", len(t.Flows)))
+	for _, fl := range t.Flows {
+		sb.WriteString(fmt.Sprintf("  %s\n", fl))
+	}
+	return sb.String()
+}
+
+// Translate returns flow matches that together match the packets the
+// valid filter f matches. A flow cannot express alternatives, so port
+// lists, either-direction hosts and ports, and traffic between two
+// networks become a flow per combination, and a filter leaving the
+// address family open becomes flows for both IPv4 and IPv6. Like the
+// generators, addresses without a protocol match IP packets only.
+//
+// Tunnel filters match the traffic Antrea receives on TunnelPort: outer
+// addresses become tun_src and tun_dst and the VNI tun_id, while inner
+// criteria match the decapsulated packet. OVS cannot tell VXLAN from
+// Geneve in a flow, since the tunnel type belongs to the port. The link
+// type is left out too: it only moves header offsets in a capture, and
+// OVS matches on parsed fields.
+func Translate(f *filter.PacketFilter) (*Translation, error) {
+	var base Flow
+	if f.Tunnel != "" {
+		// The outer VLAN tag goes with the outer headers
+		if f.VLAN {
+			return nil, fmt.Errorf("OVS flows cannot match the VLAN tag of %s traffic, which is removed with the tunnel headers", f.Tunnel)
+		}
+		base = append(base, Field{"in_port", TunnelPort})
+		if f.VNI != 0 {
+			base = append(base, Field{"tun_id", fmt.Sprintf("0x%x", f.VNI)})
+		}
+	}
+	if f.VLANID != 0 {
+		base = append(base, Field{"dl_vlan", fmt.Sprint(f.VLANID)})
+	} else if f.VLAN {
+		base = append(base, Field{"vlan_tci", "0x1000/0x1000"})
+	}
+
+	// Each entry is a list of groups of alternatives, one entry per
+	// address family, since the family names the address fields
+	var variants [][][]Flow
+	switch {
+	case f.Tunnel != "" && !f.Inner:
+		// The tunnel decides the outer protocol and ports, so only the
+		// outer addresses are left
+		variants = append(variants, addressGroups(f, "tun_src", "tun_dst"))
+	case f.EtherProto() != 0:
+		variants = append(variants, [][]Flow{{{etherProtoField(f)}}})
+	case f.Protocol != "" || f.HasPorts() || hasAddresses(f):
+		for _, ipv6 := range families(f) {
+			protocols, err := protocolFlows(f, ipv6)
+			if err != nil {
+				return nil, err
+			}
+			src, dst := "nw_src", "nw_dst"
+			if ipv6 {
+				src, dst = "ipv6_src", "ipv6_dst"
+			}
+			groups := append([][]Flow{protocols}, addressGroups(f, src, dst)...)
+			variants = append(variants, append(groups, portGroups(f)...))
+		}
+	default:
+		variants = append(variants, nil)
+	}
+
+	t := &Translation{FilterExpr: f.ToTcpdumpFilter()}
+	seen := make(map[string]bool)
+	var flows []Flow
+	for _, groups := range variants {
+		flows = append(flows, expand(base, groups)...)
+	}
+	for _, fl := range flows {
+		if s := fl.String(); !seen[s] {
+			seen[s] = true
+			t.Flows = append(t.Flows, fl)
+		}
+	}
+	return t, nil
+}
+
+// etherProtoField returns the match for a link-layer filter
+func etherProtoField(f *filter.PacketFilter) Field {
+	switch f.Protocol {
+	case "arp", "rarp":
+		return Field{Name: f.Protocol}
+	}
+	return Field{"dl_type", fmt.Sprintf("0x%04x", f.EtherProto())}
+}
+
+// families returns the address families the filter matches, as whether
+// each is IPv6. Addresses fix the family; protocol numbers and icmp, like
+// tcpdump's "ip proto" and "icmp", match IPv4 only.
+func families(f *filter.PacketFilter) []bool {
+	for _, addr := range addresses(f) {
+		return []bool{!isIPv4(addr)}
+	}
+	if f.HasProtocolNumber() || f.Protocol == "icmp" {
+		return []bool{false}
+	}
+	return []bool{false, true}
+}
+
+// protocolFlows returns the protocol matches of one family. Ports without
+// a protocol mean TCP, UDP or SCTP, as in tcpdump's "port".
+func protocolFlows(f *filter.PacketFilter, ipv6 bool) ([]Flow, error) {
+	if f.Protocol == "" && !f.HasPorts() {
+		if ipv6 {
+			return []Flow{{{Name: "ipv6"}}}, nil
+		}
+		return []Flow{{{Name: "ip"}}}, nil
+	}
+	numbers := []int{6, 17, 132}
+	if f.Protocol != "" {
+		numbers = []int{f.IPProtocol()}
+	}
+
+	var flows []Flow
+	for _, n := range numbers {
+		name, ok := shorthands[n]
+		switch {
+		case ipv6 && n == 1:
+			return nil, fmt.Errorf("icmp matches IPv4 only, not IPv6 addresses")
+		case ipv6:
+			name += "6"
+		case !ok:
+			flows = append(flows, Flow{{Name: "ip"}, {"nw_proto", fmt.Sprint(n)}})
+			continue
+		}
+		flows = append(flows, Flow{{Name: name}})
+	}
+	return flows, nil
+}
+
+// shorthands names the IPv4 protocols ovs-ofctl has a shorthand for; the
+// IPv6 shorthands add a 6, except for ICMP
+var shorthands = map[int]string{1: "icmp", 6: "tcp", 17: "udp", 132: "sctp"}
+
+// addressGroups returns the alternatives of each address criterion, with
+// the source and destination fields named src and dst
+func addressGroups(f *filter.PacketFilter, src, dst string) [][]Flow {
+	var groups [][]Flow
+	if f.SrcIP != "" {
+		groups = append(groups, []Flow{{{src, netText(f.SrcIP)}}})
+	}
+	if f.DstIP != "" {
+		groups = append(groups, []Flow{{{dst, netText(f.DstIP)}}})
+	}
+	if f.HostIP != "" {
+		host := netText(f.HostIP)
+		groups = append(groups, []Flow{{{src, host}}, {{dst, host}}})
+	}
+	if len(f.Between) == 2 {
+		a, b := netText(f.Between[0]), netText(f.Between[1])
+		groups = append(groups, []Flow{{{src, a}, {dst, b}}, {{src, b}, {dst, a}}})
+	}
+	return groups
+}
+
+// portGroups returns the alternatives of each port criterion
+func portGroups(f *filter.PacketFilter) [][]Flow {
+	var groups [][]Flow
+	for _, dir := range []struct {
+		field string
+		ports []int
+	}{{"tp_src", f.SrcPortList()}, {"tp_dst", f.DstPortList()}} {
+		if len(dir.ports) == 0 {
+			continue
+		}
+		group := make([]Flow, len(dir.ports))
+		for i, p := range dir.ports {
+			group[i] = Flow{{dir.field, fmt.Sprint(p)}}
+		}
+		groups = append(groups, group)
+	}
+	if f.Port != 0 {
+		port := fmt.Sprint(f.Port)
+		groups = append(groups, []Flow{{{"tp_src", port}}, {{"tp_dst", port}}})
+	}
+	return groups
+}
+
+// expand returns a flow for each way of picking one alternative from every
+// group, each starting with base. Picks that test a field twice keep the
+// narrower value, and picks that cannot both hold are left out.
+func expand(base Flow, groups [][]Flow) []Flow {
+	flows := []Flow{base}
+	for _, group := range groups {
+		var next []Flow
+		for _, fl := range flows {
+			for _, alt := range group {
+				if merged, ok := merge(fl, alt); ok {
+					next = append(next, merged)
+				}
+			}
+		}
+		flows = next
+	}
+	return flows
+}
+
+// merge adds the fields of alt to a copy of fl, reporting false when a
+// field is already tested for a value no packet can also have
+func merge(fl, alt Flow) (Flow, bool) {
+	merged := append(Flow{}, fl...)
+next:
+	for _, fd := range alt {
+		for i, have := range merged {
+			if have.Name != fd.Name {
+				continue
+			}
+			value, ok := intersect(have.Value, fd.Value)
+			if !ok {
+				return nil, false
+			}
+			merged[i].Value = value
+			continue next
+		}
+		merged = append(merged, fd)
+	}
+	return merged, true
+}
+
+// intersect returns the narrower of two values of one field: the same
+// value, or for networks the one inside the other
+func intersect(a, b string) (string, bool) {
+	if a == b {
+		return a, true
+	}
+	na, errA := filter.ParseNet(a)
+	nb, errB := filter.ParseNet(b)
+	switch {
+	case errA != nil || errB != nil:
+		return "", false
+	case na.Contains(nb.IP) && ones(nb) >= ones(na):
+		return b, true
+	case nb.Contains(na.IP) && ones(na) >= ones(nb):
+		return a, true
+	}
+	return "", false
+}
+
+// ones returns the prefix length of a network
+func ones(n *net.IPNet) int {
+	size, _ := n.Mask.Size()
+	return size
+}
+
+// addresses returns the filter's addresses and networks
+func addresses(f *filter.PacketFilter) []string {
+	var addrs []string
+	for _, addr := range append([]string{f.SrcIP, f.DstIP, f.HostIP}, f.Between...) {
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// hasAddresses reports whether the filter has any address criteria
+func hasAddresses(f *filter.PacketFilter) bool {
+	return len(addresses(f)) > 0
+}
+
+// netText formats an address or network as OVS does: a bare address for
+// a host, a network with its host bits cleared otherwise
+func netText(s string) string {
+	ipnet, err := filter.ParseNet(s)
+	if err != nil {
+		return s
+	}
+	if ones, bits := ipnet.Mask.Size(); ones == bits {
+		return ipnet.IP.String()
+	}
+	return ipnet.String()
+}
+
+// isIPv4 reports whether addr is an IPv4 address or network
+func isIPv4(addr string) bool {
+	ipnet, err := filter.ParseNet(addr)
+	return err == nil && ipnet.IP.To4() != nil
+}
@@ -0,0 +1,144 @@
+package bpfgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Options control prototype program generation
+type Options struct {
+	// Partial drops criteria the generator cannot express instead of
+	// failing. The program then matches a superset of the requested
+	// traffic, and the dropped criteria are listed in BPFCode.Uncovered so
+	// the capture can be post-filtered.
+	Partial bool
+
+	// OptLevel selects the optimizer passes run on the program; the zero
+	// value runs them all
+	OptLevel OptLevel
+
+	// Fragments decides which IPv4 fragments can match; the zero value
+	// matches like tcpdump
+	Fragments FragmentPolicy
+
+	// Snaplen is the length the program returns for a matching packet,
+	// like tcpdump -s (0 means DefaultSnaplen)
+	Snaplen int
+
+	// HeadersOnly makes accepting programs return the length of the
+	// packet's link-layer, IPv4 and transport headers, computed per packet,
+	// instead of a snapshot length. It excludes Snaplen and tunnel filters.
+	HeadersOnly bool
+
+	// MaxInstructions fails generation with a *BudgetError when the
+	// optimized program is longer, such as bpf.MaxInstructions for what the
+	// kernel loads (0 means no budget)
+	MaxInstructions int
+}
+
+// DefaultSnaplen is tcpdump's default snapshot length, which accepting
+// programs return unless Options set another
+const DefaultSnaplen = 262144
+
+// snaplen returns the length accepting programs return
+func (o Options) snaplen() int {
+	if o.Snaplen == 0 {
+		return DefaultSnaplen
+	}
+	return o.Snaplen
+}
+
+// Uncovered is a filter criterion that a partial program does not enforce
+type Uncovered struct {
+	Field  string `json:"field"`  // filter field, as named in test case YAML
+	Value  string `json:"value"`  // the requested value
+	Reason string `json:"reason"` // why the generator cannot express it
+}
+
+// String returns a one-line description of the criterion
+func (u Uncovered) String() string {
+	return fmt.Sprintf("%s=%s (%s)", u.Field, u.Value, u.Reason)
+}
+
+// UncoveredJSON returns the uncovered criteria as a JSON array, which is
+// empty when the program covers the whole filter
+func (bpf *BPFCode) UncoveredJSON() ([]byte, error) {
+	uncovered := bpf.Uncovered
+	if uncovered == nil {
+		uncovered = []Uncovered{}
+	}
+	return json.MarshalIndent(uncovered, "", "  ")
+}
+
+// supportedSubset returns a copy of the filter without the criteria the
+// generator cannot express, along with the criteria that were removed.
+// IPv6 addresses imply IPv6 packets, whose headers the generator cannot
+// parse, so in that case ipv6 is set and every criterion is uncovered; the
+// caller then matches all IPv6 packets by their link-layer protocol.
+func supportedSubset(f *filter.PacketFilter) (subset *filter.PacketFilter, uncovered []Uncovered, ipv6 bool) {
+	isIPv6 := func(addr string) bool {
+		_, _, err := netToUint32(addr)
+		return addr != "" && err != nil
+	}
+	ipv6 = isIPv6(f.SrcIP) || isIPv6(f.DstIP) || isIPv6(f.HostIP)
+	if len(f.Between) == 2 {
+		_, _, err := netToUint32(f.Between[0])
+		ipv6 = ipv6 || err != nil
+	}
+	if !ipv6 {
+		return f, nil, false
+	}
+
+	reason := "IPv6 packets are matched by IP version only"
+	add := func(field, value string) {
+		uncovered = append(uncovered, Uncovered{Field: field, Value: value, Reason: reason})
+	}
+	if f.Protocol != "" {
+		add("protocol", f.Protocol)
+	}
+	if f.SrcIP != "" {
+		add("src-ip", f.SrcIP)
+	}
+	if f.DstIP != "" {
+		add("dst-ip", f.DstIP)
+	}
+	if f.HostIP != "" {
+		add("host", f.HostIP)
+	}
+	if len(f.Between) == 2 {
+		add("between", strings.Join(f.Between, ","))
+	}
+	if f.SrcPort != 0 {
+		add("src-port", fmt.Sprintf("%d", f.SrcPort))
+	}
+	if len(f.SrcPorts) > 0 {
+		add("src-ports", joinPorts(f.SrcPorts))
+	}
+	if f.DstPort != 0 {
+		add("dst-port", fmt.Sprintf("%d", f.DstPort))
+	}
+	if len(f.DstPorts) > 0 {
+		add("dst-ports", joinPorts(f.DstPorts))
+	}
+	if f.Port != 0 {
+		add("port", fmt.Sprintf("%d", f.Port))
+	}
+	// The VLAN tag and link type apply the same way to both families
+	return &filter.PacketFilter{VLAN: f.VLAN, VLANID: f.VLANID, LinkType: f.LinkType}, uncovered, true
+}
+
+// buildIPv6Superset emits a program accepting every IPv6 frame that
+// carries the filter's VLAN tag, if any
+func buildIPv6Superset(f *filter.PacketFilter, builder *BPFBuilder) string {
+	builder.SetSource(vlanClause(f))
+	off := emitLinkChecks(f, builder)
+	builder.SetSource("(ipv6)")
+	emitFamilyCheck(builder, off, true)
+	builder.measure(&headers{off: off, network: ipv6HeaderLen})
+	emitVerdicts(builder)
+	builder.AddOptimization("Partial program: IP-version-only superset of the requested IPv6 traffic")
+	return "Antrea-style approach: IPv6 superset by IP version, remaining criteria left to post-filtering"
+}
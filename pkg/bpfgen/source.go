@@ -0,0 +1,87 @@
+package bpfgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// SourceSpan is a run of consecutive instructions generated for the same
+// filter clause. Clauses are named like test case fields ("dst-port=80");
+// checks the filter only implies, such as "(ipv4)", are in parentheses.
+type SourceSpan struct {
+	Start, End int // instruction range [Start, End)
+	Clause     string
+}
+
+// String describes the span, e.g. "instructions 4-5 implement dst-port=80"
+func (s SourceSpan) String() string {
+	if s.End-s.Start == 1 {
+		return fmt.Sprintf("instruction %d implements %s", s.Start, s.Clause)
+	}
+	return fmt.Sprintf("instructions %d-%d implement %s", s.Start, s.End-1, s.Clause)
+}
+
+// SourceMap groups the instructions by the clause they implement, in
+// program order. It is empty for a program without recorded sources.
+func (bpf *BPFCode) SourceMap() []SourceSpan {
+	var spans []SourceSpan
+	for pc, clause := range bpf.Sources {
+		if n := len(spans); n > 0 && spans[n-1].Clause == clause {
+			spans[n-1].End = pc + 1
+			continue
+		}
+		spans = append(spans, SourceSpan{Start: pc, End: pc + 1, Clause: clause})
+	}
+	return spans
+}
+
+// ClauseAt returns the clause instruction pc implements, or "" if unknown
+func (bpf *BPFCode) ClauseAt(pc int) string {
+	if pc < 0 || pc >= len(bpf.Sources) {
+		return ""
+	}
+	return bpf.Sources[pc]
+}
+
+// Disassembly lists the program like bpf.Disassemble with a comment naming
+// the clause above each span. Comments are ignored by bpf.Assemble, so the
+// listing still assembles.
+func (bpf *BPFCode) Disassembly() string {
+	return annotate(bpf.Instructions, bpf.SourceMap())
+}
+
+// annotate disassembles the instructions with a comment above each span
+func annotate(instructions []*bpf.Instruction, spans []SourceSpan) string {
+	listing := bpf.Disassemble(instructions)
+	if len(spans) == 0 {
+		return listing
+	}
+	lines := strings.SplitAfter(listing, "\n")
+	var sb strings.Builder
+	for _, span := range spans {
+		fmt.Fprintf(&sb, "; %s\n", span.Clause)
+		for pc := span.Start; pc < span.End && pc < len(lines); pc++ {
+			sb.WriteString(lines[pc])
+		}
+	}
+	return sb.String()
+}
+
+// vlanClause names the link-layer checks of a filter
+func vlanClause(f *filter.PacketFilter) string {
+	if f.VLANID != 0 {
+		return fmt.Sprintf("vlan-id=%d", f.VLANID)
+	}
+	return "vlan"
+}
+
+// portClause names a port criterion after the field that holds it
+func portClause(dir string, ports []int) string {
+	if len(ports) == 1 {
+		return fmt.Sprintf("%s-port=%d", dir, ports[0])
+	}
+	return fmt.Sprintf("%s-ports=%s", dir, joinPorts(ports))
+}
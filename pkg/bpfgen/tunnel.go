@@ -0,0 +1,125 @@
+package bpfgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Tunnel header fields, relative to the start of the VXLAN or Geneve header
+const (
+	tunnelFlags    = 0 // VXLAN flags, or Geneve version and option length
+	tunnelProtocol = 2 // Geneve protocol type of the payload
+	tunnelVNI      = 4 // 24-bit VNI followed by a reserved byte
+
+	vxlanFlagVNI        = 0x08       // I flag: the VNI is valid
+	geneveVersionMask   = 0xc0       // version, which must be 0
+	geneveOptionLenMask = 0x3f       // option length in 4-byte words
+	geneveEthernet      = 0x6558     // Transparent Ethernet Bridging
+	vniMask             = 0xffffff00 // VNI bits of the word at tunnelVNI
+)
+
+// buildTunnelBPF matches VXLAN or Geneve traffic. The outer headers get
+// the same early validation as any IPv4 filter, then the UDP port, the
+// tunnel header and the VNI are checked relative to the outer IP header
+// length in X. The other criteria apply to the outer IPv4 header, or with
+// Inner to the encapsulated frame: X then also skips the tunnel header
+// and any Geneve options, so the inner fields are loaded at computed
+// offsets.
+func buildTunnelBPF(f *filter.PacketFilter, fragments FragmentPolicy, builder *BPFBuilder) (string, error) {
+	var reasoning strings.Builder
+	reasoning.WriteString("Antrea-style approach: ")
+
+	builder.SetSource(vlanClause(f))
+	off := emitLinkChecks(f, builder)
+	if f.VLAN {
+		reasoning.WriteString("0) 802.1Q tag check with shifted offsets, ")
+	}
+
+	reasoning.WriteString("1) Early IP validation, ")
+	builder.SetSource("(ipv4)")
+	emitFamilyCheck(builder, off, false)
+
+	if err := emitTunnelChecks(f, off, fragments, builder, &reasoning); err != nil {
+		return "", err
+	}
+
+	reasoning.WriteString("5) Optimized accept/reject with minimal instructions")
+	emitVerdicts(builder)
+
+	return reasoning.String(), nil
+}
+
+// emitTunnelChecks emits the checks of a tunnel filter that follow the
+// outer IPv4 family check: the UDP port, the tunnel header and VNI, and
+// the outer or inner criteria
+func emitTunnelChecks(f *filter.PacketFilter, off offsets, fragments FragmentPolicy, builder *BPFBuilder, reasoning *strings.Builder) error {
+	// Tunnels run over UDP, whatever the filter's protocol says
+	builder.SetSource("tunnel=" + string(f.Tunnel))
+	builder.AddInstruction(0x30, 0, 0, off.protocol()) // ldb [protocol] - load IP protocol
+	builder.Jump(0x15, 0x00000011, "", rejectLabel)    // jeq #17 (udp)
+
+	if !f.Inner {
+		outer := *f
+		outer.Protocol = ""
+		if err := emitIPCriteria(&outer, off, fragments, builder, reasoning); err != nil {
+			return err
+		}
+	}
+
+	// The tunnel header is UDP payload, which only the first fragment
+	// carries. Outer criteria have already rejected every fragment if the
+	// policy says so.
+	if f.Inner || fragments != FragmentsReject {
+		emitFragmentCheck(builder, off, fragments, true)
+	}
+
+	fmt.Fprintf(reasoning, "%s port, header and VNI checks after the outer IP header, ", f.Tunnel)
+	builder.SetSource("tunnel=" + string(f.Tunnel))
+	builder.AddInstruction(0xb1, 0, 0, off.ip)                   // ldxb 4*([ip]&0xf) - IP header length
+	builder.AddInstruction(0x48, 0, 0, off.ip+2)                 // ldh [x + ip + 2] - load dest port
+	builder.Jump(0x15, uint32(f.Tunnel.Port()), "", rejectLabel) // jeq tunnel port
+
+	// The tunnel header follows the 8-byte UDP header
+	header := off.ip + 8
+	builder.AddInstruction(0x50, 0, 0, header+tunnelFlags) // ldb [x + header] - load flags
+	if f.Tunnel == filter.TunnelGeneve {
+		builder.AddInstruction(0x54, 0, 0, geneveVersionMask) // and #0xc0 - keep version
+		builder.Jump(0x15, 0x00000000, "", rejectLabel)       // jeq #0 - version 0
+	} else {
+		builder.Jump(0x45, vxlanFlagVNI, "", rejectLabel) // jset #0x08 - VNI is valid
+	}
+
+	if f.VNI != 0 {
+		builder.SetSource(fmt.Sprintf("vni=%d", f.VNI))
+		builder.AddInstruction(0x40, 0, 0, header+tunnelVNI)  // ld [x + header + 4] - load VNI
+		builder.AddInstruction(0x54, 0, 0, vniMask)           // and #0xffffff00 - drop reserved byte
+		builder.Jump(0x15, uint32(f.VNI)<<8, "", rejectLabel) // jeq vni
+	}
+
+	if f.Inner {
+		reasoning.WriteString("inner Ethernet/IPv4 validation at computed offsets, ")
+		builder.SetSource("(inner ipv4)")
+		if f.Tunnel == filter.TunnelGeneve {
+			// Geneve can carry other payloads, and options move the frame
+			builder.AddInstruction(0x48, 0, 0, header+tunnelProtocol) // ldh [x + header + 2] - load protocol type
+			builder.Jump(0x15, geneveEthernet, "", rejectLabel)       // jeq #0x6558 - Ethernet payload
+			builder.AddInstruction(0x50, 0, 0, header+tunnelFlags)    // ldb [x + header] - load option length
+			builder.AddInstruction(0x54, 0, 0, geneveOptionLenMask)   // and #0x3f - keep option length in words
+			builder.AddInstruction(0x64, 0, 0, 0x00000002)            // lsh #2 - convert to bytes
+			builder.AddInstruction(0x0c, 0, 0, 0x00000000)            // add x - add the outer IP header length
+			builder.AddInstruction(0x07, 0, 0, 0x00000000)            // tax - X now also skips the options
+		}
+
+		// The inner Ethernet frame follows the fixed tunnel header
+		frame := header + filter.TunnelHeaderLen
+		inner := offsets{link: filter.LinkEN10MB, etherType: frame + 12, ip: frame + 14, indexed: true}
+		emitFamilyCheck(builder, inner, false)
+
+		if err := emitIPCriteria(f, inner, fragments, builder, reasoning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package bpfgen
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// Unreachable returns the instructions no path from the first instruction
+// reaches, neither by falling through nor by a jump, in program order.
+// Dead code elimination removes them from optimized programs; an O0
+// program keeps them.
+func Unreachable(instructions []*bpf.Instruction) []int {
+	var dead []int
+	for pc, reached := range decode(instructions, nil).reachable() {
+		if !reached {
+			dead = append(dead, pc)
+		}
+	}
+	return dead
+}
+
+// unreachableWarnings describes the unreachable instructions of a
+// generated program, one warning per run of them with the same clause,
+// such as "Warning: unreachable instruction 12 implements (reject)"
+func unreachableWarnings(instructions []*bpf.Instruction, sources []string) []string {
+	var warnings []string
+	dead := Unreachable(instructions)
+	for i := 0; i < len(dead); {
+		first, clause := dead[i], sources[dead[i]]
+		j := i + 1
+		for j < len(dead) && dead[j] == dead[j-1]+1 && sources[dead[j]] == clause {
+			j++
+		}
+		span := SourceSpan{Start: first, End: dead[j-1] + 1, Clause: clause}
+		warnings = append(warnings, fmt.Sprintf("Warning: unreachable %s", span))
+		i = j
+	}
+	return warnings
+}
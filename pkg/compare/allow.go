@@ -0,0 +1,107 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Allowance declares a difference between the programs as intended, such
+// as the fragment checks the prototype adds on purpose, so that it neither
+// lowers the score nor is reported as a finding on every run:
+//
+//	allow:
+//	  - rule: extra-check
+//	    check: Check Fragment
+//	    reason: the prototype may add fragment checks
+//
+// Only differences in checks can be allowed. Packets decided differently,
+// offset mismatches and verifier failures are always reported. Returns
+// are matched by verdict, never by the length they return, so return
+// length differences need no allowance.
+type Allowance struct {
+	Rule   string `yaml:"rule"`   // RuleMissingCheck, RuleExtraCheck or RuleCheckCount
+	Check  string `yaml:"check"`  // kind of check as reports name it, e.g. "Check Fragment"; empty for any
+	Match  string `yaml:"match"`  // text the difference must contain, e.g. "0x1fff"; empty for any
+	Reason string `yaml:"reason"` // why the difference is intended, shown with it
+}
+
+// allowableRules are the finding rules an allowance can name
+var allowableRules = []string{RuleMissingCheck, RuleExtraCheck, RuleCheckCount}
+
+// validate checks that the allowance names an allowable rule and a known
+// kind of check, and says why
+func (a Allowance) validate() error {
+	known := false
+	for _, rule := range allowableRules {
+		known = known || a.Rule == rule
+	}
+	if !known {
+		return fmt.Errorf("invalid rule '%s', must be %s, %s or %s", a.Rule, RuleMissingCheck, RuleExtraCheck, RuleCheckCount)
+	}
+	if a.Check != "" && checkName(a.Check) != a.Check {
+		return fmt.Errorf("unknown check '%s', e.g. %q or %q", a.Check, CheckFragment.String(), CheckProtocol.String())
+	}
+	if a.Reason == "" {
+		return fmt.Errorf("%s allowance has no reason", a.Rule)
+	}
+	return nil
+}
+
+// matches reports whether the allowance covers a difference reported under
+// the rule
+func (a Allowance) matches(rule, difference string) bool {
+	if a.Rule != rule || !strings.Contains(difference, a.Match) {
+		return false
+	}
+	return a.Check == "" || checkName(strings.TrimPrefix(strings.TrimPrefix(difference, "Missing "), "Extra ")) == a.Check
+}
+
+// allowance returns the first allowance covering a difference, or nil
+func (p *ScorePolicy) allowance(rule, difference string) *Allowance {
+	for i := range p.Allow {
+		if p.Allow[i].matches(rule, difference) {
+			return &p.Allow[i]
+		}
+	}
+	return nil
+}
+
+// checkName returns the kind of check a check key or difference starts
+// with, e.g. "Check Dest Port" for "Check Dest Port (80) x2", or "" for
+// none
+func checkName(s string) string {
+	name := ""
+	for t := LoadEtherType; t <= Unknown; t++ {
+		n := t.String()
+		rest, ok := strings.CutPrefix(s, n)
+		if ok && len(n) > len(name) && (rest == "" || rest[0] == ' ' || rest[0] == ':') {
+			name = n
+		}
+	}
+	return name
+}
+
+// applyAllowances moves the differences the policy allows out of the
+// missing, extra and differing checks into Allowed, each with the reason
+// it is allowed
+func applyAllowances(result *ComparisonResult) {
+	lists := []struct {
+		rule string
+		list *[]string
+	}{
+		{RuleMissingCheck, &result.MissingInPrototype},
+		{RuleExtraCheck, &result.ExtraInPrototype},
+		{RuleCheckCount, &result.Differences},
+	}
+	for _, l := range lists {
+		kept := make([]string, 0, len(*l.list))
+		for _, d := range *l.list {
+			if a := result.Policy.allowance(l.rule, d); a != nil {
+				result.Allowed = append(result.Allowed, fmt.Sprintf("%s (allowed: %s)", d, a.Reason))
+				continue
+			}
+			kept = append(kept, d)
+		}
+		*l.list = kept
+	}
+}
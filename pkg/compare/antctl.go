@@ -0,0 +1,112 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output is a report format as antctl and kubectl take it with -o
+type Output string
+
+const (
+	OutputTable Output = "table" // aligned columns under upper-case headers
+	OutputJSON  Output = "json"  // an array of objects
+	OutputYAML  Output = "yaml"  // a sequence of mappings
+)
+
+// ParseOutput parses "table", "json" or "yaml"
+func ParseOutput(s string) (Output, error) {
+	switch Output(strings.ToLower(s)) {
+	case OutputTable:
+		return OutputTable, nil
+	case OutputJSON:
+		return OutputJSON, nil
+	case OutputYAML:
+		return OutputYAML, nil
+	}
+	return "", fmt.Errorf("invalid output '%s', must be table, json or yaml", s)
+}
+
+// Check is one comparison summarized the way antctl prints a resource:
+// a row of a table, or an object that scripts around antctl and kubectl
+// can read. Field names stay the same whatever the vocabulary.
+type Check struct {
+	Name          string    `json:"name" yaml:"name"`
+	Reference     string    `json:"reference" yaml:"reference"` // where the reference program came from, e.g. libpcap or a file
+	Filter        string    `json:"filter" yaml:"filter"`
+	Band          string    `json:"band,omitempty" yaml:"band,omitempty"` // e.g. excellent, partial, inconclusive
+	Score         float64   `json:"score" yaml:"score"`
+	Verdict       string    `json:"verdict,omitempty" yaml:"verdict,omitempty"`
+	Probes        int       `json:"probes" yaml:"probes"`
+	Disagreements int       `json:"disagreements" yaml:"disagreements"`
+	EditDistance  int       `json:"edit-distance" yaml:"edit-distance"` // instruction edits between the programs
+	Similarity    float64   `json:"similarity" yaml:"similarity"`       // 1 less the edits per instruction
+	Findings      []Finding `json:"findings,omitempty" yaml:"findings,omitempty"`
+	Explanation   []string  `json:"explanation,omitempty" yaml:"explanation,omitempty"` // summary, then the evidence behind it
+	Error         string    `json:"error,omitempty" yaml:"error,omitempty"`             // why there is no comparison
+}
+
+// Check summarizes the comparison under a name
+func (r *ComparisonResult) Check(name string) Check {
+	return Check{
+		Name:          name,
+		Reference:     r.TcpdumpBPF.Source,
+		Filter:        r.PrototypeBPF.FilterExpr,
+		Band:          r.band,
+		Score:         r.Score,
+		Verdict:       r.Verdict,
+		Probes:        r.Behavior.Packets,
+		Disagreements: r.Behavior.Disagreements,
+		EditDistance:  r.Distance.Operations,
+		Similarity:    r.Distance.Similarity,
+		Findings:      r.Findings(),
+		Explanation:   r.Explain(),
+	}
+}
+
+// WriteChecks writes the checks in the output format
+func WriteChecks(w io.Writer, output Output, checks []Check) error {
+	switch output {
+	case OutputJSON:
+		if checks == nil {
+			checks = []Check{}
+		}
+		data, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(checks)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tREFERENCE\tRESULT\tSCORE\tEDITS\tDIFFERING\tFINDINGS")
+	for _, c := range checks {
+		if c.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\terror: %s\t-\t-\t-\t-\n", c.Name, dash(c.Reference), c.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f\t%d\t%d/%d\t%d\n", c.Name, dash(c.Reference), c.Band, c.Score, c.EditDistance, c.Disagreements, c.Probes, len(c.Findings))
+	}
+	return tw.Flush()
+}
+
+// dash stands in for an empty table cell
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
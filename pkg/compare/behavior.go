@@ -0,0 +1,178 @@
+package compare
+
+import (
+	"encoding/binary"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// MaxProbes bounds the probe packets run through both programs
+const MaxProbes = 1024
+
+// Behavior is the outcome of running both programs over probe packets
+type Behavior struct {
+	Packets       int            // probe packets run through both programs
+	Disagreements int            // packets the programs decide differently
+	Examples      []Disagreement // the first few disagreements
+	Disagreeing   [][]byte       // every packet the programs decide differently
+}
+
+// Disagreement is a packet the programs decide differently
+type Disagreement struct {
+	Packet    []byte
+	Reference bool // verdict of the reference program
+	Prototype bool // verdict of the prototype program
+}
+
+// Agreement is the share of probe packets both programs decide alike, or
+// 1 when there were none
+func (b *Behavior) Agreement() float64 {
+	if b.Packets == 0 {
+		return 1
+	}
+	return float64(b.Packets-b.Disagreements) / float64(b.Packets)
+}
+
+// probe is a packet field a check tests and the constant it tests for
+type probe struct {
+	field field
+	value uint32
+}
+
+// compareBehavior runs both programs over packets built to exercise the
+// checks either of them makes. A program that fails on a packet rejects
+// it, as the kernel would have refused to load it.
+func compareBehavior(result *ComparisonResult) *Behavior {
+	link := filter.LinkType(result.TcpdumpBPF.LinkType)
+	packets := probePackets(append(append([]*SemanticInstruction{}, result.TcpdumpSemantic...), result.PrototypeSemantic...), link)
+
+	b := &Behavior{Packets: len(packets)}
+	for _, pkt := range packets {
+		reference := accepts(result.TcpdumpBPF.Instructions, pkt)
+		prototype := accepts(result.PrototypeBPF.Instructions, pkt)
+		if reference == prototype {
+			continue
+		}
+		b.Disagreements++
+		b.Disagreeing = append(b.Disagreeing, pkt)
+		if len(b.Examples) < 3 {
+			b.Examples = append(b.Examples, Disagreement{Packet: pkt, Reference: reference, Prototype: prototype})
+		}
+	}
+	return b
+}
+
+// accepts runs a program over a packet
+func accepts(prog []*bpf.Instruction, pkt []byte) bool {
+	r, err := vm.Run(prog, pkt)
+	return err == nil && r.Accepted
+}
+
+// probeBases are the packets probes start from: each transport, a non-IP
+// frame, a tagged frame, a later fragment and both tunnels
+var probeBases = []packet.Spec{
+	{Protocol: "tcp", SrcPort: 1024, DstPort: 80},
+	{Protocol: "udp", SrcPort: 1024, DstPort: 53},
+	{Protocol: "icmp"},
+	{Protocol: "132", SrcPort: 1024, DstPort: 80},
+	{Protocol: "arp"},
+	{Protocol: "tcp", SrcPort: 1024, DstPort: 80, VLAN: true, VLANID: 100},
+	{Protocol: "tcp", SrcPort: 1024, DstPort: 80, FragOff: 100},
+	{Protocol: "udp", Tunnel: string(filter.TunnelVXLAN), VNI: 100, Inner: &packet.Spec{Protocol: "tcp", DstPort: 80}},
+	{Protocol: "udp", Tunnel: string(filter.TunnelGeneve), VNI: 100, Inner: &packet.Spec{Protocol: "tcp", DstPort: 80}},
+}
+
+// probePackets builds packets for the link type from the base packets.
+// Each base gets every checked field set to the first constant tested
+// for it, then variants that set one field to each constant tested for it
+// and to the values either side, so that both branches of every check and
+// each alternative of a list are taken. Truncated copies exercise loads
+// past the end of the packet. The packets depend only on the checks, in
+// order, so a comparison always probes the same packets.
+func probePackets(semantics []*SemanticInstruction, link filter.LinkType) [][]byte {
+	var probes []probe
+	first := make(map[field]uint32)
+	var fields []field
+	for _, sem := range semantics {
+		if sem.probe == nil {
+			continue
+		}
+		probes = append(probes, *sem.probe)
+		key := sem.probe.field
+		key.mask = 0xffffffff
+		if _, ok := first[key]; !ok {
+			first[key] = sem.probe.value
+			fields = append(fields, sem.probe.field)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var packets [][]byte
+	add := func(pkt []byte) {
+		if len(packets) < MaxProbes && !seen[string(pkt)] {
+			seen[string(pkt)] = true
+			packets = append(packets, pkt)
+		}
+	}
+
+	for _, spec := range probeBases {
+		base, err := spec.BuildFor(link)
+		if err != nil {
+			// Not every base exists on every link type
+			continue
+		}
+		add(base)
+
+		matched := append([]byte{}, base...)
+		for _, f := range fields {
+			key := f
+			key.mask = 0xffffffff
+			setField(matched, f, first[key])
+		}
+		add(matched)
+		add(matched[:len(matched)/2])
+
+		for _, p := range probes {
+			for _, v := range []uint32{p.value, p.value + 1, p.value - 1, 0} {
+				variant := append([]byte{}, matched...)
+				if setField(variant, p.field, v) {
+					add(variant)
+				}
+			}
+		}
+	}
+	return packets
+}
+
+// setField stores the masked bits of value in the packet field, returning
+// false if the field is not inside the packet. Transport fields are found
+// through the IP header length, as the program finds them.
+func setField(pkt []byte, f field, value uint32) bool {
+	addr := uint64(f.offset)
+	if f.mode == bpf.ModeIND {
+		if int(f.ip) >= len(pkt) {
+			return false
+		}
+		addr += uint64(f.ip) + 4*uint64(pkt[f.ip]&0xf)
+	}
+	if addr+uint64(f.size) > uint64(len(pkt)) {
+		return false
+	}
+	b := pkt[addr : addr+uint64(f.size)]
+	switch f.size {
+	case 1:
+		b[0] = b[0]&^byte(f.mask) | byte(value&f.mask)
+	case 2:
+		old := binary.BigEndian.Uint16(b)
+		binary.BigEndian.PutUint16(b, old&^uint16(f.mask)|uint16(value&f.mask))
+	case 4:
+		old := binary.BigEndian.Uint32(b)
+		binary.BigEndian.PutUint32(b, old&^f.mask|value&f.mask)
+	default:
+		return false
+	}
+	return true
+}
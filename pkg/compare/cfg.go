@@ -0,0 +1,198 @@
+package compare
+
+import (
+	"sort"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// field is what the accumulator holds: a packet load, possibly masked by
+// an "and". A zero size means the value is not a plain packet field.
+type field struct {
+	mode   uint16 // bpf.ModeABS, or bpf.ModeIND for offsets into the transport header
+	size   int    // 1, 2 or 4 bytes; 0 when unknown
+	offset uint32 // packet offset, or transport header offset for ModeIND
+	mask   uint32 // bits kept by "and" instructions
+	ip     uint32 // offset of the IP header for ModeIND, whose length X held
+}
+
+// state is what is known about the registers and the packet at a point of
+// the program
+type state struct {
+	a      field
+	xIP    uint32 // offset of the IP header whose length X holds
+	xKnown bool   // X was loaded with ldxb 4*([xIP]&0xf)
+	family int    // IP version established by the path so far (0 if unknown)
+
+	// Scratch memory, so that a field or header length stored and loaded
+	// back is still recognized, as in unoptimized libpcap programs
+	mem [bpf.MemWords]slot
+}
+
+// slot is what a scratch memory word holds
+type slot struct {
+	a      field  // field stored from A
+	xIP    uint32 // offset of the IP header whose length was stored from X
+	xKnown bool
+}
+
+// block is a basic block: instructions entered only at the first and left
+// only after the last
+type block struct {
+	start, end int   // instruction range [start, end)
+	succs      []int // successor blocks, the true branch first
+	preds      []int // predecessor blocks
+	in         state // state on entry, agreed on by every predecessor
+	out        state // state on exit
+	family     int   // IP version the final jump establishes on its true branch
+}
+
+// cfg is the control flow graph of a classic BPF program. Jumps only go
+// forward, so the blocks in instruction order are a topological order.
+type cfg struct {
+	blocks []*block
+}
+
+// buildCFG splits a program into basic blocks and links them by their
+// jump edges
+func buildCFG(instructions []*bpf.Instruction) *cfg {
+	n := len(instructions)
+	leaders := map[int]bool{0: true}
+	for pc, inst := range instructions {
+		for _, target := range jumpTargets(inst, pc) {
+			if target < n {
+				leaders[target] = true
+			}
+		}
+		if (inst.IsJump() || inst.IsReturn()) && pc+1 < n {
+			leaders[pc+1] = true
+		}
+	}
+
+	starts := make([]int, 0, len(leaders))
+	for pc := range leaders {
+		if pc < n {
+			starts = append(starts, pc)
+		}
+	}
+	sort.Ints(starts)
+
+	g := &cfg{}
+	blockAt := make(map[int]int)
+	for i, start := range starts {
+		end := n
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		blockAt[start] = i
+		g.blocks = append(g.blocks, &block{start: start, end: end})
+	}
+
+	for i, b := range g.blocks {
+		last := b.end - 1
+		targets := jumpTargets(instructions[last], last)
+		if !instructions[last].IsJump() && !instructions[last].IsReturn() {
+			targets = []int{b.end}
+		}
+		for _, target := range targets {
+			succ, ok := blockAt[target]
+			if !ok || (len(b.succs) > 0 && b.succs[len(b.succs)-1] == succ) {
+				continue
+			}
+			b.succs = append(b.succs, succ)
+			g.blocks[succ].preds = append(g.blocks[succ].preds, i)
+		}
+	}
+	return g
+}
+
+// jumpTargets returns the instructions a jump at pc can continue at, the
+// true branch first, or nil for any other instruction
+func jumpTargets(inst *bpf.Instruction, pc int) []int {
+	if !inst.IsJump() {
+		return nil
+	}
+	if inst.Code&0xf0 == bpf.JmpJA {
+		return []int{pc + 1 + int(inst.K)}
+	}
+	return []int{pc + 1 + int(inst.JT), pc + 1 + int(inst.JF)}
+}
+
+// isConditional reports whether the instruction is a two-way jump
+func isConditional(inst *bpf.Instruction) bool {
+	return inst.IsJump() && inst.Code&0xf0 != bpf.JmpJA
+}
+
+// step applies one instruction to the state
+func step(st state, inst *bpf.Instruction) state {
+	switch inst.Class() {
+	case bpf.ClassLD:
+		mode := inst.Code & 0xe0
+		size := map[uint16]int{bpf.SizeW: 4, bpf.SizeH: 2, bpf.SizeB: 1}[inst.Code&0x18]
+		st.a = field{}
+		switch {
+		case mode == bpf.ModeMEM && inst.K < bpf.MemWords:
+			st.a = st.mem[inst.K].a
+		case mode == bpf.ModeABS:
+			st.a = field{mode: mode, size: size, offset: inst.K, mask: 0xffffffff}
+		case mode == bpf.ModeIND && st.xKnown:
+			// X is the IP header length, so the load reads the transport
+			// header at K minus the IP header offset
+			st.a = field{mode: mode, size: size, offset: inst.K - st.xIP, mask: 0xffffffff, ip: st.xIP}
+		}
+	case bpf.ClassLDX:
+		if inst.Code&0xe0 == bpf.ModeMEM && inst.K < bpf.MemWords {
+			st.xIP, st.xKnown = st.mem[inst.K].xIP, st.mem[inst.K].xKnown
+			break
+		}
+		st.xKnown = inst.Code == bpf.OpLdxMSH
+		st.xIP = inst.K
+	case bpf.ClassST:
+		if inst.K < bpf.MemWords {
+			st.mem[inst.K] = slot{a: st.a}
+		}
+	case bpf.ClassSTX:
+		if inst.K < bpf.MemWords {
+			st.mem[inst.K] = slot{xIP: st.xIP, xKnown: st.xKnown}
+		}
+	case bpf.ClassALU:
+		if inst.Code == bpf.ClassALU|bpf.ALUAnd|bpf.SrcK && st.a.size != 0 {
+			st.a.mask &= inst.K
+		} else {
+			st.a = field{}
+		}
+	case bpf.ClassMISC:
+		if inst.Code&0xf8 == bpf.MiscTAX {
+			st.xKnown = false
+		} else {
+			st.a = field{}
+		}
+	}
+	return st
+}
+
+// meet combines the states arriving over several edges, keeping only what
+// they agree on
+func meet(states []state) state {
+	if len(states) == 0 {
+		return state{}
+	}
+	m := states[0]
+	for _, s := range states[1:] {
+		if s.a != m.a {
+			m.a = field{}
+		}
+		if s.xKnown != m.xKnown || s.xIP != m.xIP {
+			m.xKnown = false
+		}
+		if s.family != m.family {
+			m.family = 0
+		}
+		for i := range m.mem {
+			if s.mem[i] != m.mem[i] {
+				m.mem[i] = slot{}
+			}
+		}
+	}
+	return m
+}
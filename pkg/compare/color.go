@@ -0,0 +1,69 @@
+package compare
+
+import (
+	"io"
+)
+
+// palette colors report text with ANSI escapes, or leaves it alone when
+// false
+type palette bool
+
+// ANSI colors used in reports
+const (
+	ansiGreen  = "32"
+	ansiYellow = "33"
+	ansiRed    = "31"
+	ansiBold   = "1"
+)
+
+// paint wraps text in the color codes
+func (p palette) paint(text string, codes ...string) string {
+	if !p || text == "" {
+		return text
+	}
+	seq := "\x1b["
+	for i, c := range codes {
+		if i > 0 {
+			seq += ";"
+		}
+		seq += c
+	}
+	return seq + "m" + text + "\x1b[0m"
+}
+
+// band colors text by a verdict band: green when excellent, yellow when
+// good and red otherwise
+func (p palette) band(text, band string) string {
+	switch band {
+	case bandExcellent:
+		return p.paint(text, ansiGreen)
+	case bandGood:
+		return p.paint(text, ansiYellow)
+	}
+	return p.paint(text, ansiRed)
+}
+
+// RenderColor writes the report as Render does, with ANSI colors for
+// terminals: matching checks and an excellent verdict in green, differences
+// in yellow, and missing checks, disagreeing probe packets and poor or
+// critical verdicts in red
+func (r *ComparisonResult) RenderColor(w io.Writer) error {
+	return r.writeReport(w, true)
+}
+
+// paint colors a key difference by its priority: disagreeing packets,
+// critical and missing checks in red, enhancements in green and structural
+// differences in yellow
+func (d Difference) paint(text string, p palette) string {
+	switch d.Priority {
+	case 0, 1:
+		return p.paint(text, ansiBold, ansiRed)
+	case 3:
+		return p.paint(text, ansiRed)
+	case 2:
+		return p.paint(text, ansiGreen)
+	case 5:
+		return p.paint(text, ansiYellow)
+	}
+	return text
+}
@@ -0,0 +1,1305 @@
+// Package compare scores a generated program against the tcpdump reference
+// by the semantic role of each instruction. Comparison does not print;
+// Render formats the report for any io.Writer.
+package compare
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+)
+
+// InstructionType represents the semantic purpose of a BPF instruction
+type InstructionType int
+
+const (
+	LoadEtherType InstructionType = iota
+	CheckIP
+	LoadProtocol
+	CheckProtocol
+	LoadSourceIP
+	CheckSourceIP
+	LoadDestIP
+	CheckDestIP
+	LoadFragmentInfo
+	CheckFragment
+	LoadHeaderLength
+	LoadSourcePort
+	LoadDestPort
+	CheckSourcePort
+	CheckDestPort
+	Accept
+	Reject
+	CheckVLAN
+	LoadVLANID
+	CheckVLANID
+	CheckEtherType
+	CheckField
+	LoadField
+	LoadConstant
+	LoadLength
+	LoadMemory
+	Store
+	Arithmetic
+	RegisterTransfer
+	Jump
+	Unknown
+)
+
+// String returns a human-readable name for the instruction type
+func (it InstructionType) String() string {
+	names := []string{
+		"Load Ethernet Type", "Check IP Protocol", "Load IP Protocol", "Check Protocol",
+		"Load Source IP", "Check Source IP", "Load Dest IP", "Check Dest IP",
+		"Load Fragment Info", "Check Fragment", "Load Header Length",
+		"Load Source Port", "Load Dest Port", "Check Source Port", "Check Dest Port",
+		"Accept Packet", "Reject Packet", "Check VLAN Tag", "Load VLAN ID", "Check VLAN ID",
+		"Check Ethernet Type", "Check Field", "Load Field", "Load Constant",
+		"Load Packet Length", "Load Scratch Memory", "Store", "Arithmetic",
+		"Register Transfer", "Jump", "Unknown",
+	}
+	if int(it) < len(names) {
+		return names[it]
+	}
+	return "Unknown"
+}
+
+// SemanticInstruction represents the semantic meaning of a BPF instruction
+type SemanticInstruction struct {
+	Type        InstructionType
+	Value       uint32 // The constant value being checked/loaded
+	Description string // Human-readable description
+	Index       int    // Original instruction index
+	Predicate   string // For a check, the value tested for, e.g. "80" or "10.0.0.0/8"
+	Field       string // For a check, the header field tested, e.g. "destination port"
+
+	probe *probe // for a check of a packet field, the field and constant
+}
+
+// ComparisonResult represents the result of comparing two BPF programs
+type ComparisonResult struct {
+	TcpdumpBPF         *tcpdump.BPFCode
+	PrototypeBPF       *bpfgen.BPFCode
+	TcpdumpSemantic    []*SemanticInstruction
+	PrototypeSemantic  []*SemanticInstruction
+	Matches            []string
+	Differences        []string
+	MissingInPrototype []string
+	ExtraInPrototype   []string
+	StructuralDiffs    []string
+	OffsetMismatches   []string // checks of the same value at another field
+	VerifierFailures   []string // why the kernel would refuse to load either program
+	Allowed            []string // differences the policy allows, with the reason, left out of the score
+	Verdict            string
+	Score              float64         // 0.0 to 1.0, higher is better match
+	Components         ScoreComponents // parts the score is weighed from
+	Behavior           *Behavior       // verdicts of both programs on probe packets
+	Distance           EditDistance    // instruction edits between the programs
+	Vocabulary         *Vocabulary     // verdict and report wording
+	Policy             *ScorePolicy    // weights and bands the score and verdict follow
+
+	band string // verdict band, after any cap for disagreements
+
+	explained map[string]bool // missing and extra entries an offset mismatch accounts for
+
+	explanation []string // Explain's lines, worked out on first use
+}
+
+// Options customizes a comparison
+type Options struct {
+	Vocabulary *Vocabulary  // wording for verdicts and reports (nil means English defaults)
+	Policy     *ScorePolicy // score weights and verdict bands (nil means DefaultScorePolicy)
+}
+
+// Compare analyzes differences between tcpdump and prototype BPF
+func Compare(tcpBPF *tcpdump.BPFCode, protoBPF *bpfgen.BPFCode) *ComparisonResult {
+	return CompareWithOptions(tcpBPF, protoBPF, Options{})
+}
+
+// CompareWithOptions analyzes differences using the given options
+func CompareWithOptions(tcpBPF *tcpdump.BPFCode, protoBPF *bpfgen.BPFCode, opts Options) *ComparisonResult {
+	vocab := opts.Vocabulary
+	if vocab == nil {
+		vocab = DefaultVocabulary()
+	}
+	policy := opts.Policy
+	if policy == nil {
+		policy = DefaultScorePolicy()
+	}
+
+	result := &ComparisonResult{
+		Vocabulary:         vocab,
+		Policy:             policy,
+		TcpdumpBPF:         tcpBPF,
+		PrototypeBPF:       protoBPF,
+		Matches:            make([]string, 0),
+		Differences:        make([]string, 0),
+		MissingInPrototype: make([]string, 0),
+		ExtraInPrototype:   make([]string, 0),
+		StructuralDiffs:    make([]string, 0),
+		OffsetMismatches:   make([]string, 0),
+		VerifierFailures:   make([]string, 0),
+		Allowed:            make([]string, 0),
+		explained:          make(map[string]bool),
+	}
+
+	// Analyze semantic meaning of both programs
+	result.TcpdumpSemantic = analyzeSemantics(tcpBPF.Instructions, filter.LinkType(tcpBPF.LinkType))
+	result.PrototypeSemantic = analyzeSemantics(protoBPF.Instructions, filter.LinkType(protoBPF.LinkType))
+
+	// Compare semantic structures
+	compareSemantics(result)
+	verifyPrograms(result)
+
+	// Compare what both programs decide
+	result.Behavior = compareBehavior(result)
+	result.Distance = editDistance(tcpBPF.Instructions, protoBPF.Instructions)
+
+	// Calculate overall score and verdict
+	calculateVerdict(result)
+
+	logging.Logger().Debug(render(vocab.Labels.ComparisonResult, result.reportData()), "score", result.Score)
+	return result
+}
+
+// layout describes where a program finds the headers it loads
+type layout struct {
+	link   filter.LinkType
+	shift  uint32 // distance of the IP header from its untagged Ethernet offset
+	tagged bool   // an 802.1Q tag check has been seen
+	ipv4   uint32 // link-layer protocol value of IPv4 packets
+}
+
+// newLayout returns the layout of untagged packets of the link type. The
+// shift wraps around for link headers shorter than Ethernet's, which the
+// unsigned offset arithmetic undoes.
+func newLayout(link filter.LinkType) layout {
+	l := layout{
+		link:  link,
+		shift: uint32(link.HeaderLen() - 14),
+		ipv4:  0x00000800,
+	}
+	switch link {
+	case filter.LinkRaw:
+		l.ipv4 = 0x40
+	case filter.LinkNull:
+		l.ipv4 = filter.NullIPv4
+	}
+	return l
+}
+
+// checkTypes maps the type of a load to the type of a comparison of the
+// loaded field
+var checkTypes = map[InstructionType]InstructionType{
+	LoadEtherType:    CheckIP,
+	LoadProtocol:     CheckProtocol,
+	LoadSourceIP:     CheckSourceIP,
+	LoadDestIP:       CheckDestIP,
+	LoadFragmentInfo: CheckFragment,
+	LoadSourcePort:   CheckSourcePort,
+	LoadDestPort:     CheckDestPort,
+	LoadVLANID:       CheckVLANID,
+}
+
+// analyzeSemantics converts BPF instructions to semantic meaning. The
+// program is walked block by block along its control flow graph while
+// tracking which header field the accumulator holds, including fields
+// saved to and loaded back from scratch memory, so a comparison is
+// classified by the field it tests and not by its constant alone. A check
+// of the IP version also fixes the header layout on its true branch.
+func analyzeSemantics(instructions []*bpf.Instruction, link filter.LinkType) []*SemanticInstruction {
+	semantics := make([]*SemanticInstruction, 0, len(instructions))
+	g := buildCFG(instructions)
+
+	// After an 802.1Q tag check, header fields sit 4 bytes further on
+	lay := newLayout(link)
+
+	for i, b := range g.blocks {
+		incoming := make([]state, 0, len(b.preds))
+		for _, p := range b.preds {
+			pred := g.blocks[p]
+			s := pred.out
+			if pred.family != 0 && len(pred.succs) == 2 && pred.succs[0] == i {
+				s.family = pred.family
+			}
+			incoming = append(incoming, s)
+		}
+		b.in = meet(incoming)
+
+		st := b.in
+		for pc := b.start; pc < b.end; pc++ {
+			inst := instructions[pc]
+			if !isConditional(inst) {
+				st = step(st, inst)
+				semantics = append(semantics, analyzeInstruction(inst, pc, st, lay))
+				continue
+			}
+
+			semantic, family := analyzeCheck(inst, pc, st, lay)
+			switch {
+			case semantic.Type == CheckVLAN && !lay.tagged:
+				lay.shift += 4
+				lay.tagged = true
+			case semantic.Type == CheckIP && inst.Code&0xf0 == bpf.JmpJEQ:
+				b.family = family
+			}
+			semantics = append(semantics, semantic)
+		}
+		b.out = st
+	}
+
+	return semantics
+}
+
+// fieldRole names the header field a load reads, given the IP version
+// the path has established (IPv4 is assumed when it is unknown). It
+// returns Unknown for anything else.
+func fieldRole(f field, family int, lay layout) (InstructionType, string) {
+	if f.size == 0 {
+		return Unknown, ""
+	}
+
+	// Transport header, addressed past the IP header length in X
+	if f.mode == bpf.ModeIND {
+		switch {
+		case f.size == 2 && f.offset == 0:
+			return LoadSourcePort, "source port"
+		case f.size == 2 && f.offset == 2:
+			return LoadDestPort, "destination port"
+		}
+		return Unknown, ""
+	}
+
+	// Link layer
+	switch lay.link {
+	case filter.LinkRaw:
+		if f.size == 1 && f.offset == 0 {
+			return LoadEtherType, "IP version"
+		}
+	case filter.LinkNull:
+		if f.size == 4 && f.offset == 0 {
+			return LoadEtherType, "address family"
+		}
+	default:
+		if lay.tagged && f.size == 2 && f.offset == 14 {
+			return LoadVLANID, "VLAN tag control information"
+		}
+		if f.size == 2 && (f.offset-lay.shift == 12 || (lay.link.IsEthernet() && f.offset == 12)) {
+			return LoadEtherType, "Ethernet type field"
+		}
+	}
+
+	// IP header, by offset from its start
+	rel := f.offset - lay.shift - 14
+	if family == 6 {
+		switch {
+		case f.size == 1 && rel == 6:
+			return LoadProtocol, "IPv6 next header"
+		case f.size == 4 && rel >= 8 && rel < 24 && rel%4 == 0:
+			return LoadSourceIP, fmt.Sprintf("source IPv6 address word %d", (rel-8)/4)
+		case f.size == 4 && rel >= 24 && rel < 40 && rel%4 == 0:
+			return LoadDestIP, fmt.Sprintf("destination IPv6 address word %d", (rel-24)/4)
+		case f.size == 2 && rel == 40:
+			return LoadSourcePort, "source port"
+		case f.size == 2 && rel == 42:
+			return LoadDestPort, "destination port"
+		}
+		return Unknown, ""
+	}
+	switch {
+	case f.size == 1 && rel == 9:
+		return LoadProtocol, "IP protocol field"
+	case f.size == 2 && rel == 6:
+		return LoadFragmentInfo, "IP fragment information"
+	case f.size == 4 && rel == 12:
+		return LoadSourceIP, "source IP address"
+	case f.size == 4 && rel == 16:
+		return LoadDestIP, "destination IP address"
+	}
+	return Unknown, ""
+}
+
+// analyzeInstruction analyzes an instruction other than a conditional
+// jump, given the state after it. Instructions that only compute, move or
+// save values get a type of their own rather than Unknown, which is left
+// for opcodes that are not valid classic BPF.
+func analyzeInstruction(inst *bpf.Instruction, index int, st state, lay layout) *SemanticInstruction {
+	semantic := &SemanticInstruction{
+		Index: index,
+		Value: inst.K,
+	}
+	mode := inst.Code & 0xe0
+
+	switch {
+	case inst.Class() == bpf.ClassLD && mode == bpf.ModeIMM:
+		semantic.Type = LoadConstant
+		semantic.Description = fmt.Sprintf("Load constant %d into A", inst.K)
+
+	case inst.Class() == bpf.ClassLD && mode == bpf.ModeLEN:
+		semantic.Type = LoadLength
+		semantic.Description = "Load packet length into A"
+
+	case inst.Class() == bpf.ClassLD:
+		semantic.Type, semantic.Description = fieldRole(st.a, st.family, lay)
+		switch {
+		case semantic.Type != Unknown:
+			semantic.Description = "Load " + semantic.Description
+		case mode == bpf.ModeMEM:
+			semantic.Type = LoadMemory
+			semantic.Description = fmt.Sprintf("Load from scratch memory M[%d]", inst.K)
+		case st.a.size != 0:
+			semantic.Type = LoadField
+			semantic.Description = "Load " + fieldText(st.a)
+		case mode == bpf.ModeABS || mode == bpf.ModeIND:
+			// An indexed load whose X is not a known header length
+			semantic.Type = LoadField
+			semantic.Description = fmt.Sprintf("Load from offset 0x%x", inst.K)
+		default:
+			semantic.Type = Unknown
+			semantic.Description = fmt.Sprintf("Unknown instruction: 0x%04x", inst.Code)
+		}
+
+	case inst.Code == bpf.OpLdxMSH:
+		semantic.Type = LoadHeaderLength
+		semantic.Description = "Load IP header length into index register"
+
+	case inst.Class() == bpf.ClassLDX && mode == bpf.ModeIMM:
+		semantic.Type = LoadConstant
+		semantic.Description = fmt.Sprintf("Load constant %d into X", inst.K)
+
+	case inst.Class() == bpf.ClassLDX && mode == bpf.ModeLEN:
+		semantic.Type = LoadLength
+		semantic.Description = "Load packet length into X"
+
+	case inst.Class() == bpf.ClassLDX && mode == bpf.ModeMEM:
+		semantic.Type = LoadMemory
+		if st.xKnown {
+			semantic.Type = LoadHeaderLength
+		}
+		semantic.Description = fmt.Sprintf("Load from scratch memory M[%d] into X", inst.K)
+
+	case inst.Class() == bpf.ClassST || inst.Class() == bpf.ClassSTX:
+		// Stores are not checks; the field they save is followed through
+		// scratch memory to the load that reads it back
+		register := "A"
+		if inst.Class() == bpf.ClassSTX {
+			register = "X"
+		}
+		semantic.Type = Store
+		semantic.Description = fmt.Sprintf("Store %s into scratch memory M[%d]", register, inst.K)
+
+	case inst.Class() == bpf.ClassALU:
+		semantic.Type = Arithmetic
+		semantic.Description = aluText(inst)
+		if semantic.Description == "" {
+			semantic.Type = Unknown
+			semantic.Description = fmt.Sprintf("Unknown instruction: 0x%04x", inst.Code)
+		}
+
+	case inst.Code == bpf.ClassMISC|bpf.MiscTAX:
+		semantic.Type = RegisterTransfer
+		semantic.Description = "Copy A into X"
+
+	case inst.Code == bpf.ClassMISC|bpf.MiscTXA:
+		semantic.Type = RegisterTransfer
+		semantic.Description = "Copy X into A"
+
+	case inst.Code == bpf.OpJA:
+		semantic.Type = Jump
+		semantic.Description = fmt.Sprintf("Jump to instruction %d", index+1+int(inst.K))
+
+	case inst.IsReturn():
+		switch {
+		case inst.Code&0x18 == bpf.RetA:
+			semantic.Type = Accept
+			semantic.Description = "Accept packet (return A bytes)"
+		case inst.Code != bpf.OpRetK || inst.K > 0:
+			semantic.Type = Accept
+			semantic.Description = fmt.Sprintf("Accept packet (return %d bytes)", inst.K)
+		default:
+			semantic.Type = Reject
+			semantic.Description = "Reject packet (return 0)"
+		}
+
+	default:
+		semantic.Type = Unknown
+		semantic.Description = fmt.Sprintf("Unknown instruction: 0x%04x", inst.Code)
+	}
+
+	return semantic
+}
+
+// aluSymbols spells out each ALU operation as an assignment operator
+var aluSymbols = map[uint16]string{
+	bpf.ALUAdd: "+=",
+	bpf.ALUSub: "-=",
+	bpf.ALUMul: "*=",
+	bpf.ALUDiv: "/=",
+	bpf.ALUOr:  "|=",
+	bpf.ALUAnd: "&=",
+	bpf.ALULsh: "<<=",
+	bpf.ALURsh: ">>=",
+	bpf.ALUMod: "%=",
+	bpf.ALUXor: "^=",
+}
+
+// aluText describes an ALU instruction, e.g. "A &= 0x1fff", or returns ""
+// for an invalid operation
+func aluText(inst *bpf.Instruction) string {
+	op := inst.Code & 0xf0
+	if op == bpf.ALUNeg {
+		return "A = -A"
+	}
+	symbol, ok := aluSymbols[op]
+	if !ok {
+		return ""
+	}
+	if inst.Code&bpf.SrcX != 0 {
+		return fmt.Sprintf("A %s X", symbol)
+	}
+	return fmt.Sprintf("A %s 0x%x", symbol, inst.K)
+}
+
+// analyzeCheck classifies a conditional jump by the field the accumulator
+// holds and the constant it is compared with. For an IP version check it
+// also returns the version (4 or 6) that holds on the true branch.
+func analyzeCheck(inst *bpf.Instruction, index int, st state, lay layout) (*SemanticInstruction, int) {
+	semantic := &SemanticInstruction{
+		Index: index,
+		Value: inst.K,
+	}
+	op := inst.Code & 0xf0
+	k := inst.K
+
+	loadType, name := fieldRole(st.a, st.family, lay)
+	if inst.Code&bpf.SrcX != 0 {
+		loadType = Unknown
+	}
+	family := 0
+	value := fmt.Sprintf("%d", k)
+	if inst.Code&bpf.SrcX == 0 && st.a.size != 0 {
+		semantic.probe = &probe{field: st.a, value: k}
+	}
+
+	switch {
+	case loadType == LoadEtherType && lay.link.IsEthernet() && st.a.offset == 12 && op == bpf.JmpJEQ &&
+		(k == 0x00008100 || k == 0x000088a8 || k == 0x00009100):
+		semantic.Type = CheckVLAN
+		name = "VLAN tag"
+		value = fmt.Sprintf("TPID 0x%x", k)
+	case loadType == LoadEtherType:
+		family = ipVersion(k, st.a, lay)
+		if family == 0 && op == bpf.JmpJEQ && lay.link != filter.LinkRaw && lay.link != filter.LinkNull {
+			// ARP and other protocols without IP headers
+			semantic.Type = CheckEtherType
+			value = etherTypeName(k)
+			break
+		}
+		if family == 0 {
+			loadType = Unknown
+			break
+		}
+		semantic.Type = CheckIP
+		value = fmt.Sprintf("IPv%d", family)
+	case loadType == LoadProtocol:
+		semantic.Type = CheckProtocol
+		value = protocolName(k)
+	case (loadType == LoadSourceIP || loadType == LoadDestIP) && st.family != 6:
+		semantic.Type = checkTypes[loadType]
+		value = addressText(k, st.a.mask)
+	case loadType == LoadFragmentInfo && op == bpf.JmpJSET:
+		semantic.Type = CheckFragment
+		value = fmt.Sprintf("offset mask 0x%x", k)
+	case loadType != Unknown:
+		semantic.Type = checkTypes[loadType]
+		if loadType == LoadSourceIP || loadType == LoadDestIP {
+			value = fmt.Sprintf("0x%08x", k)
+		}
+	}
+
+	if loadType == Unknown {
+		operand := fmt.Sprintf("0x%x", k)
+		if inst.Code&bpf.SrcX != 0 {
+			operand = "X"
+		}
+		semantic.Type = CheckField
+		semantic.Field = fieldText(st.a)
+		semantic.Predicate = fmt.Sprintf("%s %s %s", fieldText(st.a), opSymbols[op], operand)
+		semantic.Description = "Check " + semantic.Predicate
+		if _, ok := opSymbols[op]; !ok {
+			semantic.Type = Unknown
+			semantic.Predicate = ""
+			semantic.Description = fmt.Sprintf("Unknown instruction: 0x%04x", inst.Code)
+		}
+		return semantic, 0
+	}
+
+	// Only equality is implied by the value alone
+	if op != bpf.JmpJEQ && semantic.Type != CheckFragment {
+		value = fmt.Sprintf("%s %s", opSymbols[op], value)
+	}
+	semantic.Predicate = value
+	semantic.Field = name
+	semantic.Description = fmt.Sprintf("Check %s: %s", name, value)
+	return semantic, family
+}
+
+// opSymbols spells out the comparison of each conditional jump
+var opSymbols = map[uint16]string{
+	bpf.JmpJEQ:  "==",
+	bpf.JmpJGT:  ">",
+	bpf.JmpJGE:  ">=",
+	bpf.JmpJSET: "&",
+}
+
+// ipVersion returns the IP version a link-layer protocol value selects,
+// or 0 for anything but IP
+func ipVersion(k uint32, f field, lay layout) int {
+	switch lay.link {
+	case filter.LinkRaw:
+		if f.mask&0xf0 != 0xf0 {
+			return 0
+		}
+		return map[uint32]int{0x40: 4, 0x60: 6}[k]
+	case filter.LinkNull:
+		if k == filter.NullIPv4 {
+			return 4
+		}
+		// AF_INET6 differs between BSDs and is in host byte order
+		for _, family := range []uint32{24, 28, 30} {
+			if k == binary.BigEndian.Uint32(binary.NativeEndian.AppendUint32(nil, family)) {
+				return 6
+			}
+		}
+		return 0
+	}
+	return map[uint32]int{0x0800: 4, 0x86dd: 6}[k]
+}
+
+// etherTypeName names an EtherType that is not IP
+func etherTypeName(k uint32) string {
+	if name, ok := map[uint32]string{0x0806: "arp", 0x8035: "rarp"}[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", k)
+}
+
+// protocolName names an IP protocol number
+func protocolName(k uint32) string {
+	names := map[uint32]string{1: "icmp", 6: "tcp", 17: "udp", 44: "ipv6-frag", 58: "icmp6", 132: "sctp"}
+	if name, ok := names[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("protocol %d", k)
+}
+
+// addressText formats a compared IPv4 address, as a CIDR when masked
+func addressText(k, mask uint32) string {
+	ip := net.IPv4(byte(k>>24), byte(k>>16), byte(k>>8), byte(k))
+	if mask == 0xffffffff {
+		return ip.String()
+	}
+	ipnet := net.IPNet{IP: ip.To4(), Mask: net.IPv4Mask(byte(mask>>24), byte(mask>>16), byte(mask>>8), byte(mask))}
+	return ipnet.String()
+}
+
+// fieldText describes an unclassified accumulator value in pcap-filter
+// terms, e.g. "ether[30:4] & 0xffff"
+func fieldText(f field) string {
+	var text string
+	switch {
+	case f.size == 0:
+		return "A"
+	case f.mode == bpf.ModeIND:
+		text = fmt.Sprintf("transport[%d:%d]", f.offset, f.size)
+	default:
+		text = fmt.Sprintf("link[%d:%d]", f.offset, f.size)
+	}
+	if f.mask != 0xffffffff {
+		text += fmt.Sprintf(" & 0x%x", f.mask)
+	}
+	return text
+}
+
+// checkKey identifies a check or return across programs, so that blocks
+// are matched by what they test rather than where they are
+func checkKey(sem *SemanticInstruction) (string, bool) {
+	switch {
+	case sem.Predicate != "":
+		return fmt.Sprintf("%s (%s)", sem.Type, sem.Predicate), true
+	case sem.Type == Accept || sem.Type == Reject:
+		return sem.Type.String(), true
+	}
+	return "", false
+}
+
+// counted appends the number of blocks to a check key when there are
+// several
+func counted(key string, n int) string {
+	if n == 1 {
+		return key
+	}
+	return fmt.Sprintf("%s x%d", key, n)
+}
+
+// compareSemantics matches the checks of both programs by predicate, the
+// header field a block tests and the value it tests for, and the returns
+// by verdict. Loads count as part of the check that uses them.
+func compareSemantics(result *ComparisonResult) {
+	var keys []string
+	tcpChecks := make(map[string]int)
+	protoChecks := make(map[string]int)
+
+	count := func(semantics []*SemanticInstruction, checks map[string]int) {
+		for _, sem := range semantics {
+			key, ok := checkKey(sem)
+			if !ok {
+				continue
+			}
+			if tcpChecks[key] == 0 && protoChecks[key] == 0 {
+				keys = append(keys, key)
+			}
+			checks[key]++
+		}
+	}
+	count(result.TcpdumpSemantic, tcpChecks)
+	count(result.PrototypeSemantic, protoChecks)
+	clauses := checkClauses(result)
+	missing := make(map[string]string)
+	extra := make(map[string]string)
+
+	for _, key := range keys {
+		tcpCount, protoCount := tcpChecks[key], protoChecks[key]
+		switch {
+		case tcpCount == protoCount:
+			result.Matches = append(result.Matches, "Both implement "+counted(key, tcpCount))
+		case protoCount == 0:
+			missing[key] = "Missing " + counted(key, tcpCount)
+			result.MissingInPrototype = append(result.MissingInPrototype, missing[key])
+		case tcpCount == 0:
+			extra[key] = "Extra " + counted(key, protoCount) + clauses[key]
+			result.ExtraInPrototype = append(result.ExtraInPrototype, extra[key])
+		default:
+			result.Differences = append(result.Differences,
+				fmt.Sprintf("%s: tcpdump has %d, prototype has %d%s", key, tcpCount, protoCount, clauses[key]))
+		}
+	}
+
+	findOffsetMismatches(result, missing, extra)
+	applyAllowances(result)
+
+	// Analyze structural differences
+	analyzeStructuralDifferences(result)
+}
+
+// MismatchesByType counts the checks and returns that differ between the
+// programs, missing, extra or present a different number of times, by the
+// type of instruction that implements them
+func (r *ComparisonResult) MismatchesByType() map[InstructionType]int {
+	types := make(map[string]InstructionType)
+	counts := make(map[string]int)
+	for _, sem := range r.TcpdumpSemantic {
+		if key, ok := checkKey(sem); ok {
+			types[key] = sem.Type
+			counts[key]++
+		}
+	}
+	for _, sem := range r.PrototypeSemantic {
+		if key, ok := checkKey(sem); ok {
+			types[key] = sem.Type
+			counts[key]--
+		}
+	}
+	mismatches := make(map[InstructionType]int)
+	for key, n := range counts {
+		if n != 0 {
+			mismatches[types[key]]++
+		}
+	}
+	return mismatches
+}
+
+// checkClauses returns, for each check key of the prototype, the filter
+// clauses its instructions were generated for, as " from CLAUSE, ...", or
+// "" when the prototype carries no source map
+func checkClauses(result *ComparisonResult) map[string]string {
+	clauses := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, sem := range result.PrototypeSemantic {
+		key, ok := checkKey(sem)
+		clause := result.PrototypeBPF.ClauseAt(sem.Index)
+		if !ok || clause == "" || seen[key+"\x00"+clause] {
+			continue
+		}
+		seen[key+"\x00"+clause] = true
+		if clauses[key] == "" {
+			clauses[key] = " from " + clause
+		} else {
+			clauses[key] += ", " + clause
+		}
+	}
+	return clauses
+}
+
+// ReferenceClauses names, for each instruction of the reference, the
+// filter clause it implements: the clause of the prototype's matching
+// check when the prototype carries a source map and makes the same check,
+// else the check itself in parentheses, e.g. "(Check Dest Port (80))".
+// Instructions other than checks and returns get "".
+func (r *ComparisonResult) ReferenceClauses() []string {
+	byKey := make(map[string]string)
+	for _, sem := range r.PrototypeSemantic {
+		key, ok := checkKey(sem)
+		clause := r.PrototypeBPF.ClauseAt(sem.Index)
+		if ok && clause != "" && byKey[key] == "" {
+			byKey[key] = clause
+		}
+	}
+
+	clauses := make([]string, len(r.TcpdumpBPF.Instructions))
+	for _, sem := range r.TcpdumpSemantic {
+		key, ok := checkKey(sem)
+		switch {
+		case !ok:
+		case byKey[key] != "":
+			clauses[sem.Index] = byKey[key]
+		default:
+			clauses[sem.Index] = "(" + key + ")"
+		}
+	}
+	return clauses
+}
+
+// analyzeStructuralDifferences looks for structural patterns and differences
+func analyzeStructuralDifferences(result *ComparisonResult) {
+	// Check instruction count difference
+	tcpCount := len(result.TcpdumpBPF.Instructions)
+	protoCount := len(result.PrototypeBPF.Instructions)
+
+	if tcpCount == protoCount {
+		result.Matches = append(result.Matches, "Same instruction count")
+	} else {
+		diff := protoCount - tcpCount
+		if diff > 0 {
+			result.StructuralDiffs = append(result.StructuralDiffs,
+				fmt.Sprintf("Prototype has %d more instructions than tcpdump", diff))
+		} else {
+			result.StructuralDiffs = append(result.StructuralDiffs,
+				fmt.Sprintf("Prototype has %d fewer instructions than tcpdump", -diff))
+		}
+	}
+
+	// Check for fragment handling
+	tcpHasFragment := hasInstructionType(result.TcpdumpSemantic, CheckFragment)
+	protoHasFragment := hasInstructionType(result.PrototypeSemantic, CheckFragment)
+
+	if protoHasFragment && !tcpHasFragment {
+		result.StructuralDiffs = append(result.StructuralDiffs,
+			"Prototype includes fragment handling that tcpdump mock doesn't have")
+	}
+
+	// Check for IP address filtering
+	tcpHasIPFilter := hasInstructionType(result.TcpdumpSemantic, CheckSourceIP) ||
+		hasInstructionType(result.TcpdumpSemantic, CheckDestIP)
+	protoHasIPFilter := hasInstructionType(result.PrototypeSemantic, CheckSourceIP) ||
+		hasInstructionType(result.PrototypeSemantic, CheckDestIP)
+
+	if protoHasIPFilter && !tcpHasIPFilter {
+		result.StructuralDiffs = append(result.StructuralDiffs,
+			"Prototype implements IP address filtering")
+	}
+}
+
+// hasInstructionType checks if a semantic list contains a specific instruction type
+func hasInstructionType(semantics []*SemanticInstruction, instType InstructionType) bool {
+	for _, sem := range semantics {
+		if sem.Type == instType {
+			return true
+		}
+	}
+	return false
+}
+
+// calculateVerdict weighs the score components with the policy and picks
+// the verdict of the score's band. A prototype that decides any probe
+// packet differently gets at best the policy's disagreement verdict, and
+// one missing IP validation is flagged CRITICAL.
+func calculateVerdict(result *ComparisonResult) {
+	verdicts := result.Vocabulary.Verdicts
+	policy := result.Policy
+	totalMatches := len(result.Matches)
+	totalDifferences := len(result.Differences) + len(result.MissingInPrototype) + len(result.ExtraInPrototype)
+
+	// Programs without checks or returns cannot be compared
+	if totalMatches+totalDifferences == 0 {
+		result.Score = 0.0
+		result.band = bandInconclusive
+		result.Verdict = render(verdicts.Inconclusive, result.reportData())
+		return
+	}
+
+	result.Components = ScoreComponents{
+		Behavior: result.Behavior.Agreement(),
+		Safety:   safetyScore(result),
+		Checks:   float64(totalMatches) / float64(totalMatches+totalDifferences),
+		Size:     sizeScore(len(result.TcpdumpBPF.Instructions), len(result.PrototypeBPF.Instructions)),
+	}
+	result.Score = policy.score(result.Components)
+	result.band = policy.band(result.Score)
+	if result.Behavior.Disagreements > 0 && bandRank[result.band] < bandRank[policy.DisagreementVerdict] {
+		result.band = policy.DisagreementVerdict
+	}
+
+	terms := map[string]string{
+		bandExcellent: verdicts.Excellent,
+		bandGood:      verdicts.Good,
+		bandPartial:   verdicts.Partial,
+		bandPoor:      verdicts.Poor,
+	}
+	result.Verdict = render(terms[result.band], result.reportData())
+
+	// Adjust verdict for important missing functionality
+	if result.Critical() {
+		result.Verdict = render(verdicts.Critical, result.reportData())
+	}
+}
+
+// safetyScore is the share of the kinds of safety check the reference
+// makes that the prototype makes too, or 1 if the reference makes none.
+// Kinds rather than values count, so a prototype that only covers IPv4 of
+// a reference accepting IPv6 as well keeps its IP validation. Kinds the
+// policy allows the prototype to leave out do not count.
+func safetyScore(result *ComparisonResult) float64 {
+	kinds, kept := 0, 0
+	for _, t := range safetyChecks {
+		if !hasInstructionType(result.TcpdumpSemantic, t) || result.Policy.allowance(RuleMissingCheck, "Missing "+t.String()) != nil {
+			continue
+		}
+		kinds++
+		if hasInstructionType(result.PrototypeSemantic, t) {
+			kept++
+		}
+	}
+	if kinds == 0 {
+		return 1
+	}
+	return float64(kept) / float64(kinds)
+}
+
+// sizeScore is the smaller instruction count over the larger one
+func sizeScore(a, b int) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return float64(min(a, b)) / float64(max(a, b))
+}
+
+// Critical reports whether the reference checks the IP version and the
+// prototype does not check it at all, which the verdict flags as CRITICAL.
+// A prototype covering only IPv4 of a reference that also accepts IPv6
+// still validates IP.
+func (r *ComparisonResult) Critical() bool {
+	return hasInstructionType(r.TcpdumpSemantic, CheckIP) && !hasInstructionType(r.PrototypeSemantic, CheckIP)
+}
+
+// reportData collects the values available to vocabulary templates
+func (r *ComparisonResult) reportData() *ReportData {
+	return &ReportData{
+		Score:         r.Score,
+		Score10:       r.Score * 10,
+		Verdict:       r.Verdict,
+		Matches:       len(r.Matches),
+		Issues:        len(r.Differences) + len(r.MissingInPrototype),
+		Enhancements:  len(r.ExtraInPrototype),
+		Components:    r.Components,
+		Probes:        r.Behavior.Packets,
+		Disagreements: r.Behavior.Disagreements,
+		Explanation:   r.Explain()[0],
+		EditDistance:  r.Distance.Operations,
+		Similarity:    r.Distance.Similarity,
+	}
+}
+
+// Render writes the formatted comparison report to w
+func (r *ComparisonResult) Render(w io.Writer) error {
+	return r.writeReport(w, false)
+}
+
+// writeReport writes the report, in color if the palette is on
+func (r *ComparisonResult) writeReport(w io.Writer, p palette) error {
+	var sb strings.Builder
+	sb.WriteString("\n")
+	r.writeHeader(&sb)
+	r.writeSideBySideComparison(&sb, p)
+	r.writeSourceMap(&sb, p)
+	r.writeVerdictSummary(&sb, p)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeHeader writes the main comparison header
+func (r *ComparisonResult) writeHeader(sb *strings.Builder) {
+	labels := r.Vocabulary.Labels
+	data := r.reportData()
+	fmt.Fprintf(sb, "┌"+strings.Repeat("─", 78)+"┐\n")
+	fmt.Fprintf(sb, "│"+centerText(render(labels.Title, data), 78)+"│\n")
+	fmt.Fprintf(sb, "├"+strings.Repeat("─", 38)+"┬"+strings.Repeat("─", 39)+"┤\n")
+	fmt.Fprintf(sb, "│"+centerText(render(labels.Reference, data), 38)+"│"+centerText(render(labels.Prototype, data), 39)+"│\n")
+	fmt.Fprintf(sb, "├"+strings.Repeat("─", 38)+"┼"+strings.Repeat("─", 39)+"┤\n")
+}
+
+// writeSideBySideComparison writes the main comparison content
+func (r *ComparisonResult) writeSideBySideComparison(sb *strings.Builder, p palette) {
+	// Instruction counts
+	tcpCount := len(r.TcpdumpBPF.Instructions)
+	protoCount := len(r.PrototypeBPF.Instructions)
+
+	fmt.Fprintf(sb, "│ Instructions: %-23d │ Instructions: %-23d │\n", tcpCount, protoCount)
+	fmt.Fprintf(sb, "│ Filter: %-29s │ Filter: %-29s │\n",
+		truncateString(r.TcpdumpBPF.FilterExpr, 29),
+		truncateString(r.PrototypeBPF.FilterExpr, 29))
+
+	if r.TcpdumpBPF.IsMocked {
+		fmt.Fprintf(sb, "│ Source: Mock Data                    │ Source: Generated                     │\n")
+	} else {
+		fmt.Fprintf(sb, "│ Source: Real tcpdump                 │ Source: Generated                     │\n")
+	}
+
+	fmt.Fprintf(sb, "├"+strings.Repeat("─", 38)+"┼"+strings.Repeat("─", 39)+"┤\n")
+
+	// Disassembly of both programs
+	r.writeDisassembly(sb)
+
+	fmt.Fprintf(sb, "├"+strings.Repeat("─", 38)+"┼"+strings.Repeat("─", 39)+"┤\n")
+
+	// Core functionality comparison
+	r.writeFunctionalityComparison(sb, p)
+
+	fmt.Fprintf(sb, "├"+strings.Repeat("─", 38)+"┼"+strings.Repeat("─", 39)+"┤\n")
+
+	// Key differences
+	r.writeKeyDifferences(sb, p)
+
+	fmt.Fprintf(sb, "└"+strings.Repeat("─", 38)+"┴"+strings.Repeat("─", 39)+"┘\n")
+}
+
+// writeDisassembly lists both programs as mnemonics side by side
+func (r *ComparisonResult) writeDisassembly(sb *strings.Builder) {
+	tcpLines := disassemblyLines(r.TcpdumpBPF.Instructions)
+	protoLines := disassemblyLines(r.PrototypeBPF.Instructions)
+
+	rows := len(tcpLines)
+	if len(protoLines) > rows {
+		rows = len(protoLines)
+	}
+
+	for i := 0; i < rows; i++ {
+		var left, right string
+		if i < len(tcpLines) {
+			left = tcpLines[i]
+		}
+		if i < len(protoLines) {
+			right = protoLines[i]
+		}
+		fmt.Fprintf(sb, "│ %-36s │ %-37s │\n", truncateString(left, 36), truncateString(right, 37))
+	}
+}
+
+// disassemblyLines renders each instruction as a compact single-line mnemonic
+func disassemblyLines(instructions []*bpf.Instruction) []string {
+	lines := make([]string, 0, len(instructions))
+	for pc, inst := range instructions {
+		mnemonic := strings.Join(strings.Fields(inst.Mnemonic(pc)), " ")
+		lines = append(lines, fmt.Sprintf("(%03d) %s", pc, mnemonic))
+	}
+	return lines
+}
+
+// writeFunctionalityComparison writes core functionality with indicators
+func (r *ComparisonResult) writeFunctionalityComparison(sb *strings.Builder, p palette) {
+	// Create a map of all functionality
+	allTypes := make(map[InstructionType]bool)
+	tcpTypes := make(map[InstructionType]int)
+	protoTypes := make(map[InstructionType]int)
+
+	for _, sem := range r.TcpdumpSemantic {
+		allTypes[sem.Type] = true
+		tcpTypes[sem.Type]++
+	}
+
+	for _, sem := range r.PrototypeSemantic {
+		allTypes[sem.Type] = true
+		protoTypes[sem.Type]++
+	}
+
+	// Core functionality to display
+	coreTypes := []InstructionType{
+		CheckIP, CheckEtherType, CheckProtocol, CheckSourceIP, CheckDestIP,
+		CheckSourcePort, CheckDestPort, CheckFragment, CheckVLAN, CheckVLANID, Accept, Reject,
+	}
+
+	for _, instType := range coreTypes {
+		if !allTypes[instType] {
+			continue
+		}
+
+		tcpHas := tcpTypes[instType] > 0
+		protoHas := protoTypes[instType] > 0
+
+		tcpIndicator := getIndicator(tcpHas, p)
+		protoIndicator := getIndicator(protoHas, p)
+
+		funcName := getShortFunctionName(instType)
+
+		fmt.Fprintf(sb, "│ %s %-32s │ %s %-32s │\n",
+			tcpIndicator, funcName,
+			protoIndicator, funcName)
+	}
+}
+
+// writeKeyDifferences writes important differences
+func (r *ComparisonResult) writeKeyDifferences(sb *strings.Builder, p palette) {
+	labels := r.Vocabulary.Labels
+	fmt.Fprintf(sb, "│"+centerText(render(labels.KeyDifferences, r.reportData()), 78)+"│\n")
+	fmt.Fprintf(sb, "├"+strings.Repeat("─", 78)+"┤\n")
+
+	// Show most important differences first
+	differences := r.getTopDifferences(4)
+
+	if len(differences) == 0 {
+		fmt.Fprintf(sb, "│"+centerText(render(labels.NoDifferences, r.reportData()), 78)+"│\n")
+	} else {
+		for _, diff := range differences {
+			fmt.Fprintf(sb, "│ %s │\n", diff.paint(fmt.Sprintf("%s %-72s", diff.Icon, truncateString(diff.Text, 72)), p))
+		}
+	}
+}
+
+// writeSourceMap lists which filter clause each span of the prototype
+// implements, marking the spans whose checks differ from the reference
+func (r *ComparisonResult) writeSourceMap(sb *strings.Builder, p palette) {
+	spans := r.PrototypeBPF.SourceMap()
+	if len(spans) == 0 {
+		return
+	}
+	differing := unmatchedChecks(r.PrototypeSemantic, r.TcpdumpSemantic)
+	keys := make(map[int]string)
+	for _, sem := range r.PrototypeSemantic {
+		if key, ok := checkKey(sem); ok {
+			keys[sem.Index] = key
+		}
+	}
+
+	fmt.Fprintf(sb, "\n%s\n", render(r.Vocabulary.Labels.SourceMap, r.reportData()))
+	for _, span := range spans {
+		var differs []string
+		for pc := span.Start; pc < span.End; pc++ {
+			if differing[pc] {
+				differs = append(differs, keys[pc])
+			}
+		}
+		if len(differs) == 0 {
+			fmt.Fprintf(sb, "  %s\n", span)
+		} else {
+			fmt.Fprintf(sb, "%s\n", p.paint(fmt.Sprintf("⚠ %s; differs from tcpdump: %s", span, strings.Join(differs, ", ")), ansiYellow))
+		}
+	}
+}
+
+// writeVerdictSummary writes the final verdict
+func (r *ComparisonResult) writeVerdictSummary(sb *strings.Builder, p palette) {
+	fmt.Fprintf(sb, "\n")
+
+	// Score bar
+	scoreBar := p.band(r.getScoreBar(50), r.band)
+	labels := r.Vocabulary.Labels
+	data := r.reportData()
+	fmt.Fprintf(sb, "%s %s\n", render(labels.Score, data), scoreBar)
+	if r.band != bandInconclusive {
+		fmt.Fprintf(sb, "%s\n", render(labels.ScoreBreakdown, data))
+		fmt.Fprintf(sb, "%s\n", render(labels.EditDistance, data))
+		if r.Divergent() {
+			fmt.Fprintf(sb, "%s\n", p.paint(fmt.Sprintf("⚠ score %.2f and similarity %.2f are far apart; unless the programs are built differently to the same end, or alike but for an edit that matters, the analyzer may have misread one", r.Score, r.Distance.Similarity), ansiYellow))
+		}
+	}
+	for _, d := range r.Behavior.Examples {
+		fmt.Fprintf(sb, "  %s\n", p.paint(fmt.Sprintf("reference %s, prototype %s: %x", acceptText(d.Reference), acceptText(d.Prototype), d.Packet), ansiRed))
+		fmt.Fprintf(sb, "    %s\n", packet.Decode(d.Packet, filter.LinkType(r.TcpdumpBPF.LinkType)))
+	}
+
+	// Verdict with color-coded background
+	verdictColor := r.getVerdictColor(p)
+	fmt.Fprintf(sb, "\n%s\n", verdictColor)
+
+	// Quick stats
+	fmt.Fprintf(sb, "\n%s\n", render(labels.QuickStats, data))
+
+	// Key takeaway
+	fmt.Fprintf(sb, "\n%s%s\n", render(labels.KeyTakeaway, data), r.getKeyTakeaway())
+	for _, e := range r.Explain()[1:] {
+		fmt.Fprintf(sb, "  • %s\n", e)
+	}
+}
+
+// Helper functions
+
+func centerText(text string, width int) string {
+	if len(text) >= width {
+		return text[:width]
+	}
+	padding := (width - len(text)) / 2
+	return strings.Repeat(" ", padding) + text + strings.Repeat(" ", width-padding-len(text))
+}
+
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+// acceptText names a program's verdict on a packet
+func acceptText(accepted bool) string {
+	if accepted {
+		return "accepts"
+	}
+	return "rejects"
+}
+
+func getIndicator(has bool, p palette) string {
+	if has {
+		return p.paint("✓", ansiGreen)
+	}
+	return p.paint("✗", ansiRed)
+}
+
+func getShortFunctionName(instType InstructionType) string {
+	shortNames := map[InstructionType]string{
+		CheckIP:         "IP Validation",
+		CheckProtocol:   "Protocol Check",
+		CheckSourceIP:   "Source IP Filter",
+		CheckDestIP:     "Dest IP Filter",
+		CheckSourcePort: "Source Port Filter",
+		CheckDestPort:   "Dest Port Filter",
+		CheckFragment:   "Fragment Handling",
+		CheckVLAN:       "VLAN Tag Check",
+		CheckVLANID:     "VLAN ID Filter",
+		CheckEtherType:  "EtherType Check",
+		Accept:          "Accept Logic",
+		Reject:          "Reject Logic",
+	}
+
+	if name, exists := shortNames[instType]; exists {
+		return name
+	}
+	return instType.String()
+}
+
+type Difference struct {
+	Icon     string
+	Text     string
+	Priority int
+}
+
+func (r *ComparisonResult) getTopDifferences(maxCount int) []Difference {
+	var diffs []Difference
+
+	// Highest priority: programs the kernel would refuse, and packets the
+	// programs decide differently
+	for _, failure := range r.VerifierFailures {
+		diffs = append(diffs, Difference{"🚨", "VERIFIER: " + failure, 0})
+	}
+	if b := r.Behavior; b.Disagreements > 0 {
+		diffs = append(diffs, Difference{"🚨", fmt.Sprintf("BEHAVIOR: %d of %d probe packets decided differently", b.Disagreements, b.Packets), 0})
+	}
+	for _, mismatch := range r.OffsetMismatches {
+		diffs = append(diffs, Difference{"🚨", "OFFSET: " + mismatch, 1})
+	}
+
+	// High priority: Missing critical functionality
+	for _, missing := range r.MissingInPrototype {
+		if strings.HasPrefix(missing, "Missing "+CheckIP.String()) && r.Critical() {
+			diffs = append(diffs, Difference{"🚨", "CRITICAL: " + missing, 1})
+		} else {
+			diffs = append(diffs, Difference{"✗", missing, 3})
+		}
+	}
+
+	// Medium priority: Extra functionality (often good)
+	for _, extra := range r.ExtraInPrototype {
+		if strings.Contains(extra, "Fragment") || strings.Contains(extra, "IP") {
+			diffs = append(diffs, Difference{"✨", "ENHANCEMENT: " + extra, 2})
+		} else {
+			diffs = append(diffs, Difference{"+", extra, 4})
+		}
+	}
+
+	// Lower priority: Structural differences
+	for _, structural := range r.StructuralDiffs {
+		diffs = append(diffs, Difference{"⚠", structural, 5})
+	}
+
+	// Sort by priority and take top items
+	if len(diffs) > maxCount {
+		diffs = diffs[:maxCount]
+	}
+
+	return diffs
+}
+
+func (r *ComparisonResult) getScoreBar(width int) string {
+	filled := int(r.Score * float64(width))
+	empty := width - filled
+
+	bar := "["
+	if r.band == bandExcellent {
+		bar += strings.Repeat("█", filled)
+	} else if r.band == bandGood {
+		bar += strings.Repeat("▓", filled)
+	} else {
+		bar += strings.Repeat("▒", filled)
+	}
+	bar += strings.Repeat("░", empty)
+	bar += "]"
+
+	return bar
+}
+
+func (r *ComparisonResult) getVerdictColor(p palette) string {
+	verdict := render(r.Vocabulary.Labels.Verdict, r.reportData())
+	if r.Critical() {
+		verdict = p.paint(verdict, ansiBold, ansiRed)
+	} else {
+		verdict = p.band(verdict, r.band)
+	}
+	if r.band == bandExcellent {
+		return "🟢 " + verdict
+	} else if r.band == bandGood {
+		return "🟡 " + verdict
+	} else {
+		return "🔴 " + verdict
+	}
+}
+
+func (r *ComparisonResult) getKeyTakeaway() string {
+	takeaways := r.Vocabulary.Takeaways
+	data := r.reportData()
+	if r.band == bandExcellent {
+		return render(takeaways.Excellent, data)
+	} else if r.band == bandGood {
+		return render(takeaways.Good, data)
+	} else if r.band == bandPartial {
+		return render(takeaways.Partial, data)
+	} else {
+		return render(takeaways.Poor, data)
+	}
+}
@@ -0,0 +1,64 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// CorpusPacket is a probe packet with the reference's verdict on it
+type CorpusPacket struct {
+	Name   string // the headers it has and its probe number, e.g. "IPv4/TCP #12"
+	Data   []byte
+	Accept bool // whether the reference accepts it
+}
+
+// Corpus picks up to limit probe packets with the reference's verdicts,
+// for regression tests of the prototype. Packets are taken in turn from
+// each class of headers and verdict, so that every class and both
+// verdicts are covered before a class gets a second packet. They keep the
+// order the probes were built in. A limit of 0 or less takes them all.
+func (r *ComparisonResult) Corpus(limit int) []CorpusPacket {
+	link := filter.LinkType(r.TcpdumpBPF.LinkType)
+	probes := probePackets(append(append([]*SemanticInstruction{}, r.TcpdumpSemantic...), r.PrototypeSemantic...), link)
+	if limit <= 0 || limit > len(probes) {
+		limit = len(probes)
+	}
+
+	type group struct {
+		indices []int
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	packets := make([]CorpusPacket, len(probes))
+	for i, pkt := range probes {
+		class, _ := packetClass(pkt, link)
+		accept := accepts(r.TcpdumpBPF.Instructions, pkt)
+		packets[i] = CorpusPacket{Name: fmt.Sprintf("%s #%d", class, i), Data: pkt, Accept: accept}
+		key := fmt.Sprintf("%s/%t", class, accept)
+		g, ok := index[key]
+		if !ok {
+			g = &group{}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.indices = append(g.indices, i)
+	}
+
+	var picked []int
+	for round := 0; len(picked) < limit; round++ {
+		for _, g := range groups {
+			if round < len(g.indices) && len(picked) < limit {
+				picked = append(picked, g.indices[round])
+			}
+		}
+	}
+	sort.Ints(picked)
+
+	corpus := make([]CorpusPacket, len(picked))
+	for i, p := range picked {
+		corpus[i] = packets[p]
+	}
+	return corpus
+}
@@ -0,0 +1,746 @@
+package compare
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// MaxDecompilePaths bounds the accepting paths Decompile follows through
+// a program
+const MaxDecompilePaths = 256
+
+// Decompilation is a program read back as a filter expression
+type Decompilation struct {
+	// Expression is a pcap-filter expression with one alternative per
+	// accepting path. It is empty when the program accepts every packet,
+	// or none (Paths is 0).
+	Expression string
+
+	// Filter is the PacketFilter the accepting paths fit, or nil with
+	// Reason saying why none does. It is a guess: the paths only say
+	// which checks a packet passes, not which criteria the program was
+	// generated from.
+	Filter *filter.PacketFilter
+	Reason string
+
+	// Untranslated lists the checks and returns with no pcap-filter
+	// equivalent, e.g. "instruction 7: Check A > X". The expression leaves
+	// them out, so it may accept more packets than the program.
+	Untranslated []string
+
+	Paths     int  // accepting paths
+	Truncated bool // the program has more paths than MaxDecompilePaths, and the rest were not followed
+}
+
+// outcome is the branch a path takes at a conditional jump
+type outcome struct {
+	pc    int
+	taken bool // the true branch
+}
+
+// decompiler follows the accepting paths of a program
+type decompiler struct {
+	instructions []*bpf.Instruction
+	semantics    map[int]*SemanticInstruction
+	paths        [][]outcome
+	leaves       int
+	truncated    bool
+	untranslated map[int]string
+}
+
+// Decompile reconstructs a best-effort filter expression from a classic
+// BPF program, such as one read from a running agent whose expression is
+// lost. Every path from the first instruction to an accepting return is
+// a conjunction of the checks it passes and fails; checks another on the
+// path already decides, and failed checks an earlier alternative covers,
+// are left out. Checks are named by the field they test, as in the
+// comparison, and tests of fields without a name are written as link[]
+// or transport-layer byte tests. Tests of computed values, of the index
+// register and "ret a" cannot be written in pcap-filter syntax and are
+// listed in Untranslated.
+func Decompile(instructions []*bpf.Instruction, link filter.LinkType) *Decompilation {
+	d := &decompiler{
+		instructions: instructions,
+		semantics:    make(map[int]*SemanticInstruction),
+		untranslated: make(map[int]string),
+	}
+	for _, sem := range analyzeSemantics(instructions, link) {
+		d.semantics[sem.Index] = sem
+	}
+	d.walk(0, nil)
+
+	// Paths through checks written alike, such as the 802.1Q and 802.1ad
+	// tag checks, read the same
+	var rendered [][]term
+	seen := make(map[string]bool)
+	for _, p := range d.simplify() {
+		terms := d.render(p)
+		texts := make([]string, len(terms))
+		for i, t := range terms {
+			texts[i] = t.text
+		}
+		if key := strings.Join(texts, " and "); !seen[key] {
+			seen[key] = true
+			rendered = append(rendered, terms)
+		}
+	}
+
+	result := &Decompilation{
+		Expression: expression(rendered),
+		Paths:      len(rendered),
+		Truncated:  d.truncated,
+	}
+	result.Filter, result.Reason = fitFilter(rendered, link)
+	if d.truncated && result.Filter != nil {
+		result.Filter, result.Reason = nil, fmt.Sprintf("the program has more than %d accepting paths", MaxDecompilePaths)
+	}
+	pcs := make([]int, 0, len(d.untranslated))
+	for pc := range d.untranslated {
+		pcs = append(pcs, pc)
+	}
+	sort.Ints(pcs)
+	for _, pc := range pcs {
+		result.Untranslated = append(result.Untranslated, fmt.Sprintf("instruction %d: %s", pc, d.untranslated[pc]))
+	}
+	return result
+}
+
+// walk follows every feasible path from pc, recording those that accept.
+// Programs only jump forward, so every path ends at a return.
+func (d *decompiler) walk(pc int, trail []outcome) {
+	for pc < len(d.instructions) && !d.truncated {
+		inst := d.instructions[pc]
+		switch {
+		case inst.IsReturn():
+			d.leaves++
+			if inst.Code&0x18 == bpf.RetA {
+				d.untranslated[pc] = "Return A, which may accept or reject"
+				d.paths = append(d.paths, trail)
+			} else if inst.K != 0 {
+				d.paths = append(d.paths, trail)
+			}
+			// Rejecting paths cost time too, so they count against the bound
+			if len(d.paths) > MaxDecompilePaths || d.leaves > 16*MaxDecompilePaths {
+				d.truncated = true
+			}
+			return
+		case isConditional(inst):
+			targets := jumpTargets(inst, pc)
+			if targets[0] == targets[1] {
+				pc = targets[0]
+				continue
+			}
+			for i, taken := range []bool{true, false} {
+				o := outcome{pc: pc, taken: taken}
+				if d.feasible(trail, o) {
+					d.walk(targets[i], append(trail[:len(trail):len(trail)], o))
+				}
+			}
+			return
+		case inst.IsJump():
+			pc = jumpTargets(inst, pc)[0]
+			continue
+		}
+		pc++
+	}
+}
+
+// equality returns the field, mask and value an outcome's check compares
+// for equality, or false for any other check
+func (d *decompiler) equality(o outcome) (field, uint32, uint32, bool) {
+	sem := d.semantics[o.pc]
+	if sem == nil || sem.probe == nil || d.instructions[o.pc].Code&0xf0 != bpf.JmpJEQ {
+		return field{}, 0, 0, false
+	}
+	f := sem.probe.field
+	mask := f.mask
+	f.mask = 0
+	return f, mask, sem.probe.value, true
+}
+
+// feasible reports whether a packet can take the path with the outcome
+// added: an equality the path has passed decides any later test of the
+// same bits
+func (d *decompiler) feasible(trail []outcome, o outcome) bool {
+	f2, m2, k2, ok := d.equality(o)
+	if !ok {
+		return true
+	}
+	for _, p := range trail {
+		f1, m1, k1, ok := d.equality(p)
+		if !ok || f1 != f2 {
+			continue
+		}
+		switch {
+		case p.taken && o.taken && (k1^k2)&m1&m2 != 0:
+			return false
+		case p.taken && !o.taken && m2&^m1 == 0 && k1&m2 == k2:
+			return false
+		case !p.taken && o.taken && m1 == m2 && k1 == k2:
+			return false
+		}
+	}
+	return true
+}
+
+// implied reports whether another outcome on the path decides the
+// outcome at i: the same test passed or failed earlier, or an equality
+// on the same field that rules out a failed one
+func (d *decompiler) implied(p []outcome, i int) bool {
+	f2, m2, k2, ok := d.equality(p[i])
+	if !ok {
+		return false
+	}
+	for j, q := range p {
+		f1, m1, k1, ok := d.equality(q)
+		if j == i || !ok || f1 != f2 {
+			continue
+		}
+		switch {
+		case j < i && q.taken == p[i].taken && m1 == m2 && k1 == k2:
+			return true
+		case q.taken && !p[i].taken && (k1^k2)&m1&m2 != 0:
+			return true
+		}
+	}
+	return false
+}
+
+// key identifies what an outcome tests, so that the same check at two
+// places of a program compares equal
+func (d *decompiler) key(o outcome) string {
+	if f, m, k, ok := d.equality(o); ok {
+		return fmt.Sprintf("%v&%x==%x:%v", f, m, k, o.taken)
+	}
+	return fmt.Sprintf("%d:%v", o.pc, o.taken)
+}
+
+// simplify drops the outcomes each path does not need. Besides the
+// implied ones, a failed check is not needed when another path takes
+// its true branch after the same checks and needs nothing more than the
+// rest of this one: A or (not A and B) is A or B. Paths that become
+// duplicates of another, or include all of another's checks, go too.
+func (d *decompiler) simplify() [][]outcome {
+	paths := make([][]outcome, len(d.paths))
+	for i, p := range d.paths {
+		for j := range p {
+			if !d.implied(p, j) {
+				paths[i] = append(paths[i], p[j])
+			}
+		}
+	}
+
+	keys := func(p []outcome) []string {
+		ks := make([]string, len(p))
+		for i, o := range p {
+			ks[i] = d.key(o)
+		}
+		return ks
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, p := range paths {
+			for j := 0; j < len(p); j++ {
+				if p[j].taken || !d.covered(paths, i, j, keys) {
+					continue
+				}
+				p = append(p[:j:j], p[j+1:]...)
+				paths[i] = p
+				changed = true
+				j--
+			}
+		}
+	}
+
+	var kept [][]outcome
+	for i, p := range paths {
+		absorbed := false
+		for j, q := range paths {
+			if i != j && subset(keys(q), keys(p)) && (len(q) < len(p) || j < i) {
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// covered reports whether another path shares the first j checks of path
+// i, passes the check path i fails at j, and needs no check after it that
+// path i does not make
+func (d *decompiler) covered(paths [][]outcome, i, j int, keys func([]outcome) []string) bool {
+	p := paths[i]
+	pk := keys(p)
+	want := d.key(outcome{pc: p[j].pc, taken: true})
+	for n, q := range paths {
+		if n == i || len(q) <= j {
+			continue
+		}
+		qk := keys(q)
+		if strings.Join(qk[:j], "\n") != strings.Join(pk[:j], "\n") || qk[j] != want {
+			continue
+		}
+		if subset(qk[j+1:], pk[j+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// subset reports whether every string of a is in b
+func subset(a, b []string) bool {
+	for _, s := range a {
+		found := false
+		for _, t := range b {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// term is one check of a path in pcap-filter syntax
+type term struct {
+	kind  InstructionType
+	text  string // e.g. "src net 10.0.0.0/24"
+	value string // protocol, address, port or VLAN ID a passed equality tests, "" for any other test
+}
+
+// render writes the checks of a path as pcap-filter terms, recording the
+// checks that cannot be written
+func (d *decompiler) render(p []outcome) []term {
+	var terms []term
+	addresses := map[InstructionType]*ipv6Address{}
+
+	// Transport fields are named by the path's protocol
+	transport := ""
+	for _, o := range p {
+		if sem := d.semantics[o.pc]; o.taken && sem.Type == CheckProtocol {
+			transport = map[string]string{"tcp": "tcp", "udp": "udp", "sctp": "sctp", "icmp": "icmp"}[sem.Predicate]
+		}
+	}
+	later := func(i int, types ...InstructionType) bool {
+		for _, o := range p[i+1:] {
+			for _, t := range types {
+				if d.semantics[o.pc].Type == t && o.taken {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for i, o := range p {
+		sem := d.semantics[o.pc]
+		inst := d.instructions[o.pc]
+		jeq := inst.Code&0xf0 == bpf.JmpJEQ
+		ipv6 := strings.Contains(sem.Field, "IPv6")
+		t := term{kind: sem.Type}
+
+		switch {
+		case sem.Type == CheckIP && jeq:
+			t.text = map[string]string{"IPv4": "ip", "IPv6": "ip6"}[sem.Predicate]
+			t.value = t.text
+		case sem.Type == CheckEtherType:
+			t.value = sem.Predicate
+			if t.text = sem.Predicate; strings.HasPrefix(sem.Predicate, "0x") {
+				t.text = "ether proto " + sem.Predicate
+			}
+		case sem.Type == CheckVLAN:
+			// "vlan N" tests the tag too
+			if o.taken && later(i, CheckVLANID) {
+				continue
+			}
+			t.text = "vlan"
+		case sem.Type == CheckVLANID && jeq && sem.probe != nil && sem.probe.field.mask == 0x0fff:
+			t.value = sem.Predicate
+			t.text = "vlan " + sem.Predicate
+		case sem.Type == CheckProtocol && jeq:
+			t.value = strings.TrimPrefix(sem.Predicate, "protocol ")
+			switch {
+			case ipv6:
+				t.text = fmt.Sprintf("ip6 proto %d", sem.Value)
+			case strings.HasPrefix(sem.Predicate, "protocol "):
+				t.text = "ip proto " + t.value
+			default:
+				t.text = sem.Predicate
+			}
+		case (sem.Type == CheckSourceIP || sem.Type == CheckDestIP) && jeq && !ipv6:
+			t.value = sem.Predicate
+			t.text = addressTerm(sem.Type, sem.Predicate)
+		case (sem.Type == CheckSourceIP || sem.Type == CheckDestIP) && jeq && o.taken:
+			// IPv6 addresses are tested a word at a time, and written
+			// once all words are known
+			a := addresses[sem.Type]
+			if a == nil {
+				a = &ipv6Address{}
+				addresses[sem.Type] = a
+				terms = append(terms, term{kind: sem.Type})
+				a.at = len(terms) - 1
+			}
+			var word int
+			fmt.Sscanf(sem.Field[strings.LastIndex(sem.Field, " ")+1:], "%d", &word)
+			a.words[word], a.masks[word] = sem.probe.value, sem.probe.field.mask
+			a.tests = append(a.tests, rawTest(sem.probe.field, bpf.JmpJEQ, sem.Value, true, transport))
+			continue
+		case (sem.Type == CheckSourcePort || sem.Type == CheckDestPort) && jeq:
+			t.value = sem.Predicate
+			t.text = map[InstructionType]string{CheckSourcePort: "src port ", CheckDestPort: "dst port "}[sem.Type] + sem.Predicate
+		case sem.Type == CheckFragment && !o.taken && later(i, CheckSourcePort, CheckDestPort) && sem.Value == 0x1fff:
+			// Port primitives skip fragments after the first themselves
+			continue
+		case sem.probe != nil:
+			// Fragment checks keep their type, as the fragment policy
+			// covers them
+			if t.kind != CheckFragment {
+				t.kind = CheckField
+			} else if !o.taken {
+				t.value = "unfragmented"
+			}
+			t.text = rawTest(sem.probe.field, inst.Code&0xf0, sem.Value, o.taken, transport)
+			if t.text == "" {
+				d.untranslated[o.pc] = sem.Description
+				continue
+			}
+			terms = append(terms, t)
+			continue
+		default:
+			d.untranslated[o.pc] = sem.Description
+			continue
+		}
+
+		if !o.taken {
+			t.text, t.value = "not "+t.text, ""
+		}
+		terms = append(terms, t)
+	}
+
+	for _, a := range addresses {
+		terms[a.at].text = strings.Join(a.tests, " and ")
+		if network, ok := a.network(); ok {
+			terms[a.at].value = network
+			terms[a.at].text = addressTerm(terms[a.at].kind, network)
+		}
+	}
+	return terms
+}
+
+// ipv6Address collects the words of an IPv6 address a path tests
+type ipv6Address struct {
+	at    int // index of the path's term for it
+	words [4]uint32
+	masks [4]uint32
+	tests []string // the word tests, written out in case they make no network
+}
+
+// network returns the address or network the words make: whole words
+// from the first, then at most one word of leading ones
+func (a *ipv6Address) network() (string, bool) {
+	ip := make(net.IP, net.IPv6len)
+	ones := 0
+	for i := 0; i < 4; i++ {
+		m := a.masks[i]
+		if ones != i*32 && m != 0 {
+			return "", false
+		}
+		n := 0
+		for ; n < 32 && m&(1<<(31-n)) != 0; n++ {
+		}
+		if m<<n != 0 {
+			return "", false
+		}
+		ones += n
+		w := a.words[i] & m
+		ip[4*i], ip[4*i+1], ip[4*i+2], ip[4*i+3] = byte(w>>24), byte(w>>16), byte(w>>8), byte(w)
+	}
+	if ones == 128 {
+		return ip.String(), true
+	}
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(ones, 128)}).String(), true
+}
+
+// addressTerm writes a source or destination address or network test
+func addressTerm(kind InstructionType, addr string) string {
+	dir := "src"
+	if kind == CheckDestIP {
+		dir = "dst"
+	}
+	if strings.Contains(addr, "/") {
+		return fmt.Sprintf("%s net %s", dir, addr)
+	}
+	return fmt.Sprintf("%s host %s", dir, addr)
+}
+
+// rawTest writes a check of a field as a byte test, e.g.
+// "link[30:4] & 0xffffff00 = 0xa000000" or "tcp[2:2] > 1023". Fields
+// past the IP header are written relative to the transport header, which
+// needs the path's protocol; it returns "" without one.
+func rawTest(f field, op uint16, k uint32, taken bool, transport string) string {
+	var text string
+	switch {
+	case f.mode == bpf.ModeIND && transport != "":
+		text = fmt.Sprintf("%s[%d:%d]", transport, f.offset, f.size)
+	case f.mode == bpf.ModeABS:
+		text = fmt.Sprintf("link[%d:%d]", f.offset, f.size)
+	default:
+		return ""
+	}
+	if f.mask != 0xffffffff {
+		text += fmt.Sprintf(" & 0x%x", f.mask)
+	}
+	value := "0x" + strconv.FormatUint(uint64(k), 16)
+	if op == bpf.JmpJGT || op == bpf.JmpJGE {
+		value = strconv.FormatUint(uint64(k), 10)
+	}
+
+	symbols := map[uint16][2]string{
+		bpf.JmpJEQ: {"=", "!="},
+		bpf.JmpJGT: {">", "<="},
+		bpf.JmpJGE: {">=", "<"},
+	}
+	if op == bpf.JmpJSET {
+		if taken {
+			return fmt.Sprintf("%s & %s != 0", text, value)
+		}
+		return fmt.Sprintf("%s & %s = 0", text, value)
+	}
+	s, ok := symbols[op]
+	if !ok {
+		return ""
+	}
+	if taken {
+		return fmt.Sprintf("%s %s %s", text, s[0], value)
+	}
+	return fmt.Sprintf("%s %s %s", text, s[1], value)
+}
+
+// expression joins the paths into one expression, with the terms every
+// path starts with written once. Terms keep their order, since "vlan"
+// moves the offsets of the terms after it.
+func expression(paths [][]term) string {
+	texts := make([][]string, len(paths))
+	for i, p := range paths {
+		for _, t := range p {
+			texts[i] = append(texts[i], t.text)
+		}
+		if len(texts[i]) == 0 {
+			// One path accepts everything
+			return ""
+		}
+	}
+	if len(texts) == 0 {
+		return ""
+	}
+
+	// Each alternative keeps a term of its own
+	common := 0
+	for len(texts) > 1 {
+		same := true
+		for _, t := range texts {
+			if len(t) <= common+1 || t[common] != texts[0][common] {
+				same = false
+				break
+			}
+		}
+		if !same {
+			break
+		}
+		common++
+	}
+
+	parts := append([]string{}, texts[0][:common]...)
+	var alternatives []string
+	for _, t := range texts {
+		alt := strings.Join(t[common:], " and ")
+		if len(texts) > 1 && len(t)-common > 1 {
+			alt = "(" + alt + ")"
+		}
+		alternatives = append(alternatives, alt)
+	}
+	alt := strings.Join(alternatives, " or ")
+	if len(alternatives) > 1 && common > 0 {
+		alt = "(" + alt + ")"
+	}
+	if alt != "" {
+		parts = append(parts, alt)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// shape is what one path tests, in PacketFilter terms
+type shape struct {
+	etherType, protocol, vlanID string
+	vlan                        bool
+	src, dst                    string
+	srcPort, dstPort            string
+}
+
+// fitFilter returns the PacketFilter the paths fit, or the reason none
+// does. Paths that test either address or either port, or one address
+// each way, make the either-direction criteria; paths differing only in
+// a port make a port list.
+func fitFilter(paths [][]term, link filter.LinkType) (*filter.PacketFilter, string) {
+	if len(paths) == 0 {
+		return nil, "the program accepts no packets"
+	}
+	shapes := make([]shape, len(paths))
+	for i, p := range paths {
+		s := &shapes[i]
+		for _, t := range p {
+			var slot *string
+			switch {
+			case (t.kind == CheckIP || t.kind == CheckFragment) && t.value != "":
+				// The family follows from the other criteria, and fragments
+				// from the fragment policy
+				continue
+			case t.kind == CheckVLAN && t.text == "vlan":
+				s.vlan = true
+				continue
+			case t.value == "":
+				return nil, fmt.Sprintf("a PacketFilter cannot express %q", t.text)
+			case t.kind == CheckEtherType:
+				slot = &s.etherType
+			case t.kind == CheckVLANID:
+				s.vlan = true
+				slot = &s.vlanID
+			case t.kind == CheckProtocol:
+				slot = &s.protocol
+			case t.kind == CheckSourceIP:
+				slot = &s.src
+			case t.kind == CheckDestIP:
+				slot = &s.dst
+			case t.kind == CheckSourcePort:
+				slot = &s.srcPort
+			case t.kind == CheckDestPort:
+				slot = &s.dstPort
+			default:
+				return nil, fmt.Sprintf("a PacketFilter cannot express %q", t.text)
+			}
+			if *slot != "" && *slot != t.value {
+				return nil, fmt.Sprintf("a path tests %s and %s", *slot, t.value)
+			}
+			*slot = t.value
+		}
+	}
+
+	f := &filter.PacketFilter{LinkType: link}
+	first := shapes[0]
+	for _, s := range shapes[1:] {
+		if s.vlan != first.vlan || s.vlanID != first.vlanID || s.etherType != first.etherType {
+			return nil, "the paths test different link-layer criteria"
+		}
+	}
+	f.VLAN = first.vlan
+	f.VLANID, _ = strconv.Atoi(first.vlanID)
+	switch first.etherType {
+	case "":
+	case "arp", "rarp":
+		f.Protocol = first.etherType
+	default:
+		n, _ := strconv.ParseInt(first.etherType, 0, 32)
+		f.EtherType = int(n)
+	}
+
+	protocols, hasPorts := map[string]bool{}, false
+	for _, s := range shapes {
+		protocols[s.protocol] = true
+		hasPorts = hasPorts || s.srcPort != "" || s.dstPort != ""
+	}
+	switch {
+	case len(protocols) == 1:
+		f.Protocol += first.protocol
+	case hasPorts && !protocols[""] && subset(keysOf(protocols), []string{"tcp", "udp", "sctp"}):
+		// Ports without a protocol mean any transport with ports
+	default:
+		return nil, fmt.Sprintf("the paths test different protocols (%s)", strings.Join(keysOf(protocols), ", "))
+	}
+
+	pairs := func(a, b func(shape) string) [][2]string {
+		seen := map[[2]string]bool{}
+		var list [][2]string
+		for _, s := range shapes {
+			pair := [2]string{a(s), b(s)}
+			if !seen[pair] {
+				seen[pair] = true
+				list = append(list, pair)
+			}
+		}
+		return list
+	}
+
+	addrs := pairs(func(s shape) string { return s.src }, func(s shape) string { return s.dst })
+	switch {
+	case len(addrs) == 1:
+		f.SrcIP, f.DstIP = addrs[0][0], addrs[0][1]
+	case len(addrs) == 2 && addrs[0][0] == addrs[1][1] && addrs[0][1] == addrs[1][0] && (addrs[0][0] == "" || addrs[0][1] == ""):
+		f.HostIP = addrs[0][0] + addrs[0][1]
+	case len(addrs) == 2 && addrs[0][0] == addrs[1][1] && addrs[0][1] == addrs[1][0]:
+		f.Between = []string{addrs[0][0], addrs[0][1]}
+	default:
+		return nil, "the paths test addresses in a combination a PacketFilter cannot express"
+	}
+
+	ports := pairs(func(s shape) string { return s.srcPort }, func(s shape) string { return s.dstPort })
+	srcs, dsts := map[string]bool{}, map[string]bool{}
+	for _, p := range ports {
+		srcs[p[0]], dsts[p[1]] = true, true
+	}
+	switch {
+	case len(ports) == 1:
+		f.SrcPort, _ = strconv.Atoi(ports[0][0])
+		f.DstPort, _ = strconv.Atoi(ports[0][1])
+	case len(ports) == 2 && ports[0][0] == ports[1][1] && ports[0][1] == ports[1][0] && (ports[0][0] == "" || ports[0][1] == ""):
+		f.Port, _ = strconv.Atoi(ports[0][0] + ports[0][1])
+	case len(srcs) == 1 && srcs[""]:
+		f.DstPorts = portList(dsts)
+	case len(dsts) == 1 && dsts[""]:
+		f.SrcPorts = portList(srcs)
+	default:
+		return nil, "the paths test ports in a combination a PacketFilter cannot express"
+	}
+
+	normalized, err := filter.Normalize(f)
+	if err != nil {
+		return nil, err.Error()
+	}
+	return normalized, ""
+}
+
+// keysOf returns the sorted keys of a set, with the empty string shown as
+// "none"
+func keysOf(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		if k == "" {
+			k = "none"
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// portList returns the ports of a set in order
+func portList(set map[string]bool) []int {
+	var ports []int
+	for p := range set {
+		n, _ := strconv.Atoi(p)
+		ports = append(ports, n)
+	}
+	sort.Ints(ports)
+	return ports
+}
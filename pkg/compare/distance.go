@@ -0,0 +1,53 @@
+package compare
+
+import (
+	"math"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// DivergenceThreshold is how far the score and the similarity may differ
+// before the report warns that one of them misjudges the programs
+const DivergenceThreshold = 0.5
+
+// EditDistance measures how far apart the programs are as instructions,
+// independently of the score, which weighs what they check and decide
+type EditDistance struct {
+	Operations int     // edits that turn the prototype into the reference, as bpf.Diff lists them
+	Similarity float64 // 1 less the edits per instruction of the longer program, from 0 to 1
+}
+
+// editDistance compares the programs after normalizing them: accepting
+// returns keep the same length whatever the snapshot length, and jump
+// offsets that only shift with the edits are not counted by bpf.Diff
+func editDistance(reference, prototype []*bpf.Instruction) EditDistance {
+	ops := bpf.Diff(normalizeReturns(prototype), normalizeReturns(reference))
+	longer := max(len(reference), len(prototype))
+	d := EditDistance{Operations: len(ops), Similarity: 1}
+	if longer > 0 {
+		d.Similarity = math.Max(0, 1-float64(len(ops))/float64(longer))
+	}
+	return d
+}
+
+// normalizeReturns copies a program with every accepting return of a
+// constant made the same
+func normalizeReturns(prog []*bpf.Instruction) []*bpf.Instruction {
+	normalized := make([]*bpf.Instruction, len(prog))
+	for i, inst := range prog {
+		c := *inst
+		if c.Code == bpf.OpRetK && c.K != 0 {
+			c.K = 1
+		}
+		normalized[i] = &c
+	}
+	return normalized
+}
+
+// Divergent reports whether the score and the similarity differ by
+// DivergenceThreshold or more. Equivalent programs built differently, or
+// near-identical programs whose one edit changes what they accept, can
+// explain it; otherwise the analyzer may have misread one of the programs.
+func (r *ComparisonResult) Divergent() bool {
+	return r.band != bandInconclusive && math.Abs(r.Score-r.Distance.Similarity) >= DivergenceThreshold
+}
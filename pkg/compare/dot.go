@@ -0,0 +1,121 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// DOT renders the control flow graphs of both programs in Graphviz DOT
+// format, one basic block per node and one edge per jump. A block whose
+// check has no counterpart in the other program is filled red and its
+// outgoing edges are drawn bold, unless highlight is false.
+func (r *ComparisonResult) DOT(highlight bool) (reference, prototype string) {
+	var refDiff, protoDiff map[int]bool
+	if highlight {
+		refDiff = unmatchedChecks(r.TcpdumpSemantic, r.PrototypeSemantic)
+		protoDiff = unmatchedChecks(r.PrototypeSemantic, r.TcpdumpSemantic)
+	}
+	reference = renderDOT("reference", r.TcpdumpBPF.Instructions, r.TcpdumpSemantic, refDiff)
+	prototype = renderDOT("prototype", r.PrototypeBPF.Instructions, r.PrototypeSemantic, protoDiff)
+	return reference, prototype
+}
+
+// unmatchedChecks returns the instruction indices of the checks in
+// semantics that occur a different number of times in other
+func unmatchedChecks(semantics, other []*SemanticInstruction) map[int]bool {
+	counts := make(map[string]int)
+	for _, sem := range semantics {
+		if key, ok := checkKey(sem); ok {
+			counts[key]++
+		}
+	}
+	for _, sem := range other {
+		if key, ok := checkKey(sem); ok {
+			counts[key]--
+		}
+	}
+
+	unmatched := make(map[int]bool)
+	for _, sem := range semantics {
+		if key, ok := checkKey(sem); ok && counts[key] != 0 {
+			unmatched[sem.Index] = true
+		}
+	}
+	return unmatched
+}
+
+// renderDOT writes one program's blocks and jump edges. The last
+// instruction's semantics, when it is a check, titles the block.
+func renderDOT(name string, instructions []*bpf.Instruction, semantics []*SemanticInstruction, highlight map[int]bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", name)
+	sb.WriteString("  node [shape=box fontname=\"monospace\" style=filled fillcolor=white];\n")
+	sb.WriteString("  edge [fontname=\"monospace\"];\n")
+
+	byIndex := make(map[int]*SemanticInstruction, len(semantics))
+	for _, sem := range semantics {
+		byIndex[sem.Index] = sem
+	}
+
+	g := buildCFG(instructions)
+	lines := disassemblyLines(instructions)
+	for i, b := range g.blocks {
+		last := b.end - 1
+		var label strings.Builder
+		if sem := byIndex[last]; sem != nil && sem.Predicate != "" {
+			label.WriteString(dotEscape(fmt.Sprintf("%s (%s)", sem.Type, sem.Predicate)) + "\\n")
+		}
+		for pc := b.start; pc < b.end; pc++ {
+			label.WriteString(dotEscape(lines[pc]) + "\\l")
+		}
+
+		attrs := ""
+		differs := false
+		for pc := b.start; pc < b.end; pc++ {
+			differs = differs || highlight[pc]
+		}
+		switch {
+		case differs:
+			attrs = " fillcolor=\"#ffd0d0\" penwidth=2"
+		case instructions[last].IsReturn() && instructions[last].K != 0:
+			attrs = " fillcolor=\"#d8f5d8\""
+		case instructions[last].IsReturn():
+			attrs = " fillcolor=\"#eeeeee\""
+		}
+		fmt.Fprintf(&sb, "  b%d [label=\"%s\"%s];\n", i, label.String(), attrs)
+
+		bold := ""
+		if differs {
+			bold = " penwidth=2"
+		}
+		if !isConditional(instructions[last]) {
+			for _, succ := range b.succs {
+				if differs {
+					fmt.Fprintf(&sb, "  b%d -> b%d [penwidth=2];\n", i, succ)
+				} else {
+					fmt.Fprintf(&sb, "  b%d -> b%d;\n", i, succ)
+				}
+			}
+			continue
+		}
+		// jt and jf may lead to the same block, which then has one successor
+		targets := jumpTargets(instructions[last], last)
+		for j, style := range []string{"label=\"jt\" color=darkgreen", "label=\"jf\" color=red style=dashed"} {
+			for _, succ := range b.succs {
+				if g.blocks[succ].start == targets[j] {
+					fmt.Fprintf(&sb, "  b%d -> b%d [%s%s];\n", i, succ, style, bold)
+				}
+			}
+		}
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotEscape quotes text for a DOT string
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,211 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+)
+
+// misfiltered is a class of probe packets the programs decide differently
+type misfiltered struct {
+	class   string // e.g. "IPv4/TCP" or "IPv4 fragment/UDP"
+	dropped bool   // the reference accepts and the prototype rejects
+	count   int    // packets of the class decided differently this way
+	probes  int    // probe packets of the class
+	unusual bool   // fragments, malformed, truncated, tagged or tunnelled packets
+}
+
+// Explain returns the evidence behind the verdict: a summary sentence,
+// then one line for each point it rests on. The points name the checks
+// the prototype lacks or tests at the wrong place, the classes of probe
+// packets it decides differently and which way, and how far that reaches:
+// whether ordinary packets are affected or only fragments, malformed,
+// truncated, tagged or tunnelled ones. The summary is the default key takeaway.
+func (r *ComparisonResult) Explain() []string {
+	if r.explanation == nil {
+		r.explanation = r.explain()
+	}
+	return r.explanation
+}
+
+// explain works out Explain's lines
+func (r *ComparisonResult) explain() []string {
+	var evidence []string
+	for _, failure := range r.VerifierFailures {
+		evidence = append(evidence, "The kernel would refuse the "+failure)
+	}
+	if r.Critical() {
+		evidence = append(evidence, "The prototype never checks the IP version, so it reads the headers of ARP and other non-IP frames as if they were IP")
+	}
+	for _, m := range r.OffsetMismatches {
+		evidence = append(evidence, "Wrong field: "+m)
+	}
+	if len(r.MissingInPrototype) > 0 {
+		evidence = append(evidence, "Missing from the prototype: "+strings.Join(trimAll(r.MissingInPrototype, "Missing "), "; "))
+	}
+	if len(r.Differences) > 0 {
+		evidence = append(evidence, "Repeated a different number of times: "+strings.Join(r.Differences, "; "))
+	}
+
+	groups := r.misfiltered()
+	ordinary := false
+	for _, g := range groups {
+		verb := "captures"
+		if g.dropped {
+			verb = "drops"
+		}
+		evidence = append(evidence, fmt.Sprintf("The prototype %s %d of %d %s probe packets that tcpdump %s",
+			verb, g.count, g.probes, g.class, map[bool]string{true: "captures", false: "drops"}[g.dropped]))
+		ordinary = ordinary || !g.unusual
+	}
+	if b := r.Behavior; b.Disagreements > 0 {
+		reach := "only fragments, malformed, truncated, tagged or tunnelled packets differ, so ordinary traffic is filtered alike"
+		if ordinary {
+			reach = "ordinary packets differ, so everyday traffic of those classes is misfiltered"
+		}
+		evidence = append(evidence, fmt.Sprintf("Blast radius: %d of %d probe packets (%.1f%%); %s",
+			b.Disagreements, b.Packets, 100*(1-b.Agreement()), reach))
+	}
+	if len(r.ExtraInPrototype) > 0 {
+		evidence = append(evidence, "Added by the prototype: "+strings.Join(trimAll(r.ExtraInPrototype, "Extra "), "; "))
+	}
+	if len(r.Allowed) > 0 {
+		evidence = append(evidence, fmt.Sprintf("Allowed by the score policy: %d differences", len(r.Allowed)))
+	}
+
+	return append([]string{r.summary(groups, ordinary)}, evidence...)
+}
+
+// summary states in one sentence what the evidence adds up to
+func (r *ComparisonResult) summary(groups []misfiltered, ordinary bool) string {
+	b := r.Behavior
+	switch {
+	case r.band == bandInconclusive:
+		return "Neither program has checks or returns to compare, so nothing is known about either."
+	case len(r.VerifierFailures) > 0:
+		return "The kernel would refuse to load a program, so it would capture nothing at all."
+	case r.Critical():
+		return "The prototype does not validate the IP version, so non-IP frames can match its IP checks."
+	case b.Disagreements > 0:
+		var classes []string
+		for _, g := range groups {
+			classes = append(classes, g.class)
+		}
+		advice := "fix %s before relying on it"
+		if !ordinary {
+			advice = "only unusual packets differ, so fix %s or allow it in the score policy if they do not matter"
+		}
+		return fmt.Sprintf("The prototype misfilters %s packets (%d of %d probes); "+advice+".",
+			strings.Join(dedupe(classes), ", "), b.Disagreements, b.Packets, r.culprit())
+	case len(r.MissingInPrototype) > 0 || len(r.OffsetMismatches) > 0:
+		return fmt.Sprintf("Every probe packet is decided alike, but the prototype lacks %s, which probes may not reach; check it is intended.", r.culprit())
+	case len(r.ExtraInPrototype) > 0:
+		return fmt.Sprintf("The prototype decides all %d probe packets as tcpdump does, with %d checks of its own.", b.Packets, len(r.ExtraInPrototype))
+	}
+	return fmt.Sprintf("The prototype decides all %d probe packets as tcpdump does, with the same checks.", b.Packets)
+}
+
+// culprit names the likeliest cause of a difference: the first offset
+// mismatch or missing check, or the first check repeated differently
+func (r *ComparisonResult) culprit() string {
+	switch {
+	case len(r.OffsetMismatches) > 0:
+		key, _, _ := strings.Cut(r.OffsetMismatches[0], ":")
+		return "the field " + key + " reads"
+	case len(r.MissingInPrototype) > 0:
+		return "the missing " + strings.TrimPrefix(r.MissingInPrototype[0], "Missing ")
+	case len(r.Differences) > 0:
+		key, _, _ := strings.Cut(r.Differences[0], ":")
+		return "how often it checks " + key
+	case len(r.ExtraInPrototype) > 0:
+		return "the extra " + strings.TrimPrefix(r.ExtraInPrototype[0], "Extra ")
+	}
+	return "the checks around the packets shown"
+}
+
+// misfiltered groups the probe packets the programs decide differently by
+// packet class and direction, in the order the probes were built
+func (r *ComparisonResult) misfiltered() []misfiltered {
+	if r.Behavior.Disagreements == 0 {
+		return nil
+	}
+	link := filter.LinkType(r.TcpdumpBPF.LinkType)
+	probes := make(map[string]int)
+	for _, pkt := range probePackets(append(append([]*SemanticInstruction{}, r.TcpdumpSemantic...), r.PrototypeSemantic...), link) {
+		class, _ := packetClass(pkt, link)
+		probes[class]++
+	}
+
+	var groups []misfiltered
+	index := make(map[string]int)
+	for _, pkt := range r.Behavior.Disagreeing {
+		class, unusual := packetClass(pkt, link)
+		dropped := accepts(r.TcpdumpBPF.Instructions, pkt)
+		key := fmt.Sprintf("%s/%t", class, dropped)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, misfiltered{class: class, dropped: dropped, probes: max(probes[class], 1), unusual: unusual})
+		}
+		groups[i].count++
+	}
+	return groups
+}
+
+// packetClass names the headers of a packet below the link layer, e.g.
+// "802.1Q/IPv4/TCP", "IPv4 fragment/UDP" or "IPv4/truncated TCP", and reports
+// whether it is a fragment, malformed, truncated, tagged or tunnelled
+func packetClass(pkt []byte, link filter.LinkType) (string, bool) {
+	d := packet.Decode(pkt, link)
+	var names []string
+	unusual := d.Truncated != ""
+	for _, l := range d.Layers {
+		switch {
+		case l.Name == "Ethernet" || l.Name == "Linux cooked" || l.Name == "Null" || l.Name == "Raw":
+			continue
+		case strings.HasPrefix(l.Fields, "bad "):
+			names = append(names, "malformed "+l.Name)
+			unusual = true
+			continue
+		case l.Name == "IPv4" && (strings.Contains(l.Fields, "frag offset") || strings.Contains(l.Fields, "MF")):
+			names = append(names, "IPv4 fragment")
+			unusual = true
+			continue
+		case l.Name == "802.1Q" || l.Name == "VXLAN" || l.Name == "Geneve":
+			unusual = true
+		}
+		names = append(names, l.Name)
+	}
+	if d.Truncated != "" {
+		names = append(names, "truncated "+d.Truncated)
+	}
+	if len(names) == 0 {
+		return "non-IP", unusual
+	}
+	return strings.Join(names, "/"), unusual
+}
+
+// trimAll removes a prefix from every string
+func trimAll(list []string, prefix string) []string {
+	trimmed := make([]string, len(list))
+	for i, s := range list {
+		trimmed[i] = strings.TrimPrefix(s, prefix)
+	}
+	return trimmed
+}
+
+// dedupe drops repeated strings, keeping the first of each
+func dedupe(list []string) []string {
+	seen := make(map[string]bool)
+	var kept []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,107 @@
+package compare
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// Finding rules, used as SARIF rule ids
+const (
+	RuleVerifierFailure = "verifier-failure" // the kernel would refuse to load a program
+	RuleBehavior        = "behavior"         // the programs decide probe packets differently
+	RuleOffsetMismatch  = "offset-mismatch"  // a check tests the right value at the wrong offset
+	RuleMissingCheck    = "missing-check"    // the prototype lacks a check of the reference
+	RuleCheckCount      = "check-count"      // a check is repeated a different number of times
+	RuleExtraCheck      = "extra-check"      // the prototype makes a check the reference does not
+)
+
+// Finding levels, as in SARIF
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Finding is one problem a comparison found, for tools that annotate
+// changes rather than print reports
+type Finding struct {
+	Rule    string `json:"rule" yaml:"rule"`   // one of the Rule constants
+	Level   string `json:"level" yaml:"level"` // one of the Level constants
+	Message string `json:"message" yaml:"message"`
+}
+
+// Findings lists the comparison's problems, most severe first. A missing
+// and an extra check that an offset mismatch accounts for are reported
+// only as the mismatch. Matches and instruction counts are not problems.
+func (r *ComparisonResult) Findings() []Finding {
+	var findings []Finding
+	add := func(rule, level string, messages []string) {
+		for _, m := range messages {
+			if !r.explained[m] {
+				findings = append(findings, Finding{Rule: rule, Level: level, Message: m})
+			}
+		}
+	}
+
+	add(RuleVerifierFailure, LevelError, r.VerifierFailures)
+	if b := r.Behavior; b.Disagreements > 0 {
+		message := fmt.Sprintf("%d of %d probe packets decided differently", b.Disagreements, b.Packets)
+		for _, d := range b.Examples {
+			message += fmt.Sprintf("; reference %s, prototype %s: %x", acceptText(d.Reference), acceptText(d.Prototype), d.Packet)
+		}
+		add(RuleBehavior, LevelError, []string{message})
+	}
+	add(RuleOffsetMismatch, LevelError, r.OffsetMismatches)
+	add(RuleMissingCheck, LevelError, r.MissingInPrototype)
+	add(RuleCheckCount, LevelWarning, r.Differences)
+	add(RuleExtraCheck, LevelWarning, r.ExtraInPrototype)
+	return findings
+}
+
+// findOffsetMismatches pairs a check missing from the prototype with an
+// extra one of the prototype that compares the same kind of load with the
+// same constant at another offset, such as a destination port read from
+// the source port's bytes. Both entries are then explained by the pair.
+// missing and extra map check keys to their entries.
+func findOffsetMismatches(result *ComparisonResult, missing, extra map[string]string) {
+	for _, ref := range result.TcpdumpSemantic {
+		key, ok := checkKey(ref)
+		if !ok || ref.probe == nil || missing[key] == "" || result.explained[missing[key]] {
+			continue
+		}
+		for _, proto := range result.PrototypeSemantic {
+			protoKey, ok := checkKey(proto)
+			if !ok || proto.probe == nil || extra[protoKey] == "" || result.explained[extra[protoKey]] {
+				continue
+			}
+			a, b := ref.probe, proto.probe
+			if a.value != b.value || a.field.size != b.field.size || a.field.mask != b.field.mask ||
+				(a.field.offset == b.field.offset && a.field.mode == b.field.mode) ||
+				result.TcpdumpBPF.Instructions[ref.Index].Code != result.PrototypeBPF.Instructions[proto.Index].Code {
+				continue
+			}
+			result.OffsetMismatches = append(result.OffsetMismatches,
+				fmt.Sprintf("%s: tcpdump tests %s, prototype tests %s", key, fieldText(a.field), fieldText(b.field)))
+			result.explained[missing[key]] = true
+			result.explained[extra[protoKey]] = true
+			break
+		}
+	}
+}
+
+// verifyPrograms records why the kernel would refuse either program
+func verifyPrograms(result *ComparisonResult) {
+	programs := []struct {
+		name string
+		prog []*bpf.Instruction
+	}{
+		{"tcpdump", result.TcpdumpBPF.Instructions},
+		{"prototype", result.PrototypeBPF.Instructions},
+	}
+	for _, p := range programs {
+		if err := bpf.Verify(p.prog); err != nil {
+			result.VerifierFailures = append(result.VerifierFailures, fmt.Sprintf("%s: %v", p.name, err))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FailOn is a comparison finding that fails a gate whatever the score
+type FailOn string
+
+const (
+	// FailOnMissingCritical fails when the verdict is CRITICAL: the
+	// reference validates the IP version and the prototype does not
+	FailOnMissingCritical FailOn = "missing-critical"
+
+	// FailOnAnyDiff fails on any check that is missing, extra or present
+	// a different number of times. Instruction count differences alone
+	// do not fail.
+	FailOnAnyDiff FailOn = "any-diff"
+)
+
+// ParseFailOn parses a comma-separated list of findings. The empty string
+// is an empty list.
+func ParseFailOn(s string) ([]FailOn, error) {
+	var conditions []FailOn
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch FailOn(name) {
+		case "":
+			continue
+		case FailOnMissingCritical, FailOnAnyDiff:
+			conditions = append(conditions, FailOn(name))
+		default:
+			return nil, fmt.Errorf("invalid fail-on condition '%s', must be %s or %s", name, FailOnMissingCritical, FailOnAnyDiff)
+		}
+	}
+	return conditions, nil
+}
+
+// Gate is a pass/fail policy for a comparison, as used by CI
+type Gate struct {
+	MinScore float64  // lowest passing score (0 accepts any score)
+	FailOn   []FailOn // findings that fail regardless of the score
+}
+
+// Check returns nil if the comparison passes the gate, or an error naming
+// the first reason it does not
+func (g Gate) Check(r *ComparisonResult) error {
+	if r.Score < g.MinScore {
+		return fmt.Errorf("score %.2f below minimum %.2f", r.Score, g.MinScore)
+	}
+	for _, condition := range g.FailOn {
+		switch condition {
+		case FailOnMissingCritical:
+			if r.Critical() {
+				return fmt.Errorf("prototype is missing IP validation (%s)", condition)
+			}
+		case FailOnAnyDiff:
+			if n := len(r.MissingInPrototype) + len(r.ExtraInPrototype) + len(r.Differences); n > 0 {
+				return fmt.Errorf("%d differing checks (%s)", n, condition)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package compare
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderPlain writes the comparison as ASCII key=value lines, one fact per
+// line, for CI consoles that garble the box art and for grep-based
+// assertions. Keys stay the same whatever the vocabulary; values with
+// spaces or quotes are quoted. Keys that repeat, such as missing-check,
+// list one finding each.
+func (r *ComparisonResult) RenderPlain(w io.Writer) error {
+	var sb strings.Builder
+	r.writePlainVerdict(&sb)
+	fmt.Fprintf(&sb, "band=%s\n", r.band)
+	fmt.Fprintf(&sb, "critical=%t\n", r.Critical())
+
+	c := r.Components
+	fmt.Fprintf(&sb, "score.behavior=%.2f\n", c.Behavior)
+	fmt.Fprintf(&sb, "score.safety=%.2f\n", c.Safety)
+	fmt.Fprintf(&sb, "score.checks=%.2f\n", c.Checks)
+	fmt.Fprintf(&sb, "score.size=%.2f\n", c.Size)
+	fmt.Fprintf(&sb, "edit-distance=%d\n", r.Distance.Operations)
+	fmt.Fprintf(&sb, "similarity=%.2f\n", r.Distance.Similarity)
+	fmt.Fprintf(&sb, "divergent=%t\n", r.Divergent())
+
+	fmt.Fprintf(&sb, "reference.source=%s\n", plainValue(r.TcpdumpBPF.Source))
+	fmt.Fprintf(&sb, "reference.filter=%s\n", plainValue(r.TcpdumpBPF.FilterExpr))
+	fmt.Fprintf(&sb, "reference.instructions=%d\n", len(r.TcpdumpBPF.Instructions))
+	fmt.Fprintf(&sb, "prototype.filter=%s\n", plainValue(r.PrototypeBPF.FilterExpr))
+	fmt.Fprintf(&sb, "prototype.instructions=%d\n", len(r.PrototypeBPF.Instructions))
+
+	fmt.Fprintf(&sb, "probes=%d\n", r.Behavior.Packets)
+	fmt.Fprintf(&sb, "disagreements=%d\n", r.Behavior.Disagreements)
+	for _, d := range r.Behavior.Examples {
+		fmt.Fprintf(&sb, "disagreement=\"reference %s, prototype %s: %x\"\n", acceptText(d.Reference), acceptText(d.Prototype), d.Packet)
+	}
+	explanation := r.Explain()
+	fmt.Fprintf(&sb, "explanation=%s\n", plainValue(explanation[0]))
+	for _, e := range explanation[1:] {
+		fmt.Fprintf(&sb, "evidence=%s\n", plainValue(e))
+	}
+
+	lists := []struct {
+		count, item string
+		values      []string
+	}{
+		{"matches", "match", r.Matches},
+		{"differences", "difference", r.Differences},
+		{"missing", "missing-check", r.MissingInPrototype},
+		{"extra", "extra-check", r.ExtraInPrototype},
+		{"structural", "structural-difference", r.StructuralDiffs},
+		{"offset-mismatches", "offset-mismatch", r.OffsetMismatches},
+		{"allowed", "allowed-difference", r.Allowed},
+		{"verifier-failures", "verifier-failure", r.VerifierFailures},
+	}
+	for _, l := range lists {
+		fmt.Fprintf(&sb, "%s=%d\n", l.count, len(l.values))
+		for _, v := range l.values {
+			fmt.Fprintf(&sb, "%s=%s\n", l.item, plainValue(v))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// RenderQuiet writes only the verdict and score, as RenderPlain does
+func (r *ComparisonResult) RenderQuiet(w io.Writer) error {
+	var sb strings.Builder
+	r.writePlainVerdict(&sb)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writePlainVerdict writes the verdict and score lines
+func (r *ComparisonResult) writePlainVerdict(sb *strings.Builder) {
+	fmt.Fprintf(sb, "verdict=%s\n", plainValue(r.Verdict))
+	fmt.Fprintf(sb, "score=%.2f\n", r.Score)
+}
+
+// plainValue quotes a value that would not survive as a bare ASCII word
+func plainValue(s string) string {
+	if s == "" || strings.ContainsAny(s, "\"=\\") {
+		return strconv.QuoteToASCII(s)
+	}
+	for _, c := range s {
+		if c <= ' ' || c > '~' {
+			return strconv.QuoteToASCII(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package compare
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScorePolicy weighs the parts of a comparison into its score and maps the
+// score to a verdict. Behavior outweighs the rest by default: a program
+// that decides packets differently from the reference is wrong however
+// alike the two look.
+type ScorePolicy struct {
+	Weights ScoreWeights `yaml:"weights"`
+	Bands   ScoreBands   `yaml:"bands"`
+
+	// DisagreementVerdict is the best verdict (good, partial or poor) a
+	// prototype that decides any probe packet differently can get
+	DisagreementVerdict string `yaml:"disagreement-verdict"`
+
+	// Allow lists the differences in checks that are intended, which the
+	// score and findings leave out
+	Allow []Allowance `yaml:"allow"`
+}
+
+// ScoreWeights are the relative weights of the score components. They
+// need not sum to 1; the score is their weighted mean.
+type ScoreWeights struct {
+	Behavior float64 `yaml:"behavior"` // probe packets both programs decide alike
+	Safety   float64 `yaml:"safety"`   // kinds of safety check the prototype keeps
+	Checks   float64 `yaml:"checks"`   // checks matched between the programs
+	Size     float64 `yaml:"size"`     // instruction counts
+}
+
+// ScoreComponents are the parts of a score, each from 0 to 1
+type ScoreComponents struct {
+	Behavior float64 // share of probe packets both programs decide alike
+	Safety   float64 // share of the kinds of safety check in the reference the prototype also makes
+	Checks   float64 // matched checks over matched and differing ones
+	Size     float64 // smaller instruction count over the larger one
+}
+
+// safetyChecks are the checks that keep a program from reading fields of
+// the wrong protocol or of a later fragment
+var safetyChecks = []InstructionType{CheckIP, CheckEtherType, CheckProtocol, CheckFragment, CheckVLAN}
+
+// ScoreBands are the lowest scores of each verdict; lower scores are poor
+type ScoreBands struct {
+	Excellent float64 `yaml:"excellent"`
+	Good      float64 `yaml:"good"`
+	Partial   float64 `yaml:"partial"`
+}
+
+// Verdict bands, best first
+const (
+	bandExcellent    = "excellent"
+	bandGood         = "good"
+	bandPartial      = "partial"
+	bandPoor         = "poor"
+	bandInconclusive = "inconclusive"
+)
+
+// bandRank orders the bands a score can fall in, best first
+var bandRank = map[string]int{bandExcellent: 0, bandGood: 1, bandPartial: 2, bandPoor: 3}
+
+// DefaultScorePolicy returns the built-in weights and bands
+func DefaultScorePolicy() *ScorePolicy {
+	return &ScorePolicy{
+		Weights: ScoreWeights{
+			Behavior: 0.5,
+			Safety:   0.25,
+			Checks:   0.15,
+			Size:     0.1,
+		},
+		Bands: ScoreBands{
+			Excellent: 0.8,
+			Good:      0.6,
+			Partial:   0.4,
+		},
+		DisagreementVerdict: bandPartial,
+	}
+}
+
+// LoadScorePolicy reads a YAML score policy. Entries missing from the file
+// keep their defaults.
+func LoadScorePolicy(path string) (*ScorePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read score policy: %w", err)
+	}
+
+	policy := DefaultScorePolicy()
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse score policy %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid score policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Validate checks that weights are non-negative with a positive total,
+// that the bands are ordered within 0-1 and that allowances are valid
+func (p *ScorePolicy) Validate() error {
+	w := p.Weights
+	names := []string{"behavior", "safety", "checks", "size"}
+	for i, v := range []float64{w.Behavior, w.Safety, w.Checks, w.Size} {
+		if v < 0 {
+			return fmt.Errorf("weights.%s is negative", names[i])
+		}
+	}
+	if w.Behavior+w.Safety+w.Checks+w.Size == 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+	b := p.Bands
+	if !(0 <= b.Partial && b.Partial <= b.Good && b.Good <= b.Excellent && b.Excellent <= 1) {
+		return fmt.Errorf("bands must satisfy 0 <= partial <= good <= excellent <= 1")
+	}
+	if rank, ok := bandRank[p.DisagreementVerdict]; !ok || rank == 0 {
+		return fmt.Errorf("invalid disagreement-verdict '%s', must be good, partial or poor", p.DisagreementVerdict)
+	}
+	for i, a := range p.Allow {
+		if err := a.validate(); err != nil {
+			return fmt.Errorf("allow entry %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// score is the weighted mean of the components
+func (p *ScorePolicy) score(c ScoreComponents) float64 {
+	w := p.Weights
+	total := w.Behavior*c.Behavior + w.Safety*c.Safety + w.Checks*c.Checks + w.Size*c.Size
+	return total / (w.Behavior + w.Safety + w.Checks + w.Size)
+}
+
+// band returns the verdict band of a score
+func (p *ScorePolicy) band(score float64) string {
+	switch {
+	case score >= p.Bands.Excellent:
+		return bandExcellent
+	case score >= p.Bands.Good:
+		return bandGood
+	case score >= p.Bands.Partial:
+		return bandPartial
+	}
+	return bandPoor
+}
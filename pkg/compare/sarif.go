@@ -0,0 +1,154 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SARIFToolName names the validator in SARIF logs
+const SARIFToolName = "antrea-bpf-validator"
+
+// SARIFTarget is a comparison and the filter definition it was made for.
+// Code review tools anchor findings to the file and line.
+type SARIFTarget struct {
+	Name   string            // filter name, e.g. a batch entry
+	URI    string            // file defining the filter, relative to the repository root, or "" if none
+	Line   int               // line of the definition in URI, or 0
+	Result *ComparisonResult // nil if the comparison could not be made
+	Err    error             // why it could not be made
+}
+
+// RuleComparisonError marks filters whose programs could not be generated
+// or compared
+const RuleComparisonError = "comparison-error"
+
+// sarifRules describes every rule, in the order logs list them
+var sarifRules = []struct{ id, level, text string }{
+	{RuleVerifierFailure, LevelError, "The kernel's classic BPF checker would refuse to load a program"},
+	{RuleBehavior, LevelError, "The programs accept or reject some probe packets differently"},
+	{RuleOffsetMismatch, LevelError, "The prototype tests the right value at the wrong packet offset"},
+	{RuleMissingCheck, LevelError, "The prototype lacks a check the reference makes"},
+	{RuleCheckCount, LevelWarning, "A check is made a different number of times"},
+	{RuleExtraCheck, LevelWarning, "The prototype makes a check the reference does not"},
+	{RuleComparisonError, LevelError, "The programs could not be generated or compared"},
+}
+
+// sarifLog and the types below are the subset of SARIF 2.1.0 written
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// WriteSARIF writes the findings of the comparisons as a SARIF 2.1.0 log
+// with one run. Each result names its filter as a logical location, and
+// as a physical one when the target has a URI. Messages start with the
+// filter name so that annotations on the same file stay apart.
+func WriteSARIF(w io.Writer, targets []SARIFTarget) error {
+	driver := sarifDriver{Name: SARIFToolName, Rules: make([]sarifRule, 0, len(sarifRules))}
+	for _, r := range sarifRules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   r.id,
+			ShortDescription:     sarifMessage{r.text},
+			DefaultConfiguration: sarifConfiguration{r.level},
+		})
+	}
+
+	results := make([]sarifResult, 0)
+	for _, t := range targets {
+		location := sarifLocation{LogicalLocations: []sarifLogicalLocation{{Name: t.Name, Kind: "object"}}}
+		if t.URI != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{t.URI}}
+			if t.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{t.Line}
+			}
+		}
+
+		var findings []Finding
+		switch {
+		case t.Err != nil:
+			findings = []Finding{{RuleComparisonError, LevelError, t.Err.Error()}}
+		case t.Result != nil:
+			findings = t.Result.Findings()
+		}
+		for _, f := range findings {
+			results = append(results, sarifResult{
+				RuleID:    f.Rule,
+				Level:     f.Level,
+				Message:   sarifMessage{fmt.Sprintf("%s: %s", t.Name, f.Message)},
+				Locations: []sarifLocation{location},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{driver}, Results: results}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
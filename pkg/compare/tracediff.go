@@ -0,0 +1,234 @@
+package compare
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// TraceDiff aligns the checks both programs make on one packet, to find
+// where their paths part and which header field parts them
+type TraceDiff struct {
+	Packet    []byte
+	Rows      []TraceRow
+	Reference *vm.Result
+	Prototype *vm.Result
+
+	// Divergence is the index in Rows of the first check the programs do
+	// not make with the same outcome, or -1 when their paths agree
+	Divergence int
+}
+
+// TraceRow is a check or return in the path of either program, or both
+type TraceRow struct {
+	Check     string      // check key, e.g. "Check Dest Port (80)"
+	Field     string      // header field tested, "" for a return
+	Reference *TraceCheck // nil when the reference does not reach it
+	Prototype *TraceCheck // nil when the prototype does not reach it
+}
+
+// TraceCheck is a check or return as one program executed it
+type TraceCheck struct {
+	Index int    // instruction index
+	Value uint32 // A: the field tested, or the length returned
+	Held  bool   // the condition held; for a return, the packet was accepted
+
+	sem *SemanticInstruction
+}
+
+// agrees reports whether both programs made the row's check with the same
+// outcome
+func (row TraceRow) agrees() bool {
+	return row.Reference != nil && row.Prototype != nil && row.Reference.Held == row.Prototype.Held
+}
+
+// DiffTrace runs a packet through both programs and aligns the checks
+// and returns each executes by check key, the header field and the value
+// it tests for, so that checks made in another order or with different
+// instructions still line up
+func (r *ComparisonResult) DiffTrace(pkt []byte) (*TraceDiff, error) {
+	d := &TraceDiff{Packet: pkt, Divergence: -1}
+	reference, referencePath, err := tracePath(r.TcpdumpBPF.Instructions, r.TcpdumpSemantic, pkt)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %w", err)
+	}
+	prototype, prototypePath, err := tracePath(r.PrototypeBPF.Instructions, r.PrototypeSemantic, pkt)
+	if err != nil {
+		return nil, fmt.Errorf("prototype: %w", err)
+	}
+	d.Reference, d.Prototype = reference, prototype
+
+	// Longest common subsequence of the check keys
+	n, m := len(referencePath), len(prototypePath)
+	common := make([][]int, n+1)
+	for i := range common {
+		common[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if traceKey(referencePath[i]) == traceKey(prototypePath[j]) {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && traceKey(referencePath[i]) == traceKey(prototypePath[j]):
+			d.Rows = append(d.Rows, traceRow(referencePath[i], prototypePath[j]))
+			i, j = i+1, j+1
+		case j == m || (i < n && common[i+1][j] >= common[i][j+1]):
+			d.Rows = append(d.Rows, traceRow(referencePath[i], nil))
+			i++
+		default:
+			d.Rows = append(d.Rows, traceRow(nil, prototypePath[j]))
+			j++
+		}
+	}
+
+	for k, row := range d.Rows {
+		if !row.agrees() {
+			d.Divergence = k
+			break
+		}
+	}
+	return d, nil
+}
+
+// tracePath runs a program over the packet and returns the checks and
+// returns it executed
+func tracePath(prog []*bpf.Instruction, semantics []*SemanticInstruction, pkt []byte) (*vm.Result, []*TraceCheck, error) {
+	byIndex := make(map[int]*SemanticInstruction, len(semantics))
+	for _, sem := range semantics {
+		byIndex[sem.Index] = sem
+	}
+	result, steps, err := vm.Steps(prog, pkt)
+	if err != nil {
+		return nil, nil, err
+	}
+	var path []*TraceCheck
+	for _, s := range steps {
+		sem := byIndex[s.PC]
+		if sem == nil {
+			continue
+		}
+		if _, ok := checkKey(sem); !ok {
+			continue
+		}
+		check := &TraceCheck{Index: s.PC, Value: s.A, Held: s.Taken, sem: sem}
+		if prog[s.PC].IsReturn() {
+			check.Value, check.Held = result.Length, result.Accepted
+		}
+		path = append(path, check)
+	}
+	return result, path, nil
+}
+
+// traceKey is the key checks are aligned by
+func traceKey(c *TraceCheck) string {
+	key, _ := checkKey(c.sem)
+	return key
+}
+
+// traceRow pairs the checks of both programs, either of which may be nil
+func traceRow(reference, prototype *TraceCheck) TraceRow {
+	c := reference
+	if c == nil {
+		c = prototype
+	}
+	return TraceRow{Check: traceKey(c), Field: c.sem.Field, Reference: reference, Prototype: prototype}
+}
+
+// valueText formats the value a check found in the packet
+func (c *TraceCheck) valueText() string {
+	switch c.sem.Type {
+	case CheckProtocol:
+		return protocolName(c.Value)
+	case CheckSourceIP, CheckDestIP:
+		if !strings.HasPrefix(c.sem.Predicate, "0x") {
+			return addressText(c.Value, 0xffffffff)
+		}
+	case CheckSourcePort, CheckDestPort, CheckVLANID:
+		return fmt.Sprintf("%d", c.Value)
+	}
+	return fmt.Sprintf("0x%x", c.Value)
+}
+
+// outcome describes how a program executed a check
+func (c *TraceCheck) outcome() string {
+	if c == nil {
+		return "-"
+	}
+	switch {
+	case c.sem.Type == Accept || c.sem.Type == Reject:
+		return fmt.Sprintf("(%03d) ret %d", c.Index, c.Value)
+	case c.Held:
+		return fmt.Sprintf("(%03d) holds", c.Index)
+	}
+	return fmt.Sprintf("(%03d) fails", c.Index)
+}
+
+// Summary explains the first divergence, or states that the paths agree
+func (d *TraceDiff) Summary() string {
+	if d.Divergence < 0 {
+		return fmt.Sprintf("Paths agree: both programs return %d", d.Reference.Length)
+	}
+	row := d.Rows[d.Divergence]
+	verdicts := "the verdicts agree"
+	if d.Reference.Length != d.Prototype.Length {
+		verdicts = fmt.Sprintf("the reference returns %d and the prototype %d", d.Reference.Length, d.Prototype.Length)
+	}
+	if row.Field == "" {
+		return fmt.Sprintf("Paths part at the return: %s", verdicts)
+	}
+
+	var what string
+	switch {
+	case row.Prototype == nil:
+		what = fmt.Sprintf("only the reference makes it, and it %s", heldText(row.Reference.Held))
+	case row.Reference == nil:
+		what = fmt.Sprintf("only the prototype makes it, and it %s", heldText(row.Prototype.Held))
+	default:
+		what = fmt.Sprintf("it %s in the reference but %s in the prototype", heldText(row.Reference.Held), heldText(row.Prototype.Held))
+	}
+	found := row.Reference
+	if found == nil {
+		found = row.Prototype
+	}
+	return fmt.Sprintf("Paths part at %s: %s; the packet's %s is %s, and %s",
+		row.Check, what, row.Field, found.valueText(), verdicts)
+}
+
+// heldText words the outcome of a check
+func heldText(held bool) string {
+	if held {
+		return "holds"
+	}
+	return "fails"
+}
+
+// Render writes the aligned checks side by side, marking the first
+// divergence with ">", followed by the summary
+func (d *TraceDiff) Render(w io.Writer) error {
+	width := len("CHECK")
+	for _, row := range d.Rows {
+		width = max(width, len(row.Check))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  %-*s  %-16s  %s\n", width, "CHECK", "REFERENCE", "PROTOTYPE")
+	for k, row := range d.Rows {
+		marker := " "
+		if k == d.Divergence {
+			marker = ">"
+		}
+		fmt.Fprintf(&sb, "%s %-*s  %-16s  %s\n", marker, width, row.Check, row.Reference.outcome(), row.Prototype.outcome())
+	}
+	sb.WriteString(d.Summary() + "\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,187 @@
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Vocabulary holds every user-facing verdict and report string. Each entry
+// is a text/template evaluated against ReportData, so integrators can rename
+// verdicts (e.g. PASS/FAIL) or localize reports without changing code.
+type Vocabulary struct {
+	Verdicts  VerdictTerms  `yaml:"verdicts"`
+	Takeaways TakeawayTerms `yaml:"takeaways"`
+	Labels    LabelTerms    `yaml:"labels"`
+}
+
+// VerdictTerms are the verdict templates by score band
+type VerdictTerms struct {
+	Excellent    string `yaml:"excellent"`
+	Good         string `yaml:"good"`
+	Partial      string `yaml:"partial"`
+	Poor         string `yaml:"poor"`
+	Inconclusive string `yaml:"inconclusive"`
+	Critical     string `yaml:"critical"` // wraps .Verdict when IP validation is missing
+}
+
+// TakeawayTerms are the key takeaway templates by score band. The
+// defaults are the generated .Explanation for every band.
+type TakeawayTerms struct {
+	Excellent string `yaml:"excellent"`
+	Good      string `yaml:"good"`
+	Partial   string `yaml:"partial"`
+	Poor      string `yaml:"poor"`
+}
+
+// LabelTerms are the headings and labels of the comparison report
+type LabelTerms struct {
+	Title            string `yaml:"title"`
+	Reference        string `yaml:"reference"`
+	Prototype        string `yaml:"prototype"`
+	KeyDifferences   string `yaml:"key-differences"`
+	NoDifferences    string `yaml:"no-differences"`
+	Score            string `yaml:"score"`
+	Verdict          string `yaml:"verdict"`
+	QuickStats       string `yaml:"quick-stats"`
+	KeyTakeaway      string `yaml:"key-takeaway"`
+	ComparisonResult string `yaml:"comparison-result"`
+	SourceMap        string `yaml:"source-map"`
+	ScoreBreakdown   string `yaml:"score-breakdown"`
+	EditDistance     string `yaml:"edit-distance"`
+}
+
+// ReportData is the data available to vocabulary templates
+type ReportData struct {
+	Score        float64 // 0.0 to 1.0
+	Score10      float64 // score on the 0-10 display scale
+	Verdict      string  // verdict text (for the critical wrapper and labels)
+	Matches      int
+	Issues       int
+	Enhancements int
+
+	Components    ScoreComponents // parts of the score (.Components.Behavior, .Safety, .Checks, .Size)
+	Probes        int             // probe packets run through both programs
+	Disagreements int             // probe packets the programs decide differently
+	Explanation   string          // what the evidence adds up to, citing the checks and packets that differ
+	EditDistance  int             // instruction edits that turn the prototype into the reference
+	Similarity    float64         // 1 less the edits per instruction of the longer program
+}
+
+// DefaultVocabulary returns the built-in English vocabulary
+func DefaultVocabulary() *Vocabulary {
+	return &Vocabulary{
+		Verdicts: VerdictTerms{
+			Excellent:    "EXCELLENT MATCH: Prototype closely matches tcpdump behavior",
+			Good:         "GOOD MATCH: Prototype implements core functionality with some differences",
+			Partial:      "PARTIAL MATCH: Prototype covers some functionality but has significant gaps",
+			Poor:         "POOR MATCH: Prototype differs significantly from tcpdump approach",
+			Inconclusive: "INCONCLUSIVE: No comparable instructions found",
+			Critical:     "CRITICAL ISSUE: {{.Verdict}} (Missing IP validation)",
+		},
+		Takeaways: TakeawayTerms{
+			Excellent: "{{.Explanation}}",
+			Good:      "{{.Explanation}}",
+			Partial:   "{{.Explanation}}",
+			Poor:      "{{.Explanation}}",
+		},
+		Labels: LabelTerms{
+			Title:            "BPF VALIDATION COMPARISON",
+			Reference:        "TCPDUMP REFERENCE",
+			Prototype:        "ANTREA PROTOTYPE",
+			KeyDifferences:   "KEY DIFFERENCES",
+			NoDifferences:    "No significant differences found",
+			Score:            "SCORE: {{printf \"%.1f\" .Score10}}/10",
+			Verdict:          "VERDICT: {{.Verdict}}",
+			QuickStats:       "QUICK STATS: ✓ {{.Matches}} matches  ⚠ {{.Issues}} issues  + {{.Enhancements}} enhancements",
+			KeyTakeaway:      "KEY TAKEAWAY: ",
+			ComparisonResult: "Comparison complete: {{.Verdict}} (Score: {{printf \"%.2f\" .Score}})",
+			SourceMap:        "PROTOTYPE SOURCE MAP",
+			ScoreBreakdown: "SCORE BREAKDOWN: behavior {{printf \"%.2f\" .Components.Behavior}} ({{.Disagreements}} of {{.Probes}} probe packets differ), " +
+				"safety {{printf \"%.2f\" .Components.Safety}}, checks {{printf \"%.2f\" .Components.Checks}}, size {{printf \"%.2f\" .Components.Size}}",
+			EditDistance: "EDIT DISTANCE: {{.EditDistance}}, similarity {{printf \"%.2f\" .Similarity}}",
+		},
+	}
+}
+
+// LoadVocabulary reads a YAML vocabulary file. Entries missing from the file
+// keep their English defaults.
+func LoadVocabulary(path string) (*Vocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+
+	vocab := DefaultVocabulary()
+	if err := yaml.Unmarshal(data, vocab); err != nil {
+		return nil, fmt.Errorf("failed to parse vocabulary %s: %w", path, err)
+	}
+	if err := vocab.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vocabulary %s: %w", path, err)
+	}
+	return vocab, nil
+}
+
+// Validate checks that every entry is a well-formed template
+func (v *Vocabulary) Validate() error {
+	entries := map[string]string{
+		"verdicts.excellent":       v.Verdicts.Excellent,
+		"verdicts.good":            v.Verdicts.Good,
+		"verdicts.partial":         v.Verdicts.Partial,
+		"verdicts.poor":            v.Verdicts.Poor,
+		"verdicts.inconclusive":    v.Verdicts.Inconclusive,
+		"verdicts.critical":        v.Verdicts.Critical,
+		"takeaways.excellent":      v.Takeaways.Excellent,
+		"takeaways.good":           v.Takeaways.Good,
+		"takeaways.partial":        v.Takeaways.Partial,
+		"takeaways.poor":           v.Takeaways.Poor,
+		"labels.title":             v.Labels.Title,
+		"labels.reference":         v.Labels.Reference,
+		"labels.prototype":         v.Labels.Prototype,
+		"labels.key-differences":   v.Labels.KeyDifferences,
+		"labels.no-differences":    v.Labels.NoDifferences,
+		"labels.score":             v.Labels.Score,
+		"labels.verdict":           v.Labels.Verdict,
+		"labels.quick-stats":       v.Labels.QuickStats,
+		"labels.key-takeaway":      v.Labels.KeyTakeaway,
+		"labels.comparison-result": v.Labels.ComparisonResult,
+		"labels.source-map":        v.Labels.SourceMap,
+		"labels.score-breakdown":   v.Labels.ScoreBreakdown,
+		"labels.edit-distance":     v.Labels.EditDistance,
+	}
+	for name, text := range entries {
+		if _, err := template.New(name).Parse(text); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if _, err := expand(text, &ReportData{}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// render expands a vocabulary template. Templates are validated on load, so
+// a failure here falls back to the raw text rather than aborting the report.
+func render(text string, data *ReportData) string {
+	out, err := expand(text, data)
+	if err != nil {
+		return text
+	}
+	return out
+}
+
+// expand executes a single template against the report data
+func expand(text string, data *ReportData) (string, error) {
+	tmpl, err := template.New("vocabulary").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
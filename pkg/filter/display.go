@@ -0,0 +1,134 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToDisplayFilter converts the filter to a Wireshark display filter, for
+// looking at a capture in Wireshark with the same criteria, e.g.
+// "ip.src == 10.0.0.0/24 && tcp.dstport == 80". Wireshark has no
+// link type to select, so the link layer decides only where the EtherType
+// of a link-layer filter is read. Tunnel filters pick the outer or inner
+// headers with layer operators (ip.src#1, ip.src#2), which need Wireshark
+// 4.0; an inner UDP header is the second, an inner TCP header the first.
+// Like tcpdump, Wireshark also matches the headers quoted in an ICMP
+// error.
+func (f *PacketFilter) ToDisplayFilter() string {
+	var parts []string
+
+	if f.VLANID != 0 {
+		parts = append(parts, fmt.Sprintf("vlan.id == %d", f.VLANID))
+	} else if f.VLAN {
+		parts = append(parts, "vlan")
+	}
+
+	// Outer headers come first in the packet, inner ones second
+	layer := ""
+	if f.Tunnel != "" {
+		parts = append(parts, string(f.Tunnel))
+		if f.VNI != 0 {
+			parts = append(parts, fmt.Sprintf("%s.vni == %d", f.Tunnel, f.VNI))
+		}
+		layer = "#1"
+		if f.Inner {
+			layer = "#2"
+		}
+	}
+
+	if proto := f.EtherProto(); proto != 0 {
+		parts = append(parts, fmt.Sprintf("%s == 0x%04x", f.etherTypeField(), proto))
+		return strings.Join(parts, " && ")
+	}
+
+	// Ports name their transport, so a port check implies the protocol
+	transports := []string{"tcp", "udp", "sctp"}
+	if name, ok := displayTransports[f.IPProtocol()]; ok {
+		transports = []string{name}
+	}
+	switch n := f.IPProtocol(); {
+	case n < 0 || (f.Tunnel != "" && !f.Inner):
+		// No protocol, or the UDP the tunnel implies
+	case f.Inner || f.HasProtocolNumber():
+		parts = append(parts, fmt.Sprintf("ip.proto%s == %d", layer, n))
+	case n == 1 || !f.HasPorts():
+		parts = append(parts, f.Protocol)
+	}
+
+	if f.SrcIP != "" {
+		parts = append(parts, addressField(f.SrcIP, "src", layer))
+	}
+	if f.DstIP != "" {
+		parts = append(parts, addressField(f.DstIP, "dst", layer))
+	}
+	if f.HostIP != "" {
+		parts = append(parts, addressField(f.HostIP, "addr", layer))
+	}
+	if len(f.Between) == 2 {
+		a, b := f.Between[0], f.Between[1]
+		parts = append(parts, fmt.Sprintf("((%s && %s) || (%s && %s))",
+			addressField(a, "src", layer), addressField(b, "dst", layer), addressField(b, "src", layer), addressField(a, "dst", layer)))
+	}
+
+	if ports := f.SrcPortList(); len(ports) > 0 {
+		parts = append(parts, portFields(transports, "srcport", layer, ports))
+	}
+	if ports := f.DstPortList(); len(ports) > 0 {
+		parts = append(parts, portFields(transports, "dstport", layer, ports))
+	}
+	if f.Port != 0 {
+		parts = append(parts, portFields(transports, "port", layer, []int{f.Port}))
+	}
+
+	return strings.Join(parts, " && ")
+}
+
+// displayTransports names the Wireshark protocols that have ports
+var displayTransports = map[int]string{6: "tcp", 17: "udp", 132: "sctp"}
+
+// etherTypeField returns the field holding the EtherType of the frame:
+// the one after the 802.1Q tag, or the cooked header's protocol
+func (f *PacketFilter) etherTypeField() string {
+	switch {
+	case f.VLAN:
+		return "vlan.etype"
+	case f.LinkType == LinkLinuxSLL:
+		return "sll.etype"
+	}
+	return "eth.type"
+}
+
+// addressField compares an IPv4 or IPv6 address field (src, dst or addr)
+// with an address or network
+func addressField(addr, field, layer string) string {
+	family := "ip"
+	if !isIPv4(addr) {
+		family = "ipv6"
+	}
+	if strings.Contains(addr, "/") {
+		addr = canonicalNet(addr)
+	}
+	return fmt.Sprintf("%s.%s%s == %s", family, field, layer, addr)
+}
+
+// portFields compares a port field (srcport, dstport or port) of each
+// transport with any of the ports. Only UDP has a header in the outer
+// layer of a tunnel, so only UDP fields take the layer.
+func portFields(transports []string, field, layer string, ports []int) string {
+	value := fmt.Sprintf("== %d", ports[0])
+	if len(ports) > 1 {
+		value = "in {" + joinPorts(ports, " ") + "}"
+	}
+	clauses := make([]string, len(transports))
+	for i, t := range transports {
+		suffix := ""
+		if t == "udp" {
+			suffix = layer
+		}
+		clauses[i] = fmt.Sprintf("%s.%s%s %s", t, field, suffix, value)
+	}
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return "(" + strings.Join(clauses, " || ") + ")"
+}
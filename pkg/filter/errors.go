@@ -0,0 +1,24 @@
+package filter
+
+import "errors"
+
+// ErrInvalidFilter is wrapped by the errors of Validate, Normalize and
+// ParseExpr, so that callers can tell a filter no generator accepts from a
+// failure to run one
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// invalidError marks an error as ErrInvalidFilter, keeping its message
+type invalidError struct {
+	err error
+}
+
+func (e *invalidError) Error() string   { return e.err.Error() }
+func (e *invalidError) Unwrap() []error { return []error{ErrInvalidFilter, e.err} }
+
+// invalid wraps err as ErrInvalidFilter unless it already is one
+func invalid(err error) error {
+	if err == nil || errors.Is(err, ErrInvalidFilter) {
+		return err
+	}
+	return &invalidError{err}
+}
@@ -0,0 +1,559 @@
+package filter
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseExpr converts a tcpdump (pcap-filter) expression into a filter. It
+// accepts the subset the filter model can represent: a conjunction of
+// protocol (tcp, udp, icmp, arp, rarp), "ip proto", "ether proto", host, net, port and
+// vlan primitives with an optional src or dst qualifier, "proto port" shorthands such as
+// "tcp dst port 80", a geneve or vxlan primitive, and three forms of alternatives:
+//
+//	dst port 80 or dst port 443                          (port list)
+//	src host 10.0.0.1 or dst host 10.0.0.1               (either direction)
+//	(src net A and dst net B) or (src net B and dst net A)  (between)
+//
+// As in tcpdump, "and" and "or" bind equally from left to right, and an id
+// without a keyword reuses the previous qualifiers, so "dst port 80 or 443"
+// is a port list. Criteria after "geneve" or "vxlan" apply to the inner
+// packet, so they may be on either side of it but not both. Every
+// expression produced by ToTcpdumpFilter parses back
+// to the filter it came from. The result is validated; errors wrap
+// ErrInvalidFilter.
+func ParseExpr(expr string) (*PacketFilter, error) {
+	p := &exprParser{tokens: tokenizeExpr(expr)}
+	if len(p.tokens) == 0 {
+		return nil, invalid(fmt.Errorf("empty expression"))
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, invalid(err)
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, invalid(fmt.Errorf("unexpected '%s'", tok))
+	}
+
+	f := &PacketFilter{}
+	for _, term := range node.flatten("and") {
+		if err := f.applyTerm(term); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// primitive is one qualified pcap-filter primitive such as "src net
+// 10.0.0.0/8". Kind is proto, ether, host, net, port, vlan, geneve or vxlan; dir is src, dst or
+// empty for either direction. Proto qualifies a port, as in "tcp port 80".
+type primitive struct {
+	proto string
+	dir   string
+	kind  string
+	value string
+}
+
+// String returns the primitive in tcpdump syntax
+func (p primitive) String() string {
+	if p.kind == "ether" {
+		return "ether proto " + p.value
+	}
+	return strings.Join(strings.Fields(p.proto+" "+p.dir+" "+p.kind+" "+p.value), " ")
+}
+
+// exprNode is a node of the parsed expression: an "and" or "or" of its
+// children, or a primitive when op is empty
+type exprNode struct {
+	op       string
+	children []*exprNode
+	prim     primitive
+}
+
+// flatten returns the operands of a chain of op nodes, left to right
+func (n *exprNode) flatten(op string) []*exprNode {
+	if n.op != op {
+		return []*exprNode{n}
+	}
+	var terms []*exprNode
+	for _, c := range n.children {
+		terms = append(terms, c.flatten(op)...)
+	}
+	return terms
+}
+
+// String returns the node in tcpdump syntax
+func (n *exprNode) String() string {
+	if n.op == "" {
+		return n.prim.String()
+	}
+	parts := make([]string, len(n.children))
+	for i, c := range n.children {
+		parts[i] = c.String()
+		if c.op != "" {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " "+n.op+" ")
+}
+
+// tokenizeExpr splits an expression into words, parentheses and the
+// symbolic operators &&, || and !
+func tokenizeExpr(expr string) []string {
+	var tokens []string
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == '!':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\n()!&|", rune(expr[i])) {
+				i++
+			}
+			if i == start {
+				// A lone '&' or '|'
+				i++
+			}
+			tokens = append(tokens, strings.ToLower(expr[start:i]))
+		}
+	}
+	return tokens
+}
+
+// exprParser is a recursive-descent parser over the tokens of an expression
+type exprParser struct {
+	tokens []string
+	pos    int
+	last   primitive // qualifiers reused by a bare id
+}
+
+// peek returns the next token, or "" at the end
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// next consumes and returns the next token, or "" at the end
+func (p *exprParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.pos++
+	}
+	return tok
+}
+
+// parseExpr parses terms joined by "and" and "or", which bind equally
+// and associate to the left
+func (p *exprParser) parseExpr() (*exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch p.peek() {
+		case "and", "&&":
+			op = "and"
+		case "or", "||":
+			op = "or"
+		default:
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprNode{op: op, children: []*exprNode{left, right}}
+	}
+}
+
+// parseTerm parses a parenthesized expression or a primitive
+func (p *exprParser) parseTerm() (*exprNode, error) {
+	switch tok := p.next(); tok {
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case "(":
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return node, nil
+	case "not", "!":
+		return nil, fmt.Errorf("negation is not supported")
+	case "tcp", "udp", "icmp":
+		// "tcp dst port 80" is one primitive, and a later bare id keeps
+		// the protocol: "tcp dst port 80 or 443"
+		if next := p.peek(); next == "src" || next == "dst" || next == "port" {
+			port, err := p.parsePrimitive()
+			if err != nil {
+				return nil, err
+			}
+			if port.prim.kind != "port" {
+				return nil, fmt.Errorf("'%s' may only qualify a port, got '%s'", tok, port.prim)
+			}
+			port.prim.proto = tok
+			p.last.proto = tok
+			return port, nil
+		}
+		return &exprNode{prim: primitive{kind: "proto", value: tok}}, nil
+	case "arp", "rarp":
+		return &exprNode{prim: primitive{kind: "proto", value: tok}}, nil
+	case "ether":
+		return p.parseEtherProto()
+	case "ip":
+		return p.parseIPProto()
+	case "vlan", "geneve", "vxlan":
+		// The VLAN ID or VNI is optional
+		prim := primitive{kind: tok}
+		if _, err := strconv.Atoi(p.peek()); err == nil {
+			prim.value = p.next()
+		}
+		return &exprNode{prim: prim}, nil
+	default:
+		p.pos--
+		return p.parsePrimitive()
+	}
+}
+
+// parseEtherProto parses the rest of "ether proto id", where id is an
+// EtherType number or the name of a protocol the filter model has
+func (p *exprParser) parseEtherProto() (*exprNode, error) {
+	if p.next() != "proto" {
+		return nil, fmt.Errorf("only 'ether proto' is supported")
+	}
+	// Protocol names may be escaped, as in "ether proto \arp"
+	id := strings.TrimPrefix(p.next(), "\\")
+	switch id {
+	case "":
+		return nil, fmt.Errorf("missing value after 'ether proto'")
+	case "arp", "rarp":
+		return &exprNode{prim: primitive{kind: "proto", value: id}}, nil
+	}
+	etherType, err := strconv.ParseUint(id, 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EtherType '%s', must be a number or arp or rarp", id)
+	}
+	return &exprNode{prim: primitive{kind: "ether", value: fmt.Sprintf("0x%04x", etherType)}}, nil
+}
+
+// parseIPProto parses the rest of "ip proto id", where id is an IP
+// protocol number or icmp, tcp or udp. Only icmp keeps its name: "tcp"
+// and "udp" also match IPv6, "ip proto" does not.
+func (p *exprParser) parseIPProto() (*exprNode, error) {
+	if p.next() != "proto" {
+		return nil, fmt.Errorf("only 'ip proto' is supported")
+	}
+	id := strings.TrimPrefix(p.next(), "\\")
+	switch id {
+	case "":
+		return nil, fmt.Errorf("missing value after 'ip proto'")
+	case "icmp":
+		return &exprNode{prim: primitive{kind: "proto", value: id}}, nil
+	case "tcp":
+		id = "6"
+	case "udp":
+		id = "17"
+	}
+	protocol, err := strconv.ParseUint(id, 0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP protocol '%s', must be a number 0-255 or icmp, tcp or udp", id)
+	}
+	return &exprNode{prim: primitive{kind: "proto", value: strconv.Itoa(int(protocol))}}, nil
+}
+
+// parsePrimitive parses "[src|dst|src or dst] [host|net|port] id". With no
+// qualifier at all, the id takes the qualifiers of the previous primitive.
+func (p *exprParser) parsePrimitive() (*exprNode, error) {
+	prim := primitive{}
+	qualified := false
+
+	if tok := p.peek(); tok == "src" || tok == "dst" {
+		prim.dir = p.next()
+		qualified = true
+		// "src or dst host X" is "host X"; "src and dst" is not representable
+		if op := p.peek(); (op == "or" || op == "and") && p.pos+1 < len(p.tokens) {
+			if other := p.tokens[p.pos+1]; other == "src" || other == "dst" {
+				if op == "and" {
+					return nil, fmt.Errorf("'src and dst' is not supported")
+				}
+				p.pos += 2
+				prim.dir = ""
+			}
+		}
+	}
+
+	switch tok := p.peek(); tok {
+	case "host", "net", "port":
+		prim.kind = p.next()
+		qualified = true
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	id := p.next()
+	switch {
+	case id == "" || id == ")" || id == "(":
+		return nil, fmt.Errorf("missing value after '%s'", prim)
+	case !qualified:
+		// Only numbers and addresses continue the previous primitive
+		if p.last.kind == "" || !strings.ContainsAny(id[:1], "0123456789:") {
+			return nil, fmt.Errorf("unsupported primitive '%s'", id)
+		}
+		prim.proto, prim.dir, prim.kind = p.last.proto, p.last.dir, p.last.kind
+	case prim.kind == "":
+		// "src 10.0.0.1" is "src host 10.0.0.1", "src 10.0.0.0/8" a net
+		prim.kind = "host"
+		if strings.Contains(id, "/") {
+			prim.kind = "net"
+		}
+	}
+	prim.value = id
+
+	if err := checkPrimitive(prim); err != nil {
+		return nil, err
+	}
+	p.last = prim
+	return &exprNode{prim: prim}, nil
+}
+
+// checkPrimitive checks that the primitive's id suits its kind
+func checkPrimitive(prim primitive) error {
+	switch prim.kind {
+	case "host":
+		if net.ParseIP(prim.value) == nil {
+			return fmt.Errorf("invalid host '%s', must be an IP address (host names are not supported)", prim.value)
+		}
+	case "net":
+		if _, err := ParseNet(prim.value); err != nil {
+			return err
+		}
+	case "port":
+		port, err := strconv.Atoi(prim.value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port '%s', must be 1-65535 (service names are not supported)", prim.value)
+		}
+	}
+	return nil
+}
+
+// applyTerm sets the fields for one operand of the top-level conjunction
+func (f *PacketFilter) applyTerm(term *exprNode) error {
+	switch term.op {
+	case "":
+		return f.applyPrimitive(term.prim)
+	case "and":
+		// A parenthesized conjunction
+		for _, t := range term.flatten("and") {
+			if err := f.applyTerm(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := f.placeCriterion(); err != nil {
+		return err
+	}
+	alts := term.flatten("or")
+	if prims, ok := primitives(alts); ok {
+		// src host X or dst host X
+		if len(prims) == 2 && prims[0].kind == prims[1].kind && prims[0].value == prims[1].value &&
+			prims[0].proto == prims[1].proto && prims[0].dir != "" && prims[1].dir != "" && prims[0].dir != prims[1].dir {
+			return f.applyPrimitive(primitive{proto: prims[0].proto, kind: prims[0].kind, value: prims[0].value})
+		}
+		// dst port 80 or dst port 443 ...
+		if prims[0].kind == "port" && prims[0].dir != "" {
+			ports := make([]int, len(prims))
+			for i, prim := range prims {
+				if prim.kind != "port" || prim.dir != prims[0].dir || prim.proto != prims[0].proto {
+					return fmt.Errorf("unsupported alternatives '%s'", term)
+				}
+				ports[i], _ = strconv.Atoi(prim.value)
+			}
+			if err := f.applyProto(prims[0].proto); err != nil {
+				return err
+			}
+			return f.setPorts(prims[0].dir, ports)
+		}
+	}
+
+	// (src net A and dst net B) or (src net B and dst net A)
+	if a, b, ok := betweenPair(alts); ok {
+		if len(f.Between) != 0 {
+			return fmt.Errorf("between given more than once")
+		}
+		f.Between = []string{a, b}
+		return nil
+	}
+	return fmt.Errorf("unsupported alternatives '%s'", term)
+}
+
+// primitives returns the primitives of nodes if none of them is compound
+func primitives(nodes []*exprNode) ([]primitive, bool) {
+	prims := make([]primitive, len(nodes))
+	for i, n := range nodes {
+		if n.op != "" {
+			return nil, false
+		}
+		prims[i] = n.prim
+	}
+	return prims, true
+}
+
+// betweenPair recognizes the two mirrored source and destination
+// conjunctions that make up a between clause, returning the two networks
+func betweenPair(alts []*exprNode) (a, b string, ok bool) {
+	if len(alts) != 2 {
+		return "", "", false
+	}
+	var sides [2][2]string // source and destination of each alternative
+	for i, alt := range alts {
+		prims, ok := primitives(alt.flatten("and"))
+		if alt.op != "and" || !ok || len(prims) != 2 {
+			return "", "", false
+		}
+		for _, prim := range prims {
+			if prim.kind != "host" && prim.kind != "net" {
+				return "", "", false
+			}
+			switch prim.dir {
+			case "src":
+				sides[i][0] = prim.value
+			case "dst":
+				sides[i][1] = prim.value
+			}
+		}
+		if sides[i][0] == "" || sides[i][1] == "" {
+			return "", "", false
+		}
+	}
+	if !sameNet(sides[0][0], sides[1][1]) || !sameNet(sides[0][1], sides[1][0]) {
+		return "", "", false
+	}
+	return sides[0][0], sides[0][1], true
+}
+
+// sameNet reports whether two host or network ids denote the same network
+func sameNet(a, b string) bool {
+	return canonicalNet(a) == canonicalNet(b)
+}
+
+// applyProto sets the protocol, which may be repeated but not changed
+func (f *PacketFilter) applyProto(proto string) error {
+	if proto == "" {
+		return nil
+	}
+	if f.Protocol != "" && f.Protocol != proto {
+		return fmt.Errorf("conflicting protocols '%s' and '%s'", f.Protocol, proto)
+	}
+	f.Protocol = proto
+	return nil
+}
+
+// applyPrimitive sets the field for a single primitive
+func (f *PacketFilter) applyPrimitive(prim primitive) error {
+	if prim.kind != "vlan" && prim.kind != "geneve" && prim.kind != "vxlan" {
+		if err := f.placeCriterion(); err != nil {
+			return err
+		}
+	}
+	if err := f.applyProto(prim.proto); err != nil {
+		return err
+	}
+	switch prim.kind {
+	case "proto":
+		return f.applyProto(prim.value)
+	case "ether":
+		if f.EtherType != 0 {
+			return fmt.Errorf("'ether proto' given more than once")
+		}
+		etherType, _ := strconv.ParseUint(prim.value, 0, 16)
+		f.EtherType = int(etherType)
+	case "vlan":
+		if f.VLAN {
+			return fmt.Errorf("vlan given more than once")
+		}
+		if f.Tunnel != "" {
+			return fmt.Errorf("vlan after %s would match the inner frame, which is not supported", f.Tunnel)
+		}
+		f.VLAN = true
+		if prim.value != "" {
+			f.VLANID, _ = strconv.Atoi(prim.value)
+		}
+	case "geneve", "vxlan":
+		if f.Tunnel != "" {
+			return fmt.Errorf("tunnel given more than once")
+		}
+		f.Tunnel = Tunnel(prim.kind)
+		if prim.value != "" {
+			f.VNI, _ = strconv.Atoi(prim.value)
+		}
+	case "host", "net":
+		target := map[string]*string{"src": &f.SrcIP, "dst": &f.DstIP, "": &f.HostIP}[prim.dir]
+		if *target != "" {
+			return fmt.Errorf("'%s' given more than once", prim.String())
+		}
+		*target = prim.value
+	case "port":
+		port, _ := strconv.Atoi(prim.value)
+		if prim.dir == "" {
+			if f.Port != 0 {
+				return fmt.Errorf("'port' given more than once")
+			}
+			f.Port = port
+			return nil
+		}
+		return f.setPorts(prim.dir, []int{port})
+	}
+	return nil
+}
+
+// placeCriterion moves the criteria to the inner packet once a tunnel
+// primitive has been applied. Criteria already set before it stay on the
+// outer headers, and then no more may follow the tunnel.
+func (f *PacketFilter) placeCriterion() error {
+	if f.Tunnel == "" || f.Inner {
+		return nil
+	}
+	if f.Protocol != "" || f.EtherType != 0 || f.SrcIP != "" || f.DstIP != "" || f.HostIP != "" || len(f.Between) != 0 || f.HasPorts() {
+		return fmt.Errorf("criteria on both sides of '%s' are not supported", f.Tunnel)
+	}
+	f.Inner = true
+	return nil
+}
+
+// setPorts sets the source or destination ports, which may be given once
+func (f *PacketFilter) setPorts(dir string, ports []int) error {
+	single, list := &f.SrcPort, &f.SrcPorts
+	if dir == "dst" {
+		single, list = &f.DstPort, &f.DstPorts
+	}
+	if *single != 0 || len(*list) != 0 {
+		return fmt.Errorf("'%s port' given more than once", dir)
+	}
+	if len(ports) == 1 {
+		*single = ports[0]
+	} else {
+		*list = ports
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is one filter of a filter file: every PacketFilter field
+// under its YAML name, and an optional name to select it by
+type Definition struct {
+	Name         string `yaml:"name" json:"name,omitempty"`
+	PacketFilter `yaml:",inline"`
+}
+
+// FileError locates a problem in a filter file. Entry is the 1-based
+// filter it belongs to, or 0 for the file as a whole. Validation errors
+// keep wrapping ErrInvalidFilter.
+type FileError struct {
+	Path         string
+	Line, Column int
+	Entry        int
+	Err          error
+}
+
+func (e *FileError) Error() string {
+	pos := e.Path
+	if e.Line > 0 {
+		pos = fmt.Sprintf("%s:%d:%d", e.Path, e.Line, e.Column)
+	}
+	if e.Entry > 0 {
+		return fmt.Sprintf("%s: filter %d: %v", pos, e.Entry, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", pos, e.Err)
+}
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// LoadFile reads a YAML or JSON filter file: one filter as a mapping, or
+// a list of them. Keys are checked against the filter fields, so a
+// misspelled or unsupported key is an error at its position rather than a
+// criterion silently left out, and every filter is validated.
+func LoadFile(path string) ([]*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filter file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &FileError{Path: path, Err: err}
+	}
+	if len(doc.Content) == 0 {
+		return nil, &FileError{Path: path, Err: errors.New("no filters")}
+	}
+
+	root := doc.Content[0]
+	entries := []*yaml.Node{root}
+	switch root.Kind {
+	case yaml.SequenceNode:
+		entries = root.Content
+	case yaml.MappingNode:
+	default:
+		return nil, &FileError{Path: path, Line: root.Line, Column: root.Column, Err: errors.New("want a filter or a list of filters")}
+	}
+	if len(entries) == 0 {
+		return nil, &FileError{Path: path, Line: root.Line, Column: root.Column, Err: errors.New("no filters")}
+	}
+
+	names := make(map[string]int)
+	defs := make([]*Definition, len(entries))
+	for i, node := range entries {
+		fail := func(at *yaml.Node, err error) error {
+			return &FileError{Path: path, Line: at.Line, Column: at.Column, Entry: i + 1, Err: err}
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, fail(node, errors.New("want a mapping of filter fields"))
+		}
+		// Each value is decoded on its own first, to report a value of the
+		// wrong type at its position
+		for k := 0; k < len(node.Content); k += 2 {
+			key := node.Content[k]
+			if _, ok := definitionKeys[key.Value]; !ok {
+				return nil, fail(key, unknownKey(key.Value))
+			}
+			pair := &yaml.Node{Kind: yaml.MappingNode, Content: node.Content[k : k+2]}
+			if err := pair.Decode(&Definition{}); err != nil {
+				return nil, fail(node.Content[k+1], fmt.Errorf("%s: %w", key.Value, decodeError(err)))
+			}
+		}
+
+		def := &Definition{}
+		if err := node.Decode(def); err != nil {
+			return nil, fail(node, err)
+		}
+		if err := def.Validate(); err != nil {
+			return nil, fail(node, err)
+		}
+		if def.Name != "" {
+			if first, ok := names[def.Name]; ok {
+				return nil, fail(node, fmt.Errorf("name %q is already used by filter %d", def.Name, first))
+			}
+			names[def.Name] = i + 1
+		}
+		defs[i] = def
+	}
+	return defs, nil
+}
+
+// Select returns the filter of the file named name, or its only filter
+// when name is empty
+func Select(defs []*Definition, name string) (*Definition, error) {
+	if name == "" {
+		if len(defs) == 1 {
+			return defs[0], nil
+		}
+		return nil, fmt.Errorf("the file has %d filters (%s)", len(defs), definitionNames(defs))
+	}
+	for _, def := range defs {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("no filter named %q among %s", name, definitionNames(defs))
+}
+
+// definitionNames lists the filters of a file by name, or by expression
+// for unnamed ones
+func definitionNames(defs []*Definition) string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name
+		if def.Name == "" {
+			names[i] = fmt.Sprintf("filter %d: %s", i+1, def.ToTcpdumpFilter())
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// definitionKeys holds the YAML key of every Definition field
+var definitionKeys = yamlKeys(reflect.TypeOf(Definition{}))
+
+// yamlKeys returns the YAML keys of a struct's fields, with those of its
+// inline fields
+func yamlKeys(t reflect.Type) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-" || !field.IsExported():
+		case strings.Contains(opts, "inline"):
+			for key := range yamlKeys(field.Type) {
+				keys[key] = struct{}{}
+			}
+		case name != "":
+			keys[name] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// unknownKey describes a key no filter field has, suggesting the closest
+// field when it looks like a misspelling
+func unknownKey(key string) error {
+	known := make([]string, 0, len(definitionKeys))
+	for k := range definitionKeys {
+		known = append(known, k)
+	}
+	sort.Strings(known)
+
+	best, bestDistance := "", 3
+	folded := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+	for _, k := range known {
+		if d := editDistance(folded, k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown field %q, did you mean %q?", key, best)
+	}
+	return fmt.Errorf("unknown field %q; filter fields are %s", key, strings.Join(known, ", "))
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// decodeError drops the "yaml: unmarshal errors:" wrapping and the line
+// numbers of a type error, which FileError reports
+func decodeError(err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	messages := make([]string, len(typeErr.Errors))
+	for i, message := range typeErr.Errors {
+		if _, rest, ok := strings.Cut(message, ": "); ok && strings.HasPrefix(message, "line ") {
+			message = rest
+		}
+		messages[i] = message
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// LinkType is the data link type of the capture, named as for tcpdump -y.
+// It decides where the IP header starts and how IP packets are recognized.
+type LinkType string
+
+const (
+	LinkEN10MB   LinkType = "EN10MB"    // Ethernet
+	LinkLinuxSLL LinkType = "LINUX_SLL" // Linux cooked capture, as on the "any" device
+	LinkRaw      LinkType = "RAW"       // bare IP packets, as on tun devices
+	LinkNull     LinkType = "NULL"      // BSD loopback
+)
+
+// LinkTypes lists the supported link types, Ethernet first
+var LinkTypes = []LinkType{LinkEN10MB, LinkLinuxSLL, LinkRaw, LinkNull}
+
+// NullIPv4 is the NULL link header of an IPv4 packet as a word load reads
+// it. The header holds AF_INET in the capturing host's byte order.
+var NullIPv4 = binary.BigEndian.Uint32(binary.NativeEndian.AppendUint32(nil, 2))
+
+// ParseLinkType parses a link type name, ignoring case. The empty string
+// is Ethernet.
+func ParseLinkType(s string) (LinkType, error) {
+	if s == "" {
+		return LinkEN10MB, nil
+	}
+	for _, l := range LinkTypes {
+		if strings.EqualFold(s, string(l)) {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("invalid link type '%s', must be EN10MB, LINUX_SLL, RAW, or NULL", s)
+}
+
+// IsEthernet reports whether the link type is Ethernet, which is also the
+// meaning of the empty link type
+func (l LinkType) IsEthernet() bool {
+	return l == "" || l == LinkEN10MB
+}
+
+// HeaderLen returns the length of the link-layer header before the IP header
+func (l LinkType) HeaderLen() int {
+	switch l {
+	case LinkLinuxSLL:
+		return 16
+	case LinkRaw:
+		return 0
+	case LinkNull:
+		return 4
+	}
+	return 14
+}
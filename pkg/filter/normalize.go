@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Normalize returns a validated copy of the filter in canonical form, so
+// that filters written differently but with the same criteria produce the
+// same expression and byte-identical programs:
+//   - protocol names are lowercase, and protocol number 1 is icmp
+//   - addresses are written as netip prints them: IPv4-mapped addresses
+//     become IPv4, a /32 or /128 network becomes its address, and the host
+//     bits of other networks are cleared
+//   - port lists are sorted, and so are the two networks of Between
+//   - Between with the same network twice becomes a source and destination
+//   - EtherTypes 0x0806 and 0x8035 become the arp and rarp protocols
+//   - clauses the others imply are dropped: a port that a source or
+//     destination port is already restricted to, a host network containing
+//     the source, destination or one of the between networks, a 0/0
+//     network whose family another criterion fixes, and udp for the outer
+//     headers of a tunnel
+//
+// The filter itself is not modified.
+func Normalize(f *PacketFilter) (*PacketFilter, error) {
+	n := *f
+	n.SrcPorts = slices.Clone(f.SrcPorts)
+	n.DstPorts = slices.Clone(f.DstPorts)
+	n.Between = slices.Clone(f.Between)
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, addr := range []*string{&n.SrcIP, &n.DstIP, &n.HostIP} {
+		if *addr != "" {
+			*addr = canonicalAddr(*addr)
+		}
+	}
+	slices.Sort(n.SrcPorts)
+	slices.Sort(n.DstPorts)
+
+	if len(n.Between) == 2 {
+		a, b := parsePrefix(n.Between[0]), parsePrefix(n.Between[1])
+		switch {
+		case a == b:
+			n.SrcIP, n.DstIP, n.Between = canonicalAddr(n.Between[0]), canonicalAddr(n.Between[0]), nil
+		case comparePrefixes(b, a) < 0:
+			n.Between = []string{canonicalAddr(n.Between[1]), canonicalAddr(n.Between[0])}
+		default:
+			n.Between = []string{canonicalAddr(n.Between[0]), canonicalAddr(n.Between[1])}
+		}
+	}
+
+	if n.Protocol == "1" {
+		n.Protocol = "icmp"
+	}
+	switch n.EtherType {
+	case 0x0806:
+		n.Protocol, n.EtherType = "arp", 0
+	case 0x8035:
+		n.Protocol, n.EtherType = "rarp", 0
+	}
+
+	// The tunnel already requires UDP to its port
+	if n.Tunnel != "" && !n.Inner && n.Protocol == "udp" {
+		n.Protocol = ""
+	}
+	if n.Port != 0 && (slices.Equal(n.SrcPortList(), []int{n.Port}) || slices.Equal(n.DstPortList(), []int{n.Port})) {
+		n.Port = 0
+	}
+	if n.HostIP != "" && n.hostImplied() {
+		n.HostIP = ""
+	}
+	for _, addr := range []*string{&n.HostIP, &n.SrcIP, &n.DstIP} {
+		if *addr != "" && parsePrefix(*addr).Bits() == 0 && n.familyFixed(addr) {
+			*addr = ""
+		}
+	}
+	return &n, nil
+}
+
+// canonicalAddr returns a validated address or network as netip prints
+// it, with /32 and /128 networks as bare addresses
+func canonicalAddr(s string) string {
+	p := parsePrefix(s)
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	return p.String()
+}
+
+// comparePrefixes orders prefixes by address, IPv4 first, then by length
+func comparePrefixes(a, b netip.Prefix) int {
+	if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	}
+	return a.Bits() - b.Bits()
+}
+
+// hostImplied reports whether the host network contains an address that
+// every matching packet already has as its source or destination
+func (f *PacketFilter) hostImplied() bool {
+	host := parsePrefix(f.HostIP)
+	for _, addr := range append([]string{f.SrcIP, f.DstIP}, f.Between...) {
+		if addr == "" {
+			continue
+		}
+		if p := parsePrefix(addr); host.Bits() <= p.Bits() && host.Contains(p.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// familyFixed reports whether criteria other than the network in field
+// restrict the filter to the address family of that network
+func (f *PacketFilter) familyFixed(field *string) bool {
+	v4 := isIPv4(*field)
+	if v4 && (f.Protocol == "icmp" || f.Tunnel != "") {
+		return true
+	}
+	for _, other := range []*string{&f.SrcIP, &f.DstIP, &f.HostIP} {
+		if other != field && *other != "" && isIPv4(*other) == v4 {
+			return true
+		}
+	}
+	for _, n := range f.Between {
+		if isIPv4(n) == v4 {
+			return true
+		}
+	}
+	return false
+}
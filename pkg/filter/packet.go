@@ -0,0 +1,190 @@
+package filter
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+)
+
+// ErrUndecodable is returned for a packet that ends before a header field a
+// filter may test, or whose IPv4 header length is invalid. Programs give up
+// on such packets at whichever load falls outside them, which no filter
+// describes.
+var ErrUndecodable = errors.New("packet ends inside a header or has an invalid IPv4 header length")
+
+// Matches reports whether the filter selects a packet of its link type.
+// It is the filter's meaning written directly in Go, independent of both
+// compilers, so that it can judge the programs of either. Like the
+// generators, it only understands IPv4: unless the filter tests the
+// EtherType alone, no other packet matches. Neither does a packet of an
+// invalid filter or one it cannot decode; MatchesPacket tells those apart.
+func (f *PacketFilter) Matches(pkt []byte) bool {
+	match, err := f.MatchesPacket(pkt)
+	return match && err == nil
+}
+
+// MatchesPacket is Matches, reading the packet's headers with ParseHeaders
+// and deciding with MatchesHeaders. The error wraps ErrUndecodable for
+// packets it cannot decide, and ErrInvalidFilter for an invalid filter.
+func (f *PacketFilter) MatchesPacket(pkt []byte) (bool, error) {
+	h, err := ParseHeaders(pkt, f.LinkType)
+	if err != nil {
+		return false, err
+	}
+	match, err := f.MatchesHeaders(h)
+	if err != nil {
+		return false, err
+	}
+	return match && (f.EtherProto() != 0 || h.EtherType == etherIPv4), nil
+}
+
+// ParseHeaders reads the fields a filter tests from a packet of the link
+// type, as tcpdump would read them: the EtherType after at most one
+// 802.1Q tag, the IPv4 addresses and protocol, the ports of the first
+// fragment, and a valid VXLAN or Geneve header with the headers of the
+// frame it carries. The generators do not read IPv6 headers, so an IPv6
+// packet has only its EtherType, like any other non-IPv4 packet.
+func ParseHeaders(pkt []byte, link LinkType) (*Headers, error) {
+	h := &Headers{}
+	ok := false
+	switch link {
+	case LinkLinuxSLL:
+		// Tags are stripped from cooked captures, so one is only a protocol
+		if len(pkt) >= 16 {
+			h.EtherType = binary.BigEndian.Uint16(pkt[14:])
+			ok = parseAboveLink(h, pkt[16:])
+		}
+	case LinkRaw:
+		if len(pkt) >= 1 {
+			switch pkt[0] >> 4 {
+			case 4:
+				h.EtherType = etherIPv4
+			case 6:
+				h.EtherType = etherIPv6
+			}
+			ok = parseAboveLink(h, pkt)
+		}
+	case LinkNull:
+		if len(pkt) >= 4 {
+			switch binary.NativeEndian.Uint32(pkt) {
+			case 2:
+				h.EtherType = etherIPv4
+			case 24, 28, 30:
+				h.EtherType = etherIPv6
+			}
+			ok = parseAboveLink(h, pkt[4:])
+		}
+	default:
+		ok = parseEthernet(h, pkt)
+	}
+	if !ok {
+		return nil, ErrUndecodable
+	}
+	return h, nil
+}
+
+// parseEthernet reads an Ethernet frame with at most one tag, of any of
+// the TPIDs libpcap's "vlan" accepts
+func parseEthernet(h *Headers, data []byte) bool {
+	if len(data) < 14 {
+		return false
+	}
+	h.EtherType = binary.BigEndian.Uint16(data[12:])
+	data = data[14:]
+	switch h.EtherType {
+	case etherVLAN, 0x88a8, 0x9100:
+		if len(data) < 4 {
+			return false
+		}
+		h.VLAN = true
+		h.VLANID = int(binary.BigEndian.Uint16(data) & 0xfff)
+		h.EtherType = binary.BigEndian.Uint16(data[2:])
+		data = data[4:]
+	}
+	return parseAboveLink(h, data)
+}
+
+// parseAboveLink reads the IPv4 header after the link layer, and the ports
+// and tunnel of its transport header. Other protocols have no fields
+// beyond their EtherType.
+func parseAboveLink(h *Headers, data []byte) bool {
+	var rest []byte
+	switch h.EtherType {
+	case etherIPv4:
+		if len(data) < 20 {
+			return false
+		}
+		ihl := int(data[0]&0x0f) * 4
+		if ihl < 20 {
+			return false
+		}
+		h.Protocol = int(data[9])
+		h.Src = netip.AddrFrom4([4]byte(data[12:16]))
+		h.Dst = netip.AddrFrom4([4]byte(data[16:20]))
+		h.Fragment = binary.BigEndian.Uint16(data[6:])&0x1fff != 0
+		if len(data) >= ihl {
+			rest = data[ihl:]
+		}
+	default:
+		return true
+	}
+
+	// Only the first fragment carries the ports
+	h.SrcPort, h.DstPort = -1, -1
+	if h.Fragment || !hasPorts(h.Protocol) {
+		return true
+	}
+	if len(rest) < 4 {
+		return false
+	}
+	h.SrcPort = int(binary.BigEndian.Uint16(rest))
+	h.DstPort = int(binary.BigEndian.Uint16(rest[2:]))
+	if h.Protocol != 17 {
+		return true
+	}
+	return parseTunnel(h, rest)
+}
+
+// parseTunnel reads a valid VXLAN or Geneve header from the UDP payload of
+// a packet to the tunnel's port, and the headers of the Ethernet frame it
+// carries
+func parseTunnel(h *Headers, udp []byte) bool {
+	var tunnel Tunnel
+	for _, t := range Tunnels {
+		if h.DstPort == t.Port() {
+			tunnel = t
+		}
+	}
+	if tunnel == "" {
+		return true
+	}
+	data := udp[min(8, len(udp)):]
+	if len(data) < TunnelHeaderLen {
+		return false
+	}
+
+	// A header with the VNI flag clear, or of another Geneve version, is
+	// some other service on the port
+	frame := TunnelHeaderLen
+	if tunnel == TunnelGeneve {
+		if data[0]&0xc0 != 0 {
+			return true
+		}
+		frame += int(data[0]&0x3f) * 4
+	} else if data[0]&0x08 == 0 {
+		return true
+	}
+	h.Tunnel = tunnel
+	h.VNI = int(binary.BigEndian.Uint32(data[4:]) >> 8)
+
+	// Geneve can carry other payloads than Ethernet
+	if tunnel == TunnelGeneve && binary.BigEndian.Uint16(data[2:]) != 0x6558 {
+		return true
+	}
+	// The inner EtherType is read without looking for a tag
+	if len(data) < frame+14 {
+		return false
+	}
+	h.Inner = &Headers{EtherType: binary.BigEndian.Uint16(data[frame+12:])}
+	return parseAboveLink(h.Inner, data[frame+14:])
+}
@@ -0,0 +1,47 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+)
+
+// ipv6TCP is an Ethernet frame of an IPv6 TCP packet to port 80
+func ipv6TCP() []byte {
+	frame := make([]byte, 14+40+20)
+	frame[12], frame[13] = 0x86, 0xdd
+	frame[14] = 0x60
+	frame[14+6] = 6 // next header: TCP
+	frame[14+40+3] = 80
+	return frame
+}
+
+func TestMatches(t *testing.T) {
+	build := func(s packet.Spec) []byte {
+		pkt, err := s.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkt
+	}
+	for _, tc := range []struct {
+		name   string
+		filter filter.PacketFilter
+		pkt    []byte
+		want   bool
+	}{
+		{"tcp port 80", filter.PacketFilter{Protocol: "tcp", DstPort: 80}, build(packet.Spec{Protocol: "tcp", DstPort: 80}), true},
+		{"tcp port 443", filter.PacketFilter{Protocol: "tcp", DstPort: 80}, build(packet.Spec{Protocol: "tcp", DstPort: 443}), false},
+		{"udp", filter.PacketFilter{Protocol: "tcp", DstPort: 80}, build(packet.Spec{Protocol: "udp", DstPort: 80}), false},
+		{"arp", filter.PacketFilter{Protocol: "arp"}, build(packet.Spec{Protocol: "arp"}), true},
+		{"ipv6 tcp port 80", filter.PacketFilter{Protocol: "tcp", DstPort: 80}, ipv6TCP(), false},
+		{"ipv6 any", filter.PacketFilter{}, ipv6TCP(), false},
+		{"ipv6 ethertype", filter.PacketFilter{EtherType: 0x86dd}, ipv6TCP(), true},
+		{"truncated", filter.PacketFilter{Protocol: "tcp"}, build(packet.Spec{Protocol: "tcp"})[:20], false},
+	} {
+		if got := tc.filter.Matches(tc.pkt); got != tc.want {
+			t.Errorf("%s: Matches = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+package filter
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// EtherTypes the abstract packets distinguish
+const (
+	etherIPv4 = 0x0800
+	etherIPv6 = 0x86dd
+	etherARP  = 0x0806
+	etherVLAN = 0x8100
+)
+
+// MaxPacketClasses bounds the abstract packets Witness matches both
+// filters against before giving up
+const MaxPacketClasses = 1 << 22
+
+// Headers is an abstract packet: the header fields a filter tests, as
+// tcpdump would read them. Equal, Subsumes and Witness match both filters
+// against one such packet per class of packets the two cannot tell apart.
+type Headers struct {
+	VLAN      bool   // 802.1Q-tagged frame
+	VLANID    int    // VLAN ID of the tag, when VLAN is set
+	EtherType uint16 // after any tag; IPv4 and IPv6 packets have theirs on every link type
+
+	// IP fields, when EtherType is IPv4 or IPv6
+	Protocol         int
+	Src, Dst         netip.Addr
+	SrcPort, DstPort int  // -1 for protocols without ports
+	Fragment         bool // a non-first IPv4 fragment, without transport header
+
+	// Tunnel is the VXLAN or Geneve header in the payload of a UDP packet
+	// to the tunnel's port, VNI its network, and Inner the encapsulated
+	// Ethernet frame
+	Tunnel Tunnel
+	VNI    int
+	Inner  *Headers
+}
+
+// String describes the packet in the style of a tcpdump summary line
+func (h *Headers) String() string {
+	var parts []string
+	if h.VLAN {
+		parts = append(parts, fmt.Sprintf("vlan %d", h.VLANID))
+	}
+	switch h.EtherType {
+	case etherIPv4, etherIPv6:
+		version := "IP"
+		if h.EtherType == etherIPv6 {
+			version = "IP6"
+		}
+		src, dst := h.Src.String(), h.Dst.String()
+		if h.SrcPort >= 0 {
+			src += fmt.Sprintf(".%d", h.SrcPort)
+			dst += fmt.Sprintf(".%d", h.DstPort)
+		}
+		s := fmt.Sprintf("%s %s > %s %s", version, src, dst, protocolName(h.Protocol))
+		if h.Fragment {
+			s += " fragment"
+		}
+		parts = append(parts, s)
+	default:
+		parts = append(parts, fmt.Sprintf("ethertype 0x%04x", h.EtherType))
+	}
+	if h.Tunnel != "" {
+		parts = append(parts, fmt.Sprintf("%s vni %d", h.Tunnel, h.VNI))
+		if h.Inner != nil {
+			parts = append(parts, h.Inner.String())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MatchesHeaders reports whether the filter selects the abstract packet,
+// as Equal and Subsumes do. The filter is validated first.
+func (f *PacketFilter) MatchesHeaders(h *Headers) (bool, error) {
+	m, err := newMatcher(f)
+	if err != nil {
+		return false, err
+	}
+	return m.matches(h), nil
+}
+
+// Subsumes reports whether filter a matches every packet filter b matches
+func Subsumes(a, b *PacketFilter) (bool, error) {
+	witness, err := Witness(a, b)
+	return witness == nil, err
+}
+
+// Equal reports whether filters a and b match exactly the same packets,
+// however differently they are written
+func Equal(a, b *PacketFilter) (bool, error) {
+	if subsumes, err := Subsumes(a, b); err != nil || !subsumes {
+		return false, err
+	}
+	return Subsumes(b, a)
+}
+
+// Witness returns a packet that filter b matches and filter a does not, or
+// nil if a subsumes b. Both filters are validated and must be for the same
+// link type.
+//
+// The answer is exact: the networks, ports, VLAN IDs, EtherTypes and VNIs
+// of the two filters cut every header field into a few regions that both
+// filters treat alike, and Witness tries one packet from each combination
+// of regions. It fails if there are more than MaxPacketClasses of them.
+func Witness(a, b *PacketFilter) (*Headers, error) {
+	ma, err := newMatcher(a)
+	if err != nil {
+		return nil, err
+	}
+	mb, err := newMatcher(b)
+	if err != nil {
+		return nil, err
+	}
+	linkA, _ := ParseLinkType(string(ma.f.LinkType))
+	linkB, _ := ParseLinkType(string(mb.f.LinkType))
+	if linkA != linkB {
+		return nil, fmt.Errorf("filters for different link types (%s and %s) cannot be compared", linkA, linkB)
+	}
+
+	var witness *Headers
+	err = newSpace(linkA, ma, mb).each(func(h *Headers) bool {
+		if mb.matches(h) && !ma.matches(h) {
+			witness = h
+			return false
+		}
+		return true
+	})
+	if witness != nil {
+		return witness, nil
+	}
+	return nil, err
+}
+
+// matcher is a validated filter with its networks parsed, for matching many
+// packets
+type matcher struct {
+	f              *PacketFilter
+	protocol       int  // IP protocol number, -1 for any
+	ipv4Only       bool // icmp and protocol numbers, as tcpdump's "ip proto"
+	src, dst, host *netip.Prefix
+	between        []netip.Prefix
+}
+
+// newMatcher validates a copy of the filter and parses its networks
+func newMatcher(f *PacketFilter) (*matcher, error) {
+	c := *f
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	m := &matcher{f: &c, protocol: c.IPProtocol(), ipv4Only: c.Protocol == "icmp" || c.HasProtocolNumber()}
+	for _, n := range []struct {
+		addr string
+		dst  **netip.Prefix
+	}{{c.SrcIP, &m.src}, {c.DstIP, &m.dst}, {c.HostIP, &m.host}} {
+		if n.addr != "" {
+			p := parsePrefix(n.addr)
+			*n.dst = &p
+		}
+	}
+	for _, n := range c.Between {
+		m.between = append(m.between, parsePrefix(n))
+	}
+	return m, nil
+}
+
+// parsePrefix converts a validated network to a prefix with the host bits
+// cleared. IPv4-mapped IPv6 networks are IPv4, as for isIPv4.
+func parsePrefix(s string) netip.Prefix {
+	ipnet, _ := ParseNet(s)
+	ones, bits := ipnet.Mask.Size()
+	addr := netip.AddrFrom16([16]byte(ipnet.IP.To16()))
+	if addr.Is4In6() && (bits == 32 || ones >= 96) {
+		if bits == 128 {
+			ones -= 96
+		}
+		addr = addr.Unmap()
+	}
+	return netip.PrefixFrom(addr, ones).Masked()
+}
+
+// prefixes returns every network the filter tests
+func (m *matcher) prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, p := range []*netip.Prefix{m.src, m.dst, m.host} {
+		if p != nil {
+			prefixes = append(prefixes, *p)
+		}
+	}
+	return append(prefixes, m.between...)
+}
+
+// ports returns every port the filter tests
+func (m *matcher) ports() []int {
+	ports := append(m.f.SrcPortList(), m.f.DstPortList()...)
+	if m.f.Port != 0 {
+		ports = append(ports, m.f.Port)
+	}
+	return ports
+}
+
+// hasIPCriteria reports whether the filter tests any IP header field
+func (m *matcher) hasIPCriteria() bool {
+	return m.protocol >= 0 || len(m.prefixes()) > 0 || m.f.HasPorts()
+}
+
+// matches reports whether the filter selects the packet
+func (m *matcher) matches(h *Headers) bool {
+	f := m.f
+	if f.VLAN && (!h.VLAN || f.VLANID != 0 && h.VLANID != f.VLANID) {
+		return false
+	}
+
+	// Without "vlan", the tag is read where the EtherType is expected
+	etherType := h.EtherType
+	if h.VLAN && !f.VLAN {
+		etherType = etherVLAN
+	}
+	if proto := f.EtherProto(); proto != 0 {
+		return etherType == proto
+	}
+
+	if f.Tunnel != "" {
+		if etherType != etherIPv4 || h.Protocol != 17 || h.Fragment || h.DstPort != f.Tunnel.Port() || h.Tunnel != f.Tunnel {
+			return false
+		}
+		if f.VNI != 0 && h.VNI != f.VNI {
+			return false
+		}
+		if f.Inner {
+			return h.Inner != nil && h.Inner.EtherType == etherIPv4 && m.matchesIP(h.Inner)
+		}
+		return m.matchesIP(h)
+	}
+
+	if f.VLAN && !m.hasIPCriteria() {
+		return true
+	}
+	return (etherType == etherIPv4 || etherType == etherIPv6) && m.matchesIP(h)
+}
+
+// matchesIP applies the protocol, address and port criteria to the headers
+// of an IP packet
+func (m *matcher) matchesIP(h *Headers) bool {
+	f := m.f
+	switch {
+	case m.protocol >= 0:
+		if h.Protocol != m.protocol || m.ipv4Only && h.EtherType != etherIPv4 {
+			return false
+		}
+	case f.HasPorts():
+		if !hasPorts(h.Protocol) {
+			return false
+		}
+	}
+
+	// Only the first fragment carries the ports
+	if f.HasPorts() && h.Fragment {
+		return false
+	}
+
+	if m.src != nil && !m.src.Contains(h.Src) || m.dst != nil && !m.dst.Contains(h.Dst) {
+		return false
+	}
+	if m.host != nil && !m.host.Contains(h.Src) && !m.host.Contains(h.Dst) {
+		return false
+	}
+	if len(m.between) == 2 {
+		a, b := m.between[0], m.between[1]
+		if !(a.Contains(h.Src) && b.Contains(h.Dst)) && !(b.Contains(h.Src) && a.Contains(h.Dst)) {
+			return false
+		}
+	}
+
+	if ports := f.SrcPortList(); len(ports) > 0 && !slices.Contains(ports, h.SrcPort) {
+		return false
+	}
+	if ports := f.DstPortList(); len(ports) > 0 && !slices.Contains(ports, h.DstPort) {
+		return false
+	}
+	return f.Port == 0 || h.SrcPort == f.Port || h.DstPort == f.Port
+}
+
+// hasPorts reports whether an IP protocol has the ports of a bare "port"
+// primitive: TCP, UDP or SCTP
+func hasPorts(protocol int) bool {
+	return protocol == 6 || protocol == 17 || protocol == 132
+}
+
+// protocolName names an IP protocol for Headers.String
+func protocolName(protocol int) string {
+	switch protocol {
+	case 1:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	case 132:
+		return "sctp"
+	}
+	return fmt.Sprintf("proto %d", protocol)
+}
+
+// space holds representative values of each header field for a pair of
+// filters, such that every packet is matched like some combination of them
+type space struct {
+	vlans      []int // -1 for untagged frames
+	etherTypes []uint16
+	outer      ipSpace
+	tunnels    []Tunnel
+	vnis       []int
+	inner      *ipSpace // nil unless a filter tests the encapsulated frame
+}
+
+// ipSpace holds representative values of the IP header fields
+type ipSpace struct {
+	protocols []int
+	v4, v6    []netip.Addr
+	ports     []int
+}
+
+// newSpace collects the representative values for the filters' criteria.
+// A value no filter names (VLAN ID 0, port 0, VNI 0, an unused EtherType)
+// stands for all the others.
+func newSpace(link LinkType, matchers ...*matcher) *space {
+	s := &space{vlans: []int{-1}, etherTypes: []uint16{etherIPv4, etherIPv6}, vnis: []int{0}}
+	if link.IsEthernet() {
+		s.vlans = append(s.vlans, 0)
+	}
+	var outerPrefixes, innerPrefixes []netip.Prefix
+	var outerPorts, innerPorts []int
+
+	// ICMP, the protocols with ports, and those the filters name
+	protocols := []int{1, 6, 17, 132}
+	for _, m := range matchers {
+		f := m.f
+		if m.protocol >= 0 && !slices.Contains(protocols, m.protocol) {
+			protocols = append(protocols, m.protocol)
+		}
+		if f.VLANID != 0 && !slices.Contains(s.vlans, f.VLANID) {
+			s.vlans = append(s.vlans, f.VLANID)
+		}
+		if proto := f.EtherProto(); proto != 0 && proto != etherVLAN && !slices.Contains(s.etherTypes, proto) {
+			s.etherTypes = append(s.etherTypes, proto)
+		}
+		if f.Tunnel != "" {
+			if !slices.Contains(s.tunnels, f.Tunnel) {
+				s.tunnels = append(s.tunnels, f.Tunnel)
+			}
+			outerPorts = append(outerPorts, f.Tunnel.Port())
+			if f.VNI != 0 && !slices.Contains(s.vnis, f.VNI) {
+				s.vnis = append(s.vnis, f.VNI)
+			}
+		}
+		if f.Inner {
+			innerPrefixes = append(innerPrefixes, m.prefixes()...)
+			innerPorts = append(innerPorts, m.ports()...)
+			if s.inner == nil {
+				s.inner = &ipSpace{}
+			}
+		} else {
+			outerPrefixes = append(outerPrefixes, m.prefixes()...)
+			outerPorts = append(outerPorts, m.ports()...)
+		}
+	}
+	other := uint16(0x88b5) // IEEE local experimental
+	for slices.Contains(s.etherTypes, other) {
+		other++
+	}
+	s.etherTypes = append(s.etherTypes, other)
+	otherProtocol := 253 // experimental
+	for slices.Contains(protocols, otherProtocol) {
+		otherProtocol--
+	}
+	protocols = append(protocols, otherProtocol)
+
+	s.outer = newIPSpace(protocols, outerPrefixes, outerPorts)
+	if s.inner != nil {
+		*s.inner = newIPSpace(protocols, innerPrefixes, innerPorts)
+	}
+	return s
+}
+
+// newIPSpace collects the protocols, one address from each region the
+// networks cut the IPv4 and IPv6 address spaces into, and the ports
+func newIPSpace(protocols []int, prefixes []netip.Prefix, ports []int) ipSpace {
+	s := ipSpace{
+		protocols: protocols,
+		v4:        representatives(netip.MustParsePrefix("0.0.0.0/0"), prefixes),
+		v6:        representatives(netip.MustParsePrefix("::/0"), prefixes),
+		ports:     []int{0},
+	}
+	for _, p := range ports {
+		if !slices.Contains(s.ports, p) {
+			s.ports = append(s.ports, p)
+		}
+	}
+	return s
+}
+
+// representatives returns an address from each region of root that the
+// prefixes of its family cut out: for each prefix, and root itself, an
+// address in it but outside every smaller prefix inside it
+func representatives(root netip.Prefix, prefixes []netip.Prefix) []netip.Addr {
+	cuts := []netip.Prefix{root}
+	for _, p := range prefixes {
+		if p.Addr().Is4() == root.Addr().Is4() && !slices.Contains(cuts, p) {
+			cuts = append(cuts, p)
+		}
+	}
+	var addrs []netip.Addr
+	for _, c := range cuts {
+		var holes []netip.Prefix
+		for _, p := range cuts {
+			if p.Bits() > c.Bits() && c.Contains(p.Addr()) {
+				holes = append(holes, p)
+			}
+		}
+		if addr, ok := freeAddr(c, holes); ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// freeAddr returns an address in p outside every hole, or false if the
+// holes cover p
+func freeAddr(p netip.Prefix, holes []netip.Prefix) (netip.Addr, bool) {
+	var inside []netip.Prefix
+	for _, h := range holes {
+		switch {
+		case h.Bits() <= p.Bits() && h.Contains(p.Addr()):
+			return netip.Addr{}, false
+		case h.Bits() > p.Bits() && p.Contains(h.Addr()):
+			inside = append(inside, h)
+		}
+	}
+	if len(inside) == 0 {
+		return p.Addr(), true
+	}
+
+	// Some address of either half avoids the holes, unless both are covered
+	lower := netip.PrefixFrom(p.Addr(), p.Bits()+1)
+	if addr, ok := freeAddr(lower, inside); ok {
+		return addr, true
+	}
+	upper := p.Addr().AsSlice()
+	upper[p.Bits()/8] |= 0x80 >> (p.Bits() % 8)
+	addr, _ := netip.AddrFromSlice(upper)
+	return freeAddr(netip.PrefixFrom(addr, p.Bits()+1), inside)
+}
+
+// each calls visit with every combination of the representative values
+// until it returns false. It fails once more than MaxPacketClasses
+// packets have been visited.
+func (s *space) each(visit func(*Headers) bool) error {
+	count := 0
+	s.frames(func(h Headers) bool {
+		count++
+		return count <= MaxPacketClasses && visit(&h)
+	})
+	if count > MaxPacketClasses {
+		return fmt.Errorf("filters too complex to compare: more than %d packet classes", MaxPacketClasses)
+	}
+	return nil
+}
+
+// frames varies the VLAN tag and EtherType, then the IP fields of IP
+// packets
+func (s *space) frames(visit func(Headers) bool) bool {
+	for _, vlan := range s.vlans {
+		for _, etherType := range s.etherTypes {
+			h := Headers{VLAN: vlan >= 0, VLANID: max(vlan, 0), EtherType: etherType}
+			var more bool
+			if etherType == etherIPv4 || etherType == etherIPv6 {
+				more = s.outer.each(h, func(h Headers) bool { return s.encapsulations(h, visit) })
+			} else {
+				more = visit(h)
+			}
+			if !more {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// encapsulations visits the packet as is, and if it is a first IPv4 UDP
+// fragment to a tunnel port, with that tunnel's header carrying each VNI
+// and inner frame
+func (s *space) encapsulations(h Headers, visit func(Headers) bool) bool {
+	if !visit(h) {
+		return false
+	}
+	if h.EtherType != etherIPv4 || h.Protocol != 17 || h.Fragment {
+		return true
+	}
+	for _, t := range s.tunnels {
+		if h.DstPort != t.Port() {
+			continue
+		}
+		h.Tunnel = t
+		for _, vni := range s.vnis {
+			h.VNI = vni
+			if s.inner == nil {
+				if !visit(h) {
+					return false
+				}
+				continue
+			}
+			more := s.inner.each(Headers{EtherType: etherIPv4}, func(inner Headers) bool {
+				h.Inner = &inner
+				return visit(h)
+			})
+			if !more {
+				return false
+			}
+			h.Inner = &Headers{EtherType: etherARP}
+			if !visit(h) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// each varies the IP fields of h, whose EtherType is IPv4 or IPv6
+func (s *ipSpace) each(h Headers, visit func(Headers) bool) bool {
+	addrs, fragments := s.v4, []bool{false, true}
+	if h.EtherType == etherIPv6 {
+		addrs, fragments = s.v6, []bool{false}
+	}
+	noPorts := []int{-1}
+	for _, protocol := range s.protocols {
+		ports := noPorts
+		if hasPorts(protocol) {
+			ports = s.ports
+		}
+		h.Protocol = protocol
+		for _, src := range addrs {
+			for _, dst := range addrs {
+				h.Src, h.Dst = src, dst
+				for _, srcPort := range ports {
+					for _, dstPort := range ports {
+						h.SrcPort, h.DstPort = srcPort, dstPort
+						for _, fragment := range fragments {
+							h.Fragment = fragment
+							if !visit(h) {
+								return false
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package filter
+
+import "fmt"
+
+// Action is what a capture does with the packets a rule matches
+type Action string
+
+const (
+	ActionAccept   Action = "accept"   // capture the packet up to the capture's snapshot length
+	ActionTruncate Action = "truncate" // capture only the first Snaplen bytes, such as the headers
+	ActionDrop     Action = "drop"     // leave the packet out of the capture
+)
+
+// MaxSnaplen is the longest snapshot length tcpdump accepts
+const MaxSnaplen = 262144
+
+// Rule is a filter with the action taken on the packets it matches. In a
+// list of rules the first rule matching a packet decides, and a packet no
+// rule matches is dropped.
+type Rule struct {
+	PacketFilter `yaml:",inline"`
+	Action       Action `yaml:"action" json:"action,omitempty"`   // accept (the default), truncate or drop
+	Snaplen      int    `yaml:"snaplen" json:"snaplen,omitempty"` // bytes truncate keeps
+}
+
+// ParseAction parses a rule action; the empty string is accept
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case "", ActionAccept:
+		return ActionAccept, nil
+	case ActionTruncate, ActionDrop:
+		return Action(s), nil
+	}
+	return "", fmt.Errorf("invalid action '%s', must be accept, truncate or drop", s)
+}
+
+// Validate checks the filter and the action. Only truncate takes a
+// snapshot length. Its errors wrap ErrInvalidFilter.
+func (r *Rule) Validate() error {
+	action, err := ParseAction(string(r.Action))
+	if err != nil {
+		return invalid(err)
+	}
+	switch {
+	case action == ActionTruncate && (r.Snaplen < 1 || r.Snaplen > MaxSnaplen):
+		return invalid(fmt.Errorf("truncate needs a snaplen between 1 and %d, got %d", MaxSnaplen, r.Snaplen))
+	case action != ActionTruncate && r.Snaplen != 0:
+		return invalid(fmt.Errorf("snaplen applies to the truncate action, not %s", action))
+	}
+	return r.PacketFilter.Validate()
+}
+
+// String describes the rule as its action and filter, e.g.
+// "truncate 96: tcp and dst port 80"
+func (r *Rule) String() string {
+	action, _ := ParseAction(string(r.Action))
+	if action == ActionTruncate {
+		return fmt.Sprintf("%s %d: %s", action, r.Snaplen, r.ToTcpdumpFilter())
+	}
+	return fmt.Sprintf("%s: %s", action, r.ToTcpdumpFilter())
+}
@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tunnel is a UDP encapsulation the filter can look into, named as the
+// tcpdump primitive that matches it
+type Tunnel string
+
+const (
+	TunnelVXLAN  Tunnel = "vxlan"  // RFC 7348, UDP port 4789
+	TunnelGeneve Tunnel = "geneve" // RFC 8926, UDP port 6081, as used by Antrea
+)
+
+// Tunnels lists the supported tunnels
+var Tunnels = []Tunnel{TunnelVXLAN, TunnelGeneve}
+
+// MaxVNI is the largest 24-bit VXLAN or Geneve network identifier
+const MaxVNI = 1<<24 - 1
+
+// TunnelHeaderLen is the length of the fixed VXLAN or Geneve header, which
+// for Geneve is followed by options
+const TunnelHeaderLen = 8
+
+// ParseTunnel parses a tunnel name, ignoring case
+func ParseTunnel(s string) (Tunnel, error) {
+	for _, t := range Tunnels {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid tunnel '%s', must be vxlan or geneve", s)
+}
+
+// Port returns the UDP destination port the tunnel is sent to
+func (t Tunnel) Port() int {
+	if t == TunnelGeneve {
+		return 6081
+	}
+	return 4789
+}
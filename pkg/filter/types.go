@@ -0,0 +1,577 @@
+// Package filter defines PacketFilter, the packet selection criteria shared
+// by the generators and the comparison, with validation and conversion to
+// and from a tcpdump expression.
+package filter
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PacketFilter represents a structured packet filtering rule
+type PacketFilter struct {
+	Protocol string `yaml:"protocol" json:"protocol,omitempty"` // tcp, udp, icmp, arp, rarp, or an IPv4 protocol number (empty means any)
+	SrcIP    string `yaml:"src-ip" json:"src-ip,omitempty"`     // source IP address or CIDR (empty means any)
+	DstIP    string `yaml:"dst-ip" json:"dst-ip,omitempty"`     // destination IP address or CIDR (empty means any)
+	SrcPort  int    `yaml:"src-port" json:"src-port,omitempty"` // source port (0 means any)
+	DstPort  int    `yaml:"dst-port" json:"dst-port,omitempty"` // destination port (0 means any)
+
+	// SrcPorts and DstPorts match any of several ports. Validate moves a
+	// single-entry list into SrcPort or DstPort, which cannot be combined
+	// with a list.
+	SrcPorts []int `yaml:"src-ports,flow" json:"src-ports,omitempty"`
+	DstPorts []int `yaml:"dst-ports,flow" json:"dst-ports,omitempty"`
+
+	// HostIP and Port match either direction, like tcpdump's "host" (or
+	// "net" for a CIDR) and "port" primitives
+	HostIP string `yaml:"host" json:"host,omitempty"` // source or destination IP address or CIDR (empty means any)
+	Port   int    `yaml:"port" json:"port,omitempty"` // source or destination port (0 means any)
+
+	// Between holds two networks (CIDR or bare address); when set, traffic
+	// in either direction between them matches
+	Between []string `yaml:"between,flow" json:"between,omitempty"`
+
+	// VLAN matches only 802.1Q-tagged frames, with the IP header after the
+	// tag. VLANID additionally requires that VLAN ID and implies VLAN.
+	VLAN   bool `yaml:"vlan" json:"vlan,omitempty"`
+	VLANID int  `yaml:"vlan-id" json:"vlan-id,omitempty"` // 1-4095 (0 means any tag)
+
+	// EtherType matches frames of any link-layer protocol by its EtherType
+	// (0x0600-0xffff), like tcpdump's "ether proto". Like the arp and rarp
+	// protocols, it matches without any IP criteria.
+	EtherType int `yaml:"ether-type" json:"ether-type,omitempty"`
+
+	// Tunnel matches VXLAN or Geneve traffic, and VNI the network it
+	// carries. The other criteria then apply to the outer IPv4 header, or
+	// with Inner to the encapsulated Ethernet frame, like the clauses after
+	// tcpdump's "geneve" primitive. VLAN and the link type always describe
+	// the outer frame.
+	Tunnel Tunnel `yaml:"tunnel" json:"tunnel,omitempty"`
+	VNI    int    `yaml:"vni" json:"vni,omitempty"` // 1-16777215 (0 means any)
+	Inner  bool   `yaml:"inner" json:"inner,omitempty"`
+
+	// LinkType is the capture's data link type (empty means Ethernet). It
+	// is not part of the expression but moves every header offset.
+	LinkType LinkType `yaml:"link-type" json:"link-type,omitempty"`
+}
+
+// Between returns a filter matching any IP traffic between networks a and b,
+// in either direction. Further criteria may be set on the result.
+func Between(a, b string) *PacketFilter {
+	return &PacketFilter{Between: []string{a, b}}
+}
+
+// ParseNet parses a CIDR or a bare address, which is treated as a host
+// network (/32 or /128)
+func ParseNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %s", s)
+		}
+		return ipnet, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid network: %s", s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// Validate checks if the filter configuration is valid. Its errors wrap
+// ErrInvalidFilter.
+func (f *PacketFilter) Validate() error {
+	return invalid(f.validate())
+}
+
+// validate is Validate without the ErrInvalidFilter wrapping
+func (f *PacketFilter) validate() error {
+	// Validate protocol
+	if f.Protocol != "" {
+		protocol := strings.ToLower(f.Protocol)
+		if n, err := strconv.Atoi(protocol); err == nil {
+			if n < 0 || n > 255 {
+				return fmt.Errorf("invalid protocol number %d, must be 0-255", n)
+			}
+			protocol = strconv.Itoa(n)
+		} else if protocol != "tcp" && protocol != "udp" && protocol != "icmp" && protocol != "arp" && protocol != "rarp" {
+			return fmt.Errorf("invalid protocol '%s', must be tcp, udp, icmp, arp, rarp, or a number 0-255", f.Protocol)
+		}
+		f.Protocol = protocol
+	}
+
+	// Validate source IP
+	if f.SrcIP != "" {
+		if _, err := ParseNet(f.SrcIP); err != nil {
+			return fmt.Errorf("invalid source IP address: %s", f.SrcIP)
+		}
+	}
+
+	// Validate destination IP
+	if f.DstIP != "" {
+		if _, err := ParseNet(f.DstIP); err != nil {
+			return fmt.Errorf("invalid destination IP address: %s", f.DstIP)
+		}
+	}
+
+	// A packet has a single address family
+	if f.SrcIP != "" && f.DstIP != "" && isIPv4(f.SrcIP) != isIPv4(f.DstIP) {
+		return fmt.Errorf("source and destination IP addresses must be the same family")
+	}
+
+	// Validate the host
+	if f.HostIP != "" {
+		if _, err := ParseNet(f.HostIP); err != nil {
+			return fmt.Errorf("invalid host IP address: %s", f.HostIP)
+		}
+		for _, other := range []string{f.SrcIP, f.DstIP} {
+			if other != "" && isIPv4(other) != isIPv4(f.HostIP) {
+				return fmt.Errorf("host and source or destination IP addresses must be the same family")
+			}
+		}
+	}
+
+	// Validate the network pair
+	if len(f.Between) > 0 {
+		if len(f.Between) != 2 {
+			return fmt.Errorf("between requires exactly two networks, got %d", len(f.Between))
+		}
+		if f.SrcIP != "" || f.DstIP != "" {
+			return fmt.Errorf("between cannot be combined with source or destination IP")
+		}
+		var nets [2]*net.IPNet
+		for i, n := range f.Between {
+			ipnet, err := ParseNet(n)
+			if err != nil {
+				return err
+			}
+			nets[i] = ipnet
+		}
+		if (nets[0].IP.To4() == nil) != (nets[1].IP.To4() == nil) {
+			return fmt.Errorf("between networks must be the same address family")
+		}
+		if f.HostIP != "" && isIPv4(f.HostIP) != (nets[0].IP.To4() != nil) {
+			return fmt.Errorf("host and between networks must be the same address family")
+		}
+	}
+
+	// Validate ports
+	if f.SrcPort < 0 || f.SrcPort > 65535 {
+		return fmt.Errorf("invalid source port %d, must be 0-65535", f.SrcPort)
+	}
+	if f.DstPort < 0 || f.DstPort > 65535 {
+		return fmt.Errorf("invalid destination port %d, must be 0-65535", f.DstPort)
+	}
+	if f.Port < 0 || f.Port > 65535 {
+		return fmt.Errorf("invalid port %d, must be 0-65535", f.Port)
+	}
+	if err := validatePortList("source", f.SrcPort, f.SrcPorts); err != nil {
+		return err
+	}
+	if err := validatePortList("destination", f.DstPort, f.DstPorts); err != nil {
+		return err
+	}
+	if len(f.SrcPorts) == 1 {
+		f.SrcPort, f.SrcPorts = f.SrcPorts[0], nil
+	}
+	if len(f.DstPorts) == 1 {
+		f.DstPort, f.DstPorts = f.DstPorts[0], nil
+	}
+
+	// Validate the VLAN tag
+	if f.VLANID < 0 || f.VLANID > 4095 {
+		return fmt.Errorf("invalid VLAN ID %d, must be 0-4095", f.VLANID)
+	}
+	if f.VLANID != 0 {
+		f.VLAN = true
+	}
+
+	// Validate the link type; only Ethernet frames carry 802.1Q tags
+	if f.LinkType != "" {
+		link, err := ParseLinkType(string(f.LinkType))
+		if err != nil {
+			return err
+		}
+		f.LinkType = link
+	}
+	if f.VLAN && !f.LinkType.IsEthernet() {
+		return fmt.Errorf("VLAN filters require the EN10MB link type, got %s", f.LinkType)
+	}
+
+	// Non-IP frames are matched by their EtherType alone, which only
+	// Ethernet and cooked captures carry
+	if f.EtherType != 0 && (f.EtherType < 0x0600 || f.EtherType > 0xffff) {
+		return fmt.Errorf("invalid EtherType 0x%x, must be 0x0600-0xffff", f.EtherType)
+	}
+	if f.EtherType != 0 && f.Protocol != "" {
+		return fmt.Errorf("EtherType and protocol cannot be combined")
+	}
+	if f.EtherProto() != 0 {
+		if f.SrcIP != "" || f.DstIP != "" || f.HostIP != "" || len(f.Between) != 0 || f.HasPorts() {
+			return fmt.Errorf("%s filters cannot have IP addresses or ports", f.linkProtocolName())
+		}
+		if f.LinkType != LinkEN10MB && f.LinkType != LinkLinuxSLL && f.LinkType != "" {
+			return fmt.Errorf("%s filters require the EN10MB or LINUX_SLL link type, got %s", f.linkProtocolName(), f.LinkType)
+		}
+	}
+
+	if err := f.validateTunnel(); err != nil {
+		return err
+	}
+
+	// Check if at least one filter criterion is specified
+	if f.Protocol == "" && f.EtherType == 0 && f.SrcIP == "" && f.DstIP == "" && f.HostIP == "" && !f.HasPorts() && len(f.Between) == 0 && !f.VLAN && f.Tunnel == "" {
+		return fmt.Errorf("at least one filter criterion must be specified")
+	}
+
+	// ICMP doesn't use ports
+	if f.Protocol == "icmp" && f.HasPorts() {
+		return fmt.Errorf("ICMP protocol does not support port filtering")
+	}
+
+	// Like tcpdump's "ip proto", a protocol number matches IPv4 only, and
+	// only TCP, UDP and SCTP have ports where the port checks look
+	if f.HasProtocolNumber() {
+		if n := f.IPProtocol(); f.HasPorts() && n != 6 && n != 17 && n != 132 {
+			return fmt.Errorf("protocol %d does not support port filtering", n)
+		}
+		for _, addr := range append([]string{f.SrcIP, f.DstIP, f.HostIP}, f.Between...) {
+			if addr != "" && !isIPv4(addr) {
+				return fmt.Errorf("protocol numbers match IPv4 only, got %s", addr)
+			}
+		}
+	}
+
+	return nil
+}
+
+// String returns a human-readable representation of the filter
+func (f *PacketFilter) String() string {
+	var parts []string
+
+	if f.VLANID != 0 {
+		parts = append(parts, fmt.Sprintf("VLAN: %d", f.VLANID))
+	} else if f.VLAN {
+		parts = append(parts, "VLAN: any")
+	}
+
+	if f.Tunnel != "" {
+		parts = append(parts, fmt.Sprintf("Tunnel: %s", f.Tunnel))
+		if f.VNI != 0 {
+			parts = append(parts, fmt.Sprintf("VNI: %d", f.VNI))
+		}
+		if f.Inner {
+			parts = append(parts, "Headers: inner")
+		}
+	}
+
+	if f.Protocol != "" {
+		parts = append(parts, fmt.Sprintf("Protocol: %s", f.Protocol))
+	}
+	if f.EtherType != 0 {
+		parts = append(parts, fmt.Sprintf("EtherType: 0x%04x", f.EtherType))
+	}
+	if f.SrcIP != "" {
+		parts = append(parts, fmt.Sprintf("Source IP: %s", f.SrcIP))
+	}
+	if f.DstIP != "" {
+		parts = append(parts, fmt.Sprintf("Destination IP: %s", f.DstIP))
+	}
+	if f.HostIP != "" {
+		parts = append(parts, fmt.Sprintf("Host: %s", f.HostIP))
+	}
+	if len(f.Between) == 2 {
+		parts = append(parts, fmt.Sprintf("Between: %s <-> %s", f.Between[0], f.Between[1]))
+	}
+	if f.SrcPort != 0 {
+		parts = append(parts, fmt.Sprintf("Source Port: %d", f.SrcPort))
+	}
+	if len(f.SrcPorts) > 0 {
+		parts = append(parts, fmt.Sprintf("Source Ports: %s", joinPorts(f.SrcPorts, ", ")))
+	}
+	if f.DstPort != 0 {
+		parts = append(parts, fmt.Sprintf("Destination Port: %d", f.DstPort))
+	}
+	if len(f.DstPorts) > 0 {
+		parts = append(parts, fmt.Sprintf("Destination Ports: %s", joinPorts(f.DstPorts, ", ")))
+	}
+	if f.Port != 0 {
+		parts = append(parts, fmt.Sprintf("Port: %d", f.Port))
+	}
+	if !f.LinkType.IsEthernet() {
+		parts = append(parts, fmt.Sprintf("Link Type: %s", f.LinkType))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// ToTcpdumpFilter converts the filter to tcpdump filter syntax
+func (f *PacketFilter) ToTcpdumpFilter() string {
+	var parts []string
+
+	// "vlan" moves the offsets of every later clause past the tag, so it
+	// must come first
+	if f.VLANID != 0 {
+		parts = append(parts, fmt.Sprintf("vlan %d", f.VLANID))
+	} else if f.VLAN {
+		parts = append(parts, "vlan")
+	}
+
+	// Like "vlan", "geneve" and "vxlan" move the clauses after them to the
+	// encapsulated packet, so the tunnel comes last unless the criteria
+	// are on the inner headers
+	if f.Tunnel != "" && f.Inner {
+		parts = append(parts, f.tunnelClause())
+	}
+
+	if f.HasProtocolNumber() {
+		parts = append(parts, "ip proto "+f.Protocol)
+	} else if f.Protocol != "" {
+		parts = append(parts, f.Protocol)
+	}
+	if f.EtherType != 0 {
+		parts = append(parts, fmt.Sprintf("ether proto 0x%04x", f.EtherType))
+	}
+
+	if f.SrcIP != "" {
+		parts = append(parts, hostOrNet("src", f.SrcIP))
+	}
+
+	if f.DstIP != "" {
+		parts = append(parts, hostOrNet("dst", f.DstIP))
+	}
+
+	// Either direction: "host" for an address, "net" for a CIDR
+	if f.HostIP != "" {
+		if strings.Contains(f.HostIP, "/") {
+			parts = append(parts, fmt.Sprintf("net %s", canonicalNet(f.HostIP)))
+		} else {
+			parts = append(parts, fmt.Sprintf("host %s", f.HostIP))
+		}
+	}
+
+	// Symmetric net-to-net match; the OR needs its own parentheses because
+	// the parts are joined with "and"
+	if len(f.Between) == 2 {
+		a, b := canonicalNet(f.Between[0]), canonicalNet(f.Between[1])
+		parts = append(parts, fmt.Sprintf("((src net %s and dst net %s) or (src net %s and dst net %s))", a, b, b, a))
+	}
+
+	if f.SrcPort != 0 {
+		parts = append(parts, fmt.Sprintf("src port %d", f.SrcPort))
+	}
+	if len(f.SrcPorts) > 0 {
+		parts = append(parts, portAlternatives("src", f.SrcPorts))
+	}
+
+	if f.DstPort != 0 {
+		parts = append(parts, fmt.Sprintf("dst port %d", f.DstPort))
+	}
+	if len(f.DstPorts) > 0 {
+		parts = append(parts, portAlternatives("dst", f.DstPorts))
+	}
+
+	if f.Port != 0 {
+		parts = append(parts, fmt.Sprintf("port %d", f.Port))
+	}
+
+	if f.Tunnel != "" && !f.Inner {
+		parts = append(parts, f.tunnelClause())
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// tunnelClause returns the tcpdump primitive for the tunnel and its VNI
+func (f *PacketFilter) tunnelClause() string {
+	if f.VNI != 0 {
+		return fmt.Sprintf("%s %d", f.Tunnel, f.VNI)
+	}
+	return string(f.Tunnel)
+}
+
+// validateTunnel checks the tunnel and the criteria that go with it. The
+// tunnel fixes the outer protocol and ports, and both generators parse
+// IPv4 headers only on either side of it.
+func (f *PacketFilter) validateTunnel() error {
+	if f.VNI < 0 || f.VNI > MaxVNI {
+		return fmt.Errorf("invalid VNI %d, must be 0-%d", f.VNI, MaxVNI)
+	}
+	if f.Tunnel == "" {
+		if f.VNI != 0 || f.Inner {
+			return fmt.Errorf("VNI and inner criteria require a tunnel")
+		}
+		return nil
+	}
+
+	tunnel, err := ParseTunnel(string(f.Tunnel))
+	if err != nil {
+		return err
+	}
+	f.Tunnel = tunnel
+
+	if f.EtherProto() != 0 {
+		return fmt.Errorf("%s filters cannot match %s traffic", f.linkProtocolName(), f.Tunnel)
+	}
+	if !f.Inner && f.Protocol != "" && f.Protocol != "udp" {
+		return fmt.Errorf("%s traffic is UDP, set inner to match the encapsulated protocol", f.Tunnel)
+	}
+	if !f.Inner && f.HasPorts() {
+		return fmt.Errorf("%s decides the outer ports, set inner to match the encapsulated ports", f.Tunnel)
+	}
+	for _, addr := range append([]string{f.SrcIP, f.DstIP, f.HostIP}, f.Between...) {
+		if addr != "" && !isIPv4(addr) {
+			return fmt.Errorf("%s filters support IPv4 addresses only, got %s", f.Tunnel, addr)
+		}
+	}
+	return nil
+}
+
+// SrcPortList returns the source ports the filter accepts, or nil for any
+func (f *PacketFilter) SrcPortList() []int {
+	if f.SrcPort != 0 {
+		return []int{f.SrcPort}
+	}
+	return f.SrcPorts
+}
+
+// DstPortList returns the destination ports the filter accepts, or nil for
+// any
+func (f *PacketFilter) DstPortList() []int {
+	if f.DstPort != 0 {
+		return []int{f.DstPort}
+	}
+	return f.DstPorts
+}
+
+// HasPorts reports whether the filter restricts the source, destination or
+// either port
+func (f *PacketFilter) HasPorts() bool {
+	return len(f.SrcPortList()) > 0 || len(f.DstPortList()) > 0 || f.Port != 0
+}
+
+// IPProtocol returns the IP protocol number the filter requires, or -1 if
+// it requires none, as for arp, rarp and filters without a protocol
+func (f *PacketFilter) IPProtocol() int {
+	switch f.Protocol {
+	case "icmp":
+		return 1
+	case "tcp":
+		return 6
+	case "udp":
+		return 17
+	}
+	if n, err := strconv.Atoi(f.Protocol); err == nil {
+		return n
+	}
+	return -1
+}
+
+// HasProtocolNumber reports whether the protocol is given as a number,
+// which like tcpdump's "ip proto" only matches IPv4
+func (f *PacketFilter) HasProtocolNumber() bool {
+	_, err := strconv.Atoi(f.Protocol)
+	return err == nil
+}
+
+// EtherProto returns the EtherType a link-layer filter matches: ARP, RARP
+// or the EtherType field. It is 0 for filters on IP packets.
+func (f *PacketFilter) EtherProto() uint16 {
+	switch {
+	case f.EtherType != 0:
+		return uint16(f.EtherType)
+	case f.Protocol == "arp":
+		return 0x0806
+	case f.Protocol == "rarp":
+		return 0x8035
+	}
+	return 0
+}
+
+// linkProtocolName names the protocol of a link-layer filter for messages
+func (f *PacketFilter) linkProtocolName() string {
+	if f.EtherType != 0 {
+		return fmt.Sprintf("EtherType 0x%04x", f.EtherType)
+	}
+	return strings.ToUpper(f.Protocol)
+}
+
+// MaxPortList bounds a port list. Jump offsets no longer do, since branches
+// out of 8-bit reach go through long jumps; the bound keeps a program
+// with a source and a destination list of this length, at one check per
+// port, within the kernel's 4096 instructions (BPF_MAXINSNS).
+const MaxPortList = 1024
+
+// validatePortList checks a port list and that it is not combined with the
+// single port of the same direction
+func validatePortList(direction string, port int, ports []int) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	if port != 0 {
+		return fmt.Errorf("a %s port and a %s port list cannot both be set", direction, direction)
+	}
+	if len(ports) > MaxPortList {
+		return fmt.Errorf("too many %s ports (%d), at most %d fit in a program the kernel loads", direction, len(ports), MaxPortList)
+	}
+	seen := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("invalid %s port %d in list, must be 1-65535", direction, p)
+		}
+		if seen[p] {
+			return fmt.Errorf("duplicate %s port %d in list", direction, p)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// portAlternatives returns a parenthesized tcpdump clause matching any of
+// the ports in one direction
+func portAlternatives(direction string, ports []int) string {
+	clauses := make([]string, len(ports))
+	for i, p := range ports {
+		clauses[i] = fmt.Sprintf("%s port %d", direction, p)
+	}
+	return "(" + strings.Join(clauses, " or ") + ")"
+}
+
+// joinPorts formats a port list with the separator
+func joinPorts(ports []int, sep string) string {
+	s := make([]string, len(ports))
+	for i, p := range ports {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, sep)
+}
+
+// canonicalNet returns the network in CIDR form with host bits cleared,
+// which tcpdump requires
+func canonicalNet(s string) string {
+	ipnet, err := ParseNet(s)
+	if err != nil {
+		return s
+	}
+	return ipnet.String()
+}
+
+// hostOrNet returns a tcpdump "src"/"dst" clause, using the "net"
+// qualifier for CIDRs
+func hostOrNet(direction, addr string) string {
+	if strings.Contains(addr, "/") {
+		return fmt.Sprintf("%s net %s", direction, canonicalNet(addr))
+	}
+	return fmt.Sprintf("%s %s", direction, addr)
+}
+
+// isIPv4 reports whether addr is an IPv4 address or CIDR
+func isIPv4(addr string) bool {
+	ipnet, err := ParseNet(addr)
+	return err == nil && ipnet.IP.To4() != nil
+}
@@ -0,0 +1,54 @@
+// Package logging holds the logger used by the library packages (tcpdump,
+// prototype, compare). It discards everything until SetLogger installs a
+// logger, so embedding programs stay quiet unless they opt in. Progress
+// is logged at debug level and fallbacks or ignored input at warn level.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+)
+
+// logger is the current logger; see SetLogger
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	logger.Store(slog.New(discardHandler{}))
+}
+
+// Logger returns the logger library packages write to
+func Logger() *slog.Logger {
+	return logger.Load()
+}
+
+// SetLogger replaces the library logger. A nil logger discards again.
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+	logger.Store(l)
+}
+
+// NewHandler returns a text handler for command-line use: records at or
+// above level, without timestamps
+func NewHandler(w io.Writer, level slog.Level) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+// discardHandler drops every record without formatting it
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,360 @@
+package packet
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Layer is one decoded header of a packet
+type Layer struct {
+	Name   string // e.g. "Ethernet", "IPv4", "TCP"
+	Fields string // the fields worth reading, e.g. "10.0.0.1 > 10.0.0.2 ttl 64"
+}
+
+// Decoded is a packet split into its headers, outermost first, as far as
+// they can be read
+type Decoded struct {
+	Layers  []Layer
+	Payload int // bytes after the last decoded header
+
+	// Truncated names the header the packet ends inside, if any
+	Truncated string
+}
+
+// Decode splits a packet of the link type into its headers with gopacket:
+// the link layer, with any 802.1Q tags, ARP, IPv4 or IPv6, and TCP, UDP,
+// ICMP or SCTP, looking into VXLAN and Geneve packets. Decoding stops at
+// the first header it does not know or the packet cuts short; what it
+// read so far is kept.
+func Decode(data []byte, link filter.LinkType) *Decoded {
+	d := &Decoded{}
+	switch link {
+	case filter.LinkLinuxSLL:
+		d.decode(data, layers.LayerTypeLinuxSLL)
+	case filter.LinkRaw:
+		// No link header: the IP version is the first nibble
+		switch {
+		case len(data) == 0:
+			d.Truncated = "IP"
+		case data[0]>>4 == 4:
+			d.decode(data, layers.LayerTypeIPv4)
+		case data[0]>>4 == 6:
+			d.decode(data, layers.LayerTypeIPv6)
+		default:
+			d.add("Raw", "IP version %d", data[0]>>4)
+			d.Payload = len(data)
+		}
+	case filter.LinkNull:
+		d.decode(data, layers.LayerTypeLoopback)
+	default:
+		d.decode(data, layers.LayerTypeEthernet)
+	}
+	return d
+}
+
+// String renders the layers on one line, e.g. "Ethernet 02:00:00:00:00:01 >
+// 02:00:00:00:00:02, IPv4 10.0.0.1 > 10.0.0.2 ttl 64 id 1, TCP 0 > 80 [S]
+// seq 0 win 65535"
+func (d *Decoded) String() string {
+	parts := make([]string, 0, len(d.Layers)+1)
+	for _, l := range d.Layers {
+		parts = append(parts, strings.TrimSpace(l.Name+" "+l.Fields))
+	}
+	switch {
+	case d.Truncated != "":
+		parts = append(parts, fmt.Sprintf("%s header truncated", d.Truncated))
+	case d.Payload > 0:
+		parts = append(parts, fmt.Sprintf("%d bytes payload", d.Payload))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// add appends a layer
+func (d *Decoded) add(name, format string, args ...any) {
+	d.Layers = append(d.Layers, Layer{Name: name, Fields: fmt.Sprintf(format, args...)})
+}
+
+// layerNames names the gopacket layers Decode reports
+var layerNames = map[gopacket.LayerType]string{
+	layers.LayerTypeEthernet: "Ethernet",
+	layers.LayerTypeDot1Q:    "802.1Q",
+	layers.LayerTypeLinuxSLL: "Linux cooked",
+	layers.LayerTypeLoopback: "Null",
+	layers.LayerTypeARP:      "ARP",
+	layers.LayerTypeIPv4:     "IPv4",
+	layers.LayerTypeIPv6:     "IPv6",
+	layers.LayerTypeTCP:      "TCP",
+	layers.LayerTypeUDP:      "UDP",
+	layers.LayerTypeICMPv4:   "ICMP",
+	layers.LayerTypeICMPv6:   "ICMPv6",
+	layers.LayerTypeSCTP:     "SCTP",
+	layers.LayerTypeVXLAN:    "VXLAN",
+	layers.LayerTypeGeneve:   "Geneve",
+}
+
+// decodingLayer is the part of gopacket.DecodingLayer Decode uses
+type decodingLayer interface {
+	gopacket.Layer
+	DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error
+}
+
+// newLayer returns the gopacket layer to decode a header of the type
+// into, or nil for a header Decode does not read
+func newLayer(t gopacket.LayerType) decodingLayer {
+	switch t {
+	case layers.LayerTypeEthernet:
+		return &layers.Ethernet{}
+	case layers.LayerTypeDot1Q:
+		return &layers.Dot1Q{}
+	case layers.LayerTypeLinuxSLL:
+		return &layers.LinuxSLL{}
+	case layers.LayerTypeLoopback:
+		return &layers.Loopback{}
+	case layers.LayerTypeARP:
+		return &layers.ARP{}
+	case layers.LayerTypeIPv4:
+		return &layers.IPv4{}
+	case layers.LayerTypeIPv6:
+		return &layers.IPv6{}
+	case layers.LayerTypeTCP:
+		return &layers.TCP{}
+	case layers.LayerTypeUDP:
+		return &layers.UDP{}
+	case layers.LayerTypeICMPv4:
+		return &layers.ICMPv4{}
+	case layers.LayerTypeICMPv6:
+		return &layers.ICMPv6{}
+	case layers.LayerTypeSCTP:
+		return &layers.SCTP{}
+	case layers.LayerTypeVXLAN:
+		return &layers.VXLAN{}
+	case layers.LayerTypeGeneve:
+		return &geneve{}
+	}
+	return nil
+}
+
+// headerLens are the fixed header lengths of the layers whose gopacket
+// decoders fail without flagging a packet that ends inside them as
+// truncated
+var headerLens = map[gopacket.LayerType]int{
+	layers.LayerTypeEthernet: EthernetHeaderLen,
+	layers.LayerTypeLinuxSLL: 16,
+	layers.LayerTypeLoopback: 4,
+	layers.LayerTypeSCTP:     SCTPHeaderLen,
+	layers.LayerTypeVXLAN:    filter.TunnelHeaderLen,
+}
+
+// feedback records whether a gopacket decoder found the packet cut short
+type feedback struct{ truncated bool }
+
+func (f *feedback) SetTruncated() { f.truncated = true }
+
+// decode decodes data starting with a header of type t, one gopacket layer
+// at a time, until a transport header, a header it does not read or one
+// that fails to decode
+func (d *Decoded) decode(data []byte, t gopacket.LayerType) {
+	for {
+		layer := newLayer(t)
+		if layer == nil {
+			d.Payload = len(data)
+			return
+		}
+		df := &feedback{}
+		if err := layer.DecodeFromBytes(data, df); err != nil {
+			if df.truncated || len(data) < headerLens[t] {
+				d.Truncated, d.Payload = layerNames[t], 0
+				return
+			}
+			d.add(layerNames[t], "invalid: %v", err)
+			d.Payload = len(data)
+			return
+		}
+		d.describe(layer)
+		data = layer.LayerPayload()
+		d.Payload = len(data)
+		if t = nextLayer(layer); t == gopacket.LayerTypeZero {
+			return
+		}
+	}
+}
+
+// nextLayer returns the type of the header after a decoded one, or
+// LayerTypeZero when decoding stops after it: at a transport header, other
+// than UDP to a tunnel port, or at a later IPv4 fragment. Unlike gopacket's
+// own choice, it reads QinQ tags and RARP, the first fragment's transport
+// header, and tunnels by destination port only.
+func nextLayer(l decodingLayer) gopacket.LayerType {
+	switch l := l.(type) {
+	case *layers.Ethernet:
+		if l.Length != 0 {
+			return gopacket.LayerTypeZero // 802.3 length, not a type
+		}
+		return etherTypeLayer(l.EthernetType)
+	case *layers.Dot1Q:
+		return etherTypeLayer(l.Type)
+	case *layers.LinuxSLL:
+		return etherTypeLayer(l.EthernetType)
+	case *layers.Loopback:
+		return l.Family.LayerType()
+	case *layers.IPv4:
+		if l.FragOffset != 0 {
+			return gopacket.LayerTypeZero
+		}
+		return l.Protocol.LayerType()
+	case *layers.IPv6:
+		return l.NextHeader.LayerType()
+	case *layers.UDP:
+		switch int(l.DstPort) {
+		case filter.TunnelVXLAN.Port():
+			return layers.LayerTypeVXLAN
+		case filter.TunnelGeneve.Port():
+			return layers.LayerTypeGeneve
+		}
+	case *layers.VXLAN:
+		return layers.LayerTypeEthernet
+	case *geneve:
+		return l.Protocol.LayerType()
+	}
+	return gopacket.LayerTypeZero
+}
+
+// etherTypeLayer returns the layer type of a link-layer protocol
+func etherTypeLayer(etherType layers.EthernetType) gopacket.LayerType {
+	switch etherType {
+	case 0x9100: // legacy QinQ
+		return layers.LayerTypeDot1Q
+	case 0x8035: // RARP
+		return layers.LayerTypeARP
+	}
+	return etherType.LayerType()
+}
+
+// describe adds a decoded layer
+func (d *Decoded) describe(l decodingLayer) {
+	name := layerNames[l.LayerType()]
+	switch l := l.(type) {
+	case *layers.Ethernet:
+		protocol := etherTypeText(l.EthernetType)
+		if l.Length != 0 {
+			protocol = fmt.Sprintf("length %d", l.Length)
+		}
+		d.add(name, "%s > %s %s", l.SrcMAC, l.DstMAC, protocol)
+	case *layers.Dot1Q:
+		d.add(name, "vlan %d priority %d %s", l.VLANIdentifier, l.Priority, etherTypeText(l.Type))
+	case *layers.LinuxSLL:
+		d.add(name, "type %d from %s %s", l.PacketType, l.Addr, etherTypeText(l.EthernetType))
+	case *layers.Loopback:
+		d.add(name, "family %d", l.Family)
+	case *layers.ARP:
+		d.add(name, "%s who-has %s tell %s (%s)", arpOperation(l.Operation),
+			net.IP(l.DstProtAddress), net.IP(l.SourceProtAddress), net.HardwareAddr(l.SourceHwAddress))
+	case *layers.IPv4:
+		d.add(name, "%s", ipv4Fields(l))
+	case *layers.IPv6:
+		fields := fmt.Sprintf("%s > %s hlim %d len %d", l.SrcIP, l.DstIP, l.HopLimit, l.Length)
+		if _, named := layerNames[l.NextHeader.LayerType()]; !named {
+			fields += fmt.Sprintf(" next header %d", l.NextHeader)
+		}
+		d.add(name, "%s", fields)
+	case *layers.TCP:
+		d.add(name, "%d > %d [%s] seq %d ack %d win %d", l.SrcPort, l.DstPort, tcpFlags(l), l.Seq, l.Ack, l.Window)
+	case *layers.UDP:
+		d.add(name, "%d > %d len %d", l.SrcPort, l.DstPort, l.Length)
+	case *layers.ICMPv4:
+		d.add(name, "type %d code %d", l.TypeCode.Type(), l.TypeCode.Code())
+	case *layers.ICMPv6:
+		d.add(name, "type %d code %d", l.TypeCode.Type(), l.TypeCode.Code())
+	case *layers.SCTP:
+		d.add(name, "%d > %d vtag 0x%x", l.SrcPort, l.DstPort, l.VerificationTag)
+	case *layers.VXLAN:
+		d.add(name, "vni %d", l.VNI)
+	case *geneve:
+		d.add(name, "vni %d options %d", l.VNI, l.OptionsLength)
+	}
+}
+
+// geneve is gopacket's Geneve layer, ending where the header's total
+// option length says. gopacket reads option lengths from 4 of their 5
+// bits and walks them with an 8-bit offset, so the options it parses can
+// end elsewhere.
+type geneve struct{ layers.Geneve }
+
+func (g *geneve) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < filter.TunnelHeaderLen {
+		df.SetTruncated()
+		return fmt.Errorf("Geneve header needs %d bytes, got %d", filter.TunnelHeaderLen, len(data))
+	}
+	_ = g.Geneve.DecodeFromBytes(data, gopacket.NilDecodeFeedback) // only the fixed fields are used
+	n := filter.TunnelHeaderLen + int(g.OptionsLength)
+	if len(data) < n {
+		df.SetTruncated()
+		return fmt.Errorf("Geneve options need %d bytes, got %d", n, len(data))
+	}
+	g.Contents, g.Payload = data[:n], data[n:]
+	return nil
+}
+
+// ipv4Fields formats an IPv4 header's addresses, flags and fragment
+// offset, and its protocol unless Decode reads it
+func ipv4Fields(ip *layers.IPv4) string {
+	fields := fmt.Sprintf("%s > %s ttl %d id %d len %d", ip.SrcIP, ip.DstIP, ip.TTL, ip.Id, ip.Length)
+	var flags []string
+	if ip.Flags&layers.IPv4DontFragment != 0 {
+		flags = append(flags, "DF")
+	}
+	if ip.Flags&layers.IPv4MoreFragments != 0 {
+		flags = append(flags, "MF")
+	}
+	if len(flags) > 0 {
+		fields += " [" + strings.Join(flags, ",") + "]"
+	}
+	if ip.FragOffset != 0 {
+		fields += fmt.Sprintf(" frag offset %d", int(ip.FragOffset)*8)
+	}
+	if _, named := layerNames[ip.Protocol.LayerType()]; !named {
+		fields += fmt.Sprintf(" proto %d", ip.Protocol)
+	}
+	return fields
+}
+
+// arpOperation names an ARP or RARP operation
+func arpOperation(op uint16) string {
+	ops := map[uint16]string{1: "request", 2: "reply", 3: "reverse request", 4: "reverse reply"}
+	if name, ok := ops[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("op %d", op)
+}
+
+// tcpFlags spells TCP flags as tcpdump does, e.g. "S." for SYN-ACK
+func tcpFlags(tcp *layers.TCP) string {
+	var sb strings.Builder
+	for _, f := range []struct {
+		set    bool
+		letter byte
+	}{{tcp.FIN, 'F'}, {tcp.SYN, 'S'}, {tcp.RST, 'R'}, {tcp.PSH, 'P'}, {tcp.URG, 'U'}, {tcp.ECE, 'E'}, {tcp.CWR, 'W'}, {tcp.ACK, '.'}} {
+		if f.set {
+			sb.WriteByte(f.letter)
+		}
+	}
+	if sb.Len() == 0 {
+		return "none"
+	}
+	return sb.String()
+}
+
+// etherTypeText names a link-layer protocol value
+func etherTypeText(etherType layers.EthernetType) string {
+	names := map[layers.EthernetType]string{0x0800: "ipv4", 0x86dd: "ipv6", 0x0806: "arp", 0x8035: "rarp", 0x8100: "802.1q", 0x88a8: "802.1ad", 0x9100: "qinq"}
+	if name, ok := names[etherType]; ok {
+		return name
+	}
+	return fmt.Sprintf("ethertype 0x%04x", uint16(etherType))
+}
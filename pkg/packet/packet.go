@@ -0,0 +1,331 @@
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Header sizes used when building frames
+const (
+	EthernetHeaderLen = 14
+	VLANTagLen        = 4
+	IPv4HeaderLen     = 20
+	TCPHeaderLen      = 20
+	UDPHeaderLen      = 8
+	ICMPHeaderLen     = 8
+	SCTPHeaderLen     = 12 // common header, without chunks
+	ARPLen            = 28 // Ethernet/IPv4 ARP and RARP
+)
+
+// Spec describes a packet by its header fields. Unset fields receive
+// deterministic defaults so a spec only needs the fields under test.
+type Spec struct {
+	Protocol string `yaml:"protocol" json:"protocol"` // tcp, udp, icmp, arp, rarp, or an IP protocol number
+	SrcIP    string `yaml:"src-ip" json:"src-ip"`     // defaults to 10.0.0.1
+	DstIP    string `yaml:"dst-ip" json:"dst-ip"`     // defaults to 10.0.0.2
+	SrcPort  int    `yaml:"src-port" json:"src-port"` // tcp/udp/sctp source port
+	DstPort  int    `yaml:"dst-port" json:"dst-port"` // tcp/udp/sctp destination port
+	FragOff  int    `yaml:"frag-offset" json:"frag-offset"`
+	Payload  string `yaml:"payload" json:"payload"` // payload bytes as text
+
+	// MoreFragments sets the IPv4 more-fragments flag. With a zero
+	// FragOff the packet is a first fragment, which still carries the
+	// transport header.
+	MoreFragments bool `yaml:"more-fragments" json:"more-fragments,omitempty"`
+
+	// EtherType builds a frame of that type carrying only Payload, in
+	// place of the protocol
+	EtherType int `yaml:"ether-type" json:"ether-type,omitempty"`
+
+	// VLAN inserts an 802.1Q tag carrying VLANID; a non-zero VLANID
+	// implies VLAN
+	VLAN   bool `yaml:"vlan" json:"vlan,omitempty"`
+	VLANID int  `yaml:"vlan-id" json:"vlan-id,omitempty"`
+
+	// Tunnel makes the packet UDP carrying Inner, built as an Ethernet
+	// frame, behind a VXLAN or Geneve header with VNI. DstPort defaults to
+	// the tunnel's port, and GeneveOptions adds that many 4-byte words of
+	// Geneve options before the frame.
+	Tunnel        string `yaml:"tunnel" json:"tunnel,omitempty"`
+	VNI           int    `yaml:"vni" json:"vni,omitempty"`
+	GeneveOptions int    `yaml:"geneve-options" json:"geneve-options,omitempty"`
+	Inner         *Spec  `yaml:"inner" json:"inner,omitempty"`
+}
+
+// etherTypes maps the supported non-IP protocol names to EtherTypes
+var etherTypes = map[string]uint16{
+	"arp":  0x0806,
+	"rarp": 0x8035,
+}
+
+// protocolNumbers maps the supported transport names to IP protocol numbers
+var protocolNumbers = map[string]uint8{
+	"icmp": 1,
+	"tcp":  6,
+	"udp":  17,
+}
+
+// ipProtocol returns the IP protocol number of a transport name or a
+// number 0-255
+func ipProtocol(protocol string) (uint8, bool) {
+	if number, ok := protocolNumbers[protocol]; ok {
+		return number, true
+	}
+	number, err := strconv.ParseUint(protocol, 10, 8)
+	return uint8(number), err == nil
+}
+
+// Build serializes the spec into an Ethernet/IPv4 frame
+func (s *Spec) Build() ([]byte, error) {
+	return s.BuildFor(filter.LinkEN10MB)
+}
+
+// BuildFor serializes the spec into a packet behind the link-layer header
+// of the given link type. Only Ethernet frames can carry a VLAN tag, and
+// only Ethernet and cooked captures can carry ARP or another EtherType.
+func (s *Spec) BuildFor(link filter.LinkType) ([]byte, error) {
+	if s.Tunnel != "" {
+		return s.encapsulated(link)
+	}
+
+	protocol := strings.ToLower(s.Protocol)
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	etherType, nonIP := etherTypes[protocol]
+	number, isIP := ipProtocol(protocol)
+	if !isIP && !nonIP {
+		return nil, fmt.Errorf("unsupported packet protocol '%s'", s.Protocol)
+	}
+	if s.EtherType < 0 || s.EtherType > 0xffff {
+		return nil, fmt.Errorf("EtherType %d out of range", s.EtherType)
+	}
+
+	srcIP, err := parseIPv4(s.SrcIP, "10.0.0.1")
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet source IP: %w", err)
+	}
+	dstIP, err := parseIPv4(s.DstIP, "10.0.0.2")
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet destination IP: %w", err)
+	}
+
+	if s.SrcPort < 0 || s.SrcPort > 65535 || s.DstPort < 0 || s.DstPort > 65535 {
+		return nil, fmt.Errorf("packet ports must be 0-65535")
+	}
+	if s.FragOff < 0 || s.FragOff > 0x1fff {
+		return nil, fmt.Errorf("fragment offset %d out of range", s.FragOff)
+	}
+	if s.VLANID < 0 || s.VLANID > 0xfff {
+		return nil, fmt.Errorf("VLAN ID %d out of range", s.VLANID)
+	}
+	if (s.VLAN || s.VLANID != 0) && !link.IsEthernet() {
+		return nil, fmt.Errorf("VLAN tags need the EN10MB link type, got %s", link)
+	}
+
+	var network []byte
+	switch {
+	case s.EtherType != 0:
+		etherType = uint16(s.EtherType)
+		network = []byte(s.Payload)
+	case nonIP:
+		network = arpRequest(protocol, srcIP, dstIP)
+	default:
+		network = s.ipv4(number, srcIP, dstIP)
+		etherType = 0x0800
+	}
+	if etherType != 0x0800 && (link == filter.LinkRaw || link == filter.LinkNull) {
+		return nil, fmt.Errorf("EtherType 0x%04x needs the EN10MB or LINUX_SLL link type, got %s", etherType, link)
+	}
+
+	frame := make([]byte, 0, EthernetHeaderLen+VLANTagLen+len(network))
+	switch link {
+	case filter.LinkLinuxSLL:
+		frame = append(frame, 0x00, 0x00) // sent to us
+		frame = append(frame, 0x00, 0x01) // ARPHRD_ETHER
+		frame = append(frame, 0x00, 0x06) // address length
+		// Source MAC, padded to 8 bytes
+		frame = append(frame, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00)
+		frame = binary.BigEndian.AppendUint16(frame, etherType)
+	case filter.LinkRaw:
+	case filter.LinkNull:
+		frame = binary.BigEndian.AppendUint32(frame, filter.NullIPv4) // AF_INET
+	default:
+		frame = append(frame, 0x02, 0x00, 0x00, 0x00, 0x00, 0x02) // destination MAC
+		frame = append(frame, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01) // source MAC
+		if s.VLAN || s.VLANID != 0 {
+			frame = append(frame, 0x81, 0x00, byte(s.VLANID>>8), byte(s.VLANID)) // 802.1Q tag
+		}
+		frame = binary.BigEndian.AppendUint16(frame, etherType)
+	}
+	return append(frame, network...), nil
+}
+
+// encapsulated builds the spec as a VXLAN or Geneve packet, with the
+// tunnel header and the inner frame as the UDP payload
+func (s *Spec) encapsulated(link filter.LinkType) ([]byte, error) {
+	tunnel, err := filter.ParseTunnel(s.Tunnel)
+	if err != nil {
+		return nil, err
+	}
+	if protocol := strings.ToLower(s.Protocol); protocol != "" && protocol != "udp" {
+		return nil, fmt.Errorf("%s packets are UDP, got protocol '%s'", tunnel, s.Protocol)
+	}
+	if s.VNI < 0 || s.VNI > filter.MaxVNI {
+		return nil, fmt.Errorf("VNI %d out of range", s.VNI)
+	}
+	if s.GeneveOptions < 0 || s.GeneveOptions > 63 {
+		return nil, fmt.Errorf("Geneve option length %d out of range, must be 0-63 words", s.GeneveOptions)
+	}
+	if s.GeneveOptions != 0 && tunnel != filter.TunnelGeneve {
+		return nil, fmt.Errorf("only Geneve packets carry options")
+	}
+
+	inner := s.Inner
+	if inner == nil {
+		inner = &Spec{}
+	}
+	frame, err := inner.Build()
+	if err != nil {
+		return nil, fmt.Errorf("inner packet: %w", err)
+	}
+
+	header := make([]byte, filter.TunnelHeaderLen+4*s.GeneveOptions)
+	if tunnel == filter.TunnelGeneve {
+		header[0] = byte(s.GeneveOptions)              // version 0 and option length
+		binary.BigEndian.PutUint16(header[2:], 0x6558) // Transparent Ethernet Bridging
+		if s.GeneveOptions > 0 {
+			// One experimental option filling the option space
+			binary.BigEndian.PutUint16(header[8:], 0xffff)
+			header[11] = byte(s.GeneveOptions - 1)
+		}
+	} else {
+		header[0] = 0x08 // VNI is valid
+	}
+	binary.BigEndian.PutUint32(header[4:], uint32(s.VNI)<<8)
+
+	outer := *s
+	outer.Tunnel, outer.VNI, outer.GeneveOptions, outer.Inner = "", 0, 0, nil
+	outer.Protocol = "udp"
+	if outer.DstPort == 0 {
+		outer.DstPort = tunnel.Port()
+	}
+	outer.Payload = string(header) + string(frame) + s.Payload
+	return outer.BuildFor(link)
+}
+
+// ipv4 builds the IPv4 header and transport header of the packet
+func (s *Spec) ipv4(protocol uint8, srcIP, dstIP net.IP) []byte {
+	var l4 []byte
+	switch protocol {
+	case 6: // tcp
+		l4 = make([]byte, TCPHeaderLen)
+		binary.BigEndian.PutUint16(l4[0:], uint16(s.SrcPort))
+		binary.BigEndian.PutUint16(l4[2:], uint16(s.DstPort))
+		l4[12] = (TCPHeaderLen / 4) << 4
+		l4[13] = 0x02 // SYN
+		binary.BigEndian.PutUint16(l4[14:], 65535)
+	case 17: // udp
+		l4 = make([]byte, UDPHeaderLen)
+		binary.BigEndian.PutUint16(l4[0:], uint16(s.SrcPort))
+		binary.BigEndian.PutUint16(l4[2:], uint16(s.DstPort))
+		binary.BigEndian.PutUint16(l4[4:], uint16(UDPHeaderLen+len(s.Payload)))
+	case 1: // icmp
+		l4 = make([]byte, ICMPHeaderLen)
+		l4[0] = 8 // echo request
+	case 132: // sctp
+		l4 = make([]byte, SCTPHeaderLen)
+		binary.BigEndian.PutUint16(l4[0:], uint16(s.SrcPort))
+		binary.BigEndian.PutUint16(l4[2:], uint16(s.DstPort))
+	}
+	l4 = append(l4, s.Payload...)
+
+	ip := make([]byte, IPv4HeaderLen)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(IPv4HeaderLen+len(l4)))
+	binary.BigEndian.PutUint16(ip[4:], 1)
+	flags := uint16(s.FragOff)
+	if s.MoreFragments {
+		flags |= 0x2000
+	}
+	binary.BigEndian.PutUint16(ip[6:], flags)
+	ip[8] = 64
+	ip[9] = protocol
+	copy(ip[12:], srcIP)
+	copy(ip[16:], dstIP)
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+	return append(ip, l4...)
+}
+
+// arpRequest builds an ARP request from srcIP asking for dstIP, or for
+// rarp a reverse request, which carries no protocol addresses yet
+func arpRequest(protocol string, srcIP, dstIP net.IP) []byte {
+	arp := make([]byte, ARPLen)
+	binary.BigEndian.PutUint16(arp[0:], 1)      // Ethernet hardware
+	binary.BigEndian.PutUint16(arp[2:], 0x0800) // IPv4 protocol addresses
+	arp[4], arp[5] = 6, 4
+	copy(arp[8:], []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}) // sender MAC
+	if protocol == "rarp" {
+		binary.BigEndian.PutUint16(arp[6:], 3)                     // reverse request
+		copy(arp[18:], []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}) // target MAC
+		return arp
+	}
+	binary.BigEndian.PutUint16(arp[6:], 1) // request
+	copy(arp[14:], srcIP)
+	copy(arp[24:], dstIP)
+	return arp
+}
+
+// ParseHex decodes a hex dump into packet bytes. Whitespace, colons and a
+// leading 0x are ignored so output copied from most tools can be pasted as is.
+func ParseHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "0x")
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', ':':
+			return -1
+		}
+		return r
+	}, s)
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet hex: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty packet hex")
+	}
+	return data, nil
+}
+
+// parseIPv4 parses an IPv4 address, using def when the value is empty
+func parseIPv4(value, def string) (net.IP, error) {
+	if value == "" {
+		value = def
+	}
+	ip := net.ParseIP(value).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("'%s' is not an IPv4 address", value)
+	}
+	return ip, nil
+}
+
+// checksum computes the Internet checksum over a header
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,267 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/internal/lifecycle"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Magic numbers for the classic libpcap file format
+const (
+	magicMicros = 0xa1b2c3d4
+	magicNanos  = 0xa1b23c4d
+
+	fileHeaderLen   = 24
+	recordHeaderLen = 16
+
+	// Link-layer header types stored in the file header. These are the
+	// LINKTYPE_ values, which only match the DLT_ values for some types.
+	LinkTypeNull     = 0
+	LinkTypeEthernet = 1
+	LinkTypeRaw      = 101
+	LinkTypeLinuxSLL = 113
+
+	// DefaultSnaplen is the snapshot length written to new files
+	DefaultSnaplen = 262144
+)
+
+// Packet is a single captured frame
+type Packet struct {
+	Timestamp time.Time
+	Data      []byte
+	OrigLen   int // length of the packet on the wire
+}
+
+// Reader reads packets from a pcap file
+type Reader struct {
+	r        *bufio.Reader
+	closer   io.Closer
+	handle   *lifecycle.Handle
+	order    binary.ByteOrder
+	nanos    bool
+	LinkType uint32
+	Snaplen  uint32
+}
+
+// Open opens a pcap file for reading
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pcap: %w", err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	r.handle = lifecycle.Track("pcap reader", path)
+	return r, nil
+}
+
+// NewReader parses the pcap file header from r
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, fileHeaderLen)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("failed to read pcap header: %w", err)
+	}
+
+	reader := &Reader{r: br}
+	switch {
+	case binary.LittleEndian.Uint32(hdr) == magicMicros:
+		reader.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr) == magicMicros:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(hdr) == magicNanos:
+		reader.order, reader.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(hdr) == magicNanos:
+		reader.order, reader.nanos = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("not a pcap file (magic 0x%08x)", binary.LittleEndian.Uint32(hdr))
+	}
+
+	reader.Snaplen = reader.order.Uint32(hdr[16:])
+	reader.LinkType = reader.order.Uint32(hdr[20:])
+	return reader, nil
+}
+
+// Next returns the next packet, or io.EOF when the file is exhausted
+func (r *Reader) Next() (*Packet, error) {
+	hdr := make([]byte, recordHeaderLen)
+	if _, err := io.ReadFull(r.r, hdr); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read record header: %w", err)
+	}
+
+	sec := r.order.Uint32(hdr[0:])
+	frac := r.order.Uint32(hdr[4:])
+	capLen := r.order.Uint32(hdr[8:])
+	origLen := r.order.Uint32(hdr[12:])
+
+	if capLen > DefaultSnaplen*4 {
+		return nil, fmt.Errorf("record length %d is implausibly large", capLen)
+	}
+
+	data := make([]byte, capLen)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("failed to read record data: %w", err)
+	}
+
+	nsec := int64(frac)
+	if !r.nanos {
+		nsec *= 1000
+	}
+	return &Packet{
+		Timestamp: time.Unix(int64(sec), nsec).UTC(),
+		Data:      data,
+		OrigLen:   int(origLen),
+	}, nil
+}
+
+// ReadAll returns every remaining packet
+func (r *Reader) ReadAll() ([]*Packet, error) {
+	var packets []*Packet
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return packets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+	}
+}
+
+// Close releases the underlying file when the reader was opened by path
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	err := r.closer.Close()
+	r.closer = nil
+	r.handle.Release()
+	return err
+}
+
+// Writer writes packets to a pcap file with microsecond timestamps
+type Writer struct {
+	w      *bufio.Writer
+	closer io.Closer
+	handle *lifecycle.Handle
+}
+
+// Create creates a pcap file for writing Ethernet frames
+func Create(path string) (*Writer, error) {
+	return CreateLinkType(path, LinkTypeEthernet)
+}
+
+// CreateLinkType creates a pcap file for writing packets of a link type
+func CreateLinkType(path string, linkType uint32) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pcap: %w", err)
+	}
+	w, err := NewWriter(f, linkType)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	w.handle = lifecycle.Track("pcap writer", path)
+	return w, nil
+}
+
+// NewWriter writes the pcap file header to w
+func NewWriter(w io.Writer, linkType uint32) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	hdr := make([]byte, fileHeaderLen)
+	binary.LittleEndian.PutUint32(hdr[0:], magicMicros)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], DefaultSnaplen)
+	binary.LittleEndian.PutUint32(hdr[20:], linkType)
+	if _, err := bw.Write(hdr); err != nil {
+		return nil, fmt.Errorf("failed to write pcap header: %w", err)
+	}
+	return &Writer{w: bw}, nil
+}
+
+// Write appends a packet record
+func (w *Writer) Write(p *Packet) error {
+	origLen := p.OrigLen
+	if origLen < len(p.Data) {
+		origLen = len(p.Data)
+	}
+
+	hdr := make([]byte, recordHeaderLen)
+	binary.LittleEndian.PutUint32(hdr[0:], uint32(p.Timestamp.Unix()))
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(p.Timestamp.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(len(p.Data)))
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(origLen))
+
+	if _, err := w.w.Write(hdr); err != nil {
+		return fmt.Errorf("failed to write record header: %w", err)
+	}
+	if _, err := w.w.Write(p.Data); err != nil {
+		return fmt.Errorf("failed to write record data: %w", err)
+	}
+	return nil
+}
+
+// Close flushes buffered records and closes the file if the writer owns it
+func (w *Writer) Close() error {
+	if err := w.w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush pcap: %w", err)
+	}
+	if w.closer == nil {
+		return nil
+	}
+	err := w.closer.Close()
+	w.closer = nil
+	w.handle.Release()
+	return err
+}
+
+// linkTypes maps link types to the values pcap files record for them
+var linkTypes = map[filter.LinkType]uint32{
+	filter.LinkEN10MB:   LinkTypeEthernet,
+	filter.LinkLinuxSLL: LinkTypeLinuxSLL,
+	filter.LinkRaw:      LinkTypeRaw,
+	filter.LinkNull:     LinkTypeNull,
+}
+
+// LinkTypeOf returns the value pcap files record for a link type; the
+// empty link type is Ethernet
+func LinkTypeOf(link filter.LinkType) uint32 {
+	if link == "" {
+		return LinkTypeEthernet
+	}
+	return linkTypes[link]
+}
+
+// WriteFile writes packets of a link type to a new pcap file. The
+// timestamps count milliseconds from the epoch, so that writing the same
+// packets twice gives the same file.
+func WriteFile(path string, link filter.LinkType, packets [][]byte) error {
+	w, err := CreateLinkType(path, LinkTypeOf(link))
+	if err != nil {
+		return err
+	}
+	for i, data := range packets {
+		if err := w.Write(&Packet{Timestamp: time.UnixMilli(int64(i)), Data: data}); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
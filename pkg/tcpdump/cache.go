@@ -0,0 +1,169 @@
+package tcpdump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// ReferenceCache keeps the programs external reference tools compile on
+// disk, so that batch runs and repeated invocations run each tool once per
+// filter. Entries are keyed by expression, link type, snapshot length,
+// optimization and the tool with its version: upgrading tcpdump or
+// switching or rebuilding container images misses the old entries instead
+// of replaying them. Entries are never expired; remove Dir to clear the
+// cache.
+type ReferenceCache struct {
+	Dir string
+}
+
+// Cache is the cache GenerateBPF reads and fills (nil disables caching)
+var Cache *ReferenceCache
+
+// DefaultCacheDir returns the cache directory under the user's cache
+// directory, such as ~/.cache/antrea-bpf-prototype/reference on Linux
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "antrea-bpf-prototype", "reference"), nil
+}
+
+// cacheEntry is the file format of one cached program
+type cacheEntry struct {
+	Filter      string `json:"filter"`
+	LinkType    string `json:"link-type,omitempty"`
+	Snaplen     int    `json:"snaplen"`
+	Unoptimized bool   `json:"unoptimized,omitempty"`
+	Tool        string `json:"tool"`   // command line and version of the compiler, e.g. "tcpdump (tcpdump version 4.99.4)"
+	Source      string `json:"source"` // compiler that produced the program
+	Program     string `json:"program"`
+}
+
+// key returns the entry's fields that select it
+func (e *cacheEntry) key() string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%t\x00%s", e.LinkType, e.Filter, e.Snaplen, e.Unoptimized, e.Tool)
+}
+
+// path returns the file of an entry
+func (c *ReferenceCache) path(e *cacheEntry) string {
+	sum := sha256.Sum256([]byte(e.key()))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// cacheTool identifies the tool the compiler runs, with its version, or
+// returns "" for compilers not worth caching: libpcap compiles in-process
+// as fast as a cache lookup, replayed fixtures are already on disk, and
+// the mock compiler runs no tool. Without a version, an upgrade could not
+// be told apart, so tools that do not report one are not cached either.
+// A container image is versioned by its ID rather than its reference, as
+// a tag such as :latest can move to a new build; an image not pulled yet
+// has no ID.
+func cacheTool(c ReferenceCompiler) string {
+	var command []string
+	var version string
+	switch c := c.(type) {
+	case autoCompiler:
+		if LibpcapAvailable || !isTcpdumpAvailable() {
+			return ""
+		}
+		return cacheTool(Tcpdump)
+	case *CommandCompiler:
+		if len(c.Command) == 0 {
+			return ""
+		}
+		command, version = c.Command, commandVersion(c.Command[0])
+	case *TsharkCompiler:
+		command, version = []string{"dumpcap", "-i", c.Interface}, commandVersion("dumpcap")
+	case *ContainerCompiler:
+		command, version = []string{c.runtime(), c.image()}, c.imageID()
+	}
+	if version == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(command, " "), version)
+}
+
+// Load returns the cached program of a compilation with the tool, or nil
+// if there is none
+func (c *ReferenceCache) Load(filterExpr string, link filter.LinkType, unoptimized bool, snaplen int, tool string) (*BPFCode, error) {
+	want := &cacheEntry{Filter: filterExpr, LinkType: string(link), Snaplen: snaplen, Unoptimized: unoptimized, Tool: tool}
+	path := c.path(want)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse cache entry %s: %w", path, err)
+	}
+	if e.key() != want.key() {
+		return nil, nil
+	}
+	instructions, err := ParseOutput(e.Program, FormatDDD)
+	if err != nil {
+		return nil, fmt.Errorf("cache entry %s: %w", path, err)
+	}
+	return &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       e.Filter,
+			InstructionCount: len(instructions),
+			LinkType:         e.LinkType,
+			Snaplen:          e.Snaplen,
+		},
+		RawOutput:   e.Program,
+		Source:      e.Source,
+		Unoptimized: e.Unoptimized,
+		Cached:      path,
+	}, nil
+}
+
+// Save caches a program the tool compiled. The entry is written to a
+// temporary file and renamed, so concurrent comparisons never read half
+// an entry.
+func (c *ReferenceCache) Save(code *BPFCode, tool string) error {
+	e := &cacheEntry{
+		Filter:      code.FilterExpr,
+		LinkType:    code.LinkType,
+		Snaplen:     code.Snaplen,
+		Unoptimized: code.Unoptimized,
+		Tool:        tool,
+		Source:      code.Source,
+		Program:     bpf.FormatDDD(code.Instructions),
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(e))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
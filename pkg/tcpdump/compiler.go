@@ -0,0 +1,234 @@
+package tcpdump
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+)
+
+// ReferenceCompiler produces the reference program for a filter. Each
+// implementation wraps one tool, so comparisons can use whichever is
+// installed, or several at once to cross-check them.
+type ReferenceCompiler interface {
+	// Name identifies the compiler, as accepted by ParseReference
+	Name() string
+
+	// Available reports whether the compiler can run here, for example
+	// whether its binary is on the PATH
+	Available() bool
+
+	// Compile compiles a normalized filter. GenerateBPFWithOptions
+	// normalizes the filter before calling it.
+	Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error)
+}
+
+// Reference compilers selectable by name
+var (
+	// Auto uses libpcap in-process when built in, then a tcpdump binary,
+	// then, with ContainerFallback, tcpdump in a container, then a
+	// recorded fixture, and finally the mock compiler; this is what
+	// GenerateBPF does by default
+	Auto ReferenceCompiler = autoCompiler{}
+
+	// Libpcap compiles in-process with pcap_compile (-tags libpcap)
+	Libpcap ReferenceCompiler = libpcapCompiler{}
+
+	// Tcpdump runs the tcpdump binary on the PATH
+	Tcpdump ReferenceCompiler = &CommandCompiler{Command: []string{"tcpdump"}}
+
+	// Tshark runs Wireshark's dumpcap on the loopback interface
+	Tshark ReferenceCompiler = &TsharkCompiler{Interface: "lo"}
+
+	// Container runs tcpdump in a Docker or Podman container; set its
+	// fields to choose the runtime or image
+	Container = &ContainerCompiler{}
+
+	// Replay reads programs recorded in Fixtures
+	Replay ReferenceCompiler = replayCompiler{}
+
+	// Mock is the built-in IPv4 compiler used when nothing else is
+	// installed
+	Mock ReferenceCompiler = mockCompiler{}
+)
+
+// referenceCompilers lists the compilers ParseReference accepts
+var referenceCompilers = []ReferenceCompiler{Auto, Libpcap, Tcpdump, Tshark, Container, Replay, Mock}
+
+// ContainerFallback makes Auto compile in a container (see Container)
+// rather than with the mock compiler when tcpdump is not installed
+var ContainerFallback = false
+
+// ParseReference returns the compiler with the given name
+func ParseReference(name string) (ReferenceCompiler, error) {
+	var names []string
+	for _, c := range referenceCompilers {
+		if c.Name() == name {
+			return c, nil
+		}
+		names = append(names, c.Name())
+	}
+	return nil, fmt.Errorf("invalid reference compiler '%s', must be one of %s", name, strings.Join(names, ", "))
+}
+
+// ParseReferences parses a comma-separated list of compiler names
+func ParseReferences(s string) ([]ReferenceCompiler, error) {
+	var compilers []ReferenceCompiler
+	for _, name := range strings.Split(s, ",") {
+		c, err := ParseReference(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range compilers {
+			if other == c {
+				return nil, fmt.Errorf("reference compiler '%s' listed twice", name)
+			}
+		}
+		compilers = append(compilers, c)
+	}
+	return compilers, nil
+}
+
+// autoCompiler picks the best available compiler for each filter
+type autoCompiler struct{}
+
+func (autoCompiler) Name() string    { return "auto" }
+func (autoCompiler) Available() bool { return true }
+
+func (autoCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	log := logging.Logger()
+	filterExpr := f.ToTcpdumpFilter()
+
+	// Prefer compiling in-process, which needs neither tcpdump nor
+	// output parsing
+	if LibpcapAvailable {
+		code, err := compileLibpcap(filterExpr, f.LinkType, opts)
+		if err == nil {
+			return code, nil
+		}
+		log.Warn("libpcap compile failed, falling back to tcpdump", "filter", filterExpr, "err", err)
+	}
+
+	// Check if tcpdump is available
+	if !isTcpdumpAvailable() {
+		if ContainerFallback && Container.Available() {
+			code, err := Container.Compile(ctx, f, opts)
+			if err == nil {
+				return code, nil
+			}
+			log.Warn("container compile failed, falling back to the mock compiler", "filter", filterExpr, "err", err)
+		}
+		if Fixtures != nil {
+			code, err := Fixtures.Load(filterExpr, f.LinkType, opts.Unoptimized, opts.snaplen())
+			if err != nil {
+				log.Warn("failed to replay fixture, using the mock compiler", "filter", filterExpr, "err", err)
+			}
+			if code != nil {
+				return code, nil
+			}
+		}
+		log.Debug("tcpdump not available, using mock compiler", "os", runtime.GOOS)
+		return Mock.Compile(ctx, f, opts)
+	}
+
+	return compileExpr(ctx, []string{"tcpdump"}, filterExpr, f.LinkType, opts)
+}
+
+// libpcapCompiler compiles in-process
+type libpcapCompiler struct{}
+
+func (libpcapCompiler) Name() string    { return SourceLibpcap }
+func (libpcapCompiler) Available() bool { return LibpcapAvailable }
+
+func (libpcapCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	return compileLibpcap(f.ToTcpdumpFilter(), f.LinkType, opts)
+}
+
+// CommandCompiler runs a tcpdump command: the binary plus any wrapper,
+// such as a container runtime invocation (see CompileExpr)
+type CommandCompiler struct {
+	Command []string
+}
+
+func (c *CommandCompiler) Name() string { return SourceTcpdump }
+
+func (c *CommandCompiler) Available() bool {
+	return len(c.Command) > 0 && lookPath(c.Command[0])
+}
+
+func (c *CommandCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	return compileExpr(ctx, c.Command, f.ToTcpdumpFilter(), f.LinkType, opts)
+}
+
+// TsharkCompiler compiles with dumpcap -d, the capture engine of tshark
+// and Wireshark. dumpcap compiles for a live interface, whose link type
+// must support the filter's (-y); it has no way to disable libpcap's
+// optimizer.
+type TsharkCompiler struct {
+	Interface string // interface to compile for, e.g. "lo"
+}
+
+func (c *TsharkCompiler) Name() string    { return SourceTshark }
+func (c *TsharkCompiler) Available() bool { return lookPath("dumpcap") }
+
+func (c *TsharkCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	filterExpr := f.ToTcpdumpFilter()
+	if opts.Unoptimized {
+		return nil, fmt.Errorf("dumpcap cannot disable libpcap's optimizer")
+	}
+	argv := []string{"dumpcap", "-i", c.Interface}
+	if !f.LinkType.IsEthernet() {
+		argv = append(argv, "-y", string(f.LinkType))
+	}
+	if opts.Snaplen != 0 {
+		argv = append(argv, "-s", strconv.Itoa(opts.Snaplen))
+	}
+	argv = append(argv, "-f", filterExpr, "-d")
+	stdout, err := runCommand(ctx, "dumpcap", argv, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// dumpcap prints the program as tcpdump -d does, after a line naming
+	// the interface
+	var program []string
+	for _, line := range strings.Split(string(stdout), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "(") {
+			program = append(program, line)
+		}
+	}
+	instructions, err := ParseOutput(strings.Join(program, "\n"), FormatD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dumpcap output: %w", err)
+	}
+	return &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       filterExpr,
+			InstructionCount: len(instructions),
+			LinkType:         string(f.LinkType),
+			Snaplen:          opts.snaplen(),
+		},
+		RawOutput: string(stdout),
+		Source:    SourceTshark,
+	}, nil
+}
+
+// mockCompiler is the built-in compiler
+type mockCompiler struct{}
+
+func (mockCompiler) Name() string    { return SourceMock }
+func (mockCompiler) Available() bool { return true }
+
+func (mockCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	filterExpr := f.ToTcpdumpFilter()
+	if opts.Unoptimized {
+		logging.Logger().Warn("mock compiler has no unoptimized form, using the optimized program", "filter", filterExpr)
+	}
+	return generateMockBPF(f, filterExpr, opts.snaplen())
+}
@@ -0,0 +1,91 @@
+package tcpdump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// DefaultContainerImage is a small image with tcpdump on its PATH
+const DefaultContainerImage = "docker.io/corfr/tcpdump"
+
+// SourceContainer marks programs compiled by tcpdump in a container
+const SourceContainer = "container"
+
+// ContainerRuntimes are the container runtimes ContainerCompiler looks
+// for, in order
+var ContainerRuntimes = []string{"docker", "podman"}
+
+// ContainerCompiler runs tcpdump inside a container, for hosts such as
+// macOS and Windows developer machines that have Docker or Podman but no
+// tcpdump. The container has no network and is removed after each run.
+// The first run pulls the image, which may take longer than DefaultTimeout;
+// pull it beforehand.
+type ContainerCompiler struct {
+	Runtime string // docker or podman (empty means the first installed)
+	Image   string // image with tcpdump on its PATH (empty means DefaultContainerImage)
+}
+
+func (c *ContainerCompiler) Name() string    { return SourceContainer }
+func (c *ContainerCompiler) Available() bool { return c.runtime() != "" }
+
+func (c *ContainerCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	runtime := c.runtime()
+	if runtime == "" {
+		return nil, fmt.Errorf("%w: no container runtime found (tried %v)", ErrTcpdumpUnavailable, ContainerRuntimes)
+	}
+	command := []string{runtime, "run", "--rm", "--network", "none", "--entrypoint", "tcpdump", c.image()}
+	code, err := compileExpr(ctx, command, f.ToTcpdumpFilter(), f.LinkType, opts)
+	if err != nil {
+		return nil, err
+	}
+	code.Source = SourceContainer
+	return code, nil
+}
+
+// runtime returns the configured runtime, or the first one installed
+func (c *ContainerCompiler) runtime() string {
+	if c.Runtime != "" {
+		if lookPath(c.Runtime) {
+			return c.Runtime
+		}
+		return ""
+	}
+	for _, r := range ContainerRuntimes {
+		if lookPath(r) {
+			return r
+		}
+	}
+	return ""
+}
+
+func (c *ContainerCompiler) image() string {
+	if c.Image == "" {
+		return DefaultContainerImage
+	}
+	return c.Image
+}
+
+// imageID returns the ID of the image, which changes when a tag such as
+// :latest moves to a new build, or "" if the image is not present yet
+func (c *ContainerCompiler) imageID() string {
+	runtime := c.runtime()
+	if runtime == "" {
+		return ""
+	}
+	key := runtime + " " + c.image()
+	if id, ok := imageIDs.Load(key); ok {
+		return id.(string)
+	}
+	out, err := runCommand(context.Background(), runtime, []string{runtime, "image", "inspect", "--format", "{{.Id}}", c.image()}, 0)
+	if err != nil {
+		return ""
+	}
+	id := strings.TrimSpace(string(out))
+	if id != "" {
+		imageIDs.Store(key, id)
+	}
+	return id
+}
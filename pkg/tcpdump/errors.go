@@ -0,0 +1,31 @@
+package tcpdump
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTcpdumpUnavailable is wrapped by the errors of a reference compiler
+// that cannot run here: tcpdump or a wrapper missing from the PATH, no
+// container runtime, a build without the libpcap backend or no recorded
+// fixture. Callers may fall back to another compiler; other errors mean
+// the filter or the compiler's output is at fault.
+var ErrTcpdumpUnavailable = errors.New("reference compiler not available")
+
+// ParseError reports a line of a dumped program that cannot be parsed
+type ParseError struct {
+	Line  int    // line of the output, from 1
+	Field string // field that failed: count, code, jt, jf or k (empty when the line is malformed)
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid instruction format at line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("invalid %s at line %d: %v", e.Field, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
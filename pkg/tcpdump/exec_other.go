@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tcpdump
+
+import "os/exec"
+
+// killProcessGroup leaves cancellation to kill the command alone, since
+// process groups are a Unix facility
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package tcpdump
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup runs the command in its own process group and makes
+// cancellation kill the whole group, so that a wrapper such as a container
+// runtime does not leave tcpdump running
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,143 @@
+package tcpdump
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+)
+
+// DefaultFixtureDir holds the repository's recorded reference programs
+const DefaultFixtureDir = "testdata/fixtures"
+
+// FixtureStore records the programs a real reference compiler produces,
+// keyed by expression, link type, optimization and snapshot length, and
+// replays them where
+// no such compiler is installed. Tests stay hermetic without trading real
+// reference data for the mock compiler's approximation.
+type FixtureStore struct {
+	Dir    string
+	Record bool // save every program compiled by a real compiler
+}
+
+// Fixtures is the store GenerateBPF records to and Auto replays from
+// before resorting to the mock compiler (nil means neither)
+var Fixtures *FixtureStore
+
+// fixture is the file format of one recorded program
+type fixture struct {
+	Filter      string `json:"filter"`
+	LinkType    string `json:"link-type,omitempty"`
+	Unoptimized bool   `json:"unoptimized,omitempty"`
+	Snaplen     int    `json:"snaplen,omitempty"` // omitted for pcap.DefaultSnaplen
+	Source      string `json:"source"`            // compiler that produced the program
+	Version     string `json:"version"`           // its version (see SourceVersion)
+	Program     string `json:"program"`           // in tcpdump -ddd format
+}
+
+// path returns the fixture file of a compilation. The default snapshot
+// length is left out of the key, so fixtures recorded before it was
+// configurable keep their files.
+func (s *FixtureStore) path(filterExpr string, link filter.LinkType, unoptimized bool, snaplen int) string {
+	key := fmt.Sprintf("%s\x00%s\x00%t", link, filterExpr, unoptimized)
+	if snaplen != pcap.DefaultSnaplen {
+		key += fmt.Sprintf("\x00%d", snaplen)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Save records a program. Programs from the mock compiler, files and
+// fixtures are not real compiler output and are refused.
+func (s *FixtureStore) Save(code *BPFCode) error {
+	switch code.Source {
+	case SourceLibpcap, SourceTcpdump, SourceTshark, SourceContainer:
+	default:
+		return fmt.Errorf("cannot record a %s program as a fixture", code.Source)
+	}
+	if code.Fixture != "" {
+		return fmt.Errorf("cannot record a replayed program as a fixture")
+	}
+	fx := &fixture{
+		Filter:      code.FilterExpr,
+		LinkType:    code.LinkType,
+		Unoptimized: code.Unoptimized,
+		Source:      code.Source,
+		Version:     SourceVersion(code.Source),
+		Program:     bpf.FormatDDD(code.Instructions),
+	}
+	if code.Snaplen != pcap.DefaultSnaplen {
+		fx.Snaplen = code.Snaplen
+	}
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	path := s.path(code.FilterExpr, filter.LinkType(code.LinkType), code.Unoptimized, code.Snaplen)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// Load replays the recorded program of a compilation, or returns nil if
+// none was recorded
+func (s *FixtureStore) Load(filterExpr string, link filter.LinkType, unoptimized bool, snaplen int) (*BPFCode, error) {
+	path := s.path(filterExpr, link, unoptimized, snaplen)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var fx fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	instructions, err := ParseOutput(fx.Program, FormatDDD)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       fx.Filter,
+			InstructionCount: len(instructions),
+			LinkType:         fx.LinkType,
+			Snaplen:          snaplen,
+		},
+		RawOutput:   fx.Program,
+		Source:      fx.Source,
+		Unoptimized: fx.Unoptimized,
+		Fixture:     path,
+	}, nil
+}
+
+// replayCompiler only replays fixtures
+type replayCompiler struct{}
+
+func (replayCompiler) Name() string    { return "replay" }
+func (replayCompiler) Available() bool { return Fixtures != nil }
+
+func (replayCompiler) Compile(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	filterExpr := f.ToTcpdumpFilter()
+	code, err := Fixtures.Load(filterExpr, f.LinkType, opts.Unoptimized, opts.snaplen())
+	if err != nil {
+		return nil, err
+	}
+	if code == nil {
+		return nil, fmt.Errorf("%w: no fixture for '%s' in %s; record one where tcpdump is installed", ErrTcpdumpUnavailable, filterExpr, Fixtures.Dir)
+	}
+	return code, nil
+}
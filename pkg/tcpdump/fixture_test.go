@@ -0,0 +1,108 @@
+package tcpdump_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/testcase"
+)
+
+const fixtureDir = "../../testdata/fixtures"
+
+// TestFixturesReachable checks that every committed fixture is stored
+// where Load looks for its compilation, so none is dead weight
+func TestFixturesReachable(t *testing.T) {
+	store := &tcpdump.FixtureStore{Dir: fixtureDir}
+	paths, err := filepath.Glob(filepath.Join(fixtureDir, "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no fixtures in %s: %v", fixtureDir, err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fx struct {
+			Filter      string `json:"filter"`
+			LinkType    string `json:"link-type"`
+			Unoptimized bool   `json:"unoptimized"`
+			Snaplen     int    `json:"snaplen"`
+		}
+		if err := json.Unmarshal(data, &fx); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		snaplen := fx.Snaplen
+		if snaplen == 0 {
+			snaplen = pcap.DefaultSnaplen
+		}
+		code, err := store.Load(fx.Filter, filter.LinkType(fx.LinkType), fx.Unoptimized, snaplen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code == nil || code.Fixture != path {
+			t.Errorf("%s: '%s' is not replayed from this file", path, fx.Filter)
+		}
+	}
+}
+
+// TestReplay runs the reference compiler in replay mode over the cases of
+// the test-case suites that have a fixture, and checks that the replayed
+// programs decide each packet as expected. Cases with another fragment
+// policy than tcpdump's are only replayed.
+func TestReplay(t *testing.T) {
+	tcpdump.Fixtures = &tcpdump.FixtureStore{Dir: fixtureDir}
+	defer func() { tcpdump.Fixtures = nil }()
+
+	paths, err := filepath.Glob("../../testcases/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no test-case suites found: %v", err)
+	}
+	replayed := 0
+	for _, path := range paths {
+		suite, err := testcase.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range suite.Cases {
+			f := c.Filter
+			code, err := tcpdump.GenerateBPFWithOptions(context.Background(), &f, tcpdump.Options{Compiler: tcpdump.Replay})
+			if errors.Is(err, tcpdump.ErrTcpdumpUnavailable) {
+				continue // no fixture recorded
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", c.Name, err)
+			}
+			replayed++
+			if code.IsMocked || code.Fixture == "" || code.Source != tcpdump.SourceTcpdump {
+				t.Errorf("%s: got a %s program, not a replayed tcpdump one", c.Name, code.Source)
+			}
+			if c.Fragments != "" {
+				continue
+			}
+			for _, p := range c.Packets {
+				data, err := p.Bytes(f.LinkType)
+				if err != nil {
+					t.Fatalf("%s/%s: %v", c.Name, p.Name, err)
+				}
+				result, err := vm.Run(code.Instructions, data)
+				if err != nil {
+					t.Fatalf("%s/%s: %v", c.Name, p.Name, err)
+				}
+				if result.Accepted != p.Match {
+					t.Errorf("%s/%s: replayed program accepted=%t, expected %t", c.Name, p.Name, result.Accepted, p.Match)
+				}
+			}
+		}
+	}
+	if replayed == 0 {
+		t.Fatal("no case was replayed from a fixture")
+	}
+}
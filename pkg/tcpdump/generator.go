@@ -0,0 +1,359 @@
+package tcpdump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/pcap"
+)
+
+// BPFCode represents generated BPF bytecode from tcpdump
+type BPFCode struct {
+	bpf.Code
+	RawOutput   string // raw tcpdump output
+	IsMocked    bool   // true if using mock data (when tcpdump unavailable)
+	Source      string // which compiler produced the program
+	Unoptimized bool   // true if libpcap's optimizer was disabled
+	Fixture     string // fixture file a replayed program was read from
+	Cached      string // cache file the program was read from (see Cache)
+}
+
+// Options adjust how the reference program is compiled
+type Options struct {
+	// Unoptimized disables libpcap's optimizer, like tcpdump -O. The mock
+	// compiler only has an optimized form and ignores it.
+	Unoptimized bool
+
+	// Format is the dump format requested from a tcpdump binary, for
+	// builds whose -ddd output cannot be parsed (default FormatDDD)
+	Format OutputFormat
+
+	// Timeout bounds each tcpdump run (0 means DefaultTimeout). A command
+	// still running then is killed with its process group.
+	Timeout time.Duration
+
+	// Compiler produces the program (nil means Auto)
+	Compiler ReferenceCompiler
+
+	// Snaplen is the snapshot length, like tcpdump -s, which the program
+	// returns for matching packets (0 means pcap.DefaultSnaplen)
+	Snaplen int
+}
+
+// snaplen returns the snapshot length the options select
+func (o Options) snaplen() int {
+	if o.Snaplen == 0 {
+		return pcap.DefaultSnaplen
+	}
+	return o.Snaplen
+}
+
+// DefaultTimeout bounds tcpdump runs whose options set no timeout. A
+// healthy tcpdump compiles in milliseconds; wrappers such as container
+// runtimes may take seconds to start.
+var DefaultTimeout = 30 * time.Second
+
+// Reference compilers, in the order GenerateBPF tries them
+const (
+	SourceLibpcap = "libpcap"
+	SourceTcpdump = "tcpdump"
+	SourceMock    = "mock"
+)
+
+// SourceTshark marks programs compiled by Wireshark's dumpcap, which is
+// only used when selected (see Tshark)
+const SourceTshark = "tshark"
+
+// String returns a formatted representation of the BPF code
+func (bpf *BPFCode) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Tcpdump Filter: %s\n", bpf.FilterExpr))
+	if bpf.IsMocked {
+		sb.WriteString("(Using mock data - tcpdump not available)\n")
+	} else if bpf.Source == SourceLibpcap {
+		sb.WriteString("(Compiled in-process with libpcap)\n")
+	} else if bpf.Source == SourceFile {
+		sb.WriteString("(Loaded from file)\n")
+	} else if bpf.Source == SourceTshark {
+		sb.WriteString("(Compiled by Wireshark's dumpcap)\n")
+	} else if bpf.Source == SourceContainer {
+		sb.WriteString("(Compiled by tcpdump in a container)\n")
+	}
+	if bpf.Fixture != "" {
+		sb.WriteString(fmt.Sprintf("(Replayed %s output from %s)\n", bpf.Source, bpf.Fixture))
+	}
+	if bpf.Cached != "" {
+		sb.WriteString(fmt.Sprintf("(Cached %s output from %s)\n", bpf.Source, bpf.Cached))
+	}
+	if bpf.Unoptimized {
+		sb.WriteString("(libpcap optimizer disabled)\n")
+	}
+	if bpf.Snaplen != 0 && bpf.Snaplen != pcap.DefaultSnaplen {
+		sb.WriteString(fmt.Sprintf("(Snapshot length %d)\n", bpf.Snaplen))
+	}
+	sb.WriteString(fmt.Sprintf("Instructions: %d\n", bpf.InstructionCount))
+	sb.WriteString("BPF Bytecode:\n")
+
+	for i, inst := range bpf.Instructions {
+		sb.WriteString(fmt.Sprintf("  [%2d] %s  %s\n", i, inst.String(), inst.Mnemonic(i)))
+	}
+
+	return sb.String()
+}
+
+// GenerateBPF uses tcpdump to generate reference BPF code, or whichever
+// reference compiler is available (see Auto). Cancelling the context kills
+// a running tcpdump.
+func GenerateBPF(ctx context.Context, f *filter.PacketFilter) (*BPFCode, error) {
+	return GenerateBPFWithOptions(ctx, f, Options{})
+}
+
+// GenerateBPFWithOptions is GenerateBPF with compilation options
+func GenerateBPFWithOptions(ctx context.Context, f *filter.PacketFilter, opts Options) (*BPFCode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Equal filters written differently get the same expression
+	normalized, err := filter.Normalize(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	f = normalized
+
+	// Convert our filter to tcpdump filter expression
+	filterExpr := f.ToTcpdumpFilter()
+	if filterExpr == "" {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	compiler := opts.Compiler
+	if compiler == nil {
+		compiler = Auto
+	}
+	if !compiler.Available() {
+		return nil, fmt.Errorf("%w: %s", ErrTcpdumpUnavailable, compiler.Name())
+	}
+	log := logging.Logger()
+	log.Debug("generating reference BPF", "filter", filterExpr, "compiler", compiler.Name())
+
+	// Running tcpdump dominates batch runtime, so its programs are cached
+	var tool string
+	var code *BPFCode
+	if Cache != nil {
+		tool = cacheTool(compiler)
+	}
+	if tool != "" {
+		if code, err = Cache.Load(filterExpr, f.LinkType, opts.Unoptimized, opts.snaplen(), tool); err != nil {
+			log.Warn("failed to read the reference cache", "filter", filterExpr, "err", err)
+		}
+		if code != nil {
+			log.Debug("reference cache hit", "filter", filterExpr, "entry", code.Cached)
+		}
+	}
+	if code == nil {
+		if code, err = compiler.Compile(ctx, f, opts); err != nil {
+			return nil, err
+		}
+		if tool != "" && !code.IsMocked && code.Fixture == "" {
+			if err := Cache.Save(code, tool); err != nil {
+				log.Warn("failed to cache reference program", "filter", filterExpr, "err", err)
+			}
+		}
+	}
+
+	if Fixtures != nil && Fixtures.Record && !code.IsMocked && code.Fixture == "" {
+		if err := Fixtures.Save(code); err != nil {
+			log.Warn("failed to record fixture", "filter", filterExpr, "err", err)
+		}
+	}
+	return code, nil
+}
+
+// CompileExpr runs a tcpdump command (the binary plus any wrapper, such as
+// a container runtime invocation) with -ddd and parses the resulting
+// program. Link types other than Ethernet are selected with -y. The
+// command is killed after DefaultTimeout or when the context is cancelled.
+func CompileExpr(ctx context.Context, command []string, filterExpr string, link filter.LinkType) (*BPFCode, error) {
+	return compileExpr(ctx, command, filterExpr, link, Options{})
+}
+
+// compileExpr is CompileExpr with compilation options; Unoptimized adds -O,
+// Snaplen adds -s, Format replaces -ddd and Timeout replaces DefaultTimeout
+func compileExpr(ctx context.Context, command []string, filterExpr string, link filter.LinkType, opts Options) (*BPFCode, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty tcpdump command")
+	}
+
+	// Execute tcpdump with -ddd flag to get numeric BPF bytecode
+	// -ddd outputs each instruction as a decimal number on separate lines
+	args := append([]string{}, command[1:]...)
+	if !link.IsEthernet() {
+		args = append(args, "-y", string(link))
+	}
+	if opts.Unoptimized {
+		args = append(args, "-O")
+	}
+	if opts.Snaplen != 0 {
+		args = append(args, "-s", strconv.Itoa(opts.Snaplen))
+	}
+	args = append(args, opts.Format.flag(), filterExpr)
+	stdout, err := runCommand(ctx, "tcpdump", append([]string{command[0]}, args...), opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	log := logging.Logger()
+	rawOutput := string(stdout)
+	log.Debug("tcpdump output", "raw", rawOutput)
+
+	// Parse the tcpdump output
+	instructions, err := ParseOutput(rawOutput, opts.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tcpdump output: %w", err)
+	}
+
+	bpfCode := &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       filterExpr,
+			InstructionCount: len(instructions),
+			LinkType:         string(link),
+			Snaplen:          opts.snaplen(),
+		},
+		RawOutput:   rawOutput,
+		IsMocked:    false,
+		Source:      SourceTcpdump,
+		Unoptimized: opts.Unoptimized,
+	}
+
+	log.Debug("parsed tcpdump output", "instructions", len(instructions))
+	return bpfCode, nil
+}
+
+// RunCommand runs a tcpdump command other than a compilation, such as
+// filtering a pcap, the way the reference compilers run theirs: see
+// runCommand. A timeout of 0 means DefaultTimeout, which --tcpdump-timeout
+// sets.
+func RunCommand(ctx context.Context, name string, argv []string, timeout time.Duration) ([]byte, error) {
+	return runCommand(ctx, name, argv, timeout)
+}
+
+// runCommand runs argv and returns its standard output. The command gets
+// no standard input and is killed with its process group after the timeout
+// (0 means DefaultTimeout) or when the context is cancelled; name labels
+// the tool in errors.
+func runCommand(ctx context.Context, name string, argv []string, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	killProcessGroup(cmd)
+	// Give up on output pipes a killed command's children still hold
+	cmd.WaitDelay = time.Second
+	logging.Logger().Debug("executing "+name, "command", strings.Join(cmd.Args, " "))
+
+	stdout, err := cmd.Output()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if runCtx.Err() != nil {
+		return nil, fmt.Errorf("%s did not finish within %v", name, timeout)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("%s failed: %w\nStderr: %s", name, err, string(exitErr.Stderr))
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %w", ErrTcpdumpUnavailable, err)
+		}
+		return nil, fmt.Errorf("failed to execute %s: %w", name, err)
+	}
+	return stdout, nil
+}
+
+// isTcpdumpAvailable checks if tcpdump command is available
+func isTcpdumpAvailable() bool {
+	return lookPath("tcpdump")
+}
+
+// lookPath reports whether a command is on the PATH
+func lookPath(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// parseTcpdumpOutput parses the numeric output from tcpdump -ddd
+// Format: each line contains 4 decimal numbers: code jt jf k
+// Lines that cannot be parsed are reported as a *ParseError.
+func parseTcpdumpOutput(output string) ([]*bpf.Instruction, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty tcpdump output")
+	}
+
+	// First line should contain the number of instructions
+	numInstructions, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, &ParseError{Line: 1, Field: "count", Err: err}
+	}
+
+	if len(lines) != numInstructions+1 {
+		return nil, fmt.Errorf("expected %d instructions, got %d lines", numInstructions, len(lines)-1)
+	}
+
+	instructions := make([]*bpf.Instruction, 0, numInstructions)
+
+	// Parse each instruction line (skip the first line which is the count)
+	for i := 1; i <= numInstructions; i++ {
+		line := strings.TrimSpace(lines[i])
+		parts := strings.Fields(line)
+
+		if len(parts) != 4 {
+			return nil, &ParseError{Line: i + 1, Err: fmt.Errorf("want 4 fields, got '%s'", line)}
+		}
+
+		// Parse the four components: code, jt, jf, k
+		code, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil {
+			return nil, &ParseError{Line: i + 1, Field: "code", Err: err}
+		}
+
+		jt, err := strconv.ParseUint(parts[1], 10, 8)
+		if err != nil {
+			return nil, &ParseError{Line: i + 1, Field: "jt", Err: err}
+		}
+
+		jf, err := strconv.ParseUint(parts[2], 10, 8)
+		if err != nil {
+			return nil, &ParseError{Line: i + 1, Field: "jf", Err: err}
+		}
+
+		k, err := strconv.ParseUint(parts[3], 10, 32)
+		if err != nil {
+			return nil, &ParseError{Line: i + 1, Field: "k", Err: err}
+		}
+
+		instruction := &bpf.Instruction{
+			Code: uint16(code),
+			JT:   uint8(jt),
+			JF:   uint8(jf),
+			K:    uint32(k),
+		}
+
+		instructions = append(instructions, instruction)
+	}
+
+	return instructions, nil
+}
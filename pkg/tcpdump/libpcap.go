@@ -0,0 +1,37 @@
+package tcpdump
+
+import (
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/logging"
+)
+
+// CompileLibpcap compiles a filter expression in-process with libpcap's
+// pcap_compile for the given link type, producing the same program as
+// tcpdump -y LINK -ddd without running an external binary. It fails unless
+// the binary was built with "-tags libpcap" (see LibpcapAvailable).
+func CompileLibpcap(filterExpr string, link filter.LinkType) (*BPFCode, error) {
+	return compileLibpcap(filterExpr, link, Options{})
+}
+
+// compileLibpcap is CompileLibpcap with compilation options
+func compileLibpcap(filterExpr string, link filter.LinkType, opts Options) (*BPFCode, error) {
+	instructions, err := pcapCompile(filterExpr, link, opts.snaplen(), !opts.Unoptimized)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Logger().Debug("compiled with libpcap", "filter", filterExpr, "instructions", len(instructions))
+	return &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       filterExpr,
+			InstructionCount: len(instructions),
+			LinkType:         string(link),
+			Snaplen:          opts.snaplen(),
+		},
+		RawOutput:   bpf.FormatDDD(instructions),
+		Source:      SourceLibpcap,
+		Unoptimized: opts.Unoptimized,
+	}, nil
+}
@@ -0,0 +1,87 @@
+//go:build cgo && libpcap
+
+package tcpdump
+
+/*
+#cgo LDFLAGS: -lpcap
+#include <stdlib.h>
+#include <pcap/pcap.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// LibpcapAvailable reports whether the binary was built with the libpcap
+// backend
+const LibpcapAvailable = true
+
+// pcapCompileMu serializes pcap_compile, which is not reentrant before
+// libpcap 1.8
+var pcapCompileMu sync.Mutex
+
+// dlts maps link types to libpcap's DLT values, which differ between
+// platforms for DLT_RAW
+var dlts = map[filter.LinkType]C.int{
+	filter.LinkEN10MB:   C.DLT_EN10MB,
+	filter.LinkLinuxSLL: C.DLT_LINUX_SLL,
+	filter.LinkRaw:      C.DLT_RAW,
+	filter.LinkNull:     C.DLT_NULL,
+}
+
+// pcapCompile compiles an expression for the link type exactly as
+// tcpdump -y LINK -ddd does, or with -O when optimize is false
+func pcapCompile(filterExpr string, link filter.LinkType, snaplen int, optimize bool) ([]*bpf.Instruction, error) {
+	if link == "" {
+		link = filter.LinkEN10MB
+	}
+	dlt, ok := dlts[link]
+	if !ok {
+		return nil, fmt.Errorf("unsupported link type %s", link)
+	}
+
+	pcapCompileMu.Lock()
+	defer pcapCompileMu.Unlock()
+
+	handle := C.pcap_open_dead(dlt, C.int(snaplen))
+	if handle == nil {
+		return nil, fmt.Errorf("pcap_open_dead failed")
+	}
+	defer C.pcap_close(handle)
+
+	cexpr := C.CString(filterExpr)
+	defer C.free(unsafe.Pointer(cexpr))
+
+	var optimizeFlag C.int
+	if optimize {
+		optimizeFlag = 1
+	}
+	var program C.struct_bpf_program
+	if C.pcap_compile(handle, &program, cexpr, optimizeFlag, C.PCAP_NETMASK_UNKNOWN) < 0 {
+		return nil, fmt.Errorf("pcap_compile: %s", C.GoString(C.pcap_geterr(handle)))
+	}
+	defer C.pcap_freecode(&program)
+
+	raw := unsafe.Slice(program.bf_insns, int(program.bf_len))
+	instructions := make([]*bpf.Instruction, 0, len(raw))
+	for _, in := range raw {
+		instructions = append(instructions, &bpf.Instruction{
+			Code: uint16(in.code),
+			JT:   uint8(in.jt),
+			JF:   uint8(in.jf),
+			K:    uint32(in.k),
+		})
+	}
+	return instructions, nil
+}
+
+// libpcapVersion returns the linked libpcap's version string
+func libpcapVersion() string {
+	return C.GoString(C.pcap_lib_version())
+}
@@ -0,0 +1,24 @@
+//go:build !cgo || !libpcap
+
+package tcpdump
+
+import (
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// LibpcapAvailable reports whether the binary was built with the libpcap
+// backend
+const LibpcapAvailable = false
+
+// pcapCompile is unavailable without cgo and the libpcap build tag
+func pcapCompile(filterExpr string, link filter.LinkType, snaplen int, optimize bool) ([]*bpf.Instruction, error) {
+	return nil, fmt.Errorf("%w: libpcap backend not built (rebuild with -tags libpcap)", ErrTcpdumpUnavailable)
+}
+
+// libpcapVersion is empty without the libpcap backend
+func libpcapVersion() string {
+	return ""
+}
@@ -0,0 +1,176 @@
+package tcpdump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// Backend is a named way of invoking tcpdump, typically pinned to one
+// libpcap release (e.g. "docker run --rm tcpdump:4.9 tcpdump")
+type Backend struct {
+	Name    string
+	Command []string
+	Format  OutputFormat // dump format to request (default FormatDDD)
+}
+
+// ParseBackend parses a "name=command args..." backend specification
+func ParseBackend(spec string) (*Backend, error) {
+	name, command, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid backend '%s', expected name=command", spec)
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("backend '%s' has an empty command", name)
+	}
+	return &Backend{Name: name, Command: fields}, nil
+}
+
+// MatrixEntry is the reference program one backend produced
+type MatrixEntry struct {
+	Backend *Backend
+	Code    *BPFCode
+	Err     error
+	Group   int // entries with the same group produced identical programs
+}
+
+// Matrix holds the reference programs compiled by every backend
+type Matrix struct {
+	FilterExpr string
+	Entries    []*MatrixEntry
+	Groups     int // number of distinct programs
+}
+
+// RunMatrix compiles the same expression for the link type with every
+// backend and groups identical outputs together
+func RunMatrix(ctx context.Context, backends []*Backend, filterExpr string, link filter.LinkType) *Matrix {
+	m := &Matrix{FilterExpr: filterExpr}
+	var representatives [][]*bpf.Instruction
+
+	for _, b := range backends {
+		entry := &MatrixEntry{Backend: b, Group: -1}
+		m.Entries = append(m.Entries, entry)
+
+		entry.Code, entry.Err = compileExpr(ctx, b.Command, filterExpr, link, Options{Format: b.Format})
+		if entry.Err != nil {
+			continue
+		}
+
+		for g, rep := range representatives {
+			if sameProgram(rep, entry.Code.Instructions) {
+				entry.Group = g
+				break
+			}
+		}
+		if entry.Group < 0 {
+			entry.Group = len(representatives)
+			representatives = append(representatives, entry.Code.Instructions)
+		}
+	}
+
+	m.Groups = len(representatives)
+	return m
+}
+
+// Report describes how the reference program varies across backends,
+// diffing every distinct program against the first one
+func (m *Matrix) Report() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Filter expression: %s\n", m.FilterExpr))
+	sb.WriteString(fmt.Sprintf("%-20s %-8s %-12s %s\n", "BACKEND", "VARIANT", "INSTRUCTIONS", "STATUS"))
+
+	var baseline *MatrixEntry
+	for _, e := range m.Entries {
+		if e.Err != nil {
+			sb.WriteString(fmt.Sprintf("%-20s %-8s %-12s error: %v\n", e.Backend.Name, "-", "-", firstLine(e.Err.Error())))
+			continue
+		}
+		if baseline == nil {
+			baseline = e
+		}
+		status := "identical to " + baseline.Backend.Name
+		if e == baseline {
+			status = "baseline"
+		} else if e.Group != baseline.Group {
+			status = "differs from " + baseline.Backend.Name
+		}
+		sb.WriteString(fmt.Sprintf("%-20s %-8s %-12d %s\n",
+			e.Backend.Name, string(rune('A'+e.Group)), e.Code.InstructionCount, status))
+	}
+
+	switch {
+	case baseline == nil:
+		sb.WriteString("\nNo backend produced a program\n")
+		return sb.String()
+	case m.Groups == 1:
+		sb.WriteString("\nAll backends produced identical bytecode\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d distinct programs across backends\n", m.Groups))
+	reported := map[int]bool{baseline.Group: true}
+	for _, e := range m.Entries {
+		if e.Err != nil || reported[e.Group] {
+			continue
+		}
+		reported[e.Group] = true
+		sb.WriteString(fmt.Sprintf("\nVariant %c (%s) vs baseline (%s):\n", 'A'+e.Group, e.Backend.Name, baseline.Backend.Name))
+		sb.WriteString(diffPrograms(baseline.Code.Instructions, e.Code.Instructions))
+	}
+	return sb.String()
+}
+
+// sameProgram reports whether two programs are instruction-for-instruction equal
+func sameProgram(a, b []*bpf.Instruction) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffPrograms lists the instruction indices at which two programs differ
+func diffPrograms(a, b []*bpf.Instruction) string {
+	var sb strings.Builder
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		var left, right string
+		if i < len(a) {
+			left = a[i].Mnemonic(i)
+		}
+		if i < len(b) {
+			right = b[i].Mnemonic(i)
+		}
+		if i < len(a) && i < len(b) && *a[i] == *b[i] {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  (%03d) - %s\n        + %s\n", i, squeeze(left), squeeze(right)))
+	}
+	return sb.String()
+}
+
+// squeeze collapses the column padding of a mnemonic
+func squeeze(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// firstLine returns the first line of a possibly multi-line message
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,328 @@
+package tcpdump
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+)
+
+// generateMockBPF compiles the filter the way tcpdump would when tcpdump
+// itself is unavailable. It covers the IPv4 form of the expressions produced
+// by ToTcpdumpFilter (vlan, protocol, ether proto, host, net, port, geneve
+// and vxlan clauses) on every supported link type and follows libpcap's
+// instruction ordering, so the program can be compared and simulated like
+// real output. IPv6 branches are not emitted. Accepting returns keep snaplen
+// bytes, and branches out of 8-bit reach go through long jumps, as in
+// libpcap.
+func generateMockBPF(f *filter.PacketFilter, filterExpr string, snaplen int) (*BPFCode, error) {
+	text, err := mockAssembly(f, snaplen)
+	if err != nil {
+		return nil, fmt.Errorf("mock compiler: %w", err)
+	}
+
+	instructions, err := bpf.AssembleLongJumps(text)
+	if err != nil {
+		return nil, fmt.Errorf("mock compiler produced invalid program: %w", err)
+	}
+
+	return &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       filterExpr,
+			InstructionCount: len(instructions),
+			LinkType:         string(f.LinkType),
+			Snaplen:          snaplen,
+		},
+		RawOutput: bpf.FormatDDD(instructions),
+		IsMocked:  true,
+		Source:    SourceMock,
+	}, nil
+}
+
+// mockAsm accumulates labelled assembly for the mock compiler
+type mockAsm struct {
+	sb      strings.Builder
+	labels  int
+	indexed bool // loads are relative to X, as behind a tunnel
+}
+
+// emit appends one line of assembly
+func (m *mockAsm) emit(format string, args ...interface{}) {
+	m.sb.WriteString(fmt.Sprintf(format, args...))
+	m.sb.WriteString("\n")
+}
+
+// label returns a fresh label name
+func (m *mockAsm) label() string {
+	m.labels++
+	return fmt.Sprintf("L%d", m.labels)
+}
+
+// check emits a conditional jump that falls through to the next
+// instruction on success and jumps to fail otherwise
+func (m *mockAsm) check(op string, k uint32, fail string) {
+	next := m.label()
+	m.emit("%s #0x%x, %s, %s", op, k, next, fail)
+	m.emit("%s:", next)
+}
+
+// mockAssembly emits the program as labelled assembly. Every check falls
+// through on success and jumps to "reject" on failure.
+func mockAssembly(f *filter.PacketFilter, snaplen int) (string, error) {
+	m := &mockAsm{}
+
+	// ip is the start of the IPv4 header; "vlan" moves it past the tag
+	ip := f.LinkType.HeaderLen()
+	if f.VLAN || f.VLANID != 0 {
+		// libpcap accepts the 802.1Q, 802.1ad and legacy QinQ TPIDs
+		m.emit("ldh [12]")
+		m.emit("jeq #0x8100, tagged, tpid2")
+		m.emit("tpid2: jeq #0x88a8, tagged, tpid3")
+		m.emit("tpid3: jeq #0x9100, tagged, reject")
+		m.emit("tagged:")
+		if f.VLANID != 0 {
+			m.emit("ldh [14]")
+			m.emit("and #0xfff")
+			m.check("jeq", uint32(f.VLANID), "reject")
+		}
+		ip += 4
+	}
+
+	// "arp", "rarp" and "ether proto" test the EtherType and nothing else
+	if etherType := f.EtherProto(); etherType != 0 {
+		m.emit("ldh [%d]", ip-2)
+		m.check("jeq", uint32(etherType), "reject")
+		m.emit("ret #%d", snaplen)
+		m.emit("reject: ret #0")
+		return m.sb.String(), nil
+	}
+
+	switch f.LinkType {
+	case filter.LinkRaw:
+		// libpcap tests the version nibble of the IP header
+		m.emit("ldb [0]")
+		m.emit("and #0xf0")
+		m.check("jeq", 0x40, "reject")
+	case filter.LinkNull:
+		m.emit("ld [0]")
+		m.check("jeq", filter.NullIPv4, "reject")
+	default:
+		// Ethernet and cooked captures end with the EtherType
+		m.emit("ldh [%d]", ip-2)
+		m.check("jeq", 0x800, "reject")
+	}
+
+	// Clauses after "geneve" or "vxlan" test the encapsulated packet,
+	// which follows the outer IP header at an offset held in X
+	if f.Tunnel != "" && f.Inner {
+		m.tunnel(f, ip)
+		m.indexed = true
+		ip += 8 + filter.TunnelHeaderLen + 14
+		m.emit("ldh %s", m.at(ip-2))
+		m.check("jeq", 0x800, "reject")
+	}
+	if err := m.criteria(f, ip); err != nil {
+		return "", err
+	}
+	if f.Tunnel != "" && !f.Inner {
+		m.tunnel(f, ip)
+	}
+
+	m.emit("ret #%d", snaplen)
+	m.emit("reject: ret #0")
+	return m.sb.String(), nil
+}
+
+// criteria emits the protocol, address and port clauses for the IPv4
+// header at ip
+func (m *mockAsm) criteria(f *filter.PacketFilter, ip int) error {
+	hasPorts := f.HasPorts()
+	switch {
+	case f.Protocol == "udp" && f.Tunnel != "" && !f.Inner:
+		// The tunnel tests the outer protocol itself
+	case f.Protocol != "":
+		m.emit("ldb %s", m.at(ip+9))
+		m.check("jeq", uint32(f.IPProtocol()), "reject")
+	case hasPorts:
+		// A bare "port" clause matches sctp, tcp and udp
+		m.emit("ldb %s", m.at(ip+9))
+		m.emit("jeq #0x84, ports, sctp")
+		m.emit("sctp: jeq #0x6, ports, tcp")
+		m.emit("tcp: jeq #0x11, ports, reject")
+		m.emit("ports:")
+	}
+
+	src, dst := ip+12, ip+16
+	if f.SrcIP != "" {
+		if err := m.network(src, f.SrcIP, "reject"); err != nil {
+			return err
+		}
+	}
+	if f.DstIP != "" {
+		if err := m.network(dst, f.DstIP, "reject"); err != nil {
+			return err
+		}
+	}
+
+	// "host" tests the destination only when the source does not match
+	if f.HostIP != "" {
+		if err := m.networkTo(src, f.HostIP, "host", "hostdst"); err != nil {
+			return err
+		}
+		m.emit("hostdst:")
+		if err := m.network(dst, f.HostIP, "reject"); err != nil {
+			return err
+		}
+		m.emit("host:")
+	}
+
+	if len(f.Between) == 2 {
+		a, b := f.Between[0], f.Between[1]
+		if err := m.network(src, a, "reverse"); err != nil {
+			return err
+		}
+		if err := m.network(dst, b, "reverse"); err != nil {
+			return err
+		}
+		m.emit("ja between")
+		m.emit("reverse:")
+		if err := m.network(src, b, "reject"); err != nil {
+			return err
+		}
+		if err := m.network(dst, a, "reject"); err != nil {
+			return err
+		}
+		m.emit("between:")
+	}
+
+	if hasPorts {
+		m.emit("ldh %s", m.at(ip+6))
+		m.emit("jset #0x1fff, reject, frag")
+		m.emit("frag:")
+		m.headerLength(ip)
+		// The optimizer merges the loads of "(dst port A or dst port B)"
+		// into one, leaving a chain of comparisons
+		if ports := f.SrcPortList(); len(ports) > 0 {
+			m.emit("ldh [x + %d]", ip)
+			m.anyPort(ports)
+		}
+		if ports := f.DstPortList(); len(ports) > 0 {
+			m.emit("ldh [x + %d]", ip+2)
+			m.anyPort(ports)
+		}
+		if f.Port != 0 {
+			m.emit("ldh [x + %d]", ip)
+			m.emit("jeq #0x%x, port, dport", f.Port)
+			m.emit("dport: ldh [x + %d]", ip+2)
+			m.check("jeq", uint32(f.Port), "reject")
+			m.emit("port:")
+		}
+	}
+	return nil
+}
+
+// tunnel emits the "geneve" or "vxlan" clause for the outer IPv4 header at
+// ip: the UDP destination port, the header and the VNI. With inner
+// criteria X is left pointing past the tunnel header and any Geneve
+// options, relative to the UDP header.
+func (m *mockAsm) tunnel(f *filter.PacketFilter, ip int) {
+	m.emit("ldb [%d]", ip+9)
+	m.check("jeq", 17, "reject")
+	m.emit("ldh [%d]", ip+6)
+	m.emit("jset #0x1fff, reject, tunfrag")
+	m.emit("tunfrag: ldxb 4*([%d]&0xf)", ip)
+	m.emit("ldh [x + %d]", ip+2)
+	m.check("jeq", uint32(f.Tunnel.Port()), "reject")
+
+	header := ip + 8
+	m.emit("ldb [x + %d]", header)
+	if f.Tunnel == filter.TunnelGeneve {
+		m.emit("and #0xc0")
+		m.check("jeq", 0, "reject")
+	} else {
+		m.check("jset", 0x08, "reject")
+	}
+	if f.VNI != 0 {
+		m.emit("ld [x + %d]", header+4)
+		m.emit("and #0xffffff00")
+		m.check("jeq", uint32(f.VNI)<<8, "reject")
+	}
+	if f.Inner && f.Tunnel == filter.TunnelGeneve {
+		m.emit("ldh [x + %d]", header+2)
+		m.check("jeq", 0x6558, "reject")
+		m.emit("ldb [x + %d]", header)
+		m.emit("and #0x3f")
+		m.emit("lsh #2")
+		m.emit("add x")
+		m.emit("tax")
+	}
+}
+
+// headerLength loads X with the length of the IPv4 header at ip, or
+// adds it to X behind a tunnel
+func (m *mockAsm) headerLength(ip int) {
+	if !m.indexed {
+		m.emit("ldxb 4*([%d]&0xf)", ip)
+		return
+	}
+	m.emit("ldb [x + %d]", ip)
+	m.emit("and #0xf")
+	m.emit("lsh #2")
+	m.emit("add x")
+	m.emit("tax")
+}
+
+// at returns the operand of a load at offset, relative to X behind a
+// tunnel
+func (m *mockAsm) at(offset int) string {
+	if m.indexed {
+		return fmt.Sprintf("[x + %d]", offset)
+	}
+	return fmt.Sprintf("[%d]", offset)
+}
+
+// anyPort falls through when the loaded port is one of ports and jumps to
+// reject otherwise
+func (m *mockAsm) anyPort(ports []int) {
+	matched := m.label()
+	for _, p := range ports[:len(ports)-1] {
+		next := m.label()
+		m.emit("jeq #0x%x, %s, %s", p, matched, next)
+		m.emit("%s:", next)
+	}
+	m.check("jeq", uint32(ports[len(ports)-1]), "reject")
+	m.emit("%s:", matched)
+}
+
+// network compares the IPv4 address at offset with a host or network,
+// masking the address unless the network is a single host
+func (m *mockAsm) network(offset int, network, fail string) error {
+	next := m.label()
+	if err := m.networkTo(offset, network, next, fail); err != nil {
+		return err
+	}
+	m.emit("%s:", next)
+	return nil
+}
+
+// networkTo is network with an explicit target for a match
+func (m *mockAsm) networkTo(offset int, network, match, fail string) error {
+	ipnet, err := filter.ParseNet(network)
+	if err != nil {
+		return err
+	}
+	ip := ipnet.IP.To4()
+	if ip == nil || len(ipnet.Mask) != net.IPv4len {
+		return fmt.Errorf("IPv6 address %s is not supported", network)
+	}
+	m.emit("ld %s", m.at(offset))
+	if ones, _ := ipnet.Mask.Size(); ones != 32 {
+		m.emit("and #0x%x", binary.BigEndian.Uint32(ipnet.Mask))
+	}
+	m.emit("jeq #0x%x, %s, %s", binary.BigEndian.Uint32(ip), match, fail)
+	return nil
+}
@@ -0,0 +1,123 @@
+package tcpdump
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// OutputFormat is one of tcpdump's program dump formats
+type OutputFormat string
+
+const (
+	// FormatDDD is -ddd: the instruction count, then "code jt jf k" in
+	// decimal, one instruction per line. It is the default.
+	FormatDDD OutputFormat = "ddd"
+	// FormatDD is -dd: C struct initializers, "{ 0x28, 0, 0, 0x0000000c },"
+	FormatDD OutputFormat = "dd"
+	// FormatD is -d: mnemonics such as "(000) ldh [12]", and also any
+	// bpf_asm-style text bpf.Assemble accepts
+	FormatD OutputFormat = "d"
+)
+
+// ParseOutputFormat parses a format name, with or without leading dashes
+// ("dd" or "-dd"). The empty string is FormatDDD.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch f := OutputFormat(strings.TrimLeft(s, "-")); f {
+	case "":
+		return FormatDDD, nil
+	case FormatDDD, FormatDD, FormatD:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid output format '%s', must be d, dd or ddd", s)
+}
+
+// flag returns the tcpdump flag producing the format
+func (f OutputFormat) flag() string {
+	if f == "" {
+		return "-" + string(FormatDDD)
+	}
+	return "-" + string(f)
+}
+
+// DetectFormat guesses the format of a dump: -ddd starts with a bare
+// instruction count, -dd with a brace, and anything else is read as -d
+func DetectFormat(output string) OutputFormat {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "{") || strings.HasPrefix(line, "struct "):
+			return FormatDD
+		}
+		if _, err := strconv.Atoi(line); err == nil {
+			return FormatDDD
+		}
+		return FormatD
+	}
+	return FormatDDD
+}
+
+// ParseOutput parses a program dumped by tcpdump in the given format into
+// instructions. All three formats describe the same program, so the result
+// does not depend on which one tcpdump was asked for.
+func ParseOutput(output string, format OutputFormat) ([]*bpf.Instruction, error) {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	switch format {
+	case "", FormatDDD:
+		return parseTcpdumpOutput(output)
+	case FormatDD:
+		return parseDD(output)
+	case FormatD:
+		instructions, err := bpf.Assemble(output)
+		if err != nil {
+			return nil, err
+		}
+		if len(instructions) == 0 {
+			return nil, fmt.Errorf("empty tcpdump output")
+		}
+		return instructions, nil
+	}
+	return nil, fmt.Errorf("invalid output format '%s', must be d, dd or ddd", format)
+}
+
+// ddInstruction matches one struct initializer of -dd output. Lines around
+// the initializers, such as a pasted "struct sock_filter code[] = {", are
+// ignored.
+var ddInstruction = regexp.MustCompile(`\{\s*([0-9a-fA-Fx]+)\s*,\s*([0-9a-fA-Fx]+)\s*,\s*([0-9a-fA-Fx]+)\s*,\s*([0-9a-fA-Fx]+)\s*\}`)
+
+// parseDD parses the C initializers of tcpdump -dd. Fields are written in
+// hexadecimal or decimal depending on the tcpdump version, so both are
+// accepted.
+func parseDD(output string) ([]*bpf.Instruction, error) {
+	var instructions []*bpf.Instruction
+	for i, line := range strings.Split(output, "\n") {
+		m := ddInstruction.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		var fields [4]uint64
+		for j, bits := range []int{16, 8, 8, 32} {
+			v, err := strconv.ParseUint(m[j+1], 0, bits)
+			if err != nil {
+				return nil, &ParseError{Line: i + 1, Field: []string{"code", "jt", "jf", "k"}[j], Err: err}
+			}
+			fields[j] = v
+		}
+		instructions = append(instructions, &bpf.Instruction{
+			Code: uint16(fields[0]),
+			JT:   uint8(fields[1]),
+			JF:   uint8(fields[2]),
+			K:    uint32(fields[3]),
+		})
+	}
+	if len(instructions) == 0 {
+		return nil, fmt.Errorf("no { code, jt, jf, k } instructions in tcpdump -dd output")
+	}
+	return instructions, nil
+}
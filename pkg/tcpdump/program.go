@@ -0,0 +1,91 @@
+package tcpdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// Program file formats beyond tcpdump's own dumps
+const (
+	// FormatJSON is a JSON array of instructions (see bpf.DecodeJSON)
+	FormatJSON OutputFormat = "json"
+	// FormatBinary is raw struct sock_filter instructions in little-endian
+	// byte order, as found behind a struct sock_fprog on x86 and arm64
+	FormatBinary OutputFormat = "bin"
+)
+
+// SourceFile marks a program read from a file rather than compiled
+const SourceFile = "file"
+
+// LoadProgram decodes a program file in any supported format: tcpdump -d,
+// -dd or -ddd text, JSON, or binary. An empty format detects it from the
+// content. It returns the instructions and the format that was read.
+func LoadProgram(data []byte, format OutputFormat) ([]*bpf.Instruction, OutputFormat, error) {
+	if format == "" {
+		format = detectProgramFormat(data)
+	}
+
+	var instructions []*bpf.Instruction
+	var err error
+	switch format {
+	case FormatJSON:
+		instructions, err = bpf.DecodeJSON(data)
+	case FormatBinary:
+		instructions, err = bpf.DecodeBinary(data, binary.LittleEndian)
+	case FormatD, FormatDD, FormatDDD:
+		instructions, err = ParseOutput(string(data), format)
+	default:
+		return nil, "", fmt.Errorf("invalid program format '%s', must be d, dd, ddd, json or bin", format)
+	}
+	return instructions, format, err
+}
+
+// ProgramFromFile wraps a loaded program so it can take the reference's
+// place in a comparison
+func ProgramFromFile(path string, instructions []*bpf.Instruction, linkType string) *BPFCode {
+	return &BPFCode{
+		Code: bpf.Code{
+			Instructions:     instructions,
+			FilterExpr:       path,
+			InstructionCount: len(instructions),
+			LinkType:         linkType,
+		},
+		RawOutput: bpf.FormatDDD(instructions),
+		Source:    SourceFile,
+	}
+}
+
+// ParseProgramFormat parses a program format name; "auto" and the empty
+// string select detection
+func ParseProgramFormat(s string) (OutputFormat, error) {
+	switch OutputFormat(s) {
+	case "", "auto":
+		return "", nil
+	case FormatJSON, FormatBinary:
+		return OutputFormat(s), nil
+	}
+	f, err := ParseOutputFormat(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid program format '%s', must be auto, d, dd, ddd, json or bin", s)
+	}
+	return f, nil
+}
+
+// detectProgramFormat tells binary from text by NUL bytes and invalid
+// UTF-8, and JSON from tcpdump -dd (which also starts with a brace) by
+// whether the whole text parses as JSON
+func detectProgramFormat(data []byte) OutputFormat {
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return FormatBinary
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') && json.Valid(trimmed) {
+		return FormatJSON
+	}
+	return DetectFormat(string(data))
+}
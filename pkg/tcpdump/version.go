@@ -0,0 +1,52 @@
+package tcpdump
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// versions caches the first line of "COMMAND --version" by command
+var versions sync.Map
+
+// imageIDs caches the IDs of container images by runtime and image. An
+// image that is not present is looked up again, as the first run pulls it.
+var imageIDs sync.Map
+
+// commandVersion returns the first line of "name --version", or "" if the
+// command fails
+func commandVersion(name string) string {
+	if v, ok := versions.Load(name); ok {
+		return v.(string)
+	}
+	out, err := runCommand(context.Background(), name, []string{name, "--version"}, 0)
+	version := ""
+	if err == nil {
+		line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+		version = strings.TrimSpace(line)
+	}
+	versions.Store(name, version)
+	return version
+}
+
+// SourceVersion describes the compiler behind a reference source, such as
+// "tcpdump version 4.99.4" or "libpcap version 1.10.4", so that programs
+// recorded with one release are not compared with another's. The mock
+// compiler and program files have no version and return the source itself.
+func SourceVersion(source string) string {
+	var version string
+	switch source {
+	case SourceLibpcap:
+		version = libpcapVersion()
+	case SourceTcpdump:
+		version = commandVersion("tcpdump")
+	case SourceTshark:
+		version = commandVersion("dumpcap")
+	case SourceContainer:
+		version = "tcpdump in " + Container.image()
+	}
+	if version == "" {
+		return source
+	}
+	return version
+}
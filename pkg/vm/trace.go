@@ -0,0 +1,69 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// FormatSteps renders the steps of a run as a table: each executed
+// instruction with the registers it left and, for a conditional jump, the
+// instruction it went to. clauses, if not nil, names the filter clause of
+// each instruction, printed as a comment where it changes.
+func FormatSteps(prog []*bpf.Instruction, steps []Step, clauses []string) string {
+	var sb strings.Builder
+	clause := ""
+	for _, s := range steps {
+		writeClause(&sb, clauses, s.PC, &clause, "")
+		sb.WriteString(stepLine(prog, s))
+	}
+	return sb.String()
+}
+
+// Annotate renders the whole program as a disassembly marking the path a
+// run took: executed instructions start with ">" and carry the registers
+// they left, the others are listed unmarked. Programs only jump forward,
+// so an instruction runs at most once.
+func Annotate(prog []*bpf.Instruction, steps []Step, clauses []string) string {
+	executed := make(map[int]Step, len(steps))
+	for _, s := range steps {
+		executed[s.PC] = s
+	}
+	var sb strings.Builder
+	clause := ""
+	for pc := range prog {
+		writeClause(&sb, clauses, pc, &clause, "  ")
+		if s, ok := executed[pc]; ok {
+			sb.WriteString("> " + stepLine(prog, s))
+			continue
+		}
+		fmt.Fprintf(&sb, "  (%03d) %s\n", pc, mnemonic(prog, pc))
+	}
+	return sb.String()
+}
+
+// stepLine formats one step
+func stepLine(prog []*bpf.Instruction, s Step) string {
+	inst := prog[s.PC]
+	line := fmt.Sprintf("(%03d) %-44s A=%#x X=%#x", s.PC, mnemonic(prog, s.PC), s.A, s.X)
+	if inst.IsJump() && inst.Code&0xf0 != bpf.JmpJA && s.Next >= 0 {
+		line += fmt.Sprintf(" -> %03d", s.Next)
+	}
+	return line + "\n"
+}
+
+// mnemonic returns the instruction's disassembly on one line of spaces
+func mnemonic(prog []*bpf.Instruction, pc int) string {
+	return strings.ReplaceAll(prog[pc].Mnemonic(pc), "\t", " ")
+}
+
+// writeClause writes the clause of instruction pc as a comment when it
+// differs from the last one written
+func writeClause(sb *strings.Builder, clauses []string, pc int, last *string, indent string) {
+	if pc >= len(clauses) || clauses[pc] == "" || clauses[pc] == *last {
+		return
+	}
+	*last = clauses[pc]
+	fmt.Fprintf(sb, "%s; %s\n", indent, *last)
+}
@@ -0,0 +1,309 @@
+// Package vm interprets classic BPF programs in userspace, with the
+// kernel's semantics: loads past the end of the packet and division by
+// zero end the program with a return of 0. Run gives a program's verdict
+// on one packet, and Steps the instructions it executed to get there.
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// Result describes the outcome of running a program over one packet
+type Result struct {
+	Accepted bool   // true if the program returned a non-zero length
+	Length   uint32 // value returned by the program
+	Executed int    // number of instructions executed
+
+	// Aborted is set when an out-of-bounds load or a division by zero
+	// ended the program before it reached a return
+	Aborted bool
+}
+
+// Run executes the program against the packet and returns the verdict.
+// Out-of-bounds packet loads and division by zero terminate the program
+// with a return value of 0, matching the kernel's behavior. A jump past the
+// last instruction fails with an error wrapping bpf.ErrJumpOutOfRange.
+func Run(prog []*bpf.Instruction, pkt []byte) (*Result, error) {
+	return run(prog, pkt, observer{})
+}
+
+// Branch is the outcome of a conditional jump
+type Branch struct {
+	PC    int  // instruction index of the jump
+	Taken bool // true if the condition held
+}
+
+// Trace is Run that also returns the outcome of every conditional jump
+// executed, in order
+func Trace(prog []*bpf.Instruction, pkt []byte) (*Result, []Branch, error) {
+	var branches []Branch
+	result, err := run(prog, pkt, observer{branch: func(pc int, taken bool) {
+		branches = append(branches, Branch{PC: pc, Taken: taken})
+	}})
+	return result, branches, err
+}
+
+// Step is the machine state after one executed instruction
+type Step struct {
+	PC   int    // instruction index
+	A, X uint32 // registers after the instruction
+	Next int    // index of the next instruction, -1 after the last
+
+	// Taken is set for a conditional jump whose condition held
+	Taken bool
+}
+
+// Steps is Run that also returns every instruction executed, in order,
+// with the registers it left. A load outside the packet is the last step,
+// with the registers it found.
+func Steps(prog []*bpf.Instruction, pkt []byte) (*Result, []Step, error) {
+	var steps []Step
+	result, err := run(prog, pkt, observer{step: func(s Step) {
+		steps = append(steps, s)
+	}})
+	return result, steps, err
+}
+
+// observer is called as a program runs; either function may be nil
+type observer struct {
+	branch func(pc int, taken bool) // outcome of each conditional jump
+	step   func(Step)               // each instruction executed
+}
+
+// run executes the program, reporting its progress to obs
+func run(prog []*bpf.Instruction, pkt []byte, obs observer) (*Result, error) {
+	if len(prog) == 0 {
+		return nil, fmt.Errorf("empty program")
+	}
+
+	var (
+		a, x uint32
+		mem  [bpf.MemWords]uint32
+	)
+	result := &Result{}
+	// stop records the last step, of an instruction ending the program
+	stop := func(pc int) {
+		if obs.step != nil {
+			obs.step(Step{PC: pc, A: a, X: x, Next: -1})
+		}
+	}
+
+	for pc := 0; pc < len(prog); pc++ {
+		inst := prog[pc]
+		at, taken := pc, false
+		result.Executed++
+
+		switch inst.Code & 0x07 {
+		case bpf.ClassLD:
+			v, ok, err := load(inst, pkt, x, &mem)
+			if err != nil {
+				return nil, fmt.Errorf("instruction %d: %w", pc, err)
+			}
+			if !ok {
+				stop(pc)
+				result.Aborted = true
+				return result, nil
+			}
+			a = v
+
+		case bpf.ClassLDX:
+			switch inst.Code & 0xe0 {
+			case bpf.ModeIMM:
+				x = inst.K
+			case bpf.ModeMEM:
+				if inst.K >= bpf.MemWords {
+					return nil, fmt.Errorf("instruction %d: scratch index %d out of range", pc, inst.K)
+				}
+				x = mem[inst.K]
+			case bpf.ModeLEN:
+				x = uint32(len(pkt))
+			case bpf.ModeMSH:
+				if int(inst.K) >= len(pkt) {
+					stop(pc)
+					result.Aborted = true
+					return result, nil
+				}
+				x = uint32(pkt[inst.K]&0x0f) * 4
+			default:
+				return nil, fmt.Errorf("instruction %d: invalid ldx mode 0x%02x", pc, inst.Code&0xe0)
+			}
+
+		case bpf.ClassST, bpf.ClassSTX:
+			if inst.K >= bpf.MemWords {
+				return nil, fmt.Errorf("instruction %d: scratch index %d out of range", pc, inst.K)
+			}
+			if inst.Code&0x07 == bpf.ClassST {
+				mem[inst.K] = a
+			} else {
+				mem[inst.K] = x
+			}
+
+		case bpf.ClassALU:
+			operand := inst.K
+			if inst.Code&bpf.SrcX != 0 {
+				operand = x
+			}
+			v, ok, err := alu(inst.Code&0xf0, a, operand)
+			if err != nil {
+				return nil, fmt.Errorf("instruction %d: %w", pc, err)
+			}
+			if !ok {
+				stop(pc)
+				result.Aborted = true
+				return result, nil
+			}
+			a = v
+
+		case bpf.ClassJMP:
+			op := inst.Code & 0xf0
+			if op == bpf.JmpJA {
+				if pc+1+int(inst.K) >= len(prog) {
+					return nil, fmt.Errorf("instruction %d: %w", pc, bpf.ErrJumpOutOfRange)
+				}
+				pc += int(inst.K)
+				break
+			}
+			operand := inst.K
+			if inst.Code&bpf.SrcX != 0 {
+				operand = x
+			}
+			var cond bool
+			switch op {
+			case bpf.JmpJEQ:
+				cond = a == operand
+			case bpf.JmpJGT:
+				cond = a > operand
+			case bpf.JmpJGE:
+				cond = a >= operand
+			case bpf.JmpJSET:
+				cond = a&operand != 0
+			default:
+				return nil, fmt.Errorf("instruction %d: invalid jump op 0x%02x", pc, op)
+			}
+			off := int(inst.JF)
+			if cond {
+				off = int(inst.JT)
+			}
+			if obs.branch != nil {
+				obs.branch(pc, cond)
+			}
+			taken = cond
+			if pc+1+off >= len(prog) {
+				return nil, fmt.Errorf("instruction %d: %w", pc, bpf.ErrJumpOutOfRange)
+			}
+			pc += off
+
+		case bpf.ClassRET:
+			switch inst.Code & 0x18 {
+			case bpf.SrcK:
+				result.Length = inst.K
+			case bpf.RetA:
+				result.Length = a
+			default:
+				return nil, fmt.Errorf("instruction %d: invalid return source", pc)
+			}
+			result.Accepted = result.Length != 0
+			stop(pc)
+			return result, nil
+
+		case bpf.ClassMISC:
+			switch inst.Code & 0xf8 {
+			case bpf.MiscTAX:
+				x = a
+			case bpf.MiscTXA:
+				a = x
+			default:
+				return nil, fmt.Errorf("instruction %d: invalid misc op 0x%02x", pc, inst.Code)
+			}
+		}
+		if obs.step != nil {
+			obs.step(Step{PC: at, A: a, X: x, Next: pc + 1, Taken: taken})
+		}
+	}
+
+	return nil, fmt.Errorf("program fell off the end without returning")
+}
+
+// load evaluates a BPF_LD instruction. The boolean result is false when the
+// load falls outside the packet and the program must terminate.
+func load(inst *bpf.Instruction, pkt []byte, x uint32, mem *[bpf.MemWords]uint32) (uint32, bool, error) {
+	mode := inst.Code & 0xe0
+	switch mode {
+	case bpf.ModeIMM:
+		return inst.K, true, nil
+	case bpf.ModeLEN:
+		return uint32(len(pkt)), true, nil
+	case bpf.ModeMEM:
+		if inst.K >= bpf.MemWords {
+			return 0, false, fmt.Errorf("scratch index %d out of range", inst.K)
+		}
+		return mem[inst.K], true, nil
+	case bpf.ModeABS, bpf.ModeIND:
+		offset := uint64(inst.K)
+		if mode == bpf.ModeIND {
+			offset += uint64(x)
+		}
+		var size uint64
+		switch inst.Code & 0x18 {
+		case bpf.SizeW:
+			size = 4
+		case bpf.SizeH:
+			size = 2
+		case bpf.SizeB:
+			size = 1
+		default:
+			return 0, false, fmt.Errorf("invalid load size")
+		}
+		if offset+size > uint64(len(pkt)) {
+			return 0, false, nil
+		}
+		switch size {
+		case 4:
+			return binary.BigEndian.Uint32(pkt[offset:]), true, nil
+		case 2:
+			return uint32(binary.BigEndian.Uint16(pkt[offset:])), true, nil
+		default:
+			return uint32(pkt[offset]), true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("invalid ld mode 0x%02x", mode)
+}
+
+// alu evaluates an arithmetic instruction. The boolean result is false when
+// the operation is a division or modulo by zero.
+func alu(op uint16, a, operand uint32) (uint32, bool, error) {
+	switch op {
+	case bpf.ALUAdd:
+		return a + operand, true, nil
+	case bpf.ALUSub:
+		return a - operand, true, nil
+	case bpf.ALUMul:
+		return a * operand, true, nil
+	case bpf.ALUDiv:
+		if operand == 0 {
+			return 0, false, nil
+		}
+		return a / operand, true, nil
+	case bpf.ALUMod:
+		if operand == 0 {
+			return 0, false, nil
+		}
+		return a % operand, true, nil
+	case bpf.ALUOr:
+		return a | operand, true, nil
+	case bpf.ALUAnd:
+		return a & operand, true, nil
+	case bpf.ALUXor:
+		return a ^ operand, true, nil
+	case bpf.ALULsh:
+		return a << (operand & 31), true, nil
+	case bpf.ALURsh:
+		return a >> (operand & 31), true, nil
+	case bpf.ALUNeg:
+		return -a, true, nil
+	}
+	return 0, false, fmt.Errorf("invalid alu op 0x%02x", op)
+}
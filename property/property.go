@@ -0,0 +1,221 @@
+// Package property states the invariants every generated program must
+// hold, whatever the filter: it terminates, it never reads past the
+// snapshot length, and it accepts only packets the filter selects, as
+// filter.PacketFilter.MatchesPacket decides them in Go. Unlike package
+// fuzz, the checks need no reference compiler, so they apply to programs
+// from any generator, including Antrea's.
+//
+// Each property is a function of a program and a packet returning a
+// *Violation. CheckProgram applies all of them to a program and its
+// packets, and Check to the prototype's program for a case decoded by
+// fuzz.CaseFromBytes, so that any property-based testing library can drive
+// them from random bytes. The package's own tests draw them with
+// pgregory.net/rapid, which shrinks a failing input; with testing/quick,
+// Input generates the bytes:
+//
+//	func TestProperties(t *testing.T) {
+//		if err := quick.Check(func(in property.Input) bool {
+//			return property.Check(in) == nil
+//		}, nil); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// With github.com/leanovate/gopter:
+//
+//	func TestProperties(t *testing.T) {
+//		properties := gopter.NewProperties(nil)
+//		properties.Property("generated programs", prop.ForAll(func(data []byte) bool {
+//			return property.Check(data) == nil
+//		}, gen.SliceOfN(fuzz.InputSize, gen.UInt8())))
+//		properties.TestingRun(t)
+//	}
+package property
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/fuzz"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// Names of the properties, as Violation reports them
+const (
+	PropertyTerminates    = "terminates"
+	PropertyWithinSnaplen = "within-snaplen"
+	PropertyMatchesFilter = "matches-filter"
+)
+
+// Snaplens are the snapshot lengths Check generates programs with: 0 for
+// the default, and the lengths tcpdump users commonly pass with -s
+var Snaplens = []int{0, 65535, 9216, 1518}
+
+// Violation reports a packet on which a program breaks a property
+type Violation struct {
+	Property string // one of the Property constants
+	Filter   string // tcpdump expression of the filter, if known
+	Packet   []byte
+	Detail   string
+}
+
+// Error names the property, what broke it and the packet
+func (v *Violation) Error() string {
+	filter := ""
+	if v.Filter != "" {
+		filter = fmt.Sprintf("filter '%s': ", v.Filter)
+	}
+	return fmt.Sprintf("%sproperty %s: %s, packet %s", filter, v.Property, v.Detail, hex.EncodeToString(v.Packet))
+}
+
+// Terminates checks that the program passes bpf.Verify, so that every jump
+// goes forward to an instruction and the last one returns, and that it
+// reaches a return over the packet having executed each instruction at
+// most once
+func Terminates(prog []*bpf.Instruction, pkt []byte) error {
+	if err := bpf.Verify(prog); err != nil {
+		return &Violation{Property: PropertyTerminates, Packet: pkt, Detail: err.Error()}
+	}
+	result, err := vm.Run(prog, pkt)
+	if err != nil {
+		return &Violation{Property: PropertyTerminates, Packet: pkt, Detail: err.Error()}
+	}
+	if result.Executed > len(prog) {
+		return &Violation{Property: PropertyTerminates, Packet: pkt,
+			Detail: fmt.Sprintf("executed %d instructions of %d", result.Executed, len(prog))}
+	}
+	return nil
+}
+
+// WithinSnaplen checks that the program loads no packet byte at or past
+// the snapshot length, so that its verdict on the packet is the same as on
+// a capture of it, and that it accepts with at most that length. A
+// snaplen of 0 means bpfgen.DefaultSnaplen.
+func WithinSnaplen(prog []*bpf.Instruction, pkt []byte, snaplen int) error {
+	if snaplen == 0 {
+		snaplen = bpfgen.DefaultSnaplen
+	}
+	result, steps, err := vm.Steps(prog, pkt)
+	if err != nil {
+		return &Violation{Property: PropertyWithinSnaplen, Packet: pkt, Detail: err.Error()}
+	}
+	for _, s := range steps {
+		if end, ok := loadEnd(prog[s.PC], s.X); ok && end > uint64(snaplen) {
+			return &Violation{Property: PropertyWithinSnaplen, Packet: pkt,
+				Detail: fmt.Sprintf("instruction %d (%s) reads up to byte %d of a %d-byte snapshot", s.PC, strings.Join(strings.Fields(prog[s.PC].Mnemonic(s.PC)), " "), end, snaplen)}
+		}
+	}
+	if result.Length > uint32(snaplen) {
+		return &Violation{Property: PropertyWithinSnaplen, Packet: pkt,
+			Detail: fmt.Sprintf("accepts %d bytes of a %d-byte snapshot", result.Length, snaplen)}
+	}
+	return nil
+}
+
+// loadEnd returns the end of the packet bytes an instruction loads, given
+// X as it was when the instruction ran. Loads leave X as they find it, so
+// the X a step records will do.
+func loadEnd(inst *bpf.Instruction, x uint32) (uint64, bool) {
+	switch inst.Class() {
+	case bpf.ClassLD:
+		mode := inst.Code & 0xe0
+		if mode != bpf.ModeABS && mode != bpf.ModeIND {
+			return 0, false
+		}
+		end := uint64(inst.K)
+		if mode == bpf.ModeIND {
+			end += uint64(x)
+		}
+		switch inst.Code & 0x18 {
+		case bpf.SizeW:
+			return end + 4, true
+		case bpf.SizeH:
+			return end + 2, true
+		}
+		return end + 1, true
+	case bpf.ClassLDX:
+		if inst.Code&0xe0 == bpf.ModeMSH {
+			return uint64(inst.K) + 1, true
+		}
+	}
+	return 0, false
+}
+
+// MatchesFilter checks that the program accepts the packet only if the
+// filter selects it, as filter.PacketFilter.MatchesPacket decides. Packets
+// it cannot decide, cut short or with an invalid IPv4 header length,
+// satisfy it whatever the verdict.
+func MatchesFilter(f *filter.PacketFilter, prog []*bpf.Instruction, pkt []byte) error {
+	result, err := vm.Run(prog, pkt)
+	if err != nil {
+		return &Violation{Property: PropertyMatchesFilter, Packet: pkt, Detail: err.Error()}
+	}
+	if !result.Accepted {
+		return nil
+	}
+	match, err := f.MatchesPacket(pkt)
+	if errors.Is(err, filter.ErrUndecodable) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !match {
+		return &Violation{Property: PropertyMatchesFilter, Packet: pkt,
+			Detail: fmt.Sprintf("accepts %s, which the filter does not select", packet.Decode(pkt, f.LinkType))}
+	}
+	return nil
+}
+
+// CheckProgram checks every property of a program generated for the filter
+// with the snapshot length over each packet, returning the first
+// *Violation
+func CheckProgram(f *filter.PacketFilter, prog []*bpf.Instruction, snaplen int, packets [][]byte) error {
+	for _, pkt := range packets {
+		for _, check := range []func() error{
+			func() error { return Terminates(prog, pkt) },
+			func() error { return WithinSnaplen(prog, pkt, snaplen) },
+			func() error { return MatchesFilter(f, prog, pkt) },
+		} {
+			if err := check(); err != nil {
+				if v, ok := err.(*Violation); ok {
+					v.Filter = f.ToTcpdumpFilter()
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Check generates the prototype's program for the case fuzz.CaseFromBytes
+// decodes from data and checks every property over its packets. The last
+// byte of data picks the snapshot length from Snaplens.
+func Check(data []byte) error {
+	c := fuzz.CaseFromBytes(data)
+	snaplen := 0
+	if len(data) > 0 {
+		snaplen = Snaplens[int(data[len(data)-1])%len(Snaplens)]
+	}
+	prog, err := bpfgen.GenerateBPFWithOptions(c.Filter, bpfgen.Options{Snaplen: snaplen})
+	if err != nil {
+		return fmt.Errorf("prototype generator: %w", err)
+	}
+	return CheckProgram(c.Filter, prog.Instructions, snaplen, c.Packets)
+}
+
+// Input is a random input for Check that testing/quick can generate
+type Input []byte
+
+// Generate returns fuzz.InputSize random bytes, implementing quick.Generator
+func (Input) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Input(fuzz.RandomInput(r)))
+}
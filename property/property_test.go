@@ -0,0 +1,68 @@
+package property
+
+import (
+	"errors"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/fuzz"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// input draws the bytes a case is decoded from
+func input(t *rapid.T) []byte {
+	return rapid.SliceOfN(rapid.Byte(), fuzz.InputSize, fuzz.InputSize).Draw(t, "input")
+}
+
+// TestGeneratedPrograms checks every property of the prototype's program
+// for random filters and packets
+func TestGeneratedPrograms(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		if err := Check(input(t)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// TestAcceptAllViolates checks that a program accepting everything breaks
+// matches-filter on any packet the filter decodably rejects
+func TestAcceptAllViolates(t *testing.T) {
+	acceptAll := []*bpf.Instruction{{Code: bpf.OpRetK, K: 1}}
+	rapid.Check(t, func(t *rapid.T) {
+		c := fuzz.CaseFromBytes(input(t))
+		rejected := false
+		for _, pkt := range c.Packets {
+			if match, err := c.Filter.MatchesPacket(pkt); err == nil && !match {
+				rejected = true
+			}
+		}
+		err := CheckProgram(c.Filter, acceptAll, 0, c.Packets)
+		var v *Violation
+		switch {
+		case rejected && (!errors.As(err, &v) || v.Property != PropertyMatchesFilter):
+			t.Fatalf("accept-all program passed: %v", err)
+		case !rejected && err != nil:
+			t.Fatalf("accept-all program failed on packets the filter selects: %v", err)
+		}
+	})
+}
+
+// TestSmallSnaplenViolates checks that a snapshot length ending before
+// the EtherType breaks within-snaplen for a program that reads it
+func TestSmallSnaplenViolates(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		c := fuzz.CaseFromBytes(input(t))
+		snaplen := rapid.IntRange(1, 11).Draw(t, "snaplen")
+		prog := []*bpf.Instruction{{Code: bpf.OpLdH, K: 12}, {Code: bpf.OpRetK, K: uint32(snaplen)}}
+		for _, pkt := range c.Packets {
+			if len(pkt) < 14 {
+				continue
+			}
+			var v *Violation
+			if err := WithinSnaplen(prog, pkt, snaplen); !errors.As(err, &v) || v.Property != PropertyWithinSnaplen {
+				t.Fatalf("reading bytes 12-13 of a %d-byte snapshot passed: %v", snaplen, err)
+			}
+		}
+	})
+}
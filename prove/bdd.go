@@ -0,0 +1,152 @@
+package prove
+
+import "math"
+
+// Terminal nodes of every BDD
+const (
+	bddFalse = 0
+	bddTrue  = 1
+)
+
+// terminalLevel orders the terminals after every variable
+const terminalLevel = math.MaxInt
+
+// MaxNodes bounds the nodes of the diagrams of both programs. Programs
+// that index packets by several header lengths, such as filters on the
+// inner packet of a tunnel with options, can need more.
+const MaxNodes = 1 << 21
+
+// bddNode tests variable level: lo is the function when it is 0, hi when
+// it is 1
+type bddNode struct {
+	level  int
+	lo, hi int
+}
+
+// bdd is a store of reduced ordered binary decision diagrams. Functions are
+// node indices; equal functions have equal indices, so comparing two
+// programs' acceptance conditions is comparing two integers.
+type bdd struct {
+	nodes  []bddNode
+	unique map[bddNode]int
+	memo   map[[3]int]int
+	full   bool // MaxNodes was reached, and results are meaningless
+}
+
+func newBDD() *bdd {
+	return &bdd{
+		nodes:  []bddNode{{level: terminalLevel}, {level: terminalLevel}},
+		unique: make(map[bddNode]int),
+		memo:   make(map[[3]int]int),
+	}
+}
+
+// mk returns the node testing level, sharing an existing one
+func (b *bdd) mk(level, lo, hi int) int {
+	if lo == hi {
+		return lo
+	}
+	n := bddNode{level, lo, hi}
+	if id, ok := b.unique[n]; ok {
+		return id
+	}
+	if len(b.nodes) >= MaxNodes {
+		b.full = true
+		return lo
+	}
+	b.nodes = append(b.nodes, n)
+	b.unique[n] = len(b.nodes) - 1
+	return len(b.nodes) - 1
+}
+
+// variable returns the function that is true when variable v is 1
+func (b *bdd) variable(v int) int {
+	return b.mk(v, bddFalse, bddTrue)
+}
+
+// constant returns the terminal for a truth value
+func constant(v bool) int {
+	if v {
+		return bddTrue
+	}
+	return bddFalse
+}
+
+// ite is if f then g else h, from which every other operation follows
+func (b *bdd) ite(f, g, h int) int {
+	switch {
+	case f == bddTrue:
+		return g
+	case f == bddFalse:
+		return h
+	case g == h:
+		return g
+	case g == bddTrue && h == bddFalse:
+		return f
+	}
+	key := [3]int{f, g, h}
+	if r, ok := b.memo[key]; ok {
+		return r
+	}
+	top := min(b.nodes[f].level, b.nodes[g].level, b.nodes[h].level)
+	f0, f1 := b.cofactors(f, top)
+	g0, g1 := b.cofactors(g, top)
+	h0, h1 := b.cofactors(h, top)
+	r := b.mk(top, b.ite(f0, g0, h0), b.ite(f1, g1, h1))
+	b.memo[key] = r
+	return r
+}
+
+// cofactors returns f with the variable at level set to 0 and to 1
+func (b *bdd) cofactors(f, level int) (int, int) {
+	n := b.nodes[f]
+	if n.level != level {
+		return f, f
+	}
+	return n.lo, n.hi
+}
+
+func (b *bdd) not(f int) int    { return b.ite(f, bddFalse, bddTrue) }
+func (b *bdd) and(f, g int) int { return b.ite(f, g, bddFalse) }
+func (b *bdd) or(f, g int) int  { return b.ite(f, bddTrue, g) }
+func (b *bdd) xor(f, g int) int { return b.ite(f, b.not(g), g) }
+
+// fraction is the share of assignments that satisfy f
+func (b *bdd) fraction(f int) float64 {
+	memo := make(map[int]float64)
+	var walk func(int) float64
+	walk = func(f int) float64 {
+		switch f {
+		case bddFalse:
+			return 0
+		case bddTrue:
+			return 1
+		}
+		if r, ok := memo[f]; ok {
+			return r
+		}
+		n := b.nodes[f]
+		r := (walk(n.lo) + walk(n.hi)) / 2
+		memo[f] = r
+		return r
+	}
+	return walk(f)
+}
+
+// cube returns the variables on one path from f to true and their values,
+// preferring zeros. Every assignment agreeing with them satisfies f. f
+// must not be false.
+func (b *bdd) cube(f int) map[int]bool {
+	assignment := make(map[int]bool)
+	for f != bddTrue {
+		n := b.nodes[f]
+		if n.lo != bddFalse {
+			assignment[n.level] = false
+			f = n.lo
+		} else {
+			assignment[n.level] = true
+			f = n.hi
+		}
+	}
+	return assignment
+}
@@ -0,0 +1,269 @@
+// Package prove decides whether two classic BPF programs accept exactly
+// the same packets. Both are executed symbolically: every packet bit the
+// programs load is a variable, and each program's acceptance condition is
+// built as a binary decision diagram over those variables. Reduced ordered
+// BDDs are canonical, so the programs are equivalent exactly when the two
+// conditions are the same node. Otherwise the packets one program accepts
+// and the other rejects form a region of header values, reported as field
+// constraints with a packet from it.
+//
+// The model assumes packets are long enough for every load of both
+// programs, so it does not see a difference between programs that only
+// disagree on truncated packets. ProveSMT has no such limits but needs an
+// external SMT solver, and finds a single packet rather than a region.
+package prove
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// ErrUnsupported is returned for programs using instructions the symbolic
+// model does not cover, such as packet length or ancillary loads
+var ErrUnsupported = errors.New("program is outside the symbolic model")
+
+// Proof is the outcome of comparing two programs
+type Proof struct {
+	Equivalent bool
+
+	// Fraction is the share of packet contents, over the loaded bytes, on
+	// which the programs disagree. Only Prove sets it.
+	Fraction float64
+
+	// Region constrains the header fields of a set of packets the programs
+	// disagree on. Bits not constrained can take any value. Only Prove sets
+	// it.
+	Region []Constraint
+
+	// Witness is a packet from Region, checked on both programs
+	Witness []byte
+
+	// FirstAccepts is true if the first program accepts the Region's
+	// packets and the second rejects them
+	FirstAccepts bool
+}
+
+// Constraint fixes the bits of Mask in a big-endian packet field to those
+// of Value
+type Constraint struct {
+	Field  string // e.g. "ip dst" or "byte 40"
+	Offset int
+	Size   int // bytes
+	Value  uint64
+	Mask   uint64
+}
+
+// String formats the constraint, with addresses dotted and ports and
+// protocols decimal when the whole field is fixed
+func (c Constraint) String() string {
+	full := uint64(1)<<(8*c.Size) - 1
+	switch {
+	case c.Mask == full && c.Size == 4 && (c.Field == "ip src" || c.Field == "ip dst"):
+		return fmt.Sprintf("%s = %s", c.Field, net.IPv4(byte(c.Value>>24), byte(c.Value>>16), byte(c.Value>>8), byte(c.Value)))
+	case c.Mask == full && (c.Field == "ip proto" || c.Field == "src port" || c.Field == "dst port"):
+		return fmt.Sprintf("%s = %d", c.Field, c.Value)
+	case c.Mask == full:
+		return fmt.Sprintf("%s = 0x%0*x", c.Field, 2*c.Size, c.Value)
+	}
+	return fmt.Sprintf("%s & 0x%0*x = 0x%0*x", c.Field, 2*c.Size, c.Mask, 2*c.Size, c.Value)
+}
+
+// Prove decides whether a and b accept the same packets of the link type.
+// Both programs must pass bpf.Verify.
+func Prove(a, b []*bpf.Instruction, link filter.LinkType) (*Proof, error) {
+	for i, prog := range [][]*bpf.Instruction{a, b} {
+		if err := bpf.Verify(prog); err != nil {
+			return nil, fmt.Errorf("program %d: %w", i+1, err)
+		}
+	}
+
+	m := &machine{b: newBDD()}
+	accA, err := m.accepts(a)
+	if err != nil {
+		return nil, fmt.Errorf("program 1: %w", err)
+	}
+	accB, err := m.accepts(b)
+	if err != nil {
+		return nil, fmt.Errorf("program 2: %w", err)
+	}
+	if accA == accB {
+		return &Proof{Equivalent: true}, nil
+	}
+
+	// The region is taken from packets with a 20-byte IPv4 header, after a
+	// VLAN tag if need be, if the programs disagree on any, as they are the
+	// ones filters are written for
+	diff := m.b.xor(accA, accB)
+	region := diff
+	headers := []int{link.HeaderLen()}
+	if link.IsEthernet() {
+		headers = append(headers, link.HeaderLen()+4)
+	}
+	for _, offset := range headers {
+		if typical := m.b.and(diff, m.byteEquals(offset, 0x45)); typical != bddFalse {
+			region = typical
+			break
+		}
+	}
+	if m.b.full {
+		return nil, errTooLarge
+	}
+	bits := m.b.cube(region)
+	proof := &Proof{
+		Fraction: m.b.fraction(diff),
+		Region:   constraints(bits, link),
+		Witness:  witness(bits, max(packetLen(a), packetLen(b))),
+	}
+
+	// The witness confirms the region on the programs themselves
+	ra, err := vm.Run(a, proof.Witness)
+	if err != nil {
+		return nil, fmt.Errorf("program 1 on the witness: %w", err)
+	}
+	rb, err := vm.Run(b, proof.Witness)
+	if err != nil {
+		return nil, fmt.Errorf("program 2 on the witness: %w", err)
+	}
+	if ra.Accepted == rb.Accepted {
+		return nil, fmt.Errorf("internal error: the programs agree on witness %x", proof.Witness)
+	}
+	proof.FirstAccepts = ra.Accepted
+	return proof, nil
+}
+
+// packetLen returns the length of a packet covering every load of prog,
+// with the largest IP header before indexed loads
+func packetLen(prog []*bpf.Instruction) int {
+	n := 0
+	for _, inst := range prog {
+		var end int
+		size := map[uint16]int{bpf.SizeW: 4, bpf.SizeH: 2, bpf.SizeB: 1}[inst.Code&0x18]
+		switch {
+		case inst.Class() == bpf.ClassLD && inst.Code&0xe0 == bpf.ModeABS:
+			end = int(inst.K) + size
+		case inst.Class() == bpf.ClassLD && inst.Code&0xe0 == bpf.ModeIND:
+			end = int(inst.K) + size + 60
+		case inst.Class() == bpf.ClassLDX && inst.Code&0xe0 == bpf.ModeMSH:
+			end = int(inst.K) + 1
+		}
+		n = max(n, min(end, MaxOffset))
+	}
+	return n
+}
+
+// witness builds a packet of n bytes from the fixed bits, leaving the rest 0
+func witness(bits map[int]bool, n int) []byte {
+	for v := range bits {
+		n = max(n, v/8+1)
+	}
+	pkt := make([]byte, n)
+	for v, one := range bits {
+		if one {
+			pkt[v/8] |= 0x80 >> (v % 8)
+		}
+	}
+	return pkt
+}
+
+// field is a named header field at a packet offset
+type field struct {
+	name   string
+	offset int
+	size   int
+}
+
+// constraints turns fixed bits into constraints on the fields holding them.
+// IP fields are placed after the link header and a VLAN tag the bits fix,
+// and ports after an IP header of the length the bits fix, or of 20 bytes.
+func constraints(bits map[int]bool, link filter.LinkType) []Constraint {
+	ip := link.HeaderLen()
+	var fields []field
+	if link.IsEthernet() {
+		fields = append(fields, field{"ethertype", 12, 2})
+		if fixed(bits, 12, 0, 16) {
+			switch value(bits, 12, 0, 16) {
+			case 0x8100, 0x88a8, 0x9100:
+				fields = append(fields, field{"vlan tci", 14, 2}, field{"inner ethertype", 16, 2})
+				ip += 4
+			}
+		}
+	}
+	fields = append(fields,
+		field{"ip version/ihl", ip, 1},
+		field{"ip flags/frag", ip + 6, 2},
+		field{"ip proto", ip + 9, 1},
+		field{"ip src", ip + 12, 4},
+		field{"ip dst", ip + 16, 4},
+	)
+	ihl := 5
+	if fixed(bits, ip, 4, 4) {
+		ihl = int(value(bits, ip, 4, 4))
+	}
+	if transport := ip + 4*ihl; ihl >= 5 {
+		fields = append(fields, field{"src port", transport, 2}, field{"dst port", transport + 2, 2})
+	}
+
+	byOffset := make(map[int]*Constraint)
+	for v, one := range bits {
+		o := v / 8
+		c := byOffset[o]
+		if c == nil {
+			f := field{fmt.Sprintf("byte %d", o), o, 1}
+			for _, candidate := range fields {
+				if o >= candidate.offset && o < candidate.offset+candidate.size {
+					f = candidate
+					break
+				}
+			}
+			c = &Constraint{Field: f.name, Offset: f.offset, Size: f.size}
+			for i := 0; i < f.size; i++ {
+				byOffset[f.offset+i] = c
+			}
+		}
+		bit := uint64(1) << (8*(c.Offset+c.Size) - 1 - v)
+		c.Mask |= bit
+		if one {
+			c.Value |= bit
+		}
+	}
+
+	seen := make(map[*Constraint]bool)
+	var out []Constraint
+	for _, c := range byOffset {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, *c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Offset < out[j].Offset })
+	return out
+}
+
+// fixed reports whether bits fixes the n bits starting at bit first of
+// the byte at offset
+func fixed(bits map[int]bool, offset, first, n int) bool {
+	for i := 0; i < n; i++ {
+		if _, ok := bits[8*offset+first+i]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// value reads the n fixed bits starting at bit first of the byte at offset
+func value(bits map[int]bool, offset, first, n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if bits[8*offset+first+i] {
+			v |= 1
+		}
+	}
+	return v
+}
@@ -0,0 +1,492 @@
+package prove
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// MaxPacketLen bounds the packets the SMT backend considers, at tcpdump's
+// default snapshot length
+const MaxPacketLen = 262144
+
+// preferredLen is the length of an Ethernet frame at the usual MTU. Packets
+// up to it are tried first so that witnesses stay short.
+const preferredLen = 1514
+
+// Solver runs an SMT-LIB 2 solver that reads commands on standard input
+// and answers on standard output, such as z3 or cvc5
+type Solver struct {
+	Command []string      // program and arguments
+	Timeout time.Duration // bounds the whole proof (0 means DefaultSolverTimeout)
+}
+
+// DefaultSolver runs z3
+var DefaultSolver = Solver{Command: []string{"z3", "-in", "-smt2"}}
+
+// DefaultSolverTimeout bounds solver runs whose Solver sets no timeout
+var DefaultSolverTimeout = 60 * time.Second
+
+// ParseSolver parses a solver command line such as "cvc5 --lang smt2
+// --incremental". The empty string is DefaultSolver.
+func ParseSolver(s string) (Solver, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultSolver, nil
+	}
+	return Solver{Command: strings.Fields(s)}, nil
+}
+
+// ProveSMT decides whether a and b accept the same packets with an SMT
+// solver. Unlike Prove, it models the packet length, so it also tells
+// programs apart on truncated packets, and it covers every instruction
+// the VM runs, with symbolic offsets and arbitrary arithmetic. The proof
+// has a witness packet but no region or fraction.
+func ProveSMT(ctx context.Context, a, b []*bpf.Instruction, solver Solver) (*Proof, error) {
+	for i, prog := range [][]*bpf.Instruction{a, b} {
+		if err := bpf.Verify(prog); err != nil {
+			return nil, fmt.Errorf("program %d: %w", i+1, err)
+		}
+	}
+
+	var script strings.Builder
+	script.WriteString("(set-option :produce-models true)\n(set-logic QF_ABV)\n")
+	script.WriteString("(declare-const pkt (Array (_ BitVec 32) (_ BitVec 8)))\n(declare-const len (_ BitVec 32))\n")
+	fmt.Fprintf(&script, "(assert (bvule len %s))\n", bv(MaxPacketLen))
+	accA := encode(&script, "p1", a)
+	accB := encode(&script, "p2", b)
+	fmt.Fprintf(&script, "(assert (distinct %s %s))\n", accA, accB)
+
+	timeout := solver.Timeout
+	if timeout == 0 {
+		timeout = DefaultSolverTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	s, err := startSession(ctx, solver)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	// Short packets first, then any up to the snapshot length
+	answer, err := s.ask(script.String() + fmt.Sprintf("(push 1)\n(assert (bvule len %s))\n(check-sat)\n", bv(preferredLen)))
+	if err == nil && answer == "unsat" {
+		answer, err = s.ask("(pop 1)\n(check-sat)\n")
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case answer == "unsat":
+		return &Proof{Equivalent: true}, nil
+	case answer != "sat":
+		return nil, fmt.Errorf("solver answered %s", answer)
+	}
+
+	answer, err = s.ask("(get-value (len))\n")
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseValues(answer)
+	if err != nil || len(values) != 1 {
+		return nil, fmt.Errorf("unexpected solver answer %q", answer)
+	}
+	n := int(values[0])
+	witness := make([]byte, n)
+	if n > 0 {
+		var query strings.Builder
+		query.WriteString("(get-value (")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&query, "(select pkt %s) ", bv(uint32(i)))
+		}
+		query.WriteString("))\n")
+		if answer, err = s.ask(query.String()); err != nil {
+			return nil, err
+		}
+		// Every pair holds the index and the byte
+		values, err := parseValues(answer)
+		if err != nil || len(values) != 2*n {
+			return nil, fmt.Errorf("unexpected solver answer to the packet bytes")
+		}
+		for i := 0; i < n; i++ {
+			witness[values[2*i]] = byte(values[2*i+1])
+		}
+	}
+
+	proof := &Proof{Witness: witness}
+	ra, err := vm.Run(a, witness)
+	if err != nil {
+		return nil, fmt.Errorf("program 1 on the witness: %w", err)
+	}
+	rb, err := vm.Run(b, witness)
+	if err != nil {
+		return nil, fmt.Errorf("program 2 on the witness: %w", err)
+	}
+	if ra.Accepted == rb.Accepted {
+		return nil, fmt.Errorf("internal error: the programs agree on witness %x", witness)
+	}
+	proof.FirstAccepts = ra.Accepted
+	return proof, nil
+}
+
+// bv formats a 32-bit constant
+func bv(k uint32) string {
+	return fmt.Sprintf("#x%08x", k)
+}
+
+// edge is a way into an instruction: the guard under which the program
+// takes it and the registers it brings
+type edge struct {
+	guard string
+	a, x  string
+	mem   [bpf.MemWords]string
+}
+
+// encode writes definitions of the program's state before each instruction
+// as functions of pkt and len, prefixed with name, and returns the name of
+// the condition under which the program accepts. Registers after an
+// instruction are selected from its incoming edges; the paths taking them
+// are disjoint, and jumps only go forward, so each instruction is defined
+// after those it depends on. A load outside the packet and a division by
+// zero leave no edge, as they end the program with 0.
+func encode(w io.Writer, name string, prog []*bpf.Instruction) string {
+	in := make([][]edge, len(prog)+1)
+	entry := edge{guard: "true", a: bv(0), x: bv(0)}
+	for k := range entry.mem {
+		entry.mem[k] = bv(0)
+	}
+	in[0] = []edge{entry}
+	var accepts []string
+
+	for pc, inst := range prog {
+		p := fmt.Sprintf("%s_%d", name, pc)
+		if len(in[pc]) == 0 {
+			continue // unreachable
+		}
+
+		// Registers before the instruction
+		var guards []string
+		for _, e := range in[pc] {
+			guards = append(guards, e.guard)
+		}
+		reach := guards[0]
+		if len(guards) > 1 {
+			reach = "(or " + strings.Join(guards, " ") + ")"
+		}
+		fmt.Fprintf(w, "(define-fun %s_r () Bool %s)\n", p, reach)
+		pick := func(get func(edge) string) string {
+			v := get(in[pc][len(in[pc])-1])
+			for i := len(in[pc]) - 2; i >= 0; i-- {
+				if e := in[pc][i]; get(e) != v {
+					v = fmt.Sprintf("(ite %s %s %s)", e.guard, get(e), v)
+				}
+			}
+			return v
+		}
+		st := edge{guard: p + "_r", a: p + "_a", x: p + "_x"}
+		fmt.Fprintf(w, "(define-fun %s_a () (_ BitVec 32) %s)\n", p, pick(func(e edge) string { return e.a }))
+		fmt.Fprintf(w, "(define-fun %s_x () (_ BitVec 32) %s)\n", p, pick(func(e edge) string { return e.x }))
+		for k := range st.mem {
+			k := k
+			st.mem[k] = pick(func(e edge) string { return e.mem[k] })
+			if strings.HasPrefix(st.mem[k], "(") {
+				fmt.Fprintf(w, "(define-fun %s_m%d () (_ BitVec 32) %s)\n", p, k, st.mem[k])
+				st.mem[k] = fmt.Sprintf("%s_m%d", p, k)
+			}
+		}
+
+		// goTo adds an edge taken under cond with registers out
+		goTo := func(target int, cond string, out edge) {
+			out.guard = st.guard
+			if cond != "true" {
+				out.guard = fmt.Sprintf("(and %s %s)", st.guard, cond)
+			}
+			in[target] = append(in[target], out)
+		}
+		operand := bv(inst.K)
+		if inst.Code&bpf.SrcX != 0 {
+			operand = st.x
+		}
+
+		switch inst.Class() {
+		case bpf.ClassLD, bpf.ClassLDX:
+			v, cond, ok := loadTerm(inst, st)
+			if !ok {
+				break
+			}
+			out := st
+			if inst.Class() == bpf.ClassLD {
+				out.a = v
+			} else {
+				out.x = v
+			}
+			goTo(pc+1, cond, out)
+
+		case bpf.ClassST, bpf.ClassSTX:
+			out := st
+			out.mem[inst.K] = st.a
+			if inst.Class() == bpf.ClassSTX {
+				out.mem[inst.K] = st.x
+			}
+			goTo(pc+1, "true", out)
+
+		case bpf.ClassALU:
+			op := inst.Code & 0xf0
+			cond := "true"
+			if op == bpf.ALUDiv || op == bpf.ALUMod {
+				if inst.Code&bpf.SrcX == 0 && inst.K == 0 {
+					break
+				}
+				if inst.Code&bpf.SrcX != 0 {
+					cond = fmt.Sprintf("(distinct %s %s)", st.x, bv(0))
+				}
+			}
+			out := st
+			out.a = aluTerm(op, st.a, operand)
+			goTo(pc+1, cond, out)
+
+		case bpf.ClassJMP:
+			op := inst.Code & 0xf0
+			if op == bpf.JmpJA {
+				goTo(pc+1+int(inst.K), "true", st)
+				break
+			}
+			var c string
+			switch op {
+			case bpf.JmpJEQ:
+				c = fmt.Sprintf("(= %s %s)", st.a, operand)
+			case bpf.JmpJGT:
+				c = fmt.Sprintf("(bvugt %s %s)", st.a, operand)
+			case bpf.JmpJGE:
+				c = fmt.Sprintf("(bvuge %s %s)", st.a, operand)
+			default:
+				c = fmt.Sprintf("(distinct (bvand %s %s) %s)", st.a, operand, bv(0))
+			}
+			goTo(pc+1+int(inst.JT), c, st)
+			goTo(pc+1+int(inst.JF), "(not "+c+")", st)
+
+		case bpf.ClassRET:
+			v := bv(inst.K)
+			if inst.Code&0x18 == bpf.RetA {
+				v = st.a
+			}
+			accepts = append(accepts, fmt.Sprintf("(and %s (distinct %s %s))", st.guard, v, bv(0)))
+
+		case bpf.ClassMISC:
+			out := st
+			if inst.Code&0xf8 == bpf.MiscTXA {
+				out.a = st.x
+			} else {
+				out.x = st.a
+			}
+			goTo(pc+1, "true", out)
+		}
+	}
+
+	accept := "false"
+	if len(accepts) > 0 {
+		accept = "(or false " + strings.Join(accepts, " ") + ")"
+	}
+	fmt.Fprintf(w, "(define-fun %s_accept () Bool %s)\n", name, accept)
+	return name + "_accept"
+}
+
+// loadTerm returns the value a load instruction reads and the condition
+// that it lies inside the packet. The boolean result is false when it
+// never does, as for ancillary loads, which the VM treats the same way.
+func loadTerm(inst *bpf.Instruction, st edge) (string, string, bool) {
+	switch inst.Code & 0xe0 {
+	case bpf.ModeIMM:
+		return bv(inst.K), "true", true
+	case bpf.ModeMEM:
+		return st.mem[inst.K], "true", true
+	case bpf.ModeLEN:
+		return "len", "true", true
+	case bpf.ModeMSH:
+		if inst.K >= MaxPacketLen {
+			return "", "", false
+		}
+		nibble := fmt.Sprintf("((_ zero_extend 24) (bvand (select pkt %s) #x0f))", bv(inst.K))
+		return fmt.Sprintf("(bvshl %s %s)", nibble, bv(2)), fmt.Sprintf("(bvult %s len)", bv(inst.K)), true
+	}
+
+	size := map[uint16]uint32{bpf.SizeW: 4, bpf.SizeH: 2, bpf.SizeB: 1}[inst.Code&0x18]
+	var offset, cond string
+	if inst.Code&0xe0 == bpf.ModeIND {
+		// The offset is computed in 64 bits so that it cannot wrap
+		wide := fmt.Sprintf("(bvadd ((_ zero_extend 32) %s) #x%016x)", st.x, uint64(inst.K)+uint64(size))
+		cond = fmt.Sprintf("(bvule %s ((_ zero_extend 32) len))", wide)
+		offset = fmt.Sprintf("(bvadd %s %s)", st.x, bv(inst.K))
+	} else {
+		if uint64(inst.K)+uint64(size) > MaxPacketLen {
+			return "", "", false
+		}
+		cond = fmt.Sprintf("(bvule %s len)", bv(inst.K+size))
+		offset = bv(inst.K)
+	}
+	parts := make([]string, size)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("(select pkt (bvadd %s %s))", offset, bv(uint32(i)))
+	}
+	v := parts[0]
+	if size > 1 {
+		v = "(concat " + strings.Join(parts, " ") + ")"
+	}
+	if size < 4 {
+		v = fmt.Sprintf("((_ zero_extend %d) %s)", 32-8*size, v)
+	}
+	return v, cond, true
+}
+
+// aluTerm returns the result of an arithmetic instruction. Division by
+// zero is excluded by the edge's guard.
+func aluTerm(op uint16, a, operand string) string {
+	fn := map[uint16]string{
+		bpf.ALUAdd: "bvadd", bpf.ALUSub: "bvsub", bpf.ALUMul: "bvmul",
+		bpf.ALUDiv: "bvudiv", bpf.ALUMod: "bvurem",
+		bpf.ALUOr: "bvor", bpf.ALUAnd: "bvand", bpf.ALUXor: "bvxor",
+		bpf.ALULsh: "bvshl", bpf.ALURsh: "bvlshr",
+	}
+	switch op {
+	case bpf.ALUNeg:
+		return fmt.Sprintf("(bvneg %s)", a)
+	case bpf.ALULsh, bpf.ALURsh:
+		operand = fmt.Sprintf("(bvand %s %s)", operand, bv(31))
+	}
+	return fmt.Sprintf("(%s %s %s)", fn[op], a, operand)
+}
+
+// session is a running solver
+type session struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr bytes.Buffer
+	ctx    context.Context
+}
+
+// startSession starts the solver, which is killed when ctx is done
+func startSession(ctx context.Context, solver Solver) (*session, error) {
+	if len(solver.Command) == 0 {
+		return nil, fmt.Errorf("no solver command")
+	}
+	s := &session{ctx: ctx, cmd: exec.CommandContext(ctx, solver.Command[0], solver.Command[1:]...)}
+	s.cmd.Stderr = &s.stderr
+	stdin, err := s.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("SMT solver %s not found; install it or choose another with --solver: %w", solver.Command[0], err)
+		}
+		return nil, fmt.Errorf("failed to start %s: %v", solver.Command[0], err)
+	}
+	s.stdin, s.stdout = stdin, bufio.NewReader(stdout)
+	return s, nil
+}
+
+// ask sends commands ending in one that answers and reads the answer
+func (s *session) ask(commands string) (string, error) {
+	if _, err := io.WriteString(s.stdin, commands); err != nil {
+		return "", s.failure(err)
+	}
+	answer, err := readSExpr(s.stdout)
+	if err != nil {
+		return "", s.failure(err)
+	}
+	if strings.HasPrefix(answer, "(error") {
+		return "", fmt.Errorf("solver error: %s", answer)
+	}
+	return answer, nil
+}
+
+// failure explains a broken conversation with the solver
+func (s *session) failure(err error) error {
+	if s.ctx.Err() != nil {
+		return fmt.Errorf("solver %s: %w", s.cmd.Path, s.ctx.Err())
+	}
+	if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+		return fmt.Errorf("solver %s: %v: %s", s.cmd.Path, err, msg)
+	}
+	return fmt.Errorf("solver %s: %v", s.cmd.Path, err)
+}
+
+// close ends the solver
+func (s *session) close() {
+	io.WriteString(s.stdin, "(exit)\n")
+	s.stdin.Close()
+	s.cmd.Wait()
+}
+
+// readSExpr reads one atom or parenthesized expression
+func readSExpr(r *bufio.Reader) (string, error) {
+	var sb strings.Builder
+	depth, quoted := 0, false
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case quoted:
+			quoted = c != '"'
+		case c == '"':
+			quoted = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+			if depth == 0 {
+				if sb.Len() > 0 {
+					return sb.String(), nil
+				}
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		if depth == 0 && c == ')' {
+			return sb.String(), nil
+		}
+	}
+}
+
+// bitvector matches the bit-vector literals solvers print
+var bitvector = regexp.MustCompile(`#x[0-9a-fA-F]+|#b[01]+|\(_ bv([0-9]+) [0-9]+\)`)
+
+// parseValues returns the bit-vector literals of a get-value answer in order
+func parseValues(answer string) ([]uint64, error) {
+	var values []uint64
+	for _, m := range bitvector.FindAllStringSubmatch(answer, -1) {
+		var v uint64
+		var err error
+		switch {
+		case strings.HasPrefix(m[0], "#x"):
+			v, err = strconv.ParseUint(m[0][2:], 16, 64)
+		case strings.HasPrefix(m[0], "#b"):
+			v, err = strconv.ParseUint(m[0][2:], 2, 64)
+		default:
+			v, err = strconv.ParseUint(m[1], 10, 64)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
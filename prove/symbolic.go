@@ -0,0 +1,410 @@
+package prove
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+)
+
+// MaxOffset bounds the packet bytes a program may load. The model treats
+// every packet as long enough for the loads of both programs.
+const MaxOffset = 512
+
+// errTooLarge is returned once the diagrams reach MaxNodes
+var errTooLarge = fmt.Errorf("%w: the decision diagrams need more than %d nodes", ErrUnsupported, MaxNodes)
+
+// maxValues bounds the values a symbolic index register is split into
+const maxValues = 256
+
+// word is a 32-bit value as one function of the packet bits per bit,
+// least significant first
+type word [32]int
+
+// state is the machine on every path reaching an instruction with the
+// same index register. Paths are disjoint, so states that meet are merged
+// bit by bit under their conditions.
+type state struct {
+	cond int // packets that take these paths
+	a    word
+	x    uint32
+	mem  [bpf.MemWords]word
+}
+
+// machine runs programs symbolically over a shared BDD store, in which
+// bit j (most significant first) of packet byte o is variable 8*o+j
+type machine struct {
+	b *bdd
+}
+
+// constWord returns the function of a constant
+func constWord(k uint32) word {
+	var w word
+	for i := range w {
+		w[i] = constant(k>>i&1 == 1)
+	}
+	return w
+}
+
+// accepts returns the condition on packet bits under which the program
+// returns a non-zero length. Programs are assumed to have passed
+// bpf.Verify, so jumps only go forward and every path returns.
+func (m *machine) accepts(prog []*bpf.Instruction) (int, error) {
+	pending := make(map[int][]*state)
+	pending[0] = []*state{{cond: bddTrue}}
+	accept := bddFalse
+
+	for pc := 0; pc < len(prog); pc++ {
+		for _, st := range m.merge(pending[pc]) {
+			next, ret, err := m.step(prog, pc, st)
+			if err != nil {
+				return 0, fmt.Errorf("instruction %d: %w", pc, err)
+			}
+			accept = m.b.or(accept, ret)
+			for _, n := range next {
+				if n.s.cond != bddFalse {
+					pending[n.pc] = append(pending[n.pc], n.s)
+				}
+			}
+		}
+		delete(pending, pc)
+		if m.b.full {
+			return 0, fmt.Errorf("instruction %d: %w", pc, errTooLarge)
+		}
+	}
+	return accept, nil
+}
+
+// merge joins the states that hold the same index register
+func (m *machine) merge(states []*state) []*state {
+	byX := make(map[uint32]*state)
+	var xs []uint32
+	for _, s := range states {
+		into, ok := byX[s.x]
+		if !ok {
+			byX[s.x] = s
+			xs = append(xs, s.x)
+			continue
+		}
+		merged := &state{cond: m.b.or(into.cond, s.cond), x: s.x}
+		for i := range merged.a {
+			merged.a[i] = m.b.ite(s.cond, s.a[i], into.a[i])
+		}
+		for k := range merged.mem {
+			for i := range merged.mem[k] {
+				merged.mem[k][i] = m.b.ite(s.cond, s.mem[k][i], into.mem[k][i])
+			}
+		}
+		byX[s.x] = merged
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+	out := make([]*state, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, byX[x])
+	}
+	return out
+}
+
+// successor is a state and the instruction it continues at
+type successor struct {
+	pc int
+	s  *state
+}
+
+// step executes one instruction, returning the states after it and the
+// condition under which it returns a non-zero length
+func (m *machine) step(prog []*bpf.Instruction, pc int, st *state) ([]successor, int, error) {
+	inst := prog[pc]
+	b := m.b
+	next := func(s *state) []successor { return []successor{{pc + 1, s}} }
+	with := func(a word) *state {
+		s := *st
+		s.a = a
+		return &s
+	}
+
+	switch inst.Class() {
+	case bpf.ClassLD:
+		a, err := m.load(inst, st)
+		if err != nil {
+			return nil, 0, err
+		}
+		return next(with(a)), bddFalse, nil
+
+	case bpf.ClassLDX:
+		var v word
+		switch inst.Code & 0xe0 {
+		case bpf.ModeIMM:
+			v = constWord(inst.K)
+		case bpf.ModeMEM:
+			v = st.mem[inst.K]
+		case bpf.ModeMSH:
+			if inst.K+1 > MaxOffset {
+				return nil, 0, fmt.Errorf("%w: load beyond the first %d bytes", ErrUnsupported, MaxOffset)
+			}
+			nibble := m.bytes(inst.K, 1)
+			v = constWord(0)
+			for i := 0; i < 4; i++ {
+				v[i+2] = nibble[i]
+			}
+		default:
+			return nil, 0, fmt.Errorf("%w: the packet length is not modeled", ErrUnsupported)
+		}
+		splits, err := m.split(st.cond, v)
+		if err != nil {
+			return nil, 0, err
+		}
+		var out []successor
+		for _, sp := range splits {
+			s := *st
+			s.cond, s.x = sp.cond, sp.value
+			out = append(out, successor{pc + 1, &s})
+		}
+		return out, bddFalse, nil
+
+	case bpf.ClassST:
+		s := *st
+		s.mem[inst.K] = st.a
+		return next(&s), bddFalse, nil
+
+	case bpf.ClassSTX:
+		s := *st
+		s.mem[inst.K] = constWord(st.x)
+		return next(&s), bddFalse, nil
+
+	case bpf.ClassALU:
+		operand := inst.K
+		if inst.Code&bpf.SrcX != 0 {
+			operand = st.x
+		}
+		a, ok, err := m.alu(inst.Code&0xf0, st.a, operand)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			// Division by zero ends the program with 0
+			return nil, bddFalse, nil
+		}
+		return next(with(a)), bddFalse, nil
+
+	case bpf.ClassJMP:
+		op := inst.Code & 0xf0
+		if op == bpf.JmpJA {
+			return []successor{{pc + 1 + int(inst.K), st}}, bddFalse, nil
+		}
+		operand := inst.K
+		if inst.Code&bpf.SrcX != 0 {
+			operand = st.x
+		}
+		c := m.compare(op, st.a, operand)
+		taken, fallen := *st, *st
+		taken.cond = b.and(st.cond, c)
+		fallen.cond = b.and(st.cond, b.not(c))
+		return []successor{{pc + 1 + int(inst.JT), &taken}, {pc + 1 + int(inst.JF), &fallen}}, bddFalse, nil
+
+	case bpf.ClassRET:
+		if inst.Code&0x18 == bpf.RetA {
+			nonZero := bddFalse
+			for _, bit := range st.a {
+				nonZero = b.or(nonZero, bit)
+			}
+			return nil, b.and(st.cond, nonZero), nil
+		}
+		return nil, b.and(constant(inst.K != 0), st.cond), nil
+
+	case bpf.ClassMISC:
+		if inst.Code&0xf8 == bpf.MiscTXA {
+			return next(with(constWord(st.x))), bddFalse, nil
+		}
+		splits, err := m.split(st.cond, st.a)
+		if err != nil {
+			return nil, 0, err
+		}
+		var out []successor
+		for _, sp := range splits {
+			s := *st
+			s.cond, s.x = sp.cond, sp.value
+			out = append(out, successor{pc + 1, &s})
+		}
+		return out, bddFalse, nil
+	}
+	return nil, 0, fmt.Errorf("%w: opcode 0x%04x", ErrUnsupported, inst.Code)
+}
+
+// load evaluates a BPF_LD instruction
+func (m *machine) load(inst *bpf.Instruction, st *state) (word, error) {
+	switch inst.Code & 0xe0 {
+	case bpf.ModeIMM:
+		return constWord(inst.K), nil
+	case bpf.ModeMEM:
+		return st.mem[inst.K], nil
+	case bpf.ModeABS, bpf.ModeIND:
+		offset := uint64(inst.K)
+		if inst.Code&0xe0 == bpf.ModeIND {
+			offset += uint64(st.x)
+		}
+		size := map[uint16]uint64{bpf.SizeW: 4, bpf.SizeH: 2, bpf.SizeB: 1}[inst.Code&0x18]
+		if offset+size > MaxOffset {
+			return word{}, fmt.Errorf("%w: load beyond the first %d bytes", ErrUnsupported, MaxOffset)
+		}
+		return m.bytes(uint32(offset), int(size)), nil
+	}
+	return word{}, fmt.Errorf("%w: the packet length is not modeled", ErrUnsupported)
+}
+
+// bytes returns the big-endian value of size packet bytes at offset
+func (m *machine) bytes(offset uint32, size int) word {
+	w := constWord(0)
+	for i := 0; i < size; i++ {
+		o := int(offset) + i
+		for j := 0; j < 8; j++ {
+			w[(size-1-i)*8+7-j] = m.b.variable(8*o + j)
+		}
+	}
+	return w
+}
+
+// byteEquals returns the condition that the packet byte at offset is v
+func (m *machine) byteEquals(offset int, v byte) int {
+	return m.compare(bpf.JmpJEQ, m.bytes(uint32(offset), 1), uint32(v))
+}
+
+// alu evaluates an arithmetic instruction with a constant operand. The
+// boolean result is false for a division or modulo by zero.
+func (m *machine) alu(op uint16, a word, k uint32) (word, bool, error) {
+	b := m.b
+	var out word
+	switch op {
+	case bpf.ALUAdd:
+		return m.add(a, constWord(k)), true, nil
+	case bpf.ALUSub:
+		return m.add(a, constWord(-k)), true, nil
+	case bpf.ALUNeg:
+		for i := range a {
+			out[i] = b.not(a[i])
+		}
+		return m.add(out, constWord(1)), true, nil
+	case bpf.ALUMul:
+		out = constWord(0)
+		for s := 0; s < 32; s++ {
+			if k>>s&1 == 1 {
+				out = m.add(out, shift(a, s))
+			}
+		}
+		return out, true, nil
+	case bpf.ALUDiv, bpf.ALUMod:
+		if k == 0 {
+			return word{}, false, nil
+		}
+		if k&(k-1) != 0 {
+			return word{}, false, fmt.Errorf("%w: division by %d, which is not a power of two", ErrUnsupported, k)
+		}
+		s := 0
+		for k>>s != 1 {
+			s++
+		}
+		if op == bpf.ALUDiv {
+			return shift(a, -s), true, nil
+		}
+		return m.bitwise(a, k-1, b.and), true, nil
+	case bpf.ALUAnd:
+		return m.bitwise(a, k, b.and), true, nil
+	case bpf.ALUOr:
+		return m.bitwise(a, k, b.or), true, nil
+	case bpf.ALUXor:
+		return m.bitwise(a, k, b.xor), true, nil
+	case bpf.ALULsh:
+		return shift(a, int(k&31)), true, nil
+	case bpf.ALURsh:
+		return shift(a, -int(k&31)), true, nil
+	}
+	return word{}, false, fmt.Errorf("%w: alu op 0x%02x", ErrUnsupported, op)
+}
+
+// bitwise applies op to each bit of a and of the constant
+func (m *machine) bitwise(a word, k uint32, op func(int, int) int) word {
+	c := constWord(k)
+	var out word
+	for i := range a {
+		out[i] = op(a[i], c[i])
+	}
+	return out
+}
+
+// shift moves the bits of a left by s, or right when s is negative
+func shift(a word, s int) word {
+	out := constWord(0)
+	for i := range a {
+		if j := i + s; j >= 0 && j < 32 {
+			out[j] = a[i]
+		}
+	}
+	return out
+}
+
+// add is a ripple-carry adder modulo 2^32
+func (m *machine) add(x, y word) word {
+	b := m.b
+	var out word
+	carry := bddFalse
+	for i := range x {
+		out[i] = b.xor(b.xor(x[i], y[i]), carry)
+		carry = b.or(b.and(x[i], y[i]), b.and(carry, b.xor(x[i], y[i])))
+	}
+	return out
+}
+
+// compare returns the condition of a conditional jump on a and k
+func (m *machine) compare(op uint16, a word, k uint32) int {
+	b := m.b
+	eq, gt, set := bddTrue, bddFalse, bddFalse
+	for i := range a {
+		if k>>i&1 == 1 {
+			eq = b.and(eq, a[i])
+			gt = b.and(a[i], gt)
+			set = b.or(set, a[i])
+		} else {
+			eq = b.and(eq, b.not(a[i]))
+			gt = b.or(a[i], gt)
+		}
+	}
+	switch op {
+	case bpf.JmpJEQ:
+		return eq
+	case bpf.JmpJGT:
+		return gt
+	case bpf.JmpJGE:
+		return b.or(gt, eq)
+	}
+	return set
+}
+
+// valued is a concrete value and the packets that give it
+type valued struct {
+	value uint32
+	cond  int
+}
+
+// split enumerates the values w takes on the packets of cond, for the
+// index register, which must be concrete to address packet bytes
+func (m *machine) split(cond int, w word) ([]valued, error) {
+	var out []valued
+	var walk func(cond, i int, v uint32) error
+	walk = func(cond, i int, v uint32) error {
+		if cond == bddFalse {
+			return nil
+		}
+		if i < 0 {
+			if len(out) == maxValues {
+				return fmt.Errorf("%w: the index register takes more than %d values", ErrUnsupported, maxValues)
+			}
+			out = append(out, valued{v, cond})
+			return nil
+		}
+		if err := walk(m.b.and(cond, w[i]), i-1, v|1<<i); err != nil {
+			return err
+		}
+		return walk(m.b.and(cond, m.b.not(w[i])), i-1, v)
+	}
+	return out, walk(cond, 31, 0)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+)
+
+// Metrics are the Prometheus metrics of a Server, for watching a validator
+// that runs continuously against policy corpora. A nil *Metrics records
+// nothing.
+type Metrics struct {
+	compiles   *prometheus.CounterVec
+	scores     prometheus.Histogram
+	mismatches *prometheus.CounterVec
+	reference  *prometheus.HistogramVec
+}
+
+// NewMetrics creates the metrics and registers them with reg
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		compiles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bpfvalidator_compiles_total",
+			Help: "Programs generated, by backend: prototype, or the reference compiler (libpcap, tcpdump, mock).",
+		}, []string{"backend"}),
+		scores: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "bpfvalidator_compare_score",
+			Help:    "Scores of comparisons between the prototype and the reference, 0 to 1.",
+			Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+		}),
+		mismatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bpfvalidator_mismatches_total",
+			Help: "Checks that differ between the compared programs, by instruction type.",
+		}, []string{"instruction"}),
+		reference: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "bpfvalidator_reference_compile_duration_seconds",
+			Help:    "Time to compile a reference program, including running tcpdump, by backend.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 4, 8),
+		}, []string{"backend"}),
+	}
+	for _, c := range []prometheus.Collector{m.compiles, m.scores, m.mismatches, m.reference} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// compiled records a generated program
+func (m *Metrics) compiled(backend string) {
+	if m == nil {
+		return
+	}
+	m.compiles.WithLabelValues(backend).Inc()
+}
+
+// referenceCompiled records a reference program and the time it took
+func (m *Metrics) referenceCompiled(backend string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.compiles.WithLabelValues(backend).Inc()
+	m.reference.WithLabelValues(backend).Observe(elapsed.Seconds())
+}
+
+// compared records the score and mismatches of a comparison
+func (m *Metrics) compared(r *compare.ComparisonResult) {
+	if m == nil {
+		return
+	}
+	m.scores.Observe(r.Score)
+	for t, n := range r.MismatchesByType() {
+		m.mismatches.WithLabelValues(t.String()).Add(float64(n))
+	}
+}
@@ -0,0 +1,316 @@
+// Package server implements the Validator gRPC service of api/validator/v1
+// over the same packages the CLI uses. Invalid requests fail with
+// InvalidArgument; a program that cannot be generated fails with
+// FailedPrecondition, as the compare command would have, or Unavailable when
+// the reference compiler cannot run here.
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	validatorv1 "github.com/imshubham22apr-gif/Antrea-Project-Prototype/api/validator/v1"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpf"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// Server is the Validator service. The zero value is ready to use.
+type Server struct {
+	validatorv1.UnimplementedValidatorServer
+
+	// Metrics, if set, records compilations and comparisons
+	Metrics *Metrics
+}
+
+// Register adds a Server with the given metrics, which may be nil, to a
+// gRPC server
+func Register(s *grpc.Server, metrics *Metrics) {
+	validatorv1.RegisterValidatorServer(s, &Server{Metrics: metrics})
+}
+
+// Compile generates the requested programs for the filter
+func (s *Server) Compile(ctx context.Context, req *validatorv1.CompileRequest) (*validatorv1.CompileResponse, error) {
+	f, err := buildFilter(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	prototype, reference, err := s.generate(ctx, f, req.GetProgram(), req.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+	resp := &validatorv1.CompileResponse{}
+	if prototype != nil {
+		resp.Prototype = prototypeProgram(prototype)
+	}
+	if reference != nil {
+		resp.Reference = referenceProgram(reference)
+	}
+	return resp, nil
+}
+
+// Compare generates both programs, compares them and applies the gate
+func (s *Server) Compare(ctx context.Context, req *validatorv1.CompareRequest) (*validatorv1.CompareResponse, error) {
+	f, err := buildFilter(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := compare.ParseFailOn(strings.Join(req.GetFailOn(), ","))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	gate := compare.Gate{MinScore: req.GetMinScore(), FailOn: conditions}
+	prototype, reference, err := s.generate(ctx, f, validatorv1.ProgramKind_PROGRAM_KIND_BOTH, req.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	result := compare.Compare(reference, prototype)
+	s.Metrics.compared(result)
+	var report bytes.Buffer
+	if err := result.Render(&report); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to render report: %v", err)
+	}
+	resp := &validatorv1.CompareResponse{
+		Verdict:            result.Verdict,
+		Score:              result.Score,
+		Matches:            result.Matches,
+		Differences:        result.Differences,
+		MissingInPrototype: result.MissingInPrototype,
+		ExtraInPrototype:   result.ExtraInPrototype,
+		StructuralDiffs:    result.StructuralDiffs,
+		Passed:             true,
+		Prototype:          prototypeProgram(prototype),
+		Reference:          referenceProgram(reference),
+		Report:             report.String(),
+	}
+	if err := gate.Check(result); err != nil {
+		resp.Passed, resp.GateFailure = false, err.Error()
+	}
+	return resp, nil
+}
+
+// Simulate runs every packet through the requested programs, sending one
+// result per packet. A program that fails on a packet reports the error in
+// its verdict rather than ending the stream.
+func (s *Server) Simulate(req *validatorv1.SimulateRequest, stream validatorv1.Validator_SimulateServer) error {
+	f, err := buildFilter(req.GetFilter())
+	if err != nil {
+		return err
+	}
+	prototype, reference, err := s.generate(stream.Context(), f, req.GetProgram(), req.GetOptions())
+	if err != nil {
+		return err
+	}
+
+	type namedProgram struct {
+		name string
+		prog []*bpf.Instruction
+	}
+	var programs []namedProgram
+	if prototype != nil {
+		programs = append(programs, namedProgram{"prototype", prototype.Instructions})
+	}
+	if reference != nil {
+		programs = append(programs, namedProgram{"reference", reference.Instructions})
+	}
+
+	for i, data := range req.GetPackets() {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		result := &validatorv1.SimulateResult{Index: uint32(i), Agree: true}
+		for _, p := range programs {
+			verdict := &validatorv1.Verdict{Program: p.name}
+			if r, err := vm.Run(p.prog, data); err != nil {
+				verdict.Error = err.Error()
+			} else {
+				verdict.Accepted, verdict.Length, verdict.Executed = r.Accepted, r.Length, uint32(r.Executed)
+			}
+			if len(result.Verdicts) > 0 {
+				first := result.Verdicts[0]
+				result.Agree = result.Agree && first.Accepted == verdict.Accepted && first.Error == "" && verdict.Error == ""
+			}
+			result.Verdicts = append(result.Verdicts, verdict)
+		}
+		if err := stream.Send(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildFilter converts the Filter message, parsing the expression if one
+// is given
+func buildFilter(m *validatorv1.Filter) (*filter.PacketFilter, error) {
+	if m == nil {
+		return nil, status.Error(codes.InvalidArgument, "filter is required")
+	}
+	var f *filter.PacketFilter
+	if m.GetExpr() != "" {
+		if hasStructuredFields(m) {
+			return nil, status.Error(codes.InvalidArgument, "filter expr cannot be combined with structured fields other than link_type")
+		}
+		parsed, err := filter.ParseExpr(m.GetExpr())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expression: %v", err)
+		}
+		f = parsed
+	} else {
+		f = &filter.PacketFilter{
+			Protocol:  m.GetProtocol(),
+			SrcIP:     m.GetSrcIp(),
+			DstIP:     m.GetDstIp(),
+			SrcPort:   int(m.GetSrcPort()),
+			DstPort:   int(m.GetDstPort()),
+			SrcPorts:  ints(m.GetSrcPorts()),
+			DstPorts:  ints(m.GetDstPorts()),
+			HostIP:    m.GetHost(),
+			Port:      int(m.GetPort()),
+			Between:   m.GetBetween(),
+			VLAN:      m.GetVlan(),
+			VLANID:    int(m.GetVlanId()),
+			EtherType: int(m.GetEtherType()),
+			VNI:       int(m.GetVni()),
+			Inner:     m.GetInner(),
+		}
+		if m.GetTunnel() != "" {
+			tunnel, err := filter.ParseTunnel(m.GetTunnel())
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			f.Tunnel = tunnel
+		}
+	}
+	f.LinkType = filter.LinkType(m.GetLinkType())
+	if err := f.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+	}
+	return f, nil
+}
+
+// hasStructuredFields reports whether any criterion besides the expression
+// is set; the link type is not a criterion
+func hasStructuredFields(m *validatorv1.Filter) bool {
+	return m.GetProtocol() != "" || m.GetSrcIp() != "" || m.GetDstIp() != "" ||
+		m.GetSrcPort() != 0 || m.GetDstPort() != 0 || len(m.GetSrcPorts()) > 0 || len(m.GetDstPorts()) > 0 ||
+		m.GetHost() != "" || m.GetPort() != 0 || len(m.GetBetween()) > 0 ||
+		m.GetVlan() || m.GetVlanId() != 0 || m.GetEtherType() != 0 ||
+		m.GetTunnel() != "" || m.GetVni() != 0 || m.GetInner()
+}
+
+// generate builds the programs of the requested kind; the one not
+// requested is nil
+func (s *Server) generate(ctx context.Context, f *filter.PacketFilter, kind validatorv1.ProgramKind, opts *validatorv1.GenerateOptions) (*bpfgen.BPFCode, *tcpdump.BPFCode, error) {
+	genOpts, err := generateOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var prototype *bpfgen.BPFCode
+	var reference *tcpdump.BPFCode
+	switch kind {
+	case validatorv1.ProgramKind_PROGRAM_KIND_BOTH, validatorv1.ProgramKind_PROGRAM_KIND_PROTOTYPE, validatorv1.ProgramKind_PROGRAM_KIND_REFERENCE:
+	default:
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid program kind %d", kind)
+	}
+	if kind != validatorv1.ProgramKind_PROGRAM_KIND_REFERENCE {
+		if prototype, err = bpfgen.GenerateBPFWithOptions(f, genOpts); err != nil {
+			return nil, nil, status.Errorf(generateCode(err), "failed to generate prototype BPF: %v", err)
+		}
+		s.Metrics.compiled("prototype")
+	}
+	if kind != validatorv1.ProgramKind_PROGRAM_KIND_PROTOTYPE {
+		refOpts := tcpdump.Options{Unoptimized: opts.GetUnoptimizedReference()}
+		start := time.Now()
+		if reference, err = tcpdump.GenerateBPFWithOptions(ctx, f, refOpts); err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, status.FromContextError(ctx.Err()).Err()
+			}
+			return nil, nil, status.Errorf(generateCode(err), "failed to generate tcpdump BPF: %v", err)
+		}
+		s.Metrics.referenceCompiled(reference.Source, time.Since(start))
+	}
+	return prototype, reference, nil
+}
+
+// generateCode returns the status code of a generation error
+func generateCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, filter.ErrInvalidFilter):
+		return codes.InvalidArgument
+	case errors.Is(err, tcpdump.ErrTcpdumpUnavailable):
+		return codes.Unavailable
+	}
+	return codes.FailedPrecondition
+}
+
+// generateOptions converts the options message, with the CLI defaults for
+// empty fields
+func generateOptions(m *validatorv1.GenerateOptions) (bpfgen.Options, error) {
+	fragments := m.GetFragments()
+	if fragments == "" {
+		fragments = string(bpfgen.FragmentsMatchFirst)
+	}
+	policy, err := bpfgen.ParseFragmentPolicy(fragments)
+	if err != nil {
+		return bpfgen.Options{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	optLevel := m.GetOptLevel()
+	if optLevel == "" {
+		optLevel = "2"
+	}
+	level, err := bpfgen.ParseOptLevel(optLevel)
+	if err != nil {
+		return bpfgen.Options{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return bpfgen.Options{Partial: m.GetPartial(), OptLevel: level, Fragments: policy}, nil
+}
+
+// prototypeProgram converts a generated prototype program
+func prototypeProgram(code *bpfgen.BPFCode) *validatorv1.Program {
+	p := program(&code.Code, code.Disassembly(), "prototype")
+	p.Sources = code.Sources
+	for _, u := range code.Uncovered {
+		p.Uncovered = append(p.Uncovered, u.String())
+	}
+	return p
+}
+
+// referenceProgram converts a reference program
+func referenceProgram(code *tcpdump.BPFCode) *validatorv1.Program {
+	return program(&code.Code, bpf.Disassemble(code.Instructions), code.Source)
+}
+
+func program(code *bpf.Code, disassembly, compiler string) *validatorv1.Program {
+	p := &validatorv1.Program{
+		FilterExpr:  code.FilterExpr,
+		LinkType:    string(code.LinkType),
+		Disassembly: disassembly,
+		Compiler:    compiler,
+	}
+	for _, inst := range code.Instructions {
+		p.Instructions = append(p.Instructions, &validatorv1.Instruction{
+			Code: uint32(inst.Code), Jt: uint32(inst.JT), Jf: uint32(inst.JF), K: inst.K,
+		})
+	}
+	return p
+}
+
+func ints(values []uint32) []int {
+	var out []int
+	for _, v := range values {
+		out = append(out, int(v))
+	}
+	return out
+}
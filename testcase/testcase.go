@@ -0,0 +1,245 @@
+package testcase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/bpfgen"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/compare"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/filter"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/packet"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/tcpdump"
+	"github.com/imshubham22apr-gif/Antrea-Project-Prototype/pkg/vm"
+)
+
+// Suite is the top-level structure of a test-case file
+type Suite struct {
+	Cases []*Case `yaml:"cases"`
+}
+
+// Case combines a filter, sample packets and the expected outcomes
+type Case struct {
+	Name    string              `yaml:"name"`
+	Filter  filter.PacketFilter `yaml:"filter"`
+	Packets []*Packet           `yaml:"packets"`
+	Verdict string              `yaml:"verdict"` // expected verdict substring (empty means don't check)
+
+	// Fragments is the prototype's fragment policy; the reference always
+	// behaves like tcpdump
+	Fragments bpfgen.FragmentPolicy `yaml:"fragments"`
+}
+
+// Packet is a single packet given either as hex or as header fields
+type Packet struct {
+	Name   string       `yaml:"name"`
+	Hex    string       `yaml:"hex"`
+	Fields *packet.Spec `yaml:"fields"`
+	Match  bool         `yaml:"match"` // true if the filter should accept the packet
+}
+
+// PacketResult records how both programs handled one packet
+type PacketResult struct {
+	Name           string
+	Expected       bool
+	PrototypeMatch bool
+	ReferenceMatch bool
+	Err            error
+}
+
+// Passed reports whether the prototype program produced the expected verdict
+func (p *PacketResult) Passed() bool {
+	return p.Err == nil && p.PrototypeMatch == p.Expected
+}
+
+// CaseResult is the outcome of running one test case
+type CaseResult struct {
+	Name            string
+	Verdict         string
+	ExpectedVerdict string
+	Score           float64
+	ReferenceMocked bool
+	Packets         []*PacketResult
+	Err             error
+}
+
+// Passed reports whether the case met every expectation
+func (r *CaseResult) Passed() bool {
+	if r.Err != nil {
+		return false
+	}
+	if r.ExpectedVerdict != "" && !strings.Contains(r.Verdict, r.ExpectedVerdict) {
+		return false
+	}
+	for _, p := range r.Packets {
+		if !p.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Load reads a YAML test-case file
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test file: %w", err)
+	}
+
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse test file %s: %w", path, err)
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("test file %s contains no cases", path)
+	}
+
+	for i, c := range suite.Cases {
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		if _, err := bpfgen.ParseFragmentPolicy(string(c.Fragments)); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		for j, p := range c.Packets {
+			if p.Name == "" {
+				p.Name = fmt.Sprintf("packet-%d", j+1)
+			}
+			if (p.Hex == "") == (p.Fields == nil) {
+				return nil, fmt.Errorf("%s/%s: exactly one of hex or fields must be set", c.Name, p.Name)
+			}
+		}
+	}
+
+	return &suite, nil
+}
+
+// Run executes every case in the suite
+func (s *Suite) Run() []*CaseResult {
+	results := make([]*CaseResult, 0, len(s.Cases))
+	for _, c := range s.Cases {
+		results = append(results, c.Run())
+	}
+	return results
+}
+
+// Run generates both programs for the case's filter, compares them and
+// checks every packet against the expected verdict
+func (c *Case) Run() *CaseResult {
+	result := &CaseResult{
+		Name:            c.Name,
+		ExpectedVerdict: c.Verdict,
+	}
+
+	f := c.Filter
+	if err := f.Validate(); err != nil {
+		result.Err = fmt.Errorf("invalid filter: %w", err)
+		return result
+	}
+
+	tcpdumpBPF, err := tcpdump.GenerateBPF(context.Background(), &f)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to generate tcpdump BPF: %w", err)
+		return result
+	}
+	prototypeBPF, err := bpfgen.GenerateBPFWithOptions(&f, bpfgen.Options{Fragments: c.Fragments})
+	if err != nil {
+		result.Err = fmt.Errorf("failed to generate prototype BPF: %w", err)
+		return result
+	}
+
+	comparison := compare.Compare(tcpdumpBPF, prototypeBPF)
+	result.Verdict = comparison.Verdict
+	result.Score = comparison.Score
+	result.ReferenceMocked = tcpdumpBPF.IsMocked
+
+	referenceProg := tcpdumpBPF.Instructions
+	prototypeProg := prototypeBPF.Instructions
+
+	for _, p := range c.Packets {
+		pr := &PacketResult{Name: p.Name, Expected: p.Match}
+		result.Packets = append(result.Packets, pr)
+
+		data, err := p.Bytes(f.LinkType)
+		if err != nil {
+			pr.Err = err
+			continue
+		}
+
+		protoResult, err := vm.Run(prototypeProg, data)
+		if err != nil {
+			pr.Err = fmt.Errorf("prototype program: %w", err)
+			continue
+		}
+		pr.PrototypeMatch = protoResult.Accepted
+
+		refResult, err := vm.Run(referenceProg, data)
+		if err != nil {
+			pr.Err = fmt.Errorf("reference program: %w", err)
+			continue
+		}
+		pr.ReferenceMatch = refResult.Accepted
+	}
+
+	return result
+}
+
+// Bytes returns the raw packet, building it from fields for the filter's
+// link type when necessary
+func (p *Packet) Bytes(link filter.LinkType) ([]byte, error) {
+	if p.Hex != "" {
+		return packet.ParseHex(p.Hex)
+	}
+	return p.Fields.BuildFor(link)
+}
+
+// Report formats case results as a human-readable summary
+func Report(results []*CaseResult) string {
+	var sb strings.Builder
+	passed := 0
+
+	for _, r := range results {
+		status := "FAIL"
+		if r.Passed() {
+			status = "PASS"
+			passed++
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s\n", status, r.Name))
+
+		if r.Err != nil {
+			sb.WriteString(fmt.Sprintf("    error: %v\n", r.Err))
+			continue
+		}
+
+		if r.ExpectedVerdict != "" && !strings.Contains(r.Verdict, r.ExpectedVerdict) {
+			sb.WriteString(fmt.Sprintf("    verdict: expected %q, got %q\n", r.ExpectedVerdict, r.Verdict))
+		}
+
+		for _, p := range r.Packets {
+			if p.Err != nil {
+				sb.WriteString(fmt.Sprintf("    %s: error: %v\n", p.Name, p.Err))
+				continue
+			}
+			mark := "ok"
+			if !p.Passed() {
+				mark = "MISMATCH"
+			}
+			refNote := ""
+			if p.ReferenceMatch != p.Expected {
+				if r.ReferenceMocked {
+					refNote = " (reference disagrees, mock data)"
+				} else {
+					refNote = " (reference disagrees)"
+				}
+			}
+			sb.WriteString(fmt.Sprintf("    %-24s expected=%-5t prototype=%-5t reference=%-5t %s%s\n",
+				p.Name, p.Expected, p.PrototypeMatch, p.ReferenceMatch, mark, refNote))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d/%d cases passed\n", passed, len(results)))
+	return sb.String()
+}